@@ -0,0 +1,41 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+	"github.com/coolsnady/hcutil/hdkeychain"
+)
+
+// VerifyColdWalletExtPub implements rpcserver.ColdWalletVerifier.  The keys
+// are compared after parsing so both encodings of the same key match, and the
+// first fee address is derived from the key of stakepoold so the frontend can
+// check it derives the same one.
+func (ctx *appContext) VerifyColdWalletExtPub(xpub string) (string, error) {
+	key, err := hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return "", err
+	}
+	if key.IsPrivate() {
+		return "", errors.New("extended key is private")
+	}
+	if !key.IsForNet(ctx.params) {
+		return "", errors.New("extended public key is for wrong network")
+	}
+	if key.String() != ctx.coldwalletextpub.String() {
+		log.Errorf("Frontend is configured with coldwalletextpub %s "+
+			"which does not match %s", xpub, ctx.coldwalletextpub)
+		return "", rpcserver.ErrColdWalletExtPubMismatch
+	}
+
+	addr, err := voting.FeeAddress(ctx.coldwalletextpub, 0, ctx.params)
+	if err != nil {
+		return "", err
+	}
+	return addr.EncodeAddress(), nil
+}
@@ -7,6 +7,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
@@ -15,18 +16,35 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	flags "github.com/btcsuite/go-flags"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/grpcserver"
+	"github.com/coolsnady/hcstakepool/scrub"
+	"github.com/coolsnady/hcstakepool/tracing"
+	"github.com/coolsnady/hcstakepool/version"
 	"github.com/coolsnady/hcutil"
 )
 
 const (
-	defaultConfigFilename = "stakepoold.conf"
-	defaultDataDirname    = "data"
-	defaultLogLevel       = "info"
-	defaultLogDirname     = "logs"
-	defaultLogFilename    = "stakepoold.log"
-	defaultPoolFees       = 5
+	defaultConfigFilename  = "stakepoold.conf"
+	defaultDataDirname     = "data"
+	defaultForkCheck       = time.Second * 30
+	defaultForkTolerance   = 1
+	defaultLeaseTTL        = time.Second * 30
+	defaultLogLevel        = "info"
+	defaultLogDirname      = "logs"
+	defaultLogFilename     = "stakepoold.log"
+	defaultNtfnBuffer      = 64
+	defaultPoolFees        = 5
+	defaultRPCKeepalive    = time.Hour * 2
+	defaultRPCCertRenewal  = time.Hour * 24 * 30
+	defaultRPCPingTimeout  = time.Second * 20
+	defaultSnapshot        = time.Minute * 10
+	defaultTicketReconcile = time.Minute * 30
+	defaultVoteLatencyWarn = time.Second * 5
+	defaultVoteWorkers     = 5
+	defaultWalletRelock    = time.Minute
 )
 
 var (
@@ -46,23 +64,34 @@ var (
 // to parse and execute service commands specified via the -s flag.
 var runServiceCommand func(string) error
 
+// stakepoold builds are marked as development builds unless the build
+// metadata is stamped with -ldflags "-X
+// github.com/coolsnady/hcstakepool/version.Build=foo".
+func init() {
+	if version.Build == "" {
+		version.Build = "dev"
+	}
+}
+
 // config defines the configuration options for hcd.
 //
 // See loadConfig for details on the configuration load process.
 type config struct {
 	HomeDir          string  `short:"A" long:"appdata" description:"Path to application home directory"`
 	ShowVersion      bool    `short:"V" long:"version" description:"Display version information and exit"`
+	ShowVersionJSON  bool    `long:"json" description:"Display version information as JSON (with --version)"`
 	ConfigFile       string  `short:"C" long:"configfile" description:"Path to configuration file"`
 	DataDir          string  `short:"b" long:"datadir" description:"Directory to store data"`
 	LogDir           string  `long:"logdir" description:"Directory to log output."`
 	TestNet          bool    `long:"testnet" description:"Use the test network"`
 	SimNet           bool    `long:"simnet" description:"Use the simulation test network"`
-	Profile          string  `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
+	Profile          string  `long:"profile" description:"Enable HTTP profiling on given port of localhost -- NOTE port must be between 1024 and 65536"`
 	CPUProfile       string  `long:"cpuprofile" description:"Write CPU profile to the specified file"`
 	MemProfile       string  `long:"memprofile" description:"Write mem profile to the specified file"`
 	DebugLevel       string  `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
 	ColdWalletExtPub string  `long:"coldwalletextpub" description:"The extended public key to send user stake pool fees to"`
 	PoolFees         float64 `long:"poolfees" description:"The per-ticket fees the user must send to the pool with their tickets"`
+	MaxVoteAge       int64   `long:"maxvoteage" description:"On startup, only vote winning tickets left over from the last run if their block is at most this many blocks behind the tip"`
 	DBHost           string  `long:"dbhost" description:"Hostname for database connection"`
 	DBUser           string  `long:"dbuser" description:"Username for database connection"`
 	DBPassword       string  `long:"dbpassword" description:"Password for database connection"`
@@ -76,11 +105,44 @@ type config struct {
 	WalletUser       string  `long:"walletuser" description:"Username for wallet server"`
 	WalletPassword   string  `long:"walletpassword" description:"Password for wallet server"`
 	WalletCert       string  `long:"walletcert" description:"Certificate path for wallet server"`
+	Proxy            string  `long:"proxy" description:"Connect to hcd, wallet servers and the trace collector via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
+	ProxyUser        string  `long:"proxyuser" description:"Username for proxy server"`
+	ProxyPass        string  `long:"proxypass" default-mask:"-" description:"Password for proxy server"`
 	Version          string
-	NoRPCListen      bool     `long:"norpclisten" description:"Do not start a gRPC server. User voting preferences update on a ticker"`
-	RPCListeners     []string `long:"rpclisten" description:"Add an interface/port to listen for RPC connections (default port: 9113, testnet: 19113)"`
-	RPCCert          string   `long:"rpccert" description:"File containing the certificate file"`
-	RPCKey           string   `long:"rpckey" description:"File containing the certificate key"`
+	NoRPCListen      bool          `long:"norpclisten" description:"Do not start a gRPC server. User voting preferences update on a ticker"`
+	NoJournal        bool          `long:"nojournal" description:"Do not journal the ticket notifications to replay the ones cut off by a crash at the next start"`
+	RPCListeners     []string      `long:"rpclisten" description:"Add an IP, IPv6 zoned address (e.g. [fe80::1%eth0]) or interface name, with optional port, to listen for RPC connections (default port: 9113, testnet: 19113)"`
+	RPCCert          string        `long:"rpccert" description:"File containing the certificate file"`
+	RPCKey           string        `long:"rpckey" description:"File containing the certificate key"`
+	RPCCertRenewal   time.Duration `long:"rpccertrenewal" description:"Renew autogenerated RPC certificates this long before they expire"`
+	RPCKeepalive     time.Duration `long:"rpckeepalive" description:"Ping RPC clients after this long without activity to keep their connections open through NATs and load balancers"`
+	RPCPingTimeout   time.Duration `long:"rpcpingtimeout" description:"Close the connection of an RPC client that doesn't answer a keepalive ping within this long"`
+	RPCMaxConnIdle   time.Duration `long:"rpcmaxconnidle" description:"Close RPC connections without calls for this long (0 never closes them)"`
+	RPCMaxConnAge    time.Duration `long:"rpcmaxconnage" description:"Close RPC connections this long after they are opened, once their calls finish, so clients reconnect and are balanced again (0 never closes them)"`
+	RPCReflection    bool          `long:"rpcreflection" description:"Register the gRPC reflection service for debugging tools like grpcurl"`
+	RPCTimeouts      []string      `long:"rpctimeout" description:"Set the timeout of an RPC method, as method:duration (eg. GetPoolStats:50ms), or of every method without its own with default:duration.  May be repeated"`
+	RPCAuth          []string      `long:"rpcauth" description:"Require RPC clients to send a token and grant them a role, as role:token where role is frontend (all methods) or monitor (read-only status methods).  May be repeated"`
+	RPCQuotas        []string      `long:"rpcquota" description:"Limit the RPC calls each client of a role may make per minute, as role:calls (eg. monitor:600).  Clients over their quota get ResourceExhausted errors.  May be repeated"`
+	HealthListen     string        `long:"healthlisten" description:"Serve the /healthz liveness and /readyz readiness endpoints over HTTP on this address for container health checks (disabled if empty)"`
+	OTLPEndpoint     string        `long:"otlpendpoint" description:"Export gRPC request traces to the OpenTelemetry collector at this OTLP/HTTP URL (eg. http://127.0.0.1:4318)"`
+	VoteLatencyWarn  time.Duration `long:"votelatencywarn" description:"Log a warning when sending a vote takes longer than this after the winning tickets notification (0 disables)"`
+	TicketReconcile  time.Duration `long:"ticketreconcile" description:"Reconcile the cached live tickets with the wallet and hcd this often (0 disables)"`
+	NoStartReconcile bool          `long:"nostartreconcile" description:"Do not check the tickets against the live ticket pool of hcd at startup"`
+	NtfnBuffer       int           `long:"ntfnbuffer" description:"Number of notifications from hcd queued for each handler before ntfnpolicy applies"`
+	NtfnPolicy       string        `long:"ntfnpolicy" description:"What to do with a notification when its handler's queue is full {queue, drop}.  drop only drops new and spent/missed tickets notifications, which the ticket reconciliation and the journal catch up on"`
+	VoteWorkers      int           `long:"voteworkers" description:"Maximum number of votes sent concurrently when a block selects several pool tickets"`
+	LeaseFile        string        `long:"leasefile" description:"Only vote while holding the lease in this file, which redundant stakepoold instances running against the same wallets share, and stand by otherwise (disabled if empty)"`
+	LeaseTTL         time.Duration `long:"leasettl" description:"How long the lease is held without being renewed, after which a standby takes over"`
+	SnapshotInterval time.Duration `long:"snapshotinterval" description:"Save the in-memory state this often so a restart after a crash doesn't look up the known tickets again (0 only saves on shutdown)"`
+	WalletPassFile   string        `long:"walletpassfile" description:"Unlock the wallet only to vote with the passphrase in this file, which only its owner may access"`
+	WalletPassPrompt bool          `long:"walletpassprompt" description:"Unlock the wallet only to vote with a passphrase prompted for at startup"`
+	WalletRelock     time.Duration `long:"walletrelock" description:"Lock the wallet again after this long without votes when it is unlocked with walletpassfile or walletpassprompt (0 locks it right after voting)"`
+	DefaultVoteBits  string        `long:"defaultvotebits" description:"Vote bits of the users without valid voting preferences for the vote version of hcwallet: wallet (those of hcwallet), abstain (the previous block is valid, abstain on every agenda) or a number"`
+	ForkCheckHcd     []string      `long:"forkcheckhcd" description:"Compare the chain of hcd with this other hcd node, as host or host,cert, to detect chain forks.  It is accessed with hcduser and hcdpassword, and with hcdcert unless a cert is given.  May be repeated"`
+	ForkCheck        time.Duration `long:"forkcheck" description:"Compare the chain of hcd with the forkcheckhcd nodes this often"`
+	ForkTolerance    int64         `long:"forktolerance" description:"Number of blocks at the tip of hcd and another node that may differ before a chain fork is suspected"`
+	ForkPause        bool          `long:"forkpause" description:"Pause voting while a chain fork is suspected and send the votes held back once the hcd nodes agree again, if they still can be"`
+	Faults           faultOptions  `group:"Fault injection" namespace:"fault" hidden:"true"`
 }
 
 // serviceOptions defines the configuration options for the daemon as a service
@@ -255,18 +317,32 @@ func newConfigParser(cfg *config, so *serviceOptions, options flags.Options) *fl
 func loadConfig() (*config, []string, error) {
 	// Default config.
 	cfg := config{
-		HomeDir:    defaultHomeDir,
-		ConfigFile: defaultConfigFile,
-		DebugLevel: defaultLogLevel,
-		DataDir:    defaultDataDir,
-		DBName:     defaultDBName,
-		DBPort:     defaultDBPort,
-		DBUser:     defaultDBUser,
-		LogDir:     defaultLogDir,
-		PoolFees:   defaultPoolFees,
-		RPCKey:     defaultRPCKeyFile,
-		RPCCert:    defaultRPCCertFile,
-		Version:    version(),
+		HomeDir:          defaultHomeDir,
+		LeaseTTL:         defaultLeaseTTL,
+		ConfigFile:       defaultConfigFile,
+		DebugLevel:       defaultLogLevel,
+		DataDir:          defaultDataDir,
+		DefaultVoteBits:  defaultVoteBitsWallet,
+		ForkCheck:        defaultForkCheck,
+		ForkTolerance:    defaultForkTolerance,
+		DBName:           defaultDBName,
+		DBPort:           defaultDBPort,
+		DBUser:           defaultDBUser,
+		LogDir:           defaultLogDir,
+		NtfnBuffer:       defaultNtfnBuffer,
+		NtfnPolicy:       ntfnPolicyQueue,
+		PoolFees:         defaultPoolFees,
+		RPCKey:           defaultRPCKeyFile,
+		RPCCert:          defaultRPCCertFile,
+		RPCCertRenewal:   defaultRPCCertRenewal,
+		RPCKeepalive:     defaultRPCKeepalive,
+		RPCPingTimeout:   defaultRPCPingTimeout,
+		SnapshotInterval: defaultSnapshot,
+		TicketReconcile:  defaultTicketReconcile,
+		VoteLatencyWarn:  defaultVoteLatencyWarn,
+		VoteWorkers:      defaultVoteWorkers,
+		WalletRelock:     defaultWalletRelock,
+		Version:          version.String(),
 	}
 
 	// Service options which are only added on Windows.
@@ -291,7 +367,16 @@ func loadConfig() (*config, []string, error) {
 	appName = strings.TrimSuffix(appName, filepath.Ext(appName))
 	usageMessage := fmt.Sprintf("Use %s -h to show usage", appName)
 	if preCfg.ShowVersion {
-		fmt.Println(appName, "version", version())
+		if preCfg.ShowVersionJSON {
+			info, err := json.Marshal(version.Get())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return nil, nil, err
+			}
+			fmt.Println(string(info))
+			os.Exit(0)
+		}
+		fmt.Println(appName, "version", version.String())
 		os.Exit(0)
 	}
 
@@ -399,6 +484,15 @@ func loadConfig() (*config, []string, error) {
 		// Also disable dns seeding on the simulation test network.
 		activeNetParams = &simNetParams
 	}
+	if numNets == 0 {
+		// Use the network the binary was built for by default.
+		switch version.Network {
+		case "testnet":
+			activeNetParams = &testNet2Params
+		case "simnet":
+			activeNetParams = &simNetParams
+		}
+	}
 	if numNets > 1 {
 		str := "%s: The testnet and simnet params can't be " +
 			"used together -- choose one of the three"
@@ -422,6 +516,13 @@ func loadConfig() (*config, []string, error) {
 	cfg.LogDir = cleanAndExpandPath(cfg.LogDir)
 	cfg.LogDir = filepath.Join(cfg.LogDir, netName(activeNetParams))
 
+	if cfg.LeaseFile != "" {
+		cfg.LeaseFile = cleanAndExpandPath(cfg.LeaseFile)
+	}
+	if cfg.WalletPassFile != "" {
+		cfg.WalletPassFile = cleanAndExpandPath(cfg.WalletPassFile)
+	}
+
 	// Special show command to list supported subsystems and exit.
 	if cfg.DebugLevel == "show" {
 		fmt.Println("Supported subsystems", supportedSubsystems())
@@ -573,6 +674,182 @@ func loadConfig() (*config, []string, error) {
 		cfg.WalletCert = path
 	}
 
+	// The proxy credentials are meaningless without a proxy to use them.
+	if cfg.Proxy == "" && (cfg.ProxyUser != "" || cfg.ProxyPass != "") {
+		str := "%s: proxyuser and proxypass require proxy to be set"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	// gRPC doesn't ping more often than every second.
+	if cfg.RPCKeepalive < time.Second || cfg.RPCPingTimeout < time.Second {
+		str := "%s: rpckeepalive and rpcpingtimeout must be at least 1s"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.RPCMaxConnIdle < 0 || cfg.RPCMaxConnAge < 0 {
+		str := "%s: rpcmaxconnidle and rpcmaxconnage may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.HealthListen != "" {
+		if _, _, err := net.SplitHostPort(cfg.HealthListen); err != nil {
+			str := "%s: healthlisten must be host:port: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
+	if cfg.SnapshotInterval < 0 {
+		str := "%s: snapshotinterval may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.TicketReconcile < 0 {
+		str := "%s: ticketreconcile may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.WalletPassFile != "" && cfg.WalletPassPrompt {
+		str := "%s: walletpassfile and walletpassprompt may not both be set"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.WalletRelock < 0 {
+		str := "%s: walletrelock may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if len(cfg.ForkCheckHcd) != 0 && cfg.ForkCheck < time.Second {
+		str := "%s: forkcheck must be at least 1s"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.ForkTolerance < 0 {
+		str := "%s: forktolerance may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.ForkPause && len(cfg.ForkCheckHcd) == 0 {
+		str := "%s: forkpause requires forkcheckhcd to be set"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.LeaseFile != "" && cfg.LeaseTTL < time.Second {
+		str := "%s: leasettl must be at least 1s"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.NtfnBuffer < 0 {
+		str := "%s: ntfnbuffer may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.NtfnPolicy != ntfnPolicyQueue && cfg.NtfnPolicy != ntfnPolicyDrop {
+		str := "%s: ntfnpolicy must be %s or %s"
+		err := fmt.Errorf(str, funcName, ntfnPolicyQueue, ntfnPolicyDrop)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	switch cfg.DefaultVoteBits {
+	case defaultVoteBitsWallet, defaultVoteBitsAbstain:
+	default:
+		if _, err := strconv.ParseUint(cfg.DefaultVoteBits, 10, 16); err != nil {
+			str := "%s: defaultvotebits must be %s, %s or a number of " +
+				"at most 65535"
+			err := fmt.Errorf(str, funcName, defaultVoteBitsWallet,
+				defaultVoteBitsAbstain)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
+	if cfg.VoteWorkers < 1 {
+		str := "%s: voteworkers must be at least 1"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.VoteLatencyWarn < 0 {
+		str := "%s: votelatencywarn may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.MaxVoteAge < 0 {
+		str := "%s: maxvoteage may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	// A certificate can't be renewed before it is created.
+	if cfg.RPCCertRenewal <= 0 || cfg.RPCCertRenewal >= grpcserver.CertValidity {
+		str := "%s: rpccertrenewal must be positive and less than %v"
+		err := fmt.Errorf(str, funcName, grpcserver.CertValidity)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if err := cfg.Faults.validate(); err != nil {
+		err := fmt.Errorf("%s: %v", funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if _, err := newRPCTimeouts(cfg.RPCTimeouts); err != nil {
+		err := fmt.Errorf("%s: %v", funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if _, err := newRPCAuthorizer(cfg.RPCAuth); err != nil {
+		err := fmt.Errorf("%s: %v", funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if _, err := newRPCQuotas(cfg.RPCQuotas); err != nil {
+		err := fmt.Errorf("%s: %v", funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.OTLPEndpoint != "" {
+		if err := tracing.ValidateEndpoint(cfg.OTLPEndpoint); err != nil {
+			err := fmt.Errorf("%s: otlpendpoint: %v", funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
 	// Set default listener to localhost
 	if len(cfg.RPCListeners) == 0 {
 		addrs, err := net.LookupHost("localhost")
@@ -591,6 +868,15 @@ func loadConfig() (*config, []string, error) {
 		cfg.RPCListeners = normalizeAddresses(cfg.RPCListeners, activeNetParams.RPCServerPort)
 	}
 
+	// Keep configured credentials out of the logs and RPC errors.
+	scrub.AddSecrets(cfg.DBPassword, cfg.HcdPassword, cfg.WalletPassword,
+		cfg.ProxyPass)
+	for _, option := range cfg.RPCAuth {
+		if i := strings.Index(option, ":"); i >= 0 {
+			scrub.AddSecrets(option[i+1:])
+		}
+	}
+
 	// Warn about missing config file only after all other configuration is
 	// done.  This prevents the warning on help messages and invalid
 	// options.  Note this should go directly before the return.
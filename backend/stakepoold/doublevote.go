@@ -0,0 +1,199 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+)
+
+// doubleVotesSize is the number of most recent double votes kept for the
+// GetDoubleVotes RPC.
+const doubleVotesSize = 1000
+
+const (
+	opReturn = 0x6a
+	opData75 = 0x4b
+)
+
+// doubleVoteCandidate is a vote hcd rejected because another vote of the
+// ticket was seen first.  The fields are copied from the voting.Vote of the
+// vote, which is modified while the votes are logged.
+type doubleVoteCandidate struct {
+	ticket      chainhash.Hash
+	msa         string
+	voteHash    chainhash.Hash
+	voteBits    uint16
+	conflicting chainhash.Hash
+}
+
+// DoubleVotes implements rpcserver.DoubleVoteReporter.
+func (ctx *appContext) DoubleVotes(sinceHeight int64) []*rpcserver.DoubleVote {
+	return ctx.doubleVotes.Since(sinceHeight)
+}
+
+// reportDoubleVotes records and logs the votes of winners that hcd rejected
+// for another vote of the ticket.  Redundant voting wallets with the same
+// vote bits sign the very same vote, which isn't a double vote.  The
+// conflicting votes are looked up outside of the handler.
+func (ctx *appContext) reportDoubleVotes(wt WinningTicketsForBlock, winners []*voting.Vote) {
+	var candidates []doubleVoteCandidate
+	for _, w := range winners {
+		if w.Err == nil || w.VoteHash == nil || !voting.IsDuplicateVote(w.Err) {
+			continue
+		}
+		conflicting := voting.DuplicateVoteTxid(w.Err)
+		if conflicting == nil || *conflicting == *w.VoteHash {
+			continue
+		}
+		candidates = append(candidates, doubleVoteCandidate{
+			ticket:      *w.Ticket,
+			msa:         w.MultiSigAddress,
+			voteHash:    *w.VoteHash,
+			voteBits:    w.VoteBits,
+			conflicting: *conflicting,
+		})
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	go func() {
+		for i := range candidates {
+			v := ctx.checkDoubleVote(wt.blockHash, wt.blockHeight,
+				&candidates[i])
+			ctx.doubleVotes.Add(v)
+			switch v.Source {
+			case rpcserver.DoubleVoteSourceExternal:
+				log.Errorf("double vote of ticket %v msa %v on block %v "+
+					"(height %v): vote %v with bits %v conflicts with vote "+
+					"%v with bits %v signed outside of the pool, the "+
+					"voting key may be compromised", v.Ticket,
+					v.MultiSigAddress, v.BlockHash, v.BlockHeight,
+					v.VoteHash, v.VoteBits, v.ConflictingVoteHash,
+					v.ConflictingVoteBits)
+			case rpcserver.DoubleVoteSourcePool:
+				log.Warnf("double vote of ticket %v msa %v on block %v "+
+					"(height %v): vote %v with bits %v conflicts with vote "+
+					"%v with bits %v of another voting wallet, the voting "+
+					"preferences of the wallets differ", v.Ticket,
+					v.MultiSigAddress, v.BlockHash, v.BlockHeight,
+					v.VoteHash, v.VoteBits, v.ConflictingVoteHash,
+					v.ConflictingVoteBits)
+			default:
+				log.Warnf("double vote of ticket %v msa %v on block %v "+
+					"(height %v): vote %v with bits %v conflicts with vote "+
+					"%v of unknown source", v.Ticket, v.MultiSigAddress,
+					v.BlockHash, v.BlockHeight, v.VoteHash, v.VoteBits,
+					v.ConflictingVoteHash)
+			}
+		}
+	}()
+}
+
+// checkDoubleVote looks up the conflicting vote of c and tells who signed it
+// by generating the vote again with its vote bits: hcwallet signs
+// deterministically, so the vote of another voting wallet of the pool is
+// generated again as is.
+func (ctx *appContext) checkDoubleVote(blockHash *chainhash.Hash, blockHeight int64,
+	c *doubleVoteCandidate) *rpcserver.DoubleVote {
+	v := &rpcserver.DoubleVote{
+		Ticket:              c.ticket,
+		MultiSigAddress:     c.msa,
+		BlockHash:           *blockHash,
+		BlockHeight:         blockHeight,
+		VoteHash:            c.voteHash,
+		VoteBits:            c.voteBits,
+		ConflictingVoteHash: c.conflicting,
+		Source:              rpcserver.DoubleVoteSourceUnknown,
+		Time:                time.Now(),
+	}
+
+	node := ctx.node()
+	if node == nil || node.Disconnected() {
+		return v
+	}
+	tx, err := node.GetRawTransaction(&c.conflicting)
+	if err != nil {
+		log.Debugf("unable to look up vote %v conflicting with vote %v: %v",
+			c.conflicting, c.voteHash, err)
+		return v
+	}
+	bits, bitsExt, ok := voteBits(tx)
+	if !ok {
+		log.Debugf("transaction %v conflicting with vote %v is no vote",
+			c.conflicting, c.voteHash)
+		return v
+	}
+	v.ConflictingVoteBits = bits
+
+	hash, err := ctx.regenerateVote(blockHash, blockHeight, &c.ticket, bits,
+		bitsExt)
+	if err != nil {
+		log.Debugf("unable to generate vote of ticket %v with bits %v again: "+
+			"%v", c.ticket, bits, err)
+		return v
+	}
+	if *hash == c.conflicting {
+		v.Source = rpcserver.DoubleVoteSourcePool
+	} else {
+		v.Source = rpcserver.DoubleVoteSourceExternal
+	}
+	return v
+}
+
+// regenerateVote generates the vote of ticket with voteBits without sending
+// it and returns its hash.
+func (ctx *appContext) regenerateVote(blockHash *chainhash.Hash, blockHeight int64,
+	ticket *chainhash.Hash, voteBits uint16, voteBitsExt string) (*chainhash.Hash, error) {
+	wallet := ctx.wallet()
+	if wallet == nil || wallet.Disconnected() {
+		return nil, errors.New("hcwallet is disconnected")
+	}
+	if ctx.unlocker != nil {
+		if err := ctx.unlocker.unlock(); err != nil {
+			return nil, err
+		}
+		defer ctx.unlocker.release()
+	}
+	res, err := wallet.GenerateVote(blockHash, blockHeight, ticket, voteBits,
+		voteBitsExt)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := hex.DecodeString(res.Hex)
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx()
+	if err := tx.FromBytes(buf); err != nil {
+		return nil, err
+	}
+	hash := tx.TxHash()
+	return &hash, nil
+}
+
+// voteBits returns the vote bits and the hex encoded extended vote bits of a
+// vote.  They are pushed by the null data script of its second output, the
+// vote bits first and little-endian.
+func voteBits(tx *wire.MsgTx) (uint16, string, bool) {
+	if len(tx.TxOut) < 2 {
+		return 0, "", false
+	}
+	script := tx.TxOut[1].PkScript
+	if len(script) < 4 || script[0] != opReturn || script[1] > opData75 ||
+		len(script) != 2+int(script[1]) {
+		return 0, "", false
+	}
+	data := script[2:]
+	return binary.LittleEndian.Uint16(data), hex.EncodeToString(data[2:]), true
+}
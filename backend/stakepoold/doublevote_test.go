@@ -0,0 +1,95 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+)
+
+func TestCheckDoubleVote(t *testing.T) {
+	ticket := chainhash.Hash{1}
+	blockHash := chainhash.Hash{2}
+	const blockHeight = 100
+
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	ctx := &appContext{
+		doubleVotes:      voting.NewDoubleVotes(10),
+		nodeConnection:   node,
+		walletConnection: wallet,
+	}
+
+	generate := func(bits uint16, ext string) *wire.MsgTx {
+		res, err := wallet.GenerateVote(&blockHash, blockHeight, &ticket,
+			bits, ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := hex.DecodeString(res.Hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := wire.NewMsgTx()
+		if err := tx.FromBytes(buf); err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	ours := generate(1, "")
+
+	// Another voting wallet of the pool voted with other vote bits.
+	pool := generate(5, "0102")
+	if bits, ext, ok := voteBits(pool); !ok || bits != 5 || ext != "0102" {
+		t.Fatalf("vote bits %v %q %v, want 5 \"0102\" true", bits, ext, ok)
+	}
+
+	// Someone else signed a vote with the same vote bits, which differs.
+	external := generate(5, "0102")
+	external.TxOut[2].PkScript = []byte{0}
+
+	node.AddTransaction(pool)
+	node.AddTransaction(external)
+
+	tests := []struct {
+		name        string
+		conflicting chainhash.Hash
+		source      rpcserver.DoubleVoteSource
+		bits        uint16
+	}{
+		{"pool", pool.TxHash(), rpcserver.DoubleVoteSourcePool, 5},
+		{"external", external.TxHash(), rpcserver.DoubleVoteSourceExternal, 5},
+		{"unknown", chainhash.Hash{3}, rpcserver.DoubleVoteSourceUnknown, 0},
+	}
+	for _, test := range tests {
+		v := ctx.checkDoubleVote(&blockHash, blockHeight, &doubleVoteCandidate{
+			ticket:      ticket,
+			msa:         "msa",
+			voteHash:    ours.TxHash(),
+			voteBits:    1,
+			conflicting: test.conflicting,
+		})
+		if v.Source != test.source {
+			t.Errorf("%s: source %v, want %v", test.name, v.Source,
+				test.source)
+		}
+		if v.ConflictingVoteBits != test.bits {
+			t.Errorf("%s: conflicting vote bits %v, want %v", test.name,
+				v.ConflictingVoteBits, test.bits)
+		}
+		if v.BlockHeight != blockHeight || v.Ticket != ticket {
+			t.Errorf("%s: double vote of ticket %v at height %v", test.name,
+				v.Ticket, v.BlockHeight)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"expvar"
+	"runtime"
+	"sync"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+)
+
+// walletInFlight is the number of hcwallet RPC calls in progress.
+var walletInFlight = expvar.NewInt("walletRPCInFlight")
+
+// publishExpvars publishes the runtime stats of ctx with expvar, which the
+// profiling server serves at /debug/vars.  It must only be called once.
+func (ctx *appContext) publishExpvars() {
+	expvar.Publish("channelDepths", expvar.Func(func() interface{} {
+		return map[string]int{
+			"blockConnected":     len(ctx.blockConnectedChan),
+			"grpcCommandQueue":   len(ctx.grpcCommandQueueChan),
+			"newTickets":         len(ctx.newTicketsChan),
+			"reorganization":     len(ctx.reorganizationChan),
+			"spentmissedTickets": len(ctx.spentmissedTicketsChan),
+			"winningTickets":     len(ctx.winningTicketsChan),
+		}
+	}))
+	expvar.Publish("goroutines", expvar.Func(func() interface{} {
+		return runtime.NumGoroutine()
+	}))
+}
+
+// countingWallet counts the calls in progress to a wallet in
+// walletInFlight.
+type countingWallet struct {
+	rpcclient.WalletSource
+}
+
+// countWallet returns w with its calls counted.
+func countWallet(w rpcclient.WalletSource) rpcclient.WalletSource {
+	return &countingWallet{WalletSource: w}
+}
+
+func (w *countingWallet) GenerateVote(blockHash *chainhash.Hash, height int64,
+	sstxHash *chainhash.Hash, voteBits uint16,
+	voteBitsExt string) (*dcrjson.GenerateVoteResult, error) {
+	walletInFlight.Add(1)
+	defer walletInFlight.Add(-1)
+	return w.WalletSource.GenerateVote(blockHash, height, sstxHash, voteBits,
+		voteBitsExt)
+}
+
+func (w *countingWallet) GetBestBlock() (*chainhash.Hash, int64, error) {
+	walletInFlight.Add(1)
+	defer walletInFlight.Add(-1)
+	return w.WalletSource.GetBestBlock()
+}
+
+func (w *countingWallet) GetTickets(includeImmature bool) ([]*chainhash.Hash, error) {
+	walletInFlight.Add(1)
+	defer walletInFlight.Add(-1)
+	return w.WalletSource.GetTickets(includeImmature)
+}
+
+func (w *countingWallet) GetTransaction(txHash *chainhash.Hash) (*dcrjson.GetTransactionResult, error) {
+	walletInFlight.Add(1)
+	defer walletInFlight.Add(-1)
+	return w.WalletSource.GetTransaction(txHash)
+}
+
+// GetTransactionAsync counts the call until its result is received.
+func (w *countingWallet) GetTransactionAsync(txHash *chainhash.Hash) rpcclient.TransactionFuture {
+	walletInFlight.Add(1)
+	return &countingFuture{
+		TransactionFuture: w.WalletSource.GetTransactionAsync(txHash),
+	}
+}
+
+func (w *countingWallet) ImportScriptRescanFrom(script []byte, rescan bool, scanFrom int) error {
+	walletInFlight.Add(1)
+	defer walletInFlight.Add(-1)
+	return w.WalletSource.ImportScriptRescanFrom(script, rescan, scanFrom)
+}
+
+func (w *countingWallet) ListScripts() ([][]byte, error) {
+	walletInFlight.Add(1)
+	defer walletInFlight.Add(-1)
+	return w.WalletSource.ListScripts()
+}
+
+func (w *countingWallet) RescanWallet(beginHeight int64) error {
+	walletInFlight.Add(1)
+	defer walletInFlight.Add(-1)
+	return w.WalletSource.RescanWallet(beginHeight)
+}
+
+func (w *countingWallet) WalletInfo() (*dcrjson.WalletInfoResult, error) {
+	walletInFlight.Add(1)
+	defer walletInFlight.Add(-1)
+	return w.WalletSource.WalletInfo()
+}
+
+func (w *countingWallet) WalletLock() error {
+	walletInFlight.Add(1)
+	defer walletInFlight.Add(-1)
+	return w.WalletSource.WalletLock()
+}
+
+func (w *countingWallet) WalletPassphrase(passphrase string, timeoutSecs int64) error {
+	walletInFlight.Add(1)
+	defer walletInFlight.Add(-1)
+	return w.WalletSource.WalletPassphrase(passphrase, timeoutSecs)
+}
+
+// countingFuture stops counting a GetTransactionAsync call once its result is
+// received.
+type countingFuture struct {
+	rpcclient.TransactionFuture
+	once sync.Once
+}
+
+func (f *countingFuture) Receive() (*dcrjson.GetTransactionResult, error) {
+	defer f.once.Do(func() { walletInFlight.Add(-1) })
+	return f.TransactionFuture.Receive()
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+)
+
+// faultOptions inject faults into stakepoold so operators can check that
+// their alerting and failover work before a real incident.  They are hidden
+// from the help output and must never be used on a production pool.
+type faultOptions struct {
+	WalletLatency     time.Duration `long:"walletlatency" description:"Delay every hcwallet RPC call by this long"`
+	DropNotifications float64       `long:"dropnotifications" description:"Drop this fraction (0-1) of the hcd notifications"`
+	GRPCErrors        float64       `long:"grpcerrors" description:"Fail this fraction (0-1) of the gRPC calls with Unavailable"`
+}
+
+// validate checks the fault options.
+func (f *faultOptions) validate() error {
+	if f.WalletLatency < 0 {
+		return fmt.Errorf("fault.walletlatency may not be negative")
+	}
+	if f.DropNotifications < 0 || f.DropNotifications > 1 {
+		return fmt.Errorf("fault.dropnotifications must be between 0 and 1")
+	}
+	if f.GRPCErrors < 0 || f.GRPCErrors > 1 {
+		return fmt.Errorf("fault.grpcerrors must be between 0 and 1")
+	}
+	return nil
+}
+
+// enabled returns whether any fault is injected.
+func (f *faultOptions) enabled() bool {
+	return f.WalletLatency > 0 || f.DropNotifications > 0 || f.GRPCErrors > 0
+}
+
+// faultInjector decides when faults are injected.  A nil faultInjector never
+// injects any.
+type faultInjector struct {
+	faultOptions
+
+	mtx  sync.Mutex
+	rand *rand.Rand
+}
+
+// faults is the fault injector of stakepoold.  It is nil unless faults are
+// enabled in the config.
+var faults *faultInjector
+
+// newFaultInjector returns a faultInjector for the options, or nil if they
+// don't enable any fault.
+func newFaultInjector(opts faultOptions) *faultInjector {
+	if !opts.enabled() {
+		return nil
+	}
+	return &faultInjector{
+		faultOptions: opts,
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// hit returns true for a fraction rate of its calls.
+func (f *faultInjector) hit(rate float64) bool {
+	if f == nil || rate <= 0 {
+		return false
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.rand.Float64() < rate
+}
+
+// failGRPC returns whether a gRPC call should fail.
+func (f *faultInjector) failGRPC() bool {
+	return f != nil && f.hit(f.GRPCErrors)
+}
+
+// dropNotification returns whether an hcd notification should be dropped
+// and logs it if so.
+func (f *faultInjector) dropNotification(name string) bool {
+	if f == nil || !f.hit(f.DropNotifications) {
+		return false
+	}
+	log.Warnf("fault injection: dropping %s notification", name)
+	return true
+}
+
+// wallet returns w with the wallet faults injected.
+func (f *faultInjector) wallet(w rpcclient.WalletSource) rpcclient.WalletSource {
+	if f == nil || f.WalletLatency == 0 {
+		return w
+	}
+	return &faultyWallet{WalletSource: w, latency: f.WalletLatency}
+}
+
+// notifications returns n with the notification faults injected.
+func (f *faultInjector) notifications(n rpcclient.ChainNotifications) rpcclient.ChainNotifications {
+	if f == nil || f.DropNotifications == 0 {
+		return n
+	}
+	return &faultyNotifications{ChainNotifications: n, faults: f}
+}
+
+// faultyWallet delays the calls to a wallet.
+type faultyWallet struct {
+	rpcclient.WalletSource
+	latency time.Duration
+}
+
+func (w *faultyWallet) GenerateVote(blockHash *chainhash.Hash, height int64,
+	sstxHash *chainhash.Hash, voteBits uint16,
+	voteBitsExt string) (*dcrjson.GenerateVoteResult, error) {
+	time.Sleep(w.latency)
+	return w.WalletSource.GenerateVote(blockHash, height, sstxHash, voteBits,
+		voteBitsExt)
+}
+
+func (w *faultyWallet) GetBestBlock() (*chainhash.Hash, int64, error) {
+	time.Sleep(w.latency)
+	return w.WalletSource.GetBestBlock()
+}
+
+func (w *faultyWallet) GetTickets(includeImmature bool) ([]*chainhash.Hash, error) {
+	time.Sleep(w.latency)
+	return w.WalletSource.GetTickets(includeImmature)
+}
+
+func (w *faultyWallet) GetTransaction(txHash *chainhash.Hash) (*dcrjson.GetTransactionResult, error) {
+	time.Sleep(w.latency)
+	return w.WalletSource.GetTransaction(txHash)
+}
+
+func (w *faultyWallet) GetTransactionAsync(txHash *chainhash.Hash) rpcclient.TransactionFuture {
+	time.Sleep(w.latency)
+	return w.WalletSource.GetTransactionAsync(txHash)
+}
+
+func (w *faultyWallet) ImportScriptRescanFrom(script []byte, rescan bool, scanFrom int) error {
+	time.Sleep(w.latency)
+	return w.WalletSource.ImportScriptRescanFrom(script, rescan, scanFrom)
+}
+
+func (w *faultyWallet) ListScripts() ([][]byte, error) {
+	time.Sleep(w.latency)
+	return w.WalletSource.ListScripts()
+}
+
+func (w *faultyWallet) RescanWallet(beginHeight int64) error {
+	time.Sleep(w.latency)
+	return w.WalletSource.RescanWallet(beginHeight)
+}
+
+func (w *faultyWallet) WalletInfo() (*dcrjson.WalletInfoResult, error) {
+	time.Sleep(w.latency)
+	return w.WalletSource.WalletInfo()
+}
+
+func (w *faultyWallet) WalletLock() error {
+	time.Sleep(w.latency)
+	return w.WalletSource.WalletLock()
+}
+
+func (w *faultyWallet) WalletPassphrase(passphrase string, timeoutSecs int64) error {
+	time.Sleep(w.latency)
+	return w.WalletSource.WalletPassphrase(passphrase, timeoutSecs)
+}
+
+// faultyNotifications drops some of the notifications to a
+// ChainNotifications.
+type faultyNotifications struct {
+	rpcclient.ChainNotifications
+	faults *faultInjector
+}
+
+func (n *faultyNotifications) BlockConnected(blockHeader []byte) {
+	if !n.faults.dropNotification("block connected") {
+		n.ChainNotifications.BlockConnected(blockHeader)
+	}
+}
+
+func (n *faultyNotifications) NewTickets(blockHash *chainhash.Hash,
+	blockHeight int64, tickets []*chainhash.Hash) {
+	if !n.faults.dropNotification("new tickets") {
+		n.ChainNotifications.NewTickets(blockHash, blockHeight, tickets)
+	}
+}
+
+func (n *faultyNotifications) Reorganization(oldHash *chainhash.Hash,
+	oldHeight int64, newHash *chainhash.Hash, newHeight int64) {
+	if !n.faults.dropNotification("reorganization") {
+		n.ChainNotifications.Reorganization(oldHash, oldHeight, newHash,
+			newHeight)
+	}
+}
+
+func (n *faultyNotifications) SpentAndMissedTickets(blockHash *chainhash.Hash,
+	blockHeight int64, tickets map[chainhash.Hash]bool) {
+	if !n.faults.dropNotification("spent and missed tickets") {
+		n.ChainNotifications.SpentAndMissedTickets(blockHash, blockHeight,
+			tickets)
+	}
+}
+
+func (n *faultyNotifications) WinningTickets(blockHash *chainhash.Hash,
+	blockHeight int64, winningTickets []*chainhash.Hash) {
+	if !n.faults.dropNotification("winning tickets") {
+		n.ChainNotifications.WinningTickets(blockHash, blockHeight,
+			winningTickets)
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+)
+
+// forkCheckNode is an hcd node the best block of hcd is compared with.  node
+// is nil while it is disconnected.
+type forkCheckNode struct {
+	cfg  *rpcclient.Config
+	node rpcclient.ChainSource
+}
+
+// forkChecker compares the chain hcd follows with other hcd nodes to detect
+// that one of them is on a fork.  hcd and a node agree when they have the same
+// block tolerance blocks below the lower of their best blocks, so blocks
+// racing at the tip aren't taken for a fork, nor is a node lagging behind.
+type forkChecker struct {
+	nodes     []*forkCheckNode
+	tolerance int64
+	pause     bool // pause voting while the nodes diverge
+
+	mtx      sync.Mutex
+	diverged string // how the nodes diverge, empty while they agree
+}
+
+// forkCheckConfigs returns the connection settings of the forkcheckhcd nodes,
+// given as host or host,cert.  They share the credentials of hcdhost and its
+// certificate unless they have their own.
+func forkCheckConfigs(cfg *config) ([]*rpcclient.Config, error) {
+	configs := make([]*rpcclient.Config, 0, len(cfg.ForkCheckHcd))
+	for _, entry := range cfg.ForkCheckHcd {
+		c := nodeRPCConfig(cfg)
+		parts := strings.SplitN(entry, ",", 2)
+		if parts[0] == "" {
+			return nil, fmt.Errorf("%q has no host", entry)
+		}
+		c.Host = normalizeAddress(parts[0],
+			activeNetParams.HcdRPCServerPort)
+		if len(parts) == 2 {
+			c.CertPath = cleanAndExpandPath(parts[1])
+			if !fileExists(c.CertPath) {
+				return nil, fmt.Errorf("certificate %s of %s doesn't "+
+					"exist", c.CertPath, c.Host)
+			}
+		}
+		if c.Host == cfg.HcdHost {
+			return nil, fmt.Errorf("%s is hcdhost itself", c.Host)
+		}
+		configs = append(configs, c)
+	}
+	return configs, nil
+}
+
+// newForkChecker returns a forkChecker of the nodes with configs, which are
+// connected when they are first checked.
+func newForkChecker(configs []*rpcclient.Config, tolerance int64,
+	pause bool) *forkChecker {
+	nodes := make([]*forkCheckNode, 0, len(configs))
+	for _, c := range configs {
+		nodes = append(nodes, &forkCheckNode{cfg: c})
+	}
+	return &forkChecker{
+		nodes:     nodes,
+		tolerance: tolerance,
+		pause:     pause,
+	}
+}
+
+// paused returns how the nodes diverge when voting is paused for it.
+func (f *forkChecker) paused() (string, bool) {
+	if f == nil || !f.pause {
+		return "", false
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.diverged, f.diverged != ""
+}
+
+// connect connects the nodes that are disconnected.  The connections never
+// deliver notifications.
+func (f *forkChecker) connect() {
+	for _, n := range f.nodes {
+		if n.node != nil && !n.node.Disconnected() {
+			continue
+		}
+		if n.node != nil {
+			n.node.Shutdown()
+			n.node = nil
+		}
+		node, _, err := rpcclient.ConnectNode(n.cfg, nil)
+		if err != nil {
+			log.Warnf("fork check: unable to connect to hcd %s: %v",
+				n.cfg.Host, err)
+			continue
+		}
+		n.node = node
+	}
+}
+
+// shutdown closes the connections to the nodes.
+func (f *forkChecker) shutdown() {
+	for _, n := range f.nodes {
+		if n.node != nil {
+			n.node.Shutdown()
+			n.node = nil
+		}
+	}
+}
+
+// chainTip is the best block of a node.
+type chainTip struct {
+	host   string
+	node   rpcclient.ChainSource
+	hash   *chainhash.Hash
+	height int64
+}
+
+// chainDivergence returns how the chains of the other nodes diverge from the
+// chain of the first beyond tolerance, or an empty string when they agree.
+// Each node is compared with the first at the lower of their best blocks.  ok
+// is false when the first node and none of the others could be compared.
+func chainDivergence(hosts []string, nodes []rpcclient.ChainSource,
+	tolerance int64) (divergence string, ok bool) {
+	var tips []chainTip
+	for i, node := range nodes {
+		if node == nil || node.Disconnected() {
+			if i == 0 {
+				return "", false
+			}
+			continue
+		}
+		hash, height, err := node.GetBestBlock()
+		if err != nil {
+			log.Warnf("fork check: unable to get the best block of hcd "+
+				"%s: %v", hosts[i], err)
+			if i == 0 {
+				return "", false
+			}
+			continue
+		}
+		tips = append(tips, chainTip{hosts[i], node, hash, height})
+	}
+
+	first := tips[0]
+	firstHashes := make(map[int64]*chainhash.Hash)
+	for _, t := range tips[1:] {
+		height := first.height
+		if t.height < height {
+			height = t.height
+		}
+		if first.height-t.height > tolerance {
+			log.Debugf("fork check: hcd %s is at height %d, behind hcd "+
+				"%s at height %d", t.host, t.height, first.host,
+				first.height)
+		}
+		height -= tolerance
+		if height < 0 {
+			height = 0
+		}
+
+		firstHash, found := firstHashes[height]
+		if !found {
+			var err error
+			firstHash, err = first.node.GetBlockHash(height)
+			if err != nil {
+				log.Warnf("fork check: unable to get the block at "+
+					"height %d of hcd %s: %v", height, first.host, err)
+				continue
+			}
+			firstHashes[height] = firstHash
+		}
+		hash, err := t.node.GetBlockHash(height)
+		if err != nil {
+			log.Warnf("fork check: unable to get the block at height %d "+
+				"of hcd %s: %v", height, t.host, err)
+			continue
+		}
+		if *hash != *firstHash {
+			return fmt.Sprintf("hcd %s has block %v at height %d but hcd "+
+				"%s has block %v", first.host, firstHash, height, t.host,
+				hash), true
+		}
+		ok = true
+	}
+	return "", ok
+}
+
+// check compares the chain of hcd with the other nodes and returns how they
+// diverge and whether that changed.  Without a verdict, as when only hcd
+// answers, the nodes are no longer taken to diverge, so voting isn't paused
+// for as long as the nodes that diverged are down.
+func (f *forkChecker) check(hcdHost string, hcd rpcclient.ChainSource) (string, bool) {
+	f.connect()
+	hosts := []string{hcdHost}
+	nodes := []rpcclient.ChainSource{hcd}
+	for _, n := range f.nodes {
+		hosts = append(hosts, n.cfg.Host)
+		nodes = append(nodes, n.node)
+	}
+	divergence, ok := chainDivergence(hosts, nodes, f.tolerance)
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if !ok && f.diverged != "" {
+		log.Warnf("fork check: unable to compare hcd with the other " +
+			"nodes, no longer suspecting a chain fork")
+	}
+	if divergence == f.diverged {
+		return f.diverged, false
+	}
+	f.diverged = divergence
+	return divergence, true
+}
+
+// forkCheckHandler compares the chain of hcd with the forkcheckhcd nodes
+// every interval.  The operator is alerted when they diverge and, with
+// forkpause, voting is paused until they agree again.  The votes held back
+// meanwhile are then sent if they still can be.  It must be run as a
+// goroutine.
+func (ctx *appContext) forkCheckHandler(hcdHost string, interval time.Duration) {
+	defer ctx.wg.Done()
+	defer ctx.forks.shutdown()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			divergence, changed := ctx.forks.check(hcdHost, ctx.node())
+			if !changed {
+				continue
+			}
+			if divergence != "" {
+				if ctx.forks.pause {
+					log.Errorf("fork check: suspected chain fork, %s.  "+
+						"Pausing voting until the nodes agree again",
+						divergence)
+				} else {
+					log.Errorf("fork check: suspected chain fork, %s",
+						divergence)
+				}
+				continue
+			}
+			log.Infof("fork check: the hcd nodes no longer diverge")
+			if ctx.forks.pause {
+				ctx.RLock()
+				tipHeight := ctx.lastBlockSeenHeight
+				ctx.RUnlock()
+				ctx.processPendingVotes(tipHeight)
+			}
+		case <-ctx.quit:
+			return
+		}
+	}
+}
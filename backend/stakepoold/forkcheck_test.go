@@ -0,0 +1,104 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+)
+
+// testChain returns a node whose main chain has the blocks of hashes at
+// heights 0 and up.
+func testChain(hashes ...byte) *rpcclienttest.Node {
+	n := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	for height, h := range hashes {
+		n.SetBlockHash(int64(height), chainhash.Hash{h})
+	}
+	return n
+}
+
+func TestChainDivergence(t *testing.T) {
+	hosts := []string{"a", "b", "c"}
+
+	tests := []struct {
+		name      string
+		nodes     []*rpcclienttest.Node
+		tolerance int64
+		diverged  bool
+		ok        bool
+	}{
+		{"same chain", []*rpcclienttest.Node{testChain(1, 2, 3), testChain(1, 2, 3)},
+			0, false, true},
+		{"racing tips", []*rpcclienttest.Node{testChain(1, 2, 3), testChain(1, 2, 4)},
+			1, false, true},
+		{"racing tips without tolerance",
+			[]*rpcclienttest.Node{testChain(1, 2, 3), testChain(1, 2, 4)},
+			0, true, true},
+		{"one block behind", []*rpcclienttest.Node{testChain(1, 2), testChain(1, 2, 3)},
+			1, false, true},
+		{"stuck node", []*rpcclienttest.Node{testChain(1, 2, 3), testChain(1)},
+			1, false, true},
+		{"fork below a stuck node",
+			[]*rpcclienttest.Node{testChain(1, 2, 3, 4, 5), testChain(1, 6, 7)},
+			1, true, true},
+		{"fork below the tip",
+			[]*rpcclienttest.Node{testChain(1, 2, 3), testChain(1, 5, 6)},
+			1, true, true},
+		{"fork on a third node",
+			[]*rpcclienttest.Node{testChain(1, 2, 3), testChain(1, 2, 3), testChain(1, 5, 6)},
+			1, true, true},
+		{"nothing to compare", []*rpcclienttest.Node{testChain(1, 2, 3)},
+			1, false, false},
+	}
+	for _, test := range tests {
+		nodes := make([]rpcclient.ChainSource, 0, len(test.nodes))
+		for _, n := range test.nodes {
+			nodes = append(nodes, n)
+		}
+		divergence, ok := chainDivergence(hosts[:len(nodes)], nodes,
+			test.tolerance)
+		if ok != test.ok || (divergence != "") != test.diverged {
+			t.Errorf("%s: divergence %q ok %v, want diverged %v ok %v",
+				test.name, divergence, ok, test.diverged, test.ok)
+		}
+	}
+
+	// A disconnected node can't be compared.
+	stuck := testChain(1)
+	stuck.SetDisconnected(true)
+	_, ok := chainDivergence(hosts[:2],
+		[]rpcclient.ChainSource{testChain(1, 2, 3), stuck}, 1)
+	if ok {
+		t.Error("compared a disconnected node")
+	}
+}
+
+func TestForkCheckerQuorumLost(t *testing.T) {
+	f := newForkChecker(nil, 1, true)
+	other := testChain(1, 5, 6)
+	f.nodes = []*forkCheckNode{{cfg: &rpcclient.Config{Host: "b"}, node: other}}
+
+	if divergence, changed := f.check("a", testChain(1, 2, 3)); divergence == "" || !changed {
+		t.Fatalf("divergence %q changed %v, want a divergence", divergence,
+			changed)
+	}
+	if _, paused := f.paused(); !paused {
+		t.Fatal("voting not paused on a divergence")
+	}
+
+	// The node that diverged going down doesn't keep voting paused.
+	other.SetDisconnected(true)
+	if divergence, changed := f.check("a", testChain(1, 2, 3)); divergence != "" || !changed {
+		t.Errorf("divergence %q changed %v, want it cleared", divergence,
+			changed)
+	}
+	if _, paused := f.paused(); paused {
+		t.Error("voting still paused without nodes to compare")
+	}
+}
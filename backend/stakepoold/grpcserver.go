@@ -8,222 +8,211 @@ package main
 
 import (
 	"context"
-	"crypto/elliptic"
-	"crypto/tls"
-	"errors"
-	"io/ioutil"
-	"net"
-	"os"
-	"path/filepath"
-	"runtime"
 	"strings"
 	"time"
 
 	xcontext "golang.org/x/net/context"
 
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/grpcserver"
 	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
-	"github.com/coolsnady/hcutil"
+	"github.com/coolsnady/hcstakepool/scrub"
+	"github.com/coolsnady/hcstakepool/tracing"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
-// generateRPCKeyPair generates a new RPC TLS keypair and writes the cert and
-// possibly also the key in PEM format to the paths specified by the config.  If
-// successful, the new keypair is returned.
-func generateRPCKeyPair(writeKey bool) (tls.Certificate, error) {
-	log.Info("Generating TLS certificates...")
+func interceptUnary(ctx xcontext.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	startTime := time.Now()
 
-	// Create directories for cert and key files if they do not yet exist.
-	certDir, _ := filepath.Split(cfg.RPCCert)
-	keyDir, _ := filepath.Split(cfg.RPCKey)
-	err := os.MkdirAll(certDir, 0700)
-	if err != nil {
-		return tls.Certificate{}, err
-	}
-	err = os.MkdirAll(keyDir, 0700)
-	if err != nil {
-		return tls.Certificate{}, err
-	}
+	// parse out method from '/package.service/method'
+	methodSplit := strings.SplitAfterN(info.FullMethod, "/", 3)
+	method := methodSplit[2]
+	peer, peerOk := peer.FromContext(ctx)
 
-	// Generate cert pair.
-	org := "stakepoold autogenerated cert"
-	validUntil := time.Now().Add(time.Hour * 24 * 365 * 10)
-	cert, key, err := hcutil.NewTLSCertPair(elliptic.P521(), org,
-		validUntil, nil)
-	if err != nil {
-		return tls.Certificate{}, err
-	}
-	keyPair, err := tls.X509KeyPair(cert, key)
-	if err != nil {
-		return tls.Certificate{}, err
+	// Continue the trace of the frontend request the call is made for and
+	// tag the log lines with its request ID.
+	ctx = tracing.FromIncomingContext(ctx)
+	prefix := requestLogPrefix(ctx)
+	ctx, span := tracer.Start(ctx, info.FullMethod, tracing.SpanKindServer)
+	defer span.End()
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+	if peerOk {
+		span.SetAttribute("net.peer.addr", peer.Addr.String())
 	}
 
-	// Write cert and (potentially) the key files.
-	err = ioutil.WriteFile(cfg.RPCCert, cert, 0600)
+	client, err := rpcAuth.authorize(ctx, info.FullMethod)
 	if err != nil {
-		return tls.Certificate{}, err
-	}
-	if writeKey {
-		err = ioutil.WriteFile(cfg.RPCKey, key, 0600)
-		if err != nil {
-			rmErr := os.Remove(cfg.RPCCert)
-			if rmErr != nil {
-				log.Warnf("Cannot remove written certificates: %v",
-					rmErr)
-			}
-			return tls.Certificate{}, err
+		if peerOk {
+			grpcLog.Warnf("%s%s denied to %s: %v", prefix, method,
+				peer.Addr.String(), err)
+		} else {
+			grpcLog.Warnf("%s%s denied: %v", prefix, method, err)
 		}
+		span.SetError(err)
+		return nil, err
 	}
+	span.SetAttribute("rpc.role", string(client.role))
+	span.SetAttribute("rpc.client", client.name)
 
-	log.Info("Done generating TLS certificates")
-	return keyPair, nil
-}
-
-func interceptUnary(ctx xcontext.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-	startTime := time.Now()
+	if md, err := rpcQuotas.countRPCCall(client); err != nil {
+		grpcLog.Warnf("%s%s denied: %v", prefix, method, err)
+		grpc.SetTrailer(ctx, md)
+		span.SetError(err)
+		return nil, err
+	}
 
-	// parse out method from '/package.service/method'
-	methodSplit := strings.SplitAfterN(info.FullMethod, "/", 3)
-	method := methodSplit[2]
-	peer, peerOk := peer.FromContext(ctx)
+	if faults.failGRPC() {
+		grpcLog.Warnf("%sfault injection: failing %s", prefix, method)
+		err := status.Error(codes.Unavailable, "injected fault")
+		span.SetError(err)
+		return nil, err
+	}
 
 	// limit the time we take
-	ctx, cancel := context.WithTimeout(ctx, rpcserver.GRPCCommandTimeout)
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeouts.timeout(method))
 	// it is good practice to use the cancellation function even with a timeout
 	defer cancel()
 
 	resp, err = handler(ctx, req)
+	if err != nil {
+		// Error strings end up in the frontend's logs and API responses.
+		if s, ok := status.FromError(err); ok {
+			err = status.Error(s.Code(), scrub.String(s.Message()))
+		} else {
+			err = scrub.Error(err)
+		}
+		span.SetError(err)
+	}
 	if err != nil && peerOk {
-		grpcLog.Errorf("%s invoked by %s failed: %v",
+		grpcLog.Errorf("%s%s invoked by %s failed: %v", prefix,
 			method, peer.Addr.String(), err)
 	}
 
 	defer func() {
 		if peerOk {
-			grpcLog.Infof("%s invoked by %s processed in %v", method,
-				peer.Addr.String(), time.Since(startTime))
+			grpcLog.Infof("%s%s invoked by %s processed in %v", prefix,
+				method, peer.Addr.String(), time.Since(startTime))
 		} else {
-			grpcLog.Infof("%s processed in %v", method,
+			grpcLog.Infof("%s%s processed in %v", prefix, method,
 				time.Since(startTime))
 		}
 	}()
 	return resp, err
 }
 
-type listenFunc func(net string, laddr string) (net.Listener, error)
-
-// makeListeners splits the normalized listen addresses into IPv4 and IPv6
-// addresses and creates new net.Listeners for each with the passed listen func.
-// Invalid addresses are logged and skipped.
-func makeListeners(normalizedListenAddrs []string, listen listenFunc) []net.Listener {
-	ipv4Addrs := make([]string, 0, len(normalizedListenAddrs)*2)
-	ipv6Addrs := make([]string, 0, len(normalizedListenAddrs)*2)
-	for _, addr := range normalizedListenAddrs {
-		host, _, err := net.SplitHostPort(addr)
-		if err != nil {
-			// Shouldn't happen due to already being normalized.
-			log.Errorf("`%s` is not a normalized "+
-				"listener address", addr)
-			continue
-		}
+// serverStream is a grpc.ServerStream with the context of the traced call.
+type serverStream struct {
+	grpc.ServerStream
+	ctx xcontext.Context
+}
 
-		// Empty host or host of * on plan9 is both IPv4 and IPv6.
-		if host == "" || (host == "*" && runtime.GOOS == "plan9") {
-			ipv4Addrs = append(ipv4Addrs, addr)
-			ipv6Addrs = append(ipv6Addrs, addr)
-			continue
-		}
+func (s *serverStream) Context() xcontext.Context {
+	return s.ctx
+}
 
-		// Remove the IPv6 zone from the host, if present.  The zone
-		// prevents ParseIP from correctly parsing the IP address.
-		// ResolveIPAddr is intentionally not used here due to the
-		// possibility of leaking a DNS query over Tor if the host is a
-		// hostname and not an IP address.
-		zoneIndex := strings.Index(host, "%")
-		if zoneIndex != -1 {
-			host = host[:zoneIndex]
-		}
+// interceptStream is interceptUnary for streaming calls.  Streams are not
+// limited by the rpctimeout since they last until the client cancels them.
+func interceptStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	startTime := time.Now()
 
-		ip := net.ParseIP(host)
-		switch {
-		case ip == nil:
-			log.Warnf("`%s` is not a valid IP address", host)
-		case ip.To4() == nil:
-			ipv6Addrs = append(ipv6Addrs, addr)
-		default:
-			ipv4Addrs = append(ipv4Addrs, addr)
-		}
+	methodSplit := strings.SplitAfterN(info.FullMethod, "/", 3)
+	method := methodSplit[2]
+	ctx := ss.Context()
+	peer, peerOk := peer.FromContext(ctx)
+	peerAddr := "unknown peer"
+	if peerOk {
+		peerAddr = peer.Addr.String()
 	}
-	listeners := make([]net.Listener, 0, len(ipv6Addrs)+len(ipv4Addrs))
-	for _, addr := range ipv4Addrs {
-		listener, err := listen("tcp4", addr)
-		if err != nil {
-			log.Warnf("Can't listen on %s: %v", addr, err)
-			continue
-		}
-		listeners = append(listeners, listener)
+
+	ctx = tracing.FromIncomingContext(ctx)
+	prefix := requestLogPrefix(ctx)
+	ctx, span := tracer.Start(ctx, info.FullMethod, tracing.SpanKindServer)
+	defer span.End()
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+	span.SetAttribute("net.peer.addr", peerAddr)
+
+	client, err := rpcAuth.authorize(ctx, info.FullMethod)
+	if err != nil {
+		grpcLog.Warnf("%s%s denied to %s: %v", prefix, method, peerAddr,
+			err)
+		span.SetError(err)
+		return err
 	}
-	for _, addr := range ipv6Addrs {
-		listener, err := listen("tcp6", addr)
-		if err != nil {
-			log.Warnf("Can't listen on %s: %v", addr, err)
-			continue
-		}
-		listeners = append(listeners, listener)
+	span.SetAttribute("rpc.role", string(client.role))
+	span.SetAttribute("rpc.client", client.name)
+
+	if md, err := rpcQuotas.countRPCCall(client); err != nil {
+		grpcLog.Warnf("%s%s denied: %v", prefix, method, err)
+		ss.SetTrailer(md)
+		span.SetError(err)
+		return err
 	}
-	return listeners
-}
 
-// openRPCKeyPair creates or loads the RPC TLS keypair specified by the
-// application config.
-func openRPCKeyPair() (tls.Certificate, error) {
-	// Generate a new keypair when the key is missing.
-	_, e := os.Stat(cfg.RPCKey)
-	keyExists := !os.IsNotExist(e)
-	if !keyExists {
-		return generateRPCKeyPair(true)
+	if faults.failGRPC() {
+		grpcLog.Warnf("%sfault injection: failing %s", prefix, method)
+		err := status.Error(codes.Unavailable, "injected fault")
+		span.SetError(err)
+		return err
 	}
 
-	return tls.LoadX509KeyPair(cfg.RPCCert, cfg.RPCKey)
+	grpcLog.Infof("%s%s stream opened by %s", prefix, method, peerAddr)
+	err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	if err != nil {
+		if s, ok := status.FromError(err); ok {
+			err = status.Error(s.Code(), scrub.String(s.Message()))
+		} else {
+			err = scrub.Error(err)
+		}
+		span.SetError(err)
+	}
+	grpcLog.Infof("%s%s stream of %s closed after %v: %v", prefix, method,
+		peerAddr, time.Since(startTime), err)
+	return err
 }
 
-func startGRPCServers(grpcCommandQueueChan chan *rpcserver.GRPCCommandQueue) (*grpc.Server, error) {
-	var (
-		server  *grpc.Server
-		keyPair tls.Certificate
-		err     error
-	)
-
-	keyPair, err = openRPCKeyPair()
-	if err != nil {
-		return nil, err
+// keepaliveParams returns the keepalive parameters of the gRPC server.  gRPC
+// treats zero durations as infinite.
+func keepaliveParams() keepalive.ServerParameters {
+	return keepalive.ServerParameters{
+		MaxConnectionIdle: cfg.RPCMaxConnIdle,
+		MaxConnectionAge:  cfg.RPCMaxConnAge,
+		Time:              cfg.RPCKeepalive,
+		Timeout:           cfg.RPCPingTimeout,
 	}
+}
 
-	listeners := makeListeners(cfg.RPCListeners, net.Listen)
-	if len(listeners) == 0 {
-		err := errors.New("failed to create listeners for RPC server")
+func startGRPCServers(grpcCommandQueueChan chan *rpcserver.GRPCCommandQueue, coldWalletVerifier rpcserver.ColdWalletVerifier, doubleVoteReporter rpcserver.DoubleVoteReporter, missingTicketAdder rpcserver.MissingTicketAdder, spentMissedFeed *rpcserver.SpentMissedFeed, stakeDifficultyReporter rpcserver.StakeDifficultyReporter, statusReporter rpcserver.StatusReporter, userDataMigrator rpcserver.UserDataMigrator, voteHistoryFeed *rpcserver.VoteHistoryFeed, walletRescanner rpcserver.WalletRescanner, quit <-chan struct{}) (*grpc.Server, error) {
+	srv, err := grpcserver.New(&grpcserver.Config{
+		Listeners:         cfg.RPCListeners,
+		CertFile:          cfg.RPCCert,
+		KeyFile:           cfg.RPCKey,
+		CertRenewal:       cfg.RPCCertRenewal,
+		Keepalive:         keepaliveParams(),
+		Reflection:        cfg.RPCReflection,
+		UnaryInterceptor:  interceptUnary,
+		StreamInterceptor: interceptStream,
+	})
+	if err != nil {
 		return nil, err
 	}
-	creds := credentials.NewServerTLSFromCert(&keyPair)
-	server = grpc.NewServer(grpc.Creds(creds), grpc.UnaryInterceptor(interceptUnary))
+	server := srv.GRPCServer()
 	rpcserver.StartVersionService(server)
-	rpcserver.StartStakepooldService(grpcCommandQueueChan, server)
-	for _, lis := range listeners {
-		lis := lis
-		go func() {
-			log.Infof("gRPC server listening on %s",
-				lis.Addr())
-			err := server.Serve(lis)
-			log.Tracef("Finished serving gRPC: %v",
-				err)
-		}()
+	rpcserver.StartStakepooldService(grpcCommandQueueChan,
+		srv.RotateCertificate, coldWalletVerifier, doubleVoteReporter,
+		missingTicketAdder, spentMissedFeed, stakeDifficultyReporter,
+		statusReporter, userDataMigrator, voteHistoryFeed,
+		walletRescanner, server)
+	for _, method := range rpcTimeouts.unknownMethods(server) {
+		log.Warnf("rpctimeout is set for unknown method %s", method)
 	}
-
-	// Error when the server can't be started
-	if server == nil {
-		return nil, errors.New("gRPC service cannot be started")
+	if err := srv.Serve(quit); err != nil {
+		return nil, err
 	}
 
 	return server, nil
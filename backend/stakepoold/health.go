@@ -0,0 +1,206 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+// healthCheckTimeout is how long the health endpoint waits for the locks
+// before reporting stakepoold as wedged.
+const healthCheckTimeout = time.Second * 5
+
+// healthy returns whether stakepoold isn't wedged, which is when the app and
+// connection locks can be taken within timeout.  Lost hcd and hcwallet
+// connections don't count since the connection watchdog reconnects them and
+// a restart wouldn't bring them back sooner.
+func (ctx *appContext) healthy(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		ctx.RLock()
+		ctx.RUnlock()
+		ctx.connMtx.RLock()
+		ctx.connMtx.RUnlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// notReady returns why stakepoold can't serve the frontend and vote, or
+// nothing when it can: it must have started the gRPC server and be connected
+// to hcd and hcwallet.
+func (ctx *appContext) notReady() []string {
+	var reasons []string
+	select {
+	case <-ctx.ready:
+	default:
+		reasons = append(reasons, "starting")
+	}
+	ctx.connMtx.RLock()
+	node, wallet := ctx.nodeConnection, ctx.walletConnection
+	ctx.connMtx.RUnlock()
+	if node == nil || node.Disconnected() {
+		reasons = append(reasons, "hcd: disconnected")
+	}
+	if wallet == nil || wallet.Disconnected() {
+		reasons = append(reasons, "hcwallet: disconnected")
+	}
+	return reasons
+}
+
+// Status implements rpcserver.StatusReporter.  hcwallet is only asked for
+// its best block when it is connected, and not waited for past the deadline
+// of reqCtx.
+func (ctx *appContext) Status(reqCtx context.Context) (*rpcserver.Status, error) {
+	ctx.RLock()
+	s := &rpcserver.Status{
+		BlockHeight:          ctx.lastBlockSeenHeight,
+		Users:                len(ctx.userVotingConfig),
+		LiveTickets:          len(ctx.liveTicketsMSA),
+		AddedLowFeeTickets:   len(ctx.addedLowFeeTicketsMSA),
+		IgnoredLowFeeTickets: len(ctx.ignoredLowFeeTicketsMSA),
+	}
+	ctx.RUnlock()
+	s.Fork, s.VotingPaused = ctx.forks.paused()
+	s.PendingNotifications = len(ctx.blockConnectedChan) +
+		len(ctx.newTicketsChan) + len(ctx.reorganizationChan) +
+		len(ctx.spentmissedTicketsChan) + len(ctx.winningTicketsChan)
+
+	node, wallet := ctx.node(), ctx.wallet()
+	s.NodeConnected = node != nil && !node.Disconnected()
+	s.WalletConnected = wallet != nil && !wallet.Disconnected()
+	if !s.WalletConnected {
+		return s, nil
+	}
+
+	type bestBlock struct {
+		height int64
+		err    error
+	}
+	c := make(chan bestBlock, 1)
+	go func() {
+		_, height, err := traceWallet(reqCtx, wallet).GetBestBlock()
+		c <- bestBlock{height, err}
+	}()
+	select {
+	case b := <-c:
+		if b.err != nil {
+			log.Warnf("Status: hcwallet GetBestBlock failed: %v", b.err)
+			break
+		}
+		s.WalletHeight = b.height
+	case <-reqCtx.Done():
+		return nil, reqCtx.Err()
+	}
+
+	return s, nil
+}
+
+// WalletInfo implements rpcserver.StatusReporter.  Like Status, it doesn't
+// wait for hcwallet past the deadline of reqCtx.
+func (ctx *appContext) WalletInfo(reqCtx context.Context) (*rpcserver.WalletInfo, error) {
+	ctx.connMtx.RLock()
+	wallet, version := ctx.walletConnection, ctx.walletVersion
+	ctx.connMtx.RUnlock()
+	info := &rpcserver.WalletInfo{
+		Connected: wallet != nil && !wallet.Disconnected(),
+	}
+	if !info.Connected {
+		return info, nil
+	}
+	info.Version = version.String()
+
+	type walletState struct {
+		info   *dcrjson.WalletInfoResult
+		height int64
+		err    error
+	}
+	c := make(chan walletState, 1)
+	go func() {
+		w := traceWallet(reqCtx, wallet)
+		res, err := w.WalletInfo()
+		if err != nil {
+			c <- walletState{err: err}
+			return
+		}
+		_, height, err := w.GetBestBlock()
+		c <- walletState{res, height, err}
+	}()
+	select {
+	case state := <-c:
+		if state.err != nil {
+			return nil, state.err
+		}
+		info.DaemonConnected = state.info.DaemonConnected
+		info.Unlocked = state.info.Unlocked
+		info.AutoUnlock = ctx.unlocker != nil
+		info.Voting = state.info.Voting
+		info.VoteVersion = state.info.VoteVersion
+		info.Height = state.height
+	case <-reqCtx.Done():
+		return nil, reqCtx.Err()
+	}
+
+	return info, nil
+}
+
+// healthHandler returns the handler of the liveness endpoint, /healthz, and
+// of the readiness endpoint, /readyz.  Both answer with status 200 and "ok",
+// or with status 503 and the reasons for failing.
+func (ctx *appContext) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !ctx.healthy(healthCheckTimeout) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, "wedged")
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		reasons := ctx.notReady()
+		if len(reasons) != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			for _, reason := range reasons {
+				fmt.Fprintln(w, reason)
+			}
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// startHealthServer serves the health endpoints on addr until quit is
+// closed.
+func (ctx *appContext) startHealthServer(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: ctx.healthHandler()}
+	go func() {
+		log.Infof("Health endpoints listening on %s", listener.Addr())
+		err := server.Serve(listener)
+		log.Tracef("Finished serving health endpoints: %v", err)
+	}()
+	go func() {
+		<-ctx.quit
+		server.Close()
+	}()
+	return nil
+}
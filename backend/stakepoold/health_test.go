@@ -0,0 +1,138 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+)
+
+func TestHealthHandler(t *testing.T) {
+	ctx := &appContext{ready: make(chan struct{})}
+	handler := ctx.healthHandler()
+	get := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	if code, body := get("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz while starting: %d %q", code, body)
+	}
+	code, body := get("/readyz")
+	if code != http.StatusServiceUnavailable ||
+		body != "starting\nhcd: disconnected\nhcwallet: disconnected\n" {
+		t.Errorf("/readyz while starting: %d %q", code, body)
+	}
+
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	ctx.nodeConnection = node
+	ctx.walletConnection = wallet
+	close(ctx.ready)
+	if code, body := get("/readyz"); code != http.StatusOK || body != "ok\n" {
+		t.Errorf("/readyz when started: %d %q", code, body)
+	}
+	wallet.SetDisconnected(true)
+	if code, body := get("/readyz"); code != http.StatusServiceUnavailable ||
+		body != "hcwallet: disconnected\n" {
+		t.Errorf("/readyz with the wallet disconnected: %d %q", code, body)
+	}
+
+	// A goroutine stuck holding the lock wedges stakepoold.
+	ctx.Lock()
+	defer ctx.Unlock()
+	if ctx.healthy(10 * time.Millisecond) {
+		t.Error("healthy while the lock is held")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ctx := &appContext{
+		addedLowFeeTicketsMSA:   map[chainhash.Hash]string{{1}: "msa"},
+		ignoredLowFeeTicketsMSA: map[chainhash.Hash]string{{2}: "msa", {3}: "msa"},
+		liveTicketsMSA:          map[chainhash.Hash]string{{1}: "msa", {4}: "msa"},
+		userVotingConfig:        map[string]userdata.UserVotingConfig{"msa": {}},
+		lastBlockSeenHeight:     100,
+		newTicketsChan:          make(chan NewTicketsForBlock, 2),
+	}
+	ctx.newTicketsChan <- NewTicketsForBlock{}
+
+	s, err := ctx.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := rpcserver.Status{
+		BlockHeight:          100,
+		Users:                1,
+		LiveTickets:          2,
+		AddedLowFeeTickets:   1,
+		IgnoredLowFeeTickets: 2,
+		PendingNotifications: 1,
+	}
+	if *s != want {
+		t.Errorf("status while disconnected: got %+v, want %+v", *s, want)
+	}
+
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	wallet.SetBestBlock(&chainhash.Hash{5}, 98)
+	ctx.nodeConnection = rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	ctx.walletConnection = wallet
+	s, err = ctx.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.NodeConnected = true
+	want.WalletConnected = true
+	want.WalletHeight = 98
+	if *s != want {
+		t.Errorf("status while connected: got %+v, want %+v", *s, want)
+	}
+}
+
+func TestWalletInfo(t *testing.T) {
+	ctx := &appContext{}
+	info, err := ctx.WalletInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *info != (rpcserver.WalletInfo{}) {
+		t.Errorf("wallet info without a wallet: %+v", *info)
+	}
+
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{
+		DaemonConnected: true,
+		Voting:          true,
+		VoteVersion:     5,
+	})
+	wallet.SetBestBlock(&chainhash.Hash{5}, 98)
+	ctx.walletConnection = wallet
+	ctx.walletVersion = rpcclient.Semver{Major: 5, Minor: 1}
+	info, err = ctx.WalletInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := rpcserver.WalletInfo{
+		Connected:       true,
+		Version:         "5.1.0",
+		DaemonConnected: true,
+		Voting:          true,
+		Height:          98,
+		VoteVersion:     5,
+	}
+	if *info != want {
+		t.Errorf("got wallet info %+v, want %+v", *info, want)
+	}
+}
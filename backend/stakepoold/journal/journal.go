@@ -0,0 +1,224 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package journal records notifications on disk before they are processed so
+// the ones that weren't processed completely can be replayed after a crash.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// compactAfter is how many processed entries are appended to a journal before
+// it is rewritten with only the unprocessed ones.
+const compactAfter = 1000
+
+// Entry is a journaled notification, or the record that the notification
+// with the same sequence number was processed.
+type Entry struct {
+	Seq  uint64          `json:"seq"`
+	Kind string          `json:"kind,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+	Done bool            `json:"done,omitempty"`
+}
+
+// Journal is an append-only file of notifications and of the sequence numbers
+// of the ones that were processed.  Every record is synced to disk before it
+// is considered written.  It is safe for concurrent access.
+type Journal struct {
+	mtx     sync.Mutex
+	path    string
+	f       *os.File
+	seq     uint64
+	pending map[uint64]Entry
+	done    int // processed entries appended since the last compaction
+}
+
+// Open opens the journal at path, creating it if needed, and returns the
+// entries that were not processed, oldest first.  They stay in the journal
+// until Done is called for them.  A partially written last record, left by a
+// crash while appending, is dropped.
+func Open(path string) (*Journal, []Entry, error) {
+	j := &Journal{
+		path:    path,
+		pending: make(map[uint64]Entry),
+	}
+	if err := j.read(); err != nil {
+		return nil, nil, err
+	}
+	if err := j.compact(); err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]Entry, 0, len(j.pending))
+	for _, e := range j.pending {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].Seq < entries[b].Seq
+	})
+	return j, entries, nil
+}
+
+// read loads the unprocessed entries and the last sequence number from the
+// journal file.
+func (j *Journal) read() error {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if e.Seq > j.seq {
+			j.seq = e.Seq
+		}
+		if e.Done {
+			delete(j.pending, e.Seq)
+		} else {
+			j.pending[e.Seq] = e
+		}
+	}
+	return scanner.Err()
+}
+
+// compact replaces the journal file with one holding only the unprocessed
+// entries and opens it for appending.
+//
+// This function MUST be called with the journal lock held.
+func (j *Journal) compact() error {
+	if j.f != nil {
+		j.f.Close()
+		j.f = nil
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(j.path),
+		filepath.Base(j.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	seqs := make([]uint64, 0, len(j.pending))
+	for seq := range j.pending {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(a, b int) bool { return seqs[a] < seqs[b] })
+	for _, seq := range seqs {
+		if err = enc.Encode(j.pending[seq]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), j.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("unable to compact journal %s: %v", j.path, err)
+	}
+
+	j.f, err = os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	j.done = 0
+	return nil
+}
+
+// write appends a record and syncs it to disk.
+//
+// This function MUST be called with the journal lock held.
+func (j *Journal) write(e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := j.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+// Append journals a notification of kind with data encoded as JSON and
+// returns its sequence number.
+func (j *Journal) Append(kind string, data interface{}) (uint64, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return 0, err
+	}
+
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	e := Entry{
+		Seq:  j.seq + 1,
+		Kind: kind,
+		Data: b,
+	}
+	if err := j.write(&e); err != nil {
+		return 0, err
+	}
+	j.seq = e.Seq
+	j.pending[e.Seq] = e
+	return e.Seq, nil
+}
+
+// Done records that the notification with sequence number seq was processed
+// so it isn't replayed.
+func (j *Journal) Done(seq uint64) error {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	if _, ok := j.pending[seq]; !ok {
+		return nil
+	}
+	if err := j.write(&Entry{Seq: seq, Done: true}); err != nil {
+		return err
+	}
+	delete(j.pending, seq)
+
+	j.done++
+	if j.done >= compactAfter {
+		return j.compact()
+	}
+	return nil
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package journal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "notifications.journal")
+
+	j, entries, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("new journal has %d entries", len(entries))
+	}
+	var seqs []uint64
+	for i := 0; i < 3; i++ {
+		seq, err := j.Append("kind", i)
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		seqs = append(seqs, seq)
+	}
+	if err := j.Done(seqs[1]); err != nil {
+		t.Fatalf("Done: %v", err)
+	}
+	j.Close()
+
+	// A crash while appending leaves a partial record behind.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":4,"kind":"ki`)
+	f.Close()
+
+	j, entries, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Seq != seqs[0] ||
+		entries[1].Seq != seqs[2] || string(entries[1].Data) != "2" {
+		t.Fatalf("unprocessed entries %+v, want %d and %d", entries,
+			seqs[0], seqs[2])
+	}
+
+	// Sequence numbers keep increasing and processed entries are gone
+	// after compaction.
+	seq, err := j.Append("kind", 3)
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if seq <= seqs[2] {
+		t.Errorf("sequence number %d after %d", seq, seqs[2])
+	}
+	for _, seq := range []uint64{seqs[0], seqs[2], seq} {
+		if err := j.Done(seq); err != nil {
+			t.Fatalf("Done: %v", err)
+		}
+	}
+	if err := j.compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	j.Close()
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Errorf("compacted journal without unprocessed entries: %v %v",
+			fi, err)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/lease"
+)
+
+// leaderElection decides whether this stakepoold votes when redundant
+// instances run against the same wallets.  The one holding the lease is the
+// leader, the others are hot standbys that keep their state up to date but
+// leave voting to the leader.
+type leaderElection struct {
+	lease lease.Lease
+	ttl   time.Duration
+
+	mtx     sync.Mutex
+	held    bool
+	expires time.Time // when the lease held runs out unless renewed
+	leader  bool      // last reported by campaign
+}
+
+// newLeaderElection returns an election for lease, which is held for ttl at a
+// time.
+func newLeaderElection(l lease.Lease, ttl time.Duration) *leaderElection {
+	return &leaderElection{
+		lease: l,
+		ttl:   ttl,
+	}
+}
+
+// leading returns whether the lease is held and hasn't expired.  A leader that
+// can't renew its lease stops leading once it expires, when a standby may take
+// over.
+func (e *leaderElection) leading() bool {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return e.held && time.Now().Before(e.expires)
+}
+
+// campaign takes or renews the lease and returns whether this instance became
+// or stopped being the leader since the last campaign.
+func (e *leaderElection) campaign() (elected, deposed bool) {
+	start := time.Now()
+	held, err := e.lease.Acquire(e.ttl)
+	if err != nil {
+		log.Warnf("leader election: unable to acquire the lease: %v", err)
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	switch {
+	case held:
+		e.held = true
+		e.expires = start.Add(e.ttl)
+	case err == nil:
+		e.held = false
+	}
+	// The lease held is kept until it expires when it can't be renewed.
+	leader := e.held && time.Now().Before(e.expires)
+	elected = leader && !e.leader
+	deposed = !leader && e.leader
+	e.leader = leader
+	return elected, deposed
+}
+
+// leading returns whether this stakepoold votes.  It always does without
+// leader election.
+func (ctx *appContext) leading() bool {
+	return ctx.election == nil || ctx.election.leading()
+}
+
+// leaderElectionHandler renews or tries to take the lease several times per
+// ttl.  On becoming the leader it sends the votes still pending, which the
+// previous leader may not have sent.  The lease is released on shutdown so a
+// standby takes over right away.  It must be run as a goroutine.
+func (ctx *appContext) leaderElectionHandler() {
+	defer ctx.wg.Done()
+
+	ticker := time.NewTicker(ctx.election.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			elected, deposed := ctx.election.campaign()
+			switch {
+			case elected:
+				log.Infof("leader election: this instance is the " +
+					"leader, voting")
+				ctx.RLock()
+				tipHeight := ctx.lastBlockSeenHeight
+				ctx.RUnlock()
+				ctx.processPendingVotes(tipHeight)
+			case deposed:
+				log.Warnf("leader election: lost the lease, " +
+					"standing by")
+			}
+		case <-ctx.quit:
+			if err := ctx.election.lease.Release(); err != nil {
+				log.Warnf("leader election: unable to release the "+
+					"lease: %v", err)
+			}
+			return
+		}
+	}
+}
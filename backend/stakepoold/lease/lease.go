@@ -0,0 +1,233 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package lease elects the leader of redundant stakepoold instances with a
+// lease that at most one of them holds at a time.  The holder renews the lease
+// before it expires.  When it stops doing so another instance takes over.
+package lease
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Lease is leadership held by one holder until it expires.
+type Lease interface {
+	// Acquire takes the lease, or renews it if it is held already, until
+	// ttl from now.  It returns false when another holder has the lease.
+	Acquire(ttl time.Duration) (bool, error)
+
+	// Release gives up the lease if it is held so another holder can take
+	// it over right away.
+	Release() error
+}
+
+// lockStale is how old the lock file of a FileLease must be to be considered
+// left over from a crashed holder.
+const lockStale = 10 * time.Second
+
+// errLocked is returned when another holder is updating a FileLease.
+var errLocked = errors.New("lease file is being updated by another holder")
+
+// fileRecord is the content of a lease file.
+type fileRecord struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// FileLease is a Lease kept in a file, which redundant instances on other
+// hosts can share over a network file system.  The expiry is an absolute
+// time, so the clocks of the hosts must be synchronized.
+type FileLease struct {
+	path   string
+	holder string
+	now    func() time.Time
+}
+
+var _ Lease = (*FileLease)(nil)
+
+// NewFileLease returns a Lease kept in the file at path for holder, which must
+// be unique among the instances sharing the file.
+func NewFileLease(path, holder string) *FileLease {
+	return &FileLease{
+		path:   path,
+		holder: holder,
+		now:    time.Now,
+	}
+}
+
+// fileLock is a lock file holding a token unique to the holder that created
+// it.
+type fileLock struct {
+	path  string
+	token []byte
+}
+
+// held returns whether the lock file is still the one created with the token.
+// It is not when the lock was taken over because it seemed stale.
+func (f *fileLock) held() bool {
+	b, err := ioutil.ReadFile(f.path)
+	return err == nil && bytes.Equal(b, f.token)
+}
+
+// unlock removes the lock file unless another holder took it over.
+func (f *fileLock) unlock() {
+	if f.held() {
+		os.Remove(f.path)
+	}
+}
+
+// lock keeps other holders from updating the lease file until the returned
+// lock is unlocked.  The lock file is created by linking a file with a unique
+// token, which fails when it exists, and the token is checked before the
+// lease file is written.  A lock file left over by a holder that crashed
+// while updating the lease is taken over once it is stale.
+func (l *FileLease) lock() (*fileLock, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(nonce[:])
+	lockPath := l.path + ".lock"
+	tmpPath := lockPath + "." + token
+	err := ioutil.WriteFile(tmpPath, []byte(token), 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpPath)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		err := os.Link(tmpPath, lockPath)
+		if err == nil {
+			return &fileLock{path: lockPath, token: []byte(token)}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if !l.breakStaleLock(lockPath, lockPath+"."+token+".stale") {
+			break
+		}
+	}
+	return nil, errLocked
+}
+
+// breakStaleLock removes the lock file at lockPath if it is stale and returns
+// whether it did.  The lock file is renamed to stalePath first, so of the
+// holders finding the same stale lock only one removes it.  A holder that
+// was too slow and renamed the lock another holder created in the meantime
+// puts it back.
+func (l *FileLease) breakStaleLock(lockPath, stalePath string) bool {
+	fi, err := os.Stat(lockPath)
+	if err != nil || l.now().Sub(fi.ModTime()) < lockStale {
+		return false
+	}
+	if err := os.Rename(lockPath, stalePath); err != nil {
+		return false
+	}
+	defer os.Remove(stalePath)
+
+	fi, err = os.Stat(stalePath)
+	if err != nil {
+		return false
+	}
+	if l.now().Sub(fi.ModTime()) < lockStale {
+		// The link fails if yet another holder took the lock, whose
+		// token check then fails for the holder of the renamed lock.
+		os.Link(stalePath, lockPath)
+		return false
+	}
+	return true
+}
+
+// read returns the lease record, or a zero record if there is no lease file.
+func (l *FileLease) read() (*fileRecord, error) {
+	b, err := ioutil.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return &fileRecord{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r fileRecord
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("invalid lease file %s: %v", l.path, err)
+	}
+	return &r, nil
+}
+
+// write replaces the lease record.  The record is renamed into place so
+// readers never see a partial one.
+func (l *FileLease) write(r *fileRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(l.path),
+		filepath.Base(l.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), l.path)
+}
+
+// Acquire takes or renews the lease until ttl from now unless another holder
+// has it.
+func (l *FileLease) Acquire(ttl time.Duration) (bool, error) {
+	lock, err := l.lock()
+	if err != nil {
+		return false, err
+	}
+	defer lock.unlock()
+
+	r, err := l.read()
+	if err != nil {
+		return false, err
+	}
+	now := l.now()
+	if r.Holder != l.holder && now.Before(r.Expires) {
+		return false, nil
+	}
+	if !lock.held() {
+		return false, errLocked
+	}
+	err = l.write(&fileRecord{
+		Holder:  l.holder,
+		Expires: now.Add(ttl),
+	})
+	return err == nil, err
+}
+
+// Release expires the lease if it is held.
+func (l *FileLease) Release() error {
+	lock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	r, err := l.read()
+	if err != nil || r.Holder != l.holder {
+		return err
+	}
+	if !lock.held() {
+		return errLocked
+	}
+	return l.write(&fileRecord{Holder: l.holder})
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package lease
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lease")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Unix(1500000000, 0)
+	clock := func() time.Time { return now }
+	path := filepath.Join(dir, "leader")
+	a := NewFileLease(path, "a")
+	a.now = clock
+	b := NewFileLease(path, "b")
+	b.now = clock
+
+	acquire := func(l *FileLease, want bool) {
+		t.Helper()
+		held, err := l.Acquire(time.Minute)
+		if err != nil {
+			t.Fatalf("%s: Acquire: %v", l.holder, err)
+		}
+		if held != want {
+			t.Fatalf("%s: Acquire returned %v, want %v", l.holder, held,
+				want)
+		}
+	}
+
+	acquire(a, true)
+	acquire(b, false)
+
+	// The holder renews its lease, the other one takes it over once it
+	// expires.
+	now = now.Add(50 * time.Second)
+	acquire(a, true)
+	now = now.Add(50 * time.Second)
+	acquire(b, false)
+	now = now.Add(11 * time.Second)
+	acquire(b, true)
+	acquire(a, false)
+
+	// A released lease is free right away.
+	if err := b.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	acquire(a, true)
+
+	// A stale lock file left by a crashed holder doesn't block the lease.
+	lockPath := path + ".lock"
+	if err := ioutil.WriteFile(lockPath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Acquire(time.Minute); err != errLocked {
+		t.Fatalf("Acquire with a fresh lock file: %v", err)
+	}
+	stale := time.Now().Add(-2 * lockStale)
+	if err := os.Chtimes(lockPath, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	now = time.Now()
+	acquire(a, true)
+}
+
+func TestFileLockTakenOver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lease")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "leader")
+	a := NewFileLease(path, "a")
+	lock, err := a.lock()
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	if !lock.held() {
+		t.Fatalf("lock not held after locking")
+	}
+
+	// Another holder took the lock over, believing it stale.
+	if err := ioutil.WriteFile(lock.path, []byte("b"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if lock.held() {
+		t.Errorf("lock held after it was taken over")
+	}
+	lock.unlock()
+	if b, err := ioutil.ReadFile(lock.path); err != nil || string(b) != "b" {
+		t.Errorf("unlock removed the lock of the other holder: %q %v", b,
+			err)
+	}
+
+	// Only the lock file is left behind.
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the lock file, got %d files", len(entries))
+	}
+}
@@ -11,8 +11,14 @@ import (
 	"path/filepath"
 
 	"github.com/btcsuite/btclog"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/grpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
 	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/store"
 	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+	"github.com/coolsnady/hcstakepool/scrub"
+	"github.com/coolsnady/hcstakepool/tracing"
 	"github.com/jrick/logrotate/rotator"
 )
 
@@ -21,13 +27,15 @@ import (
 type logWriter struct{}
 
 func (logWriter) Write(p []byte) (n int, err error) {
+	n = len(p)
+	p = scrub.Bytes(p)
 	os.Stdout.Write(p)
 	// Normally this if isn't required, but the tests in this main package need
 	// to work with uninitialized log rotators.
 	if logRotator != nil {
 		logRotator.Write(p)
 	}
-	return len(p), nil
+	return n, nil
 }
 
 // Loggers per subsystem.  A single backend logger is created and all subsytem
@@ -65,7 +73,12 @@ var subsystemLoggers = map[string]btclog.Logger{
 // Initialize package-global logger variables.
 func init() {
 	userdata.UseLogger(dbLog)
+	grpcserver.UseLogger(log)
 	rpcserver.UseLogger(grpcLog)
+	rpcclient.UseLogger(log)
+	store.UseLogger(log)
+	tracing.UseLogger(log)
+	voting.UseLogger(log)
 }
 
 // initLogRotator initializes the logging rotater to write logs to logFile and
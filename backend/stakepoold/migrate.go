@@ -0,0 +1,263 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+	"github.com/coolsnady/hcutil"
+)
+
+// appContext implements rpcserver.UserDataMigrator with the users it votes
+// for and the redeem scripts in the wallet.
+var _ rpcserver.UserDataMigrator = (*appContext)(nil)
+
+// validateUserData checks users and added low fee tickets that are about to
+// be imported.  Every redeem script must hash to the multisig address of its
+// user on the network in params, users may not be listed twice or clash with
+// existing users, and added low fee tickets must belong to a known user.  It
+// returns a description of every problem found.
+func validateUserData(users []rpcserver.UserData,
+	addedLowFeeTickets map[chainhash.Hash]string,
+	existing map[string]userdata.UserVotingConfig,
+	params *chaincfg.Params) []string {
+	var errs []string
+
+	msas := make(map[string]struct{}, len(users))
+	userIDs := make(map[int64]string, len(users))
+	for _, u := range users {
+		msa := u.VotingConfig.MultiSigAddress
+		userID := u.VotingConfig.Userid
+		if _, ok := msas[msa]; ok {
+			errs = append(errs, fmt.Sprintf("user %d: multisig address "+
+				"%v is listed more than once", userID, msa))
+			continue
+		}
+		msas[msa] = struct{}{}
+		if other, ok := userIDs[userID]; ok {
+			errs = append(errs, fmt.Sprintf("user %d: listed with "+
+				"multisig addresses %v and %v", userID, other, msa))
+			continue
+		}
+		userIDs[userID] = msa
+
+		if e, ok := existing[msa]; ok && e.Userid != userID {
+			errs = append(errs, fmt.Sprintf("user %d: multisig address "+
+				"%v belongs to existing user %d", userID, msa, e.Userid))
+		}
+
+		if len(u.RedeemScript) == 0 {
+			errs = append(errs, fmt.Sprintf("user %d: missing redeem "+
+				"script", userID))
+			continue
+		}
+		addr, err := hcutil.NewAddressScriptHash(u.RedeemScript, params)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("user %d: invalid redeem "+
+				"script: %v", userID, err))
+			continue
+		}
+		if addr.EncodeAddress() != msa {
+			errs = append(errs, fmt.Sprintf("user %d: redeem script is "+
+				"for %v on %s, not multisig address %v", userID,
+				addr.EncodeAddress(), params.Name, msa))
+		}
+	}
+
+	for ticket, msa := range addedLowFeeTickets {
+		_, imported := msas[msa]
+		_, exists := existing[msa]
+		if !imported && !exists {
+			errs = append(errs, fmt.Sprintf("added low fee ticket %v: "+
+				"unknown multisig address %v", ticket, msa))
+		}
+	}
+
+	sort.Strings(errs)
+	return errs
+}
+
+// ExportUserData returns the users stakepoold votes for along with their
+// redeem scripts from the wallet, ordered by user id, and the low fee tickets
+// added by the admin.  The wallet calls are traced as part of the request in
+// reqCtx.
+func (ctx *appContext) ExportUserData(reqCtx context.Context) ([]rpcserver.UserData,
+	map[chainhash.Hash]string, error) {
+	scripts, err := traceWallet(reqCtx, ctx.wallet()).ListScripts()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ListScripts failed: %v", err)
+	}
+	redeemScripts := make(map[string][]byte, len(scripts))
+	for _, script := range scripts {
+		addr, err := hcutil.NewAddressScriptHash(script, ctx.params)
+		if err != nil {
+			log.Warnf("ExportUserData: skipping script %x: %v", script,
+				err)
+			continue
+		}
+		redeemScripts[addr.EncodeAddress()] = script
+	}
+
+	ctx.RLock()
+	users := make([]rpcserver.UserData, 0, len(ctx.userVotingConfig))
+	for msa, config := range ctx.userVotingConfig {
+		script, ok := redeemScripts[msa]
+		if !ok {
+			log.Warnf("ExportUserData: no redeem script for user %d "+
+				"multisig address %v in the wallet", config.Userid, msa)
+		}
+		users = append(users, rpcserver.UserData{
+			VotingConfig: config,
+			RedeemScript: script,
+		})
+	}
+	addedLowFeeTickets := make(map[chainhash.Hash]string,
+		len(ctx.addedLowFeeTicketsMSA))
+	for ticket, msa := range ctx.addedLowFeeTicketsMSA {
+		addedLowFeeTickets[ticket] = msa
+	}
+	ctx.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].VotingConfig.Userid < users[j].VotingConfig.Userid
+	})
+
+	log.Infof("ExportUserData: exported %d users and %d added low fee "+
+		"tickets", len(users), len(addedLowFeeTickets))
+	return users, addedLowFeeTickets, nil
+}
+
+// ImportUserData validates users and added low fee tickets exported from
+// another stakepoold instance and, unless dryRun is set or there are
+// validation errors, imports the redeem scripts the wallet doesn't have yet
+// and starts voting for the users.  The wallet rescans for their tickets from
+// rescanHeight once all scripts are imported.  The tickets found by the
+// rescan are picked up the next time the ticket lists are fetched from the
+// wallet.
+//
+// The frontend database is the source of the voting config, so the users must
+// be added there as well or the next update from the frontend drops them.
+func (ctx *appContext) ImportUserData(reqCtx context.Context,
+	users []rpcserver.UserData,
+	addedLowFeeTickets map[chainhash.Hash]string, rescanHeight int64,
+	dryRun bool) (*rpcserver.ImportUserDataResult, error) {
+	ctx.RLock()
+	errs := validateUserData(users, addedLowFeeTickets, ctx.userVotingConfig,
+		ctx.params)
+	ctx.RUnlock()
+
+	result := &rpcserver.ImportUserDataResult{
+		Errors:             errs,
+		Users:              len(users),
+		AddedLowFeeTickets: len(addedLowFeeTickets),
+	}
+	if len(errs) != 0 {
+		log.Warnf("ImportUserData: rejected import of %d users with %d "+
+			"errors", len(users), len(errs))
+		return result, nil
+	}
+
+	wallet := traceWallet(reqCtx, ctx.wallet())
+	scripts, err := wallet.ListScripts()
+	if err != nil {
+		return nil, fmt.Errorf("ListScripts failed: %v", err)
+	}
+	var missing [][]byte
+	for _, u := range users {
+		found := false
+		for _, script := range scripts {
+			if bytes.Equal(script, u.RedeemScript) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, u.RedeemScript)
+		}
+	}
+	result.ScriptsImported = len(missing)
+
+	if dryRun {
+		log.Infof("ImportUserData: dry run would import %d users, %d "+
+			"redeem scripts and %d added low fee tickets", len(users),
+			len(missing), len(addedLowFeeTickets))
+		return result, nil
+	}
+
+	for i, script := range missing {
+		// Only rescan once all scripts are known to the wallet.
+		rescan := i == len(missing)-1
+		err := wallet.ImportScriptRescanFrom(script, rescan,
+			int(rescanHeight))
+		if err != nil {
+			return nil, fmt.Errorf("ImportScript failed after importing "+
+				"%d of %d redeem scripts: %v", i, len(missing), err)
+		}
+	}
+
+	// The users are validated again while holding the write lock since the
+	// frontend may have changed them while the scripts were imported.
+	if errs := ctx.mergeImportedUserData(users, addedLowFeeTickets); len(errs) != 0 {
+		log.Warnf("ImportUserData: rejected import of %d users after "+
+			"importing %d redeem scripts with %d errors", len(users),
+			len(missing), len(errs))
+		result.Errors = errs
+		return result, nil
+	}
+
+	log.Infof("ImportUserData: imported %d users, %d redeem scripts and %d "+
+		"added low fee tickets", len(users), len(missing),
+		len(addedLowFeeTickets))
+	return result, nil
+}
+
+// mergeImportedUserData validates the imported users and added low fee tickets
+// against the current ones and adds them unless there are validation errors,
+// which are returned.  The write lock is held throughout so updates of the
+// voting config made in the meantime are neither lost nor clash with the
+// import.
+func (ctx *appContext) mergeImportedUserData(users []rpcserver.UserData,
+	addedLowFeeTickets map[chainhash.Hash]string) []string {
+	ctx.Lock()
+	defer ctx.Unlock()
+
+	errs := validateUserData(users, addedLowFeeTickets, ctx.userVotingConfig,
+		ctx.params)
+	if len(errs) != 0 {
+		return errs
+	}
+
+	userVotingConfig := make(map[string]userdata.UserVotingConfig,
+		len(ctx.userVotingConfig)+len(users))
+	for msa, config := range ctx.userVotingConfig {
+		userVotingConfig[msa] = config
+	}
+	for _, u := range users {
+		userVotingConfig[u.VotingConfig.MultiSigAddress] = u.VotingConfig
+	}
+	ctx.userVotingConfig = userVotingConfig
+
+	// Like updateTicketData, added low fee tickets are voted for even if
+	// they were ignored before.
+	addedLowFeeTicketsMSA := make(map[chainhash.Hash]string,
+		len(ctx.addedLowFeeTicketsMSA)+len(addedLowFeeTickets))
+	for ticket, msa := range ctx.addedLowFeeTicketsMSA {
+		addedLowFeeTicketsMSA[ticket] = msa
+	}
+	for ticket, msa := range addedLowFeeTickets {
+		addedLowFeeTicketsMSA[ticket] = msa
+		delete(ctx.ignoredLowFeeTicketsMSA, ticket)
+		ctx.liveTicketsMSA[ticket] = msa
+	}
+	ctx.addedLowFeeTicketsMSA = addedLowFeeTicketsMSA
+	return nil
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+	"github.com/coolsnady/hcutil"
+)
+
+// testUserData returns user data with a redeem script hashing to the multisig
+// address of the user on the network in params.
+func testUserData(t *testing.T, userID int64, params *chaincfg.Params) rpcserver.UserData {
+	script := []byte{0x51, byte(userID), 0xae}
+	addr, err := hcutil.NewAddressScriptHash(script, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rpcserver.UserData{
+		VotingConfig: userdata.UserVotingConfig{
+			Userid:          userID,
+			MultiSigAddress: addr.EncodeAddress(),
+		},
+		RedeemScript: script,
+	}
+}
+
+func TestValidateUserData(t *testing.T) {
+	params := &chaincfg.TestNet2Params
+	user1 := testUserData(t, 1, params)
+	user2 := testUserData(t, 2, params)
+	msa1 := user1.VotingConfig.MultiSigAddress
+
+	sameMSA := user2
+	sameMSA.VotingConfig.MultiSigAddress = msa1
+	sameID := user2
+	sameID.VotingConfig.Userid = 1
+	noScript := user2
+	noScript.RedeemScript = nil
+	wrongScript := user2
+	wrongScript.RedeemScript = user1.RedeemScript
+	mainNet := testUserData(t, 2, &chaincfg.MainNetParams)
+
+	tests := []struct {
+		name     string
+		users    []rpcserver.UserData
+		added    map[chainhash.Hash]string
+		existing map[string]userdata.UserVotingConfig
+		errs     []string
+	}{
+		{
+			name:  "valid",
+			users: []rpcserver.UserData{user1, user2},
+			added: map[chainhash.Hash]string{{1}: msa1},
+		},
+		{
+			name:  "existing user reimported",
+			users: []rpcserver.UserData{user1},
+			existing: map[string]userdata.UserVotingConfig{
+				msa1: user1.VotingConfig,
+			},
+		},
+		{
+			name:  "added ticket of existing user",
+			added: map[chainhash.Hash]string{{1}: msa1},
+			existing: map[string]userdata.UserVotingConfig{
+				msa1: user1.VotingConfig,
+			},
+		},
+		{
+			name:  "duplicate multisig address",
+			users: []rpcserver.UserData{user1, sameMSA},
+			errs:  []string{"is listed more than once"},
+		},
+		{
+			name:  "duplicate user id",
+			users: []rpcserver.UserData{user1, sameID},
+			errs:  []string{"listed with multisig addresses"},
+		},
+		{
+			name:  "multisig address of another existing user",
+			users: []rpcserver.UserData{user1},
+			existing: map[string]userdata.UserVotingConfig{
+				msa1: {Userid: 7, MultiSigAddress: msa1},
+			},
+			errs: []string{"belongs to existing user 7"},
+		},
+		{
+			name:  "missing redeem script",
+			users: []rpcserver.UserData{noScript},
+			errs:  []string{"missing redeem script"},
+		},
+		{
+			name:  "redeem script of another address",
+			users: []rpcserver.UserData{wrongScript},
+			errs:  []string{"not multisig address"},
+		},
+		{
+			name:  "other network",
+			users: []rpcserver.UserData{mainNet},
+			errs:  []string{"not multisig address"},
+		},
+		{
+			name:  "added ticket of unknown user",
+			users: []rpcserver.UserData{user1},
+			added: map[chainhash.Hash]string{{1}: "unknown"},
+			errs:  []string{"unknown multisig address unknown"},
+		},
+	}
+
+	for _, test := range tests {
+		errs := validateUserData(test.users, test.added, test.existing,
+			params)
+		if len(errs) != len(test.errs) {
+			t.Errorf("%s: expected %d errors, got %v", test.name,
+				len(test.errs), errs)
+			continue
+		}
+		for i, want := range test.errs {
+			if !strings.Contains(errs[i], want) {
+				t.Errorf("%s: expected error containing %q, got %q",
+					test.name, want, errs[i])
+			}
+		}
+	}
+}
+
+func TestMergeImportedUserData(t *testing.T) {
+	params := &chaincfg.TestNet2Params
+	imported := testUserData(t, 1, params)
+	current := testUserData(t, 2, params)
+	ignored := chainhash.Hash{1}
+
+	// The frontend replaced the users while the scripts were imported, so
+	// the users must be merged with its update instead of the users seen
+	// when the import started.
+	ctx := &appContext{
+		addedLowFeeTicketsMSA:   make(map[chainhash.Hash]string),
+		ignoredLowFeeTicketsMSA: map[chainhash.Hash]string{ignored: "msa"},
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+		params:                  params,
+		userVotingConfig:        make(map[string]userdata.UserVotingConfig),
+	}
+	ctx.updateUserData(map[string]userdata.UserVotingConfig{
+		current.VotingConfig.MultiSigAddress: current.VotingConfig,
+	})
+
+	errs := ctx.mergeImportedUserData([]rpcserver.UserData{imported},
+		map[chainhash.Hash]string{ignored: imported.VotingConfig.MultiSigAddress})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(ctx.userVotingConfig) != 2 {
+		t.Errorf("expected 2 users, got %v", ctx.userVotingConfig)
+	}
+	for _, u := range []rpcserver.UserData{imported, current} {
+		if _, ok := ctx.userVotingConfig[u.VotingConfig.MultiSigAddress]; !ok {
+			t.Errorf("user %d missing after the import",
+				u.VotingConfig.Userid)
+		}
+	}
+	if _, ok := ctx.ignoredLowFeeTicketsMSA[ignored]; ok {
+		t.Errorf("added low fee ticket still ignored")
+	}
+	if _, ok := ctx.liveTicketsMSA[ignored]; !ok {
+		t.Errorf("added low fee ticket not live")
+	}
+	if _, ok := ctx.addedLowFeeTicketsMSA[ignored]; !ok {
+		t.Errorf("added low fee ticket not recorded")
+	}
+
+	// A user with the same multisig address but another id added in the
+	// meantime rejects the import without changing anything.
+	clash := imported
+	clash.VotingConfig.Userid = 3
+	errs = ctx.mergeImportedUserData([]rpcserver.UserData{clash}, nil)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	if got := ctx.userVotingConfig[imported.VotingConfig.MultiSigAddress]; got.Userid != 1 {
+		t.Errorf("clashing import replaced user 1 with %d", got.Userid)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+)
+
+var _ rpcserver.MissingTicketAdder = (*appContext)(nil)
+
+// missingTicket is a ticket found to be missing from the live or ignored low
+// fee tickets.
+type missingTicket struct {
+	msa       string
+	height    int64
+	feesValid bool
+}
+
+// AddMissingTickets implements rpcserver.MissingTicketAdder.  Tickets are
+// checked like those loaded from the wallet at startup: hcd must consider
+// them live, the wallet must have paid them to a pool user, and those that
+// don't pay the pool fee are ignored unless they were added as low fee
+// tickets.
+func (ctx *appContext) AddMissingTickets(reqCtx context.Context, tickets []chainhash.Hash) []rpcserver.MissingTicketResult {
+	outcomes := make(map[chainhash.Hash]*rpcserver.MissingTicketResult,
+		len(tickets))
+	unknown := make(map[chainhash.Hash]string)
+	ctx.RLock()
+	for _, ticket := range tickets {
+		if _, ok := outcomes[ticket]; ok {
+			continue
+		}
+		outcomes[ticket] = &rpcserver.MissingTicketResult{Ticket: ticket}
+		if ctx.ticketTracked(ticket) {
+			outcomes[ticket].Status = rpcserver.MissingTicketAlreadyTracked
+			continue
+		}
+		unknown[ticket] = ""
+	}
+	ctx.RUnlock()
+
+	live := make(map[chainhash.Hash]string, len(unknown))
+	for ticket := range unknown {
+		live[ticket] = ""
+	}
+	err := ctx.filterLiveTickets(live)
+	if err != nil {
+		log.Warnf("AddMissingTickets: ExistsLiveTickets failed: %v", err)
+	}
+
+	type promise struct {
+		ticket chainhash.Hash
+		future rpcclient.TransactionFuture
+	}
+	var promises []promise
+	wallet := traceWallet(reqCtx, ctx.wallet())
+	for ticket := range unknown {
+		switch _, ok := live[ticket]; {
+		case err != nil:
+			outcomes[ticket].Err = err
+		case !ok:
+			outcomes[ticket].Status = rpcserver.MissingTicketNotLive
+		default:
+			ticket := ticket
+			promises = append(promises, promise{ticket,
+				wallet.GetTransactionAsync(&ticket)})
+		}
+	}
+
+	found := make(map[chainhash.Hash]*missingTicket, len(promises))
+	for _, p := range promises {
+		outcome := outcomes[p.ticket]
+		if err := reqCtx.Err(); err != nil {
+			outcome.Err = err
+			continue
+		}
+		res, err := p.future.Receive()
+		if err != nil {
+			if strings.HasPrefix(err.Error(), errNoTxInfo) {
+				outcome.Status = rpcserver.MissingTicketNotInWallet
+			} else {
+				outcome.Err = err
+			}
+			continue
+		}
+		t, err := ctx.checkMissingTicket(&p.ticket, res)
+		switch {
+		case err != nil:
+			outcome.Err = err
+		case t == nil:
+			outcome.Status = rpcserver.MissingTicketNotPoolTicket
+		default:
+			found[p.ticket] = t
+		}
+	}
+
+	ctx.Lock()
+	for ticket, t := range found {
+		outcome := outcomes[ticket]
+		switch {
+		case ctx.ticketTracked(ticket):
+			// Added by a notification in the meantime.
+			outcome.Status = rpcserver.MissingTicketAlreadyTracked
+		case t.feesValid:
+			ctx.liveTicketsMSA[ticket] = t.msa
+			outcome.Status = rpcserver.MissingTicketAdded
+		default:
+			ctx.ignoredLowFeeTicketsMSA[ticket] = t.msa
+			outcome.Status = rpcserver.MissingTicketIgnoredLowFee
+		}
+		if outcome.Status != rpcserver.MissingTicketAlreadyTracked {
+			ctx.ticketHeights[ticket] = t.height
+			log.Infof("AddMissingTickets: added ticket %v (msa %v, height "+
+				"%v, valid fees %v)", ticket, t.msa, t.height,
+				t.feesValid)
+		}
+	}
+	ctx.Unlock()
+
+	results := make([]rpcserver.MissingTicketResult, 0, len(tickets))
+	for _, ticket := range tickets {
+		results = append(results, *outcomes[ticket])
+	}
+	return results
+}
+
+// ticketTracked reports whether the ticket is a live or ignored low fee
+// ticket.  It must be called with the lock held.
+func (ctx *appContext) ticketTracked(ticket chainhash.Hash) bool {
+	_, live := ctx.liveTicketsMSA[ticket]
+	_, ignored := ctx.ignoredLowFeeTicketsMSA[ticket]
+	return live || ignored
+}
+
+// checkMissingTicket returns the user, purchase height and fee validity of
+// the ticket with the wallet transaction res, or nil when it isn't a ticket
+// of a pool user.
+func (ctx *appContext) checkMissingTicket(ticket *chainhash.Hash, res *dcrjson.GetTransactionResult) (*missingTicket, error) {
+	t := &missingTicket{}
+	ctx.RLock()
+	for i := range res.Details {
+		if _, ok := ctx.userVotingConfig[res.Details[i].Address]; ok {
+			t.msa = res.Details[i].Address
+			break
+		}
+	}
+	_, added := ctx.addedLowFeeTicketsMSA[*ticket]
+	ctx.RUnlock()
+	if t.msa == "" {
+		return nil, nil
+	}
+
+	blockHash, err := chainhash.NewHashFromStr(res.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	header, err := ctx.node().GetBlockHeader(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	t.height = int64(header.Height)
+
+	if added {
+		t.feesValid = true
+		return t, nil
+	}
+	msgTx := voting.MsgTxFromHex(res.Hex)
+	if msgTx == nil {
+		return nil, errors.New("undecodable ticket transaction")
+	}
+	t.feesValid, err = voting.EvaluateStakePoolTicket(msgTx,
+		int32(t.height), ctx.feeAddrs, ctx.poolFees, ctx.params)
+	if err != nil {
+		log.Warnf("AddMissingTickets: ticket %v for msa %v pays invalid "+
+			"fees: %v", ticket, t.msa, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+)
+
+func TestAddMissingTickets(t *testing.T) {
+	tracked := chainhash.Hash{1}
+	spent := chainhash.Hash{2}
+	foreign := chainhash.Hash{3}
+	notPool := chainhash.Hash{4}
+	added := chainhash.Hash{5}
+
+	header := &wire.BlockHeader{Height: 90}
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	node.AddBlock(header)
+	node.SetLiveTickets(tracked, foreign, notPool, added)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	wallet.AddTransaction(&notPool, &dcrjson.GetTransactionResult{
+		BlockHash: header.BlockHash().String(),
+		Details: []dcrjson.GetTransactionDetailsResult{
+			{Address: "someone"},
+		},
+	})
+	wallet.AddTransaction(&added, &dcrjson.GetTransactionResult{
+		BlockHash: header.BlockHash().String(),
+		Details: []dcrjson.GetTransactionDetailsResult{
+			{Address: "msa"},
+		},
+	})
+
+	ctx := &appContext{
+		addedLowFeeTicketsMSA:   map[chainhash.Hash]string{added: "msa"},
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          map[chainhash.Hash]string{tracked: "msa"},
+		nodeConnection:          node,
+		ticketHeights:           make(map[chainhash.Hash]int64),
+		userVotingConfig: map[string]userdata.UserVotingConfig{
+			"msa": {MultiSigAddress: "msa"},
+		},
+		walletConnection: wallet,
+	}
+
+	tickets := []chainhash.Hash{tracked, spent, foreign, notPool, added,
+		tracked}
+	want := []rpcserver.MissingTicketStatus{
+		rpcserver.MissingTicketAlreadyTracked,
+		rpcserver.MissingTicketNotLive,
+		rpcserver.MissingTicketNotInWallet,
+		rpcserver.MissingTicketNotPoolTicket,
+		rpcserver.MissingTicketAdded,
+		rpcserver.MissingTicketAlreadyTracked,
+	}
+	results := ctx.AddMissingTickets(context.Background(), tickets)
+	if len(results) != len(want) {
+		t.Fatalf("%d results, want %d", len(results), len(want))
+	}
+	for i, r := range results {
+		if r.Ticket != tickets[i] || r.Status != want[i] || r.Err != nil {
+			t.Errorf("result %d: ticket %v status %v err %v, want "+
+				"ticket %v status %v", i, r.Ticket, r.Status, r.Err,
+				tickets[i], want[i])
+		}
+	}
+	if ctx.liveTicketsMSA[added] != "msa" || ctx.ticketHeights[added] != 90 {
+		t.Errorf("added ticket not live at height 90: live %v heights %v",
+			ctx.liveTicketsMSA, ctx.ticketHeights)
+	}
+}
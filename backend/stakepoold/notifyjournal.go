@@ -0,0 +1,178 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/journal"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+)
+
+// Kinds of the notifications recorded in the journal.
+const (
+	journalNewTickets         = "newtickets"
+	journalSpentMissedTickets = "spentmissedtickets"
+	journalWinningTickets     = "winningtickets"
+)
+
+// journalFilename is the name of the notification journal in the data
+// directory.
+const journalFilename = "notifications.journal"
+
+// journalTickets is the journal record of a ticket notification for a block.
+// Spent is only set for spent and missed tickets.
+type journalTickets struct {
+	BlockHash   string          `json:"blockhash"`
+	BlockHeight int64           `json:"blockheight"`
+	Tickets     []string        `json:"tickets"`
+	Spent       map[string]bool `json:"spent,omitempty"`
+}
+
+// journalAppend records a ticket notification in the journal before it is
+// queued and returns its sequence number, or 0 when it wasn't recorded.  A
+// notification that can't be recorded is still processed.
+func (ctx *appContext) journalAppend(kind string, blockHash *chainhash.Hash,
+	blockHeight int64, tickets []*chainhash.Hash,
+	spent map[chainhash.Hash]bool) uint64 {
+	if ctx.journal == nil {
+		return 0
+	}
+
+	r := journalTickets{
+		BlockHash:   blockHash.String(),
+		BlockHeight: blockHeight,
+		Tickets:     make([]string, 0, len(tickets)),
+	}
+	for _, ticket := range tickets {
+		r.Tickets = append(r.Tickets, ticket.String())
+	}
+	if spent != nil {
+		r.Spent = make(map[string]bool, len(spent))
+		for ticket, s := range spent {
+			r.Spent[ticket.String()] = s
+		}
+	}
+	seq, err := ctx.journal.Append(kind, &r)
+	if err != nil {
+		log.Errorf("unable to journal %s notification for block %v "+
+			"(height %v): %v", kind, blockHash, blockHeight, err)
+		return 0
+	}
+	return seq
+}
+
+// journalDone records that the notification with sequence number seq was
+// processed.
+func (ctx *appContext) journalDone(seq uint64) {
+	if ctx.journal == nil || seq == 0 {
+		return
+	}
+	if err := ctx.journal.Done(seq); err != nil {
+		log.Errorf("unable to mark journaled notification %v as "+
+			"processed: %v", seq, err)
+	}
+}
+
+// decodeJournalTickets decodes the record of a ticket notification.
+func decodeJournalTickets(e *journal.Entry) (*chainhash.Hash, int64,
+	[]*chainhash.Hash, map[chainhash.Hash]bool, error) {
+	var r journalTickets
+	if err := json.Unmarshal(e.Data, &r); err != nil {
+		return nil, 0, nil, nil, err
+	}
+	blockHash, err := chainhash.NewHashFromStr(r.BlockHash)
+	if err != nil {
+		return nil, 0, nil, nil, err
+	}
+	tickets := make([]*chainhash.Hash, 0, len(r.Tickets))
+	for _, s := range r.Tickets {
+		ticket, err := chainhash.NewHashFromStr(s)
+		if err != nil {
+			return nil, 0, nil, nil, err
+		}
+		tickets = append(tickets, ticket)
+	}
+	var spent map[chainhash.Hash]bool
+	if r.Spent != nil {
+		spent = make(map[chainhash.Hash]bool, len(r.Spent))
+		for s, isSpent := range r.Spent {
+			ticket, err := chainhash.NewHashFromStr(s)
+			if err != nil {
+				return nil, 0, nil, nil, err
+			}
+			spent[*ticket] = isSpent
+		}
+	}
+	return blockHash, r.BlockHeight, tickets, spent, nil
+}
+
+// replayJournal processes the notifications that were journaled but not
+// processed completely before the last shutdown, in the order they arrived.
+// Winning tickets whose block is too old or was reorganized out since aren't
+// voted.
+func (ctx *appContext) replayJournal(entries []journal.Entry, tipHeight int64) {
+	if len(entries) == 0 {
+		return
+	}
+	log.Infof("replayJournal: replaying %d notifications that were not "+
+		"processed before the last shutdown", len(entries))
+
+	for i := range entries {
+		e := &entries[i]
+		blockHash, blockHeight, tickets, spent, err :=
+			decodeJournalTickets(e)
+		if err != nil {
+			log.Warnf("replayJournal: dropping invalid %s notification "+
+				"%v: %v", e.Kind, e.Seq, err)
+			ctx.journalDone(e.Seq)
+			continue
+		}
+
+		switch e.Kind {
+		case journalNewTickets:
+			ctx.processNewTickets(NewTicketsForBlock{
+				blockHash:   blockHash,
+				blockHeight: blockHeight,
+				newTickets:  tickets,
+			})
+		case journalSpentMissedTickets:
+			smTickets := make(map[*chainhash.Hash]bool, len(spent))
+			for ticket, isSpent := range spent {
+				ticket := ticket
+				smTickets[&ticket] = isSpent
+			}
+			ctx.processSpentMissedTickets(SpentMissedTicketsForBlock{
+				blockHash:   blockHash,
+				blockHeight: blockHeight,
+				smTickets:   smTickets,
+			})
+		case journalWinningTickets:
+			if !voting.VoteViable(blockHeight, tipHeight, ctx.maxVoteAge) {
+				log.Infof("replayJournal: not voting on block %v "+
+					"(height %v), it is too old", blockHash,
+					blockHeight)
+				break
+			}
+			mainHash, err := ctx.node().GetBlockHash(blockHeight)
+			if err != nil || *mainHash != *blockHash {
+				log.Infof("replayJournal: not voting on block %v "+
+					"(height %v), it is no longer in the main "+
+					"chain", blockHash, blockHeight)
+				break
+			}
+			ctx.processWinningTickets(WinningTicketsForBlock{
+				blockHash:      blockHash,
+				blockHeight:    blockHeight,
+				winningTickets: tickets,
+			})
+		default:
+			log.Warnf("replayJournal: dropping unknown %s notification "+
+				"%v", e.Kind, e.Seq)
+		}
+		ctx.journalDone(e.Seq)
+	}
+}
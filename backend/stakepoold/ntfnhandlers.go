@@ -1,51 +1,150 @@
 package main
 
 import (
-	"encoding/json"
+	"expvar"
+	"time"
 
 	"github.com/coolsnady/hcd/chaincfg/chainhash"
-	"github.com/coolsnady/hcrpcclient"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
 )
 
-// Define notification handlers
-func getNodeNtfnHandlers(ctx *appContext, connCfg *hcrpcclient.ConnConfig) *hcrpcclient.NotificationHandlers {
-	return &hcrpcclient.NotificationHandlers{
-		OnNewTickets: func(blockHash *chainhash.Hash, blockHeight int64, stakeDifficulty int64, tickets []*chainhash.Hash) {
-			nt := NewTicketsForBlock{
-				blockHash:   blockHash,
-				blockHeight: blockHeight,
-				newTickets:  tickets,
-			}
-			ctx.newTicketsChan <- nt
-		},
-		OnSpentAndMissedTickets: func(blockHash *chainhash.Hash, blockHeight int64, stakeDifficulty int64, tickets map[chainhash.Hash]bool) {
-			ticketsFixed := make(map[*chainhash.Hash]bool)
-			for ticketHash, spent := range tickets {
-				ticketHash := ticketHash
-				ticketsFixed[&ticketHash] = spent
-			}
-			smt := SpentMissedTicketsForBlock{
-				blockHash:   blockHash,
-				blockHeight: blockHeight,
-				smTickets:   ticketsFixed,
-			}
-			ctx.spentmissedTicketsChan <- smt
-		},
-		OnWinningTickets: func(blockHash *chainhash.Hash, blockHeight int64, winningTickets []*chainhash.Hash) {
-			wt := WinningTicketsForBlock{
-				blockHash:      blockHash,
-				blockHeight:    blockHeight,
-				winningTickets: winningTickets,
-			}
-			ctx.winningTicketsChan <- wt
-		},
+// appContext implements rpcclient.ChainNotifications by queueing the
+// notifications for its handlers.
+var _ rpcclient.ChainNotifications = (*appContext)(nil)
+
+// Policies for notifications that arrive while the queue of their handler is
+// full.
+const (
+	// ntfnPolicyQueue waits for room in the queue, which holds up the
+	// notifications that follow.
+	ntfnPolicyQueue = "queue"
+
+	// ntfnPolicyDrop drops new tickets and spent and missed tickets
+	// notifications.  Their journal entries are replayed at the next start
+	// and the ticket reconciliation catches up on them.  The other
+	// notifications are queued.
+	ntfnPolicyDrop = "drop"
+)
+
+var (
+	// ntfnBacklogged counts by kind the notifications that found the
+	// queue of their handler full.
+	ntfnBacklogged = expvar.NewMap("ntfnBacklogged")
+
+	// ntfnDropped counts by kind the notifications dropped by
+	// ntfnPolicyDrop.
+	ntfnDropped = expvar.NewMap("ntfnDropped")
+)
+
+// ntfnQueueFull is called when a notification of kind finds the queue of its
+// handler full and returns whether to drop it rather than wait.
+func (ctx *appContext) ntfnQueueFull(kind string, droppable bool) bool {
+	ntfnBacklogged.Add(kind, 1)
+	if droppable && ctx.ntfnPolicy == ntfnPolicyDrop {
+		ntfnDropped.Add(kind, 1)
+		log.Warnf("%s handler is falling behind, dropping the "+
+			"notification", kind)
+		return true
+	}
+	log.Warnf("%s handler is falling behind, waiting to queue the "+
+		"notification", kind)
+	return false
+}
+
+// BlockConnected queues a connected block for blockConnectedHandler.
+func (ctx *appContext) BlockConnected(blockHeader []byte) {
+	select {
+	case ctx.blockConnectedChan <- blockHeader:
+	default:
+		ctx.ntfnQueueFull("blockConnected", false)
+		ctx.blockConnectedChan <- blockHeader
+	}
+}
+
+// NewTickets journals and queues the tickets purchased in a block for
+// newTicketHandler.
+func (ctx *appContext) NewTickets(blockHash *chainhash.Hash,
+	blockHeight int64, tickets []*chainhash.Hash) {
+	seq := ctx.journalAppend(journalNewTickets, blockHash, blockHeight,
+		tickets, nil)
+	nt := NewTicketsForBlock{
+		blockHash:   blockHash,
+		blockHeight: blockHeight,
+		newTickets:  tickets,
+		seq:         seq,
+	}
+	select {
+	case ctx.newTicketsChan <- nt:
+	default:
+		if ctx.ntfnQueueFull("newTickets", true) {
+			return
+		}
+		ctx.newTicketsChan <- nt
+	}
+}
+
+// Reorganization queues a chain reorganization for reorganizationHandler.
+func (ctx *appContext) Reorganization(oldHash *chainhash.Hash, oldHeight int64,
+	newHash *chainhash.Hash, newHeight int64) {
+	reorg := Reorganization{
+		oldHash:   oldHash,
+		oldHeight: oldHeight,
+		newHash:   newHash,
+		newHeight: newHeight,
+	}
+	select {
+	case ctx.reorganizationChan <- reorg:
+	default:
+		ctx.ntfnQueueFull("reorganization", false)
+		ctx.reorganizationChan <- reorg
 	}
 }
 
-func getWalletNtfnHandlers(cfg *config) *hcrpcclient.NotificationHandlers {
-	return &hcrpcclient.NotificationHandlers{
-		OnUnknownNotification: func(method string, params []json.RawMessage) {
-			log.Infof("ignoring notification %v", method)
-		},
+// SpentAndMissedTickets journals and queues the tickets spent or missed in a
+// block for spentmissedTicketHandler.
+func (ctx *appContext) SpentAndMissedTickets(blockHash *chainhash.Hash,
+	blockHeight int64, tickets map[chainhash.Hash]bool) {
+	seq := ctx.journalAppend(journalSpentMissedTickets, blockHash,
+		blockHeight, nil, tickets)
+	ticketsFixed := make(map[*chainhash.Hash]bool)
+	for ticketHash, spent := range tickets {
+		ticketHash := ticketHash
+		ticketsFixed[&ticketHash] = spent
+	}
+	smt := SpentMissedTicketsForBlock{
+		blockHash:   blockHash,
+		blockHeight: blockHeight,
+		smTickets:   ticketsFixed,
+		seq:         seq,
+	}
+	select {
+	case ctx.spentmissedTicketsChan <- smt:
+	default:
+		if ctx.ntfnQueueFull("spentmissedTickets", true) {
+			return
+		}
+		ctx.spentmissedTicketsChan <- smt
+	}
+}
+
+// WinningTickets journals and queues the tickets selected to vote on a block
+// for winningTicketHandler.
+func (ctx *appContext) WinningTickets(blockHash *chainhash.Hash,
+	blockHeight int64, winningTickets []*chainhash.Hash) {
+	received := time.Now()
+	seq := ctx.journalAppend(journalWinningTickets, blockHash, blockHeight,
+		winningTickets, nil)
+	wt := WinningTicketsForBlock{
+		blockHash:      blockHash,
+		blockHeight:    blockHeight,
+		winningTickets: winningTickets,
+		received:       received,
+		seq:            seq,
+	}
+	select {
+	case ctx.winningTicketsChan <- wt:
+	default:
+		ctx.ntfnQueueFull("winningTickets", false)
+		ctx.winningTicketsChan <- wt
 	}
 }
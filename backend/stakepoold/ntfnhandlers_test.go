@@ -0,0 +1,56 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"expvar"
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+)
+
+func TestNtfnPolicyDrop(t *testing.T) {
+	ctx := &appContext{
+		newTicketsChan:     make(chan NewTicketsForBlock, 1),
+		ntfnPolicy:         ntfnPolicyDrop,
+		winningTicketsChan: make(chan WinningTicketsForBlock, 1),
+	}
+	dropped := func(kind string) int64 {
+		if v, ok := ntfnDropped.Get(kind).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	before := dropped("newTickets")
+
+	// The second notification finds the queue full and is dropped instead
+	// of blocking.
+	for height := int64(1); height <= 2; height++ {
+		ctx.NewTickets(&chainhash.Hash{byte(height)}, height, nil)
+	}
+	if n := dropped("newTickets") - before; n != 1 {
+		t.Errorf("dropped %d new tickets notifications, want 1", n)
+	}
+	if nt := <-ctx.newTicketsChan; nt.blockHeight != 1 {
+		t.Errorf("queued notification for height %d, want 1",
+			nt.blockHeight)
+	}
+
+	// Winning tickets wait for room.
+	ctx.WinningTickets(&chainhash.Hash{1}, 1, nil)
+	done := make(chan struct{})
+	go func() {
+		ctx.WinningTickets(&chainhash.Hash{2}, 2, nil)
+		close(done)
+	}()
+	<-ctx.winningTicketsChan
+	<-done
+	if wt := <-ctx.winningTicketsChan; wt.blockHeight != 2 {
+		t.Errorf("queued winning tickets for height %d, want 2",
+			wt.blockHeight)
+	}
+	if n := dropped("winningTickets"); n != 0 {
+		t.Errorf("dropped %d winning tickets notifications", n)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// readWalletPassphrase returns the passphrase of the voting wallet, read from
+// file or, with prompt, from the terminal.  It returns an empty passphrase
+// when neither is set.
+func readWalletPassphrase(file string, prompt bool) (string, error) {
+	switch {
+	case file != "":
+		return readPassphraseFile(file)
+	case prompt:
+		return promptPassphrase()
+	}
+	return "", nil
+}
+
+// readPassphraseFile returns the first line of the file at path.  Except on
+// Windows, which has no such permissions, the file may only be accessible to
+// its owner.  The permissions are those of the file opened, so it can't be
+// swapped for another between the check and the read.
+func readPassphraseFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("%s is accessible to other users than its "+
+			"owner (mode %v), restrict it with chmod 600", path,
+			fi.Mode().Perm())
+	}
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	passphrase := strings.SplitN(string(b), "\n", 2)[0]
+	passphrase = strings.TrimSuffix(passphrase, "\r")
+	if passphrase == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return passphrase, nil
+}
+
+// promptPassphrase reads the passphrase from the terminal without echoing it.
+func promptPassphrase() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return "", errors.New("stdin is not a terminal to prompt for the " +
+			"voting wallet passphrase")
+	}
+	fmt.Print("Voting wallet passphrase: ")
+	b, err := terminal.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	if len(b) == 0 {
+		return "", errors.New("no voting wallet passphrase entered")
+	}
+	return string(b), nil
+}
+
+// walletUnlockMargin is how much longer than the relock period hcwallet keeps
+// the wallet unlocked by itself, so it is locked even if stakepoold is killed.
+// hcwallet can only extend the timeout by unlocking the wallet again, which
+// takes as long as unlocking it, so that is only done once less than the
+// relock period and half the margin is left.
+const walletUnlockMargin = 10 * time.Minute
+
+// walletUnlocker unlocks the wallet with its passphrase only while votes are
+// generated, and locks it again once no votes were generated for the relock
+// period.  It only locks the wallet it unlocked, so standbys sharing the
+// wallet of the leader, which don't vote, never lock it.
+type walletUnlocker struct {
+	wallet     func() rpcclient.WalletSource
+	passphrase string
+	relock     time.Duration
+
+	mtx      sync.Mutex
+	voting   int  // unlock calls not released yet
+	relocks  int  // bumped to cancel a pending relock
+	unlocked bool // the wallet was unlocked by this instance
+	expires  time.Time
+	timer    *time.Timer
+}
+
+// newWalletUnlocker returns a walletUnlocker of the wallet returned by wallet,
+// which may change when the connection is replaced.
+func newWalletUnlocker(wallet func() rpcclient.WalletSource, passphrase string,
+	relock time.Duration) *walletUnlocker {
+	return &walletUnlocker{
+		wallet:     wallet,
+		passphrase: passphrase,
+		relock:     relock,
+	}
+}
+
+// timeoutSecs returns the timeout hcwallet unlocks the wallet for.
+func (u *walletUnlocker) timeoutSecs() int64 {
+	return int64((u.relock + walletUnlockMargin) / time.Second)
+}
+
+// check unlocks and locks the wallet, so a wrong passphrase is noticed at
+// startup rather than when the first vote is due.  A wallet unlocked already,
+// as by another stakepoold sharing it, is left alone.
+func (u *walletUnlocker) check() error {
+	w := u.wallet()
+	info, err := w.WalletInfo()
+	if err != nil {
+		return err
+	}
+	if info.Unlocked {
+		log.Infof("The wallet is unlocked already, the voting wallet " +
+			"passphrase is checked when the first vote is due")
+		return nil
+	}
+	if err := w.WalletPassphrase(u.passphrase, u.timeoutSecs()); err != nil {
+		return err
+	}
+	return w.WalletLock()
+}
+
+// unlock unlocks the wallet, unless it is unlocked already, and keeps it
+// unlocked until every unlock is matched by a release.
+func (u *walletUnlocker) unlock() error {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	if u.timer != nil {
+		u.timer.Stop()
+		u.timer = nil
+	}
+	u.relocks++
+	if u.voting == 0 {
+		w := u.wallet()
+		if w == nil || w.Disconnected() {
+			return errors.New("hcwallet is disconnected")
+		}
+		// hcwallet locks itself when restarted.
+		info, err := w.WalletInfo()
+		if err != nil {
+			return err
+		}
+		// It is also unlocked again when another instance unlocked it,
+		// or hcwallet would lock it soon.
+		start := time.Now()
+		if !info.Unlocked || !u.unlocked ||
+			u.expires.Sub(start) < u.relock+walletUnlockMargin/2 {
+			err := w.WalletPassphrase(u.passphrase, u.timeoutSecs())
+			if err != nil {
+				return err
+			}
+			u.unlocked = true
+			u.expires = start.Add(u.relock + walletUnlockMargin)
+			log.Debugf("Unlocked the wallet to vote (took %v)",
+				time.Since(start))
+		}
+	}
+	u.voting++
+	return nil
+}
+
+// release ends a successful unlock.  The wallet is locked after the relock
+// period unless it is unlocked again before.
+func (u *walletUnlocker) release() {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	u.voting--
+	if u.voting > 0 {
+		return
+	}
+	relocks := u.relocks
+	u.timer = time.AfterFunc(u.relock, func() {
+		u.mtx.Lock()
+		defer u.mtx.Unlock()
+		if u.relocks != relocks {
+			return
+		}
+		u.timer = nil
+		u.lockWallet()
+	})
+}
+
+// lock locks the wallet right away unless votes are being generated.
+func (u *walletUnlocker) lock() {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	if u.voting > 0 {
+		return
+	}
+	if u.timer != nil {
+		u.timer.Stop()
+		u.timer = nil
+	}
+	u.relocks++
+	u.lockWallet()
+}
+
+// lockWallet locks the wallet if this instance unlocked it.  The mutex must be
+// held.
+func (u *walletUnlocker) lockWallet() {
+	if !u.unlocked {
+		return
+	}
+	w := u.wallet()
+	if w == nil || w.Disconnected() {
+		return
+	}
+	if err := w.WalletLock(); err != nil {
+		log.Warnf("Unable to lock the wallet: %v", err)
+		return
+	}
+	u.unlocked = false
+	log.Debugf("Locked the wallet")
+}
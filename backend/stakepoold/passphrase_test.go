@@ -0,0 +1,164 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+)
+
+func TestReadPassphraseFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stakepoold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "walletpass")
+
+	if err := ioutil.WriteFile(path, []byte("secret\r\nignored\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	passphrase, err := readPassphraseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passphrase != "secret" {
+		t.Errorf("passphrase %q, want %q", passphrase, "secret")
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPassphraseFile(path); err == nil {
+		t.Error("read a passphrase file other users may access")
+	}
+}
+
+func TestWalletUnlocker(t *testing.T) {
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	wallet.SetPassphrase("secret")
+	walletFn := func() rpcclient.WalletSource { return wallet }
+
+	if err := newWalletUnlocker(walletFn, "wrong", 0).check(); err == nil {
+		t.Error("checked a wrong passphrase")
+	}
+
+	const relock = time.Millisecond * 50
+	u := newWalletUnlocker(walletFn, "secret", relock)
+	if err := u.check(); err != nil {
+		t.Fatal(err)
+	}
+	unlocked := func() bool {
+		info, _ := wallet.WalletInfo()
+		return info.Unlocked
+	}
+	if unlocked() {
+		t.Fatal("wallet unlocked after the check")
+	}
+
+	// Overlapping votes unlock the wallet once and keep it unlocked.
+	for i := 0; i < 2; i++ {
+		if err := u.unlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u.release()
+	time.Sleep(relock * 2)
+	if !unlocked() {
+		t.Fatal("wallet locked while voting")
+	}
+	u.release()
+	if !unlocked() {
+		t.Fatal("wallet locked before the relock period")
+	}
+	if n := wallet.Unlocks(); n != 2 {
+		t.Errorf("wallet unlocked %d times, want 2", n)
+	}
+
+	// Voting again within the relock period cancels the relock.
+	if err := u.unlock(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(relock * 2)
+	if !unlocked() {
+		t.Fatal("wallet locked while voting")
+	}
+	u.release()
+	time.Sleep(relock * 2)
+	if unlocked() {
+		t.Error("wallet still unlocked after the relock period")
+	}
+
+	// A wallet locked behind our back is unlocked again.
+	if err := u.unlock(); err != nil {
+		t.Fatal(err)
+	}
+	wallet.WalletLock()
+	u.release()
+	if err := u.unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if !unlocked() {
+		t.Error("wallet locked while voting")
+	}
+	u.release()
+	u.lock()
+	if unlocked() {
+		t.Error("wallet unlocked after lock")
+	}
+}
+
+func TestWalletUnlockerShared(t *testing.T) {
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	wallet.SetPassphrase("secret")
+	walletFn := func() rpcclient.WalletSource { return wallet }
+	unlocked := func() bool {
+		info, _ := wallet.WalletInfo()
+		return info.Unlocked
+	}
+
+	// The leader sharing the wallet unlocked it to vote.
+	if err := wallet.WalletPassphrase("secret", 60); err != nil {
+		t.Fatal(err)
+	}
+
+	// A standby doesn't lock it, neither when checking the passphrase
+	// nor when shutting down.
+	u := newWalletUnlocker(walletFn, "secret", time.Minute)
+	if err := u.check(); err != nil {
+		t.Fatal(err)
+	}
+	if !unlocked() {
+		t.Fatal("standby locked the wallet when checking the passphrase")
+	}
+	u.lock()
+	if !unlocked() {
+		t.Fatal("standby locked the wallet on shutdown")
+	}
+
+	// Once it votes, it unlocks the wallet itself and locks it again.
+	if err := u.unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if n := wallet.Unlocks(); n != 2 {
+		t.Errorf("wallet unlocked %d times, want 2", n)
+	}
+	u.release()
+	u.lock()
+	if unlocked() {
+		t.Error("wallet unlocked after lock")
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+)
+
+// flagMissed logs the winning tickets whose votes can no longer be mined.
+// They are left in the live tickets, as their votes may still have been
+// mined, sent before a crash or by a redundant instance.  hcd reports the
+// tickets that really missed their votes in the spent and missed
+// notifications, which remove them.
+func (ctx *appContext) flagMissed(missed map[chainhash.Hash]voting.PendingVote,
+	reason string) {
+	for ticket, v := range missed {
+		log.Warnf("flagging ticket %v msa %v as missed: won block %v "+
+			"(height %v) but %s", ticket, v.MultiSigAddress, v.BlockHash,
+			v.BlockHeight, reason)
+	}
+}
+
+// processPendingVotes decides what to do with the winning tickets that had
+// not been voted when stakepoold last stopped.  Tickets whose block is still
+// in the main chain and at most maxVoteAge blocks behind the tip are voted.
+// The others can't be voted anymore and are flagged as missed instead of
+// sending stale votes.  Tickets whose block can't be looked up stay pending
+// until the next call.
+func (ctx *appContext) processPendingVotes(tipHeight int64) {
+	votes := ctx.pendingVotes.Take()
+	if len(votes) == 0 {
+		return
+	}
+	log.Infof("processPendingVotes: %d winning tickets from the last run "+
+		"were not voted", len(votes))
+
+	stale := make(map[chainhash.Hash]voting.PendingVote)
+	viable := make(map[chainhash.Hash]*WinningTicketsForBlock)
+	for ticket, v := range votes {
+		ticket, v := ticket, v
+		if !voting.VoteViable(v.BlockHeight, tipHeight, ctx.maxVoteAge) {
+			stale[ticket] = v
+			continue
+		}
+		// A ticket that won a block which has since been reorganized
+		// out is dealt with by the notifications for the new chain.
+		mainHash, err := ctx.node().GetBlockHash(v.BlockHeight)
+		if err != nil {
+			log.Warnf("keeping pending vote for ticket %v: unable to "+
+				"look up block %v (height %v): %v", ticket, v.BlockHash,
+				v.BlockHeight, err)
+			ctx.pendingVotes.Add(&ticket, v)
+			continue
+		}
+		if *mainHash != v.BlockHash {
+			log.Infof("dropping pending vote for ticket %v: block %v "+
+				"(height %v) is no longer in the main chain", ticket,
+				v.BlockHash, v.BlockHeight)
+			continue
+		}
+		wt, ok := viable[v.BlockHash]
+		if !ok {
+			wt = &WinningTicketsForBlock{
+				blockHash:   &v.BlockHash,
+				blockHeight: v.BlockHeight,
+			}
+			viable[v.BlockHash] = wt
+		}
+		wt.winningTickets = append(wt.winningTickets, &ticket)
+	}
+
+	ctx.flagMissed(stale, "the vote is too old to be mined")
+	for _, wt := range viable {
+		ctx.processWinningTickets(*wt)
+	}
+}
+
+// retryClaimedVotes votes the tickets another instance claimed first on a
+// block, once its claims expired, unless their votes were seen on the chain
+// or expired in the meantime.  A vote the other instance sent already is
+// rejected as a duplicate, which counts as voted.
+func (ctx *appContext) retryClaimedVotes(blockHash *chainhash.Hash,
+	blockHeight int64, tickets []*chainhash.Hash) {
+	select {
+	case <-ctx.quit:
+		return
+	default:
+	}
+
+	pending := ctx.pendingVotes.Snapshot()
+	retry := make([]*chainhash.Hash, 0, len(tickets))
+	for _, ticket := range tickets {
+		if v, ok := pending[*ticket]; ok && v.BlockHash == *blockHash {
+			retry = append(retry, ticket)
+		}
+	}
+	if len(retry) == 0 {
+		return
+	}
+
+	// Like the pending votes of the last run, votes on a block that was
+	// reorganized out are left to the notifications for the new chain.
+	mainHash, err := ctx.node().GetBlockHash(blockHeight)
+	if err != nil {
+		log.Warnf("retryClaimedVotes: unable to look up block %v "+
+			"(height %v): %v", blockHash, blockHeight, err)
+		return
+	}
+	if *mainHash != *blockHash {
+		log.Infof("retryClaimedVotes: block %v (height %v) is no longer "+
+			"in the main chain", blockHash, blockHeight)
+		return
+	}
+
+	log.Infof("retryClaimedVotes: retrying %d votes on block %v (height "+
+		"%v) claimed by other instances", len(retry), blockHash,
+		blockHeight)
+	ctx.processWinningTickets(WinningTicketsForBlock{
+		blockHash:      blockHash,
+		blockHeight:    blockHeight,
+		winningTickets: retry,
+	})
+}
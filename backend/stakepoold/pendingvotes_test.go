@@ -0,0 +1,92 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+)
+
+func TestProcessPendingVotes(t *testing.T) {
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	ctx := &appContext{
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+		maxVoteAge:              2,
+		nodeConnection:          node,
+		pendingVotes:            voting.NewPendingVotes(),
+		stats:                   voting.NewStats(chaincfg.TestNet2Params.StakeDiffWindowSize),
+		userStats:               voting.NewUserStats(),
+		userVotingConfig:        make(map[string]userdata.UserVotingConfig),
+		voteClaims:              voting.NewVoteClaims(),
+		voteHistory:             voting.NewVoteHistory(10),
+		voteHistoryFeed:         rpcserver.NewVoteHistoryFeed(),
+		voteLatency:             voting.NewVoteLatency(10),
+		votingConfig:            &VotingConfig{VoteBits: 1, VoteVersion: 5},
+		walletConnection:        wallet,
+	}
+	ctx.voter = voting.NewVoter(ctx.wallet, ctx.node, 0)
+
+	// The node has blocks 100 and 101 but can't look up block 99.
+	node.SetBlockHash(100, chainhash.Hash{100})
+	node.SetBlockHash(101, chainhash.Hash{101})
+
+	viable := chainhash.Hash{1}
+	reorged := chainhash.Hash{2}
+	unknown := chainhash.Hash{3}
+	stale := chainhash.Hash{4}
+	votes := map[chainhash.Hash]voting.PendingVote{
+		viable:  {BlockHash: chainhash.Hash{100}, BlockHeight: 100},
+		reorged: {BlockHash: chainhash.Hash{201}, BlockHeight: 101},
+		unknown: {BlockHash: chainhash.Hash{99}, BlockHeight: 99},
+		stale:   {BlockHash: chainhash.Hash{90}, BlockHeight: 90},
+	}
+	for ticket, v := range votes {
+		ticket := ticket
+		v.MultiSigAddress = "msa"
+		ctx.liveTicketsMSA[ticket] = v.MultiSigAddress
+		ctx.pendingVotes.Add(&ticket, v)
+	}
+
+	ctx.processPendingVotes(101)
+
+	// Only the ticket of the main chain block is voted.
+	if votes := wallet.Votes(); len(votes) != 1 || *votes[0] != viable {
+		t.Errorf("expected a vote to be generated for %v only, got %v",
+			viable, votes)
+	}
+
+	// The ticket whose block couldn't be looked up stays pending for the
+	// next try.
+	pending := ctx.pendingVotes.Snapshot()
+	if _, ok := pending[unknown]; !ok || len(pending) != 1 {
+		t.Errorf("expected only %v to be pending, got %v", unknown,
+			pending)
+	}
+
+	// The stale ticket is left live until hcd reports it missed.
+	if _, ok := ctx.liveTicketsMSA[stale]; !ok {
+		t.Errorf("stale ticket %v was removed from the live tickets", stale)
+	}
+
+	// Once its block can be looked up, it is voted.
+	node.SetBlockHash(99, chainhash.Hash{99})
+	ctx.processPendingVotes(101)
+	if votes := wallet.Votes(); len(votes) != 2 || *votes[1] != unknown {
+		t.Errorf("expected a vote to be generated for %v, got %v",
+			unknown, votes)
+	}
+	if pending := ctx.pendingVotes.Snapshot(); len(pending) != 0 {
+		t.Errorf("expected no pending votes, got %v", pending)
+	}
+}
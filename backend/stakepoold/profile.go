@@ -0,0 +1,45 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// profileHandler returns the handler of the profiling server, which serves
+// the net/http/pprof endpoints under /debug/pprof/ and the expvar runtime
+// stats at /debug/vars.
+func profileHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/", http.RedirectHandler("/debug/pprof/",
+		http.StatusSeeOther))
+	return mux
+}
+
+// startProfileServer serves the profiling endpoints on port of the loopback
+// interface only, since profiles reveal the internals of stakepoold and
+// capturing them slows it down.  Admins reach it locally or through an SSH
+// tunnel.
+func startProfileServer(port string) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", port))
+	if err != nil {
+		return err
+	}
+	go func() {
+		log.Infof("Profiling server listening on %s", listener.Addr())
+		err := http.Serve(listener, profileHandler())
+		log.Errorf("Profiling server stopped: %v", err)
+	}()
+	return nil
+}
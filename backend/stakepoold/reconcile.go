@@ -0,0 +1,349 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+// existsLiveTicketsBatch is the number of tickets checked with hcd in one
+// existslivetickets call.
+const existsLiveTicketsBatch = 5000
+
+// ticketDiff is how a cached ticket map differs from the authoritative one.
+type ticketDiff struct {
+	missing map[chainhash.Hash]string // live but not cached
+	stale   map[chainhash.Hash]string // cached but no longer live
+	changed map[chainhash.Hash]string // cached with another multisig address
+}
+
+// empty returns whether the maps agree.
+func (d *ticketDiff) empty() bool {
+	return len(d.missing) == 0 && len(d.stale) == 0 && len(d.changed) == 0
+}
+
+// diffTickets compares the cached tickets with the authoritative ones.
+func diffTickets(cached, live map[chainhash.Hash]string) *ticketDiff {
+	d := &ticketDiff{
+		missing: make(map[chainhash.Hash]string),
+		stale:   make(map[chainhash.Hash]string),
+		changed: make(map[chainhash.Hash]string),
+	}
+	for ticket, msa := range live {
+		cachedMSA, ok := cached[ticket]
+		switch {
+		case !ok:
+			d.missing[ticket] = msa
+		case cachedMSA != msa:
+			d.changed[ticket] = msa
+		}
+	}
+	for ticket, msa := range cached {
+		if _, ok := live[ticket]; !ok {
+			d.stale[ticket] = msa
+		}
+	}
+	return d
+}
+
+// logDiff logs the discrepancies found in the cached tickets of kind.
+func logDiff(kind string, d *ticketDiff) {
+	for ticket, msa := range d.missing {
+		log.Warnf("reconcileTickets: %s ticket %v (msa %v) was missing "+
+			"from the cache", kind, ticket, msa)
+	}
+	for ticket, msa := range d.stale {
+		log.Warnf("reconcileTickets: %s ticket %v (msa %v) was spent "+
+			"or missed", kind, ticket, msa)
+	}
+	for ticket, msa := range d.changed {
+		log.Warnf("reconcileTickets: %s ticket %v was cached with the "+
+			"wrong msa, it is %v", kind, ticket, msa)
+	}
+}
+
+// filterLiveTickets removes the tickets hcd doesn't consider live from
+// tickets.  The wallet keeps reporting tickets that were spent or missed
+// until it has processed the block, so its list alone isn't authoritative.
+func (ctx *appContext) filterLiveTickets(tickets map[chainhash.Hash]string) error {
+	hashes := make([]*chainhash.Hash, 0, len(tickets))
+	for ticket := range tickets {
+		ticket := ticket
+		hashes = append(hashes, &ticket)
+	}
+	for len(hashes) > 0 {
+		batch := hashes
+		if len(batch) > existsLiveTicketsBatch {
+			batch = batch[:existsLiveTicketsBatch]
+		}
+		hashes = hashes[len(batch):]
+
+		live, err := ctx.node().ExistsLiveTickets(batch)
+		if err != nil {
+			return err
+		}
+		for i, ticket := range batch {
+			if !live[i] {
+				delete(tickets, *ticket)
+			}
+		}
+	}
+	return nil
+}
+
+// dropUnspentTickets removes the tickets that may not have been spent or
+// missed from stale, the cached tickets that are not among the mature live
+// tickets of the wallet: those hcd considers live, and those that are not
+// mature at tipHeight or whose purchase height is unknown, since the wallet
+// doesn't list immature tickets and hcd doesn't consider them live yet.
+func (ctx *appContext) dropUnspentTickets(stale map[chainhash.Hash]string,
+	heights map[chainhash.Hash]int64, tipHeight int64) error {
+	live := copyTickets(stale)
+	if err := ctx.filterLiveTickets(live); err != nil {
+		return err
+	}
+	maturity := int64(ctx.params.TicketMaturity)
+	for ticket := range stale {
+		height := heights[ticket]
+		_, isLive := live[ticket]
+		if isLive || height == 0 || tipHeight-height < maturity {
+			delete(stale, ticket)
+		}
+	}
+	return nil
+}
+
+// reconcileTickets merges the tickets the wallet has that hcd considers live
+// into the live and ignored low fee ticket caches, which are kept up to date
+// from the new and spent/missed ticket notifications.  Tickets missing from
+// the caches are added and those cached with the wrong multisig address are
+// corrected, but cached tickets are only dropped once they are mature and
+// neither the wallet nor hcd considers them live, so tickets added since the
+// wallet was asked are kept.  Discrepancies are logged.  Nothing is changed
+// when a block came in since the tickets were fetched, since its
+// notifications may not have been processed yet.
+func (ctx *appContext) reconcileTickets() error {
+	start := time.Now()
+
+	ignoredLowFeeTicketsMSA, liveTicketsMSA, tipHash, tipHeight, err :=
+		ctx.fetchTickets(nil)
+	if err != nil {
+		return err
+	}
+	if err := ctx.filterLiveTickets(liveTicketsMSA); err != nil {
+		return err
+	}
+	if err := ctx.filterLiveTickets(ignoredLowFeeTicketsMSA); err != nil {
+		return err
+	}
+
+	ctx.RLock()
+	liveDiff := diffTickets(ctx.liveTicketsMSA, liveTicketsMSA)
+	ignoredDiff := diffTickets(ctx.ignoredLowFeeTicketsMSA,
+		ignoredLowFeeTicketsMSA)
+	heights := make(map[chainhash.Hash]int64)
+	for _, stale := range []map[chainhash.Hash]string{liveDiff.stale,
+		ignoredDiff.stale} {
+		for ticket := range stale {
+			heights[ticket] = ctx.purchaseHeight(ticket)
+		}
+	}
+	ctx.RUnlock()
+
+	if err := ctx.dropUnspentTickets(liveDiff.stale, heights,
+		tipHeight); err != nil {
+		return err
+	}
+	if err := ctx.dropUnspentTickets(ignoredDiff.stale, heights,
+		tipHeight); err != nil {
+		return err
+	}
+
+	ctx.Lock()
+	defer ctx.Unlock()
+
+	if ctx.lastBlockSeenHash == nil || *ctx.lastBlockSeenHash != *tipHash {
+		log.Debugf("reconcileTickets: block %v (height %v) not processed "+
+			"yet, trying again later", tipHash, tipHeight)
+		return nil
+	}
+
+	if liveDiff.empty() && ignoredDiff.empty() {
+		log.Infof("reconcileTickets: %v live and %v ignored low fee "+
+			"tickets at height %v are correct (took %v)",
+			len(ctx.liveTicketsMSA), len(ctx.ignoredLowFeeTicketsMSA),
+			tipHeight, time.Since(start))
+		return nil
+	}
+
+	// The purchase heights of the spent and missed tickets can go.
+	defer ctx.pruneTicketHeights()
+
+	logDiff("live", liveDiff)
+	logDiff("ignored low fee", ignoredDiff)
+	mergeTickets(ctx.liveTicketsMSA, liveDiff)
+	mergeTickets(ctx.ignoredLowFeeTicketsMSA, ignoredDiff)
+	log.Warnf("reconcileTickets: corrected the tickets at height %v: "+
+		"live %v missing %v stale %v changed, ignored %v missing %v "+
+		"stale %v changed", tipHeight, len(liveDiff.missing),
+		len(liveDiff.stale), len(liveDiff.changed),
+		len(ignoredDiff.missing), len(ignoredDiff.stale),
+		len(ignoredDiff.changed))
+	return nil
+}
+
+// mergeTickets applies a diff to the cached tickets.
+func mergeTickets(cached map[chainhash.Hash]string, d *ticketDiff) {
+	for ticket, msa := range d.missing {
+		cached[ticket] = msa
+	}
+	for ticket, msa := range d.changed {
+		cached[ticket] = msa
+	}
+	for ticket := range d.stale {
+		delete(cached, ticket)
+	}
+}
+
+// copyTickets returns a copy of a ticket map.
+func copyTickets(tickets map[chainhash.Hash]string) map[chainhash.Hash]string {
+	c := make(map[chainhash.Hash]string, len(tickets))
+	for ticket, msa := range tickets {
+		c[ticket] = msa
+	}
+	return c
+}
+
+// reconcileStartupTickets checks the tickets loaded at startup, which may be
+// stale after downtime, against the live ticket pool of hcd.  Tickets hcd
+// doesn't consider live are dropped, but only those cached when the check
+// started, so tickets added by notifications while hcd is asked are kept.
+// The live tickets hcd has for the
+// multisig addresses of the users but stakepoold doesn't track are added like
+// AddMissingTickets does when the wallet has them, and flagged otherwise since
+// the wallet must be rescanned before it can vote them.  It returns the
+// tickets flagged.  It also runs after RescanWallet to pick up the tickets the
+// rescan found.
+func (ctx *appContext) reconcileStartupTickets() ([]chainhash.Hash, error) {
+	start := time.Now()
+
+	ctx.RLock()
+	liveSnapshot := copyTickets(ctx.liveTicketsMSA)
+	ignoredSnapshot := copyTickets(ctx.ignoredLowFeeTicketsMSA)
+	msas := make([]string, 0, len(ctx.userVotingConfig))
+	for msa := range ctx.userVotingConfig {
+		msas = append(msas, msa)
+	}
+	ctx.RUnlock()
+
+	liveTicketsMSA := copyTickets(liveSnapshot)
+	ignoredLowFeeTicketsMSA := copyTickets(ignoredSnapshot)
+
+	if err := ctx.filterLiveTickets(liveTicketsMSA); err != nil {
+		return nil, err
+	}
+	if err := ctx.filterLiveTickets(ignoredLowFeeTicketsMSA); err != nil {
+		return nil, err
+	}
+
+	var missing []chainhash.Hash
+	missingMSA := make(map[chainhash.Hash]string)
+	for _, msa := range msas {
+		tickets, err := ctx.node().LiveTicketsForAddress(msa)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the live tickets of "+
+				"%v: %v", msa, err)
+		}
+		for _, ticket := range tickets {
+			_, live := liveTicketsMSA[ticket]
+			_, ignored := ignoredLowFeeTicketsMSA[ticket]
+			_, seen := missingMSA[ticket]
+			if live || ignored || seen {
+				continue
+			}
+			missing = append(missing, ticket)
+			missingMSA[ticket] = msa
+		}
+	}
+
+	// The stale tickets are those of the snapshots hcd reported not live.
+	var dropped int
+	ctx.Lock()
+	liveDiff := diffTickets(liveSnapshot, liveTicketsMSA)
+	ignoredDiff := diffTickets(ignoredSnapshot, ignoredLowFeeTicketsMSA)
+	for ticket, msa := range liveDiff.stale {
+		if _, ok := ctx.liveTicketsMSA[ticket]; !ok {
+			continue
+		}
+		log.Infof("reconcileStartupTickets: dropping live ticket %v "+
+			"(msa %v), it was spent or missed", ticket, msa)
+		delete(ctx.liveTicketsMSA, ticket)
+		dropped++
+	}
+	for ticket, msa := range ignoredDiff.stale {
+		if _, ok := ctx.ignoredLowFeeTicketsMSA[ticket]; !ok {
+			continue
+		}
+		log.Infof("reconcileStartupTickets: dropping ignored low fee "+
+			"ticket %v (msa %v), it was spent or missed", ticket, msa)
+		delete(ctx.ignoredLowFeeTicketsMSA, ticket)
+		dropped++
+	}
+	ctx.pruneTicketHeights()
+	ctx.Unlock()
+
+	var added, ignored int
+	var flagged []chainhash.Hash
+	for _, r := range ctx.AddMissingTickets(context.Background(), missing) {
+		switch r.Status {
+		case rpcserver.MissingTicketAdded:
+			added++
+		case rpcserver.MissingTicketIgnoredLowFee:
+			ignored++
+		case rpcserver.MissingTicketNotInWallet,
+			rpcserver.MissingTicketNotPoolTicket:
+			log.Warnf("reconcileStartupTickets: live ticket %v of msa "+
+				"%v is unknown to the wallet, rescan it to vote the "+
+				"ticket", r.Ticket, missingMSA[r.Ticket])
+			flagged = append(flagged, r.Ticket)
+		case rpcserver.MissingTicketFailed:
+			log.Warnf("reconcileStartupTickets: unable to add live "+
+				"ticket %v of msa %v: %v", r.Ticket,
+				missingMSA[r.Ticket], r.Err)
+			flagged = append(flagged, r.Ticket)
+		}
+	}
+
+	log.Infof("reconcileStartupTickets: checked the tickets of %d users "+
+		"with hcd: dropped %d stale, added %d live and %d ignored low "+
+		"fee, flagged %d unknown (took %v)", len(msas), dropped, added,
+		ignored, len(flagged), time.Since(start))
+	return flagged, nil
+}
+
+// ticketReconcileHandler reconciles the ticket caches every interval.  It
+// must be run as a goroutine.
+func (ctx *appContext) ticketReconcileHandler(interval time.Duration) {
+	defer ctx.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := ctx.reconcileTickets(); err != nil {
+				log.Warnf("reconcileTickets failed: %v", err)
+			}
+		case <-ctx.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+)
+
+// testMSA is a testnet address walletGetTickets accepts as the multisig
+// address of a user.
+const testMSA = "TsYLznZJn2xhM9F7Vnt7i39NuUFENGx9Hff"
+
+func TestDiffTickets(t *testing.T) {
+	kept := chainhash.Hash{1}
+	missing := chainhash.Hash{2}
+	stale := chainhash.Hash{3}
+	changed := chainhash.Hash{4}
+	cached := map[chainhash.Hash]string{
+		kept:    "msa1",
+		stale:   "msa3",
+		changed: "msa4",
+	}
+	live := map[chainhash.Hash]string{
+		kept:    "msa1",
+		missing: "msa2",
+		changed: "msa5",
+	}
+
+	d := diffTickets(cached, live)
+	if d.empty() {
+		t.Fatal("diff of different maps is empty")
+	}
+	want := &ticketDiff{
+		missing: map[chainhash.Hash]string{missing: "msa2"},
+		stale:   map[chainhash.Hash]string{stale: "msa3"},
+		changed: map[chainhash.Hash]string{changed: "msa5"},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("diff %+v, want %+v", d, want)
+	}
+	if d := diffTickets(live, live); !d.empty() {
+		t.Errorf("diff of equal maps %+v", d)
+	}
+}
+
+func TestFilterLiveTickets(t *testing.T) {
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	ctx := &appContext{nodeConnection: node}
+
+	tickets := make(map[chainhash.Hash]string)
+	var live []chainhash.Hash
+	for i := 0; i < existsLiveTicketsBatch+10; i++ {
+		ticket := chainhash.Hash{byte(i), byte(i >> 8)}
+		tickets[ticket] = "msa"
+		if i%2 == 0 {
+			live = append(live, ticket)
+		}
+	}
+	node.SetLiveTickets(live...)
+
+	if err := ctx.filterLiveTickets(tickets); err != nil {
+		t.Fatalf("filterLiveTickets: %v", err)
+	}
+	if len(tickets) != len(live) {
+		t.Fatalf("%d tickets left, want %d", len(tickets), len(live))
+	}
+	for _, ticket := range live {
+		if _, ok := tickets[ticket]; !ok {
+			t.Errorf("live ticket %v was removed", ticket)
+		}
+	}
+}
+
+func TestReconcileTickets(t *testing.T) {
+	kept := chainhash.Hash{1}
+	missing := chainhash.Hash{2}
+	spent := chainhash.Hash{3}
+	immature := chainhash.Hash{4}
+	unknown := chainhash.Hash{5}
+	stillLive := chainhash.Hash{6}
+
+	params := &chaincfg.TestNet2Params
+	tipHeight := int64(1000)
+	header := &wire.BlockHeader{Height: uint32(tipHeight)}
+	tipHash := header.BlockHash()
+	node := rpcclienttest.NewNode(params.Net)
+	node.AddBlock(header)
+	node.SetLiveTickets(kept, missing, stillLive)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	for _, ticket := range []chainhash.Hash{kept, missing} {
+		ticket := ticket
+		wallet.AddTicket(&ticket, &dcrjson.GetTransactionResult{
+			TxID:      ticket.String(),
+			BlockHash: tipHash.String(),
+			Details: []dcrjson.GetTransactionDetailsResult{
+				{Address: testMSA},
+			},
+		})
+	}
+
+	ctx := &appContext{
+		addedLowFeeTicketsMSA:   map[chainhash.Hash]string{kept: testMSA, missing: testMSA},
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		lastBlockSeenHash:       &tipHash,
+		liveTicketsMSA: map[chainhash.Hash]string{
+			kept:      testMSA,
+			spent:     testMSA,
+			immature:  testMSA,
+			unknown:   testMSA,
+			stillLive: testMSA,
+		},
+		nodeConnection: node,
+		params:         params,
+		ticketHeights: map[chainhash.Hash]int64{
+			spent:     tipHeight - int64(params.TicketMaturity) - 10,
+			immature:  tipHeight - 1,
+			stillLive: tipHeight - int64(params.TicketMaturity) - 10,
+		},
+		userVotingConfig: map[string]userdata.UserVotingConfig{
+			testMSA: {MultiSigAddress: testMSA},
+		},
+		walletConnection: wallet,
+	}
+
+	if err := ctx.reconcileTickets(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[chainhash.Hash]string{
+		kept:      testMSA,
+		missing:   testMSA,
+		immature:  testMSA,
+		unknown:   testMSA,
+		stillLive: testMSA,
+	}
+	if !reflect.DeepEqual(ctx.liveTicketsMSA, want) {
+		t.Errorf("live tickets %v, want %v", ctx.liveTicketsMSA, want)
+	}
+	if _, ok := ctx.ticketHeights[spent]; ok {
+		t.Error("purchase height of the spent ticket was kept")
+	}
+
+	// Nothing changes while the block the tickets were fetched at hasn't
+	// been processed.
+	ctx.liveTicketsMSA[spent] = testMSA
+	ctx.lastBlockSeenHash = &chainhash.Hash{9}
+	if err := ctx.reconcileTickets(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ctx.liveTicketsMSA[spent]; !ok {
+		t.Error("ticket dropped before the block was processed")
+	}
+}
+
+func TestReconcileStartupTickets(t *testing.T) {
+	kept := chainhash.Hash{1}
+	stale := chainhash.Hash{2}
+	added := chainhash.Hash{3}
+	unknown := chainhash.Hash{4}
+
+	header := &wire.BlockHeader{Height: 90}
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	node.AddBlock(header)
+	node.SetLiveTickets(kept, added, unknown)
+	node.SetAddressTickets("msa", kept, stale, added, unknown)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	wallet.AddTransaction(&added, &dcrjson.GetTransactionResult{
+		BlockHash: header.BlockHash().String(),
+		Details: []dcrjson.GetTransactionDetailsResult{
+			{Address: "msa"},
+		},
+	})
+
+	ctx := &appContext{
+		addedLowFeeTicketsMSA:   map[chainhash.Hash]string{added: "msa"},
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          map[chainhash.Hash]string{kept: "msa", stale: "msa"},
+		nodeConnection:          node,
+		ticketHeights:           map[chainhash.Hash]int64{stale: 80},
+		userVotingConfig: map[string]userdata.UserVotingConfig{
+			"msa": {MultiSigAddress: "msa"},
+		},
+		walletConnection: wallet,
+	}
+
+	flagged, err := ctx.reconcileStartupTickets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(flagged, []chainhash.Hash{unknown}) {
+		t.Errorf("flagged %v, want %v", flagged, unknown)
+	}
+	want := map[chainhash.Hash]string{kept: "msa", added: "msa"}
+	if !reflect.DeepEqual(ctx.liveTicketsMSA, want) {
+		t.Errorf("live tickets %v, want %v", ctx.liveTicketsMSA, want)
+	}
+	if _, ok := ctx.ticketHeights[stale]; ok {
+		t.Error("purchase height of the stale ticket was kept")
+	}
+}
+
+// notifyingNode is a node during whose LiveTicketsForAddress calls stakepoold
+// processes a new tickets notification adding ticket.
+type notifyingNode struct {
+	rpcclient.ChainSource
+	ctx    *appContext
+	ticket chainhash.Hash
+}
+
+func (n *notifyingNode) LiveTicketsForAddress(address string) ([]chainhash.Hash, error) {
+	n.ctx.Lock()
+	n.ctx.liveTicketsMSA[n.ticket] = "msa"
+	n.ctx.Unlock()
+	return n.ChainSource.LiveTicketsForAddress(address)
+}
+
+func TestReconcileStartupTicketsKeepsNewTickets(t *testing.T) {
+	kept := chainhash.Hash{1}
+	stale := chainhash.Hash{2}
+	notified := chainhash.Hash{3}
+
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	node.AddBlock(&wire.BlockHeader{Height: 90})
+	node.SetLiveTickets(kept)
+	node.SetAddressTickets("msa", kept)
+
+	ctx := &appContext{
+		addedLowFeeTicketsMSA:   make(map[chainhash.Hash]string),
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          map[chainhash.Hash]string{kept: "msa", stale: "msa"},
+		ticketHeights:           make(map[chainhash.Hash]int64),
+		userVotingConfig: map[string]userdata.UserVotingConfig{
+			"msa": {MultiSigAddress: "msa"},
+		},
+		walletConnection: rpcclienttest.NewWallet(dcrjson.WalletInfoResult{}),
+	}
+	ctx.nodeConnection = &notifyingNode{ChainSource: node, ctx: ctx,
+		ticket: notified}
+
+	if _, err := ctx.reconcileStartupTickets(); err != nil {
+		t.Fatal(err)
+	}
+	// hcd doesn't consider the notified ticket live yet, but it wasn't
+	// cached when the check started.
+	want := map[chainhash.Hash]string{kept: "msa", notified: "msa"}
+	if !reflect.DeepEqual(ctx.liveTicketsMSA, want) {
+		t.Errorf("live tickets %v, want %v", ctx.liveTicketsMSA, want)
+	}
+}
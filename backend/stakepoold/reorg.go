@@ -0,0 +1,188 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+)
+
+// ticketChangesHistory is the number of blocks for which ticket changes are
+// remembered.  Reorganizations deeper than this are not undone.
+const ticketChangesHistory = 64
+
+// blockTicketChanges records how a single block changed the ticket maps so
+// the changes can be undone if the block is disconnected by a
+// reorganization.
+type blockTicketChanges struct {
+	blockHash      chainhash.Hash
+	added          map[chainhash.Hash]string // [ticket]multisigaddr
+	addedIgnored   map[chainhash.Hash]string // [ticket]multisigaddr
+	removed        map[chainhash.Hash]string // [ticket]multisigaddr
+	removedIgnored map[chainhash.Hash]string // [ticket]multisigaddr
+}
+
+// Reorganization is sent by the notification handler when the best chain
+// switches to a different branch.
+type Reorganization struct {
+	oldHash   *chainhash.Hash
+	oldHeight int64
+	newHash   *chainhash.Hash
+	newHeight int64
+}
+
+// ticketChangesForBlock returns the change record for the block, creating it
+// if needed and discarding records that are too old to be reorganized.  A
+// record of a different block at the same height is of a block disconnected
+// by a reorganization, so its changes are reverted before it is replaced.
+// Recorded blocks above it are reverted as the new branch reaches their
+// heights, or by processReorganization if it doesn't.
+//
+// This function MUST be called with the context lock held (for writes).
+func (ctx *appContext) ticketChangesForBlock(blockHash *chainhash.Hash,
+	blockHeight int64) *blockTicketChanges {
+	changes, ok := ctx.blockTicketChanges[blockHeight]
+	if ok && changes.blockHash == *blockHash {
+		return changes
+	}
+	if ok {
+		removed, restored := ctx.revertBlockTicketChanges([]int64{blockHeight})
+		log.Infof("Block %v replaces %v at height %v: removed %v "+
+			"restored %v tickets", blockHash, changes.blockHash,
+			blockHeight, removed, restored)
+	}
+
+	changes = &blockTicketChanges{
+		blockHash:      *blockHash,
+		added:          make(map[chainhash.Hash]string),
+		addedIgnored:   make(map[chainhash.Hash]string),
+		removed:        make(map[chainhash.Hash]string),
+		removedIgnored: make(map[chainhash.Hash]string),
+	}
+	ctx.blockTicketChanges[blockHeight] = changes
+
+	for height := range ctx.blockTicketChanges {
+		if height <= blockHeight-ticketChangesHistory {
+			delete(ctx.blockTicketChanges, height)
+		}
+	}
+
+	return changes
+}
+
+// revertBlockTicketChanges undoes the ticket changes of the blocks at the
+// passed heights, most recent first, and forgets them.  Tickets that became
+// live in those blocks are removed and tickets that were spent or missed in
+// them are live again.  It returns the number of tickets that were removed
+// and restored.
+//
+// This function MUST be called with the context lock held (for writes).
+func (ctx *appContext) revertBlockTicketChanges(heights []int64) (int, int) {
+	sort.Slice(heights, func(i, j int) bool { return heights[i] > heights[j] })
+
+	var removed, restored int
+	for _, height := range heights {
+		changes, ok := ctx.blockTicketChanges[height]
+		if !ok {
+			continue
+		}
+		for ticket := range changes.added {
+			delete(ctx.liveTicketsMSA, ticket)
+			removed++
+		}
+		for ticket := range changes.addedIgnored {
+			delete(ctx.ignoredLowFeeTicketsMSA, ticket)
+		}
+		for ticket, msa := range changes.removed {
+			ctx.liveTicketsMSA[ticket] = msa
+			restored++
+		}
+		for ticket, msa := range changes.removedIgnored {
+			ctx.ignoredLowFeeTicketsMSA[ticket] = msa
+		}
+		delete(ctx.blockTicketChanges, height)
+	}
+
+	return removed, restored
+}
+
+// revertDisconnected reverts the ticket changes of the recorded blocks in
+// disconnected, keyed by height.  Records replaced by a block of the new
+// branch since disconnected was determined have already been reverted and
+// are left alone.  It returns the number of tickets that were removed and
+// restored.
+//
+// This function MUST be called with the context lock held (for writes).
+func (ctx *appContext) revertDisconnected(disconnected map[int64]chainhash.Hash) (int, int) {
+	heights := make([]int64, 0, len(disconnected))
+	for height, hash := range disconnected {
+		changes, ok := ctx.blockTicketChanges[height]
+		if ok && changes.blockHash == hash {
+			heights = append(heights, height)
+		}
+	}
+	return ctx.revertBlockTicketChanges(heights)
+}
+
+// processReorganization finds the blocks that were disconnected by a
+// reorganization by comparing the recorded block hashes against the new
+// best chain and reverts their ticket changes.  The blocks of the new branch
+// are handled by the regular notifications, which revert the blocks they
+// replace themselves, so this only matters when the new branch is shorter
+// or its notifications were missed.
+func (ctx *appContext) processReorganization(r Reorganization) {
+	start := time.Now()
+
+	ctx.RLock()
+	recorded := make(map[int64]chainhash.Hash, len(ctx.blockTicketChanges))
+	for height, changes := range ctx.blockTicketChanges {
+		recorded[height] = changes.blockHash
+	}
+	ctx.RUnlock()
+
+	disconnected := make(map[int64]chainhash.Hash)
+	for height, hash := range recorded {
+		if height > r.newHeight {
+			disconnected[height] = hash
+			continue
+		}
+		mainHash, err := ctx.node().GetBlockHash(height)
+		if err != nil {
+			log.Errorf("processReorganization: GetBlockHash(%v) failed: %v",
+				height, err)
+			continue
+		}
+		if *mainHash != hash {
+			disconnected[height] = hash
+		}
+	}
+
+	ctx.Lock()
+	removed, restored := ctx.revertDisconnected(disconnected)
+	liveTicketsCount := len(ctx.liveTicketsMSA)
+	ctx.Unlock()
+
+	log.Infof("processReorganization: old %v (height %v) new %v (height %v) "+
+		"duration %v disconnected %v removed %v restored %v live %v",
+		r.oldHash, r.oldHeight, r.newHash, r.newHeight, time.Since(start),
+		len(disconnected), removed, restored, liveTicketsCount)
+}
+
+// reorganizationHandler processes the queued reorganizations one at a time,
+// in the order they were notified.
+func (ctx *appContext) reorganizationHandler() {
+	defer ctx.wg.Done()
+
+	for {
+		select {
+		case r := <-ctx.reorganizationChan:
+			ctx.processReorganization(r)
+		case <-ctx.quit:
+			return
+		}
+	}
+}
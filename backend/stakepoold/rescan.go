@@ -0,0 +1,129 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+// rescanProgressInterval is how often the progress of a wallet rescan is
+// reported to the caller of RescanWallet.
+const rescanProgressInterval = time.Second * 10
+
+// rescanResult is the outcome of a wallet rescan and the reconciliation of the
+// tickets that follows it.
+type rescanResult struct {
+	progress *rpcserver.RescanProgress
+	err      error
+}
+
+// RescanWallet implements rpcserver.WalletRescanner.  The rescan and the
+// reconciliation of the tickets with hcd run in their own goroutine, so they
+// finish even when reqCtx is done, and aren't traced as part of the request.
+func (ctx *appContext) RescanWallet(reqCtx context.Context, beginHeight int64,
+	progress func(*rpcserver.RescanProgress) error) error {
+	if !atomic.CompareAndSwapInt32(&ctx.rescanning, 0, 1) {
+		return rpcserver.ErrRescanInProgress
+	}
+	started := false
+	defer func() {
+		if !started {
+			atomic.StoreInt32(&ctx.rescanning, 0)
+		}
+	}()
+
+	node, wallet := ctx.node(), ctx.wallet()
+	if node == nil || node.Disconnected() {
+		return errors.New("hcd is disconnected")
+	}
+	if wallet == nil || wallet.Disconnected() {
+		return errors.New("hcwallet is disconnected")
+	}
+	_, bestHeight, err := node.GetBestBlock()
+	if err != nil {
+		return fmt.Errorf("unable to get the best block: %v", err)
+	}
+	if beginHeight > bestHeight {
+		return rpcserver.ErrRescanHeight
+	}
+
+	prefix := requestLogPrefix(reqCtx)
+	log.Infof("%sRescanning the wallet from height %d to %d", prefix,
+		beginHeight, bestHeight)
+	start := time.Now()
+	result := make(chan rescanResult, 1)
+	started = true
+	go func() {
+		defer atomic.StoreInt32(&ctx.rescanning, 0)
+		p, err := ctx.rescanWallet(wallet, beginHeight, bestHeight)
+		if err != nil {
+			log.Errorf("%sWallet rescan from height %d failed: %v", prefix,
+				beginHeight, err)
+		}
+		result <- rescanResult{p, err}
+	}()
+
+	ticker := time.NewTicker(rescanProgressInterval)
+	defer ticker.Stop()
+	p := &rpcserver.RescanProgress{
+		BeginHeight: beginHeight,
+		BestHeight:  bestHeight,
+	}
+	for {
+		if err := progress(p); err != nil {
+			return err
+		}
+		select {
+		case r := <-result:
+			if r.err != nil {
+				return r.err
+			}
+			return progress(r.progress)
+		case <-ticker.C:
+			p.Elapsed = time.Since(start)
+		case <-reqCtx.Done():
+			log.Infof("%sCaller of the wallet rescan went away, the rescan "+
+				"continues", prefix)
+			return reqCtx.Err()
+		}
+	}
+}
+
+// rescanWallet rescans wallet from beginHeight and then reconciles the
+// tickets with hcd, adding those the rescan found.
+func (ctx *appContext) rescanWallet(wallet rpcclient.WalletSource,
+	beginHeight, bestHeight int64) (*rpcserver.RescanProgress, error) {
+	start := time.Now()
+	if err := wallet.RescanWallet(beginHeight); err != nil {
+		return nil, err
+	}
+	log.Infof("Wallet rescan from height %d done (took %v)", beginHeight,
+		time.Since(start))
+
+	flagged, err := ctx.reconcileStartupTickets()
+	if err != nil {
+		return nil, fmt.Errorf("unable to reconcile the tickets with hcd "+
+			"after the rescan: %v", err)
+	}
+	ctx.RLock()
+	live := len(ctx.liveTicketsMSA)
+	ctx.RUnlock()
+
+	return &rpcserver.RescanProgress{
+		BeginHeight:    beginHeight,
+		BestHeight:     bestHeight,
+		Elapsed:        time.Since(start),
+		Done:           true,
+		LiveTickets:    live,
+		FlaggedTickets: flagged,
+	}, nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+)
+
+func TestRescanWallet(t *testing.T) {
+	restored := chainhash.Hash{1}
+
+	header := &wire.BlockHeader{Height: 90}
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	node.AddBlock(header)
+	node.SetLiveTickets(restored)
+	node.SetAddressTickets("msa", restored)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+
+	ctx := &appContext{
+		addedLowFeeTicketsMSA:   map[chainhash.Hash]string{restored: "msa"},
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+		nodeConnection:          node,
+		ticketHeights:           make(map[chainhash.Hash]int64),
+		userVotingConfig: map[string]userdata.UserVotingConfig{
+			"msa": {MultiSigAddress: "msa"},
+		},
+		walletConnection: wallet,
+	}
+
+	// The restored wallet only finds the ticket by rescanning, during
+	// which other rescans are refused.
+	var concurrentErr error
+	wallet.SetRescanHook(func(int64) error {
+		concurrentErr = ctx.RescanWallet(context.Background(), 0,
+			func(*rpcserver.RescanProgress) error { return nil })
+		wallet.AddTransaction(&restored, &dcrjson.GetTransactionResult{
+			BlockHash: header.BlockHash().String(),
+			Details: []dcrjson.GetTransactionDetailsResult{
+				{Address: "msa"},
+			},
+		})
+		return nil
+	})
+
+	var progress []rpcserver.RescanProgress
+	err := ctx.RescanWallet(context.Background(), 80,
+		func(p *rpcserver.RescanProgress) error {
+			progress = append(progress, *p)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if concurrentErr != rpcserver.ErrRescanInProgress {
+		t.Errorf("concurrent rescan: got error %v, want %v", concurrentErr,
+			rpcserver.ErrRescanInProgress)
+	}
+	if rescans := wallet.Rescans(); !reflect.DeepEqual(rescans, []int64{80}) {
+		t.Errorf("rescans %v, want [80]", rescans)
+	}
+	if len(progress) < 2 {
+		t.Fatalf("progress %v, want at least a start and the end", progress)
+	}
+	first, last := progress[0], progress[len(progress)-1]
+	if first.Done || first.BeginHeight != 80 || first.BestHeight != 90 {
+		t.Errorf("first progress %+v", first)
+	}
+	if !last.Done || last.LiveTickets != 1 || len(last.FlaggedTickets) != 0 {
+		t.Errorf("last progress %+v, want done with 1 live ticket", last)
+	}
+	if ctx.liveTicketsMSA[restored] != "msa" {
+		t.Errorf("restored ticket not live: %v", ctx.liveTicketsMSA)
+	}
+
+	err = ctx.RescanWallet(context.Background(), 91,
+		func(*rpcserver.RescanProgress) error { return nil })
+	if err != rpcserver.ErrRescanHeight {
+		t.Errorf("rescan above the best block: got error %v, want %v", err,
+			rpcserver.ErrRescanHeight)
+	}
+}
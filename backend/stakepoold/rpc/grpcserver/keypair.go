@@ -0,0 +1,252 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Copyright (c) 2015-2017 The Decred developers
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package grpcserver
+
+import (
+	"crypto/elliptic"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcutil"
+)
+
+const (
+	// certOrg is the organization of autogenerated certificates.  It is
+	// used to tell them apart from certificates provided by the user,
+	// which are never renewed automatically.
+	certOrg = "stakepoold autogenerated cert"
+
+	// CertValidity is how long autogenerated certificates are valid.
+	CertValidity = time.Hour * 24 * 365 * 10
+
+	// certRetryInterval is how long to wait before trying again when
+	// renewing the certificate fails.
+	certRetryInterval = time.Hour
+)
+
+// generateKeyPair generates a new TLS keypair and writes the cert and possibly
+// also the key in PEM format to the passed paths.  If successful, the new
+// keypair is returned.
+func generateKeyPair(certFile, keyFile string, writeKey bool) (tls.Certificate, error) {
+	log.Info("Generating TLS certificates...")
+
+	// Create directories for cert and key files if they do not yet exist.
+	certDir, _ := filepath.Split(certFile)
+	keyDir, _ := filepath.Split(keyFile)
+	err := os.MkdirAll(certDir, 0700)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	err = os.MkdirAll(keyDir, 0700)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	// Generate cert pair.
+	validUntil := time.Now().Add(CertValidity)
+	cert, key, err := hcutil.NewTLSCertPair(elliptic.P521(), certOrg,
+		validUntil, nil)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	// Write cert and (potentially) the key files.
+	err = ioutil.WriteFile(certFile, cert, 0600)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if writeKey {
+		err = ioutil.WriteFile(keyFile, key, 0600)
+		if err != nil {
+			rmErr := os.Remove(certFile)
+			if rmErr != nil {
+				log.Warnf("Cannot remove written certificates: %v",
+					rmErr)
+			}
+			return tls.Certificate{}, err
+		}
+	}
+
+	log.Info("Done generating TLS certificates")
+	return keyPair, nil
+}
+
+// keyPairInfo parses the leaf certificate of the keypair and reports whether
+// it was autogenerated by stakepoold.
+func keyPairInfo(keyPair *tls.Certificate) (*x509.Certificate, bool, error) {
+	if len(keyPair.Certificate) == 0 {
+		return nil, false, errors.New("keypair has no certificate")
+	}
+	leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, false, err
+	}
+	for _, org := range leaf.Subject.Organization {
+		if org == certOrg {
+			return leaf, true, nil
+		}
+	}
+	return leaf, false, nil
+}
+
+// openKeyPair creates or loads the TLS keypair in the passed files.
+// Autogenerated certificates that have expired or are within the renewal
+// window are regenerated, while expired certificates provided by the user are
+// an error.
+func openKeyPair(certFile, keyFile string, renewal time.Duration) (tls.Certificate, error) {
+	// Generate a new keypair when the key is missing.
+	_, e := os.Stat(keyFile)
+	keyExists := !os.IsNotExist(e)
+	if !keyExists {
+		return generateKeyPair(certFile, keyFile, true)
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, autogenerated, err := keyPairInfo(&keyPair)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	switch {
+	case autogenerated && now.Add(renewal).After(leaf.NotAfter):
+		log.Infof("RPC certificate %s expires %v, renewing it",
+			certFile, leaf.NotAfter)
+		return generateKeyPair(certFile, keyFile, true)
+	case now.After(leaf.NotAfter):
+		return tls.Certificate{}, fmt.Errorf("RPC certificate %s "+
+			"expired %v", certFile, leaf.NotAfter)
+	case now.Add(renewal).After(leaf.NotAfter):
+		log.Warnf("RPC certificate %s expires %v and must be replaced "+
+			"manually", certFile, leaf.NotAfter)
+	}
+
+	return keyPair, nil
+}
+
+// keyPair holds the keypair used by the gRPC server.  It is consulted on
+// every TLS handshake, so a replaced keypair is used for all new connections
+// without restarting the server.
+type keyPair struct {
+	certFile string
+	keyFile  string
+	renewal  time.Duration
+
+	mtx           sync.RWMutex
+	keyPair       *tls.Certificate
+	notAfter      time.Time
+	autogenerated bool
+}
+
+// newKeyPair returns a keyPair serving the passed keypair, which is replaced
+// by writing new keypairs to certFile and keyFile.
+func newKeyPair(kp tls.Certificate, certFile, keyFile string, renewal time.Duration) (*keyPair, error) {
+	k := &keyPair{
+		certFile: certFile,
+		keyFile:  keyFile,
+		renewal:  renewal,
+	}
+	if err := k.set(kp); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// set replaces the served keypair.
+func (k *keyPair) set(kp tls.Certificate) error {
+	leaf, autogenerated, err := keyPairInfo(&kp)
+	if err != nil {
+		return err
+	}
+
+	k.mtx.Lock()
+	k.keyPair = &kp
+	k.notAfter = leaf.NotAfter
+	k.autogenerated = autogenerated
+	k.mtx.Unlock()
+	return nil
+}
+
+// getCertificate implements the GetCertificate callback of tls.Config.
+func (k *keyPair) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	k.mtx.RLock()
+	defer k.mtx.RUnlock()
+	return k.keyPair, nil
+}
+
+// rotate generates a new keypair, writes it to the keypair files and starts
+// serving it.  The new certificate is returned in PEM format along with its
+// expiration time so it can be distributed to clients.
+func (k *keyPair) rotate() ([]byte, time.Time, error) {
+	kp, err := generateKeyPair(k.certFile, k.keyFile, true)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if err := k.set(kp); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	k.mtx.RLock()
+	notAfter := k.notAfter
+	k.mtx.RUnlock()
+	log.Infof("RPC certificate rotated, the new certificate in %s expires "+
+		"%v and must be copied to all RPC clients", k.certFile, notAfter)
+
+	cert := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: kp.Certificate[0],
+	})
+	return cert, notAfter, nil
+}
+
+// renewHandler rotates autogenerated keypairs when they are within the
+// renewal window of their expiration time.  Keypairs provided by the user are
+// left alone.  It must be run as a goroutine.
+func (k *keyPair) renewHandler(quit <-chan struct{}) {
+	for {
+		k.mtx.RLock()
+		autogenerated := k.autogenerated
+		wait := time.Until(k.notAfter.Add(-k.renewal))
+		k.mtx.RUnlock()
+		if !autogenerated {
+			return
+		}
+		if wait < 0 {
+			wait = 0
+		}
+
+		select {
+		case <-time.After(wait):
+			if _, _, err := k.rotate(); err != nil {
+				log.Errorf("Failed to renew RPC certificate, retrying "+
+					"in %v: %v", certRetryInterval, err)
+				select {
+				case <-time.After(certRetryInterval):
+				case <-quit:
+					return
+				}
+			}
+		case <-quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+package grpcserver
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenKeyPair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpcserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "rpc.cert")
+	keyFile := filepath.Join(dir, "rpc.key")
+
+	// A missing key generates a new keypair.
+	kp, err := openKeyPair(certFile, keyFile, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("openKeyPair: %v", err)
+	}
+	leaf, autogenerated, err := keyPairInfo(&kp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !autogenerated {
+		t.Errorf("expected generated certificate to be autogenerated")
+	}
+	cert, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An existing keypair outside the renewal window is loaded as is.
+	loaded, err := openKeyPair(certFile, keyFile, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("openKeyPair: %v", err)
+	}
+	if !bytes.Equal(loaded.Certificate[0], kp.Certificate[0]) {
+		t.Errorf("expected the existing keypair to be loaded")
+	}
+
+	// An autogenerated keypair within the renewal window is regenerated.
+	renewal := time.Until(leaf.NotAfter) + time.Hour
+	renewed, err := openKeyPair(certFile, keyFile, renewal)
+	if err != nil {
+		t.Fatalf("openKeyPair: %v", err)
+	}
+	if bytes.Equal(renewed.Certificate[0], kp.Certificate[0]) {
+		t.Errorf("expected the keypair to be renewed")
+	}
+	renewedCert, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(renewedCert, cert) {
+		t.Errorf("expected the renewed certificate to be written")
+	}
+}
+
+func TestKeyPairRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpcserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "rpc.cert")
+	keyFile := filepath.Join(dir, "rpc.key")
+
+	kp, err := openKeyPair(certFile, keyFile, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := newKeyPair(kp, certFile, keyFile, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, notAfter, err := keys.rotate()
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	written, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cert, written) {
+		t.Errorf("expected the returned certificate to be written to %s",
+			certFile)
+	}
+	if !notAfter.After(time.Now()) {
+		t.Errorf("expected the new certificate to be valid, expires %v",
+			notAfter)
+	}
+	served, err := keys.getCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(served.Certificate[0], kp.Certificate[0]) {
+		t.Errorf("expected the rotated keypair to be served")
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Copyright (c) 2015-2017 The Decred developers
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package grpcserver
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+)
+
+type listenFunc func(net string, laddr string) (net.Listener, error)
+
+// interfaceListenAddrs returns the IPv4 and IPv6 listen addresses for every
+// address assigned to the named local network interface using the passed
+// port.  Link-local IPv6 addresses are scoped to the interface with a zone so
+// they can be bound.  Interface names are resolved from the local system only,
+// so no DNS queries are made.
+func interfaceListenAddrs(name, port string) ([]string, []string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, nil, err
+	}
+	return ifaceListenAddrs(iface.Name, addrs, port)
+}
+
+// ifaceListenAddrs returns the IPv4 and IPv6 listen addresses for the
+// addresses of the network interface name using the passed port.
+func ifaceListenAddrs(name string, addrs []net.Addr, port string) ([]string, []string, error) {
+	var ipv4Addrs, ipv6Addrs []string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP
+		if ip.To4() != nil {
+			ipv4Addrs = append(ipv4Addrs, net.JoinHostPort(ip.String(), port))
+			continue
+		}
+		host := ip.String()
+		if ip.IsLinkLocalUnicast() {
+			host += "%" + name
+		}
+		ipv6Addrs = append(ipv6Addrs, net.JoinHostPort(host, port))
+	}
+	if len(ipv4Addrs) == 0 && len(ipv6Addrs) == 0 {
+		return nil, nil, fmt.Errorf("interface %s has no IP addresses",
+			name)
+	}
+
+	return ipv4Addrs, ipv6Addrs, nil
+}
+
+// makeListeners splits the normalized listen addresses into IPv4 and IPv6
+// addresses and creates new net.Listeners for each with the passed listen func.
+// Hosts may also be IPv6 addresses with a zone (e.g. [fe80::1%eth0]:9113) or
+// the name of a local network interface, in which case every address of that
+// interface is listened on.  Invalid addresses are logged and skipped.
+func makeListeners(normalizedListenAddrs []string, listen listenFunc) []net.Listener {
+	ipv4Addrs := make([]string, 0, len(normalizedListenAddrs)*2)
+	ipv6Addrs := make([]string, 0, len(normalizedListenAddrs)*2)
+	anyAddrs := make([]string, 0, len(normalizedListenAddrs))
+	for _, addr := range normalizedListenAddrs {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			// Shouldn't happen due to already being normalized.
+			log.Errorf("`%s` is not a normalized "+
+				"listener address", addr)
+			continue
+		}
+
+		// Empty host or host of * on plan9 is both IPv4 and IPv6.
+		if host == "" || (host == "*" && runtime.GOOS == "plan9") {
+			anyAddrs = append(anyAddrs, addr)
+			continue
+		}
+
+		// Remove the IPv6 zone from the host, if present.  The zone
+		// prevents ParseIP from correctly parsing the IP address.
+		// ResolveIPAddr is intentionally not used here due to the
+		// possibility of leaking a DNS query over Tor if the host is a
+		// hostname and not an IP address.
+		zone := ""
+		zoneIndex := strings.Index(host, "%")
+		if zoneIndex != -1 {
+			zone = host[zoneIndex+1:]
+			host = host[:zoneIndex]
+		}
+
+		ip := net.ParseIP(host)
+		switch {
+		case ip == nil && zone == "":
+			// Not an IP address, so try it as an interface name.
+			ifaceIPv4Addrs, ifaceIPv6Addrs, err :=
+				interfaceListenAddrs(host, port)
+			if err != nil {
+				log.Warnf("`%s` is not a valid IP address or "+
+					"interface: %v", host, err)
+				continue
+			}
+			ipv4Addrs = append(ipv4Addrs, ifaceIPv4Addrs...)
+			ipv6Addrs = append(ipv6Addrs, ifaceIPv6Addrs...)
+		case ip == nil:
+			log.Warnf("`%s` is not a valid IP address", host)
+		case ip.To4() == nil:
+			ipv6Addrs = append(ipv6Addrs, addr)
+		case zone != "":
+			log.Warnf("`%s` has a zone but is not an IPv6 address",
+				addr)
+		default:
+			ipv4Addrs = append(ipv4Addrs, addr)
+		}
+	}
+	listeners := make([]net.Listener, 0,
+		len(ipv6Addrs)+len(ipv4Addrs)+len(anyAddrs)*2)
+	for _, addr := range ipv4Addrs {
+		listener, err := listen("tcp4", addr)
+		if err != nil {
+			log.Warnf("Can't listen on %s: %v", addr, err)
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+	for _, addr := range ipv6Addrs {
+		listener, err := listen("tcp6", addr)
+		if err != nil {
+			log.Warnf("Can't listen on %s: %v", addr, err)
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+
+	// Addresses for all interfaces are opened for both IP families, but it
+	// is only an error when neither can be opened since many hosts only
+	// have one of the families available.
+	for _, addr := range anyAddrs {
+		var opened int
+		for _, network := range []string{"tcp4", "tcp6"} {
+			listener, err := listen(network, addr)
+			if err != nil {
+				log.Infof("Can't listen on %s using %s, IP family "+
+					"may be unavailable: %v", addr, network, err)
+				continue
+			}
+			listeners = append(listeners, listener)
+			opened++
+		}
+		if opened == 0 {
+			log.Warnf("Can't listen on %s with either IP family", addr)
+		}
+	}
+	return listeners
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+package grpcserver
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// fakeListener is a net.Listener that never accepts connections.  It only
+// records what it was opened with.
+type fakeListener struct {
+	network string
+	addr    string
+}
+
+func (l *fakeListener) Accept() (net.Conn, error) { return nil, errors.New("closed") }
+func (l *fakeListener) Close() error              { return nil }
+func (l *fakeListener) Addr() net.Addr            { return &net.TCPAddr{} }
+
+func TestMakeListeners(t *testing.T) {
+	tests := []struct {
+		name     string
+		addrs    []string
+		noIPv6   bool
+		expected []fakeListener
+	}{
+		{
+			name:  "ipv4 and ipv6",
+			addrs: []string{"127.0.0.1:9113", "[::1]:9113"},
+			expected: []fakeListener{
+				{"tcp4", "127.0.0.1:9113"},
+				{"tcp6", "[::1]:9113"},
+			},
+		},
+		{
+			name:  "ipv6 zone",
+			addrs: []string{"[fe80::1%eth0]:9113"},
+			expected: []fakeListener{
+				{"tcp6", "[fe80::1%eth0]:9113"},
+			},
+		},
+		{
+			name:     "ipv4 with zone",
+			addrs:    []string{"[127.0.0.1%eth0]:9113"},
+			expected: []fakeListener{},
+		},
+		{
+			name:  "all interfaces",
+			addrs: []string{":9113"},
+			expected: []fakeListener{
+				{"tcp4", ":9113"},
+				{"tcp6", ":9113"},
+			},
+		},
+		{
+			name:   "all interfaces without ipv6",
+			addrs:  []string{":9113"},
+			noIPv6: true,
+			expected: []fakeListener{
+				{"tcp4", ":9113"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		listen := func(network, addr string) (net.Listener, error) {
+			if test.noIPv6 && network == "tcp6" {
+				return nil, errors.New("address family not supported")
+			}
+			return &fakeListener{network, addr}, nil
+		}
+
+		listeners := makeListeners(test.addrs, listen)
+		got := make([]fakeListener, 0, len(listeners))
+		for _, l := range listeners {
+			got = append(got, *l.(*fakeListener))
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected listeners %v, got %v", test.name,
+				test.expected, got)
+		}
+	}
+}
+
+func TestIfaceListenAddrs(t *testing.T) {
+	ipNet := func(s string) net.Addr {
+		ip, n, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.IP = ip
+		return n
+	}
+	addrs := []net.Addr{
+		ipNet("192.168.1.10/24"),
+		ipNet("2001:db8::10/64"),
+		ipNet("fe80::10/64"),
+		&net.IPAddr{IP: net.ParseIP("10.0.0.1")},
+	}
+	ipv4, ipv6, err := ifaceListenAddrs("eth0", addrs, "9113")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"192.168.1.10:9113"}; !reflect.DeepEqual(ipv4, want) {
+		t.Errorf("expected IPv4 addresses %v, got %v", want, ipv4)
+	}
+	want := []string{"[2001:db8::10]:9113", "[fe80::10%eth0]:9113"}
+	if !reflect.DeepEqual(ipv6, want) {
+		t.Errorf("expected IPv6 addresses %v, got %v", want, ipv6)
+	}
+
+	if _, _, err := ifaceListenAddrs("eth0", nil, "9113"); err == nil {
+		t.Errorf("expected an error for an interface without addresses")
+	}
+}
+
+func TestMakeListenersInterface(t *testing.T) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Skipf("unable to list interfaces: %v", err)
+	}
+	var loopback *net.Interface
+	for i := range ifaces {
+		if ifaces[i].Flags&net.FlagLoopback != 0 {
+			loopback = &ifaces[i]
+			break
+		}
+	}
+	if loopback == nil {
+		t.Skip("no loopback interface")
+	}
+	addrs, err := loopback.Addrs()
+	if err != nil || len(addrs) == 0 {
+		t.Skipf("loopback interface %s has no addresses", loopback.Name)
+	}
+	ipv4, ipv6, err := ifaceListenAddrs(loopback.Name, addrs, "9113")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := make([]fakeListener, 0, len(ipv4)+len(ipv6))
+	for _, addr := range ipv4 {
+		expected = append(expected, fakeListener{"tcp4", addr})
+	}
+	for _, addr := range ipv6 {
+		expected = append(expected, fakeListener{"tcp6", addr})
+	}
+
+	listen := func(network, addr string) (net.Listener, error) {
+		return &fakeListener{network, addr}, nil
+	}
+	listeners := makeListeners([]string{net.JoinHostPort(loopback.Name,
+		"9113")}, listen)
+	got := make([]fakeListener, 0, len(listeners))
+	for _, l := range listeners {
+		got = append(got, *l.(*fakeListener))
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected listeners %v, got %v", expected, got)
+	}
+
+	listeners = makeListeners([]string{"nosuchinterface0:9113"}, listen)
+	if len(listeners) != 0 {
+		t.Errorf("expected no listeners for an unknown interface, got %d",
+			len(listeners))
+	}
+}
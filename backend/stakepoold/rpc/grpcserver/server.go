@@ -0,0 +1,157 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Copyright (c) 2015-2017 The Decred developers
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package grpcserver runs the gRPC server of stakepoold.  It serves the TLS
+// keypair, renewing autogenerated certificates, and listens on the configured
+// addresses or on the sockets passed by systemd.  The services and the
+// interceptors authorizing and limiting calls are provided by the caller.
+package grpcserver
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/systemd"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+)
+
+// Config configures a Server.
+type Config struct {
+	// Listeners are the normalized addresses listened on when stakepoold
+	// wasn't passed sockets by systemd.
+	Listeners []string
+
+	// CertFile and KeyFile are the paths of the TLS keypair.  A keypair
+	// is generated when the key doesn't exist.
+	CertFile string
+	KeyFile  string
+
+	// CertRenewal is how long before they expire autogenerated
+	// certificates are renewed.
+	CertRenewal time.Duration
+
+	Keepalive keepalive.ServerParameters
+
+	// Reflection registers the gRPC reflection service.
+	Reflection bool
+
+	UnaryInterceptor  grpc.UnaryServerInterceptor
+	StreamInterceptor grpc.StreamServerInterceptor
+}
+
+// Server is a gRPC server with a TLS keypair that can be replaced while it
+// runs.
+type Server struct {
+	cfg     *Config
+	keyPair *keyPair
+	server  *grpc.Server
+}
+
+// New loads or generates the keypair of cfg and returns a Server using it.
+// Services are registered with GRPCServer before calling Serve.
+func New(cfg *Config) (*Server, error) {
+	kp, err := openKeyPair(cfg.CertFile, cfg.KeyFile, cfg.CertRenewal)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := newKeyPair(kp, cfg.CertFile, cfg.KeyFile, cfg.CertRenewal)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := credentials.NewTLS(&tls.Config{
+		GetCertificate: keys.getCertificate,
+	})
+	// Calls the client compressed with gzip, which is registered by
+	// importing its package, are answered with compressed responses, so
+	// clients choose the calls worth compressing.
+	serverOpts := []grpc.ServerOption{
+		grpc.Creds(creds),
+		grpc.KeepaliveParams(cfg.Keepalive),
+	}
+	if cfg.UnaryInterceptor != nil {
+		serverOpts = append(serverOpts,
+			grpc.UnaryInterceptor(cfg.UnaryInterceptor))
+	}
+	if cfg.StreamInterceptor != nil {
+		serverOpts = append(serverOpts,
+			grpc.StreamInterceptor(cfg.StreamInterceptor))
+	}
+	server := grpc.NewServer(serverOpts...)
+	if cfg.Reflection {
+		reflection.Register(server)
+		log.Info("gRPC reflection service registered")
+	}
+
+	return &Server{
+		cfg:     cfg,
+		keyPair: keys,
+		server:  server,
+	}, nil
+}
+
+// GRPCServer returns the gRPC server services are registered with.
+func (s *Server) GRPCServer() *grpc.Server {
+	return s.server
+}
+
+// RotateCertificate generates a new keypair, writes it to the configured
+// files and starts serving it.  The new certificate is returned in PEM format
+// along with its expiration time so it can be distributed to clients.
+func (s *Server) RotateCertificate() ([]byte, time.Time, error) {
+	return s.keyPair.rotate()
+}
+
+// Serve listens on the sockets passed by systemd or, without them, on the
+// configured addresses and serves gRPC calls on them in the background.
+// Autogenerated certificates are renewed until quit is closed.
+func (s *Server) Serve(quit <-chan struct{}) error {
+	listeners, err := activatedListeners()
+	if err != nil {
+		return err
+	}
+	if listeners == nil {
+		listeners = makeListeners(s.cfg.Listeners, net.Listen)
+	}
+	if len(listeners) == 0 {
+		return errors.New("failed to create listeners for RPC server")
+	}
+
+	go s.keyPair.renewHandler(quit)
+	for _, lis := range listeners {
+		lis := lis
+		go func() {
+			log.Infof("gRPC server listening on %s", lis.Addr())
+			err := s.server.Serve(lis)
+			log.Tracef("Finished serving gRPC: %v", err)
+		}()
+	}
+	return nil
+}
+
+// activatedListeners returns the sockets passed by systemd socket activation,
+// which are served instead of binding to the rpclisten addresses, or nil when
+// stakepoold wasn't socket activated.
+func activatedListeners() ([]net.Listener, error) {
+	activated, err := systemd.Listeners()
+	if err != nil {
+		return nil, err
+	}
+	var listeners []net.Listener
+	for name, ls := range activated {
+		log.Infof("Using %d %s socket(s) passed by systemd", len(ls),
+			name)
+		listeners = append(listeners, ls...)
+	}
+	return listeners, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcclient
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcrpcclient"
+	"github.com/coolsnady/hcutil"
+)
+
+// ChainSource is the chain data stakepoold needs.  It is provided by hcd over
+// RPC but may be implemented by other data sources such as an indexer, or by
+// test doubles.
+type ChainSource interface {
+	GetBestBlock() (*chainhash.Hash, int64, error)
+	GetBlockHash(blockHeight int64) (*chainhash.Hash, error)
+	GetBlockHeader(blockHash *chainhash.Hash) (*wire.BlockHeader, error)
+	GetCurrentNet() (wire.CurrencyNet, error)
+	SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error)
+
+	// GetRawTransaction returns a transaction of the mempool or, when hcd
+	// keeps a transaction index, of the chain.
+	GetRawTransaction(txHash *chainhash.Hash) (*wire.MsgTx, error)
+
+	// ExistsLiveTickets returns whether each of the tickets is live.
+	ExistsLiveTickets(tickets []*chainhash.Hash) ([]bool, error)
+	// LiveTicketsForAddress returns the live tickets paying the address.
+	LiveTicketsForAddress(address string) ([]chainhash.Hash, error)
+
+	// Disconnected returns whether the connection to the source was lost.
+	Disconnected() bool
+	// Shutdown closes the connection to the source.
+	Shutdown()
+
+	// NotifyChain registers for the notifications delivered to the
+	// ChainNotifications the source was created with.
+	NotifyChain() error
+}
+
+// ChainNotifications receives the chain notifications stakepoold acts on.
+type ChainNotifications interface {
+	BlockConnected(blockHeader []byte)
+	NewTickets(blockHash *chainhash.Hash, blockHeight int64,
+		tickets []*chainhash.Hash)
+	Reorganization(oldHash *chainhash.Hash, oldHeight int64,
+		newHash *chainhash.Hash, newHeight int64)
+	SpentAndMissedTickets(blockHash *chainhash.Hash, blockHeight int64,
+		tickets map[chainhash.Hash]bool)
+	WinningTickets(blockHash *chainhash.Hash, blockHeight int64,
+		winningTickets []*chainhash.Hash)
+}
+
+// rpcChainSource is a ChainSource backed by an hcd RPC connection.
+type rpcChainSource struct {
+	*hcrpcclient.Client
+}
+
+// ExistsLiveTickets asks hcd whether each of the tickets is live.
+func (s *rpcChainSource) ExistsLiveTickets(tickets []*chainhash.Hash) ([]bool, error) {
+	bitsetHex, err := s.Client.ExistsLiveTickets(tickets)
+	if err != nil {
+		return nil, err
+	}
+	return decodeLiveBitset(bitsetHex, len(tickets))
+}
+
+// decodeLiveBitset decodes the hex bitset hcd returns for n tickets, where bit
+// i%8 of byte i/8 is set when ticket i is live.
+func decodeLiveBitset(bitsetHex string, n int) ([]bool, error) {
+	bitset, err := hex.DecodeString(bitsetHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid existslivetickets bitset: %v", err)
+	}
+	if len(bitset) < (n+7)/8 {
+		return nil, fmt.Errorf("existslivetickets returned %d bytes for "+
+			"%d tickets", len(bitset), n)
+	}
+	live := make([]bool, n)
+	for i := range live {
+		live[i] = bitset[i/8]&(1<<uint(i%8)) != 0
+	}
+	return live, nil
+}
+
+// GetRawTransaction asks hcd for the transaction with txHash.
+func (s *rpcChainSource) GetRawTransaction(txHash *chainhash.Hash) (*wire.MsgTx, error) {
+	tx, err := s.Client.GetRawTransaction(txHash)
+	if err != nil {
+		return nil, err
+	}
+	return tx.MsgTx(), nil
+}
+
+// LiveTicketsForAddress asks hcd for the live tickets whose voting rights
+// belong to the address.
+func (s *rpcChainSource) LiveTicketsForAddress(address string) ([]chainhash.Hash, error) {
+	addr, err := hcutil.DecodeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Client.TicketsForAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	tickets := make([]chainhash.Hash, 0, len(res.Tickets))
+	for _, t := range res.Tickets {
+		hash, err := chainhash.NewHashFromStr(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ticket hash %q: %v", t, err)
+		}
+		tickets = append(tickets, *hash)
+	}
+	return tickets, nil
+}
+
+// NotifyChain registers for block, winning ticket, new ticket and spent and
+// missed ticket notifications.
+func (s *rpcChainSource) NotifyChain() error {
+	if err := s.NotifyBlocks(); err != nil {
+		return fmt.Errorf("block notifications: %v", err)
+	}
+	if err := s.NotifyWinningTickets(); err != nil {
+		return fmt.Errorf("winning tickets notifications: %v", err)
+	}
+	if err := s.NotifyNewTickets(); err != nil {
+		return fmt.Errorf("new tickets notifications: %v", err)
+	}
+	if err := s.NotifySpentAndMissedTickets(); err != nil {
+		return fmt.Errorf("spent/missed tickets notifications: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package rpcclient connects stakepoold to the JSON-RPC servers of hcd and
+// hcwallet.
+package rpcclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcrpcclient"
+)
+
+var requiredChainServerAPI = Semver{Major: 3, Minor: 1, Patch: 0}
+var requiredWalletAPI = Semver{Major: 5, Minor: 0, Patch: 0}
+
+// Config describes how to connect to a JSON-RPC server.
+type Config struct {
+	Host      string
+	User      string
+	Password  string
+	CertPath  string
+	Proxy     string
+	ProxyUser string
+	ProxyPass string
+}
+
+// connConfig returns the websocket connection config for cfg.  The
+// connection isn't reconnected automatically as the caller is expected to
+// catch up on what it missed when it reconnects.
+func (cfg *Config) connConfig(cert []byte) *hcrpcclient.ConnConfig {
+	return &hcrpcclient.ConnConfig{
+		Host:                 cfg.Host,
+		Endpoint:             "ws", // websocket
+		User:                 cfg.User,
+		Pass:                 cfg.Password,
+		Certificates:         cert,
+		Proxy:                cfg.Proxy,
+		ProxyUser:            cfg.ProxyUser,
+		ProxyPass:            cfg.ProxyPass,
+		DisableAutoReconnect: true,
+	}
+}
+
+// ConnectNode connects to hcd and returns it as the ChainSource delivering
+// notifications to n.  n may be nil when NotifyChain is never called.
+func ConnectNode(cfg *Config, n ChainNotifications) (ChainSource, Semver, error) {
+	var nodeVer Semver
+
+	hcdCert, err := ioutil.ReadFile(cfg.CertPath)
+	if err != nil {
+		log.Errorf("Failed to read hcd cert file at %s: %s\n",
+			cfg.CertPath, err.Error())
+		return nil, nodeVer, err
+	}
+
+	log.Debugf("Attempting to connect to hcd RPC %s as user %s "+
+		"using certificate located in %s",
+		cfg.Host, cfg.User, cfg.CertPath)
+
+	hcdClient, err := hcrpcclient.New(cfg.connConfig(hcdCert),
+		nodeNtfnHandlers(n))
+	if err != nil {
+		log.Errorf("Failed to start hcd RPC client: %s\n", err.Error())
+		return nil, nodeVer, err
+	}
+
+	// Ensure the RPC server has a compatible API version.
+	ver, err := hcdClient.Version()
+	if err != nil {
+		log.Error("Unable to get RPC version: ", err)
+		hcdClient.Shutdown()
+		return nil, nodeVer, fmt.Errorf("Unable to get node RPC version")
+	}
+
+	hcdVer := ver["hcdjsonrpcapi"]
+	nodeVer = Semver{hcdVer.Major, hcdVer.Minor, hcdVer.Patch}
+
+	if !SemverCompatible(requiredChainServerAPI, nodeVer) {
+		hcdClient.Shutdown()
+		return nil, nodeVer, fmt.Errorf("Node JSON-RPC server does not have "+
+			"a compatible API version. Advertises %v but require %v",
+			nodeVer, requiredChainServerAPI)
+	}
+
+	return &rpcChainSource{hcdClient}, nodeVer, nil
+}
+
+// ConnectWallet connects to hcwallet.  An incompatible API version is only
+// logged.
+func ConnectWallet(cfg *Config) (WalletSource, Semver, error) {
+	var walletVer Semver
+
+	hxwCert, err := ioutil.ReadFile(cfg.CertPath)
+	if err != nil {
+		log.Errorf("Failed to read hcwallet cert file at %s: %s\n",
+			cfg.CertPath, err.Error())
+		return nil, walletVer, err
+	}
+
+	log.Infof("Attempting to connect to hcwallet RPC %s as user %s "+
+		"using certificate located in %s",
+		cfg.Host, cfg.User, cfg.CertPath)
+
+	hxwClient, err := hcrpcclient.New(cfg.connConfig(hxwCert),
+		walletNtfnHandlers())
+	if err != nil {
+		log.Errorf("Verify that username and password is correct and that "+
+			"rpc.cert is for your wallet: %v", cfg.CertPath)
+		return nil, walletVer, err
+	}
+
+	// Ensure the wallet RPC server has a compatible API version.
+	ver, err := hxwClient.Version()
+	if err != nil {
+		log.Error("Unable to get RPC version: ", err)
+		hxwClient.Shutdown()
+		return nil, walletVer, fmt.Errorf("Unable to get node RPC version")
+	}
+
+	hxwVer := ver["hcwalletjsonrpcapi"]
+	walletVer = Semver{hxwVer.Major, hxwVer.Minor, hxwVer.Patch}
+
+	if !SemverCompatible(requiredWalletAPI, walletVer) {
+		log.Warnf("Node JSON-RPC server %v does not have "+
+			"a compatible API version. Advertizes %v but require %v",
+			cfg.Host, walletVer, requiredWalletAPI)
+	}
+
+	return &rpcWalletSource{hxwClient}, walletVer, nil
+}
+
+// nodeNtfnHandlers returns the hcrpcclient notification handlers that pass
+// the node notifications on to n.
+func nodeNtfnHandlers(n ChainNotifications) *hcrpcclient.NotificationHandlers {
+	return &hcrpcclient.NotificationHandlers{
+		OnBlockConnected: func(blockHeader []byte, transactions [][]byte) {
+			n.BlockConnected(blockHeader)
+		},
+		OnNewTickets: func(blockHash *chainhash.Hash, blockHeight int64, stakeDifficulty int64, tickets []*chainhash.Hash) {
+			n.NewTickets(blockHash, blockHeight, tickets)
+		},
+		OnReorganization: func(oldHash *chainhash.Hash, oldHeight int32, newHash *chainhash.Hash, newHeight int32) {
+			n.Reorganization(oldHash, int64(oldHeight), newHash, int64(newHeight))
+		},
+		OnSpentAndMissedTickets: func(blockHash *chainhash.Hash, blockHeight int64, stakeDifficulty int64, tickets map[chainhash.Hash]bool) {
+			n.SpentAndMissedTickets(blockHash, blockHeight, tickets)
+		},
+		OnWinningTickets: func(blockHash *chainhash.Hash, blockHeight int64, winningTickets []*chainhash.Hash) {
+			n.WinningTickets(blockHash, blockHeight, winningTickets)
+		},
+	}
+}
+
+func walletNtfnHandlers() *hcrpcclient.NotificationHandlers {
+	return &hcrpcclient.NotificationHandlers{
+		OnUnknownNotification: func(method string, params []json.RawMessage) {
+			log.Infof("ignoring notification %v", method)
+		},
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+package rpcclient
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+)
+
+func TestSemverCompatible(t *testing.T) {
+	tests := []struct {
+		required, actual Semver
+		compatible       bool
+	}{
+		{Semver{3, 1, 0}, Semver{3, 1, 0}, true},
+		{Semver{3, 1, 0}, Semver{3, 2, 0}, true},
+		{Semver{3, 1, 0}, Semver{3, 1, 5}, true},
+		{Semver{3, 1, 1}, Semver{3, 1, 0}, false},
+		{Semver{3, 1, 0}, Semver{3, 0, 9}, false},
+		{Semver{3, 1, 0}, Semver{4, 1, 0}, false},
+		{Semver{3, 1, 0}, Semver{2, 9, 9}, false},
+	}
+	for _, test := range tests {
+		got := SemverCompatible(test.required, test.actual)
+		if got != test.compatible {
+			t.Errorf("SemverCompatible(%v, %v): expected %v, got %v",
+				test.required, test.actual, test.compatible, got)
+		}
+	}
+}
+
+func TestDecodeLiveBitset(t *testing.T) {
+	tests := []struct {
+		name   string
+		bitset string
+		n      int
+		live   []bool
+		err    bool
+	}{
+		{"none", "", 0, []bool{}, false},
+		{"first", "01", 3, []bool{true, false, false}, false},
+		{"second byte", "0002", 10, []bool{false, false, false,
+			false, false, false, false, false, false, true}, false},
+		{"all of a byte", "ff", 8, []bool{true, true, true, true,
+			true, true, true, true}, false},
+		{"short", "ff", 9, nil, true},
+		{"invalid hex", "zz", 1, nil, true},
+	}
+	for _, test := range tests {
+		live, err := decodeLiveBitset(test.bitset, test.n)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(live, test.live) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.live,
+				live)
+		}
+	}
+}
+
+// fakeNotifications is a ChainNotifications recording the notifications it
+// receives.
+type fakeNotifications struct {
+	calls []string
+}
+
+func (n *fakeNotifications) BlockConnected(blockHeader []byte) {
+	n.calls = append(n.calls, "BlockConnected")
+}
+
+func (n *fakeNotifications) NewTickets(blockHash *chainhash.Hash,
+	blockHeight int64, tickets []*chainhash.Hash) {
+	n.calls = append(n.calls, "NewTickets")
+}
+
+func (n *fakeNotifications) Reorganization(oldHash *chainhash.Hash,
+	oldHeight int64, newHash *chainhash.Hash, newHeight int64) {
+	n.calls = append(n.calls, "Reorganization")
+}
+
+func (n *fakeNotifications) SpentAndMissedTickets(blockHash *chainhash.Hash,
+	blockHeight int64, tickets map[chainhash.Hash]bool) {
+	n.calls = append(n.calls, "SpentAndMissedTickets")
+}
+
+func (n *fakeNotifications) WinningTickets(blockHash *chainhash.Hash,
+	blockHeight int64, winningTickets []*chainhash.Hash) {
+	n.calls = append(n.calls, "WinningTickets")
+}
+
+func TestNodeNtfnHandlers(t *testing.T) {
+	n := &fakeNotifications{}
+	h := nodeNtfnHandlers(n)
+	hash := &chainhash.Hash{}
+
+	h.OnBlockConnected(nil, nil)
+	h.OnNewTickets(hash, 1, 0, nil)
+	h.OnReorganization(hash, 1, hash, 1)
+	h.OnSpentAndMissedTickets(hash, 1, 0, nil)
+	h.OnWinningTickets(hash, 1, nil)
+
+	want := []string{"BlockConnected", "NewTickets", "Reorganization",
+		"SpentAndMissedTickets", "WinningTickets"}
+	if !reflect.DeepEqual(n.calls, want) {
+		t.Errorf("expected notifications %v, got %v", want, n.calls)
+	}
+}
+
+func TestConnectMissingCert(t *testing.T) {
+	cfg := &Config{Host: "127.0.0.1:1", CertPath: "/nonexistent/rpc.cert"}
+	if _, _, err := ConnectNode(cfg, nil); err == nil {
+		t.Errorf("ConnectNode: expected an error for a missing cert")
+	}
+	if _, _, err := ConnectWallet(cfg); err == nil {
+		t.Errorf("ConnectWallet: expected an error for a missing cert")
+	}
+}
+
+func TestConnConfigNoAutoReconnect(t *testing.T) {
+	cfg := &Config{Host: "127.0.0.1:12009", User: "u", Password: "p"}
+	cc := cfg.connConfig(nil)
+	if !cc.DisableAutoReconnect {
+		t.Errorf("expected automatic reconnection to be disabled")
+	}
+	if cc.Endpoint != "ws" {
+		t.Errorf("expected the websocket endpoint, got %q", cc.Endpoint)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package rpcclienttest provides in-memory implementations of the rpcclient
+// ChainSource and WalletSource interfaces so stakepoold can be tested without
+// hcd and hcwallet.
+package rpcclienttest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+)
+
+// Node is a ChainSource serving a chain kept in memory.  The transactions
+// sent to it are recorded.  It is safe for concurrent access.
+type Node struct {
+	mtx          sync.Mutex
+	net          wire.CurrencyNet
+	hashes       map[int64]chainhash.Hash // [height]
+	headers      map[chainhash.Hash]*wire.BlockHeader
+	live         map[chainhash.Hash]struct{}
+	addrTickets  map[string][]chainhash.Hash
+	sent         []*wire.MsgTx
+	txs          map[chainhash.Hash]*wire.MsgTx
+	sendErr      error
+	disconnected bool
+}
+
+var _ rpcclient.ChainSource = (*Node)(nil)
+
+// NewNode returns a Node on the network net without any blocks.
+func NewNode(net wire.CurrencyNet) *Node {
+	return &Node{
+		net:         net,
+		hashes:      make(map[int64]chainhash.Hash),
+		headers:     make(map[chainhash.Hash]*wire.BlockHeader),
+		live:        make(map[chainhash.Hash]struct{}),
+		addrTickets: make(map[string][]chainhash.Hash),
+		txs:         make(map[chainhash.Hash]*wire.MsgTx),
+	}
+}
+
+// AddTransaction adds a transaction as if it was in the mempool.
+func (n *Node) AddTransaction(tx *wire.MsgTx) {
+	n.mtx.Lock()
+	n.txs[tx.TxHash()] = tx
+	n.mtx.Unlock()
+}
+
+// SetLiveTickets replaces the tickets ExistsLiveTickets reports as live.
+func (n *Node) SetLiveTickets(tickets ...chainhash.Hash) {
+	n.mtx.Lock()
+	n.live = make(map[chainhash.Hash]struct{}, len(tickets))
+	for _, ticket := range tickets {
+		n.live[ticket] = struct{}{}
+	}
+	n.mtx.Unlock()
+}
+
+// SetAddressTickets sets the tickets paying address.  LiveTicketsForAddress
+// reports those set as live with SetLiveTickets.
+func (n *Node) SetAddressTickets(address string, tickets ...chainhash.Hash) {
+	n.mtx.Lock()
+	n.addrTickets[address] = tickets
+	n.mtx.Unlock()
+}
+
+// AddBlock connects the block to the main chain at its height, replacing
+// the block there.
+func (n *Node) AddBlock(header *wire.BlockHeader) {
+	hash := header.BlockHash()
+	n.mtx.Lock()
+	n.hashes[int64(header.Height)] = hash
+	n.headers[hash] = header
+	n.mtx.Unlock()
+}
+
+// SetBlockHash puts a block without a header at height in the main chain.
+func (n *Node) SetBlockHash(height int64, hash chainhash.Hash) {
+	n.mtx.Lock()
+	n.hashes[height] = hash
+	n.mtx.Unlock()
+}
+
+// SetSendError makes SendRawTransaction fail with err.  A nil err makes it
+// succeed again.
+func (n *Node) SetSendError(err error) {
+	n.mtx.Lock()
+	n.sendErr = err
+	n.mtx.Unlock()
+}
+
+// SetDisconnected sets what Disconnected returns.
+func (n *Node) SetDisconnected(disconnected bool) {
+	n.mtx.Lock()
+	n.disconnected = disconnected
+	n.mtx.Unlock()
+}
+
+// Sent returns the transactions sent with SendRawTransaction.
+func (n *Node) Sent() []*wire.MsgTx {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return append([]*wire.MsgTx(nil), n.sent...)
+}
+
+// GetBestBlock returns the highest block of the main chain.
+func (n *Node) GetBestBlock() (*chainhash.Hash, int64, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if len(n.hashes) == 0 {
+		return nil, 0, errors.New("no blocks")
+	}
+	var best int64 = -1
+	for height := range n.hashes {
+		if height > best {
+			best = height
+		}
+	}
+	hash := n.hashes[best]
+	return &hash, best, nil
+}
+
+// GetBlockHash returns the hash of the main chain block at blockHeight.
+func (n *Node) GetBlockHash(blockHeight int64) (*chainhash.Hash, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	hash, ok := n.hashes[blockHeight]
+	if !ok {
+		return nil, fmt.Errorf("no block at height %d", blockHeight)
+	}
+	return &hash, nil
+}
+
+// GetBlockHeader returns the header of a block added with AddBlock.
+func (n *Node) GetBlockHeader(blockHash *chainhash.Hash) (*wire.BlockHeader, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	header, ok := n.headers[*blockHash]
+	if !ok {
+		return nil, fmt.Errorf("block %v not found", blockHash)
+	}
+	return header, nil
+}
+
+// GetCurrentNet returns the network of the node.
+func (n *Node) GetCurrentNet() (wire.CurrencyNet, error) {
+	return n.net, nil
+}
+
+// SendRawTransaction records tx and returns its hash.
+func (n *Node) SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if n.sendErr != nil {
+		return nil, n.sendErr
+	}
+	n.sent = append(n.sent, tx)
+	hash := tx.TxHash()
+	n.txs[hash] = tx
+	return &hash, nil
+}
+
+// GetRawTransaction returns a transaction sent to the node or added with
+// AddTransaction.
+func (n *Node) GetRawTransaction(txHash *chainhash.Hash) (*wire.MsgTx, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	tx, ok := n.txs[*txHash]
+	if !ok {
+		return nil, fmt.Errorf("transaction %v not found", txHash)
+	}
+	return tx, nil
+}
+
+// ExistsLiveTickets returns whether each ticket was set with SetLiveTickets.
+func (n *Node) ExistsLiveTickets(tickets []*chainhash.Hash) ([]bool, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	live := make([]bool, len(tickets))
+	for i, ticket := range tickets {
+		_, live[i] = n.live[*ticket]
+	}
+	return live, nil
+}
+
+// LiveTicketsForAddress returns the live tickets set for address with
+// SetAddressTickets.
+func (n *Node) LiveTicketsForAddress(address string) ([]chainhash.Hash, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	var tickets []chainhash.Hash
+	for _, ticket := range n.addrTickets[address] {
+		if _, ok := n.live[ticket]; ok {
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets, nil
+}
+
+// Disconnected returns what was set with SetDisconnected.
+func (n *Node) Disconnected() bool {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return n.disconnected
+}
+
+// Shutdown disconnects the node.
+func (n *Node) Shutdown() {
+	n.SetDisconnected(true)
+}
+
+// NotifyChain does nothing.  Tests deliver notifications themselves.
+func (n *Node) NotifyChain() error {
+	return nil
+}
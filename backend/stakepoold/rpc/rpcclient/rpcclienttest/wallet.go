@@ -0,0 +1,326 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcclienttest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+)
+
+// ErrNoTxInfo is returned by GetTransaction for transactions the wallet
+// doesn't know, like hcwallet does.
+var ErrNoTxInfo = errors.New("-5: No information for transaction")
+
+// ErrPassphrase is returned by WalletPassphrase for a passphrase other than
+// the one set with SetPassphrase, like hcwallet does.
+var ErrPassphrase = errors.New("-14: invalid passphrase for master private key")
+
+// ErrLocked is returned by GenerateVote while a wallet with a passphrase is
+// locked, like hcwallet does.
+var ErrLocked = errors.New("-13: the wallet is locked")
+
+// VoteReward is the value of the stakebase input of the votes generated by
+// Wallet, in atoms.
+const VoteReward = 150000000
+
+// ImportedScript is a script imported with ImportScriptRescanFrom.
+type ImportedScript struct {
+	Script   []byte
+	Rescan   bool
+	ScanFrom int
+}
+
+// Wallet is a WalletSource with tickets and transactions kept in memory.
+// The votes it generates and the scripts imported into it are recorded.  It
+// is safe for concurrent access.
+type Wallet struct {
+	mtx          sync.Mutex
+	info         dcrjson.WalletInfoResult
+	bestHash     chainhash.Hash
+	bestHeight   int64
+	tickets      []*chainhash.Hash
+	txs          map[chainhash.Hash]*dcrjson.GetTransactionResult
+	voteErrs     map[chainhash.Hash]error
+	votes        []*chainhash.Hash
+	scripts      [][]byte
+	imported     []ImportedScript
+	rescans      []int64
+	rescanHook   func(beginHeight int64) error
+	passphrase   string
+	unlocks      int
+	disconnected bool
+}
+
+var _ rpcclient.WalletSource = (*Wallet)(nil)
+
+// NewWallet returns a Wallet without tickets whose WalletInfo returns info.
+func NewWallet(info dcrjson.WalletInfoResult) *Wallet {
+	return &Wallet{
+		info:     info,
+		txs:      make(map[chainhash.Hash]*dcrjson.GetTransactionResult),
+		voteErrs: make(map[chainhash.Hash]error),
+	}
+}
+
+// AddTicket adds a ticket owned by the wallet along with its transaction.
+func (w *Wallet) AddTicket(ticket *chainhash.Hash, tx *dcrjson.GetTransactionResult) {
+	w.mtx.Lock()
+	w.tickets = append(w.tickets, ticket)
+	w.txs[*ticket] = tx
+	w.mtx.Unlock()
+}
+
+// AddTransaction adds a transaction that is not a ticket of the wallet.
+func (w *Wallet) AddTransaction(hash *chainhash.Hash, tx *dcrjson.GetTransactionResult) {
+	w.mtx.Lock()
+	w.txs[*hash] = tx
+	w.mtx.Unlock()
+}
+
+// AddScript adds a redeem script as if it had been imported before.
+func (w *Wallet) AddScript(script []byte) {
+	w.mtx.Lock()
+	w.scripts = append(w.scripts, script)
+	w.mtx.Unlock()
+}
+
+// SetVoteError makes GenerateVote fail with err for ticket.
+func (w *Wallet) SetVoteError(ticket *chainhash.Hash, err error) {
+	w.mtx.Lock()
+	w.voteErrs[*ticket] = err
+	w.mtx.Unlock()
+}
+
+// SetBestBlock sets the block GetBestBlock returns.
+func (w *Wallet) SetBestBlock(hash *chainhash.Hash, height int64) {
+	w.mtx.Lock()
+	w.bestHash = *hash
+	w.bestHeight = height
+	w.mtx.Unlock()
+}
+
+// SetDisconnected sets what Disconnected returns.
+func (w *Wallet) SetDisconnected(disconnected bool) {
+	w.mtx.Lock()
+	w.disconnected = disconnected
+	w.mtx.Unlock()
+}
+
+// SetPassphrase locks the wallet with passphrase.  Its WalletInfo reports
+// whether it is unlocked from then on, and it only generates votes while
+// unlocked.
+func (w *Wallet) SetPassphrase(passphrase string) {
+	w.mtx.Lock()
+	w.passphrase = passphrase
+	w.info.Unlocked = false
+	w.mtx.Unlock()
+}
+
+// Unlocks returns how often the wallet was unlocked with WalletPassphrase.
+func (w *Wallet) Unlocks() int {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.unlocks
+}
+
+// SetRescanHook makes RescanWallet call hook and return its error.
+func (w *Wallet) SetRescanHook(hook func(beginHeight int64) error) {
+	w.mtx.Lock()
+	w.rescanHook = hook
+	w.mtx.Unlock()
+}
+
+// Votes returns the tickets votes were generated for.
+func (w *Wallet) Votes() []*chainhash.Hash {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return append([]*chainhash.Hash(nil), w.votes...)
+}
+
+// Imported returns the scripts imported with ImportScriptRescanFrom.
+func (w *Wallet) Imported() []ImportedScript {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return append([]ImportedScript(nil), w.imported...)
+}
+
+// Rescans returns the heights RescanWallet was called with.
+func (w *Wallet) Rescans() []int64 {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return append([]int64(nil), w.rescans...)
+}
+
+// GenerateVote returns a transaction standing in for the vote of sstxHash.
+// Its only input is a stakebase worth VoteReward.  Like a real vote, its
+// first output commits to the block voted on and its second output holds the
+// vote bits, little-endian, followed by the extended vote bits.  Its last
+// output pays to a script holding the ticket hash so every vote is unique.
+func (w *Wallet) GenerateVote(blockHash *chainhash.Hash, height int64,
+	sstxHash *chainhash.Hash, voteBits uint16,
+	voteBitsExt string) (*dcrjson.GenerateVoteResult, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.passphrase != "" && !w.info.Unlocked {
+		return nil, ErrLocked
+	}
+	if err := w.voteErrs[*sstxHash]; err != nil {
+		return nil, err
+	}
+
+	ext, err := hex.DecodeString(voteBitsExt)
+	if err != nil {
+		return nil, err
+	}
+	blockRef := make([]byte, chainhash.HashSize+4)
+	copy(blockRef, blockHash[:])
+	binary.LittleEndian.PutUint32(blockRef[chainhash.HashSize:], uint32(height))
+	bits := make([]byte, 2, 2+len(ext))
+	binary.LittleEndian.PutUint16(bits, voteBits)
+	bits = append(bits, ext...)
+
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(&wire.TxIn{ValueIn: VoteReward})
+	tx.AddTxOut(wire.NewTxOut(0, nullDataScript(blockRef)))
+	tx.AddTxOut(wire.NewTxOut(0, nullDataScript(bits)))
+	tx.AddTxOut(wire.NewTxOut(0, sstxHash.CloneBytes()))
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	w.votes = append(w.votes, sstxHash)
+	return &dcrjson.GenerateVoteResult{Hex: hex.EncodeToString(buf.Bytes())}, nil
+}
+
+// nullDataScript returns an OP_RETURN script pushing data, which must not be
+// longer than 75 bytes.
+func nullDataScript(data []byte) []byte {
+	const opReturn = 0x6a
+	return append([]byte{opReturn, byte(len(data))}, data...)
+}
+
+// GetBestBlock returns the block set with SetBestBlock.
+func (w *Wallet) GetBestBlock() (*chainhash.Hash, int64, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	hash := w.bestHash
+	return &hash, w.bestHeight, nil
+}
+
+// GetTickets returns the tickets added with AddTicket.
+func (w *Wallet) GetTickets(includeImmature bool) ([]*chainhash.Hash, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return append([]*chainhash.Hash(nil), w.tickets...), nil
+}
+
+// GetTransaction returns a transaction added with AddTicket or
+// AddTransaction, or ErrNoTxInfo.
+func (w *Wallet) GetTransaction(txHash *chainhash.Hash) (*dcrjson.GetTransactionResult, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	tx, ok := w.txs[*txHash]
+	if !ok {
+		return nil, ErrNoTxInfo
+	}
+	return tx, nil
+}
+
+// transactionFuture is the already known result of GetTransactionAsync.
+type transactionFuture struct {
+	tx  *dcrjson.GetTransactionResult
+	err error
+}
+
+func (f transactionFuture) Receive() (*dcrjson.GetTransactionResult, error) {
+	return f.tx, f.err
+}
+
+// GetTransactionAsync looks up the transaction right away.
+func (w *Wallet) GetTransactionAsync(txHash *chainhash.Hash) rpcclient.TransactionFuture {
+	tx, err := w.GetTransaction(txHash)
+	return transactionFuture{tx, err}
+}
+
+// ImportScriptRescanFrom records the import and adds the script.
+func (w *Wallet) ImportScriptRescanFrom(script []byte, rescan bool, scanFrom int) error {
+	w.mtx.Lock()
+	w.scripts = append(w.scripts, script)
+	w.imported = append(w.imported, ImportedScript{script, rescan, scanFrom})
+	w.mtx.Unlock()
+	return nil
+}
+
+// ListScripts returns the scripts added with AddScript or imported.
+func (w *Wallet) ListScripts() ([][]byte, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return append([][]byte(nil), w.scripts...), nil
+}
+
+// RescanWallet records the rescan and calls the hook set with SetRescanHook,
+// if any.
+func (w *Wallet) RescanWallet(beginHeight int64) error {
+	w.mtx.Lock()
+	w.rescans = append(w.rescans, beginHeight)
+	hook := w.rescanHook
+	w.mtx.Unlock()
+	if hook != nil {
+		return hook(beginHeight)
+	}
+	return nil
+}
+
+// WalletInfo returns the info the wallet was created with.
+func (w *Wallet) WalletInfo() (*dcrjson.WalletInfoResult, error) {
+	w.mtx.Lock()
+	info := w.info
+	w.mtx.Unlock()
+	return &info, nil
+}
+
+// WalletLock locks the wallet.
+func (w *Wallet) WalletLock() error {
+	w.mtx.Lock()
+	w.info.Unlocked = false
+	w.mtx.Unlock()
+	return nil
+}
+
+// WalletPassphrase unlocks the wallet when passphrase is the one set with
+// SetPassphrase.  The wallet stays unlocked until WalletLock is called,
+// whatever timeoutSecs is.
+func (w *Wallet) WalletPassphrase(passphrase string, timeoutSecs int64) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if passphrase != w.passphrase {
+		return ErrPassphrase
+	}
+	w.info.Unlocked = true
+	w.unlocks++
+	return nil
+}
+
+// Disconnected returns what was set with SetDisconnected.
+func (w *Wallet) Disconnected() bool {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.disconnected
+}
+
+// Shutdown disconnects the wallet.
+func (w *Wallet) Shutdown() {
+	w.SetDisconnected(true)
+}
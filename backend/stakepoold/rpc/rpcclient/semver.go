@@ -0,0 +1,32 @@
+// Copyright (c) 2016 The Decred developers
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcclient
+
+import "fmt"
+
+// Semver is the version of a JSON-RPC API.
+type Semver struct {
+	Major, Minor, Patch uint32
+}
+
+// SemverCompatible returns whether the actual API version provides the
+// required one.
+func SemverCompatible(required, actual Semver) bool {
+	switch {
+	case required.Major != actual.Major:
+		return false
+	case required.Minor > actual.Minor:
+		return false
+	case required.Minor == actual.Minor && required.Patch > actual.Patch:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s Semver) String() string {
+	return fmt.Sprintf("%d.%d.%d", s.Major, s.Minor, s.Patch)
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcclient
+
+import (
+	"encoding/json"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcrpcclient"
+)
+
+// WalletSource is the wallet functionality stakepoold needs.  It is provided
+// by hcwallet over RPC but may be implemented by test doubles such as those
+// in package rpcclienttest.
+type WalletSource interface {
+	GenerateVote(blockHash *chainhash.Hash, height int64,
+		sstxHash *chainhash.Hash, voteBits uint16,
+		voteBitsExt string) (*dcrjson.GenerateVoteResult, error)
+	GetBestBlock() (*chainhash.Hash, int64, error)
+	GetTickets(includeImmature bool) ([]*chainhash.Hash, error)
+	GetTransaction(txHash *chainhash.Hash) (*dcrjson.GetTransactionResult, error)
+	GetTransactionAsync(txHash *chainhash.Hash) TransactionFuture
+	ImportScriptRescanFrom(script []byte, rescan bool, scanFrom int) error
+	ListScripts() ([][]byte, error)
+	WalletInfo() (*dcrjson.WalletInfoResult, error)
+
+	// RescanWallet rescans the chain from beginHeight for the transactions
+	// of the wallet and returns once the rescan is done.
+	RescanWallet(beginHeight int64) error
+
+	// WalletPassphrase unlocks the wallet for timeoutSecs seconds, or until
+	// WalletLock is called when timeoutSecs is 0.
+	WalletPassphrase(passphrase string, timeoutSecs int64) error
+	// WalletLock locks the wallet.
+	WalletLock() error
+
+	// Disconnected returns whether the connection to the wallet was lost.
+	Disconnected() bool
+	// Shutdown closes the connection to the wallet.
+	Shutdown()
+}
+
+// TransactionFuture is the pending result of GetTransactionAsync.
+type TransactionFuture interface {
+	Receive() (*dcrjson.GetTransactionResult, error)
+}
+
+// rpcWalletSource is a WalletSource backed by an hcwallet RPC connection.
+type rpcWalletSource struct {
+	*hcrpcclient.Client
+}
+
+// GetTransactionAsync starts looking up a wallet transaction and returns the
+// future for its result.
+func (w *rpcWalletSource) GetTransactionAsync(txHash *chainhash.Hash) TransactionFuture {
+	return w.Client.GetTransactionAsync(txHash)
+}
+
+// RescanWallet calls rescanwallet, which hcrpcclient has no method for.
+func (w *rpcWalletSource) RescanWallet(beginHeight int64) error {
+	param, err := json.Marshal(beginHeight)
+	if err != nil {
+		return err
+	}
+	_, err = w.Client.RawRequest("rescanwallet", []json.RawMessage{param})
+	return err
+}
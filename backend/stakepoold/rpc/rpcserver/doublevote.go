@@ -0,0 +1,91 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+)
+
+// DoubleVoteSource is who signed the conflicting vote of a double vote.
+type DoubleVoteSource int
+
+const (
+	// DoubleVoteSourceUnknown is used when the conflicting vote can't be
+	// looked up anymore.
+	DoubleVoteSourceUnknown DoubleVoteSource = iota
+	// DoubleVoteSourcePool votes were signed with the voting key of the
+	// pool by another voting wallet, with other vote bits.
+	DoubleVoteSourcePool
+	// DoubleVoteSourceExternal votes were signed with another key, that of
+	// the user or of someone holding it.
+	DoubleVoteSourceExternal
+)
+
+var doubleVoteSources = map[DoubleVoteSource]pb.DoubleVoteSource{
+	DoubleVoteSourceUnknown:  pb.DoubleVoteSource_DOUBLE_VOTE_SOURCE_UNKNOWN,
+	DoubleVoteSourcePool:     pb.DoubleVoteSource_DOUBLE_VOTE_SOURCE_POOL,
+	DoubleVoteSourceExternal: pb.DoubleVoteSource_DOUBLE_VOTE_SOURCE_EXTERNAL,
+}
+
+// String returns the name of the source, e.g. pool.
+func (s DoubleVoteSource) String() string {
+	switch s {
+	case DoubleVoteSourcePool:
+		return "pool"
+	case DoubleVoteSourceExternal:
+		return "external"
+	default:
+		return "unknown"
+	}
+}
+
+// DoubleVote is a vote of stakepoold with VoteHash that hcd rejected because
+// the vote with ConflictingVoteHash was seen first.  ConflictingVoteBits is
+// only set when the conflicting vote could be looked up.
+type DoubleVote struct {
+	Ticket              chainhash.Hash
+	MultiSigAddress     string
+	BlockHash           chainhash.Hash
+	BlockHeight         int64
+	VoteHash            chainhash.Hash
+	VoteBits            uint16
+	ConflictingVoteHash chainhash.Hash
+	ConflictingVoteBits uint16
+	Source              DoubleVoteSource
+	Time                time.Time
+}
+
+// DoubleVoteReporter reports the double votes seen at or above sinceHeight,
+// oldest first.
+type DoubleVoteReporter interface {
+	DoubleVotes(sinceHeight int64) []*DoubleVote
+}
+
+func (s *stakepooldServer) GetDoubleVotes(ctx context.Context, req *pb.GetDoubleVotesRequest) (*pb.GetDoubleVotesResponse, error) {
+	votes := s.doubleVoteReporter.DoubleVotes(req.SinceHeight)
+	resp := &pb.GetDoubleVotesResponse{
+		DoubleVotes: make([]*pb.DoubleVote, 0, len(votes)),
+	}
+	for _, v := range votes {
+		resp.DoubleVotes = append(resp.DoubleVotes, &pb.DoubleVote{
+			TicketHash:          v.Ticket.CloneBytes(),
+			MultisigAddress:     v.MultiSigAddress,
+			BlockHash:           v.BlockHash.CloneBytes(),
+			BlockHeight:         v.BlockHeight,
+			VoteHash:            v.VoteHash.CloneBytes(),
+			VoteBits:            uint32(v.VoteBits),
+			ConflictingVoteHash: v.ConflictingVoteHash.CloneBytes(),
+			ConflictingVoteBits: uint32(v.ConflictingVoteBits),
+			Source:              doubleVoteSources[v.Source],
+			Time:                v.Time.Unix(),
+		})
+	}
+	return resp, nil
+}
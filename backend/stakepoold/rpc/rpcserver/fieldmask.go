@@ -0,0 +1,74 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+)
+
+// Fields of the partial responses.
+const (
+	fieldTicketHash     = "TicketHash"
+	fieldTicketAddress  = "TicketAddress"
+	fieldPurchaseHeight = "purchase_height"
+
+	fieldUsersVotingConfig  = "users.voting_config"
+	fieldUsersRedeemScript  = "users.redeem_script"
+	fieldAddedLowFeeTickets = "added_low_fee_tickets"
+)
+
+// fieldMask is the set of fields a caller asked for.  The empty mask selects
+// every field.
+type fieldMask map[string]bool
+
+// newFieldMask returns the mask of fields, which must all be among valid.
+func newFieldMask(fields []string, valid ...string) (fieldMask, error) {
+	m := make(fieldMask, len(fields))
+	for _, f := range fields {
+		known := false
+		for _, v := range valid {
+			if f == v {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"unknown field %q", f)
+		}
+		m[f] = true
+	}
+	return m, nil
+}
+
+// has returns whether the field is selected.
+func (m fieldMask) has(field string) bool {
+	return len(m) == 0 || m[field]
+}
+
+// ticketEntryMask returns the mask of the fields of ticket list entries.
+func ticketEntryMask(options *pb.TicketListOptions) (fieldMask, error) {
+	return newFieldMask(options.GetFields(), fieldTicketHash,
+		fieldTicketAddress, fieldPurchaseHeight)
+}
+
+// maskTicketEntries clears the fields of entries not selected by m.  The
+// ticket hash is always kept.
+func (m fieldMask) maskTicketEntries(entries []*pb.TicketEntry) {
+	if len(m) == 0 {
+		return
+	}
+	for _, e := range entries {
+		if !m.has(fieldTicketAddress) {
+			e.TicketAddress = ""
+		}
+		if !m.has(fieldPurchaseHeight) {
+			e.PurchaseHeight = 0
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+)
+
+func TestTicketEntryMask(t *testing.T) {
+	entry := func() *pb.TicketEntry {
+		return &pb.TicketEntry{
+			TicketAddress:  "msa",
+			TicketHash:     []byte{1},
+			PurchaseHeight: 90,
+		}
+	}
+	tests := []struct {
+		fields []string
+		want   pb.TicketEntry
+	}{
+		{nil, *entry()},
+		{[]string{"TicketHash"}, pb.TicketEntry{TicketHash: []byte{1}}},
+		{[]string{"purchase_height"},
+			pb.TicketEntry{TicketHash: []byte{1}, PurchaseHeight: 90}},
+		{[]string{"TicketAddress", "TicketHash"},
+			pb.TicketEntry{TicketAddress: "msa", TicketHash: []byte{1}}},
+	}
+	for _, test := range tests {
+		mask, err := ticketEntryMask(&pb.TicketListOptions{
+			Fields: test.fields,
+		})
+		if err != nil {
+			t.Fatalf("fields %v: %v", test.fields, err)
+		}
+		entries := []*pb.TicketEntry{entry()}
+		mask.maskTicketEntries(entries)
+		e := entries[0]
+		if e.TicketAddress != test.want.TicketAddress ||
+			string(e.TicketHash) != string(test.want.TicketHash) ||
+			e.PurchaseHeight != test.want.PurchaseHeight {
+			t.Errorf("fields %v: got %v, want %v", test.fields, e,
+				&test.want)
+		}
+	}
+
+	_, err := ticketEntryMask(&pb.TicketListOptions{
+		Fields: []string{"ticket_address"},
+	})
+	if grpc.Code(err) != codes.InvalidArgument {
+		t.Errorf("unknown field: got error %v, want InvalidArgument", err)
+	}
+}
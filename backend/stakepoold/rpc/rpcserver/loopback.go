@@ -0,0 +1,36 @@
+// Copyright (c) 2018 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"net"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// isLoopback returns whether addr is a loopback address.
+func isLoopback(addr net.Addr) bool {
+	host := addr.String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requireLoopback returns a PermissionDenied error unless the call in ctx was
+// made from the stakepoold host itself.  It guards the calls that change the
+// server, which operators make from the host rather than hcstakepool.
+func requireLoopback(ctx context.Context, method string) error {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil || !isLoopback(p.Addr) {
+		return status.Errorf(codes.PermissionDenied, "%s is only allowed "+
+			"from localhost", method)
+	}
+	return nil
+}
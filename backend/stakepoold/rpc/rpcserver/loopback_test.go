@@ -0,0 +1,42 @@
+// Copyright (c) 2018 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRequireLoopback(t *testing.T) {
+	tests := []struct {
+		addr    net.Addr
+		allowed bool
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 50000}, true},
+		{&net.TCPAddr{IP: net.ParseIP("::1"), Port: 50000}, true},
+		{&net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 50000}, false},
+		{&net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 50000, Zone: "eth0"}, false},
+		{nil, false},
+	}
+	for i, test := range tests {
+		ctx := context.Background()
+		if test.addr != nil {
+			ctx = peer.NewContext(ctx, &peer.Peer{Addr: test.addr})
+		}
+		err := requireLoopback(ctx, "RotateRPCCertificate")
+		if test.allowed != (err == nil) {
+			t.Errorf("%d: allowed %v, got error %v", i, test.allowed, err)
+		}
+		if s, _ := status.FromError(err); err != nil &&
+			s.Code() != codes.PermissionDenied {
+			t.Errorf("%d: code %v, want PermissionDenied", i, s.Code())
+		}
+	}
+}
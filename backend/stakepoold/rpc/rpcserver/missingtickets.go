@@ -0,0 +1,89 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+)
+
+// maxMissingTickets is the most tickets an AddMissingTickets call may add.
+const maxMissingTickets = 10000
+
+// MissingTicketStatus is the outcome of adding a missing ticket.
+type MissingTicketStatus int
+
+const (
+	MissingTicketFailed MissingTicketStatus = iota
+	MissingTicketAdded
+	MissingTicketIgnoredLowFee
+	MissingTicketAlreadyTracked
+	MissingTicketNotLive
+	MissingTicketNotInWallet
+	MissingTicketNotPoolTicket
+)
+
+// MissingTicketResult is the outcome of adding Ticket.  Err is set when
+// Status is MissingTicketFailed.
+type MissingTicketResult struct {
+	Ticket chainhash.Hash
+	Status MissingTicketStatus
+	Err    error
+}
+
+// MissingTicketAdder validates tickets against the chain and the wallet and
+// starts tracking those that belong to the pool.  It returns a result for
+// every ticket in the order they were passed.  Tickets not looked up before
+// ctx is done fail with its error.
+type MissingTicketAdder interface {
+	AddMissingTickets(ctx context.Context, tickets []chainhash.Hash) []MissingTicketResult
+}
+
+var missingTicketStatuses = map[MissingTicketStatus]pb.MissingTicketStatus{
+	MissingTicketFailed:         pb.MissingTicketStatus_MISSING_TICKET_FAILED,
+	MissingTicketAdded:          pb.MissingTicketStatus_MISSING_TICKET_ADDED,
+	MissingTicketIgnoredLowFee:  pb.MissingTicketStatus_MISSING_TICKET_IGNORED_LOW_FEE,
+	MissingTicketAlreadyTracked: pb.MissingTicketStatus_MISSING_TICKET_ALREADY_TRACKED,
+	MissingTicketNotLive:        pb.MissingTicketStatus_MISSING_TICKET_NOT_LIVE,
+	MissingTicketNotInWallet:    pb.MissingTicketStatus_MISSING_TICKET_NOT_IN_WALLET,
+	MissingTicketNotPoolTicket:  pb.MissingTicketStatus_MISSING_TICKET_NOT_POOL_TICKET,
+}
+
+func (s *stakepooldServer) AddMissingTickets(ctx context.Context, req *pb.AddMissingTicketsRequest) (*pb.AddMissingTicketsResponse, error) {
+	if len(req.TicketHashes) > maxMissingTickets {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"%d tickets requested, at most %d may be added per call",
+			len(req.TicketHashes), maxMissingTickets)
+	}
+	tickets := make([]chainhash.Hash, 0, len(req.TicketHashes))
+	for _, b := range req.TicketHashes {
+		hash, err := chainhash.NewHash(b)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"invalid ticket hash %x", b)
+		}
+		tickets = append(tickets, *hash)
+	}
+
+	results := s.missingTicketAdder.AddMissingTickets(ctx, tickets)
+	resp := &pb.AddMissingTicketsResponse{
+		Results: make([]*pb.MissingTicketResult, 0, len(results)),
+	}
+	for i := range results {
+		r := &pb.MissingTicketResult{
+			TicketHash: results[i].Ticket.CloneBytes(),
+			Status:     missingTicketStatuses[results[i].Status],
+		}
+		if results[i].Err != nil {
+			r.Error = results[i].Err.Error()
+		}
+		resp.Results = append(resp.Results, r)
+	}
+	return resp, nil
+}
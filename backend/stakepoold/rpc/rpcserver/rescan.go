@@ -0,0 +1,77 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+)
+
+// ErrRescanInProgress is returned by WalletRescanner when the wallet is
+// already being rescanned.
+var ErrRescanInProgress = errors.New("a wallet rescan is already in progress")
+
+// ErrRescanHeight is returned by WalletRescanner when the rescan would start
+// above the best block.
+var ErrRescanHeight = errors.New("rescan height is above the best block")
+
+// RescanProgress is the progress of a wallet rescan from BeginHeight.
+// Elapsed is the time the rescan has taken so far.  Once Done, LiveTickets is
+// the number of live tickets tracked after reconciling them with hcd and
+// FlaggedTickets those the wallet still doesn't know.
+type RescanProgress struct {
+	BeginHeight    int64
+	BestHeight     int64
+	Elapsed        time.Duration
+	Done           bool
+	LiveTickets    int
+	FlaggedTickets []chainhash.Hash
+}
+
+// WalletRescanner rescans the wallet from beginHeight and calls progress with
+// the progress of the rescan until it is done.  The rescan isn't stopped when
+// ctx is done, only the progress is no longer reported.  It returns
+// ErrRescanInProgress when another rescan runs and ErrRescanHeight when
+// beginHeight is above the best block.
+type WalletRescanner interface {
+	RescanWallet(ctx context.Context, beginHeight int64,
+		progress func(*RescanProgress) error) error
+}
+
+func (s *stakepooldServer) RescanWallet(req *pb.RescanWalletRequest, stream pb.StakepooldService_RescanWalletServer) error {
+	if req.BeginHeight < 0 {
+		return status.Error(codes.InvalidArgument,
+			"begin_height may not be negative")
+	}
+	err := s.walletRescanner.RescanWallet(stream.Context(), req.BeginHeight,
+		func(p *RescanProgress) error {
+			flagged := make([][]byte, 0, len(p.FlaggedTickets))
+			for i := range p.FlaggedTickets {
+				flagged = append(flagged, p.FlaggedTickets[i].CloneBytes())
+			}
+			return stream.Send(&pb.RescanWalletProgress{
+				BeginHeight:    p.BeginHeight,
+				BestHeight:     p.BestHeight,
+				ElapsedSeconds: int64(p.Elapsed / time.Second),
+				Done:           p.Done,
+				LiveTickets:    uint32(p.LiveTickets),
+				FlaggedTickets: flagged,
+			})
+		})
+	switch err {
+	case ErrRescanInProgress:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case ErrRescanHeight:
+		return status.Error(codes.OutOfRange, err.Error())
+	}
+	return err
+}
@@ -13,14 +13,21 @@
 package rpcserver
 
 import (
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/coolsnady/hcd/chaincfg/chainhash"
 	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
 	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+	"github.com/coolsnady/hcstakepool/tracing"
+	"github.com/coolsnady/hcstakepool/version"
 )
 
 // Public API version constants
@@ -33,23 +40,45 @@ const (
 	// collection cycle to also trigger a timeout but the current allocation
 	// pattern of stakepoold is not known to cause such conditions at this time.
 	GRPCCommandTimeout = time.Millisecond * 100
-	semverString       = "4.0.0"
+	semverString       = "4.26.0"
 	semverMajor        = 4
-	semverMinor        = 0
+	semverMinor        = 26
 	semverPatch        = 0
 )
 
+// UserDataTimeout is the timeout of ImportUserData and ExportUserData, which
+// talk to the wallet and go through every user.
+const UserDataTimeout = time.Minute * 5
+
+// MissingTicketsTimeout is the timeout of AddMissingTickets, which looks up
+// every ticket in hcd and the wallet.
+const MissingTicketsTimeout = time.Minute * 5
+
+// StatusTimeout is the timeout of GetStatus, which asks hcwallet for its best
+// block.
+const StatusTimeout = time.Second * 10
+
 // CommandName maps function names to an integer.
 type CommandName int
 
 func (s CommandName) String() string {
 	switch s {
+	case BatchSetUserVotingPrefs:
+		return "BatchSetUserVotingPrefs"
 	case GetAddedLowFeeTickets:
 		return "GetAddedLowFeeTickets"
 	case GetIgnoredLowFeeTickets:
 		return "GetIgnoredLowFeeTickets"
 	case GetLiveTickets:
 		return "GetLiveTickets"
+	case GetPoolStats:
+		return "GetPoolStats"
+	case GetUserVotingStats:
+		return "GetUserVotingStats"
+	case GetVoteHistory:
+		return "GetVoteHistory"
+	case GetVoteLatency:
+		return "GetVoteLatency"
 	case SetAddedLowFeeTickets:
 		return "SetAddedLowFeeTickets"
 	case SetUserVotingPrefs:
@@ -64,20 +93,218 @@ const (
 	GetAddedLowFeeTickets CommandName = iota
 	GetIgnoredLowFeeTickets
 	GetLiveTickets
+	GetPoolStats
+	GetUserVotingStats
+	GetVoteHistory
+	GetVoteLatency
 	SetAddedLowFeeTickets
 	SetUserVotingPrefs
+	BatchSetUserVotingPrefs
 )
 
+// GRPCCommandQueue is a command sent to the handler in main.  Ctx carries the
+// trace of the request the command is processed for.
 type GRPCCommandQueue struct {
-	Command                CommandName
-	RequestTicketData      map[chainhash.Hash]string
-	RequestUserData        map[string]userdata.UserVotingConfig
-	ResponseEmptyChan      chan struct{}
-	ResponseTicketsMSAChan chan map[chainhash.Hash]string
+	Command                     CommandName
+	Ctx                         context.Context
+	RequestMultiSigAddresses    []string
+	RequestSinceHeight          int64
+	RequestTicketData           map[chainhash.Hash]string
+	RequestTicketQuery          *TicketQuery
+	RequestUserData             map[string]userdata.UserVotingConfig
+	ResponseEmptyChan           chan struct{}
+	ResponseCountChan           chan int
+	ResponsePoolStatsChan       chan *PoolStats
+	ResponseUserVotingStatsChan chan []*UserVotingStats
+	ResponseVoteHistoryChan     chan []*VoteHistoryEvent
+	ResponseVoteLatencyChan     chan *VoteLatencyStats
+	ResponseTicketPageChan      chan *TicketPage
+}
+
+// PoolStats are the rolling pool statistics over the last WindowSize blocks
+// as of the block BlockHash.  TotalMisses is the number of missed pool
+// tickets since stakepoold started.
+type PoolStats struct {
+	BlockHash       chainhash.Hash
+	BlockHeight     int64
+	PoolSize        uint32
+	StakeDifficulty int64
+	WindowSize      int64
+	WindowBlocks    int64
+	NetworkVotes    int64
+	PoolVotes       int64
+	PoolMisses      int64
+	TotalMisses     int64
+	LiveTickets     int64
+}
+
+// UserVotingStats are the votes and misses of the tickets of a pool user and
+// the total reward of the votes in atoms.  Fallbacks are the winning tickets
+// voted with the default vote bits.
+type UserVotingStats struct {
+	MultiSigAddress string
+	Votes           int64
+	Misses          int64
+	Reward          int64
+	Fallbacks       int64
+}
+
+// VoteEvent is something that happened to a pool ticket.
+type VoteEvent int
+
+const (
+	// VoteEventSelected is a ticket being selected to vote on a block.
+	VoteEventSelected VoteEvent = iota
+
+	// VoteEventVoted is the vote of a ticket being sent, or found to be
+	// sent already.
+	VoteEventVoted
+
+	// VoteEventMissed is hcd reporting a ticket missed.
+	VoteEventMissed
+)
+
+// VoteHistoryEvent is an event of a pool ticket.  BlockHash and BlockHeight
+// are the block the ticket was selected to vote on, or the block that missed
+// it.  VoteHash and Reward, in atoms, are only set for sent votes.
+type VoteHistoryEvent struct {
+	Ticket          chainhash.Hash
+	MultiSigAddress string
+	Event           VoteEvent
+	BlockHash       chainhash.Hash
+	BlockHeight     int64
+	VoteHash        *chainhash.Hash
+	VoteBits        uint16
+	Reward          int64
+	Time            time.Time
+}
+
+// VoteLatencyStats are percentiles of the time from the winning tickets
+// notification to the vote being sent over the last WindowVotes votes.  Votes
+// is the number of votes sent since stakepoold started.
+type VoteLatencyStats struct {
+	Votes       int64
+	WindowVotes int64
+	P50         time.Duration
+	P90         time.Duration
+	P99         time.Duration
+	Max         time.Duration
+}
+
+// UserData is a pool user as migrated between stakepoold instances: the
+// voting config along with the redeem script of the multisig address.
+type UserData struct {
+	VotingConfig userdata.UserVotingConfig
+	RedeemScript []byte
+}
+
+// ImportUserDataResult reports the outcome of an import.  Nothing is imported
+// when there are validation errors.
+type ImportUserDataResult struct {
+	Errors             []string
+	Users              int
+	ScriptsImported    int
+	AddedLowFeeTickets int
+}
+
+// UserDataMigrator exports the pool users and imports users exported from
+// another stakepoold instance.
+type UserDataMigrator interface {
+	ExportUserData(ctx context.Context) ([]UserData, map[chainhash.Hash]string, error)
+	ImportUserData(ctx context.Context, users []UserData,
+		addedLowFeeTickets map[chainhash.Hash]string, rescanHeight int64,
+		dryRun bool) (*ImportUserDataResult, error)
+}
+
+// CommandTimeout returns how long the method may take by default.  The
+// rpctimeout option of stakepoold overrides it.
+func CommandTimeout(method string) time.Duration {
+	switch method {
+	case "AddMissingTickets":
+		return MissingTicketsTimeout
+	case "ExportUserData", "ImportUserData":
+		return UserDataTimeout
+	case "GetStatus", "GetWalletInfo":
+		return StatusTimeout
+	default:
+		return GRPCCommandTimeout
+	}
+}
+
+// Status is the state of stakepoold reported by GetStatus.  BlockHeight is the
+// last block stakepoold processed and WalletHeight the best block of
+// hcwallet, 0 when it can't be asked.  PendingNotifications are the hcd
+// notifications waiting to be processed.  VotingPaused is set while voting is
+// paused on a suspected chain fork, which Fork describes.
+type Status struct {
+	NodeConnected        bool
+	WalletConnected      bool
+	BlockHeight          int64
+	WalletHeight         int64
+	Users                int
+	LiveTickets          int
+	AddedLowFeeTickets   int
+	IgnoredLowFeeTickets int
+	PendingNotifications int
+	VotingPaused         bool
+	Fork                 string
+}
+
+// WalletInfo is the state of the hcwallet of stakepoold reported by
+// GetWalletInfo.  Only Connected is set when hcwallet is disconnected.
+// AutoUnlock is set when stakepoold unlocks the wallet only to vote.
+type WalletInfo struct {
+	Connected       bool
+	Version         string
+	DaemonConnected bool
+	Unlocked        bool
+	AutoUnlock      bool
+	Voting          bool
+	Height          int64
+	VoteVersion     uint32
+}
+
+// StatusReporter reports the state of stakepoold and its hcwallet.
+type StatusReporter interface {
+	Status(ctx context.Context) (*Status, error)
+	WalletInfo(ctx context.Context) (*WalletInfo, error)
+}
+
+// ErrColdWalletExtPubMismatch is returned by ColdWalletVerifier when the
+// frontend and stakepoold are configured with different cold wallet extended
+// public keys.
+var ErrColdWalletExtPubMismatch = errors.New("coldwalletextpub does not " +
+	"match the one of stakepoold")
+
+// ColdWalletVerifier checks that the frontend sends stake pool fees to the
+// same cold wallet as stakepoold accepts them for.  VerifyColdWalletExtPub
+// returns the first fee address derived from xpub when it is the
+// coldwalletextpub of stakepoold, ErrColdWalletExtPubMismatch when it isn't
+// or another error when xpub isn't a valid extended public key.
+type ColdWalletVerifier interface {
+	VerifyColdWalletExtPub(xpub string) (string, error)
+}
+
+// CertificateRotator replaces the TLS keypair of the RPC server and returns
+// the new certificate in PEM format along with its expiration time.
+type CertificateRotator func() ([]byte, time.Time, error)
+
+// capabilities are the optional features advertised by Version.
+var capabilities = []pb.Capability{
+	pb.Capability_CAPABILITY_SPENT_MISSED_STREAM,
+	pb.Capability_CAPABILITY_AGENDA_VOTING,
+	pb.Capability_CAPABILITY_BATCH_USER_VOTING_PREFS,
+	pb.Capability_CAPABILITY_TICKET_FILTERS,
+	pb.Capability_CAPABILITY_MISSING_TICKETS,
+	pb.Capability_CAPABILITY_WALLET_INFO,
+	pb.Capability_CAPABILITY_WALLET_RESCAN,
+	pb.Capability_CAPABILITY_DOUBLE_VOTES,
+	pb.Capability_CAPABILITY_STAKE_DIFFICULTY_HISTORY,
+	pb.Capability_CAPABILITY_VOTE_HISTORY_STREAM,
 }
 
 // versionServer provides RPC clients with the ability to query the RPC server
-// version.
+// version and the optional features it supports.
 type versionServer struct {
 }
 
@@ -93,24 +320,69 @@ func (v *versionServer) Version(ctx context.Context, req *pb.VersionRequest) (*p
 		Major:         semverMajor,
 		Minor:         semverMinor,
 		Patch:         semverPatch,
+		AppVersion:    version.String(),
+		AppCommit:     version.Commit,
+		AppBuildDate:  version.BuildDate,
+		Capabilities:  capabilities,
 	}, nil
 }
 
 // StakepooldServer provides RPC clients with the ability to trigger updates
 // to the user voting config
 type stakepooldServer struct {
-	grpcCommandQueueChan chan *GRPCCommandQueue
+	grpcCommandQueueChan    chan *GRPCCommandQueue
+	rotateCert              CertificateRotator
+	coldWalletVerifier      ColdWalletVerifier
+	doubleVoteReporter      DoubleVoteReporter
+	missingTicketAdder      MissingTicketAdder
+	spentMissedFeed         *SpentMissedFeed
+	stakeDifficultyReporter StakeDifficultyReporter
+	statusReporter          StatusReporter
+	userDataMigrator        UserDataMigrator
+	voteHistoryFeed         *VoteHistoryFeed
+	walletRescanner         WalletRescanner
+
+	// pendingCommands is the number of commands waiting for or being
+	// processed by the handler in main.  It must be accessed atomically.
+	pendingCommands int32
 }
 
 // StartStakepooldService creates an implementation of the StakepooldService
 // and registers it.
-func StartStakepooldService(grpcCommandQueueChan chan *GRPCCommandQueue, server *grpc.Server) {
+func StartStakepooldService(grpcCommandQueueChan chan *GRPCCommandQueue, rotateCert CertificateRotator, coldWalletVerifier ColdWalletVerifier, doubleVoteReporter DoubleVoteReporter, missingTicketAdder MissingTicketAdder, spentMissedFeed *SpentMissedFeed, stakeDifficultyReporter StakeDifficultyReporter, statusReporter StatusReporter, userDataMigrator UserDataMigrator, voteHistoryFeed *VoteHistoryFeed, walletRescanner WalletRescanner, server *grpc.Server) {
 	pb.RegisterStakepooldServiceServer(server, &stakepooldServer{
-		grpcCommandQueueChan: grpcCommandQueueChan,
+		grpcCommandQueueChan:    grpcCommandQueueChan,
+		rotateCert:              rotateCert,
+		coldWalletVerifier:      coldWalletVerifier,
+		doubleVoteReporter:      doubleVoteReporter,
+		missingTicketAdder:      missingTicketAdder,
+		spentMissedFeed:         spentMissedFeed,
+		stakeDifficultyReporter: stakeDifficultyReporter,
+		statusReporter:          statusReporter,
+		userDataMigrator:        userDataMigrator,
+		voteHistoryFeed:         voteHistoryFeed,
+		walletRescanner:         walletRescanner,
 	})
 }
 
+// queueCommand starts the span of cmd waiting for and being processed by the
+// handler in main and sets the context cmd is processed with.  cmd counts as
+// pending until the returned function ends the span.
+func (s *stakepooldServer) queueCommand(ctx context.Context, cmd *GRPCCommandQueue) func() {
+	atomic.AddInt32(&s.pendingCommands, 1)
+	ctx, span := tracing.Start(ctx, "queue "+cmd.Command.String(),
+		tracing.SpanKindInternal)
+	cmd.Ctx = ctx
+	return func() {
+		span.End()
+		atomic.AddInt32(&s.pendingCommands, -1)
+	}
+}
+
 func (s *stakepooldServer) processSetCommand(ctx context.Context, cmd *GRPCCommandQueue) error {
+	done := s.queueCommand(ctx, cmd)
+	defer done()
+
 	// send gRPC command to the handler in main
 	select {
 	case s.grpcCommandQueueChan <- cmd:
@@ -128,22 +400,28 @@ func (s *stakepooldServer) processSetCommand(ctx context.Context, cmd *GRPCComma
 	}
 }
 
-func (s *stakepooldServer) processGetTicketCommand(ctx context.Context, cmd *GRPCCommandQueue) ([]*pb.TicketEntry, error) {
-	tickets := make([]*pb.TicketEntry, 0)
+func (s *stakepooldServer) processGetTicketCommand(ctx context.Context, cmd *GRPCCommandQueue, options *pb.TicketListOptions) (*TicketPage, error) {
+	q, err := ticketQuery(options)
+	if err != nil {
+		return nil, err
+	}
+	mask, err := ticketEntryMask(options)
+	if err != nil {
+		return nil, err
+	}
+	cmd.RequestTicketQuery = q
+	cmd.ResponseTicketPageChan = make(chan *TicketPage)
+
+	done := s.queueCommand(ctx, cmd)
+	defer done()
 
 	// send gRPC command to the handler in main
 	select {
 	case s.grpcCommandQueueChan <- cmd:
 		select {
-		case ticketsResponse := <-cmd.ResponseTicketsMSAChan:
-			// format and return the gRPC response
-			for tickethash, msa := range ticketsResponse {
-				tickets = append(tickets, &pb.TicketEntry{
-					TicketAddress: msa,
-					TicketHash:    tickethash.CloneBytes(),
-				})
-			}
-			return tickets, nil
+		case page := <-cmd.ResponseTicketPageChan:
+			mask.maskTicketEntries(page.Tickets)
+			return page, nil
 		case <-ctx.Done():
 			// hit the timeout
 			return nil, ctx.Err()
@@ -154,43 +432,367 @@ func (s *stakepooldServer) processGetTicketCommand(ctx context.Context, cmd *GRP
 	}
 }
 
+// nextCursor returns the next_cursor of the response with page.
+func nextCursor(page *TicketPage) []byte {
+	if page.NextCursor == nil {
+		return nil
+	}
+	return page.NextCursor.CloneBytes()
+}
+
+// ticketEntries converts tickets to their gRPC form.
+func ticketEntries(tickets map[chainhash.Hash]string) []*pb.TicketEntry {
+	entries := make([]*pb.TicketEntry, 0, len(tickets))
+	for tickethash, msa := range tickets {
+		entries = append(entries, &pb.TicketEntry{
+			TicketAddress: msa,
+			TicketHash:    tickethash.CloneBytes(),
+		})
+	}
+	return entries
+}
+
+func (s *stakepooldServer) ExportUserData(ctx context.Context, req *pb.ExportUserDataRequest) (*pb.ExportUserDataResponse, error) {
+	mask, err := newFieldMask(req.Fields, fieldUsersVotingConfig,
+		fieldUsersRedeemScript, fieldAddedLowFeeTickets)
+	if err != nil {
+		return nil, err
+	}
+	users, addedLowFeeTickets, err := s.userDataMigrator.ExportUserData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ExportUserDataResponse{}
+	if mask.has(fieldAddedLowFeeTickets) {
+		resp.AddedLowFeeTickets = ticketEntries(addedLowFeeTickets)
+	}
+	if !mask.has(fieldUsersVotingConfig) && !mask.has(fieldUsersRedeemScript) {
+		return resp, nil
+	}
+	resp.Users = make([]*pb.UserDataEntry, 0, len(users))
+	for _, u := range users {
+		entry := &pb.UserDataEntry{}
+		if mask.has(fieldUsersVotingConfig) {
+			entry.VotingConfig = &pb.UserVotingConfigEntry{
+				UserId:          u.VotingConfig.Userid,
+				MultiSigAddress: u.VotingConfig.MultiSigAddress,
+				VoteBits:        int64(u.VotingConfig.VoteBits),
+				VoteBitsVersion: int64(u.VotingConfig.VoteBitsVersion),
+			}
+		}
+		if mask.has(fieldUsersRedeemScript) {
+			entry.RedeemScript = u.RedeemScript
+		}
+		resp.Users = append(resp.Users, entry)
+	}
+	return resp, nil
+}
+
 func (s *stakepooldServer) GetAddedLowFeeTickets(ctx context.Context, req *pb.GetAddedLowFeeTicketsRequest) (*pb.GetAddedLowFeeTicketsResponse, error) {
-	tickets, err := s.processGetTicketCommand(ctx, &GRPCCommandQueue{
-		Command:                GetAddedLowFeeTickets,
-		ResponseTicketsMSAChan: make(chan map[chainhash.Hash]string),
-	})
+	page, err := s.processGetTicketCommand(ctx, &GRPCCommandQueue{
+		Command: GetAddedLowFeeTickets,
+	}, req.Options)
 	if err != nil {
 		return nil, err
 	}
-	return &pb.GetAddedLowFeeTicketsResponse{Tickets: tickets}, nil
+	return &pb.GetAddedLowFeeTicketsResponse{
+		Tickets:    page.Tickets,
+		NextCursor: nextCursor(page),
+		Total:      uint32(page.Total),
+	}, nil
 }
 
 func (s *stakepooldServer) GetIgnoredLowFeeTickets(ctx context.Context, req *pb.GetIgnoredLowFeeTicketsRequest) (*pb.GetIgnoredLowFeeTicketsResponse, error) {
-	tickets, err := s.processGetTicketCommand(ctx, &GRPCCommandQueue{
-		Command:                GetIgnoredLowFeeTickets,
-		ResponseTicketsMSAChan: make(chan map[chainhash.Hash]string),
-	})
+	page, err := s.processGetTicketCommand(ctx, &GRPCCommandQueue{
+		Command: GetIgnoredLowFeeTickets,
+	}, req.Options)
 	if err != nil {
 		return nil, err
 	}
-	return &pb.GetIgnoredLowFeeTicketsResponse{Tickets: tickets}, nil
+	return &pb.GetIgnoredLowFeeTicketsResponse{
+		Tickets:    page.Tickets,
+		NextCursor: nextCursor(page),
+		Total:      uint32(page.Total),
+	}, nil
 }
 
 func (s *stakepooldServer) GetLiveTickets(ctx context.Context, req *pb.GetLiveTicketsRequest) (*pb.GetLiveTicketsResponse, error) {
-	tickets, err := s.processGetTicketCommand(ctx, &GRPCCommandQueue{
-		Command:                GetLiveTickets,
-		ResponseTicketsMSAChan: make(chan map[chainhash.Hash]string),
-	})
+	page, err := s.processGetTicketCommand(ctx, &GRPCCommandQueue{
+		Command: GetLiveTickets,
+	}, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetLiveTicketsResponse{
+		Tickets:    page.Tickets,
+		NextCursor: nextCursor(page),
+		Total:      uint32(page.Total),
+	}, nil
+}
+
+func (s *stakepooldServer) GetPoolStats(ctx context.Context, req *pb.GetPoolStatsRequest) (*pb.GetPoolStatsResponse, error) {
+	cmd := &GRPCCommandQueue{
+		Command:               GetPoolStats,
+		ResponsePoolStatsChan: make(chan *PoolStats),
+	}
+	done := s.queueCommand(ctx, cmd)
+	defer done()
+
+	// send gRPC command to the handler in main
+	select {
+	case s.grpcCommandQueueChan <- cmd:
+		select {
+		case stats := <-cmd.ResponsePoolStatsChan:
+			return &pb.GetPoolStatsResponse{
+				BlockHash:       stats.BlockHash.CloneBytes(),
+				BlockHeight:     stats.BlockHeight,
+				PoolSize:        stats.PoolSize,
+				StakeDifficulty: stats.StakeDifficulty,
+				WindowSize:      stats.WindowSize,
+				WindowBlocks:    stats.WindowBlocks,
+				NetworkVotes:    stats.NetworkVotes,
+				PoolVotes:       stats.PoolVotes,
+				LiveTickets:     stats.LiveTickets,
+				PoolMisses:      stats.PoolMisses,
+				TotalMisses:     stats.TotalMisses,
+			}, nil
+		case <-ctx.Done():
+			// hit the timeout
+			return nil, ctx.Err()
+		}
+	case <-ctx.Done():
+		// hit the timeout
+		return nil, ctx.Err()
+	}
+}
+
+func (s *stakepooldServer) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.GetStatusResponse, error) {
+	// Read the pending commands first so this call isn't among them.
+	pending := atomic.LoadInt32(&s.pendingCommands)
+	status, err := s.statusReporter.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetStatusResponse{
+		NodeConnected:        status.NodeConnected,
+		WalletConnected:      status.WalletConnected,
+		BlockHeight:          status.BlockHeight,
+		WalletHeight:         status.WalletHeight,
+		Users:                uint32(status.Users),
+		LiveTickets:          uint32(status.LiveTickets),
+		AddedLowFeeTickets:   uint32(status.AddedLowFeeTickets),
+		IgnoredLowFeeTickets: uint32(status.IgnoredLowFeeTickets),
+		PendingCommands:      uint32(pending),
+		PendingNotifications: uint32(status.PendingNotifications),
+		VotingPaused:         status.VotingPaused,
+		Fork:                 status.Fork,
+	}, nil
+}
+
+func (s *stakepooldServer) GetWalletInfo(ctx context.Context, req *pb.GetWalletInfoRequest) (*pb.GetWalletInfoResponse, error) {
+	info, err := s.statusReporter.WalletInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetWalletInfoResponse{
+		WalletConnected: info.Connected,
+		Version:         info.Version,
+		Unlocked:        info.Unlocked,
+		DaemonConnected: info.DaemonConnected,
+		Voting:          info.Voting,
+		BestBlockHeight: info.Height,
+		VoteVersion:     info.VoteVersion,
+		AutoUnlock:      info.AutoUnlock,
+	}, nil
+}
+
+func (s *stakepooldServer) GetUserVotingStats(ctx context.Context, req *pb.GetUserVotingStatsRequest) (*pb.GetUserVotingStatsResponse, error) {
+	cmd := &GRPCCommandQueue{
+		Command:                     GetUserVotingStats,
+		RequestMultiSigAddresses:    req.MultisigAddresses,
+		ResponseUserVotingStatsChan: make(chan []*UserVotingStats),
+	}
+	done := s.queueCommand(ctx, cmd)
+	defer done()
+
+	// send gRPC command to the handler in main
+	select {
+	case s.grpcCommandQueueChan <- cmd:
+		select {
+		case stats := <-cmd.ResponseUserVotingStatsChan:
+			resp := &pb.GetUserVotingStatsResponse{
+				Users: make([]*pb.UserVotingStatsEntry, 0, len(stats)),
+			}
+			for _, u := range stats {
+				resp.Users = append(resp.Users, &pb.UserVotingStatsEntry{
+					MultisigAddress: u.MultiSigAddress,
+					Votes:           u.Votes,
+					Misses:          u.Misses,
+					Reward:          u.Reward,
+					Fallbacks:       u.Fallbacks,
+				})
+			}
+			return resp, nil
+		case <-ctx.Done():
+			// hit the timeout
+			return nil, ctx.Err()
+		}
+	case <-ctx.Done():
+		// hit the timeout
+		return nil, ctx.Err()
+	}
+}
+
+// voteEvents are the gRPC forms of the vote events.
+var voteEvents = map[VoteEvent]pb.VoteEvent{
+	VoteEventSelected: pb.VoteEvent_SELECTED,
+	VoteEventVoted:    pb.VoteEvent_VOTED,
+	VoteEventMissed:   pb.VoteEvent_MISSED,
+}
+
+// voteHistorySince returns the events of the tickets of multiSigAddresses, or
+// of every user without any, at or above sinceHeight, oldest first.
+func (s *stakepooldServer) voteHistorySince(ctx context.Context, sinceHeight int64, multiSigAddresses []string) ([]*VoteHistoryEvent, error) {
+	cmd := &GRPCCommandQueue{
+		Command:                  GetVoteHistory,
+		RequestMultiSigAddresses: multiSigAddresses,
+		RequestSinceHeight:       sinceHeight,
+		ResponseVoteHistoryChan:  make(chan []*VoteHistoryEvent),
+	}
+	done := s.queueCommand(ctx, cmd)
+	defer done()
+
+	// send gRPC command to the handler in main
+	select {
+	case s.grpcCommandQueueChan <- cmd:
+		select {
+		case events := <-cmd.ResponseVoteHistoryChan:
+			return events, nil
+		case <-ctx.Done():
+			// hit the timeout
+			return nil, ctx.Err()
+		}
+	case <-ctx.Done():
+		// hit the timeout
+		return nil, ctx.Err()
+	}
+}
+
+func (s *stakepooldServer) GetVoteHistory(ctx context.Context, req *pb.GetVoteHistoryRequest) (*pb.GetVoteHistoryResponse, error) {
+	events, err := s.voteHistorySince(ctx, req.SinceHeight,
+		req.MultisigAddresses)
 	if err != nil {
 		return nil, err
 	}
-	return &pb.GetLiveTicketsResponse{Tickets: tickets}, nil
+	resp := &pb.GetVoteHistoryResponse{
+		Events: make([]*pb.VoteHistoryEntry, 0, len(events)),
+	}
+	for _, e := range events {
+		resp.Events = append(resp.Events, voteHistoryEntry(e))
+	}
+	return resp, nil
+}
+
+func (s *stakepooldServer) GetVoteLatency(ctx context.Context, req *pb.GetVoteLatencyRequest) (*pb.GetVoteLatencyResponse, error) {
+	cmd := &GRPCCommandQueue{
+		Command:                 GetVoteLatency,
+		ResponseVoteLatencyChan: make(chan *VoteLatencyStats),
+	}
+	done := s.queueCommand(ctx, cmd)
+	defer done()
+
+	// send gRPC command to the handler in main
+	select {
+	case s.grpcCommandQueueChan <- cmd:
+		select {
+		case stats := <-cmd.ResponseVoteLatencyChan:
+			return &pb.GetVoteLatencyResponse{
+				Votes:       stats.Votes,
+				WindowVotes: stats.WindowVotes,
+				P50Ns:       int64(stats.P50),
+				P90Ns:       int64(stats.P90),
+				P99Ns:       int64(stats.P99),
+				MaxNs:       int64(stats.Max),
+			}, nil
+		case <-ctx.Done():
+			// hit the timeout
+			return nil, ctx.Err()
+		}
+	case <-ctx.Done():
+		// hit the timeout
+		return nil, ctx.Err()
+	}
+}
+
+func (s *stakepooldServer) ImportUserData(ctx context.Context, req *pb.ImportUserDataRequest) (*pb.ImportUserDataResponse, error) {
+	var errs []string
+
+	users := make([]UserData, 0, len(req.Users))
+	for i, u := range req.Users {
+		if u.VotingConfig == nil {
+			errs = append(errs, fmt.Sprintf("user %d: missing voting config", i))
+			continue
+		}
+		users = append(users, UserData{
+			VotingConfig: userdata.UserVotingConfig{
+				Userid:          u.VotingConfig.UserId,
+				MultiSigAddress: u.VotingConfig.MultiSigAddress,
+				VoteBits:        uint16(u.VotingConfig.VoteBits),
+				VoteBitsVersion: uint32(u.VotingConfig.VoteBitsVersion),
+			},
+			RedeemScript: u.RedeemScript,
+		})
+	}
+
+	addedLowFeeTickets := make(map[chainhash.Hash]string)
+	for _, data := range req.AddedLowFeeTickets {
+		hash, err := chainhash.NewHash(data.TicketHash)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid ticket hash %x",
+				data.TicketHash))
+			continue
+		}
+		addedLowFeeTickets[*hash] = data.TicketAddress
+	}
+
+	if len(errs) != 0 {
+		return &pb.ImportUserDataResponse{Errors: errs}, nil
+	}
+
+	result, err := s.userDataMigrator.ImportUserData(ctx, users,
+		addedLowFeeTickets, req.RescanHeight, req.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ImportUserDataResponse{
+		Errors:             result.Errors,
+		Users:              uint32(result.Users),
+		ScriptsImported:    uint32(result.ScriptsImported),
+		AddedLowFeeTickets: uint32(result.AddedLowFeeTickets),
+	}, nil
 }
 
 func (s *stakepooldServer) Ping(ctx context.Context, req *pb.PingRequest) (*pb.PingResponse, error) {
 	return &pb.PingResponse{}, nil
 }
 
+func (s *stakepooldServer) RotateRPCCertificate(ctx context.Context, req *pb.RotateRPCCertificateRequest) (*pb.RotateRPCCertificateResponse, error) {
+	// Every client needs the new certificate to connect again, so only
+	// operators on the host may rotate it.
+	if err := requireLoopback(ctx, "RotateRPCCertificate"); err != nil {
+		return nil, err
+	}
+	cert, notAfter, err := s.rotateCert()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RotateRPCCertificateResponse{
+		Certificate: cert,
+		NotAfter:    notAfter.Unix(),
+	}, nil
+}
+
 func (s *stakepooldServer) SetAddedLowFeeTickets(ctx context.Context, req *pb.SetAddedLowFeeTicketsRequest) (*pb.SetAddedLowFeeTicketsResponse, error) {
 	addedLowFeeTickets := make(map[chainhash.Hash]string)
 
@@ -214,17 +816,34 @@ func (s *stakepooldServer) SetAddedLowFeeTickets(ctx context.Context, req *pb.Se
 	return &pb.SetAddedLowFeeTicketsResponse{}, nil
 }
 
-func (s *stakepooldServer) SetUserVotingPrefs(ctx context.Context, req *pb.SetUserVotingPrefsRequest) (*pb.SetUserVotingPrefsResponse, error) {
-	userVotingPrefs := make(map[string]userdata.UserVotingConfig)
-	for _, data := range req.UserVotingConfig {
-		userVotingPrefs[data.MultiSigAddress] = userdata.UserVotingConfig{
-			Userid:          data.UserId,
-			MultiSigAddress: data.MultiSigAddress,
-			VoteBits:        uint16(data.VoteBits),
-			VoteBitsVersion: uint32(data.VoteBitsVersion),
+func (s *stakepooldServer) BatchSetUserVotingPrefs(ctx context.Context, req *pb.BatchSetUserVotingPrefsRequest) (*pb.BatchSetUserVotingPrefsResponse, error) {
+	userVotingPrefs := userVotingConfig(req.UserVotingConfig)
+	cmd := &GRPCCommandQueue{
+		Command:           BatchSetUserVotingPrefs,
+		RequestUserData:   userVotingPrefs,
+		ResponseCountChan: make(chan int),
+	}
+
+	done := s.queueCommand(ctx, cmd)
+	defer done()
+
+	select {
+	case s.grpcCommandQueueChan <- cmd:
+		select {
+		case users := <-cmd.ResponseCountChan:
+			return &pb.BatchSetUserVotingPrefsResponse{
+				Users: uint32(users),
+			}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+}
 
+func (s *stakepooldServer) SetUserVotingPrefs(ctx context.Context, req *pb.SetUserVotingPrefsRequest) (*pb.SetUserVotingPrefsResponse, error) {
+	userVotingPrefs := userVotingConfig(req.UserVotingConfig)
 	err := s.processSetCommand(ctx, &GRPCCommandQueue{
 		Command:           SetUserVotingPrefs,
 		RequestUserData:   userVotingPrefs,
@@ -235,3 +854,30 @@ func (s *stakepooldServer) SetUserVotingPrefs(ctx context.Context, req *pb.SetUs
 	}
 	return &pb.SetUserVotingPrefsResponse{}, nil
 }
+
+// userVotingConfig returns the voting preferences of entries keyed by
+// multisig address.
+func userVotingConfig(entries []*pb.UserVotingConfigEntry) map[string]userdata.UserVotingConfig {
+	userVotingPrefs := make(map[string]userdata.UserVotingConfig, len(entries))
+	for _, data := range entries {
+		userVotingPrefs[data.MultiSigAddress] = userdata.UserVotingConfig{
+			Userid:          data.UserId,
+			MultiSigAddress: data.MultiSigAddress,
+			VoteBits:        uint16(data.VoteBits),
+			VoteBitsVersion: uint32(data.VoteBitsVersion),
+		}
+	}
+	return userVotingPrefs
+}
+
+func (s *stakepooldServer) VerifyColdWalletExtPub(ctx context.Context, req *pb.VerifyColdWalletExtPubRequest) (*pb.VerifyColdWalletExtPubResponse, error) {
+	addr, err := s.coldWalletVerifier.VerifyColdWalletExtPub(req.ColdWalletExtPub)
+	if err == ErrColdWalletExtPubMismatch {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid coldwalletextpub: %v", err)
+	}
+	return &pb.VerifyColdWalletExtPubResponse{TestAddress: addr}, nil
+}
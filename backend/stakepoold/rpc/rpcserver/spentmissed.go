@@ -0,0 +1,138 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+)
+
+// spentMissedBuffer is the number of blocks a SubscribeSpentMissed client may
+// fall behind before it is dropped.
+const spentMissedBuffer = 64
+
+// SpentMissedTicket is a ticket spent or missed in a block.  MultiSigAddress
+// is only set for pool tickets.
+type SpentMissedTicket struct {
+	Ticket          chainhash.Hash
+	Spent           bool
+	MultiSigAddress string
+}
+
+// SpentMissedBlock are the tickets spent or missed in a block.
+type SpentMissedBlock struct {
+	BlockHash   chainhash.Hash
+	BlockHeight int64
+	Tickets     []SpentMissedTicket
+}
+
+// spentMissedSubscriber is a SubscribeSpentMissed client.  dropped is closed
+// when it fell too far behind.
+type spentMissedSubscriber struct {
+	blocks  chan *SpentMissedBlock
+	dropped chan struct{}
+}
+
+// SpentMissedFeed sends the spent and missed tickets of every processed block
+// to the SubscribeSpentMissed clients.  It is safe for concurrent access.
+type SpentMissedFeed struct {
+	mtx         sync.Mutex
+	subscribers map[*spentMissedSubscriber]struct{}
+}
+
+// NewSpentMissedFeed returns a feed without subscribers.
+func NewSpentMissedFeed() *SpentMissedFeed {
+	return &SpentMissedFeed{
+		subscribers: make(map[*spentMissedSubscriber]struct{}),
+	}
+}
+
+// Publish sends block to every subscriber without waiting for them.
+// Subscribers whose buffer is full are dropped.
+func (f *SpentMissedFeed) Publish(block *SpentMissedBlock) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for sub := range f.subscribers {
+		select {
+		case sub.blocks <- block:
+		default:
+			close(sub.dropped)
+			delete(f.subscribers, sub)
+		}
+	}
+}
+
+// subscribe adds a subscriber to the feed.
+func (f *SpentMissedFeed) subscribe() *spentMissedSubscriber {
+	sub := &spentMissedSubscriber{
+		blocks:  make(chan *SpentMissedBlock, spentMissedBuffer),
+		dropped: make(chan struct{}),
+	}
+	f.mtx.Lock()
+	f.subscribers[sub] = struct{}{}
+	f.mtx.Unlock()
+	return sub
+}
+
+// unsubscribe removes sub from the feed.
+func (f *SpentMissedFeed) unsubscribe(sub *spentMissedSubscriber) {
+	f.mtx.Lock()
+	delete(f.subscribers, sub)
+	f.mtx.Unlock()
+}
+
+// spentMissedNotification returns the notification of block for a client, or
+// nil when poolOnly is set and no pool ticket was spent or missed in it.
+func spentMissedNotification(block *SpentMissedBlock, poolOnly bool) *pb.SpentMissedNotification {
+	tickets := make([]*pb.SpentMissedTicketEntry, 0, len(block.Tickets))
+	for i := range block.Tickets {
+		t := &block.Tickets[i]
+		if poolOnly && t.MultiSigAddress == "" {
+			continue
+		}
+		tickets = append(tickets, &pb.SpentMissedTicketEntry{
+			TicketHash:      t.Ticket[:],
+			Spent:           t.Spent,
+			MultisigAddress: t.MultiSigAddress,
+		})
+	}
+	if poolOnly && len(tickets) == 0 {
+		return nil
+	}
+	return &pb.SpentMissedNotification{
+		BlockHash:   block.BlockHash[:],
+		BlockHeight: block.BlockHeight,
+		Tickets:     tickets,
+	}
+}
+
+func (s *stakepooldServer) SubscribeSpentMissed(req *pb.SubscribeSpentMissedRequest, stream pb.StakepooldService_SubscribeSpentMissedServer) error {
+	sub := s.spentMissedFeed.subscribe()
+	defer s.spentMissedFeed.unsubscribe(sub)
+
+	ctx := stream.Context()
+	for {
+		select {
+		case block := <-sub.blocks:
+			n := spentMissedNotification(block, req.PoolOnly)
+			if n == nil {
+				continue
+			}
+			if err := stream.Send(n); err != nil {
+				return err
+			}
+		case <-sub.dropped:
+			return status.Error(codes.ResourceExhausted,
+				"client fell too far behind")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+)
+
+func TestSpentMissedFeed(t *testing.T) {
+	block := &SpentMissedBlock{
+		BlockHeight: 100,
+		Tickets: []SpentMissedTicket{
+			{Ticket: chainhash.Hash{1}, Spent: true, MultiSigAddress: "msa"},
+			{Ticket: chainhash.Hash{2}},
+		},
+	}
+	if n := spentMissedNotification(block, false); len(n.Tickets) != 2 {
+		t.Errorf("got %d tickets, want 2", len(n.Tickets))
+	}
+	n := spentMissedNotification(block, true)
+	if len(n.Tickets) != 1 || n.Tickets[0].MultisigAddress != "msa" {
+		t.Errorf("pool only notification has tickets %v", n.Tickets)
+	}
+	block.Tickets = block.Tickets[1:]
+	if n := spentMissedNotification(block, true); n != nil {
+		t.Errorf("notification without pool tickets: %v", n)
+	}
+
+	// A subscriber that doesn't keep up is dropped.
+	feed := NewSpentMissedFeed()
+	sub := feed.subscribe()
+	for i := 0; i <= spentMissedBuffer; i++ {
+		feed.Publish(block)
+	}
+	select {
+	case <-sub.dropped:
+	default:
+		t.Fatal("subscriber was not dropped")
+	}
+	if len(feed.subscribers) != 0 {
+		t.Errorf("%d subscribers left", len(feed.subscribers))
+	}
+	feed.unsubscribe(sub)
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+)
+
+// StakeDifficultyInterval is the stake difficulty of the interval of blocks
+// starting at StartHeight.  PoolSize is the ticket pool size at the last block
+// of the interval stakepoold saw and Time the timestamp of the first.
+type StakeDifficultyInterval struct {
+	StartHeight     int64
+	StakeDifficulty int64
+	PoolSize        uint32
+	Time            time.Time
+}
+
+// StakeDifficultyReporter reports the stake difficulty of the intervals
+// starting at or above sinceHeight, oldest first.
+type StakeDifficultyReporter interface {
+	StakeDifficulties(sinceHeight int64) []*StakeDifficultyInterval
+}
+
+func (s *stakepooldServer) GetStakeDifficultyHistory(ctx context.Context, req *pb.GetStakeDifficultyHistoryRequest) (*pb.GetStakeDifficultyHistoryResponse, error) {
+	intervals := s.stakeDifficultyReporter.StakeDifficulties(req.SinceHeight)
+	resp := &pb.GetStakeDifficultyHistoryResponse{
+		Intervals: make([]*pb.StakeDifficultyInterval, 0, len(intervals)),
+	}
+	for _, in := range intervals {
+		resp.Intervals = append(resp.Intervals, &pb.StakeDifficultyInterval{
+			StartHeight:     in.StartHeight,
+			StakeDifficulty: in.StakeDifficulty,
+			PoolSize:        in.PoolSize,
+			Time:            in.Time.Unix(),
+		})
+	}
+	return resp, nil
+}
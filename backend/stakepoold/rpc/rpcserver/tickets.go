@@ -0,0 +1,177 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"bytes"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+)
+
+// TicketStatus is a list a ticket can be in besides the one being queried.
+type TicketStatus int
+
+const (
+	TicketStatusAny TicketStatus = iota
+	TicketStatusLive
+	TicketStatusAddedLowFee
+	TicketStatusIgnoredLowFee
+)
+
+// TicketFeeStatus is whether a ticket paid the pool fee.  Low fee tickets
+// are the added and ignored low fee tickets.
+type TicketFeeStatus int
+
+const (
+	TicketFeeAny TicketFeeStatus = iota
+	TicketFeeValid
+	TicketFeeLow
+)
+
+// TicketQuery selects a page of a ticket list ordered by hash.  A zero Limit
+// selects every ticket after Cursor.  Non-zero purchase heights bound the
+// purchase height of the tickets, which leaves out those whose purchase
+// height isn't known.
+type TicketQuery struct {
+	Limit             int
+	Cursor            *chainhash.Hash
+	MultiSigAddress   string
+	Status            TicketStatus
+	FeeStatus         TicketFeeStatus
+	MinPurchaseHeight int64
+	MaxPurchaseHeight int64
+}
+
+// TicketPage is the page of a ticket list selected by a TicketQuery.  Total is
+// the number of tickets matching the filters.  NextCursor is nil on the last
+// page.
+type TicketPage struct {
+	Tickets    []*pb.TicketEntry
+	NextCursor *chainhash.Hash
+	Total      int
+}
+
+// ticketQuery converts the options of a ticket list call.
+func ticketQuery(options *pb.TicketListOptions) (*TicketQuery, error) {
+	q := &TicketQuery{
+		Limit:             int(options.GetLimit()),
+		MultiSigAddress:   options.GetMultisigAddress(),
+		MinPurchaseHeight: options.GetMinPurchaseHeight(),
+		MaxPurchaseHeight: options.GetMaxPurchaseHeight(),
+	}
+	if q.MinPurchaseHeight < 0 || q.MaxPurchaseHeight < 0 {
+		return nil, status.Error(codes.InvalidArgument,
+			"negative purchase height")
+	}
+	if cursor := options.GetCursor(); len(cursor) != 0 {
+		hash, err := chainhash.NewHash(cursor)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"invalid cursor %x", cursor)
+		}
+		q.Cursor = hash
+	}
+	switch options.GetStatus() {
+	case pb.TicketStatus_ANY:
+		q.Status = TicketStatusAny
+	case pb.TicketStatus_LIVE:
+		q.Status = TicketStatusLive
+	case pb.TicketStatus_ADDED_LOW_FEE:
+		q.Status = TicketStatusAddedLowFee
+	case pb.TicketStatus_IGNORED_LOW_FEE:
+		q.Status = TicketStatusIgnoredLowFee
+	default:
+		return nil, status.Errorf(codes.InvalidArgument,
+			"unknown ticket status %v", options.GetStatus())
+	}
+	switch options.GetFeeStatus() {
+	case pb.TicketFeeStatus_ANY_FEE:
+		q.FeeStatus = TicketFeeAny
+	case pb.TicketFeeStatus_VALID_FEE:
+		q.FeeStatus = TicketFeeValid
+	case pb.TicketFeeStatus_LOW_FEE:
+		q.FeeStatus = TicketFeeLow
+	default:
+		return nil, status.Errorf(codes.InvalidArgument,
+			"unknown fee status %v", options.GetFeeStatus())
+	}
+	return q, nil
+}
+
+// matchesFee reports whether ticket has the fee status of q.
+func (q *TicketQuery) matchesFee(ticket chainhash.Hash,
+	hasStatus func(chainhash.Hash, TicketStatus) bool) bool {
+	if q.FeeStatus == TicketFeeAny {
+		return true
+	}
+	lowFee := hasStatus(ticket, TicketStatusAddedLowFee) ||
+		hasStatus(ticket, TicketStatusIgnoredLowFee)
+	return lowFee == (q.FeeStatus == TicketFeeLow)
+}
+
+// matchesHeight reports whether a ticket purchased at height, 0 when unknown,
+// is within the purchase heights of q.
+func (q *TicketQuery) matchesHeight(height int64) bool {
+	if q.MinPurchaseHeight == 0 && q.MaxPurchaseHeight == 0 {
+		return true
+	}
+	return height != 0 && height >= q.MinPurchaseHeight &&
+		(q.MaxPurchaseHeight == 0 || height <= q.MaxPurchaseHeight)
+}
+
+// PageTickets returns the page of tickets selected by q.  hasStatus reports
+// whether a ticket has a status and is only called when q filters on one.
+// purchaseHeight returns the purchase height of a ticket, 0 when unknown.
+func PageTickets(tickets map[chainhash.Hash]string, q *TicketQuery,
+	hasStatus func(chainhash.Hash, TicketStatus) bool,
+	purchaseHeight func(chainhash.Hash) int64) *TicketPage {
+	hashes := make([]chainhash.Hash, 0, len(tickets))
+	for ticket, msa := range tickets {
+		if q.MultiSigAddress != "" && msa != q.MultiSigAddress {
+			continue
+		}
+		if q.Status != TicketStatusAny && !hasStatus(ticket, q.Status) {
+			continue
+		}
+		if !q.matchesFee(ticket, hasStatus) {
+			continue
+		}
+		if !q.matchesHeight(purchaseHeight(ticket)) {
+			continue
+		}
+		hashes = append(hashes, ticket)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+
+	page := &TicketPage{Total: len(hashes)}
+	if q.Cursor != nil {
+		start := sort.Search(len(hashes), func(i int) bool {
+			return bytes.Compare(hashes[i][:], q.Cursor[:]) > 0
+		})
+		hashes = hashes[start:]
+	}
+	if q.Limit > 0 && len(hashes) > q.Limit {
+		hashes = hashes[:q.Limit]
+		next := hashes[len(hashes)-1]
+		page.NextCursor = &next
+	}
+
+	page.Tickets = make([]*pb.TicketEntry, 0, len(hashes))
+	for _, ticket := range hashes {
+		page.Tickets = append(page.Tickets, &pb.TicketEntry{
+			TicketAddress:  tickets[ticket],
+			TicketHash:     ticket.CloneBytes(),
+			PurchaseHeight: purchaseHeight(ticket),
+		})
+	}
+	return page
+}
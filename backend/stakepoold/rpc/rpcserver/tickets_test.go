@@ -0,0 +1,90 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+)
+
+func TestPageTickets(t *testing.T) {
+	tickets := make(map[chainhash.Hash]string)
+	for i := 0; i < 10; i++ {
+		msa := "msa1"
+		if i%2 == 1 {
+			msa = "msa2"
+		}
+		tickets[chainhash.Hash{byte(i)}] = msa
+	}
+	live := func(ticket chainhash.Hash, status TicketStatus) bool {
+		return status == TicketStatusLive && ticket[0] < 4 ||
+			status == TicketStatusAddedLowFee && ticket[0] == 5
+	}
+	// Ticket 9 has an unknown purchase height.
+	height := func(ticket chainhash.Hash) int64 {
+		if ticket[0] == 9 {
+			return 0
+		}
+		return 100 + int64(ticket[0])
+	}
+
+	// Page through msa1's tickets, 0 2 4 6 8, two at a time.
+	q := &TicketQuery{Limit: 2, MultiSigAddress: "msa1"}
+	var got []byte
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatal("more than 3 pages")
+		}
+		page := PageTickets(tickets, q, live, height)
+		if page.Total != 5 {
+			t.Errorf("total %d, want 5", page.Total)
+		}
+		for _, entry := range page.Tickets {
+			if entry.TicketAddress != "msa1" {
+				t.Errorf("ticket %x of %s", entry.TicketHash,
+					entry.TicketAddress)
+			}
+			got = append(got, entry.TicketHash[0])
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	if string(got) != string([]byte{0, 2, 4, 6, 8}) {
+		t.Errorf("paged tickets %v, want [0 2 4 6 8]", got)
+	}
+
+	page := PageTickets(tickets, &TicketQuery{Status: TicketStatusLive}, live,
+		height)
+	if page.Total != 4 || len(page.Tickets) != 4 || page.NextCursor != nil {
+		t.Errorf("live tickets: total %d, %d tickets, cursor %v",
+			page.Total, len(page.Tickets), page.NextCursor)
+	}
+	if page.Tickets[1].PurchaseHeight != 101 {
+		t.Errorf("purchase height %d, want 101",
+			page.Tickets[1].PurchaseHeight)
+	}
+
+	tests := []struct {
+		q     TicketQuery
+		total int
+	}{
+		{TicketQuery{FeeStatus: TicketFeeLow}, 1},
+		{TicketQuery{FeeStatus: TicketFeeValid}, 9},
+		{TicketQuery{MinPurchaseHeight: 106}, 3},
+		{TicketQuery{MaxPurchaseHeight: 102}, 3},
+		{TicketQuery{MinPurchaseHeight: 102, MaxPurchaseHeight: 105,
+			FeeStatus: TicketFeeValid}, 3},
+	}
+	for i, test := range tests {
+		page := PageTickets(tickets, &test.q, live, height)
+		if page.Total != test.total {
+			t.Errorf("test %d: total %d, want %d", i, page.Total,
+				test.total)
+		}
+	}
+}
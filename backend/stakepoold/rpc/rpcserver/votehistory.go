@@ -0,0 +1,124 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+)
+
+// voteHistoryBuffer is the number of events a SubscribeVoteHistory client may
+// fall behind before it is dropped.  A block selects five tickets and misses
+// a few, so it holds the events of many blocks.
+const voteHistoryBuffer = 1024
+
+// voteHistorySubscriber is a SubscribeVoteHistory client.  dropped is closed
+// when it fell too far behind.
+type voteHistorySubscriber struct {
+	events  chan *VoteHistoryEvent
+	dropped chan struct{}
+}
+
+// VoteHistoryFeed sends every event of a pool ticket to the
+// SubscribeVoteHistory clients.  It is safe for concurrent access.
+type VoteHistoryFeed struct {
+	mtx         sync.Mutex
+	subscribers map[*voteHistorySubscriber]struct{}
+}
+
+// NewVoteHistoryFeed returns a feed without subscribers.
+func NewVoteHistoryFeed() *VoteHistoryFeed {
+	return &VoteHistoryFeed{
+		subscribers: make(map[*voteHistorySubscriber]struct{}),
+	}
+}
+
+// Publish sends e to every subscriber without waiting for them.  Subscribers
+// whose buffer is full are dropped.
+func (f *VoteHistoryFeed) Publish(e *VoteHistoryEvent) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for sub := range f.subscribers {
+		select {
+		case sub.events <- e:
+		default:
+			close(sub.dropped)
+			delete(f.subscribers, sub)
+		}
+	}
+}
+
+// subscribe adds a subscriber to the feed.
+func (f *VoteHistoryFeed) subscribe() *voteHistorySubscriber {
+	sub := &voteHistorySubscriber{
+		events:  make(chan *VoteHistoryEvent, voteHistoryBuffer),
+		dropped: make(chan struct{}),
+	}
+	f.mtx.Lock()
+	f.subscribers[sub] = struct{}{}
+	f.mtx.Unlock()
+	return sub
+}
+
+// unsubscribe removes sub from the feed.
+func (f *VoteHistoryFeed) unsubscribe(sub *voteHistorySubscriber) {
+	f.mtx.Lock()
+	delete(f.subscribers, sub)
+	f.mtx.Unlock()
+}
+
+// voteHistoryEntry returns the entry of e sent to clients.
+func voteHistoryEntry(e *VoteHistoryEvent) *pb.VoteHistoryEntry {
+	entry := &pb.VoteHistoryEntry{
+		TicketHash:      e.Ticket.CloneBytes(),
+		MultisigAddress: e.MultiSigAddress,
+		Event:           voteEvents[e.Event],
+		BlockHash:       e.BlockHash.CloneBytes(),
+		BlockHeight:     e.BlockHeight,
+		VoteBits:        uint32(e.VoteBits),
+		Reward:          e.Reward,
+		Time:            e.Time.Unix(),
+	}
+	if e.VoteHash != nil {
+		entry.VoteHash = e.VoteHash.CloneBytes()
+	}
+	return entry
+}
+
+func (s *stakepooldServer) SubscribeVoteHistory(req *pb.SubscribeVoteHistoryRequest, stream pb.StakepooldService_SubscribeVoteHistoryServer) error {
+	sub := s.voteHistoryFeed.subscribe()
+	defer s.voteHistoryFeed.unsubscribe(sub)
+
+	// The remembered events are fetched after subscribing so none added in
+	// between is missed.  Those are sent twice instead.
+	ctx := stream.Context()
+	events, err := s.voteHistorySince(ctx, req.SinceHeight, nil)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := stream.Send(voteHistoryEntry(e)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case e := <-sub.events:
+			if err := stream.Send(voteHistoryEntry(e)); err != nil {
+				return err
+			}
+		case <-sub.dropped:
+			return status.Error(codes.ResourceExhausted,
+				"client fell too far behind")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcserver
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+)
+
+func TestVoteHistoryFeed(t *testing.T) {
+	voteHash := chainhash.Hash{2}
+	e := &VoteHistoryEvent{
+		Ticket:          chainhash.Hash{1},
+		MultiSigAddress: "msa",
+		Event:           VoteEventVoted,
+		BlockHeight:     100,
+		VoteHash:        &voteHash,
+		VoteBits:        5,
+		Reward:          1e8,
+		Time:            time.Unix(1500000000, 0),
+	}
+	entry := voteHistoryEntry(e)
+	if entry.Event != pb.VoteEvent_VOTED || entry.VoteBits != 5 ||
+		!bytes.Equal(entry.VoteHash, voteHash[:]) ||
+		entry.Time != 1500000000 {
+		t.Errorf("unexpected entry %v", entry)
+	}
+
+	feed := NewVoteHistoryFeed()
+	sub := feed.subscribe()
+	feed.Publish(e)
+	if got := <-sub.events; got != e {
+		t.Errorf("got event %+v, want %+v", got, e)
+	}
+
+	// A subscriber that doesn't keep up is dropped.
+	for i := 0; i <= voteHistoryBuffer; i++ {
+		feed.Publish(e)
+	}
+	select {
+	case <-sub.dropped:
+	default:
+		t.Fatal("subscriber was not dropped")
+	}
+	if len(feed.subscribers) != 0 {
+		t.Errorf("%d subscribers left", len(feed.subscribers))
+	}
+	feed.unsubscribe(sub)
+}
@@ -9,20 +9,61 @@ It is generated from these files:
 	api.proto
 
 It has these top-level messages:
+	AddMissingTicketsRequest
+	AddMissingTicketsResponse
+	BatchSetUserVotingPrefsRequest
+	BatchSetUserVotingPrefsResponse
+	DoubleVote
+	ExportUserDataRequest
+	ExportUserDataResponse
 	GetAddedLowFeeTicketsRequest
 	GetAddedLowFeeTicketsResponse
+	GetDoubleVotesRequest
+	GetDoubleVotesResponse
 	GetIgnoredLowFeeTicketsRequest
 	GetIgnoredLowFeeTicketsResponse
 	GetLiveTicketsRequest
 	GetLiveTicketsResponse
+	GetPoolStatsRequest
+	GetPoolStatsResponse
+	GetStakeDifficultyHistoryRequest
+	GetStakeDifficultyHistoryResponse
+	GetStatusRequest
+	GetStatusResponse
+	GetUserVotingStatsRequest
+	GetUserVotingStatsResponse
+	GetVoteHistoryRequest
+	GetVoteHistoryResponse
+	GetVoteLatencyRequest
+	GetVoteLatencyResponse
+	GetWalletInfoRequest
+	GetWalletInfoResponse
+	ImportUserDataRequest
+	ImportUserDataResponse
+	MissingTicketResult
 	PingRequest
 	PingResponse
+	RescanWalletProgress
+	RescanWalletRequest
+	RotateRPCCertificateRequest
+	RotateRPCCertificateResponse
 	SetAddedLowFeeTicketsRequest
 	SetAddedLowFeeTicketsResponse
 	SetUserVotingPrefsResponse
 	SetUserVotingPrefsRequest
+	SpentMissedNotification
+	SpentMissedTicketEntry
+	StakeDifficultyInterval
+	SubscribeSpentMissedRequest
+	SubscribeVoteHistoryRequest
 	TicketEntry
+	TicketListOptions
+	UserDataEntry
+	UserVotingStatsEntry
 	UserVotingConfigEntry
+	VerifyColdWalletExtPubRequest
+	VerifyColdWalletExtPubResponse
+	VoteHistoryEntry
 	VersionRequest
 	VersionResponse
 */
@@ -48,22 +89,407 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type Capability int32
+
+const (
+	Capability_CAPABILITY_UNKNOWN                  Capability = 0
+	Capability_CAPABILITY_SPENT_MISSED_STREAM      Capability = 1
+	Capability_CAPABILITY_AGENDA_VOTING            Capability = 2
+	Capability_CAPABILITY_BATCH_USER_VOTING_PREFS  Capability = 3
+	Capability_CAPABILITY_TICKET_FILTERS           Capability = 4
+	Capability_CAPABILITY_MISSING_TICKETS          Capability = 5
+	Capability_CAPABILITY_WALLET_INFO              Capability = 6
+	Capability_CAPABILITY_WALLET_RESCAN            Capability = 7
+	Capability_CAPABILITY_DOUBLE_VOTES             Capability = 8
+	Capability_CAPABILITY_STAKE_DIFFICULTY_HISTORY Capability = 9
+	Capability_CAPABILITY_VOTE_HISTORY_STREAM      Capability = 10
+)
+
+var Capability_name = map[int32]string{
+	0:  "CAPABILITY_UNKNOWN",
+	1:  "CAPABILITY_SPENT_MISSED_STREAM",
+	2:  "CAPABILITY_AGENDA_VOTING",
+	3:  "CAPABILITY_BATCH_USER_VOTING_PREFS",
+	4:  "CAPABILITY_TICKET_FILTERS",
+	5:  "CAPABILITY_MISSING_TICKETS",
+	6:  "CAPABILITY_WALLET_INFO",
+	7:  "CAPABILITY_WALLET_RESCAN",
+	8:  "CAPABILITY_DOUBLE_VOTES",
+	9:  "CAPABILITY_STAKE_DIFFICULTY_HISTORY",
+	10: "CAPABILITY_VOTE_HISTORY_STREAM",
+}
+var Capability_value = map[string]int32{
+	"CAPABILITY_UNKNOWN":                  0,
+	"CAPABILITY_SPENT_MISSED_STREAM":      1,
+	"CAPABILITY_AGENDA_VOTING":            2,
+	"CAPABILITY_BATCH_USER_VOTING_PREFS":  3,
+	"CAPABILITY_TICKET_FILTERS":           4,
+	"CAPABILITY_MISSING_TICKETS":          5,
+	"CAPABILITY_WALLET_INFO":              6,
+	"CAPABILITY_WALLET_RESCAN":            7,
+	"CAPABILITY_DOUBLE_VOTES":             8,
+	"CAPABILITY_STAKE_DIFFICULTY_HISTORY": 9,
+	"CAPABILITY_VOTE_HISTORY_STREAM":      10,
+}
+
+func (x Capability) String() string {
+	return proto.EnumName(Capability_name, int32(x))
+}
+func (Capability) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type DoubleVoteSource int32
+
+const (
+	DoubleVoteSource_DOUBLE_VOTE_SOURCE_UNKNOWN  DoubleVoteSource = 0
+	DoubleVoteSource_DOUBLE_VOTE_SOURCE_POOL     DoubleVoteSource = 1
+	DoubleVoteSource_DOUBLE_VOTE_SOURCE_EXTERNAL DoubleVoteSource = 2
+)
+
+var DoubleVoteSource_name = map[int32]string{
+	0: "DOUBLE_VOTE_SOURCE_UNKNOWN",
+	1: "DOUBLE_VOTE_SOURCE_POOL",
+	2: "DOUBLE_VOTE_SOURCE_EXTERNAL",
+}
+var DoubleVoteSource_value = map[string]int32{
+	"DOUBLE_VOTE_SOURCE_UNKNOWN":  0,
+	"DOUBLE_VOTE_SOURCE_POOL":     1,
+	"DOUBLE_VOTE_SOURCE_EXTERNAL": 2,
+}
+
+func (x DoubleVoteSource) String() string {
+	return proto.EnumName(DoubleVoteSource_name, int32(x))
+}
+func (DoubleVoteSource) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type MissingTicketStatus int32
+
+const (
+	MissingTicketStatus_MISSING_TICKET_FAILED          MissingTicketStatus = 0
+	MissingTicketStatus_MISSING_TICKET_ADDED           MissingTicketStatus = 1
+	MissingTicketStatus_MISSING_TICKET_IGNORED_LOW_FEE MissingTicketStatus = 2
+	MissingTicketStatus_MISSING_TICKET_ALREADY_TRACKED MissingTicketStatus = 3
+	MissingTicketStatus_MISSING_TICKET_NOT_LIVE        MissingTicketStatus = 4
+	MissingTicketStatus_MISSING_TICKET_NOT_IN_WALLET   MissingTicketStatus = 5
+	MissingTicketStatus_MISSING_TICKET_NOT_POOL_TICKET MissingTicketStatus = 6
+)
+
+var MissingTicketStatus_name = map[int32]string{
+	0: "MISSING_TICKET_FAILED",
+	1: "MISSING_TICKET_ADDED",
+	2: "MISSING_TICKET_IGNORED_LOW_FEE",
+	3: "MISSING_TICKET_ALREADY_TRACKED",
+	4: "MISSING_TICKET_NOT_LIVE",
+	5: "MISSING_TICKET_NOT_IN_WALLET",
+	6: "MISSING_TICKET_NOT_POOL_TICKET",
+}
+var MissingTicketStatus_value = map[string]int32{
+	"MISSING_TICKET_FAILED":          0,
+	"MISSING_TICKET_ADDED":           1,
+	"MISSING_TICKET_IGNORED_LOW_FEE": 2,
+	"MISSING_TICKET_ALREADY_TRACKED": 3,
+	"MISSING_TICKET_NOT_LIVE":        4,
+	"MISSING_TICKET_NOT_IN_WALLET":   5,
+	"MISSING_TICKET_NOT_POOL_TICKET": 6,
+}
+
+func (x MissingTicketStatus) String() string {
+	return proto.EnumName(MissingTicketStatus_name, int32(x))
+}
+func (MissingTicketStatus) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+type TicketFeeStatus int32
+
+const (
+	TicketFeeStatus_ANY_FEE   TicketFeeStatus = 0
+	TicketFeeStatus_VALID_FEE TicketFeeStatus = 1
+	TicketFeeStatus_LOW_FEE   TicketFeeStatus = 2
+)
+
+var TicketFeeStatus_name = map[int32]string{
+	0: "ANY_FEE",
+	1: "VALID_FEE",
+	2: "LOW_FEE",
+}
+var TicketFeeStatus_value = map[string]int32{
+	"ANY_FEE":   0,
+	"VALID_FEE": 1,
+	"LOW_FEE":   2,
+}
+
+func (x TicketFeeStatus) String() string {
+	return proto.EnumName(TicketFeeStatus_name, int32(x))
+}
+func (TicketFeeStatus) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+type TicketStatus int32
+
+const (
+	TicketStatus_ANY             TicketStatus = 0
+	TicketStatus_LIVE            TicketStatus = 1
+	TicketStatus_ADDED_LOW_FEE   TicketStatus = 2
+	TicketStatus_IGNORED_LOW_FEE TicketStatus = 3
+)
+
+var TicketStatus_name = map[int32]string{
+	0: "ANY",
+	1: "LIVE",
+	2: "ADDED_LOW_FEE",
+	3: "IGNORED_LOW_FEE",
+}
+var TicketStatus_value = map[string]int32{
+	"ANY":             0,
+	"LIVE":            1,
+	"ADDED_LOW_FEE":   2,
+	"IGNORED_LOW_FEE": 3,
+}
+
+func (x TicketStatus) String() string {
+	return proto.EnumName(TicketStatus_name, int32(x))
+}
+func (TicketStatus) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type VoteEvent int32
+
+const (
+	VoteEvent_SELECTED VoteEvent = 0
+	VoteEvent_VOTED    VoteEvent = 1
+	VoteEvent_MISSED   VoteEvent = 2
+)
+
+var VoteEvent_name = map[int32]string{
+	0: "SELECTED",
+	1: "VOTED",
+	2: "MISSED",
+}
+var VoteEvent_value = map[string]int32{
+	"SELECTED": 0,
+	"VOTED":    1,
+	"MISSED":   2,
+}
+
+func (x VoteEvent) String() string {
+	return proto.EnumName(VoteEvent_name, int32(x))
+}
+func (VoteEvent) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+type AddMissingTicketsRequest struct {
+	TicketHashes [][]byte `protobuf:"bytes,1,rep,name=ticket_hashes,json=ticketHashes,proto3" json:"ticket_hashes,omitempty"`
+}
+
+func (m *AddMissingTicketsRequest) Reset()                    { *m = AddMissingTicketsRequest{} }
+func (m *AddMissingTicketsRequest) String() string            { return proto.CompactTextString(m) }
+func (*AddMissingTicketsRequest) ProtoMessage()               {}
+func (*AddMissingTicketsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *AddMissingTicketsRequest) GetTicketHashes() [][]byte {
+	if m != nil {
+		return m.TicketHashes
+	}
+	return nil
+}
+
+type AddMissingTicketsResponse struct {
+	Results []*MissingTicketResult `protobuf:"bytes,1,rep,name=results" json:"results,omitempty"`
+}
+
+func (m *AddMissingTicketsResponse) Reset()                    { *m = AddMissingTicketsResponse{} }
+func (m *AddMissingTicketsResponse) String() string            { return proto.CompactTextString(m) }
+func (*AddMissingTicketsResponse) ProtoMessage()               {}
+func (*AddMissingTicketsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *AddMissingTicketsResponse) GetResults() []*MissingTicketResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+type BatchSetUserVotingPrefsRequest struct {
+	UserVotingConfig []*UserVotingConfigEntry `protobuf:"bytes,1,rep,name=user_voting_config,json=userVotingConfig" json:"user_voting_config,omitempty"`
+}
+
+func (m *BatchSetUserVotingPrefsRequest) Reset()                    { *m = BatchSetUserVotingPrefsRequest{} }
+func (m *BatchSetUserVotingPrefsRequest) String() string            { return proto.CompactTextString(m) }
+func (*BatchSetUserVotingPrefsRequest) ProtoMessage()               {}
+func (*BatchSetUserVotingPrefsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *BatchSetUserVotingPrefsRequest) GetUserVotingConfig() []*UserVotingConfigEntry {
+	if m != nil {
+		return m.UserVotingConfig
+	}
+	return nil
+}
+
+type BatchSetUserVotingPrefsResponse struct {
+	Users uint32 `protobuf:"varint,1,opt,name=users" json:"users,omitempty"`
+}
+
+func (m *BatchSetUserVotingPrefsResponse) Reset()                    { *m = BatchSetUserVotingPrefsResponse{} }
+func (m *BatchSetUserVotingPrefsResponse) String() string            { return proto.CompactTextString(m) }
+func (*BatchSetUserVotingPrefsResponse) ProtoMessage()               {}
+func (*BatchSetUserVotingPrefsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *BatchSetUserVotingPrefsResponse) GetUsers() uint32 {
+	if m != nil {
+		return m.Users
+	}
+	return 0
+}
+
+type DoubleVote struct {
+	TicketHash          []byte           `protobuf:"bytes,1,opt,name=ticket_hash,json=ticketHash,proto3" json:"ticket_hash,omitempty"`
+	MultisigAddress     string           `protobuf:"bytes,2,opt,name=multisig_address,json=multisigAddress" json:"multisig_address,omitempty"`
+	BlockHash           []byte           `protobuf:"bytes,3,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	BlockHeight         int64            `protobuf:"varint,4,opt,name=block_height,json=blockHeight" json:"block_height,omitempty"`
+	VoteHash            []byte           `protobuf:"bytes,5,opt,name=vote_hash,json=voteHash,proto3" json:"vote_hash,omitempty"`
+	VoteBits            uint32           `protobuf:"varint,6,opt,name=vote_bits,json=voteBits" json:"vote_bits,omitempty"`
+	ConflictingVoteHash []byte           `protobuf:"bytes,7,opt,name=conflicting_vote_hash,json=conflictingVoteHash,proto3" json:"conflicting_vote_hash,omitempty"`
+	ConflictingVoteBits uint32           `protobuf:"varint,8,opt,name=conflicting_vote_bits,json=conflictingVoteBits" json:"conflicting_vote_bits,omitempty"`
+	Source              DoubleVoteSource `protobuf:"varint,9,opt,name=source,enum=stakepoolrpc.DoubleVoteSource" json:"source,omitempty"`
+	Time                int64            `protobuf:"varint,10,opt,name=time" json:"time,omitempty"`
+}
+
+func (m *DoubleVote) Reset()                    { *m = DoubleVote{} }
+func (m *DoubleVote) String() string            { return proto.CompactTextString(m) }
+func (*DoubleVote) ProtoMessage()               {}
+func (*DoubleVote) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *DoubleVote) GetTicketHash() []byte {
+	if m != nil {
+		return m.TicketHash
+	}
+	return nil
+}
+
+func (m *DoubleVote) GetMultisigAddress() string {
+	if m != nil {
+		return m.MultisigAddress
+	}
+	return ""
+}
+
+func (m *DoubleVote) GetBlockHash() []byte {
+	if m != nil {
+		return m.BlockHash
+	}
+	return nil
+}
+
+func (m *DoubleVote) GetBlockHeight() int64 {
+	if m != nil {
+		return m.BlockHeight
+	}
+	return 0
+}
+
+func (m *DoubleVote) GetVoteHash() []byte {
+	if m != nil {
+		return m.VoteHash
+	}
+	return nil
+}
+
+func (m *DoubleVote) GetVoteBits() uint32 {
+	if m != nil {
+		return m.VoteBits
+	}
+	return 0
+}
+
+func (m *DoubleVote) GetConflictingVoteHash() []byte {
+	if m != nil {
+		return m.ConflictingVoteHash
+	}
+	return nil
+}
+
+func (m *DoubleVote) GetConflictingVoteBits() uint32 {
+	if m != nil {
+		return m.ConflictingVoteBits
+	}
+	return 0
+}
+
+func (m *DoubleVote) GetSource() DoubleVoteSource {
+	if m != nil {
+		return m.Source
+	}
+	return DoubleVoteSource_DOUBLE_VOTE_SOURCE_UNKNOWN
+}
+
+func (m *DoubleVote) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+type ExportUserDataRequest struct {
+	Fields []string `protobuf:"bytes,1,rep,name=fields" json:"fields,omitempty"`
+}
+
+func (m *ExportUserDataRequest) Reset()                    { *m = ExportUserDataRequest{} }
+func (m *ExportUserDataRequest) String() string            { return proto.CompactTextString(m) }
+func (*ExportUserDataRequest) ProtoMessage()               {}
+func (*ExportUserDataRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *ExportUserDataRequest) GetFields() []string {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+type ExportUserDataResponse struct {
+	Users              []*UserDataEntry `protobuf:"bytes,1,rep,name=users" json:"users,omitempty"`
+	AddedLowFeeTickets []*TicketEntry   `protobuf:"bytes,2,rep,name=added_low_fee_tickets,json=addedLowFeeTickets" json:"added_low_fee_tickets,omitempty"`
+}
+
+func (m *ExportUserDataResponse) Reset()                    { *m = ExportUserDataResponse{} }
+func (m *ExportUserDataResponse) String() string            { return proto.CompactTextString(m) }
+func (*ExportUserDataResponse) ProtoMessage()               {}
+func (*ExportUserDataResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *ExportUserDataResponse) GetUsers() []*UserDataEntry {
+	if m != nil {
+		return m.Users
+	}
+	return nil
+}
+
+func (m *ExportUserDataResponse) GetAddedLowFeeTickets() []*TicketEntry {
+	if m != nil {
+		return m.AddedLowFeeTickets
+	}
+	return nil
+}
+
 type GetAddedLowFeeTicketsRequest struct {
+	Options *TicketListOptions `protobuf:"bytes,1,opt,name=options" json:"options,omitempty"`
 }
 
 func (m *GetAddedLowFeeTicketsRequest) Reset()                    { *m = GetAddedLowFeeTicketsRequest{} }
 func (m *GetAddedLowFeeTicketsRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetAddedLowFeeTicketsRequest) ProtoMessage()               {}
-func (*GetAddedLowFeeTicketsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+func (*GetAddedLowFeeTicketsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *GetAddedLowFeeTicketsRequest) GetOptions() *TicketListOptions {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
 
 type GetAddedLowFeeTicketsResponse struct {
-	Tickets []*TicketEntry `protobuf:"bytes,1,rep,name=tickets" json:"tickets,omitempty"`
+	Tickets    []*TicketEntry `protobuf:"bytes,1,rep,name=tickets" json:"tickets,omitempty"`
+	NextCursor []byte         `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	Total      uint32         `protobuf:"varint,3,opt,name=total" json:"total,omitempty"`
 }
 
 func (m *GetAddedLowFeeTicketsResponse) Reset()                    { *m = GetAddedLowFeeTicketsResponse{} }
 func (m *GetAddedLowFeeTicketsResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetAddedLowFeeTicketsResponse) ProtoMessage()               {}
-func (*GetAddedLowFeeTicketsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*GetAddedLowFeeTicketsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
 
 func (m *GetAddedLowFeeTicketsResponse) GetTickets() []*TicketEntry {
 	if m != nil {
@@ -72,22 +498,78 @@ func (m *GetAddedLowFeeTicketsResponse) GetTickets() []*TicketEntry {
 	return nil
 }
 
+func (m *GetAddedLowFeeTicketsResponse) GetNextCursor() []byte {
+	if m != nil {
+		return m.NextCursor
+	}
+	return nil
+}
+
+func (m *GetAddedLowFeeTicketsResponse) GetTotal() uint32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+type GetDoubleVotesRequest struct {
+	SinceHeight int64 `protobuf:"varint,1,opt,name=since_height,json=sinceHeight" json:"since_height,omitempty"`
+}
+
+func (m *GetDoubleVotesRequest) Reset()                    { *m = GetDoubleVotesRequest{} }
+func (m *GetDoubleVotesRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetDoubleVotesRequest) ProtoMessage()               {}
+func (*GetDoubleVotesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *GetDoubleVotesRequest) GetSinceHeight() int64 {
+	if m != nil {
+		return m.SinceHeight
+	}
+	return 0
+}
+
+type GetDoubleVotesResponse struct {
+	DoubleVotes []*DoubleVote `protobuf:"bytes,1,rep,name=double_votes,json=doubleVotes" json:"double_votes,omitempty"`
+}
+
+func (m *GetDoubleVotesResponse) Reset()                    { *m = GetDoubleVotesResponse{} }
+func (m *GetDoubleVotesResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetDoubleVotesResponse) ProtoMessage()               {}
+func (*GetDoubleVotesResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *GetDoubleVotesResponse) GetDoubleVotes() []*DoubleVote {
+	if m != nil {
+		return m.DoubleVotes
+	}
+	return nil
+}
+
 type GetIgnoredLowFeeTicketsRequest struct {
+	Options *TicketListOptions `protobuf:"bytes,1,opt,name=options" json:"options,omitempty"`
 }
 
 func (m *GetIgnoredLowFeeTicketsRequest) Reset()                    { *m = GetIgnoredLowFeeTicketsRequest{} }
 func (m *GetIgnoredLowFeeTicketsRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetIgnoredLowFeeTicketsRequest) ProtoMessage()               {}
-func (*GetIgnoredLowFeeTicketsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*GetIgnoredLowFeeTicketsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *GetIgnoredLowFeeTicketsRequest) GetOptions() *TicketListOptions {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
 
 type GetIgnoredLowFeeTicketsResponse struct {
-	Tickets []*TicketEntry `protobuf:"bytes,1,rep,name=tickets" json:"tickets,omitempty"`
+	Tickets    []*TicketEntry `protobuf:"bytes,1,rep,name=tickets" json:"tickets,omitempty"`
+	NextCursor []byte         `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	Total      uint32         `protobuf:"varint,3,opt,name=total" json:"total,omitempty"`
 }
 
 func (m *GetIgnoredLowFeeTicketsResponse) Reset()                    { *m = GetIgnoredLowFeeTicketsResponse{} }
 func (m *GetIgnoredLowFeeTicketsResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetIgnoredLowFeeTicketsResponse) ProtoMessage()               {}
-func (*GetIgnoredLowFeeTicketsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*GetIgnoredLowFeeTicketsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
 
 func (m *GetIgnoredLowFeeTicketsResponse) GetTickets() []*TicketEntry {
 	if m != nil {
@@ -96,154 +578,1272 @@ func (m *GetIgnoredLowFeeTicketsResponse) GetTickets() []*TicketEntry {
 	return nil
 }
 
+func (m *GetIgnoredLowFeeTicketsResponse) GetNextCursor() []byte {
+	if m != nil {
+		return m.NextCursor
+	}
+	return nil
+}
+
+func (m *GetIgnoredLowFeeTicketsResponse) GetTotal() uint32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
 type GetLiveTicketsRequest struct {
+	Options *TicketListOptions `protobuf:"bytes,1,opt,name=options" json:"options,omitempty"`
 }
 
 func (m *GetLiveTicketsRequest) Reset()                    { *m = GetLiveTicketsRequest{} }
 func (m *GetLiveTicketsRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetLiveTicketsRequest) ProtoMessage()               {}
-func (*GetLiveTicketsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*GetLiveTicketsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *GetLiveTicketsRequest) GetOptions() *TicketListOptions {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
 
 type GetLiveTicketsResponse struct {
-	Tickets []*TicketEntry `protobuf:"bytes,1,rep,name=tickets" json:"tickets,omitempty"`
+	Tickets    []*TicketEntry `protobuf:"bytes,1,rep,name=tickets" json:"tickets,omitempty"`
+	NextCursor []byte         `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	Total      uint32         `protobuf:"varint,3,opt,name=total" json:"total,omitempty"`
 }
 
 func (m *GetLiveTicketsResponse) Reset()                    { *m = GetLiveTicketsResponse{} }
 func (m *GetLiveTicketsResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetLiveTicketsResponse) ProtoMessage()               {}
-func (*GetLiveTicketsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*GetLiveTicketsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
 
 func (m *GetLiveTicketsResponse) GetTickets() []*TicketEntry {
 	if m != nil {
-		return m.Tickets
+		return m.Tickets
+	}
+	return nil
+}
+
+func (m *GetLiveTicketsResponse) GetNextCursor() []byte {
+	if m != nil {
+		return m.NextCursor
+	}
+	return nil
+}
+
+func (m *GetLiveTicketsResponse) GetTotal() uint32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+type GetPoolStatsRequest struct {
+}
+
+func (m *GetPoolStatsRequest) Reset()                    { *m = GetPoolStatsRequest{} }
+func (m *GetPoolStatsRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetPoolStatsRequest) ProtoMessage()               {}
+func (*GetPoolStatsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+type GetPoolStatsResponse struct {
+	BlockHash       []byte `protobuf:"bytes,1,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	BlockHeight     int64  `protobuf:"varint,2,opt,name=block_height,json=blockHeight" json:"block_height,omitempty"`
+	PoolSize        uint32 `protobuf:"varint,3,opt,name=pool_size,json=poolSize" json:"pool_size,omitempty"`
+	StakeDifficulty int64  `protobuf:"varint,4,opt,name=stake_difficulty,json=stakeDifficulty" json:"stake_difficulty,omitempty"`
+	WindowSize      int64  `protobuf:"varint,5,opt,name=window_size,json=windowSize" json:"window_size,omitempty"`
+	WindowBlocks    int64  `protobuf:"varint,6,opt,name=window_blocks,json=windowBlocks" json:"window_blocks,omitempty"`
+	NetworkVotes    int64  `protobuf:"varint,7,opt,name=network_votes,json=networkVotes" json:"network_votes,omitempty"`
+	PoolVotes       int64  `protobuf:"varint,8,opt,name=pool_votes,json=poolVotes" json:"pool_votes,omitempty"`
+	LiveTickets     int64  `protobuf:"varint,9,opt,name=live_tickets,json=liveTickets" json:"live_tickets,omitempty"`
+	PoolMisses      int64  `protobuf:"varint,10,opt,name=pool_misses,json=poolMisses" json:"pool_misses,omitempty"`
+	TotalMisses     int64  `protobuf:"varint,11,opt,name=total_misses,json=totalMisses" json:"total_misses,omitempty"`
+}
+
+func (m *GetPoolStatsResponse) Reset()                    { *m = GetPoolStatsResponse{} }
+func (m *GetPoolStatsResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetPoolStatsResponse) ProtoMessage()               {}
+func (*GetPoolStatsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *GetPoolStatsResponse) GetBlockHash() []byte {
+	if m != nil {
+		return m.BlockHash
+	}
+	return nil
+}
+
+func (m *GetPoolStatsResponse) GetBlockHeight() int64 {
+	if m != nil {
+		return m.BlockHeight
+	}
+	return 0
+}
+
+func (m *GetPoolStatsResponse) GetPoolSize() uint32 {
+	if m != nil {
+		return m.PoolSize
+	}
+	return 0
+}
+
+func (m *GetPoolStatsResponse) GetStakeDifficulty() int64 {
+	if m != nil {
+		return m.StakeDifficulty
+	}
+	return 0
+}
+
+func (m *GetPoolStatsResponse) GetWindowSize() int64 {
+	if m != nil {
+		return m.WindowSize
+	}
+	return 0
+}
+
+func (m *GetPoolStatsResponse) GetWindowBlocks() int64 {
+	if m != nil {
+		return m.WindowBlocks
+	}
+	return 0
+}
+
+func (m *GetPoolStatsResponse) GetNetworkVotes() int64 {
+	if m != nil {
+		return m.NetworkVotes
+	}
+	return 0
+}
+
+func (m *GetPoolStatsResponse) GetPoolVotes() int64 {
+	if m != nil {
+		return m.PoolVotes
+	}
+	return 0
+}
+
+func (m *GetPoolStatsResponse) GetLiveTickets() int64 {
+	if m != nil {
+		return m.LiveTickets
+	}
+	return 0
+}
+
+func (m *GetPoolStatsResponse) GetPoolMisses() int64 {
+	if m != nil {
+		return m.PoolMisses
+	}
+	return 0
+}
+
+func (m *GetPoolStatsResponse) GetTotalMisses() int64 {
+	if m != nil {
+		return m.TotalMisses
+	}
+	return 0
+}
+
+type GetStakeDifficultyHistoryRequest struct {
+	SinceHeight int64 `protobuf:"varint,1,opt,name=since_height,json=sinceHeight" json:"since_height,omitempty"`
+}
+
+func (m *GetStakeDifficultyHistoryRequest) Reset()                    { *m = GetStakeDifficultyHistoryRequest{} }
+func (m *GetStakeDifficultyHistoryRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetStakeDifficultyHistoryRequest) ProtoMessage()               {}
+func (*GetStakeDifficultyHistoryRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *GetStakeDifficultyHistoryRequest) GetSinceHeight() int64 {
+	if m != nil {
+		return m.SinceHeight
+	}
+	return 0
+}
+
+type GetStakeDifficultyHistoryResponse struct {
+	Intervals []*StakeDifficultyInterval `protobuf:"bytes,1,rep,name=intervals" json:"intervals,omitempty"`
+}
+
+func (m *GetStakeDifficultyHistoryResponse) Reset()                    { *m = GetStakeDifficultyHistoryResponse{} }
+func (m *GetStakeDifficultyHistoryResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetStakeDifficultyHistoryResponse) ProtoMessage()               {}
+func (*GetStakeDifficultyHistoryResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *GetStakeDifficultyHistoryResponse) GetIntervals() []*StakeDifficultyInterval {
+	if m != nil {
+		return m.Intervals
+	}
+	return nil
+}
+
+type GetStatusRequest struct {
+}
+
+func (m *GetStatusRequest) Reset()                    { *m = GetStatusRequest{} }
+func (m *GetStatusRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetStatusRequest) ProtoMessage()               {}
+func (*GetStatusRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+type GetStatusResponse struct {
+	NodeConnected        bool   `protobuf:"varint,1,opt,name=node_connected,json=nodeConnected" json:"node_connected,omitempty"`
+	WalletConnected      bool   `protobuf:"varint,2,opt,name=wallet_connected,json=walletConnected" json:"wallet_connected,omitempty"`
+	BlockHeight          int64  `protobuf:"varint,3,opt,name=block_height,json=blockHeight" json:"block_height,omitempty"`
+	WalletHeight         int64  `protobuf:"varint,4,opt,name=wallet_height,json=walletHeight" json:"wallet_height,omitempty"`
+	Users                uint32 `protobuf:"varint,5,opt,name=users" json:"users,omitempty"`
+	LiveTickets          uint32 `protobuf:"varint,6,opt,name=live_tickets,json=liveTickets" json:"live_tickets,omitempty"`
+	AddedLowFeeTickets   uint32 `protobuf:"varint,7,opt,name=added_low_fee_tickets,json=addedLowFeeTickets" json:"added_low_fee_tickets,omitempty"`
+	IgnoredLowFeeTickets uint32 `protobuf:"varint,8,opt,name=ignored_low_fee_tickets,json=ignoredLowFeeTickets" json:"ignored_low_fee_tickets,omitempty"`
+	PendingCommands      uint32 `protobuf:"varint,9,opt,name=pending_commands,json=pendingCommands" json:"pending_commands,omitempty"`
+	PendingNotifications uint32 `protobuf:"varint,10,opt,name=pending_notifications,json=pendingNotifications" json:"pending_notifications,omitempty"`
+	VotingPaused         bool   `protobuf:"varint,11,opt,name=voting_paused,json=votingPaused" json:"voting_paused,omitempty"`
+	Fork                 string `protobuf:"bytes,12,opt,name=fork" json:"fork,omitempty"`
+}
+
+func (m *GetStatusResponse) Reset()                    { *m = GetStatusResponse{} }
+func (m *GetStatusResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetStatusResponse) ProtoMessage()               {}
+func (*GetStatusResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *GetStatusResponse) GetNodeConnected() bool {
+	if m != nil {
+		return m.NodeConnected
+	}
+	return false
+}
+
+func (m *GetStatusResponse) GetWalletConnected() bool {
+	if m != nil {
+		return m.WalletConnected
+	}
+	return false
+}
+
+func (m *GetStatusResponse) GetBlockHeight() int64 {
+	if m != nil {
+		return m.BlockHeight
+	}
+	return 0
+}
+
+func (m *GetStatusResponse) GetWalletHeight() int64 {
+	if m != nil {
+		return m.WalletHeight
+	}
+	return 0
+}
+
+func (m *GetStatusResponse) GetUsers() uint32 {
+	if m != nil {
+		return m.Users
+	}
+	return 0
+}
+
+func (m *GetStatusResponse) GetLiveTickets() uint32 {
+	if m != nil {
+		return m.LiveTickets
+	}
+	return 0
+}
+
+func (m *GetStatusResponse) GetAddedLowFeeTickets() uint32 {
+	if m != nil {
+		return m.AddedLowFeeTickets
+	}
+	return 0
+}
+
+func (m *GetStatusResponse) GetIgnoredLowFeeTickets() uint32 {
+	if m != nil {
+		return m.IgnoredLowFeeTickets
+	}
+	return 0
+}
+
+func (m *GetStatusResponse) GetPendingCommands() uint32 {
+	if m != nil {
+		return m.PendingCommands
+	}
+	return 0
+}
+
+func (m *GetStatusResponse) GetPendingNotifications() uint32 {
+	if m != nil {
+		return m.PendingNotifications
+	}
+	return 0
+}
+
+func (m *GetStatusResponse) GetVotingPaused() bool {
+	if m != nil {
+		return m.VotingPaused
+	}
+	return false
+}
+
+func (m *GetStatusResponse) GetFork() string {
+	if m != nil {
+		return m.Fork
+	}
+	return ""
+}
+
+type GetUserVotingStatsRequest struct {
+	MultisigAddresses []string `protobuf:"bytes,1,rep,name=multisig_addresses,json=multisigAddresses" json:"multisig_addresses,omitempty"`
+}
+
+func (m *GetUserVotingStatsRequest) Reset()                    { *m = GetUserVotingStatsRequest{} }
+func (m *GetUserVotingStatsRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetUserVotingStatsRequest) ProtoMessage()               {}
+func (*GetUserVotingStatsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *GetUserVotingStatsRequest) GetMultisigAddresses() []string {
+	if m != nil {
+		return m.MultisigAddresses
+	}
+	return nil
+}
+
+type GetUserVotingStatsResponse struct {
+	Users []*UserVotingStatsEntry `protobuf:"bytes,1,rep,name=users" json:"users,omitempty"`
+}
+
+func (m *GetUserVotingStatsResponse) Reset()                    { *m = GetUserVotingStatsResponse{} }
+func (m *GetUserVotingStatsResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetUserVotingStatsResponse) ProtoMessage()               {}
+func (*GetUserVotingStatsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *GetUserVotingStatsResponse) GetUsers() []*UserVotingStatsEntry {
+	if m != nil {
+		return m.Users
+	}
+	return nil
+}
+
+type GetVoteHistoryRequest struct {
+	SinceHeight       int64    `protobuf:"varint,1,opt,name=since_height,json=sinceHeight" json:"since_height,omitempty"`
+	MultisigAddresses []string `protobuf:"bytes,2,rep,name=multisig_addresses,json=multisigAddresses" json:"multisig_addresses,omitempty"`
+}
+
+func (m *GetVoteHistoryRequest) Reset()                    { *m = GetVoteHistoryRequest{} }
+func (m *GetVoteHistoryRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetVoteHistoryRequest) ProtoMessage()               {}
+func (*GetVoteHistoryRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *GetVoteHistoryRequest) GetSinceHeight() int64 {
+	if m != nil {
+		return m.SinceHeight
+	}
+	return 0
+}
+
+func (m *GetVoteHistoryRequest) GetMultisigAddresses() []string {
+	if m != nil {
+		return m.MultisigAddresses
+	}
+	return nil
+}
+
+type GetVoteHistoryResponse struct {
+	Events []*VoteHistoryEntry `protobuf:"bytes,1,rep,name=events" json:"events,omitempty"`
+}
+
+func (m *GetVoteHistoryResponse) Reset()                    { *m = GetVoteHistoryResponse{} }
+func (m *GetVoteHistoryResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetVoteHistoryResponse) ProtoMessage()               {}
+func (*GetVoteHistoryResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *GetVoteHistoryResponse) GetEvents() []*VoteHistoryEntry {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+type GetVoteLatencyRequest struct {
+}
+
+func (m *GetVoteLatencyRequest) Reset()                    { *m = GetVoteLatencyRequest{} }
+func (m *GetVoteLatencyRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetVoteLatencyRequest) ProtoMessage()               {}
+func (*GetVoteLatencyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+type GetVoteLatencyResponse struct {
+	Votes       int64 `protobuf:"varint,1,opt,name=votes" json:"votes,omitempty"`
+	WindowVotes int64 `protobuf:"varint,2,opt,name=window_votes,json=windowVotes" json:"window_votes,omitempty"`
+	P50Ns       int64 `protobuf:"varint,3,opt,name=p50_ns,json=p50Ns" json:"p50_ns,omitempty"`
+	P90Ns       int64 `protobuf:"varint,4,opt,name=p90_ns,json=p90Ns" json:"p90_ns,omitempty"`
+	P99Ns       int64 `protobuf:"varint,5,opt,name=p99_ns,json=p99Ns" json:"p99_ns,omitempty"`
+	MaxNs       int64 `protobuf:"varint,6,opt,name=max_ns,json=maxNs" json:"max_ns,omitempty"`
+}
+
+func (m *GetVoteLatencyResponse) Reset()                    { *m = GetVoteLatencyResponse{} }
+func (m *GetVoteLatencyResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetVoteLatencyResponse) ProtoMessage()               {}
+func (*GetVoteLatencyResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *GetVoteLatencyResponse) GetVotes() int64 {
+	if m != nil {
+		return m.Votes
+	}
+	return 0
+}
+
+func (m *GetVoteLatencyResponse) GetWindowVotes() int64 {
+	if m != nil {
+		return m.WindowVotes
+	}
+	return 0
+}
+
+func (m *GetVoteLatencyResponse) GetP50Ns() int64 {
+	if m != nil {
+		return m.P50Ns
+	}
+	return 0
+}
+
+func (m *GetVoteLatencyResponse) GetP90Ns() int64 {
+	if m != nil {
+		return m.P90Ns
+	}
+	return 0
+}
+
+func (m *GetVoteLatencyResponse) GetP99Ns() int64 {
+	if m != nil {
+		return m.P99Ns
+	}
+	return 0
+}
+
+func (m *GetVoteLatencyResponse) GetMaxNs() int64 {
+	if m != nil {
+		return m.MaxNs
+	}
+	return 0
+}
+
+type GetWalletInfoRequest struct {
+}
+
+func (m *GetWalletInfoRequest) Reset()                    { *m = GetWalletInfoRequest{} }
+func (m *GetWalletInfoRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetWalletInfoRequest) ProtoMessage()               {}
+func (*GetWalletInfoRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+type GetWalletInfoResponse struct {
+	WalletConnected bool   `protobuf:"varint,1,opt,name=wallet_connected,json=walletConnected" json:"wallet_connected,omitempty"`
+	Version         string `protobuf:"bytes,2,opt,name=version" json:"version,omitempty"`
+	Unlocked        bool   `protobuf:"varint,3,opt,name=unlocked" json:"unlocked,omitempty"`
+	DaemonConnected bool   `protobuf:"varint,4,opt,name=daemon_connected,json=daemonConnected" json:"daemon_connected,omitempty"`
+	Voting          bool   `protobuf:"varint,5,opt,name=voting" json:"voting,omitempty"`
+	BestBlockHeight int64  `protobuf:"varint,6,opt,name=best_block_height,json=bestBlockHeight" json:"best_block_height,omitempty"`
+	VoteVersion     uint32 `protobuf:"varint,7,opt,name=vote_version,json=voteVersion" json:"vote_version,omitempty"`
+	AutoUnlock      bool   `protobuf:"varint,8,opt,name=auto_unlock,json=autoUnlock" json:"auto_unlock,omitempty"`
+}
+
+func (m *GetWalletInfoResponse) Reset()                    { *m = GetWalletInfoResponse{} }
+func (m *GetWalletInfoResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetWalletInfoResponse) ProtoMessage()               {}
+func (*GetWalletInfoResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+
+func (m *GetWalletInfoResponse) GetWalletConnected() bool {
+	if m != nil {
+		return m.WalletConnected
+	}
+	return false
+}
+
+func (m *GetWalletInfoResponse) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *GetWalletInfoResponse) GetUnlocked() bool {
+	if m != nil {
+		return m.Unlocked
+	}
+	return false
+}
+
+func (m *GetWalletInfoResponse) GetDaemonConnected() bool {
+	if m != nil {
+		return m.DaemonConnected
+	}
+	return false
+}
+
+func (m *GetWalletInfoResponse) GetVoting() bool {
+	if m != nil {
+		return m.Voting
+	}
+	return false
+}
+
+func (m *GetWalletInfoResponse) GetBestBlockHeight() int64 {
+	if m != nil {
+		return m.BestBlockHeight
+	}
+	return 0
+}
+
+func (m *GetWalletInfoResponse) GetVoteVersion() uint32 {
+	if m != nil {
+		return m.VoteVersion
+	}
+	return 0
+}
+
+func (m *GetWalletInfoResponse) GetAutoUnlock() bool {
+	if m != nil {
+		return m.AutoUnlock
+	}
+	return false
+}
+
+type ImportUserDataRequest struct {
+	Users              []*UserDataEntry `protobuf:"bytes,1,rep,name=users" json:"users,omitempty"`
+	AddedLowFeeTickets []*TicketEntry   `protobuf:"bytes,2,rep,name=added_low_fee_tickets,json=addedLowFeeTickets" json:"added_low_fee_tickets,omitempty"`
+	RescanHeight       int64            `protobuf:"varint,3,opt,name=rescan_height,json=rescanHeight" json:"rescan_height,omitempty"`
+	DryRun             bool             `protobuf:"varint,4,opt,name=dry_run,json=dryRun" json:"dry_run,omitempty"`
+}
+
+func (m *ImportUserDataRequest) Reset()                    { *m = ImportUserDataRequest{} }
+func (m *ImportUserDataRequest) String() string            { return proto.CompactTextString(m) }
+func (*ImportUserDataRequest) ProtoMessage()               {}
+func (*ImportUserDataRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
+
+func (m *ImportUserDataRequest) GetUsers() []*UserDataEntry {
+	if m != nil {
+		return m.Users
+	}
+	return nil
+}
+
+func (m *ImportUserDataRequest) GetAddedLowFeeTickets() []*TicketEntry {
+	if m != nil {
+		return m.AddedLowFeeTickets
+	}
+	return nil
+}
+
+func (m *ImportUserDataRequest) GetRescanHeight() int64 {
+	if m != nil {
+		return m.RescanHeight
+	}
+	return 0
+}
+
+func (m *ImportUserDataRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+type ImportUserDataResponse struct {
+	Errors             []string `protobuf:"bytes,1,rep,name=errors" json:"errors,omitempty"`
+	Users              uint32   `protobuf:"varint,2,opt,name=users" json:"users,omitempty"`
+	ScriptsImported    uint32   `protobuf:"varint,3,opt,name=scripts_imported,json=scriptsImported" json:"scripts_imported,omitempty"`
+	AddedLowFeeTickets uint32   `protobuf:"varint,4,opt,name=added_low_fee_tickets,json=addedLowFeeTickets" json:"added_low_fee_tickets,omitempty"`
+}
+
+func (m *ImportUserDataResponse) Reset()                    { *m = ImportUserDataResponse{} }
+func (m *ImportUserDataResponse) String() string            { return proto.CompactTextString(m) }
+func (*ImportUserDataResponse) ProtoMessage()               {}
+func (*ImportUserDataResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+
+func (m *ImportUserDataResponse) GetErrors() []string {
+	if m != nil {
+		return m.Errors
+	}
+	return nil
+}
+
+func (m *ImportUserDataResponse) GetUsers() uint32 {
+	if m != nil {
+		return m.Users
+	}
+	return 0
+}
+
+func (m *ImportUserDataResponse) GetScriptsImported() uint32 {
+	if m != nil {
+		return m.ScriptsImported
+	}
+	return 0
+}
+
+func (m *ImportUserDataResponse) GetAddedLowFeeTickets() uint32 {
+	if m != nil {
+		return m.AddedLowFeeTickets
+	}
+	return 0
+}
+
+type MissingTicketResult struct {
+	TicketHash []byte              `protobuf:"bytes,1,opt,name=ticket_hash,json=ticketHash,proto3" json:"ticket_hash,omitempty"`
+	Status     MissingTicketStatus `protobuf:"varint,2,opt,name=status,enum=stakepoolrpc.MissingTicketStatus" json:"status,omitempty"`
+	Error      string              `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *MissingTicketResult) Reset()                    { *m = MissingTicketResult{} }
+func (m *MissingTicketResult) String() string            { return proto.CompactTextString(m) }
+func (*MissingTicketResult) ProtoMessage()               {}
+func (*MissingTicketResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+
+func (m *MissingTicketResult) GetTicketHash() []byte {
+	if m != nil {
+		return m.TicketHash
+	}
+	return nil
+}
+
+func (m *MissingTicketResult) GetStatus() MissingTicketStatus {
+	if m != nil {
+		return m.Status
+	}
+	return MissingTicketStatus_MISSING_TICKET_FAILED
+}
+
+func (m *MissingTicketResult) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type PingRequest struct {
+}
+
+func (m *PingRequest) Reset()                    { *m = PingRequest{} }
+func (m *PingRequest) String() string            { return proto.CompactTextString(m) }
+func (*PingRequest) ProtoMessage()               {}
+func (*PingRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+
+type PingResponse struct {
+}
+
+func (m *PingResponse) Reset()                    { *m = PingResponse{} }
+func (m *PingResponse) String() string            { return proto.CompactTextString(m) }
+func (*PingResponse) ProtoMessage()               {}
+func (*PingResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+type RescanWalletProgress struct {
+	BeginHeight    int64    `protobuf:"varint,1,opt,name=begin_height,json=beginHeight" json:"begin_height,omitempty"`
+	BestHeight     int64    `protobuf:"varint,2,opt,name=best_height,json=bestHeight" json:"best_height,omitempty"`
+	ElapsedSeconds int64    `protobuf:"varint,3,opt,name=elapsed_seconds,json=elapsedSeconds" json:"elapsed_seconds,omitempty"`
+	Done           bool     `protobuf:"varint,4,opt,name=done" json:"done,omitempty"`
+	LiveTickets    uint32   `protobuf:"varint,5,opt,name=live_tickets,json=liveTickets" json:"live_tickets,omitempty"`
+	FlaggedTickets [][]byte `protobuf:"bytes,6,rep,name=flagged_tickets,json=flaggedTickets,proto3" json:"flagged_tickets,omitempty"`
+}
+
+func (m *RescanWalletProgress) Reset()                    { *m = RescanWalletProgress{} }
+func (m *RescanWalletProgress) String() string            { return proto.CompactTextString(m) }
+func (*RescanWalletProgress) ProtoMessage()               {}
+func (*RescanWalletProgress) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+
+func (m *RescanWalletProgress) GetBeginHeight() int64 {
+	if m != nil {
+		return m.BeginHeight
+	}
+	return 0
+}
+
+func (m *RescanWalletProgress) GetBestHeight() int64 {
+	if m != nil {
+		return m.BestHeight
+	}
+	return 0
+}
+
+func (m *RescanWalletProgress) GetElapsedSeconds() int64 {
+	if m != nil {
+		return m.ElapsedSeconds
+	}
+	return 0
+}
+
+func (m *RescanWalletProgress) GetDone() bool {
+	if m != nil {
+		return m.Done
+	}
+	return false
+}
+
+func (m *RescanWalletProgress) GetLiveTickets() uint32 {
+	if m != nil {
+		return m.LiveTickets
+	}
+	return 0
+}
+
+func (m *RescanWalletProgress) GetFlaggedTickets() [][]byte {
+	if m != nil {
+		return m.FlaggedTickets
+	}
+	return nil
+}
+
+type RescanWalletRequest struct {
+	BeginHeight int64 `protobuf:"varint,1,opt,name=begin_height,json=beginHeight" json:"begin_height,omitempty"`
+}
+
+func (m *RescanWalletRequest) Reset()                    { *m = RescanWalletRequest{} }
+func (m *RescanWalletRequest) String() string            { return proto.CompactTextString(m) }
+func (*RescanWalletRequest) ProtoMessage()               {}
+func (*RescanWalletRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+
+func (m *RescanWalletRequest) GetBeginHeight() int64 {
+	if m != nil {
+		return m.BeginHeight
+	}
+	return 0
+}
+
+type RotateRPCCertificateRequest struct {
+}
+
+func (m *RotateRPCCertificateRequest) Reset()                    { *m = RotateRPCCertificateRequest{} }
+func (m *RotateRPCCertificateRequest) String() string            { return proto.CompactTextString(m) }
+func (*RotateRPCCertificateRequest) ProtoMessage()               {}
+func (*RotateRPCCertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
+
+type RotateRPCCertificateResponse struct {
+	Certificate []byte `protobuf:"bytes,1,opt,name=certificate,proto3" json:"certificate,omitempty"`
+	NotAfter    int64  `protobuf:"varint,2,opt,name=not_after,json=notAfter" json:"not_after,omitempty"`
+}
+
+func (m *RotateRPCCertificateResponse) Reset()                    { *m = RotateRPCCertificateResponse{} }
+func (m *RotateRPCCertificateResponse) String() string            { return proto.CompactTextString(m) }
+func (*RotateRPCCertificateResponse) ProtoMessage()               {}
+func (*RotateRPCCertificateResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{37} }
+
+func (m *RotateRPCCertificateResponse) GetCertificate() []byte {
+	if m != nil {
+		return m.Certificate
+	}
+	return nil
+}
+
+func (m *RotateRPCCertificateResponse) GetNotAfter() int64 {
+	if m != nil {
+		return m.NotAfter
+	}
+	return 0
+}
+
+type SetAddedLowFeeTicketsRequest struct {
+	Tickets []*TicketEntry `protobuf:"bytes,1,rep,name=tickets" json:"tickets,omitempty"`
+}
+
+func (m *SetAddedLowFeeTicketsRequest) Reset()                    { *m = SetAddedLowFeeTicketsRequest{} }
+func (m *SetAddedLowFeeTicketsRequest) String() string            { return proto.CompactTextString(m) }
+func (*SetAddedLowFeeTicketsRequest) ProtoMessage()               {}
+func (*SetAddedLowFeeTicketsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{38} }
+
+func (m *SetAddedLowFeeTicketsRequest) GetTickets() []*TicketEntry {
+	if m != nil {
+		return m.Tickets
+	}
+	return nil
+}
+
+type SetAddedLowFeeTicketsResponse struct {
+}
+
+func (m *SetAddedLowFeeTicketsResponse) Reset()                    { *m = SetAddedLowFeeTicketsResponse{} }
+func (m *SetAddedLowFeeTicketsResponse) String() string            { return proto.CompactTextString(m) }
+func (*SetAddedLowFeeTicketsResponse) ProtoMessage()               {}
+func (*SetAddedLowFeeTicketsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{39} }
+
+type SetUserVotingPrefsResponse struct {
+}
+
+func (m *SetUserVotingPrefsResponse) Reset()                    { *m = SetUserVotingPrefsResponse{} }
+func (m *SetUserVotingPrefsResponse) String() string            { return proto.CompactTextString(m) }
+func (*SetUserVotingPrefsResponse) ProtoMessage()               {}
+func (*SetUserVotingPrefsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{40} }
+
+type SetUserVotingPrefsRequest struct {
+	UserVotingConfig []*UserVotingConfigEntry `protobuf:"bytes,1,rep,name=user_voting_config,json=userVotingConfig" json:"user_voting_config,omitempty"`
+}
+
+func (m *SetUserVotingPrefsRequest) Reset()                    { *m = SetUserVotingPrefsRequest{} }
+func (m *SetUserVotingPrefsRequest) String() string            { return proto.CompactTextString(m) }
+func (*SetUserVotingPrefsRequest) ProtoMessage()               {}
+func (*SetUserVotingPrefsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{41} }
+
+func (m *SetUserVotingPrefsRequest) GetUserVotingConfig() []*UserVotingConfigEntry {
+	if m != nil {
+		return m.UserVotingConfig
+	}
+	return nil
+}
+
+type SpentMissedNotification struct {
+	BlockHash   []byte                    `protobuf:"bytes,1,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	BlockHeight int64                     `protobuf:"varint,2,opt,name=block_height,json=blockHeight" json:"block_height,omitempty"`
+	Tickets     []*SpentMissedTicketEntry `protobuf:"bytes,3,rep,name=tickets" json:"tickets,omitempty"`
+}
+
+func (m *SpentMissedNotification) Reset()                    { *m = SpentMissedNotification{} }
+func (m *SpentMissedNotification) String() string            { return proto.CompactTextString(m) }
+func (*SpentMissedNotification) ProtoMessage()               {}
+func (*SpentMissedNotification) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{42} }
+
+func (m *SpentMissedNotification) GetBlockHash() []byte {
+	if m != nil {
+		return m.BlockHash
+	}
+	return nil
+}
+
+func (m *SpentMissedNotification) GetBlockHeight() int64 {
+	if m != nil {
+		return m.BlockHeight
+	}
+	return 0
+}
+
+func (m *SpentMissedNotification) GetTickets() []*SpentMissedTicketEntry {
+	if m != nil {
+		return m.Tickets
+	}
+	return nil
+}
+
+type SpentMissedTicketEntry struct {
+	TicketHash      []byte `protobuf:"bytes,1,opt,name=ticket_hash,json=ticketHash,proto3" json:"ticket_hash,omitempty"`
+	Spent           bool   `protobuf:"varint,2,opt,name=spent" json:"spent,omitempty"`
+	MultisigAddress string `protobuf:"bytes,3,opt,name=multisig_address,json=multisigAddress" json:"multisig_address,omitempty"`
+}
+
+func (m *SpentMissedTicketEntry) Reset()                    { *m = SpentMissedTicketEntry{} }
+func (m *SpentMissedTicketEntry) String() string            { return proto.CompactTextString(m) }
+func (*SpentMissedTicketEntry) ProtoMessage()               {}
+func (*SpentMissedTicketEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{43} }
+
+func (m *SpentMissedTicketEntry) GetTicketHash() []byte {
+	if m != nil {
+		return m.TicketHash
+	}
+	return nil
+}
+
+func (m *SpentMissedTicketEntry) GetSpent() bool {
+	if m != nil {
+		return m.Spent
+	}
+	return false
+}
+
+func (m *SpentMissedTicketEntry) GetMultisigAddress() string {
+	if m != nil {
+		return m.MultisigAddress
+	}
+	return ""
+}
+
+type StakeDifficultyInterval struct {
+	StartHeight     int64  `protobuf:"varint,1,opt,name=start_height,json=startHeight" json:"start_height,omitempty"`
+	StakeDifficulty int64  `protobuf:"varint,2,opt,name=stake_difficulty,json=stakeDifficulty" json:"stake_difficulty,omitempty"`
+	PoolSize        uint32 `protobuf:"varint,3,opt,name=pool_size,json=poolSize" json:"pool_size,omitempty"`
+	Time            int64  `protobuf:"varint,4,opt,name=time" json:"time,omitempty"`
+}
+
+func (m *StakeDifficultyInterval) Reset()                    { *m = StakeDifficultyInterval{} }
+func (m *StakeDifficultyInterval) String() string            { return proto.CompactTextString(m) }
+func (*StakeDifficultyInterval) ProtoMessage()               {}
+func (*StakeDifficultyInterval) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{44} }
+
+func (m *StakeDifficultyInterval) GetStartHeight() int64 {
+	if m != nil {
+		return m.StartHeight
+	}
+	return 0
+}
+
+func (m *StakeDifficultyInterval) GetStakeDifficulty() int64 {
+	if m != nil {
+		return m.StakeDifficulty
+	}
+	return 0
+}
+
+func (m *StakeDifficultyInterval) GetPoolSize() uint32 {
+	if m != nil {
+		return m.PoolSize
+	}
+	return 0
+}
+
+func (m *StakeDifficultyInterval) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+type SubscribeSpentMissedRequest struct {
+	PoolOnly bool `protobuf:"varint,1,opt,name=pool_only,json=poolOnly" json:"pool_only,omitempty"`
+}
+
+func (m *SubscribeSpentMissedRequest) Reset()                    { *m = SubscribeSpentMissedRequest{} }
+func (m *SubscribeSpentMissedRequest) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeSpentMissedRequest) ProtoMessage()               {}
+func (*SubscribeSpentMissedRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{45} }
+
+func (m *SubscribeSpentMissedRequest) GetPoolOnly() bool {
+	if m != nil {
+		return m.PoolOnly
+	}
+	return false
+}
+
+type SubscribeVoteHistoryRequest struct {
+	SinceHeight int64 `protobuf:"varint,1,opt,name=since_height,json=sinceHeight" json:"since_height,omitempty"`
+}
+
+func (m *SubscribeVoteHistoryRequest) Reset()                    { *m = SubscribeVoteHistoryRequest{} }
+func (m *SubscribeVoteHistoryRequest) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeVoteHistoryRequest) ProtoMessage()               {}
+func (*SubscribeVoteHistoryRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{46} }
+
+func (m *SubscribeVoteHistoryRequest) GetSinceHeight() int64 {
+	if m != nil {
+		return m.SinceHeight
+	}
+	return 0
+}
+
+type TicketEntry struct {
+	TicketAddress  string `protobuf:"bytes,1,opt,name=TicketAddress" json:"TicketAddress,omitempty"`
+	TicketHash     []byte `protobuf:"bytes,2,opt,name=TicketHash,proto3" json:"TicketHash,omitempty"`
+	PurchaseHeight int64  `protobuf:"varint,3,opt,name=purchase_height,json=purchaseHeight" json:"purchase_height,omitempty"`
+}
+
+func (m *TicketEntry) Reset()                    { *m = TicketEntry{} }
+func (m *TicketEntry) String() string            { return proto.CompactTextString(m) }
+func (*TicketEntry) ProtoMessage()               {}
+func (*TicketEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{47} }
+
+func (m *TicketEntry) GetTicketAddress() string {
+	if m != nil {
+		return m.TicketAddress
+	}
+	return ""
+}
+
+func (m *TicketEntry) GetTicketHash() []byte {
+	if m != nil {
+		return m.TicketHash
+	}
+	return nil
+}
+
+func (m *TicketEntry) GetPurchaseHeight() int64 {
+	if m != nil {
+		return m.PurchaseHeight
+	}
+	return 0
+}
+
+type TicketListOptions struct {
+	Limit             uint32          `protobuf:"varint,1,opt,name=limit" json:"limit,omitempty"`
+	Cursor            []byte          `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	MultisigAddress   string          `protobuf:"bytes,3,opt,name=multisig_address,json=multisigAddress" json:"multisig_address,omitempty"`
+	Status            TicketStatus    `protobuf:"varint,4,opt,name=status,enum=stakepoolrpc.TicketStatus" json:"status,omitempty"`
+	FeeStatus         TicketFeeStatus `protobuf:"varint,5,opt,name=fee_status,json=feeStatus,enum=stakepoolrpc.TicketFeeStatus" json:"fee_status,omitempty"`
+	MinPurchaseHeight int64           `protobuf:"varint,6,opt,name=min_purchase_height,json=minPurchaseHeight" json:"min_purchase_height,omitempty"`
+	MaxPurchaseHeight int64           `protobuf:"varint,7,opt,name=max_purchase_height,json=maxPurchaseHeight" json:"max_purchase_height,omitempty"`
+	Fields            []string        `protobuf:"bytes,8,rep,name=fields" json:"fields,omitempty"`
+}
+
+func (m *TicketListOptions) Reset()                    { *m = TicketListOptions{} }
+func (m *TicketListOptions) String() string            { return proto.CompactTextString(m) }
+func (*TicketListOptions) ProtoMessage()               {}
+func (*TicketListOptions) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{48} }
+
+func (m *TicketListOptions) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *TicketListOptions) GetCursor() []byte {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
+func (m *TicketListOptions) GetMultisigAddress() string {
+	if m != nil {
+		return m.MultisigAddress
+	}
+	return ""
+}
+
+func (m *TicketListOptions) GetStatus() TicketStatus {
+	if m != nil {
+		return m.Status
+	}
+	return TicketStatus_ANY
+}
+
+func (m *TicketListOptions) GetFeeStatus() TicketFeeStatus {
+	if m != nil {
+		return m.FeeStatus
+	}
+	return TicketFeeStatus_ANY_FEE
+}
+
+func (m *TicketListOptions) GetMinPurchaseHeight() int64 {
+	if m != nil {
+		return m.MinPurchaseHeight
+	}
+	return 0
+}
+
+func (m *TicketListOptions) GetMaxPurchaseHeight() int64 {
+	if m != nil {
+		return m.MaxPurchaseHeight
+	}
+	return 0
+}
+
+func (m *TicketListOptions) GetFields() []string {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+type UserDataEntry struct {
+	VotingConfig *UserVotingConfigEntry `protobuf:"bytes,1,opt,name=voting_config,json=votingConfig" json:"voting_config,omitempty"`
+	RedeemScript []byte                 `protobuf:"bytes,2,opt,name=redeem_script,json=redeemScript,proto3" json:"redeem_script,omitempty"`
+}
+
+func (m *UserDataEntry) Reset()                    { *m = UserDataEntry{} }
+func (m *UserDataEntry) String() string            { return proto.CompactTextString(m) }
+func (*UserDataEntry) ProtoMessage()               {}
+func (*UserDataEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{49} }
+
+func (m *UserDataEntry) GetVotingConfig() *UserVotingConfigEntry {
+	if m != nil {
+		return m.VotingConfig
+	}
+	return nil
+}
+
+func (m *UserDataEntry) GetRedeemScript() []byte {
+	if m != nil {
+		return m.RedeemScript
+	}
+	return nil
+}
+
+type UserVotingStatsEntry struct {
+	MultisigAddress string `protobuf:"bytes,1,opt,name=multisig_address,json=multisigAddress" json:"multisig_address,omitempty"`
+	Votes           int64  `protobuf:"varint,2,opt,name=votes" json:"votes,omitempty"`
+	Misses          int64  `protobuf:"varint,3,opt,name=misses" json:"misses,omitempty"`
+	Reward          int64  `protobuf:"varint,4,opt,name=reward" json:"reward,omitempty"`
+	Fallbacks       int64  `protobuf:"varint,5,opt,name=fallbacks" json:"fallbacks,omitempty"`
+}
+
+func (m *UserVotingStatsEntry) Reset()                    { *m = UserVotingStatsEntry{} }
+func (m *UserVotingStatsEntry) String() string            { return proto.CompactTextString(m) }
+func (*UserVotingStatsEntry) ProtoMessage()               {}
+func (*UserVotingStatsEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{50} }
+
+func (m *UserVotingStatsEntry) GetMultisigAddress() string {
+	if m != nil {
+		return m.MultisigAddress
+	}
+	return ""
+}
+
+func (m *UserVotingStatsEntry) GetVotes() int64 {
+	if m != nil {
+		return m.Votes
+	}
+	return 0
+}
+
+func (m *UserVotingStatsEntry) GetMisses() int64 {
+	if m != nil {
+		return m.Misses
+	}
+	return 0
+}
+
+func (m *UserVotingStatsEntry) GetReward() int64 {
+	if m != nil {
+		return m.Reward
+	}
+	return 0
+}
+
+func (m *UserVotingStatsEntry) GetFallbacks() int64 {
+	if m != nil {
+		return m.Fallbacks
+	}
+	return 0
+}
+
+type UserVotingConfigEntry struct {
+	UserId          int64  `protobuf:"varint,1,opt,name=UserId" json:"UserId,omitempty"`
+	MultiSigAddress string `protobuf:"bytes,2,opt,name=MultiSigAddress" json:"MultiSigAddress,omitempty"`
+	VoteBits        int64  `protobuf:"varint,3,opt,name=VoteBits" json:"VoteBits,omitempty"`
+	VoteBitsVersion int64  `protobuf:"varint,4,opt,name=VoteBitsVersion" json:"VoteBitsVersion,omitempty"`
+}
+
+func (m *UserVotingConfigEntry) Reset()                    { *m = UserVotingConfigEntry{} }
+func (m *UserVotingConfigEntry) String() string            { return proto.CompactTextString(m) }
+func (*UserVotingConfigEntry) ProtoMessage()               {}
+func (*UserVotingConfigEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{51} }
+
+func (m *UserVotingConfigEntry) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
 	}
-	return nil
+	return 0
 }
 
-type PingRequest struct {
+func (m *UserVotingConfigEntry) GetMultiSigAddress() string {
+	if m != nil {
+		return m.MultiSigAddress
+	}
+	return ""
 }
 
-func (m *PingRequest) Reset()                    { *m = PingRequest{} }
-func (m *PingRequest) String() string            { return proto.CompactTextString(m) }
-func (*PingRequest) ProtoMessage()               {}
-func (*PingRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
-
-type PingResponse struct {
+func (m *UserVotingConfigEntry) GetVoteBits() int64 {
+	if m != nil {
+		return m.VoteBits
+	}
+	return 0
 }
 
-func (m *PingResponse) Reset()                    { *m = PingResponse{} }
-func (m *PingResponse) String() string            { return proto.CompactTextString(m) }
-func (*PingResponse) ProtoMessage()               {}
-func (*PingResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+func (m *UserVotingConfigEntry) GetVoteBitsVersion() int64 {
+	if m != nil {
+		return m.VoteBitsVersion
+	}
+	return 0
+}
 
-type SetAddedLowFeeTicketsRequest struct {
-	Tickets []*TicketEntry `protobuf:"bytes,1,rep,name=tickets" json:"tickets,omitempty"`
+type VerifyColdWalletExtPubRequest struct {
+	ColdWalletExtPub string `protobuf:"bytes,1,opt,name=cold_wallet_ext_pub,json=coldWalletExtPub" json:"cold_wallet_ext_pub,omitempty"`
 }
 
-func (m *SetAddedLowFeeTicketsRequest) Reset()                    { *m = SetAddedLowFeeTicketsRequest{} }
-func (m *SetAddedLowFeeTicketsRequest) String() string            { return proto.CompactTextString(m) }
-func (*SetAddedLowFeeTicketsRequest) ProtoMessage()               {}
-func (*SetAddedLowFeeTicketsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (m *VerifyColdWalletExtPubRequest) Reset()                    { *m = VerifyColdWalletExtPubRequest{} }
+func (m *VerifyColdWalletExtPubRequest) String() string            { return proto.CompactTextString(m) }
+func (*VerifyColdWalletExtPubRequest) ProtoMessage()               {}
+func (*VerifyColdWalletExtPubRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{52} }
 
-func (m *SetAddedLowFeeTicketsRequest) GetTickets() []*TicketEntry {
+func (m *VerifyColdWalletExtPubRequest) GetColdWalletExtPub() string {
 	if m != nil {
-		return m.Tickets
+		return m.ColdWalletExtPub
 	}
-	return nil
+	return ""
 }
 
-type SetAddedLowFeeTicketsResponse struct {
+type VerifyColdWalletExtPubResponse struct {
+	TestAddress string `protobuf:"bytes,1,opt,name=test_address,json=testAddress" json:"test_address,omitempty"`
 }
 
-func (m *SetAddedLowFeeTicketsResponse) Reset()                    { *m = SetAddedLowFeeTicketsResponse{} }
-func (m *SetAddedLowFeeTicketsResponse) String() string            { return proto.CompactTextString(m) }
-func (*SetAddedLowFeeTicketsResponse) ProtoMessage()               {}
-func (*SetAddedLowFeeTicketsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+func (m *VerifyColdWalletExtPubResponse) Reset()                    { *m = VerifyColdWalletExtPubResponse{} }
+func (m *VerifyColdWalletExtPubResponse) String() string            { return proto.CompactTextString(m) }
+func (*VerifyColdWalletExtPubResponse) ProtoMessage()               {}
+func (*VerifyColdWalletExtPubResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{53} }
 
-type SetUserVotingPrefsResponse struct {
+func (m *VerifyColdWalletExtPubResponse) GetTestAddress() string {
+	if m != nil {
+		return m.TestAddress
+	}
+	return ""
 }
 
-func (m *SetUserVotingPrefsResponse) Reset()                    { *m = SetUserVotingPrefsResponse{} }
-func (m *SetUserVotingPrefsResponse) String() string            { return proto.CompactTextString(m) }
-func (*SetUserVotingPrefsResponse) ProtoMessage()               {}
-func (*SetUserVotingPrefsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
-
-type SetUserVotingPrefsRequest struct {
-	UserVotingConfig []*UserVotingConfigEntry `protobuf:"bytes,1,rep,name=user_voting_config,json=userVotingConfig" json:"user_voting_config,omitempty"`
+type VoteHistoryEntry struct {
+	TicketHash      []byte    `protobuf:"bytes,1,opt,name=ticket_hash,json=ticketHash,proto3" json:"ticket_hash,omitempty"`
+	MultisigAddress string    `protobuf:"bytes,2,opt,name=multisig_address,json=multisigAddress" json:"multisig_address,omitempty"`
+	Event           VoteEvent `protobuf:"varint,3,opt,name=event,enum=stakepoolrpc.VoteEvent" json:"event,omitempty"`
+	BlockHash       []byte    `protobuf:"bytes,4,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	BlockHeight     int64     `protobuf:"varint,5,opt,name=block_height,json=blockHeight" json:"block_height,omitempty"`
+	VoteHash        []byte    `protobuf:"bytes,6,opt,name=vote_hash,json=voteHash,proto3" json:"vote_hash,omitempty"`
+	VoteBits        uint32    `protobuf:"varint,7,opt,name=vote_bits,json=voteBits" json:"vote_bits,omitempty"`
+	Reward          int64     `protobuf:"varint,8,opt,name=reward" json:"reward,omitempty"`
+	Time            int64     `protobuf:"varint,9,opt,name=time" json:"time,omitempty"`
 }
 
-func (m *SetUserVotingPrefsRequest) Reset()                    { *m = SetUserVotingPrefsRequest{} }
-func (m *SetUserVotingPrefsRequest) String() string            { return proto.CompactTextString(m) }
-func (*SetUserVotingPrefsRequest) ProtoMessage()               {}
-func (*SetUserVotingPrefsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+func (m *VoteHistoryEntry) Reset()                    { *m = VoteHistoryEntry{} }
+func (m *VoteHistoryEntry) String() string            { return proto.CompactTextString(m) }
+func (*VoteHistoryEntry) ProtoMessage()               {}
+func (*VoteHistoryEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{54} }
 
-func (m *SetUserVotingPrefsRequest) GetUserVotingConfig() []*UserVotingConfigEntry {
+func (m *VoteHistoryEntry) GetTicketHash() []byte {
 	if m != nil {
-		return m.UserVotingConfig
+		return m.TicketHash
 	}
 	return nil
 }
 
-type TicketEntry struct {
-	TicketAddress string `protobuf:"bytes,1,opt,name=TicketAddress" json:"TicketAddress,omitempty"`
-	TicketHash    []byte `protobuf:"bytes,2,opt,name=TicketHash,proto3" json:"TicketHash,omitempty"`
-}
-
-func (m *TicketEntry) Reset()                    { *m = TicketEntry{} }
-func (m *TicketEntry) String() string            { return proto.CompactTextString(m) }
-func (*TicketEntry) ProtoMessage()               {}
-func (*TicketEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
-
-func (m *TicketEntry) GetTicketAddress() string {
+func (m *VoteHistoryEntry) GetMultisigAddress() string {
 	if m != nil {
-		return m.TicketAddress
+		return m.MultisigAddress
 	}
 	return ""
 }
 
-func (m *TicketEntry) GetTicketHash() []byte {
+func (m *VoteHistoryEntry) GetEvent() VoteEvent {
 	if m != nil {
-		return m.TicketHash
+		return m.Event
 	}
-	return nil
+	return VoteEvent_SELECTED
 }
 
-type UserVotingConfigEntry struct {
-	UserId          int64  `protobuf:"varint,1,opt,name=UserId" json:"UserId,omitempty"`
-	MultiSigAddress string `protobuf:"bytes,2,opt,name=MultiSigAddress" json:"MultiSigAddress,omitempty"`
-	VoteBits        int64  `protobuf:"varint,3,opt,name=VoteBits" json:"VoteBits,omitempty"`
-	VoteBitsVersion int64  `protobuf:"varint,4,opt,name=VoteBitsVersion" json:"VoteBitsVersion,omitempty"`
+func (m *VoteHistoryEntry) GetBlockHash() []byte {
+	if m != nil {
+		return m.BlockHash
+	}
+	return nil
 }
 
-func (m *UserVotingConfigEntry) Reset()                    { *m = UserVotingConfigEntry{} }
-func (m *UserVotingConfigEntry) String() string            { return proto.CompactTextString(m) }
-func (*UserVotingConfigEntry) ProtoMessage()               {}
-func (*UserVotingConfigEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
-
-func (m *UserVotingConfigEntry) GetUserId() int64 {
+func (m *VoteHistoryEntry) GetBlockHeight() int64 {
 	if m != nil {
-		return m.UserId
+		return m.BlockHeight
 	}
 	return 0
 }
 
-func (m *UserVotingConfigEntry) GetMultiSigAddress() string {
+func (m *VoteHistoryEntry) GetVoteHash() []byte {
 	if m != nil {
-		return m.MultiSigAddress
+		return m.VoteHash
 	}
-	return ""
+	return nil
 }
 
-func (m *UserVotingConfigEntry) GetVoteBits() int64 {
+func (m *VoteHistoryEntry) GetVoteBits() uint32 {
 	if m != nil {
 		return m.VoteBits
 	}
 	return 0
 }
 
-func (m *UserVotingConfigEntry) GetVoteBitsVersion() int64 {
+func (m *VoteHistoryEntry) GetReward() int64 {
 	if m != nil {
-		return m.VoteBitsVersion
+		return m.Reward
+	}
+	return 0
+}
+
+func (m *VoteHistoryEntry) GetTime() int64 {
+	if m != nil {
+		return m.Time
 	}
 	return 0
 }
@@ -254,21 +1854,25 @@ type VersionRequest struct {
 func (m *VersionRequest) Reset()                    { *m = VersionRequest{} }
 func (m *VersionRequest) String() string            { return proto.CompactTextString(m) }
 func (*VersionRequest) ProtoMessage()               {}
-func (*VersionRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+func (*VersionRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{55} }
 
 type VersionResponse struct {
-	VersionString string `protobuf:"bytes,1,opt,name=version_string,json=versionString" json:"version_string,omitempty"`
-	Major         uint32 `protobuf:"varint,2,opt,name=major" json:"major,omitempty"`
-	Minor         uint32 `protobuf:"varint,3,opt,name=minor" json:"minor,omitempty"`
-	Patch         uint32 `protobuf:"varint,4,opt,name=patch" json:"patch,omitempty"`
-	Prerelease    string `protobuf:"bytes,5,opt,name=prerelease" json:"prerelease,omitempty"`
-	BuildMetadata string `protobuf:"bytes,6,opt,name=build_metadata,json=buildMetadata" json:"build_metadata,omitempty"`
+	VersionString string       `protobuf:"bytes,1,opt,name=version_string,json=versionString" json:"version_string,omitempty"`
+	Major         uint32       `protobuf:"varint,2,opt,name=major" json:"major,omitempty"`
+	Minor         uint32       `protobuf:"varint,3,opt,name=minor" json:"minor,omitempty"`
+	Patch         uint32       `protobuf:"varint,4,opt,name=patch" json:"patch,omitempty"`
+	Prerelease    string       `protobuf:"bytes,5,opt,name=prerelease" json:"prerelease,omitempty"`
+	BuildMetadata string       `protobuf:"bytes,6,opt,name=build_metadata,json=buildMetadata" json:"build_metadata,omitempty"`
+	AppVersion    string       `protobuf:"bytes,7,opt,name=app_version,json=appVersion" json:"app_version,omitempty"`
+	AppCommit     string       `protobuf:"bytes,8,opt,name=app_commit,json=appCommit" json:"app_commit,omitempty"`
+	AppBuildDate  string       `protobuf:"bytes,9,opt,name=app_build_date,json=appBuildDate" json:"app_build_date,omitempty"`
+	Capabilities  []Capability `protobuf:"varint,10,rep,packed,name=capabilities,enum=stakepoolrpc.Capability" json:"capabilities,omitempty"`
 }
 
 func (m *VersionResponse) Reset()                    { *m = VersionResponse{} }
 func (m *VersionResponse) String() string            { return proto.CompactTextString(m) }
 func (*VersionResponse) ProtoMessage()               {}
-func (*VersionResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+func (*VersionResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{56} }
 
 func (m *VersionResponse) GetVersionString() string {
 	if m != nil {
@@ -312,23 +1916,98 @@ func (m *VersionResponse) GetBuildMetadata() string {
 	return ""
 }
 
+func (m *VersionResponse) GetAppVersion() string {
+	if m != nil {
+		return m.AppVersion
+	}
+	return ""
+}
+
+func (m *VersionResponse) GetAppCommit() string {
+	if m != nil {
+		return m.AppCommit
+	}
+	return ""
+}
+
+func (m *VersionResponse) GetAppBuildDate() string {
+	if m != nil {
+		return m.AppBuildDate
+	}
+	return ""
+}
+
+func (m *VersionResponse) GetCapabilities() []Capability {
+	if m != nil {
+		return m.Capabilities
+	}
+	return nil
+}
+
 func init() {
+	proto.RegisterType((*AddMissingTicketsRequest)(nil), "stakepoolrpc.AddMissingTicketsRequest")
+	proto.RegisterType((*AddMissingTicketsResponse)(nil), "stakepoolrpc.AddMissingTicketsResponse")
+	proto.RegisterType((*BatchSetUserVotingPrefsRequest)(nil), "stakepoolrpc.BatchSetUserVotingPrefsRequest")
+	proto.RegisterType((*BatchSetUserVotingPrefsResponse)(nil), "stakepoolrpc.BatchSetUserVotingPrefsResponse")
+	proto.RegisterType((*DoubleVote)(nil), "stakepoolrpc.DoubleVote")
+	proto.RegisterType((*ExportUserDataRequest)(nil), "stakepoolrpc.ExportUserDataRequest")
+	proto.RegisterType((*ExportUserDataResponse)(nil), "stakepoolrpc.ExportUserDataResponse")
 	proto.RegisterType((*GetAddedLowFeeTicketsRequest)(nil), "stakepoolrpc.GetAddedLowFeeTicketsRequest")
 	proto.RegisterType((*GetAddedLowFeeTicketsResponse)(nil), "stakepoolrpc.GetAddedLowFeeTicketsResponse")
+	proto.RegisterType((*GetDoubleVotesRequest)(nil), "stakepoolrpc.GetDoubleVotesRequest")
+	proto.RegisterType((*GetDoubleVotesResponse)(nil), "stakepoolrpc.GetDoubleVotesResponse")
 	proto.RegisterType((*GetIgnoredLowFeeTicketsRequest)(nil), "stakepoolrpc.GetIgnoredLowFeeTicketsRequest")
 	proto.RegisterType((*GetIgnoredLowFeeTicketsResponse)(nil), "stakepoolrpc.GetIgnoredLowFeeTicketsResponse")
 	proto.RegisterType((*GetLiveTicketsRequest)(nil), "stakepoolrpc.GetLiveTicketsRequest")
 	proto.RegisterType((*GetLiveTicketsResponse)(nil), "stakepoolrpc.GetLiveTicketsResponse")
+	proto.RegisterType((*GetPoolStatsRequest)(nil), "stakepoolrpc.GetPoolStatsRequest")
+	proto.RegisterType((*GetPoolStatsResponse)(nil), "stakepoolrpc.GetPoolStatsResponse")
+	proto.RegisterType((*GetStakeDifficultyHistoryRequest)(nil), "stakepoolrpc.GetStakeDifficultyHistoryRequest")
+	proto.RegisterType((*GetStakeDifficultyHistoryResponse)(nil), "stakepoolrpc.GetStakeDifficultyHistoryResponse")
+	proto.RegisterType((*GetStatusRequest)(nil), "stakepoolrpc.GetStatusRequest")
+	proto.RegisterType((*GetStatusResponse)(nil), "stakepoolrpc.GetStatusResponse")
+	proto.RegisterType((*GetUserVotingStatsRequest)(nil), "stakepoolrpc.GetUserVotingStatsRequest")
+	proto.RegisterType((*GetUserVotingStatsResponse)(nil), "stakepoolrpc.GetUserVotingStatsResponse")
+	proto.RegisterType((*GetVoteHistoryRequest)(nil), "stakepoolrpc.GetVoteHistoryRequest")
+	proto.RegisterType((*GetVoteHistoryResponse)(nil), "stakepoolrpc.GetVoteHistoryResponse")
+	proto.RegisterType((*GetVoteLatencyRequest)(nil), "stakepoolrpc.GetVoteLatencyRequest")
+	proto.RegisterType((*GetVoteLatencyResponse)(nil), "stakepoolrpc.GetVoteLatencyResponse")
+	proto.RegisterType((*GetWalletInfoRequest)(nil), "stakepoolrpc.GetWalletInfoRequest")
+	proto.RegisterType((*GetWalletInfoResponse)(nil), "stakepoolrpc.GetWalletInfoResponse")
+	proto.RegisterType((*ImportUserDataRequest)(nil), "stakepoolrpc.ImportUserDataRequest")
+	proto.RegisterType((*ImportUserDataResponse)(nil), "stakepoolrpc.ImportUserDataResponse")
+	proto.RegisterType((*MissingTicketResult)(nil), "stakepoolrpc.MissingTicketResult")
 	proto.RegisterType((*PingRequest)(nil), "stakepoolrpc.PingRequest")
 	proto.RegisterType((*PingResponse)(nil), "stakepoolrpc.PingResponse")
+	proto.RegisterType((*RescanWalletProgress)(nil), "stakepoolrpc.RescanWalletProgress")
+	proto.RegisterType((*RescanWalletRequest)(nil), "stakepoolrpc.RescanWalletRequest")
+	proto.RegisterType((*RotateRPCCertificateRequest)(nil), "stakepoolrpc.RotateRPCCertificateRequest")
+	proto.RegisterType((*RotateRPCCertificateResponse)(nil), "stakepoolrpc.RotateRPCCertificateResponse")
 	proto.RegisterType((*SetAddedLowFeeTicketsRequest)(nil), "stakepoolrpc.SetAddedLowFeeTicketsRequest")
 	proto.RegisterType((*SetAddedLowFeeTicketsResponse)(nil), "stakepoolrpc.SetAddedLowFeeTicketsResponse")
 	proto.RegisterType((*SetUserVotingPrefsResponse)(nil), "stakepoolrpc.SetUserVotingPrefsResponse")
 	proto.RegisterType((*SetUserVotingPrefsRequest)(nil), "stakepoolrpc.SetUserVotingPrefsRequest")
+	proto.RegisterType((*SpentMissedNotification)(nil), "stakepoolrpc.SpentMissedNotification")
+	proto.RegisterType((*SpentMissedTicketEntry)(nil), "stakepoolrpc.SpentMissedTicketEntry")
+	proto.RegisterType((*StakeDifficultyInterval)(nil), "stakepoolrpc.StakeDifficultyInterval")
+	proto.RegisterType((*SubscribeSpentMissedRequest)(nil), "stakepoolrpc.SubscribeSpentMissedRequest")
+	proto.RegisterType((*SubscribeVoteHistoryRequest)(nil), "stakepoolrpc.SubscribeVoteHistoryRequest")
 	proto.RegisterType((*TicketEntry)(nil), "stakepoolrpc.TicketEntry")
+	proto.RegisterType((*TicketListOptions)(nil), "stakepoolrpc.TicketListOptions")
+	proto.RegisterType((*UserDataEntry)(nil), "stakepoolrpc.UserDataEntry")
+	proto.RegisterType((*UserVotingStatsEntry)(nil), "stakepoolrpc.UserVotingStatsEntry")
 	proto.RegisterType((*UserVotingConfigEntry)(nil), "stakepoolrpc.UserVotingConfigEntry")
+	proto.RegisterType((*VerifyColdWalletExtPubRequest)(nil), "stakepoolrpc.VerifyColdWalletExtPubRequest")
+	proto.RegisterType((*VerifyColdWalletExtPubResponse)(nil), "stakepoolrpc.VerifyColdWalletExtPubResponse")
+	proto.RegisterType((*VoteHistoryEntry)(nil), "stakepoolrpc.VoteHistoryEntry")
 	proto.RegisterType((*VersionRequest)(nil), "stakepoolrpc.VersionRequest")
 	proto.RegisterType((*VersionResponse)(nil), "stakepoolrpc.VersionResponse")
+	proto.RegisterEnum("stakepoolrpc.Capability", Capability_name, Capability_value)
+	proto.RegisterEnum("stakepoolrpc.DoubleVoteSource", DoubleVoteSource_name, DoubleVoteSource_value)
+	proto.RegisterEnum("stakepoolrpc.MissingTicketStatus", MissingTicketStatus_name, MissingTicketStatus_value)
+	proto.RegisterEnum("stakepoolrpc.TicketFeeStatus", TicketFeeStatus_name, TicketFeeStatus_value)
+	proto.RegisterEnum("stakepoolrpc.TicketStatus", TicketStatus_name, TicketStatus_value)
+	proto.RegisterEnum("stakepoolrpc.VoteEvent", VoteEvent_name, VoteEvent_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -342,12 +2021,29 @@ const _ = grpc.SupportPackageIsVersion4
 // Client API for StakepooldService service
 
 type StakepooldServiceClient interface {
+	AddMissingTickets(ctx context.Context, in *AddMissingTicketsRequest, opts ...grpc.CallOption) (*AddMissingTicketsResponse, error)
+	BatchSetUserVotingPrefs(ctx context.Context, in *BatchSetUserVotingPrefsRequest, opts ...grpc.CallOption) (*BatchSetUserVotingPrefsResponse, error)
+	ExportUserData(ctx context.Context, in *ExportUserDataRequest, opts ...grpc.CallOption) (*ExportUserDataResponse, error)
 	GetAddedLowFeeTickets(ctx context.Context, in *GetAddedLowFeeTicketsRequest, opts ...grpc.CallOption) (*GetAddedLowFeeTicketsResponse, error)
+	GetDoubleVotes(ctx context.Context, in *GetDoubleVotesRequest, opts ...grpc.CallOption) (*GetDoubleVotesResponse, error)
 	GetIgnoredLowFeeTickets(ctx context.Context, in *GetIgnoredLowFeeTicketsRequest, opts ...grpc.CallOption) (*GetIgnoredLowFeeTicketsResponse, error)
 	GetLiveTickets(ctx context.Context, in *GetLiveTicketsRequest, opts ...grpc.CallOption) (*GetLiveTicketsResponse, error)
+	GetPoolStats(ctx context.Context, in *GetPoolStatsRequest, opts ...grpc.CallOption) (*GetPoolStatsResponse, error)
+	GetStakeDifficultyHistory(ctx context.Context, in *GetStakeDifficultyHistoryRequest, opts ...grpc.CallOption) (*GetStakeDifficultyHistoryResponse, error)
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
+	GetUserVotingStats(ctx context.Context, in *GetUserVotingStatsRequest, opts ...grpc.CallOption) (*GetUserVotingStatsResponse, error)
+	GetVoteHistory(ctx context.Context, in *GetVoteHistoryRequest, opts ...grpc.CallOption) (*GetVoteHistoryResponse, error)
+	GetVoteLatency(ctx context.Context, in *GetVoteLatencyRequest, opts ...grpc.CallOption) (*GetVoteLatencyResponse, error)
+	GetWalletInfo(ctx context.Context, in *GetWalletInfoRequest, opts ...grpc.CallOption) (*GetWalletInfoResponse, error)
+	ImportUserData(ctx context.Context, in *ImportUserDataRequest, opts ...grpc.CallOption) (*ImportUserDataResponse, error)
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
+	RescanWallet(ctx context.Context, in *RescanWalletRequest, opts ...grpc.CallOption) (StakepooldService_RescanWalletClient, error)
+	RotateRPCCertificate(ctx context.Context, in *RotateRPCCertificateRequest, opts ...grpc.CallOption) (*RotateRPCCertificateResponse, error)
 	SetAddedLowFeeTickets(ctx context.Context, in *SetAddedLowFeeTicketsRequest, opts ...grpc.CallOption) (*SetAddedLowFeeTicketsResponse, error)
 	SetUserVotingPrefs(ctx context.Context, in *SetUserVotingPrefsRequest, opts ...grpc.CallOption) (*SetUserVotingPrefsResponse, error)
+	SubscribeSpentMissed(ctx context.Context, in *SubscribeSpentMissedRequest, opts ...grpc.CallOption) (StakepooldService_SubscribeSpentMissedClient, error)
+	SubscribeVoteHistory(ctx context.Context, in *SubscribeVoteHistoryRequest, opts ...grpc.CallOption) (StakepooldService_SubscribeVoteHistoryClient, error)
+	VerifyColdWalletExtPub(ctx context.Context, in *VerifyColdWalletExtPubRequest, opts ...grpc.CallOption) (*VerifyColdWalletExtPubResponse, error)
 }
 
 type stakepooldServiceClient struct {
@@ -358,6 +2054,33 @@ func NewStakepooldServiceClient(cc *grpc.ClientConn) StakepooldServiceClient {
 	return &stakepooldServiceClient{cc}
 }
 
+func (c *stakepooldServiceClient) AddMissingTickets(ctx context.Context, in *AddMissingTicketsRequest, opts ...grpc.CallOption) (*AddMissingTicketsResponse, error) {
+	out := new(AddMissingTicketsResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/AddMissingTickets", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) BatchSetUserVotingPrefs(ctx context.Context, in *BatchSetUserVotingPrefsRequest, opts ...grpc.CallOption) (*BatchSetUserVotingPrefsResponse, error) {
+	out := new(BatchSetUserVotingPrefsResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/BatchSetUserVotingPrefs", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) ExportUserData(ctx context.Context, in *ExportUserDataRequest, opts ...grpc.CallOption) (*ExportUserDataResponse, error) {
+	out := new(ExportUserDataResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/ExportUserData", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *stakepooldServiceClient) GetAddedLowFeeTickets(ctx context.Context, in *GetAddedLowFeeTicketsRequest, opts ...grpc.CallOption) (*GetAddedLowFeeTicketsResponse, error) {
 	out := new(GetAddedLowFeeTicketsResponse)
 	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/GetAddedLowFeeTickets", in, out, c.cc, opts...)
@@ -367,6 +2090,15 @@ func (c *stakepooldServiceClient) GetAddedLowFeeTickets(ctx context.Context, in
 	return out, nil
 }
 
+func (c *stakepooldServiceClient) GetDoubleVotes(ctx context.Context, in *GetDoubleVotesRequest, opts ...grpc.CallOption) (*GetDoubleVotesResponse, error) {
+	out := new(GetDoubleVotesResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/GetDoubleVotes", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *stakepooldServiceClient) GetIgnoredLowFeeTickets(ctx context.Context, in *GetIgnoredLowFeeTicketsRequest, opts ...grpc.CallOption) (*GetIgnoredLowFeeTicketsResponse, error) {
 	out := new(GetIgnoredLowFeeTicketsResponse)
 	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/GetIgnoredLowFeeTickets", in, out, c.cc, opts...)
@@ -385,6 +2117,78 @@ func (c *stakepooldServiceClient) GetLiveTickets(ctx context.Context, in *GetLiv
 	return out, nil
 }
 
+func (c *stakepooldServiceClient) GetPoolStats(ctx context.Context, in *GetPoolStatsRequest, opts ...grpc.CallOption) (*GetPoolStatsResponse, error) {
+	out := new(GetPoolStatsResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/GetPoolStats", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) GetStakeDifficultyHistory(ctx context.Context, in *GetStakeDifficultyHistoryRequest, opts ...grpc.CallOption) (*GetStakeDifficultyHistoryResponse, error) {
+	out := new(GetStakeDifficultyHistoryResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/GetStakeDifficultyHistory", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error) {
+	out := new(GetStatusResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/GetStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) GetUserVotingStats(ctx context.Context, in *GetUserVotingStatsRequest, opts ...grpc.CallOption) (*GetUserVotingStatsResponse, error) {
+	out := new(GetUserVotingStatsResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/GetUserVotingStats", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) GetVoteHistory(ctx context.Context, in *GetVoteHistoryRequest, opts ...grpc.CallOption) (*GetVoteHistoryResponse, error) {
+	out := new(GetVoteHistoryResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/GetVoteHistory", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) GetVoteLatency(ctx context.Context, in *GetVoteLatencyRequest, opts ...grpc.CallOption) (*GetVoteLatencyResponse, error) {
+	out := new(GetVoteLatencyResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/GetVoteLatency", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) GetWalletInfo(ctx context.Context, in *GetWalletInfoRequest, opts ...grpc.CallOption) (*GetWalletInfoResponse, error) {
+	out := new(GetWalletInfoResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/GetWalletInfo", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) ImportUserData(ctx context.Context, in *ImportUserDataRequest, opts ...grpc.CallOption) (*ImportUserDataResponse, error) {
+	out := new(ImportUserDataResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/ImportUserData", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *stakepooldServiceClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
 	out := new(PingResponse)
 	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/Ping", in, out, c.cc, opts...)
@@ -394,18 +2198,132 @@ func (c *stakepooldServiceClient) Ping(ctx context.Context, in *PingRequest, opt
 	return out, nil
 }
 
-func (c *stakepooldServiceClient) SetAddedLowFeeTickets(ctx context.Context, in *SetAddedLowFeeTicketsRequest, opts ...grpc.CallOption) (*SetAddedLowFeeTicketsResponse, error) {
-	out := new(SetAddedLowFeeTicketsResponse)
-	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/SetAddedLowFeeTickets", in, out, c.cc, opts...)
-	if err != nil {
+func (c *stakepooldServiceClient) RescanWallet(ctx context.Context, in *RescanWalletRequest, opts ...grpc.CallOption) (StakepooldService_RescanWalletClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_StakepooldService_serviceDesc.Streams[0], c.cc, "/stakepoolrpc.StakepooldService/RescanWallet", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &stakepooldServiceRescanWalletClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type StakepooldService_RescanWalletClient interface {
+	Recv() (*RescanWalletProgress, error)
+	grpc.ClientStream
+}
+
+type stakepooldServiceRescanWalletClient struct {
+	grpc.ClientStream
+}
+
+func (x *stakepooldServiceRescanWalletClient) Recv() (*RescanWalletProgress, error) {
+	m := new(RescanWalletProgress)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *stakepooldServiceClient) RotateRPCCertificate(ctx context.Context, in *RotateRPCCertificateRequest, opts ...grpc.CallOption) (*RotateRPCCertificateResponse, error) {
+	out := new(RotateRPCCertificateResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/RotateRPCCertificate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) SetAddedLowFeeTickets(ctx context.Context, in *SetAddedLowFeeTicketsRequest, opts ...grpc.CallOption) (*SetAddedLowFeeTicketsResponse, error) {
+	out := new(SetAddedLowFeeTicketsResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/SetAddedLowFeeTickets", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) SetUserVotingPrefs(ctx context.Context, in *SetUserVotingPrefsRequest, opts ...grpc.CallOption) (*SetUserVotingPrefsResponse, error) {
+	out := new(SetUserVotingPrefsResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/SetUserVotingPrefs", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stakepooldServiceClient) SubscribeSpentMissed(ctx context.Context, in *SubscribeSpentMissedRequest, opts ...grpc.CallOption) (StakepooldService_SubscribeSpentMissedClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_StakepooldService_serviceDesc.Streams[1], c.cc, "/stakepoolrpc.StakepooldService/SubscribeSpentMissed", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &stakepooldServiceSubscribeSpentMissedClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type StakepooldService_SubscribeSpentMissedClient interface {
+	Recv() (*SpentMissedNotification, error)
+	grpc.ClientStream
+}
+
+type stakepooldServiceSubscribeSpentMissedClient struct {
+	grpc.ClientStream
+}
+
+func (x *stakepooldServiceSubscribeSpentMissedClient) Recv() (*SpentMissedNotification, error) {
+	m := new(SpentMissedNotification)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *stakepooldServiceClient) SubscribeVoteHistory(ctx context.Context, in *SubscribeVoteHistoryRequest, opts ...grpc.CallOption) (StakepooldService_SubscribeVoteHistoryClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_StakepooldService_serviceDesc.Streams[2], c.cc, "/stakepoolrpc.StakepooldService/SubscribeVoteHistory", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &stakepooldServiceSubscribeVoteHistoryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type StakepooldService_SubscribeVoteHistoryClient interface {
+	Recv() (*VoteHistoryEntry, error)
+	grpc.ClientStream
+}
+
+type stakepooldServiceSubscribeVoteHistoryClient struct {
+	grpc.ClientStream
+}
+
+func (x *stakepooldServiceSubscribeVoteHistoryClient) Recv() (*VoteHistoryEntry, error) {
+	m := new(VoteHistoryEntry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
-	return out, nil
+	return m, nil
 }
 
-func (c *stakepooldServiceClient) SetUserVotingPrefs(ctx context.Context, in *SetUserVotingPrefsRequest, opts ...grpc.CallOption) (*SetUserVotingPrefsResponse, error) {
-	out := new(SetUserVotingPrefsResponse)
-	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/SetUserVotingPrefs", in, out, c.cc, opts...)
+func (c *stakepooldServiceClient) VerifyColdWalletExtPub(ctx context.Context, in *VerifyColdWalletExtPubRequest, opts ...grpc.CallOption) (*VerifyColdWalletExtPubResponse, error) {
+	out := new(VerifyColdWalletExtPubResponse)
+	err := grpc.Invoke(ctx, "/stakepoolrpc.StakepooldService/VerifyColdWalletExtPub", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -415,18 +2333,89 @@ func (c *stakepooldServiceClient) SetUserVotingPrefs(ctx context.Context, in *Se
 // Server API for StakepooldService service
 
 type StakepooldServiceServer interface {
+	AddMissingTickets(context.Context, *AddMissingTicketsRequest) (*AddMissingTicketsResponse, error)
+	BatchSetUserVotingPrefs(context.Context, *BatchSetUserVotingPrefsRequest) (*BatchSetUserVotingPrefsResponse, error)
+	ExportUserData(context.Context, *ExportUserDataRequest) (*ExportUserDataResponse, error)
 	GetAddedLowFeeTickets(context.Context, *GetAddedLowFeeTicketsRequest) (*GetAddedLowFeeTicketsResponse, error)
+	GetDoubleVotes(context.Context, *GetDoubleVotesRequest) (*GetDoubleVotesResponse, error)
 	GetIgnoredLowFeeTickets(context.Context, *GetIgnoredLowFeeTicketsRequest) (*GetIgnoredLowFeeTicketsResponse, error)
 	GetLiveTickets(context.Context, *GetLiveTicketsRequest) (*GetLiveTicketsResponse, error)
+	GetPoolStats(context.Context, *GetPoolStatsRequest) (*GetPoolStatsResponse, error)
+	GetStakeDifficultyHistory(context.Context, *GetStakeDifficultyHistoryRequest) (*GetStakeDifficultyHistoryResponse, error)
+	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
+	GetUserVotingStats(context.Context, *GetUserVotingStatsRequest) (*GetUserVotingStatsResponse, error)
+	GetVoteHistory(context.Context, *GetVoteHistoryRequest) (*GetVoteHistoryResponse, error)
+	GetVoteLatency(context.Context, *GetVoteLatencyRequest) (*GetVoteLatencyResponse, error)
+	GetWalletInfo(context.Context, *GetWalletInfoRequest) (*GetWalletInfoResponse, error)
+	ImportUserData(context.Context, *ImportUserDataRequest) (*ImportUserDataResponse, error)
 	Ping(context.Context, *PingRequest) (*PingResponse, error)
+	RescanWallet(*RescanWalletRequest, StakepooldService_RescanWalletServer) error
+	RotateRPCCertificate(context.Context, *RotateRPCCertificateRequest) (*RotateRPCCertificateResponse, error)
 	SetAddedLowFeeTickets(context.Context, *SetAddedLowFeeTicketsRequest) (*SetAddedLowFeeTicketsResponse, error)
 	SetUserVotingPrefs(context.Context, *SetUserVotingPrefsRequest) (*SetUserVotingPrefsResponse, error)
+	SubscribeSpentMissed(*SubscribeSpentMissedRequest, StakepooldService_SubscribeSpentMissedServer) error
+	SubscribeVoteHistory(*SubscribeVoteHistoryRequest, StakepooldService_SubscribeVoteHistoryServer) error
+	VerifyColdWalletExtPub(context.Context, *VerifyColdWalletExtPubRequest) (*VerifyColdWalletExtPubResponse, error)
 }
 
 func RegisterStakepooldServiceServer(s *grpc.Server, srv StakepooldServiceServer) {
 	s.RegisterService(&_StakepooldService_serviceDesc, srv)
 }
 
+func _StakepooldService_AddMissingTickets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddMissingTicketsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).AddMissingTickets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/AddMissingTickets",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).AddMissingTickets(ctx, req.(*AddMissingTicketsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StakepooldService_BatchSetUserVotingPrefs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchSetUserVotingPrefsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).BatchSetUserVotingPrefs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/BatchSetUserVotingPrefs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).BatchSetUserVotingPrefs(ctx, req.(*BatchSetUserVotingPrefsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StakepooldService_ExportUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportUserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).ExportUserData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/ExportUserData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).ExportUserData(ctx, req.(*ExportUserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StakepooldService_GetAddedLowFeeTickets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetAddedLowFeeTicketsRequest)
 	if err := dec(in); err != nil {
@@ -445,6 +2434,24 @@ func _StakepooldService_GetAddedLowFeeTickets_Handler(srv interface{}, ctx conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StakepooldService_GetDoubleVotes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDoubleVotesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).GetDoubleVotes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/GetDoubleVotes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).GetDoubleVotes(ctx, req.(*GetDoubleVotesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StakepooldService_GetIgnoredLowFeeTickets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetIgnoredLowFeeTicketsRequest)
 	if err := dec(in); err != nil {
@@ -481,6 +2488,150 @@ func _StakepooldService_GetLiveTickets_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StakepooldService_GetPoolStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPoolStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).GetPoolStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/GetPoolStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).GetPoolStats(ctx, req.(*GetPoolStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StakepooldService_GetStakeDifficultyHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStakeDifficultyHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).GetStakeDifficultyHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/GetStakeDifficultyHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).GetStakeDifficultyHistory(ctx, req.(*GetStakeDifficultyHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StakepooldService_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/GetStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StakepooldService_GetUserVotingStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserVotingStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).GetUserVotingStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/GetUserVotingStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).GetUserVotingStats(ctx, req.(*GetUserVotingStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StakepooldService_GetVoteHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVoteHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).GetVoteHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/GetVoteHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).GetVoteHistory(ctx, req.(*GetVoteHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StakepooldService_GetVoteLatency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVoteLatencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).GetVoteLatency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/GetVoteLatency",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).GetVoteLatency(ctx, req.(*GetVoteLatencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StakepooldService_GetWalletInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWalletInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).GetWalletInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/GetWalletInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).GetWalletInfo(ctx, req.(*GetWalletInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StakepooldService_ImportUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportUserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).ImportUserData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/ImportUserData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).ImportUserData(ctx, req.(*ImportUserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StakepooldService_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(PingRequest)
 	if err := dec(in); err != nil {
@@ -499,6 +2650,45 @@ func _StakepooldService_Ping_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StakepooldService_RescanWallet_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RescanWalletRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StakepooldServiceServer).RescanWallet(m, &stakepooldServiceRescanWalletServer{stream})
+}
+
+type StakepooldService_RescanWalletServer interface {
+	Send(*RescanWalletProgress) error
+	grpc.ServerStream
+}
+
+type stakepooldServiceRescanWalletServer struct {
+	grpc.ServerStream
+}
+
+func (x *stakepooldServiceRescanWalletServer) Send(m *RescanWalletProgress) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _StakepooldService_RotateRPCCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateRPCCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).RotateRPCCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/RotateRPCCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).RotateRPCCertificate(ctx, req.(*RotateRPCCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StakepooldService_SetAddedLowFeeTickets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SetAddedLowFeeTicketsRequest)
 	if err := dec(in); err != nil {
@@ -535,14 +2725,90 @@ func _StakepooldService_SetUserVotingPrefs_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StakepooldService_SubscribeSpentMissed_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeSpentMissedRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StakepooldServiceServer).SubscribeSpentMissed(m, &stakepooldServiceSubscribeSpentMissedServer{stream})
+}
+
+type StakepooldService_SubscribeSpentMissedServer interface {
+	Send(*SpentMissedNotification) error
+	grpc.ServerStream
+}
+
+type stakepooldServiceSubscribeSpentMissedServer struct {
+	grpc.ServerStream
+}
+
+func (x *stakepooldServiceSubscribeSpentMissedServer) Send(m *SpentMissedNotification) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _StakepooldService_SubscribeVoteHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeVoteHistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StakepooldServiceServer).SubscribeVoteHistory(m, &stakepooldServiceSubscribeVoteHistoryServer{stream})
+}
+
+type StakepooldService_SubscribeVoteHistoryServer interface {
+	Send(*VoteHistoryEntry) error
+	grpc.ServerStream
+}
+
+type stakepooldServiceSubscribeVoteHistoryServer struct {
+	grpc.ServerStream
+}
+
+func (x *stakepooldServiceSubscribeVoteHistoryServer) Send(m *VoteHistoryEntry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _StakepooldService_VerifyColdWalletExtPub_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyColdWalletExtPubRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StakepooldServiceServer).VerifyColdWalletExtPub(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stakepoolrpc.StakepooldService/VerifyColdWalletExtPub",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StakepooldServiceServer).VerifyColdWalletExtPub(ctx, req.(*VerifyColdWalletExtPubRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _StakepooldService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "stakepoolrpc.StakepooldService",
 	HandlerType: (*StakepooldServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddMissingTickets",
+			Handler:    _StakepooldService_AddMissingTickets_Handler,
+		},
+		{
+			MethodName: "BatchSetUserVotingPrefs",
+			Handler:    _StakepooldService_BatchSetUserVotingPrefs_Handler,
+		},
+		{
+			MethodName: "ExportUserData",
+			Handler:    _StakepooldService_ExportUserData_Handler,
+		},
 		{
 			MethodName: "GetAddedLowFeeTickets",
 			Handler:    _StakepooldService_GetAddedLowFeeTickets_Handler,
 		},
+		{
+			MethodName: "GetDoubleVotes",
+			Handler:    _StakepooldService_GetDoubleVotes_Handler,
+		},
 		{
 			MethodName: "GetIgnoredLowFeeTickets",
 			Handler:    _StakepooldService_GetIgnoredLowFeeTickets_Handler,
@@ -551,10 +2817,46 @@ var _StakepooldService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetLiveTickets",
 			Handler:    _StakepooldService_GetLiveTickets_Handler,
 		},
+		{
+			MethodName: "GetPoolStats",
+			Handler:    _StakepooldService_GetPoolStats_Handler,
+		},
+		{
+			MethodName: "GetStakeDifficultyHistory",
+			Handler:    _StakepooldService_GetStakeDifficultyHistory_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _StakepooldService_GetStatus_Handler,
+		},
+		{
+			MethodName: "GetUserVotingStats",
+			Handler:    _StakepooldService_GetUserVotingStats_Handler,
+		},
+		{
+			MethodName: "GetVoteHistory",
+			Handler:    _StakepooldService_GetVoteHistory_Handler,
+		},
+		{
+			MethodName: "GetVoteLatency",
+			Handler:    _StakepooldService_GetVoteLatency_Handler,
+		},
+		{
+			MethodName: "GetWalletInfo",
+			Handler:    _StakepooldService_GetWalletInfo_Handler,
+		},
+		{
+			MethodName: "ImportUserData",
+			Handler:    _StakepooldService_ImportUserData_Handler,
+		},
 		{
 			MethodName: "Ping",
 			Handler:    _StakepooldService_Ping_Handler,
 		},
+		{
+			MethodName: "RotateRPCCertificate",
+			Handler:    _StakepooldService_RotateRPCCertificate_Handler,
+		},
 		{
 			MethodName: "SetAddedLowFeeTickets",
 			Handler:    _StakepooldService_SetAddedLowFeeTickets_Handler,
@@ -563,8 +2865,28 @@ var _StakepooldService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SetUserVotingPrefs",
 			Handler:    _StakepooldService_SetUserVotingPrefs_Handler,
 		},
+		{
+			MethodName: "VerifyColdWalletExtPub",
+			Handler:    _StakepooldService_VerifyColdWalletExtPub_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RescanWallet",
+			Handler:       _StakepooldService_RescanWallet_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeSpentMissed",
+			Handler:       _StakepooldService_SubscribeSpentMissed_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeVoteHistory",
+			Handler:       _StakepooldService_SubscribeVoteHistory_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "api.proto",
 }
 
@@ -635,44 +2957,207 @@ var _VersionService_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("api.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 612 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0xdd, 0x4e, 0xdb, 0x4c,
-	0x10, 0x55, 0xf8, 0xfd, 0x18, 0x48, 0xe0, 0x5b, 0x15, 0x30, 0x56, 0x12, 0x22, 0x43, 0xd5, 0xa8,
-	0x3f, 0xb9, 0x08, 0xd7, 0xbd, 0xa0, 0x55, 0x49, 0x91, 0x88, 0x44, 0x6d, 0x1a, 0x55, 0xea, 0x45,
-	0x64, 0xe2, 0xc1, 0x6c, 0x09, 0x5e, 0x77, 0x77, 0x93, 0xaa, 0x4f, 0xd3, 0x67, 0xe9, 0x3b, 0xf5,
-	0x01, 0x2a, 0xef, 0xda, 0x21, 0xb6, 0x13, 0x37, 0x2d, 0x77, 0x9e, 0x33, 0x93, 0x73, 0x8e, 0xd7,
-	0xb3, 0x27, 0xb0, 0xe1, 0x86, 0xb4, 0x15, 0x72, 0x26, 0x19, 0xd9, 0x12, 0xd2, 0xbd, 0xc3, 0x90,
-	0xb1, 0x21, 0x0f, 0x07, 0x56, 0x1d, 0xaa, 0x1d, 0x94, 0xa7, 0x9e, 0x87, 0xde, 0x05, 0xfb, 0x76,
-	0x86, 0x78, 0x45, 0x07, 0x77, 0x28, 0x85, 0x8d, 0x5f, 0x47, 0x28, 0xa4, 0x75, 0x05, 0xb5, 0x39,
-	0x7d, 0x11, 0xb2, 0x40, 0x20, 0x39, 0x81, 0x75, 0xa9, 0x21, 0xa3, 0xd4, 0x58, 0x6e, 0x6e, 0xb6,
-	0x0f, 0x5a, 0xd3, 0x02, 0x2d, 0x3d, 0xff, 0x2e, 0x90, 0xfc, 0xbb, 0x9d, 0x4c, 0x5a, 0x0d, 0xa8,
-	0x77, 0x50, 0x9e, 0xfb, 0x01, 0xe3, 0x73, 0x74, 0x7b, 0x70, 0x38, 0x77, 0xe2, 0x31, 0xca, 0xfb,
-	0xb0, 0xdb, 0x41, 0x79, 0x41, 0xc7, 0x59, 0xc1, 0x2e, 0xec, 0x65, 0x1b, 0x8f, 0xd1, 0x29, 0xc3,
-	0xe6, 0x25, 0x0d, 0xfc, 0x84, 0xbd, 0x02, 0x5b, 0xba, 0xd4, 0x9c, 0x96, 0x03, 0x55, 0xa7, 0xe0,
-	0xd8, 0xff, 0x4d, 0xf3, 0x10, 0x6a, 0x4e, 0xd1, 0xb7, 0xb2, 0xaa, 0x60, 0x3a, 0x28, 0x3f, 0x0a,
-	0xe4, 0x3d, 0x26, 0x69, 0xe0, 0x5f, 0x72, 0xbc, 0x79, 0xe8, 0x06, 0x70, 0x30, 0xab, 0xab, 0x0d,
-	0x7d, 0x00, 0x32, 0x12, 0xc8, 0xfb, 0x63, 0xd5, 0xea, 0x0f, 0x58, 0x70, 0x43, 0xfd, 0xd8, 0xdb,
-	0x51, 0xda, 0xdb, 0x03, 0xc3, 0x5b, 0x35, 0xa5, 0x5d, 0xee, 0x8c, 0x32, 0xb0, 0xe5, 0xc0, 0xe6,
-	0xd4, 0x6b, 0x90, 0x63, 0x28, 0xeb, 0xf2, 0xd4, 0xf3, 0x38, 0x8a, 0xe8, 0xc5, 0x4b, 0xcd, 0x0d,
-	0x3b, 0x0d, 0x92, 0x3a, 0x80, 0x06, 0xde, 0xbb, 0xe2, 0xd6, 0x58, 0x6a, 0x94, 0x9a, 0x5b, 0xf6,
-	0x14, 0x62, 0xfd, 0x28, 0xc1, 0xee, 0x4c, 0x03, 0x64, 0x0f, 0xd6, 0xa2, 0xc6, 0xb9, 0xa7, 0x88,
-	0x97, 0xed, 0xb8, 0x22, 0x4d, 0xd8, 0xee, 0x8e, 0x86, 0x92, 0x3a, 0xd4, 0x4f, 0x94, 0x97, 0x94,
-	0x72, 0x16, 0x26, 0x26, 0xfc, 0xd7, 0x63, 0x12, 0xdf, 0x50, 0x29, 0x8c, 0x65, 0xc5, 0x31, 0xa9,
-	0x23, 0x96, 0xe4, 0xb9, 0x87, 0x5c, 0x50, 0x16, 0x18, 0x2b, 0x6a, 0x24, 0x0b, 0x5b, 0x3b, 0x50,
-	0x89, 0x1f, 0x93, 0xe5, 0xf8, 0x59, 0x82, 0xed, 0x09, 0x14, 0x2f, 0xdd, 0x53, 0xa8, 0x8c, 0x35,
-	0xd4, 0x17, 0x92, 0xd3, 0xc0, 0x4f, 0x8e, 0x23, 0x46, 0x1d, 0x05, 0x92, 0x27, 0xb0, 0x7a, 0xef,
-	0x7e, 0x61, 0x5c, 0x59, 0x2e, 0xdb, 0xba, 0x50, 0x28, 0x0d, 0x18, 0x57, 0x2e, 0x23, 0x34, 0x2a,
-	0x22, 0x34, 0x74, 0xe5, 0xe0, 0x56, 0x19, 0x2b, 0xdb, 0xba, 0x88, 0x0e, 0x34, 0xe4, 0xc8, 0x71,
-	0x88, 0xae, 0x40, 0x63, 0x55, 0x89, 0x4c, 0x21, 0x91, 0x91, 0xeb, 0x11, 0x1d, 0x7a, 0xfd, 0x7b,
-	0x94, 0xae, 0xe7, 0x4a, 0xd7, 0x58, 0xd3, 0x46, 0x14, 0xda, 0x8d, 0xc1, 0xf6, 0xaf, 0x15, 0xf8,
-	0xdf, 0x49, 0xb6, 0xc0, 0x73, 0x90, 0x8f, 0xe9, 0x00, 0x49, 0xa8, 0x6e, 0x5b, 0x7e, 0x23, 0xc9,
-	0xf3, 0xf4, 0xca, 0x14, 0x45, 0x90, 0xf9, 0x62, 0xa1, 0xd9, 0xf8, 0xdc, 0xc6, 0xb0, 0x3f, 0x27,
-	0x37, 0xc8, 0xcb, 0x1c, 0x4f, 0x41, 0x00, 0x99, 0xaf, 0x16, 0x9c, 0x8e, 0x75, 0x3f, 0x43, 0x25,
-	0x1d, 0x1f, 0xe4, 0x28, 0x47, 0x90, 0x4f, 0x1d, 0xf3, 0xb8, 0x78, 0x28, 0x26, 0x7f, 0x0d, 0x2b,
-	0x51, 0x7a, 0x90, 0x4c, 0x08, 0x4c, 0x05, 0x8c, 0x69, 0xce, 0x6a, 0xc5, 0x3f, 0x0f, 0x61, 0xd7,
-	0x59, 0xe4, 0x2b, 0x38, 0x7f, 0xf1, 0x15, 0x0a, 0x83, 0x86, 0xf8, 0x40, 0xf2, 0x51, 0x42, 0x9e,
-	0xe5, 0x28, 0x66, 0x87, 0x8d, 0xd9, 0xfc, 0xf3, 0xa0, 0x16, 0x6a, 0x7f, 0x9a, 0x5c, 0xa6, 0x64,
-	0xe5, 0xce, 0x60, 0x3d, 0x46, 0x48, 0x35, 0x4d, 0x93, 0xbe, 0x75, 0x66, 0x6d, 0x4e, 0x57, 0x33,
-	0x5f, 0xaf, 0xa9, 0x7f, 0xcb, 0x93, 0xdf, 0x01, 0x00, 0x00, 0xff, 0xff, 0x3f, 0x57, 0xe8, 0x76,
-	0x3a, 0x07, 0x00, 0x00,
+	// 3230 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0xc5, 0x5a, 0x4b, 0x73, 0x1b, 0xc7,
+	0x11, 0x36, 0x00, 0x92, 0x00, 0x9a, 0x20, 0x09, 0x2e, 0x9f, 0x82, 0xf8, 0x90, 0x56, 0xb6, 0x25,
+	0x33, 0xb6, 0x6c, 0xd3, 0xe5, 0xc4, 0x72, 0x9c, 0x07, 0x08, 0x80, 0x12, 0x62, 0x08, 0x44, 0x76,
+	0x41, 0x4a, 0x2a, 0x57, 0x6a, 0x6b, 0x89, 0x5d, 0x52, 0x6b, 0x01, 0xbb, 0xc8, 0x62, 0x41, 0x49,
+	0x2e, 0x57, 0x4e, 0xa9, 0x4a, 0xaa, 0x52, 0x39, 0xe4, 0x94, 0xa4, 0x72, 0xc8, 0x25, 0x39, 0xa5,
+	0x72, 0xcc, 0x5f, 0xf1, 0x1f, 0x48, 0xe5, 0x90, 0x63, 0x0e, 0xb9, 0xa7, 0xa7, 0x67, 0x16, 0xfb,
+	0xc0, 0x02, 0xa4, 0x5d, 0xaa, 0xf8, 0x86, 0xf9, 0xa6, 0xa7, 0x67, 0xfa, 0xb1, 0x3d, 0xdd, 0x3d,
+	0x80, 0xbc, 0xde, 0xb7, 0xee, 0xf6, 0x5d, 0xc7, 0x73, 0xa4, 0xc2, 0xc0, 0xd3, 0x9f, 0x99, 0x7d,
+	0xc7, 0xe9, 0xba, 0xfd, 0x8e, 0xfc, 0x23, 0xd8, 0x2c, 0x1b, 0xc6, 0x43, 0x6b, 0x30, 0xb0, 0xec,
+	0xf3, 0xb6, 0xd5, 0x79, 0x66, 0x7a, 0x03, 0xc5, 0xfc, 0xf9, 0xd0, 0x1c, 0x78, 0xd2, 0x2d, 0x58,
+	0xf0, 0x08, 0xd1, 0x9e, 0xea, 0x83, 0xa7, 0xe6, 0x60, 0x33, 0x75, 0x23, 0x73, 0xa7, 0xa0, 0x14,
+	0x38, 0xf8, 0x80, 0x30, 0xf9, 0x31, 0x5c, 0x4b, 0x60, 0x30, 0xe8, 0x3b, 0xf6, 0xc0, 0x94, 0xbe,
+	0x0f, 0x59, 0xd7, 0x1c, 0x0c, 0xbb, 0x1e, 0x5f, 0x3b, 0xbf, 0x7f, 0xf3, 0x6e, 0x78, 0xf7, 0xbb,
+	0x91, 0x65, 0x0a, 0x51, 0x2a, 0xfe, 0x0a, 0x79, 0x00, 0x3b, 0x07, 0xba, 0xd7, 0x79, 0xaa, 0x9a,
+	0xde, 0xf1, 0xc0, 0x74, 0x4f, 0x1c, 0x0f, 0x49, 0x5b, 0xae, 0x79, 0x36, 0x3a, 0xe0, 0x4f, 0x41,
+	0x1a, 0xe2, 0x8c, 0x76, 0x41, 0x53, 0x5a, 0xc7, 0xb1, 0xcf, 0xac, 0x73, 0xb1, 0xd3, 0xad, 0xe8,
+	0x4e, 0x01, 0x87, 0x0a, 0x51, 0xd5, 0x6c, 0xcf, 0x7d, 0xa9, 0x14, 0x87, 0x31, 0x58, 0xfe, 0x1e,
+	0xec, 0x4e, 0xdc, 0x54, 0x08, 0xb5, 0x0a, 0xb3, 0x6c, 0x19, 0x13, 0x29, 0x75, 0x67, 0x41, 0xe1,
+	0x03, 0xf9, 0xd7, 0x19, 0x80, 0xaa, 0x33, 0x3c, 0xed, 0x9a, 0xb8, 0xc6, 0x94, 0x76, 0x61, 0x3e,
+	0xa4, 0x3b, 0x22, 0x2d, 0x28, 0x10, 0x68, 0x4e, 0x7a, 0x0b, 0x8a, 0x3d, 0x14, 0xd3, 0x1a, 0x58,
+	0xe7, 0x9a, 0x6e, 0x18, 0x28, 0xf4, 0x60, 0x33, 0x8d, 0x54, 0x79, 0x65, 0xc9, 0xc7, 0xcb, 0x1c,
+	0x96, 0xb6, 0x01, 0x4e, 0xbb, 0x4e, 0xe7, 0x19, 0x67, 0x95, 0x21, 0x56, 0x79, 0x42, 0x88, 0xd3,
+	0x4d, 0x28, 0x88, 0x69, 0xd3, 0x3a, 0x7f, 0xea, 0x6d, 0xce, 0x20, 0x41, 0x46, 0x99, 0xe7, 0x04,
+	0x04, 0x49, 0xd7, 0x21, 0x8f, 0x3a, 0x32, 0x39, 0x83, 0x59, 0x62, 0x90, 0x63, 0x00, 0xad, 0xf7,
+	0x27, 0x4f, 0x2d, 0x34, 0xd3, 0x1c, 0xc9, 0x44, 0x93, 0x07, 0x38, 0x96, 0xf6, 0x61, 0x8d, 0xa9,
+	0xb5, 0x6b, 0x75, 0x48, 0xc5, 0x01, 0x97, 0x2c, 0x71, 0x59, 0x09, 0x4d, 0x9e, 0xf8, 0x0c, 0x93,
+	0xd6, 0x10, 0xf3, 0x1c, 0x31, 0x8f, 0xaf, 0xa1, 0x7d, 0xbe, 0x0b, 0x73, 0x03, 0x67, 0xe8, 0x76,
+	0xcc, 0xcd, 0x3c, 0x12, 0x2d, 0xee, 0xef, 0x44, 0xcd, 0x17, 0x68, 0x56, 0x25, 0x2a, 0x45, 0x50,
+	0x4b, 0x12, 0xcc, 0x78, 0x56, 0xcf, 0xdc, 0x04, 0x12, 0x9a, 0x7e, 0xcb, 0xef, 0xc2, 0x5a, 0xed,
+	0x45, 0xdf, 0x71, 0xc9, 0x82, 0x55, 0xdd, 0xd3, 0x7d, 0x7f, 0x59, 0x87, 0xb9, 0x33, 0xcb, 0xec,
+	0x1a, 0xdc, 0x1b, 0xf3, 0x8a, 0x18, 0xc9, 0x7f, 0x4c, 0xc1, 0x7a, 0x7c, 0x85, 0x30, 0xf6, 0xfb,
+	0x81, 0xb1, 0x99, 0x57, 0x5d, 0x1f, 0xf7, 0x2a, 0x46, 0xce, 0xbd, 0x89, 0x53, 0x4a, 0x0d, 0x58,
+	0x43, 0x83, 0x9a, 0x86, 0xd6, 0x75, 0x9e, 0x6b, 0x67, 0xa6, 0xa9, 0x71, 0xab, 0x33, 0xf3, 0x32,
+	0x16, 0xd7, 0xa2, 0x2c, 0xb8, 0xef, 0x73, 0x06, 0x12, 0xad, 0x6b, 0x38, 0xcf, 0x0f, 0x4d, 0x53,
+	0x7c, 0x4a, 0xf2, 0x13, 0xd8, 0xba, 0x6f, 0x7a, 0xe5, 0xb1, 0x09, 0x5f, 0xa6, 0x7b, 0x90, 0x75,
+	0xfa, 0x9e, 0x85, 0x87, 0x25, 0x27, 0x9b, 0xdf, 0xdf, 0x4d, 0xe2, 0xdf, 0xb0, 0x06, 0xde, 0x11,
+	0x27, 0x53, 0x7c, 0x7a, 0xf9, 0x37, 0x29, 0xd8, 0x9e, 0xc0, 0x5b, 0x48, 0xff, 0x01, 0x64, 0xfd,
+	0xc3, 0xa7, 0x2e, 0x3b, 0xbc, 0x4f, 0xc9, 0x5c, 0xdf, 0x36, 0x5f, 0x78, 0x5a, 0x67, 0xe8, 0x0e,
+	0x1c, 0x97, 0x9c, 0x1a, 0x5d, 0x9f, 0x41, 0x15, 0x42, 0xd8, 0x07, 0xe4, 0x39, 0x9e, 0xde, 0x25,
+	0x57, 0xc6, 0x0f, 0x88, 0x06, 0xf2, 0xc7, 0xb0, 0x86, 0x87, 0x09, 0x0c, 0x3d, 0x92, 0x10, 0xfd,
+	0x1b, 0x83, 0x44, 0xc7, 0xf4, 0xfd, 0x3b, 0xc5, 0xfd, 0x9b, 0x30, 0xee, 0xdf, 0xf2, 0x31, 0xac,
+	0xc7, 0xd7, 0x8e, 0x22, 0x50, 0xc1, 0x20, 0x98, 0xdc, 0xd0, 0x17, 0x63, 0x73, 0x92, 0x77, 0x29,
+	0xf3, 0x46, 0xc0, 0x44, 0xfe, 0x0c, 0x76, 0x90, 0x6d, 0xfd, 0xdc, 0x76, 0xdc, 0x57, 0xaf, 0xfd,
+	0xdf, 0xa6, 0x60, 0x77, 0x22, 0xf7, 0x6f, 0x41, 0xff, 0x0a, 0xe9, 0xbf, 0x61, 0x5d, 0xbc, 0x42,
+	0x19, 0x7f, 0x99, 0x22, 0xc3, 0x44, 0x98, 0x7e, 0x0b, 0xa2, 0xad, 0xc1, 0x0a, 0x9e, 0xa2, 0x85,
+	0x9c, 0x55, 0x4f, 0x1f, 0x09, 0x26, 0xff, 0x2e, 0x03, 0xab, 0x51, 0x5c, 0x9c, 0x2d, 0x1a, 0x70,
+	0x53, 0x97, 0x05, 0xdc, 0x74, 0x62, 0xc0, 0x65, 0x82, 0x68, 0x03, 0xeb, 0x0b, 0x53, 0x9c, 0x25,
+	0xc7, 0x00, 0x15, 0xc7, 0x2c, 0xf4, 0x93, 0xa8, 0x9a, 0x61, 0x9d, 0x9d, 0x59, 0x1d, 0x8c, 0xf6,
+	0x2f, 0x45, 0xd0, 0x5e, 0x22, 0xbc, 0x3a, 0x82, 0x99, 0xc0, 0xcf, 0x2d, 0xdb, 0xc0, 0x40, 0x42,
+	0x9c, 0x66, 0x89, 0x0a, 0x38, 0x44, 0xbc, 0xf0, 0x8e, 0x16, 0x04, 0xb4, 0x3d, 0x0f, 0xe0, 0x19,
+	0xa5, 0xc0, 0xc1, 0x03, 0xc2, 0x18, 0x91, 0x6d, 0x7a, 0xcf, 0x1d, 0xf7, 0x99, 0xf8, 0x0a, 0xb2,
+	0x9c, 0x48, 0x80, 0xe4, 0xec, 0x4c, 0x68, 0x3a, 0x32, 0xa7, 0xc8, 0x11, 0x05, 0x09, 0xc1, 0xa7,
+	0x51, 0xe8, 0x2e, 0x9a, 0x71, 0x14, 0xcc, 0xf2, 0x5c, 0xe8, 0x6e, 0x60, 0x5a, 0x76, 0x58, 0xe2,
+	0xd0, 0xc3, 0x5b, 0x1d, 0x59, 0xf0, 0x90, 0x4c, 0x4c, 0x1f, 0x12, 0xc2, 0x78, 0x90, 0x41, 0x7c,
+	0x8a, 0x79, 0xce, 0x83, 0x30, 0x4e, 0x22, 0xd7, 0xe0, 0x06, 0x9a, 0x44, 0x8d, 0xaa, 0xe1, 0x01,
+	0xfa, 0x96, 0x83, 0x7e, 0x70, 0xf5, 0x80, 0xf0, 0x14, 0x6e, 0x4e, 0x61, 0x23, 0xcc, 0x5c, 0x81,
+	0xbc, 0x65, 0x7b, 0xa6, 0x7b, 0xa1, 0x77, 0x7d, 0x27, 0x7c, 0x23, 0xea, 0x84, 0x31, 0x06, 0x75,
+	0x41, 0xad, 0x04, 0xeb, 0x64, 0x09, 0x8a, 0x7c, 0x27, 0x6f, 0x38, 0x72, 0xac, 0x7f, 0x67, 0x60,
+	0x39, 0x04, 0x8a, 0xed, 0xde, 0x80, 0x45, 0xdb, 0x31, 0x4c, 0x96, 0xa6, 0xd8, 0x66, 0xc7, 0x33,
+	0x0d, 0x3a, 0x78, 0x4e, 0x59, 0x60, 0x68, 0xc5, 0x07, 0x99, 0x77, 0x3c, 0xd7, 0xbb, 0x5d, 0xcc,
+	0x1c, 0x02, 0xc2, 0x34, 0x11, 0x2e, 0x71, 0x3c, 0x20, 0x8d, 0x3b, 0x62, 0x66, 0xdc, 0x11, 0x99,
+	0x7f, 0x70, 0x6e, 0x91, 0xec, 0xa0, 0xc0, 0x41, 0x41, 0x34, 0xca, 0x68, 0x66, 0x43, 0x19, 0xcd,
+	0x98, 0xc5, 0x79, 0x6a, 0x10, 0xb1, 0xf8, 0xfb, 0x93, 0xae, 0xba, 0x2c, 0xd1, 0x26, 0xdc, 0x67,
+	0xd2, 0x87, 0xb0, 0x61, 0xf1, 0x90, 0x37, 0xb6, 0x88, 0xa7, 0x07, 0xab, 0x56, 0x42, 0x44, 0x64,
+	0x5a, 0xe9, 0x9b, 0xb6, 0xc1, 0xd3, 0xbc, 0x5e, 0x4f, 0xb7, 0x0d, 0xee, 0x82, 0x0b, 0xca, 0x92,
+	0xc0, 0x2b, 0x02, 0xc6, 0xc8, 0xb2, 0xe6, 0x93, 0xda, 0x98, 0xbe, 0xa1, 0xed, 0x74, 0x1e, 0xbd,
+	0x80, 0xf3, 0x17, 0x93, 0xcd, 0xf0, 0x1c, 0xd3, 0x93, 0xc8, 0x22, 0xfb, 0x3a, 0x8a, 0x6f, 0x90,
+	0x6f, 0xe6, 0x94, 0x02, 0x07, 0x5b, 0x84, 0xb1, 0x64, 0xe3, 0x0c, 0xbf, 0x97, 0xcd, 0x02, 0xe5,
+	0x69, 0xf4, 0x5b, 0xfe, 0x09, 0x5c, 0xbb, 0x1f, 0xce, 0x15, 0xc3, 0x11, 0x46, 0x7a, 0x07, 0xa4,
+	0x78, 0x92, 0x67, 0xfa, 0xc9, 0xc7, 0x72, 0x2c, 0xcd, 0x43, 0xe7, 0x3f, 0x81, 0x52, 0x12, 0x2f,
+	0xe1, 0x3f, 0x1f, 0x45, 0x53, 0x11, 0x79, 0x52, 0x82, 0x4b, 0xab, 0xc2, 0x19, 0x89, 0x6c, 0x51,
+	0x68, 0xa7, 0xfc, 0xec, 0xeb, 0x7e, 0x49, 0x13, 0x44, 0x48, 0x4f, 0x12, 0xa1, 0x45, 0x01, 0x3f,
+	0xb2, 0x95, 0x38, 0x3e, 0x66, 0x78, 0xe6, 0x85, 0x69, 0x8f, 0xe2, 0x7d, 0x2c, 0xc3, 0x0b, 0x2d,
+	0xe1, 0x67, 0x17, 0xd4, 0xf2, 0xc6, 0xe8, 0xf0, 0x0d, 0xdd, 0x33, 0xed, 0x8e, 0x7f, 0x78, 0xf9,
+	0xef, 0xa9, 0xd1, 0x5e, 0xa3, 0x99, 0x20, 0x45, 0xf7, 0xaf, 0x7b, 0x26, 0x10, 0x1f, 0x30, 0x69,
+	0x45, 0xac, 0xe4, 0x93, 0x22, 0x6e, 0x73, 0x8c, 0x47, 0xb9, 0x35, 0x98, 0xeb, 0x7f, 0xf8, 0x9e,
+	0x86, 0xce, 0xc2, 0xbf, 0xa5, 0x59, 0x1c, 0x35, 0x39, 0x7c, 0x8f, 0xe0, 0x19, 0x01, 0xdf, 0x1b,
+	0xc1, 0xf7, 0x18, 0x3c, 0xeb, 0xc3, 0xf7, 0x38, 0xdc, 0xd3, 0x5f, 0x30, 0x98, 0x07, 0xe3, 0x59,
+	0x1c, 0x35, 0x07, 0xf2, 0x3a, 0xdd, 0x36, 0x8f, 0xe8, 0xc3, 0xab, 0xdb, 0x67, 0x8e, 0x2f, 0xc7,
+	0xdf, 0xd2, 0x24, 0x61, 0x78, 0x42, 0x88, 0x91, 0x14, 0x0a, 0x52, 0xc9, 0xa1, 0x60, 0x13, 0xb2,
+	0x17, 0x68, 0x6a, 0xf4, 0x65, 0x51, 0x45, 0xf8, 0x43, 0xa9, 0x04, 0xb9, 0xa1, 0xcd, 0x22, 0x02,
+	0x2e, 0xce, 0xd0, 0xe2, 0xd1, 0x98, 0x6d, 0x60, 0xe8, 0x66, 0xcf, 0xb1, 0x43, 0x1b, 0xcc, 0xf0,
+	0x0d, 0x38, 0x1e, 0x6c, 0x80, 0xb9, 0x33, 0xff, 0x16, 0x48, 0xd6, 0x9c, 0x22, 0x46, 0xd2, 0x1e,
+	0x2c, 0x9f, 0xa2, 0x14, 0x5a, 0x24, 0x10, 0x71, 0xb9, 0x97, 0xd8, 0xc4, 0x41, 0x28, 0x18, 0xa1,
+	0x01, 0xa8, 0x18, 0xf0, 0x4f, 0xca, 0xa3, 0xc4, 0x3c, 0xc3, 0x4e, 0xc4, 0x69, 0xf1, 0x0e, 0xd1,
+	0x87, 0x9e, 0xa3, 0xf1, 0x23, 0x52, 0x48, 0xc8, 0x29, 0xc0, 0xa0, 0x63, 0x42, 0xe4, 0xaf, 0x52,
+	0xb0, 0x56, 0xef, 0x25, 0x65, 0xf7, 0xdf, 0x76, 0xaa, 0xce, 0x62, 0x08, 0x7e, 0x05, 0x1d, 0xdd,
+	0x8e, 0xc6, 0xe3, 0x02, 0x07, 0x85, 0x0e, 0x36, 0x20, 0x6b, 0xb8, 0x2f, 0x35, 0x77, 0x68, 0x0b,
+	0x4d, 0xcf, 0xe1, 0x50, 0x19, 0xda, 0xf2, 0x5f, 0xd0, 0x9d, 0xe3, 0x82, 0x09, 0x3f, 0x40, 0xdd,
+	0x9b, 0xae, 0xeb, 0xb8, 0xa3, 0xba, 0x85, 0x8f, 0x82, 0xb8, 0x9d, 0x0e, 0xc7, 0x6d, 0x96, 0x5e,
+	0x74, 0x5c, 0xab, 0xef, 0x0d, 0x34, 0x8b, 0xf8, 0x09, 0xc3, 0x63, 0xa8, 0x14, 0x78, 0x5d, 0xc0,
+	0x93, 0xe3, 0xf7, 0xcc, 0xa4, 0xf8, 0x2d, 0xff, 0x2a, 0x05, 0x2b, 0x09, 0x65, 0xfb, 0xe5, 0x05,
+	0xef, 0x3d, 0xac, 0xf0, 0xe8, 0x42, 0xa4, 0xd3, 0x2e, 0x4e, 0x6d, 0x05, 0x88, 0x9b, 0x53, 0x2c,
+	0x60, 0x72, 0x92, 0xc4, 0x24, 0x46, 0x5e, 0xe1, 0x03, 0x79, 0x01, 0xe6, 0x5b, 0xb8, 0xc2, 0xff,
+	0x8c, 0x16, 0xa1, 0xc0, 0x87, 0x5c, 0x69, 0xf2, 0x3f, 0x53, 0xb0, 0xaa, 0x90, 0xe6, 0xf9, 0x97,
+	0xd5, 0x72, 0x9d, 0x73, 0x2a, 0xa7, 0xd9, 0xad, 0x69, 0x9e, 0x5b, 0x76, 0x2c, 0xe8, 0x11, 0x26,
+	0x8c, 0x84, 0xc2, 0x90, 0x53, 0x47, 0x12, 0x3c, 0x60, 0x90, 0x20, 0xb8, 0x0d, 0x4b, 0x66, 0x57,
+	0xef, 0xe3, 0xa5, 0xa0, 0x0d, 0x4c, 0xfc, 0x76, 0x0c, 0x3f, 0x60, 0x2c, 0x0a, 0x58, 0xe5, 0x28,
+	0xbb, 0x32, 0x0c, 0xc7, 0x36, 0x85, 0xad, 0xe9, 0xf7, 0xd8, 0xc5, 0x3a, 0x3b, 0x7e, 0xb1, 0x22,
+	0xff, 0xb3, 0xae, 0x7e, 0x7e, 0x8e, 0xfc, 0x83, 0xeb, 0x97, 0x35, 0x5f, 0x16, 0x05, 0xec, 0x9b,
+	0xe3, 0x23, 0x58, 0x09, 0x0b, 0x19, 0x0a, 0xec, 0x97, 0xc8, 0x28, 0x6f, 0xc3, 0x75, 0x05, 0x13,
+	0x2f, 0xac, 0x79, 0x5a, 0x95, 0x8a, 0xe9, 0x8a, 0xcb, 0xd0, 0xf4, 0xd5, 0xf9, 0x33, 0xd8, 0x4a,
+	0x9e, 0x16, 0x3e, 0x79, 0x03, 0xe6, 0x3b, 0x01, 0x2c, 0xec, 0x1d, 0x86, 0x58, 0x0e, 0x8c, 0xf7,
+	0xaf, 0xa6, 0x9f, 0x61, 0xaa, 0x24, 0x54, 0x98, 0x43, 0xa0, 0xcc, 0xc6, 0xb2, 0x0a, 0x5b, 0xea,
+	0xb4, 0xb2, 0xf6, 0x9b, 0x94, 0x07, 0xf2, 0x2e, 0x6c, 0xab, 0xd3, 0xea, 0x59, 0x79, 0x0b, 0x4a,
+	0x93, 0x1b, 0x3b, 0xb2, 0x0d, 0xd7, 0xfe, 0xaf, 0xbd, 0xa6, 0x3f, 0xa5, 0x60, 0x43, 0xc5, 0x6c,
+	0xc4, 0xa3, 0xdc, 0xd7, 0x08, 0x27, 0x24, 0xaf, 0xa0, 0x04, 0xf9, 0x61, 0xa0, 0xc1, 0x0c, 0x9d,
+	0xf2, 0xf5, 0x58, 0x6e, 0x1b, 0xec, 0x9c, 0xa8, 0xcc, 0x2f, 0x60, 0x3d, 0x99, 0xe4, 0xf2, 0x4f,
+	0x1d, 0xbf, 0xd7, 0x01, 0x5b, 0x2a, 0xf2, 0x56, 0x3e, 0x48, 0xec, 0x78, 0x65, 0x12, 0x3b, 0x5e,
+	0xf2, 0x1f, 0x98, 0x66, 0x92, 0x73, 0x6f, 0xca, 0x59, 0x3c, 0xdd, 0xf5, 0xe2, 0x39, 0x0b, 0xc3,
+	0x84, 0xe8, 0x49, 0x05, 0x56, 0x3a, 0xb9, 0xc0, 0x9a, 0x5a, 0xa8, 0xf9, 0xcd, 0xa5, 0x99, 0x50,
+	0x73, 0xe9, 0x63, 0xb8, 0xae, 0x0e, 0x4f, 0x59, 0x20, 0x3d, 0x35, 0x43, 0xfa, 0xf1, 0xdd, 0xc4,
+	0xe7, 0xe7, 0xd8, 0xdd, 0x97, 0xe2, 0xae, 0x26, 0x7e, 0x47, 0x38, 0x96, 0x7f, 0x1c, 0x5a, 0xfb,
+	0x8d, 0xb2, 0x31, 0xf9, 0x4b, 0x98, 0x0f, 0x5b, 0xe2, 0x75, 0x58, 0xe0, 0x43, 0xa1, 0x38, 0x5a,
+	0x92, 0x57, 0xa2, 0xa0, 0xb4, 0x03, 0xd0, 0x1e, 0x19, 0xc7, 0x2f, 0x9a, 0x03, 0x84, 0x05, 0x9b,
+	0xfe, 0xd0, 0xed, 0xa0, 0x31, 0xcd, 0xe8, 0xcd, 0xb5, 0xe8, 0xc3, 0x62, 0xf7, 0xaf, 0xd2, 0xb0,
+	0x3c, 0x56, 0xed, 0x33, 0x6b, 0x77, 0xad, 0x9e, 0xe5, 0xf9, 0xfd, 0x50, 0x1a, 0xb0, 0x3b, 0x2b,
+	0x52, 0xa5, 0x8b, 0xd1, 0xd7, 0xf0, 0x02, 0x69, 0x7f, 0x74, 0x63, 0xcc, 0xd0, 0x8d, 0x51, 0x4a,
+	0x0a, 0x01, 0xb1, 0xab, 0xe2, 0x13, 0x00, 0x76, 0x8f, 0x89, 0x75, 0xb3, 0xb4, 0x6e, 0x3b, 0x69,
+	0x1d, 0x46, 0x07, 0xb1, 0x34, 0x7f, 0xe6, 0xff, 0x94, 0xee, 0xc2, 0x4a, 0x0f, 0x83, 0x66, 0x5c,
+	0x1b, 0x3c, 0x9d, 0x59, 0xc6, 0xa9, 0x56, 0x44, 0x21, 0x44, 0x8f, 0x99, 0x5e, 0x9c, 0x3e, 0x2b,
+	0xe8, 0xf5, 0x17, 0x31, 0xfa, 0xa0, 0x01, 0x99, 0x8b, 0x34, 0x20, 0x7f, 0x01, 0x0b, 0x91, 0xfc,
+	0x44, 0x7a, 0x30, 0x2a, 0x47, 0x46, 0x81, 0x26, 0x75, 0xd5, 0x40, 0x23, 0x6a, 0x16, 0x0e, 0xf1,
+	0xa4, 0xc4, 0x30, 0xcd, 0x9e, 0xc6, 0x2f, 0x7f, 0x61, 0x8e, 0x02, 0x07, 0x55, 0xc2, 0xe4, 0xbf,
+	0xe2, 0x5d, 0x99, 0x54, 0x40, 0x24, 0x5a, 0x2b, 0x95, 0x6c, 0xad, 0x51, 0xce, 0x9d, 0x0e, 0xe7,
+	0xdc, 0x28, 0xb1, 0x28, 0xf6, 0xb9, 0x4b, 0x89, 0x11, 0xc3, 0x5d, 0xf3, 0xb9, 0xee, 0x1a, 0xe2,
+	0xe3, 0x12, 0x23, 0x69, 0x0b, 0xf2, 0x67, 0x78, 0x93, 0x9d, 0xea, 0xac, 0x97, 0xc1, 0xb3, 0xea,
+	0x00, 0x90, 0xff, 0x8c, 0xc9, 0x5f, 0xa2, 0xd0, 0x8c, 0x1f, 0x9b, 0xa8, 0x1b, 0xe2, 0xab, 0x11,
+	0x23, 0xe9, 0x0e, 0x2c, 0x3d, 0x64, 0x07, 0x55, 0x47, 0x07, 0xf5, 0xbb, 0xec, 0x31, 0x98, 0xe5,
+	0xc9, 0x7e, 0x37, 0x5a, 0x9c, 0x75, 0x34, 0x66, 0x5c, 0xfc, 0xdf, 0x22, 0x51, 0xf5, 0x1b, 0x36,
+	0x31, 0x58, 0x6e, 0xc2, 0x36, 0xfe, 0xb4, 0xce, 0x5e, 0x56, 0x9c, 0xae, 0xc1, 0xef, 0xe4, 0xda,
+	0x0b, 0xaf, 0x35, 0x3c, 0x0d, 0x4a, 0xc2, 0x95, 0x0e, 0x4e, 0x69, 0x22, 0xb1, 0x67, 0xdd, 0xac,
+	0xfe, 0xf0, 0x54, 0x28, 0xb5, 0xd8, 0x89, 0xad, 0x92, 0x2b, 0xb0, 0x33, 0x89, 0x9f, 0xb8, 0x88,
+	0x59, 0x53, 0x85, 0xe5, 0x2a, 0x51, 0xf3, 0xcc, 0x33, 0xcc, 0x0f, 0xa7, 0xff, 0x48, 0x43, 0x31,
+	0x5e, 0x5f, 0xbd, 0xd2, 0x17, 0x8a, 0x77, 0x30, 0x41, 0x63, 0xd5, 0x1a, 0x29, 0x6e, 0x71, 0x7f,
+	0x63, 0xbc, 0xb4, 0xab, 0xb1, 0x69, 0x85, 0x53, 0xc5, 0x2e, 0xb7, 0x99, 0xcb, 0x2e, 0xb7, 0xd9,
+	0x4b, 0x1e, 0x34, 0xe6, 0xa6, 0x3d, 0x68, 0x64, 0x63, 0x0f, 0x1a, 0x81, 0xe3, 0xe5, 0x22, 0x8e,
+	0xe7, 0xc7, 0xfa, 0x7c, 0x28, 0xd6, 0x17, 0x61, 0x51, 0xd8, 0xd5, 0xcf, 0x8a, 0xfe, 0x95, 0x46,
+	0x4f, 0xf0, 0xa1, 0xa0, 0xaf, 0x23, 0x0a, 0x1a, 0x0c, 0x3b, 0x2e, 0xab, 0x90, 0x44, 0x14, 0x16,
+	0xa8, 0x4a, 0x20, 0xfb, 0x3e, 0x7a, 0xfa, 0xe7, 0x22, 0x1e, 0x2e, 0x28, 0x7c, 0x40, 0xa8, 0x65,
+	0x8b, 0xd4, 0x96, 0xa1, 0x6c, 0xc0, 0xd0, 0x3e, 0x7b, 0x85, 0x12, 0x79, 0x38, 0x1f, 0xb0, 0x38,
+	0xde, 0x77, 0x4d, 0xd7, 0xec, 0x9a, 0x18, 0x51, 0x48, 0x2b, 0x79, 0x25, 0x84, 0xb0, 0x83, 0x9c,
+	0x0e, 0x2d, 0xf4, 0xad, 0x9e, 0xe9, 0xe9, 0x06, 0xc6, 0x12, 0xd2, 0x0c, 0x1e, 0x84, 0xd0, 0x87,
+	0x02, 0xa4, 0x12, 0xab, 0xdf, 0x8f, 0x14, 0x61, 0xc8, 0x07, 0x21, 0xbf, 0x06, 0x43, 0xf3, 0x30,
+	0x02, 0xd6, 0x67, 0xc1, 0xa8, 0x9e, 0xa3, 0xf9, 0x3c, 0x22, 0x15, 0x02, 0xf0, 0xd2, 0x59, 0x64,
+	0xd3, 0x7c, 0x2b, 0x83, 0x25, 0x7f, 0x79, 0x22, 0x29, 0x20, 0x7a, 0xc0, 0xc0, 0x2a, 0xcb, 0xfe,
+	0x3e, 0x81, 0x42, 0x47, 0xef, 0xeb, 0xa7, 0x56, 0xd7, 0xf2, 0x2c, 0xea, 0x06, 0x66, 0xd0, 0x33,
+	0x62, 0x8d, 0xf7, 0x8a, 0x4f, 0x81, 0x51, 0x2b, 0x4c, 0xbd, 0xf7, 0x9f, 0x34, 0x40, 0x30, 0x89,
+	0x46, 0x93, 0x2a, 0xe5, 0x56, 0xf9, 0xa0, 0xde, 0xa8, 0xb7, 0x9f, 0x68, 0xc7, 0xcd, 0x4f, 0x9b,
+	0x47, 0x8f, 0x9a, 0xc5, 0xd7, 0x24, 0x19, 0x76, 0x42, 0xb8, 0xda, 0xaa, 0x35, 0xdb, 0xda, 0xc3,
+	0xba, 0xaa, 0xd6, 0xaa, 0x9a, 0xda, 0x56, 0x6a, 0xe5, 0x87, 0xc5, 0x14, 0x46, 0x94, 0xcd, 0x10,
+	0x4d, 0xf9, 0x7e, 0xad, 0x59, 0x2d, 0x6b, 0x27, 0x47, 0xed, 0x7a, 0xf3, 0x7e, 0x31, 0x2d, 0xbd,
+	0x09, 0x72, 0x68, 0xf6, 0xa0, 0xdc, 0xae, 0x3c, 0xd0, 0x8e, 0xd5, 0x9a, 0x22, 0x28, 0xb4, 0x96,
+	0x52, 0x3b, 0x54, 0x8b, 0x19, 0xd4, 0xc9, 0xb5, 0x10, 0x5d, 0xbb, 0x5e, 0xf9, 0xb4, 0xd6, 0xd6,
+	0x0e, 0xeb, 0x8d, 0x76, 0x4d, 0x51, 0x8b, 0x33, 0x68, 0x9a, 0x52, 0x68, 0x9a, 0x1d, 0x81, 0x2d,
+	0xe6, 0x64, 0x6a, 0x71, 0x16, 0x83, 0xcb, 0x7a, 0x68, 0xfe, 0x51, 0xb9, 0xd1, 0xc0, 0xe5, 0xf5,
+	0xe6, 0xe1, 0x51, 0x71, 0x2e, 0x76, 0x40, 0x31, 0xa7, 0xd4, 0xd4, 0x4a, 0xb9, 0x59, 0xcc, 0xa2,
+	0x33, 0x6f, 0x84, 0x66, 0xab, 0x47, 0xc7, 0x07, 0x8d, 0x1a, 0x3b, 0x5c, 0x4d, 0x2d, 0xe6, 0xf0,
+	0xe6, 0xbe, 0x15, 0x96, 0xbf, 0x5d, 0xfe, 0xb4, 0xa6, 0x55, 0xeb, 0x87, 0x87, 0xf5, 0xca, 0x71,
+	0x03, 0x81, 0x07, 0x75, 0xb5, 0x7d, 0xa4, 0x3c, 0x29, 0xe6, 0x63, 0x8a, 0x62, 0xcb, 0xfd, 0x49,
+	0x5f, 0x51, 0xb0, 0xd7, 0x87, 0x62, 0xfc, 0x99, 0x8d, 0xc9, 0x15, 0xda, 0x52, 0x53, 0x8f, 0x8e,
+	0x95, 0x4a, 0x2d, 0x64, 0x00, 0x3c, 0x5d, 0xc2, 0x7c, 0xeb, 0xe8, 0xa8, 0x81, 0x9a, 0xdf, 0x85,
+	0xeb, 0x09, 0x93, 0xb5, 0xc7, 0xa8, 0xb3, 0x66, 0xb9, 0x51, 0x4c, 0xef, 0xfd, 0x37, 0x5e, 0x4b,
+	0x8a, 0x6b, 0xf8, 0x1a, 0xac, 0x45, 0x55, 0xa8, 0x1d, 0x96, 0xeb, 0x8d, 0x5a, 0x15, 0x37, 0xdc,
+	0x84, 0xd5, 0xd8, 0x54, 0xb9, 0x5a, 0xc5, 0x99, 0x14, 0x13, 0x31, 0x36, 0x53, 0xbf, 0xdf, 0x3c,
+	0x52, 0xd0, 0x15, 0x1a, 0x47, 0x8f, 0xb4, 0xc3, 0x5a, 0x0d, 0xad, 0x3d, 0x4e, 0x53, 0x6e, 0xa0,
+	0xf4, 0x55, 0xb4, 0xa8, 0x52, 0xc6, 0x71, 0x15, 0x2d, 0x8d, 0x22, 0xc5, 0x68, 0x9a, 0x47, 0x6d,
+	0xad, 0x51, 0x3f, 0xa9, 0xa1, 0x9d, 0x6f, 0xc0, 0x56, 0xc2, 0x64, 0xbd, 0x29, 0xcc, 0x86, 0x96,
+	0x1e, 0xdf, 0x82, 0x51, 0x30, 0x8d, 0x88, 0x71, 0x71, 0x6e, 0xef, 0x63, 0x58, 0x8a, 0x25, 0x21,
+	0xd2, 0x3c, 0x64, 0xcb, 0xcd, 0x27, 0x74, 0xcc, 0xd7, 0xa4, 0x05, 0xc8, 0x9f, 0x94, 0x1b, 0xf5,
+	0x2a, 0x0d, 0x53, 0x6c, 0x6e, 0x24, 0xc2, 0x5e, 0x1d, 0x0a, 0x11, 0x5d, 0x65, 0x21, 0x83, 0x0b,
+	0x71, 0x51, 0x0e, 0x66, 0xe8, 0x90, 0x29, 0x69, 0x19, 0x16, 0x48, 0x29, 0x21, 0xc1, 0x57, 0x60,
+	0x29, 0xae, 0x8d, 0xcc, 0xde, 0x7b, 0xb8, 0x8d, 0x1f, 0x9a, 0xa5, 0x02, 0xe4, 0xd4, 0x5a, 0xa3,
+	0x56, 0x69, 0x93, 0x9a, 0xf3, 0x30, 0xcb, 0x6c, 0xc6, 0xf4, 0x0a, 0x30, 0xc7, 0x3f, 0xa9, 0x62,
+	0x7a, 0xff, 0xf7, 0x12, 0x2c, 0xab, 0xfe, 0x07, 0x8c, 0x35, 0xae, 0x7b, 0x61, 0xa1, 0x93, 0x18,
+	0xb0, 0x3c, 0xf6, 0x17, 0x00, 0xe9, 0xcd, 0xe8, 0x97, 0x3e, 0xe9, 0x4f, 0x06, 0xa5, 0xdb, 0x97,
+	0xd2, 0x89, 0x30, 0x7b, 0x01, 0x1b, 0x13, 0x5e, 0xe6, 0xa5, 0xb7, 0xa3, 0x3c, 0xa6, 0xff, 0x6b,
+	0xa0, 0xf4, 0xce, 0x15, 0xa9, 0xc5, 0xbe, 0x9f, 0xc1, 0x62, 0xf4, 0x6d, 0x58, 0x8a, 0x65, 0x61,
+	0x89, 0x6f, 0xcd, 0xa5, 0xd7, 0xa7, 0x13, 0x09, 0xe6, 0x7d, 0x6a, 0xfd, 0x8d, 0x57, 0xac, 0xd2,
+	0x5e, 0x74, 0xf9, 0xb4, 0x27, 0xe0, 0xd2, 0x77, 0xae, 0x44, 0x1b, 0x88, 0x13, 0x7d, 0x2a, 0x8d,
+	0x8b, 0x93, 0xf8, 0x08, 0x1b, 0x17, 0x67, 0xc2, 0x6b, 0x2b, 0xda, 0x68, 0xc2, 0x93, 0x66, 0xdc,
+	0x46, 0xd3, 0xdf, 0x55, 0xe3, 0x36, 0xba, 0xec, 0x9d, 0x94, 0x0b, 0x15, 0x7a, 0x66, 0x4c, 0x10,
+	0x6a, 0xfc, 0x65, 0x33, 0x41, 0xa8, 0xa4, 0x97, 0xca, 0x63, 0x28, 0x84, 0x5f, 0x09, 0xa5, 0x9b,
+	0x63, 0xab, 0xe2, 0x2f, 0x8b, 0x25, 0x79, 0x1a, 0x89, 0x60, 0xfb, 0x25, 0x3d, 0x1c, 0x24, 0x3f,
+	0x51, 0x49, 0x77, 0xc7, 0x18, 0x4c, 0x7d, 0x12, 0x2b, 0xbd, 0x7b, 0x65, 0x7a, 0xb1, 0x7b, 0x03,
+	0xf2, 0xa3, 0x17, 0x2a, 0x69, 0x27, 0x69, 0x75, 0xf0, 0x9e, 0x55, 0xda, 0x9d, 0x38, 0x2f, 0xb8,
+	0x9d, 0x83, 0x34, 0xfe, 0x70, 0x21, 0xdd, 0x1e, 0x5b, 0x96, 0xfc, 0x4c, 0x52, 0xba, 0x73, 0x39,
+	0x61, 0xc4, 0xd0, 0xa1, 0x5c, 0x36, 0xc1, 0xd0, 0xe3, 0x95, 0x75, 0x82, 0xa1, 0x93, 0x5e, 0x28,
+	0x02, 0xe6, 0xe2, 0x3d, 0x61, 0x02, 0xf3, 0xe8, 0x3b, 0xc4, 0x04, 0xe6, 0xf1, 0x27, 0x89, 0xc7,
+	0xb0, 0x10, 0x69, 0xf2, 0x4b, 0xe3, 0x3e, 0x32, 0xf6, 0x34, 0x50, 0xba, 0x35, 0x95, 0x26, 0x38,
+	0x76, 0xb4, 0x6f, 0x1c, 0x3f, 0x76, 0x62, 0xbb, 0x3c, 0x7e, 0xec, 0x09, 0xad, 0xe7, 0x1f, 0xc0,
+	0x0c, 0xeb, 0xaa, 0x4a, 0xb1, 0xf6, 0x5b, 0xa8, 0xf1, 0x5a, 0x2a, 0x25, 0x4d, 0x89, 0xe5, 0x8f,
+	0xa0, 0x10, 0x6e, 0x4f, 0xc6, 0xbf, 0x9d, 0x84, 0xd6, 0x65, 0xfc, 0xdb, 0x49, 0x6a, 0xe1, 0xbe,
+	0x97, 0x92, 0x7a, 0xb0, 0x9a, 0xd4, 0x9e, 0x94, 0xde, 0x8a, 0xad, 0x9e, 0xdc, 0xe1, 0x2c, 0xed,
+	0x5d, 0x85, 0x34, 0x88, 0xd3, 0xea, 0x55, 0xe2, 0xb4, 0xfa, 0x35, 0xe2, 0xf4, 0xd4, 0x56, 0x25,
+	0xfb, 0xa4, 0x12, 0x6e, 0xba, 0xdb, 0x63, 0x2c, 0x26, 0x5c, 0x72, 0x77, 0x2e, 0x27, 0x14, 0x1b,
+	0x7d, 0x0e, 0xab, 0x49, 0x0d, 0xad, 0xb8, 0x26, 0xa7, 0x34, 0xbd, 0x4a, 0x6f, 0x4c, 0xec, 0x2c,
+	0x86, 0x7b, 0x9a, 0x68, 0xb5, 0x4e, 0x68, 0xaf, 0xf0, 0x47, 0x3c, 0x69, 0xaf, 0x84, 0x4f, 0xf9,
+	0x92, 0x67, 0x43, 0xdc, 0x64, 0x00, 0xeb, 0xc9, 0x25, 0xb3, 0x14, 0x33, 0xc0, 0xd4, 0x42, 0xbd,
+	0xf4, 0xf6, 0xd5, 0x88, 0xb9, 0x16, 0xf7, 0x1f, 0x8f, 0x4a, 0x45, 0x3f, 0x2b, 0x3a, 0x84, 0xac,
+	0x5f, 0x50, 0x6d, 0x8d, 0xb1, 0x0a, 0xd5, 0x94, 0xa5, 0xed, 0x09, 0xb3, 0x9c, 0xf3, 0xe9, 0x1c,
+	0xfd, 0x6d, 0xf3, 0x83, 0xff, 0x01, 0x26, 0x37, 0x1b, 0xeb, 0xc3, 0x29, 0x00, 0x00,
 }
@@ -0,0 +1,178 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"strings"
+
+	xcontext "golang.org/x/net/context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// rpcAuthHeader is the gRPC metadata key carrying the token of an RPC
+// client, as "Bearer <token>".
+const rpcAuthHeader = "authorization"
+
+// rpcRole is what an RPC client is allowed to do.
+type rpcRole string
+
+const (
+	// rpcRoleFrontend may call every method.  It is the role of
+	// hcstakepool.
+	rpcRoleFrontend rpcRole = "frontend"
+
+	// rpcRoleMonitor may only call the methods in monitorMethods, which
+	// report status without changing anything or exposing user data.
+	rpcRoleMonitor rpcRole = "monitor"
+)
+
+// monitorMethods are the full names of the methods the monitor role may call.
+// The ticket lists and double votes name the multisig addresses of the users,
+// so they are left to the frontend.
+var monitorMethods = map[string]bool{
+	"/stakepoolrpc.StakepooldService/GetPoolStats":              true,
+	"/stakepoolrpc.StakepooldService/GetStakeDifficultyHistory": true,
+	"/stakepoolrpc.StakepooldService/GetStatus":                 true,
+	"/stakepoolrpc.StakepooldService/GetVoteLatency":            true,
+	"/stakepoolrpc.StakepooldService/GetWalletInfo":             true,
+	"/stakepoolrpc.StakepooldService/Ping":                      true,
+	"/stakepoolrpc.VersionService/Version":                      true,
+}
+
+// rpcClient is the identity of an RPC client: the role its token grants and
+// its name, which quotas and call counts are kept by.  The name is the role
+// and the start of the hash of the token, e.g. monitor:5f1e2d3c4b5a6978, so
+// it doesn't change when the rpcauth options are reordered.  Without rpcauth
+// every client is frontend, named by its TLS client certificate or, without
+// one, its IP address.
+type rpcClient struct {
+	name string
+	role rpcRole
+}
+
+// rpcToken is a token accepted from RPC clients and the client it
+// identifies.
+type rpcToken struct {
+	token  []byte
+	client rpcClient
+}
+
+// rpcAuthorizer decides which RPC methods a client may call based on the
+// token it sends.  A nil rpcAuthorizer lets every client call every method,
+// which is how stakepoold behaves when no rpcauth option is set.
+type rpcAuthorizer struct {
+	tokens []rpcToken
+}
+
+// rpcAuth is the authorizer of the gRPC server.  It is nil unless rpcauth is
+// set in the config.
+var rpcAuth *rpcAuthorizer
+
+// newRPCAuthorizer parses rpcauth options of the form role:token.  It returns
+// nil when there are none.
+func newRPCAuthorizer(options []string) (*rpcAuthorizer, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+	a := &rpcAuthorizer{}
+	seen := make(map[string]bool)
+	for _, option := range options {
+		parts := strings.SplitN(option, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("rpcauth must be role:token")
+		}
+		role := rpcRole(parts[0])
+		if role != rpcRoleFrontend && role != rpcRoleMonitor {
+			return nil, fmt.Errorf("unknown rpcauth role %q, must be "+
+				"%s or %s", role, rpcRoleFrontend, rpcRoleMonitor)
+		}
+		if seen[parts[1]] {
+			return nil, fmt.Errorf("rpcauth token given more than once")
+		}
+		seen[parts[1]] = true
+		a.tokens = append(a.tokens, rpcToken{
+			token: []byte(parts[1]),
+			client: rpcClient{
+				name: tokenClientName(role, parts[1]),
+				role: role,
+			},
+		})
+	}
+	return a, nil
+}
+
+// tokenClientName returns the name of the client of role with token.  Only
+// the start of the hash of the token is used, so the name can be logged.
+func tokenClientName(role rpcRole, token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("%s:%x", role, hash[:8])
+}
+
+// peerClientName returns the name of the frontend client making the call in
+// ctx without rpcauth: the start of the hash of its TLS client certificate,
+// or its IP address when it sent none.
+func peerClientName(ctx xcontext.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return string(rpcRoleFrontend)
+	}
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok &&
+		len(tlsInfo.State.PeerCertificates) != 0 {
+		hash := sha256.Sum256(tlsInfo.State.PeerCertificates[0].Raw)
+		return fmt.Sprintf("%s:%x", rpcRoleFrontend, hash[:8])
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return fmt.Sprintf("%s@%s", rpcRoleFrontend, host)
+}
+
+// client returns the client identified by token, comparing it with every
+// configured token in constant time.
+func (a *rpcAuthorizer) client(token string) (rpcClient, bool) {
+	var client rpcClient
+	var found bool
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(t.token, []byte(token)) == 1 {
+			client, found = t.client, true
+		}
+	}
+	return client, found
+}
+
+// authorize returns the client making the call in ctx to fullMethod, or an
+// Unauthenticated error when it sent no valid token and a PermissionDenied
+// error when its role may not call the method.
+func (a *rpcAuthorizer) authorize(ctx xcontext.Context, fullMethod string) (rpcClient, error) {
+	if a == nil {
+		return rpcClient{name: peerClientName(ctx), role: rpcRoleFrontend}, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md[rpcAuthHeader]
+	if len(values) != 1 || !strings.HasPrefix(values[0], "Bearer ") {
+		return rpcClient{}, status.Error(codes.Unauthenticated, "missing token")
+	}
+	client, ok := a.client(strings.TrimPrefix(values[0], "Bearer "))
+	if !ok {
+		return rpcClient{}, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	if client.role == rpcRoleMonitor && !monitorMethods[fullMethod] {
+		return client, status.Errorf(codes.PermissionDenied,
+			"role %s may not call %s", client.role, fullMethod)
+	}
+	return client, nil
+}
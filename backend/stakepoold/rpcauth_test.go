@@ -0,0 +1,89 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"net"
+	"testing"
+
+	xcontext "golang.org/x/net/context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestRPCAuthorizer(t *testing.T) {
+	const (
+		setPrefs    = "/stakepoolrpc.StakepooldService/SetUserVotingPrefs"
+		ping        = "/stakepoolrpc.StakepooldService/Ping"
+		liveTickets = "/stakepoolrpc.StakepooldService/GetLiveTickets"
+	)
+
+	// Without rpcauth, clients are told apart by their address.
+	var noAuth *rpcAuthorizer
+	ctx := peer.NewContext(xcontext.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 40000},
+	})
+	if client, err := noAuth.authorize(ctx, setPrefs); err != nil ||
+		client.role != rpcRoleFrontend || client.name != "frontend@10.0.0.1" {
+		t.Fatalf("nil authorizer returned %v, %v", client, err)
+	}
+
+	// Names don't depend on the order of the tokens.
+	a, err := newRPCAuthorizer([]string{"monitor:mtoken2", "frontend:ftoken",
+		"monitor:mtoken"})
+	if err != nil {
+		t.Fatalf("newRPCAuthorizer: %v", err)
+	}
+	frontend := tokenClientName(rpcRoleFrontend, "ftoken")
+	monitor1 := tokenClientName(rpcRoleMonitor, "mtoken")
+	monitor2 := tokenClientName(rpcRoleMonitor, "mtoken2")
+	if monitor1 == monitor2 {
+		t.Fatalf("monitors share the name %s", monitor1)
+	}
+
+	tests := []struct {
+		name   string
+		header []string
+		method string
+		client string
+		code   codes.Code
+	}{
+		{"no token", nil, ping, "", codes.Unauthenticated},
+		{"not bearer", []string{"ftoken"}, ping, "", codes.Unauthenticated},
+		{"unknown token", []string{"Bearer other"}, ping, "", codes.Unauthenticated},
+		{"frontend mutation", []string{"Bearer ftoken"}, setPrefs, frontend, codes.OK},
+		{"monitor status", []string{"Bearer mtoken"}, ping, monitor1, codes.OK},
+		{"second monitor", []string{"Bearer mtoken2"}, ping, monitor2, codes.OK},
+		{"monitor mutation", []string{"Bearer mtoken"}, setPrefs, monitor1, codes.PermissionDenied},
+		{"monitor user data", []string{"Bearer mtoken"}, liveTickets, monitor1, codes.PermissionDenied},
+		{"frontend user data", []string{"Bearer ftoken"}, liveTickets, frontend, codes.OK},
+	}
+	for _, test := range tests {
+		md := metadata.MD{}
+		if test.header != nil {
+			md[rpcAuthHeader] = test.header
+		}
+		ctx := metadata.NewIncomingContext(xcontext.Background(), md)
+		client, err := a.authorize(ctx, test.method)
+		if client.name != test.client || grpc.Code(err) != test.code {
+			t.Errorf("%s: got %v, %v, want %v, %v", test.name, client.name,
+				err, test.client, test.code)
+		}
+	}
+
+	invalid := [][]string{
+		{"frontend"},
+		{"frontend:"},
+		{"admin:token"},
+		{"frontend:token", "monitor:token"},
+	}
+	for _, options := range invalid {
+		if _, err := newRPCAuthorizer(options); err == nil {
+			t.Errorf("newRPCAuthorizer(%q) succeeded", options)
+		}
+	}
+}
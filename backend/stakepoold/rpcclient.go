@@ -1,117 +1,58 @@
 package main
 
 import (
-	"fmt"
-	"io/ioutil"
 	"time"
 
 	"github.com/coolsnady/hcd/chaincfg/chainhash"
 	"github.com/coolsnady/hcutil"
-	"github.com/coolsnady/hcrpcclient"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
 	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
 )
 
-var requiredChainServerAPI = semver{major: 3, minor: 1, patch: 0}
-var requiredWalletAPI = semver{major: 5, minor: 0, patch: 0}
-
-func connectNodeRPC(ctx *appContext, cfg *config) (*hcrpcclient.Client, semver, error) {
-	var nodeVer semver
-
-	hcdCert, err := ioutil.ReadFile(cfg.HcdCert)
-	if err != nil {
-		log.Errorf("Failed to read hcd cert file at %s: %s\n",
-			cfg.HcdCert, err.Error())
-		return nil, nodeVer, err
-	}
-
-	log.Debugf("Attempting to connect to hcd RPC %s as user %s "+
-		"using certificate located in %s",
-		cfg.HcdHost, cfg.HcdUser, cfg.HcdCert)
-
-	connCfgDaemon := &hcrpcclient.ConnConfig{
-		Host:         cfg.HcdHost,
-		Endpoint:     "ws", // websocket
-		User:         cfg.HcdUser,
-		Pass:         cfg.HcdPassword,
-		Certificates: hcdCert,
-	}
-
-	ntfnHandlers := getNodeNtfnHandlers(ctx, connCfgDaemon)
-	hcdClient, err := hcrpcclient.New(connCfgDaemon, ntfnHandlers)
-	if err != nil {
-		log.Errorf("Failed to start hcd RPC client: %s\n", err.Error())
-		return nil, nodeVer, err
-	}
-
-	// Ensure the RPC server has a compatible API version.
-	ver, err := hcdClient.Version()
-	if err != nil {
-		log.Error("Unable to get RPC version: ", err)
-		return nil, nodeVer, fmt.Errorf("Unable to get node RPC version")
+// nodeRPCConfig returns the hcd connection settings from cfg.
+func nodeRPCConfig(cfg *config) *rpcclient.Config {
+	return &rpcclient.Config{
+		Host:      cfg.HcdHost,
+		User:      cfg.HcdUser,
+		Password:  cfg.HcdPassword,
+		CertPath:  cfg.HcdCert,
+		Proxy:     cfg.Proxy,
+		ProxyUser: cfg.ProxyUser,
+		ProxyPass: cfg.ProxyPass,
 	}
-
-	hcdVer := ver["hcdjsonrpcapi"]
-	nodeVer = semver{hcdVer.Major, hcdVer.Minor, hcdVer.Patch}
-
-	if !semverCompatible(requiredChainServerAPI, nodeVer) {
-		return nil, nodeVer, fmt.Errorf("Node JSON-RPC server does not have "+
-			"a compatible API version. Advertises %v but require %v",
-			nodeVer, requiredChainServerAPI)
-	}
-
-	return hcdClient, nodeVer, nil
 }
 
-func connectWalletRPC(cfg *config) (*hcrpcclient.Client, semver, error) {
-	var walletVer semver
-
-	hxwCert, err := ioutil.ReadFile(cfg.WalletCert)
-	if err != nil {
-		log.Errorf("Failed to read hcwallet cert file at %s: %s\n",
-			cfg.WalletCert, err.Error())
-		return nil, walletVer, err
-	}
-
-	log.Infof("Attempting to connect to hcwallet RPC %s as user %s "+
-		"using certificate located in %s",
-		cfg.WalletHost, cfg.WalletUser, cfg.WalletCert)
-
-	connCfgWallet := &hcrpcclient.ConnConfig{
-		Host:         cfg.WalletHost,
-		Endpoint:     "ws",
-		User:         cfg.WalletUser,
-		Pass:         cfg.WalletPassword,
-		Certificates: hxwCert,
-	}
-
-	ntfnHandlers := getWalletNtfnHandlers(cfg)
-	hxwClient, err := hcrpcclient.New(connCfgWallet, ntfnHandlers)
-	if err != nil {
-		log.Errorf("Verify that username and password is correct and that "+
-			"rpc.cert is for your wallet: %v", cfg.WalletCert)
-		return nil, walletVer, err
+// walletRPCConfig returns the hcwallet connection settings from cfg.
+func walletRPCConfig(cfg *config) *rpcclient.Config {
+	return &rpcclient.Config{
+		Host:      cfg.WalletHost,
+		User:      cfg.WalletUser,
+		Password:  cfg.WalletPassword,
+		CertPath:  cfg.WalletCert,
+		Proxy:     cfg.Proxy,
+		ProxyUser: cfg.ProxyUser,
+		ProxyPass: cfg.ProxyPass,
 	}
+}
 
-	// Ensure the wallet RPC server has a compatible API version.
-	ver, err := hxwClient.Version()
+// connectWallet connects to hcwallet with the configured faults injected and
+// the calls in progress counted.
+func connectWallet(cfg *config) (rpcclient.WalletSource, rpcclient.Semver, error) {
+	w, ver, err := rpcclient.ConnectWallet(walletRPCConfig(cfg))
 	if err != nil {
-		log.Error("Unable to get RPC version: ", err)
-		return nil, walletVer, fmt.Errorf("Unable to get node RPC version")
-	}
-
-	hxwVer := ver["hcwalletjsonrpcapi"]
-	walletVer = semver{hxwVer.Major, hxwVer.Minor, hxwVer.Patch}
-
-	if !semverCompatible(requiredWalletAPI, walletVer) {
-		log.Warnf("Node JSON-RPC server %v does not have "+
-			"a compatible API version. Advertizes %v but require %v",
-			cfg.WalletHost, walletVer, requiredWalletAPI)
+		return nil, ver, err
 	}
+	return countWallet(faults.wallet(w)), ver, nil
+}
 
-	return hxwClient, walletVer, nil
+// connectNode connects to hcd with the configured faults injected into the
+// notifications to n.
+func connectNode(cfg *config, n rpcclient.ChainNotifications) (rpcclient.ChainSource, rpcclient.Semver, error) {
+	return rpcclient.ConnectNode(nodeRPCConfig(cfg), faults.notifications(n))
 }
 
-func walletGetTickets(ctx *appContext, currentHeight int64) (map[chainhash.Hash]string, map[chainhash.Hash]string, error) {
+func walletGetTickets(ctx *appContext, currentHeight int64, known map[chainhash.Hash]snapshotTicket) (map[chainhash.Hash]string, map[chainhash.Hash]string, error) {
 	blockHashToHeightCache := make(map[chainhash.Hash]int32)
 
 	// This is suboptimal to copy and needs fixing.
@@ -124,11 +65,12 @@ func walletGetTickets(ctx *appContext, currentHeight int64) (map[chainhash.Hash]
 
 	ignoredLowFeeTickets := make(map[chainhash.Hash]string)
 	liveTickets := make(map[chainhash.Hash]string)
+	heights := make(map[chainhash.Hash]int64)
 	normalFee := 0
 
 	log.Info("Calling GetTickets...")
 	timenow := time.Now()
-	tickets, err := ctx.walletConnection.GetTickets(false)
+	tickets, err := ctx.wallet().GetTickets(false)
 	log.Infof("GetTickets: took %v", time.Since(timenow))
 
 	if err != nil {
@@ -137,20 +79,71 @@ func walletGetTickets(ctx *appContext, currentHeight int64) (map[chainhash.Hash]
 	}
 
 	type promise struct {
-		hcrpcclient.FutureGetTransactionResult
+		rpcclient.TransactionFuture
 	}
 	promises := make([]promise, 0, len(tickets))
 
-	log.Debugf("setting up GetTransactionAsync for %v tickets", len(tickets))
+	// The tickets of a snapshot were looked up before, unless their user is
+	// gone since.
+	reused := 0
+	for _, ticket := range tickets {
+		k, ok := known[*ticket]
+		if !ok {
+			continue
+		}
+		if _, ok := userVotingConfig[k.MultiSigAddress]; !ok {
+			continue
+		}
+		reused++
+		if k.PurchaseHeight != 0 {
+			heights[*ticket] = k.PurchaseHeight
+		}
+		_, isAdded := ctx.addedLowFeeTicketsMSA[*ticket]
+		switch {
+		case isAdded:
+			liveTickets[*ticket] = k.MultiSigAddress
+		case k.FeesValid:
+			normalFee++
+			liveTickets[*ticket] = k.MultiSigAddress
+		default:
+			ignoredLowFeeTickets[*ticket] = k.MultiSigAddress
+		}
+	}
+
+	log.Debugf("setting up GetTransactionAsync for %v tickets",
+		len(tickets)-reused)
 	for _, ticket := range tickets {
+		if _, ok := liveTickets[*ticket]; ok {
+			continue
+		}
+		if _, ok := ignoredLowFeeTickets[*ticket]; ok {
+			continue
+		}
 		// lookup ownership of each ticket
-		promises = append(promises, promise{ctx.walletConnection.GetTransactionAsync(ticket)})
+		promises = append(promises, promise{ctx.wallet().GetTransactionAsync(ticket)})
+	}
+
+	// ticketHeight returns the height of the block with the hash.
+	ticketHeight := func(blockHash string) (int32, error) {
+		hash, err := chainhash.NewHashFromStr(blockHash)
+		if err != nil {
+			return 0, err
+		}
+		if height, ok := blockHashToHeightCache[*hash]; ok {
+			return height, nil
+		}
+		gbh, err := ctx.node().GetBlockHeader(hash)
+		if err != nil {
+			return 0, err
+		}
+		blockHashToHeightCache[*hash] = int32(gbh.Height)
+		return int32(gbh.Height), nil
 	}
 
 	counter := 0
 	for _, p := range promises {
 		counter++
-		log.Debugf("Receiving GetTransaction result for ticket %v/%v", counter, len(tickets))
+		log.Debugf("Receiving GetTransaction result for ticket %v/%v", counter, len(promises))
 		gt, err := p.Receive()
 		if err != nil {
 			// All tickets should exist and be able to be looked up
@@ -164,8 +157,7 @@ func walletGetTickets(ctx *appContext, currentHeight int64) (map[chainhash.Hash]
 				continue
 			}
 
-			addr, err := hcutil.DecodeAddress(gt.Details[i].Address)
-			if err != nil {
+			if _, err := hcutil.DecodeAddress(gt.Details[i].Address); err != nil {
 				log.Warnf("invalid address %v", err)
 				continue
 			}
@@ -176,6 +168,12 @@ func walletGetTickets(ctx *appContext, currentHeight int64) (map[chainhash.Hash]
 				continue
 			}
 
+			// look up the height at which this ticket was purchased
+			ticketBlockHeight, heightErr := ticketHeight(gt.BlockHash)
+			if heightErr == nil {
+				heights[*hash] = int64(ticketBlockHeight)
+			}
+
 			// All tickets are present in the GetTickets response, whether they
 			// pay the correct fee or not.  So we need to verify fees and
 			// sort the tickets into their respective maps.
@@ -183,36 +181,20 @@ func walletGetTickets(ctx *appContext, currentHeight int64) (map[chainhash.Hash]
 			if isAdded {
 				liveTickets[*hash] = userVotingConfig[gt.Details[i].Address].MultiSigAddress
 			} else {
-
-				msgTx := MsgTxFromHex(gt.Hex)
-				if msgTx == nil {
-					log.Warnf("MsgTxFromHex failed for %v", gt.Hex)
+				if heightErr != nil {
+					log.Warnf("unable to look up the block of ticket %v: %v",
+						*hash, heightErr)
 					continue
 				}
 
-				// look up the height at which this ticket was purchased
-				var ticketBlockHeight int32
-				ticketBlockHash, err := chainhash.NewHashFromStr(gt.BlockHash)
-				if err != nil {
-					log.Warnf("NewHashFromStr failed for %v: %v", gt.BlockHash, err)
+				msgTx := voting.MsgTxFromHex(gt.Hex)
+				if msgTx == nil {
+					log.Warnf("MsgTxFromHex failed for %v", gt.Hex)
 					continue
 				}
 
-				height, inCache := blockHashToHeightCache[*ticketBlockHash]
-				if inCache {
-					ticketBlockHeight = height
-				} else {
-					gbh, err := ctx.nodeConnection.GetBlockHeader(ticketBlockHash)
-					if err != nil {
-						log.Warnf("GetBlockHeader failed for %v: %v", ticketBlockHash, err)
-						continue
-					}
-
-					blockHashToHeightCache[*ticketBlockHash] = int32(gbh.Height)
-					ticketBlockHeight = int32(gbh.Height)
-				}
-
-				ticketFeesValid, err := evaluateStakePoolTicket(ctx, msgTx, ticketBlockHeight, addr)
+				ticketFeesValid, err := voting.EvaluateStakePoolTicket(msgTx,
+					ticketBlockHeight, ctx.feeAddrs, ctx.poolFees, ctx.params)
 				if ticketFeesValid {
 					normalFee++
 					liveTickets[*hash] = userVotingConfig[gt.Details[i].Address].MultiSigAddress
@@ -226,10 +208,16 @@ func walletGetTickets(ctx *appContext, currentHeight int64) (map[chainhash.Hash]
 		}
 	}
 
+	ctx.Lock()
+	for ticket, height := range heights {
+		ctx.ticketHeights[ticket] = height
+	}
+	ctx.Unlock()
+
 	log.Infof("tickets loaded -- addedLowFee %v ignoredLowFee %v normalFee %v "+
-		"live %v total %v", len(ctx.addedLowFeeTicketsMSA),
+		"live %v total %v reused %v", len(ctx.addedLowFeeTicketsMSA),
 		len(ignoredLowFeeTickets), normalFee, len(liveTickets),
-		len(tickets))
+		len(tickets), reused)
 
 	return ignoredLowFeeTickets, liveTickets, nil
 }
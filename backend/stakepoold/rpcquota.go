@@ -0,0 +1,123 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// rpcQuotaWindow is the period the calls limited by rpcquota are counted
+// over.
+const rpcQuotaWindow = time.Minute
+
+// rpcRetryAfterHeader is the gRPC metadata key telling a client over its
+// quota how many seconds to wait before calling again.
+const rpcRetryAfterHeader = "retry-after"
+
+// rpcClientCalls is the number of calls made by every RPC client since
+// stakepoold started, by client name.  Without rpcauth, clients are named by
+// their address, so it has an entry for every address that called.
+var rpcClientCalls = expvar.NewMap("rpcClientCalls")
+
+// rpcQuotaWindowCount is the number of calls a client made in its current
+// quota window.
+type rpcQuotaWindowCount struct {
+	start time.Time
+	calls int
+}
+
+// rpcQuotaLimiter limits the calls each RPC client, told apart by its name,
+// may make per rpcQuotaWindow by the role of the client.  Clients of roles without a quota
+// aren't limited.  A nil rpcQuotaLimiter limits nobody.  It is safe for
+// concurrent access.
+type rpcQuotaLimiter struct {
+	quotas map[rpcRole]int
+	now    func() time.Time
+
+	mtx     sync.Mutex
+	windows map[string]*rpcQuotaWindowCount
+}
+
+// rpcQuotas is the quota limiter of the gRPC server.  It is nil unless
+// rpcquota is set in the config.
+var rpcQuotas *rpcQuotaLimiter
+
+// newRPCQuotas parses rpcquota options of the form role:calls.  It returns nil
+// when there are none.
+func newRPCQuotas(options []string) (*rpcQuotaLimiter, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+	q := &rpcQuotaLimiter{
+		quotas:  make(map[rpcRole]int, len(options)),
+		now:     time.Now,
+		windows: make(map[string]*rpcQuotaWindowCount),
+	}
+	for _, option := range options {
+		parts := strings.SplitN(option, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("rpcquota must be role:calls")
+		}
+		role := rpcRole(parts[0])
+		if role != rpcRoleFrontend && role != rpcRoleMonitor {
+			return nil, fmt.Errorf("unknown rpcquota role %q, must be "+
+				"%s or %s", role, rpcRoleFrontend, rpcRoleMonitor)
+		}
+		calls, err := strconv.Atoi(parts[1])
+		if err != nil || calls <= 0 {
+			return nil, fmt.Errorf("rpcquota of %s must be a positive "+
+				"number of calls", role)
+		}
+		if _, ok := q.quotas[role]; ok {
+			return nil, fmt.Errorf("rpcquota of %s given more than once",
+				role)
+		}
+		q.quotas[role] = calls
+	}
+	return q, nil
+}
+
+// countRPCCall counts a call of client and checks it against the quota of
+// its role.  When the client is over its quota, it returns a
+// ResourceExhausted error and the metadata telling the client when to retry.
+func (q *rpcQuotaLimiter) countRPCCall(client rpcClient) (metadata.MD, error) {
+	rpcClientCalls.Add(client.name, 1)
+	if q == nil {
+		return nil, nil
+	}
+	quota, ok := q.quotas[client.role]
+	if !ok {
+		return nil, nil
+	}
+
+	now := q.now()
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	w := q.windows[client.name]
+	if w == nil || now.Sub(w.start) >= rpcQuotaWindow {
+		w = &rpcQuotaWindowCount{start: now}
+		q.windows[client.name] = w
+	}
+	if w.calls >= quota {
+		retryAfter := w.start.Add(rpcQuotaWindow).Sub(now)
+		seconds := int64((retryAfter + time.Second - 1) / time.Second)
+		md := metadata.Pairs(rpcRetryAfterHeader,
+			strconv.FormatInt(seconds, 10))
+		return md, status.Errorf(codes.ResourceExhausted, "quota of %d "+
+			"calls per %v exceeded by %s, retry after %ds", quota,
+			rpcQuotaWindow, client.name, seconds)
+	}
+	w.calls++
+	return nil, nil
+}
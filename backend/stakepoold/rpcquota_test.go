@@ -0,0 +1,72 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestRPCQuotas(t *testing.T) {
+	var noQuotas *rpcQuotaLimiter
+	frontend := rpcClient{name: "frontend@10.0.0.1", role: rpcRoleFrontend}
+	if _, err := noQuotas.countRPCCall(frontend); err != nil {
+		t.Fatalf("nil limiter denied a call: %v", err)
+	}
+
+	q, err := newRPCQuotas([]string{"monitor:2"})
+	if err != nil {
+		t.Fatalf("newRPCQuotas: %v", err)
+	}
+	now := time.Unix(1000, 0)
+	q.now = func() time.Time { return now }
+
+	monitor1 := rpcClient{name: "monitor:1111111111111111", role: rpcRoleMonitor}
+	monitor2 := rpcClient{name: "monitor:2222222222222222", role: rpcRoleMonitor}
+	for i := 0; i < 2; i++ {
+		if _, err := q.countRPCCall(monitor1); err != nil {
+			t.Fatalf("call %d denied: %v", i, err)
+		}
+	}
+	now = now.Add(20*time.Second + time.Millisecond)
+	md, err := q.countRPCCall(monitor1)
+	if grpc.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("call over quota: got %v, want ResourceExhausted", err)
+	}
+	if retry := md[rpcRetryAfterHeader]; len(retry) != 1 || retry[0] != "40" {
+		t.Errorf("retry after %v, want 40", retry)
+	}
+
+	// Other clients and roles have quotas of their own.
+	if _, err := q.countRPCCall(monitor2); err != nil {
+		t.Errorf("second monitor denied: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := q.countRPCCall(frontend); err != nil {
+			t.Fatalf("frontend without quota denied: %v", err)
+		}
+	}
+
+	now = now.Add(40 * time.Second)
+	if _, err := q.countRPCCall(monitor1); err != nil {
+		t.Errorf("call in the next window denied: %v", err)
+	}
+
+	invalid := [][]string{
+		{"monitor"},
+		{"monitor:0"},
+		{"monitor:many"},
+		{"admin:10"},
+		{"monitor:10", "monitor:20"},
+	}
+	for _, options := range invalid {
+		if _, err := newRPCQuotas(options); err == nil {
+			t.Errorf("newRPCQuotas(%q) succeeded", options)
+		}
+	}
+}
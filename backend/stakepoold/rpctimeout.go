@@ -0,0 +1,91 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"google.golang.org/grpc"
+)
+
+// rpcTimeoutDefault is the rpctimeout method name that sets the timeout of the
+// methods without one of their own.
+const rpcTimeoutDefault = "default"
+
+// rpcTimeoutSet is the timeouts of the RPC methods set with rpctimeout
+// options, by method name.  Methods missing from it use the timeout of
+// rpcserver.CommandTimeout.
+type rpcTimeoutSet map[string]time.Duration
+
+// rpcTimeouts are the timeouts of the gRPC server.  It is nil unless
+// rpctimeout is set in the config.
+var rpcTimeouts rpcTimeoutSet
+
+// newRPCTimeouts parses rpctimeout options of the form method:duration.  It
+// returns nil when there are none.
+func newRPCTimeouts(options []string) (rpcTimeoutSet, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+	t := make(rpcTimeoutSet, len(options))
+	for _, option := range options {
+		parts := strings.SplitN(option, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("rpctimeout must be method:duration")
+		}
+		d, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rpctimeout duration %q: %v",
+				parts[1], err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("rpctimeout of %s must be positive",
+				parts[0])
+		}
+		if _, ok := t[parts[0]]; ok {
+			return nil, fmt.Errorf("rpctimeout of %s given more than "+
+				"once", parts[0])
+		}
+		t[parts[0]] = d
+	}
+	return t, nil
+}
+
+// timeout returns how long method may take.
+func (t rpcTimeoutSet) timeout(method string) time.Duration {
+	if d, ok := t[method]; ok {
+		return d
+	}
+	// Methods that take long by design keep their own timeout unless it
+	// is set explicitly.
+	d := rpcserver.CommandTimeout(method)
+	if def, ok := t[rpcTimeoutDefault]; ok && d == rpcserver.GRPCCommandTimeout {
+		return def
+	}
+	return d
+}
+
+// unknownMethods returns the methods with a timeout that server doesn't
+// serve, which are most likely misspelled.
+func (t rpcTimeoutSet) unknownMethods(server *grpc.Server) []string {
+	served := make(map[string]bool)
+	for _, info := range server.GetServiceInfo() {
+		for _, m := range info.Methods {
+			served[m.Name] = true
+		}
+	}
+	var unknown []string
+	for method := range t {
+		if method != rpcTimeoutDefault && !served[method] {
+			unknown = append(unknown, method)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+func TestRPCTimeouts(t *testing.T) {
+	var none rpcTimeoutSet
+	if d := none.timeout("Ping"); d != rpcserver.GRPCCommandTimeout {
+		t.Errorf("Ping timeout without rpctimeout is %v", d)
+	}
+	if d := none.timeout("ImportUserData"); d != rpcserver.UserDataTimeout {
+		t.Errorf("ImportUserData timeout without rpctimeout is %v", d)
+	}
+
+	timeouts, err := newRPCTimeouts([]string{"default:250ms",
+		"GetPoolStats:50ms", "ImportUserData:30m"})
+	if err != nil {
+		t.Fatalf("newRPCTimeouts: %v", err)
+	}
+	tests := []struct {
+		method  string
+		timeout time.Duration
+	}{
+		{"GetPoolStats", 50 * time.Millisecond},
+		{"Ping", 250 * time.Millisecond},
+		{"ImportUserData", 30 * time.Minute},
+		{"ExportUserData", rpcserver.UserDataTimeout},
+	}
+	for _, test := range tests {
+		if d := timeouts.timeout(test.method); d != test.timeout {
+			t.Errorf("%s timeout is %v, want %v", test.method, d,
+				test.timeout)
+		}
+	}
+
+	for _, options := range [][]string{
+		{"Ping"},
+		{":1s"},
+		{"Ping:soon"},
+		{"Ping:0s"},
+		{"Ping:1s", "Ping:2s"},
+	} {
+		if _, err := newRPCTimeouts(options); err == nil {
+			t.Errorf("newRPCTimeouts(%q) succeeded", options)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"time"
+
+	"github.com/coolsnady/hcstakepool/systemd"
+)
+
+// sdWatchdogHandler pings the systemd watchdog twice per interval while
+// stakepoold is healthy, so systemd restarts it when it is wedged.  It must be
+// run as a goroutine.
+func (ctx *appContext) sdWatchdogHandler(interval time.Duration) {
+	defer ctx.wg.Done()
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !ctx.healthy(interval / 4) {
+				log.Errorf("health check failed, not pinging the " +
+					"systemd watchdog")
+				continue
+			}
+			if err := systemd.Notify("WATCHDOG=1"); err != nil {
+				log.Warnf("unable to ping the systemd watchdog: %v",
+					err)
+			}
+		case <-ctx.quit:
+			return
+		}
+	}
+}
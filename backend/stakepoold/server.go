@@ -6,34 +6,36 @@
 package main
 
 import (
-	"bytes"
-	"encoding/gob"
-	"encoding/hex"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/coolsnady/hcd/blockchain/stake"
 	"github.com/coolsnady/hcd/chaincfg"
 	"github.com/coolsnady/hcd/chaincfg/chainhash"
-	"github.com/coolsnady/hcd/dcrjson"
-	"github.com/coolsnady/hcd/wire"
 	"github.com/coolsnady/hcrpcclient"
 	"github.com/coolsnady/hcutil"
 	"github.com/coolsnady/hcutil/hdkeychain"
 
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/journal"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/lease"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
 	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/store"
 	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+	"github.com/coolsnady/hcstakepool/proxy"
+	"github.com/coolsnady/hcstakepool/systemd"
+	"github.com/coolsnady/hcstakepool/tracing"
+	"github.com/coolsnady/hcstakepool/version"
 	"github.com/coolsnady/hcwallet/wallet/txrules"
-	"github.com/coolsnady/hcwallet/wallet/udb"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -43,78 +45,140 @@ type appContext struct {
 
 	// locking required
 	addedLowFeeTicketsMSA   map[chainhash.Hash]string            // [ticket]multisigaddr
+	blockTicketChanges      map[int64]*blockTicketChanges        // [height]
 	ignoredLowFeeTicketsMSA map[chainhash.Hash]string            // [ticket]multisigaddr
 	liveTicketsMSA          map[chainhash.Hash]string            // [ticket]multisigaddr
+	ticketHeights           map[chainhash.Hash]int64             // [ticket]purchase height
 	userVotingConfig        map[string]userdata.UserVotingConfig // [multisigaddr]
+	lastBlockSeenHash       *chainhash.Hash
+	lastBlockSeenHeight     int64
 
 	// no locking required
+	blockConnectedChan     chan []byte
 	coldwalletextpub       *hdkeychain.ExtendedKey
 	dataPath               string
+	doubleVotes            *voting.DoubleVotes
+	election               *leaderElection  // nil without leader election
+	journal                *journal.Journal // nil with nojournal
 	feeAddrs               map[string]struct{}
+	forks                  *forkChecker // nil without forkcheckhcd
 	poolFees               float64
 	grpcCommandQueueChan   chan *rpcserver.GRPCCommandQueue
 	newTicketsChan         chan NewTicketsForBlock
-	nodeConnection         *hcrpcclient.Client
+	ntfnPolicy             string
 	params                 *chaincfg.Params
-	lastBlockSeenHash      *chainhash.Hash
-	lastBlockSeenHeight    int64
+	pendingVotes           *voting.PendingVotes
+	spentMissedFeed        *rpcserver.SpentMissedFeed
+	maxVoteAge             int64
 	wg                     sync.WaitGroup // wait group for go routine exits
 	quit                   chan struct{}
+	ready                  chan struct{} // closed once fully started
+	rescanning             int32         // 1 while the wallet is rescanned, accessed atomically
+	reorganizationChan     chan Reorganization
 	spentmissedTicketsChan chan SpentMissedTicketsForBlock
+	stakeDiffs             *voting.StakeDifficultyHistory
+	stats                  *voting.Stats
+	store                  *store.Store
 	userData               *userdata.UserData
+	userStats              *voting.UserStats
+	voteClaims             *voting.VoteClaims
+	voteClaimRetry         time.Duration // 0 disables retrying votes claimed by other instances
+	voteHistory            *voting.VoteHistory
+	voteHistoryFeed        *rpcserver.VoteHistoryFeed
+	voteLatency            *voting.VoteLatency
+	voteLatencyWarn        time.Duration
+	voter                  *voting.Voter
 	votingConfig           *VotingConfig
-	walletConnection       *hcrpcclient.Client
 	winningTicketsChan     chan WinningTicketsForBlock
-	testing                bool // enabled only for testing
+	unlocker               *walletUnlocker // nil unless the wallet is only unlocked to vote
+	testing                bool            // enabled only for testing
+
+	// connMtx protects the connections, which are replaced by
+	// connectionWatchdog when they drop.  Use node and wallet to access
+	// them.  walletVersion is the JSON-RPC API version of walletConnection.
+	connMtx          sync.RWMutex
+	nodeConnection   rpcclient.ChainSource
+	walletConnection rpcclient.WalletSource
+	walletVersion    rpcclient.Semver
+
+	// catchUpMtx serializes catchUp, which both connection watchers
+	// run after reconnecting.
+	catchUpMtx sync.Mutex
 }
 
 type NewTicketsForBlock struct {
 	blockHash   *chainhash.Hash
 	blockHeight int64
 	newTickets  []*chainhash.Hash
+	seq         uint64 // journal sequence number, 0 if not journaled
 }
 
 type SpentMissedTicketsForBlock struct {
 	blockHash   *chainhash.Hash
 	blockHeight int64
 	smTickets   map[*chainhash.Hash]bool
+	seq         uint64 // journal sequence number, 0 if not journaled
 }
 
-// VotingConfig contains global voting defaults.
+// VotingConfig contains global voting defaults.  VoteBits are the vote bits
+// of the users without valid voting preferences for VoteVersion.
 type VotingConfig struct {
 	VoteBits         uint16
 	VoteVersion      uint32
 	VoteBitsExtended string
 }
 
+const (
+	// defaultVoteBitsWallet makes the users without valid voting
+	// preferences vote with the vote bits of hcwallet.
+	defaultVoteBitsWallet = "wallet"
+
+	// defaultVoteBitsAbstain makes them vote the previous block valid and
+	// abstain on every agenda, i.e. with voteBitsAbstain.
+	defaultVoteBitsAbstain = "abstain"
+	voteBitsAbstain        = uint16(1)
+)
+
+// WinningTicketsForBlock are the tickets selected to vote on a block.
+// received is when the notification arrived, or zero for the winning tickets
+// left over from the last run.
 type WinningTicketsForBlock struct {
 	blockHash      *chainhash.Hash
 	blockHeight    int64
 	winningTickets []*chainhash.Hash
+	received       time.Time
+	seq            uint64 // journal sequence number, 0 if not journaled
 }
 
 var (
-	cfg              *config
-	errDuplicateVote = "-32603: already have transaction "
-	errNoTxInfo      = "-5: No information for transaction"
-	errSuccess       = errors.New("success")
+	cfg         *config
+	errNoTxInfo = "-5: No information for transaction"
+	errSuccess  = errors.New("success")
 
-	dataFilenameTemplate = "KIND-DATE-VERSION.gob"
 	// save individual versions of fields in case they're changed in the future
 	// and keep a global version that represents the overall schema version too
-	dataVersionCommon             = "1.1.0"
+	dataVersionCommon             = "1.4.0"
 	dataVersionAddedLowFeeTickets = "1.0.0"
 	dataVersionLiveTickets        = "1.0.0"
+	dataVersionPendingVotes       = "1.0.0"
+	dataVersionTicketSnapshot     = "1.0.0"
+	dataVersionUserVoteStats      = "1.0.0"
 	dataVersionUserVotingConfig   = "1.0.0"
 	saveFilesToKeep               = 10
 	saveFileSchema                = struct {
 		AddedLowFeeTickets string
 		LiveTickets        string
+		PendingVotes       string
+		TicketSnapshot     string
+		UserVoteStats      string
 		UserVotingConfig   string
 		Version            string
 	}{
 		AddedLowFeeTickets: dataVersionAddedLowFeeTickets,
 		LiveTickets:        dataVersionLiveTickets,
+		PendingVotes:       dataVersionPendingVotes,
+		TicketSnapshot:     dataVersionTicketSnapshot,
+		UserVoteStats:      dataVersionUserVoteStats,
 		UserVotingConfig:   dataVersionUserVotingConfig,
 		Version:            dataVersionCommon,
 	}
@@ -122,180 +186,90 @@ var (
 	ticketTypeSpentMissed = "SpentMissed"
 )
 
-// calculateFeeAddresses decodes the string of stake pool payment addresses
-// to search incoming tickets for. The format for the passed string is:
-// "xpub...:end"
-// where xpub... is the extended public key and end is the last
-// address index to scan to, exclusive. Effectively, it returns the derived
-// addresses for this public key for the address indexes [0,end). The branch
-// used for the derivation is always the external branch.
-func calculateFeeAddresses(xpubStr string, params *chaincfg.Params) (map[string]struct{}, error) {
-	end := uint32(10000)
-
-	log.Infof("Please wait, deriving %v stake pool fees addresses "+
-		"for extended public key %s", end, xpubStr)
-
-	// Parse the extended public key and ensure it's the right network.
-	key, err := hdkeychain.NewKeyFromString(xpubStr)
-	if err != nil {
-		return nil, err
-	}
-	if !key.IsForNet(params) {
-		return nil, fmt.Errorf("extended public key is for wrong network")
-	}
+// shutdownRequestChannel is closed by the Windows service handler to stop
+// runMain like CTRL+C does.
+var shutdownRequestChannel = make(chan struct{})
 
-	// Derive from external branch
-	branchKey, err := key.Child(udb.ExternalBranch)
-	if err != nil {
-		return nil, err
-	}
+// winServiceMain is only invoked on Windows.  It detects when stakepoold is
+// running as a service and reacts accordingly.
+var winServiceMain func() (bool, error)
 
-	// Derive the addresses from [0, end) for this extended public key.
-	// deriveChildAddresses takes the start index and the count.
-	addrs, err := deriveChildAddresses(branchKey, 0, end, params)
+func runMain() error {
+	// Load configuration and parse command line.  This function also
+	// initializes logging and configures it accordingly.
+	loadedCfg, _, err := loadConfig()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	cfg = loadedCfg
 
-	addrMap := make(map[string]struct{})
-	for i := range addrs {
-		addrMap[addrs[i].EncodeAddress()] = struct{}{}
-	}
+	defer func() {
+		if logRotator != nil {
+			logRotator.Close()
+		}
+	}()
 
-	return addrMap, nil
-}
+	log.Infof("Version %s", version.Summary())
+	log.Infof("Network: %s", activeNetParams.Params.Name)
+	log.Infof("Home dir: %s", cfg.HomeDir)
 
-func deriveChildAddresses(key *hdkeychain.ExtendedKey, startIndex, count uint32, params *chaincfg.Params) ([]hcutil.Address, error) {
-	addresses := make([]hcutil.Address, 0, count)
-	for i := uint32(0); i < count; {
-		child, err := key.Child(startIndex + i)
-		if err == hdkeychain.ErrInvalidChild {
-			continue
-		}
-		if err != nil {
-			return nil, err
+	// Enable http profiling server if requested.
+	if cfg.Profile != "" {
+		if err = startProfileServer(cfg.Profile); err != nil {
+			log.Errorf("unable to start the profiling server: %v", err)
+			return err
 		}
+	}
 
-		addrType := uint8(0)
-		if key.GetAlgType() == 4 {
-			addrType = 1
-		}
-		addr, err := child.Address(params, addrType)
-		if err != nil {
-			return nil, err
-		}
-		addresses = append(addresses, addr)
-		i++
+	faults = newFaultInjector(cfg.Faults)
+	if faults != nil {
+		log.Warnf("Fault injection enabled, do not use on a production "+
+			"pool: wallet latency %v, dropped notifications %v, gRPC "+
+			"errors %v", cfg.Faults.WalletLatency,
+			cfg.Faults.DropNotifications, cfg.Faults.GRPCErrors)
 	}
-	return addresses, nil
-}
 
-// evaluateStakePoolTicket evaluates a stake pool ticket to see if it's
-// acceptable to the stake pool. The ticket must pay out to the stake
-// pool cold wallet, and must have a sufficient fee.
-func evaluateStakePoolTicket(ctx *appContext, tx *wire.MsgTx, blockHeight int32, poolUser hcutil.Address) (bool, error) {
-	// Check the first commitment output (txOuts[1])
-	// and ensure that the address found there exists
-	// in the list of approved addresses. Also ensure
-	// that the fee exists and is of the amount
-	// requested by the pool.
-	commitmentOut := tx.TxOut[1]
-	commitAddr, err := stake.AddrFromSStxPkScrCommitment(
-		commitmentOut.PkScript, ctx.params)
+	rpcTimeouts, err = newRPCTimeouts(cfg.RPCTimeouts)
 	if err != nil {
-		return false, fmt.Errorf("Failed to parse commit out addr: %s",
-			err.Error())
-	}
-
-	// Extract the fee from the ticket.
-	in := hcutil.Amount(0)
-	for i := range tx.TxOut {
-		if i%2 != 0 {
-			commitAmt, err := stake.AmountFromSStxPkScrCommitment(
-				tx.TxOut[i].PkScript)
-			if err != nil {
-				return false, fmt.Errorf("Failed to parse commit "+
-					"out amt for commit in vout %v: %s", i, err.Error())
-			}
-			in += commitAmt
-		}
-	}
-	out := hcutil.Amount(0)
-	for i := range tx.TxOut {
-		out += hcutil.Amount(tx.TxOut[i].Value)
+		log.Errorf("Invalid rpctimeout: %v", err)
+		return err
 	}
-	fees := in - out
-
-	_, exists := ctx.feeAddrs[commitAddr.EncodeAddress()]
-	if exists {
-		commitAmt, err := stake.AmountFromSStxPkScrCommitment(
-			commitmentOut.PkScript)
-		if err != nil {
-			return false, fmt.Errorf("failed to parse commit "+
-				"out amt: %s", err.Error())
-		}
 
-		// Calculate the fee required based on the current
-		// height and the required amount from the pool.
-		feeNeeded := txrules.StakePoolTicketFee(hcutil.Amount(
-			tx.TxOut[0].Value), fees, blockHeight, ctx.poolFees,
-			ctx.params)
-		if commitAmt < feeNeeded {
-			log.Warnf("User %s submitted ticket %v which "+
-				"has less fees than are required to use this "+
-				"stake pool and is being skipped (required: %v"+
-				", found %v)", commitAddr.EncodeAddress(),
-				tx.TxHash(), feeNeeded, commitAmt)
-
-			// Reject the entire transaction if it didn't
-			// pay the pool server fees.
-			return false, nil
-		}
-	} else {
-		log.Warnf("Unknown pool commitment address %s for ticket %v",
-			commitAddr.EncodeAddress(), tx.TxHash())
-		return false, nil
+	rpcAuth, err = newRPCAuthorizer(cfg.RPCAuth)
+	if err != nil {
+		log.Errorf("Invalid rpcauth: %v", err)
+		return err
+	}
+	if rpcAuth != nil {
+		log.Infof("RPC clients must authenticate with one of %d tokens",
+			len(rpcAuth.tokens))
 	}
 
-	log.Debugf("Accepted valid stake pool ticket %v committing %v in fees",
-		tx.TxHash(), tx.TxOut[0].Value)
-
-	return true, nil
-}
-
-// MsgTxFromHex returns a wire.MsgTx struct built from the transaction hex string
-func MsgTxFromHex(txhex string) *wire.MsgTx {
-	txBytes, err := hex.DecodeString(txhex)
+	rpcQuotas, err = newRPCQuotas(cfg.RPCQuotas)
 	if err != nil {
-		log.Warnf("DecodeString failed for %v: %v", txhex, err)
-		return nil
-	}
-	msgTx := wire.NewMsgTx()
-	if err = msgTx.Deserialize(bytes.NewReader(txBytes)); err != nil {
-		log.Warnf("Deserialize failed for %v: %v", txBytes, err)
-		return nil
+		log.Errorf("Invalid rpcquota: %v", err)
+		return err
 	}
-	return msgTx
-}
 
-func runMain() error {
-	// Load configuration and parse command line.  This function also
-	// initializes logging and configures it accordingly.
-	loadedCfg, _, err := loadConfig()
+	forkNodes, err := forkCheckConfigs(cfg)
 	if err != nil {
+		log.Errorf("Invalid forkcheckhcd: %v", err)
 		return err
 	}
-	cfg = loadedCfg
 
-	defer func() {
-		if logRotator != nil {
-			logRotator.Close()
-		}
-	}()
+	walletPassphrase, err := readWalletPassphrase(cfg.WalletPassFile,
+		cfg.WalletPassPrompt)
+	if err != nil {
+		log.Errorf("Unable to read the voting wallet passphrase: %v", err)
+		return err
+	}
 
-	log.Infof("Version %s (Go version %s)", version(), runtime.Version())
-	log.Infof("Network: %s", activeNetParams.Params.Name)
-	log.Infof("Home dir: %s", cfg.HomeDir)
+	tracer = tracing.NewTracer("stakepoold", cfg.OTLPEndpoint,
+		proxy.HTTPTransport(cfg.Proxy, cfg.ProxyUser, cfg.ProxyPass))
+	if tracer != nil {
+		log.Infof("Exporting traces to %s", cfg.OTLPEndpoint)
+		defer tracer.Shutdown()
+	}
 
 	// Create the data directory in case it does not exist.
 	err = os.MkdirAll(cfg.DataDir, 0700)
@@ -304,17 +278,18 @@ func runMain() error {
 		return err
 	}
 
-	feeAddrs, err := calculateFeeAddresses(cfg.ColdWalletExtPub,
+	feeAddrs, err := voting.CalculateFeeAddresses(cfg.ColdWalletExtPub,
 		activeNetParams.Params)
 	if err != nil {
 		log.Errorf("Error calculating fee payment addresses: %v", err)
 		return err
 	}
+	// The key was validated by CalculateFeeAddresses.
+	coldWalletKey, _ := hdkeychain.NewKeyFromString(cfg.ColdWalletExtPub)
 
 	hcrpcclient.UseLogger(clientLog)
 
-	var walletVer semver
-	walletConn, walletVer, err := connectWalletRPC(cfg)
+	walletConn, walletVer, err := connectWallet(cfg)
 	if err != nil || walletConn == nil {
 		log.Infof("Connection to hcwallet failed: %v", err)
 		return err
@@ -332,6 +307,21 @@ func runMain() error {
 		VoteBitsExtended: walletInfoRes.VoteBitsExtended,
 		VoteVersion:      walletInfoRes.VoteVersion,
 	}
+	switch cfg.DefaultVoteBits {
+	case defaultVoteBitsWallet:
+	case defaultVoteBitsAbstain:
+		votingConfig.VoteBits = voteBitsAbstain
+	default:
+		voteBits, _ := strconv.ParseUint(cfg.DefaultVoteBits, 10, 16)
+		votingConfig.VoteBits = uint16(voteBits)
+	}
+	if !voting.ValidVoteBits(activeNetParams.Params, votingConfig.VoteVersion,
+		votingConfig.VoteBits) {
+		err := fmt.Errorf("defaultvotebits %v are invalid for vote version %v",
+			votingConfig.VoteBits, votingConfig.VoteVersion)
+		log.Error(err)
+		return err
+	}
 	log.Infof("default voting config: VoteVersion %v VoteBits %v", votingConfig.VoteVersion,
 		votingConfig.VoteBits)
 
@@ -356,26 +346,88 @@ func runMain() error {
 		return errors.New("invalid pool fee ")
 	}
 
+	// The name of this instance among redundant ones.
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Errorf("unable to get the hostname: %v", err)
+		return err
+	}
+	holder := fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	sharedClaims := &sharedVoteClaims{
+		userData: userData,
+		holder:   holder,
+	}
+
 	ctx := &appContext{
-		addedLowFeeTicketsMSA: addedLowFeeTicketsMSA,
-		dataPath:              cfg.DataDir,
-		feeAddrs:              feeAddrs,
-		poolFees:              cfg.PoolFees,
-		grpcCommandQueueChan:  make(chan *rpcserver.GRPCCommandQueue),
-		newTicketsChan:        make(chan NewTicketsForBlock),
-		params:                activeNetParams.Params,
-		quit:                  make(chan struct{}),
-		spentmissedTicketsChan: make(chan SpentMissedTicketsForBlock),
+		addedLowFeeTicketsMSA:  addedLowFeeTicketsMSA,
+		blockConnectedChan:     make(chan []byte, cfg.NtfnBuffer),
+		blockTicketChanges:     make(map[int64]*blockTicketChanges),
+		coldwalletextpub:       coldWalletKey,
+		dataPath:               cfg.DataDir,
+		doubleVotes:            voting.NewDoubleVotes(doubleVotesSize),
+		feeAddrs:               feeAddrs,
+		poolFees:               cfg.PoolFees,
+		grpcCommandQueueChan:   make(chan *rpcserver.GRPCCommandQueue),
+		maxVoteAge:             cfg.MaxVoteAge,
+		newTicketsChan:         make(chan NewTicketsForBlock, cfg.NtfnBuffer),
+		ntfnPolicy:             cfg.NtfnPolicy,
+		params:                 activeNetParams.Params,
+		pendingVotes:           voting.NewPendingVotes(),
+		quit:                   make(chan struct{}),
+		ready:                  make(chan struct{}),
+		reorganizationChan:     make(chan Reorganization, cfg.NtfnBuffer),
+		spentMissedFeed:        rpcserver.NewSpentMissedFeed(),
+		spentmissedTicketsChan: make(chan SpentMissedTicketsForBlock, cfg.NtfnBuffer),
+		stakeDiffs:             voting.NewStakeDifficultyHistory(activeNetParams.StakeDiffWindowSize, stakeDiffHistorySize),
+		stats:                  voting.NewStats(activeNetParams.StakeDiffWindowSize),
+		store:                  store.New(cfg.DataDir, saveFilesToKeep),
+		ticketHeights:          make(map[chainhash.Hash]int64),
 		userData:               userData,
+		userStats:              voting.NewUserStats(),
 		userVotingConfig:       userVotingConfig,
+		voteClaims:             voting.NewSharedVoteClaims(sharedClaims),
+		voteClaimRetry:         userdata.VoteClaimTTL,
+		voteHistory:            voting.NewVoteHistory(voteHistorySize),
+		voteHistoryFeed:        rpcserver.NewVoteHistoryFeed(),
+		voteLatency:            voting.NewVoteLatency(voteLatencyWindow),
+		voteLatencyWarn:        cfg.VoteLatencyWarn,
 		votingConfig:           &votingConfig,
 		walletConnection:       walletConn,
-		winningTicketsChan:     make(chan WinningTicketsForBlock),
+		walletVersion:          walletVer,
+		winningTicketsChan:     make(chan WinningTicketsForBlock, cfg.NtfnBuffer),
 		testing:                false,
 	}
 
+	ctx.voter = voting.NewVoter(ctx.wallet, ctx.node, cfg.VoteWorkers)
+
+	if len(forkNodes) != 0 {
+		ctx.forks = newForkChecker(forkNodes, cfg.ForkTolerance,
+			cfg.ForkPause)
+	}
+
+	if walletPassphrase != "" {
+		ctx.unlocker = newWalletUnlocker(ctx.wallet, walletPassphrase,
+			cfg.WalletRelock)
+		if err = ctx.unlocker.check(); err != nil {
+			log.Errorf("Unable to unlock the wallet with the voting wallet "+
+				"passphrase: %v", err)
+			return err
+		}
+		log.Infof("The wallet is locked and only unlocked to vote, until "+
+			"%v after the last vote", cfg.WalletRelock)
+	}
+
+	ctx.publishExpvars()
+
+	if cfg.HealthListen != "" {
+		if err = ctx.startHealthServer(cfg.HealthListen); err != nil {
+			log.Errorf("unable to start the health endpoints: %v", err)
+			return err
+		}
+	}
+
 	// Daemon client connection
-	nodeConn, nodeVer, err := connectNodeRPC(ctx, cfg)
+	nodeConn, nodeVer, err := connectNode(cfg, ctx)
 	if err != nil || nodeConn == nil {
 		log.Infof("Connection to hcd failed: %v", err)
 		return err
@@ -429,82 +481,139 @@ func runMain() error {
 		log.Warn("0 active users")
 	}
 
-	// refresh the ticket list and make sure a block didn't come in
-	// while we were getting it
-	for {
-		curHash, curHeight, err := nodeConn.GetBestBlock()
-		if err != nil {
-			log.Errorf("unable to get bestblock from hcd: %v", err)
-			return err
+	// load winning tickets that were not voted before the last shutdown
+	err = loadData(ctx, "PendingVotes")
+	if err != nil {
+		log.Warnf("unable to load pending votes from disk cache: %v", err)
+	}
+
+	err = loadData(ctx, "UserVoteStats")
+	if err != nil {
+		log.Warnf("unable to load user vote stats from disk cache: %v", err)
+	}
+
+	// refresh the ticket list
+	var tipHash *chainhash.Hash
+	var tipHeight int64
+	ctx.ignoredLowFeeTicketsMSA, ctx.liveTicketsMSA, tipHash, tipHeight, err =
+		ctx.fetchTickets(ctx.loadTicketSnapshot())
+	if err != nil {
+		log.Errorf("unable to get tickets: %v", err)
+		return err
+	}
+	ctx.setLastBlockSeen(tipHash, tipHeight)
+
+	// don't trust the tickets of the wallet and snapshot after downtime
+	if !cfg.NoStartReconcile {
+		if _, err := ctx.reconcileStartupTickets(); err != nil {
+			log.Warnf("unable to reconcile the tickets with hcd: %v", err)
 		}
-		log.Infof("current block height %v hash %v", curHeight, curHash)
+	}
 
-		ctx.ignoredLowFeeTicketsMSA, ctx.liveTicketsMSA, err = walletGetTickets(ctx, curHeight)
-		if err != nil {
-			log.Errorf("unable to get tickets: %v", err)
-			return err
+	if cfg.LeaseFile != "" {
+		ctx.election = newLeaderElection(
+			lease.NewFileLease(cfg.LeaseFile, holder), cfg.LeaseTTL)
+		if elected, _ := ctx.election.campaign(); elected {
+			log.Infof("leader election: this instance is the leader")
+		} else {
+			log.Infof("leader election: standing by until the lease "+
+				"in %v is free", cfg.LeaseFile)
 		}
+	}
+
+	// vote or flag as missed the winning tickets left over from the last run
+	ctx.processPendingVotes(tipHeight)
 
-		afterHash, afterHeight, err := nodeConn.GetBestBlock()
+	// process the notifications that were cut off by the last shutdown
+	if !cfg.NoJournal {
+		var entries []journal.Entry
+		ctx.journal, entries, err = journal.Open(
+			filepath.Join(cfg.DataDir, journalFilename))
 		if err != nil {
-			log.Errorf("unable to get bestblock from hcd: %v", err)
+			log.Errorf("unable to open the notification journal: %v", err)
 			return err
 		}
-
-		// if a block didn't come in while we were processing tickets
-		// then we're fine
-		if curHash.IsEqual(afterHash) && curHeight == afterHeight {
-			break
-		}
-		log.Infof("block %v hash %v came in during GetTickets, refreshing...",
-			afterHeight, afterHash)
+		defer ctx.journal.Close()
+		ctx.replayJournal(entries, tipHeight)
 	}
 
-	if err = nodeConn.NotifyBlocks(); err != nil {
-		fmt.Printf("Failed to register daemon RPC client for "+
-			"block notifications: %s\n", err.Error())
-		return err
-	}
-	if err = nodeConn.NotifyWinningTickets(); err != nil {
-		fmt.Printf("Failed to register daemon RPC client for "+
-			"winning tickets notifications: %s\n", err.Error())
-		return err
-	}
-	if err = nodeConn.NotifyNewTickets(); err != nil {
-		fmt.Printf("Failed to register daemon RPC client for "+
-			"new tickets notifications: %s\n", err.Error())
-		return err
-	}
-	if err = nodeConn.NotifySpentAndMissedTickets(); err != nil {
+	if err = nodeConn.NotifyChain(); err != nil {
 		fmt.Printf("Failed to register daemon RPC client for "+
-			"spent/missed tickets notifications: %s\n", err.Error())
+			"%s\n", err.Error())
 		return err
 	}
 	log.Info("subscribed to notifications from hcd")
 
 	if !cfg.NoRPCListen {
-		startGRPCServers(ctx.grpcCommandQueueChan)
+		_, err = startGRPCServers(ctx.grpcCommandQueueChan, ctx, ctx, ctx,
+			ctx.spentMissedFeed, ctx, ctx, ctx, ctx.voteHistoryFeed, ctx,
+			ctx.quit)
+		if err != nil {
+			log.Errorf("unable to start the gRPC server: %v", err)
+			return err
+		}
+	}
+
+	sdWatchdog, err := systemd.WatchdogInterval()
+	if err != nil {
+		log.Warnf("systemd watchdog disabled: %v", err)
 	}
 
 	// Only accept a single CTRL+C
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
-	// Start waiting for the interrupt signal
+	// Start waiting for the interrupt signal or a shutdown request from the
+	// Windows service control manager
 	go func() {
-		<-c
+		select {
+		case <-c:
+			log.Info("CTRL+C hit.  Closing goroutines.")
+		case <-shutdownRequestChannel:
+			log.Info("Shutdown requested.  Closing goroutines.")
+		}
 		signal.Stop(c)
 		// Close the channel so multiple goroutines can get the message
-		log.Info("CTRL+C hit.  Closing goroutines.")
+		systemd.Notify("STOPPING=1")
 		saveData(ctx)
 		close(ctx.quit)
 	}()
 
-	ctx.wg.Add(4)
+	ctx.wg.Add(8)
+	go ctx.blockConnectedHandler()
+	go ctx.connectionWatchdog(cfg)
 	go ctx.grpcCommandQueueHandler()
 	go ctx.newTicketHandler()
+	go ctx.reorganizationHandler()
 	go ctx.spentmissedTicketHandler()
+	go ctx.stakeDifficultyBackfill()
 	go ctx.winningTicketHandler()
+	if cfg.TicketReconcile > 0 {
+		ctx.wg.Add(1)
+		go ctx.ticketReconcileHandler(cfg.TicketReconcile)
+	}
+	if ctx.election != nil {
+		ctx.wg.Add(1)
+		go ctx.leaderElectionHandler()
+	}
+	if ctx.forks != nil {
+		ctx.wg.Add(1)
+		go ctx.forkCheckHandler(cfg.HcdHost, cfg.ForkCheck)
+	}
+	if cfg.SnapshotInterval > 0 {
+		ctx.wg.Add(1)
+		go ctx.snapshotHandler(cfg.SnapshotInterval)
+	}
+	if sdWatchdog > 0 {
+		ctx.wg.Add(1)
+		go ctx.sdWatchdogHandler(sdWatchdog)
+	}
+
+	// hcd, hcwallet and the gRPC server are up.
+	close(ctx.ready)
+	if err := systemd.Notify("READY=1"); err != nil {
+		log.Warnf("unable to notify systemd: %v", err)
+	}
 
 	if cfg.NoRPCListen {
 		// Start reloading when a ticker fires
@@ -526,10 +635,66 @@ func runMain() error {
 	// Wait for CTRL+C to signal goroutines to terminate via quit channel.
 	ctx.wg.Wait()
 
+	// Don't leave the wallet unlocked until the relock timer would fire.
+	if ctx.unlocker != nil {
+		ctx.unlocker.lock()
+	}
+
 	return nil
 }
 
+// fetchTickets fetches the ignored low fee and live tickets from the wallet.
+// It makes sure a block didn't come in while they were fetched and returns
+// the block they are current at.  The tickets in known, if any, aren't looked
+// up again.
+func (ctx *appContext) fetchTickets(known map[chainhash.Hash]snapshotTicket) (map[chainhash.Hash]string,
+	map[chainhash.Hash]string, *chainhash.Hash, int64, error) {
+	for {
+		curHash, curHeight, err := ctx.node().GetBestBlock()
+		if err != nil {
+			return nil, nil, nil, 0, fmt.Errorf("unable to get "+
+				"bestblock from hcd: %v", err)
+		}
+		log.Infof("current block height %v hash %v", curHeight, curHash)
+
+		ignoredLowFeeTicketsMSA, liveTicketsMSA, err :=
+			walletGetTickets(ctx, curHeight, known)
+		if err != nil {
+			return nil, nil, nil, 0, err
+		}
+
+		afterHash, afterHeight, err := ctx.node().GetBestBlock()
+		if err != nil {
+			return nil, nil, nil, 0, fmt.Errorf("unable to get "+
+				"bestblock from hcd: %v", err)
+		}
+
+		// if a block didn't come in while we were processing tickets
+		// then we're fine
+		if curHash.IsEqual(afterHash) && curHeight == afterHeight {
+			return ignoredLowFeeTicketsMSA, liveTicketsMSA, curHash,
+				curHeight, nil
+		}
+		log.Infof("block %v hash %v came in during GetTickets, refreshing...",
+			afterHeight, afterHash)
+	}
+}
+
 func main() {
+	// Call serviceMain on Windows to handle running as a service.  When
+	// the return isService flag is true, exit now since we ran as a
+	// service.  Otherwise, just fall through to normal operation.
+	if runtime.GOOS == "windows" {
+		isService, err := winServiceMain()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if isService {
+			os.Exit(0)
+		}
+	}
+
 	if err := runMain(); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
@@ -554,45 +719,14 @@ func getDataNames() map[string]string {
 
 // pruneData prunes any extra save files.
 func pruneData(ctx *appContext) error {
-	saveFiles := getDataNames()
-
 	if !fileExists(ctx.dataPath) {
 		return fmt.Errorf("datapath %v doesn't exist", ctx.dataPath)
 	}
 
-	for dataKind, dataVersion := range saveFiles {
-		var filesToPrune []string
-
-		files, err := ioutil.ReadDir(ctx.dataPath)
-		if err != nil {
+	for dataKind, dataVersion := range getDataNames() {
+		if err := ctx.store.Prune(dataKind, dataVersion); err != nil {
 			return err
 		}
-
-		for i, file := range files {
-			log.Debugf("entry %d => %s", i, file.Name())
-			if strings.HasPrefix(file.Name(), strings.ToLower(dataKind)) &&
-				strings.Contains(file.Name(), dataVersion) &&
-				strings.HasSuffix(file.Name(), ".gob") {
-				filesToPrune = append(filesToPrune, filepath.Join(ctx.dataPath, file.Name()))
-			}
-		}
-
-		if len(filesToPrune) <= saveFilesToKeep {
-			continue
-		}
-
-		filesToPruneCount := len(filesToPrune) - saveFilesToKeep
-		for i, filepath := range filesToPrune {
-			err = os.Remove(filepath)
-			if err != nil {
-				log.Warnf("unable to prune %v: %v", filepath, err)
-			} else {
-				log.Infof("pruned old data file %v", filepath)
-			}
-			if filesToPruneCount == i+1 {
-				break
-			}
-		}
 	}
 
 	return nil
@@ -601,166 +735,127 @@ func pruneData(ctx *appContext) error {
 // loadData looks for and attempts to load into memory the most recent save
 // file for a passed data kind.
 func loadData(ctx *appContext, dataKind string) error {
-	dataVersion := ""
-	found := false
-	saveFiles := getDataNames()
-
-	for filenameprefix, dataversion := range saveFiles {
-		if dataKind == filenameprefix {
-			dataVersion = dataversion
-			found = true
-		}
-	}
-
+	dataVersion, found := getDataNames()[dataKind]
 	if !found {
 		return errors.New("unhandled data kind of " + dataKind)
 	}
 
-	if fileExists(ctx.dataPath) {
-		files, err := ioutil.ReadDir(ctx.dataPath)
-		if err != nil {
-			return err
-		}
-
-		lastseen := ""
-
-		for i, file := range files {
-			log.Debugf("entry %d => %s", i, file.Name())
-			if strings.HasPrefix(file.Name(), strings.ToLower(dataKind)) &&
-				strings.Contains(file.Name(), dataVersion) &&
-				strings.HasSuffix(file.Name(), ".gob") {
-				lastseen = file.Name()
-			}
-		}
-
-		// we could warn/error here but it's not really a problem.
-		// maybe the admin deleted the gob files to reset the cache
-		// or the cache hasn't been initialized yet.
-		if lastseen == "" {
-			return nil
-		}
-
-		fullPath := filepath.Join(ctx.dataPath, lastseen)
-
-		r, err := os.Open(fullPath)
-		if err != nil {
-			return err
+	// shouldn't happen -- data dir is created on startup
+	if !fileExists(ctx.dataPath) {
+		return errors.New("loadData - path " + ctx.dataPath + " does not exist")
+	}
+
+	var path string
+	var err error
+	switch dataKind {
+	case "AddedLowFeeTickets":
+		path, err = ctx.store.Load(dataKind, dataVersion,
+			&ctx.addedLowFeeTicketsMSA)
+	case "LiveTickets":
+		path, err = ctx.store.Load(dataKind, dataVersion, &ctx.liveTicketsMSA)
+	case "PendingVotes":
+		var votes map[chainhash.Hash]voting.PendingVote
+		path, err = ctx.store.Load(dataKind, dataVersion, &votes)
+		if err == nil && votes != nil {
+			ctx.pendingVotes.Restore(votes)
 		}
-		dec := gob.NewDecoder(r)
-		switch dataKind {
-		case "AddedLowFeeTickets":
-			err = dec.Decode(&ctx.addedLowFeeTicketsMSA)
-			if err != nil {
-				return err
-			}
-		case "LiveTickets":
-			err = dec.Decode(&ctx.liveTicketsMSA)
-			if err != nil {
-				return err
-			}
-		case "UserVotingConfig":
-			err = dec.Decode(&ctx.userVotingConfig)
-			if err != nil {
-				return err
-			}
+	case "UserVoteStats":
+		var users map[string]voting.UserVoteStats
+		path, err = ctx.store.Load(dataKind, dataVersion, &users)
+		if err == nil && users != nil {
+			ctx.userStats.Restore(users)
 		}
-		log.Infof("Loaded %s from %s", dataKind, fullPath)
-		return nil
+	case "UserVotingConfig":
+		path, err = ctx.store.Load(dataKind, dataVersion,
+			&ctx.userVotingConfig)
+	}
+	if err != nil {
+		return err
 	}
 
-	// shouldn't get here -- data dir is created on startup
-	return errors.New("loadData - path " + ctx.dataPath + " does not exist")
+	// we could warn/error if there was no file but it's not really a
+	// problem.  maybe the admin deleted the gob files to reset the cache
+	// or the cache hasn't been initialized yet.
+	if path != "" {
+		log.Infof("Loaded %s from %s", dataKind, path)
+	}
+	return nil
 }
 
 // saveData saves some appContext fields to a file so they can be loaded back
-// into memory at next run.
+// into memory at next run.  It is called periodically and on shutdown.
 func saveData(ctx *appContext) {
-	ctx.Lock()
-	defer ctx.Unlock()
-
-	saveFiles := getDataNames()
-
-	for filenameprefix, dataversion := range saveFiles {
-		t := time.Now()
-		destFilename := strings.Replace(dataFilenameTemplate, "KIND", filenameprefix, -1)
-		destFilename = strings.Replace(destFilename, "DATE", t.Format("2006_01_02_15_04_05"), -1)
-		destFilename = strings.Replace(destFilename, "VERSION", dataversion, -1)
-		destPath := strings.ToLower(filepath.Join(ctx.dataPath, destFilename))
+	ctx.RLock()
+	defer ctx.RUnlock()
 
-		// Pre-validate whether we'll be saving or not.
-		switch filenameprefix {
+	for dataKind, dataVersion := range getDataNames() {
+		var data interface{}
+		switch dataKind {
 		case "AddedLowFeeTickets":
 			if len(ctx.addedLowFeeTicketsMSA) == 0 {
 				log.Warn("saveData: addedLowFeeTicketsMSA is empty; skipping save")
 				continue
 			}
+			data = &ctx.addedLowFeeTicketsMSA
 		case "LiveTickets":
 			if len(ctx.liveTicketsMSA) == 0 {
 				log.Warn("saveData: liveTicketsMSA is empty; skipping save")
 				continue
 			}
+			data = &ctx.liveTicketsMSA
+		case "PendingVotes":
+			// Always save so votes from an older file aren't retried.
+			votes := ctx.pendingVotes.Snapshot()
+			data = &votes
+		case "TicketSnapshot":
+			snapshot := ctx.takeTicketSnapshot()
+			if snapshot == nil || len(snapshot.Tickets) == 0 {
+				log.Warn("saveData: TicketSnapshot is empty; skipping save")
+				continue
+			}
+			data = snapshot
+		case "UserVoteStats":
+			users := ctx.userStats.Snapshot()
+			if len(users) == 0 {
+				log.Warn("saveData: UserVoteStats is empty; skipping save")
+				continue
+			}
+			data = &users
 		case "UserVotingConfig":
 			if len(ctx.userVotingConfig) == 0 {
 				log.Warn("saveData: UserVotingConfig is empty; skipping save")
 				continue
 			}
+			data = &ctx.userVotingConfig
 		default:
-			log.Warn("saveData: passed unhandled data name " + filenameprefix)
+			log.Warn("saveData: passed unhandled data name " + dataKind)
 			continue
 		}
 
-		w, err := os.Create(destPath)
-		defer w.Close()
+		destPath, err := ctx.store.Save(dataKind, dataVersion, data)
 		if err != nil {
-			log.Errorf("Error opening file %s: %v", ctx.dataPath, err)
+			log.Errorf("saveData: %v", err)
 			continue
 		}
 
-		switch filenameprefix {
-		case "AddedLowFeeTickets":
-			enc := gob.NewEncoder(w)
-			if err := enc.Encode(&ctx.addedLowFeeTicketsMSA); err != nil {
-				log.Errorf("Failed to encode file %s: %v", ctx.dataPath, err)
-				continue
-			}
-		case "LiveTickets":
-			enc := gob.NewEncoder(w)
-			if err := enc.Encode(&ctx.liveTicketsMSA); err != nil {
-				log.Errorf("Failed to encode file %s: %v", ctx.dataPath, err)
-				continue
-			}
-		case "UserVotingConfig":
-			enc := gob.NewEncoder(w)
-			if err := enc.Encode(&ctx.userVotingConfig); err != nil {
-				log.Errorf("Failed to encode file %s: %v", ctx.dataPath, err)
-				continue
-			}
-		}
-
 		log.Infof("saveData: successfully saved %v data to %s",
-			filenameprefix, destPath)
+			dataKind, destPath)
 	}
 }
 
-// ticketMetadata contains all the bits and pieces required to vote new tickets,
-// to look up new/missed/spent tickets, and to print statistics after usage.
+// ticketMetadata contains all the bits and pieces required to look up
+// new/missed/spent tickets, and to print statistics after usage.
 type ticketMetadata struct {
-	blockHash        *chainhash.Hash
-	blockHeight      int64
-	msa              string                    // multisig
-	ticket           *chainhash.Hash           // ticket
-	spent            bool                      // spent (true) or missed (false)
-	config           userdata.UserVotingConfig // voting config
-	duration         time.Duration             // overall vote duration
-	getDuration      time.Duration             // time to gettransaction
-	hex              string                    // hex encoded tx data
-	txid             *chainhash.Hash           // transaction id
-	ticketType       string                    // new or spentmissed
-	signDuration     time.Duration             // time to generatevote
-	sendDuration     time.Duration             // time to sendrawtransaction
-	err              error                     // log errors along the way
-	voteBits         uint16                    // voteBits
-	voteBitsExtended string                    // voteBits extended
+	blockHash   *chainhash.Hash
+	blockHeight int64
+	msa         string          // multisig
+	ticket      *chainhash.Hash // ticket
+	spent       bool            // spent (true) or missed (false)
+	duration    time.Duration   // overall lookup duration
+	getDuration time.Duration   // time to gettransaction
+	hex         string          // hex encoded tx data
+	ticketType  string          // new or spentmissed
+	err         error           // log errors along the way
 }
 
 // getticket pulls the transaction information for a ticket from hcwallet. This is a go routine!
@@ -775,7 +870,7 @@ func (ctx *appContext) getticket(wg *sync.WaitGroup, nt *ticketMetadata) {
 	// Ask wallet to look up vote transaction to see if it belongs to us
 	log.Debugf("calling GetTransaction for %v ticket %v",
 		strings.ToLower(nt.ticketType), nt.ticket)
-	res, err := ctx.walletConnection.GetTransaction(nt.ticket)
+	res, err := ctx.wallet().GetTransaction(nt.ticket)
 	nt.getDuration = time.Since(start)
 	if err != nil {
 		// suppress "No information for transaction ..." errors
@@ -864,6 +959,23 @@ func (ctx *appContext) updateUserData(newUserVotingConfig map[string]userdata.Us
 	log.Debug("updateUserData ctx.Unlock")
 }
 
+// mergeUserData adds or replaces the voting config of the users in
+// userVotingConfig and returns the number of users voted for.
+func (ctx *appContext) mergeUserData(userVotingConfig map[string]userdata.UserVotingConfig) int {
+	ctx.Lock()
+	defer ctx.Unlock()
+	merged := make(map[string]userdata.UserVotingConfig,
+		len(ctx.userVotingConfig)+len(userVotingConfig))
+	for msa, config := range ctx.userVotingConfig {
+		merged[msa] = config
+	}
+	for msa, config := range userVotingConfig {
+		merged[msa] = config
+	}
+	ctx.userVotingConfig = merged
+	return len(merged)
+}
+
 func (ctx *appContext) updateUserDataFromMySQL() error {
 	start := time.Now()
 	newUserVotingConfig, err := ctx.userData.MySQLFetchUserVotingConfig()
@@ -876,46 +988,23 @@ func (ctx *appContext) updateUserDataFromMySQL() error {
 	return nil
 }
 
-// vote Generates a vote and send it off to the network.  This is a go routine!
-func (ctx *appContext) vote(wg *sync.WaitGroup, blockHash *chainhash.Hash, blockHeight int64, w *ticketMetadata) {
-	start := time.Now()
-
-	defer func() {
-		w.duration = time.Since(start)
-		wg.Done()
-	}()
-
-	// Ask wallet to generate vote result.
-	var res *dcrjson.GenerateVoteResult
-	res, w.err = ctx.walletConnection.GenerateVote(blockHash, blockHeight,
-		w.ticket, w.config.VoteBits, ctx.votingConfig.VoteBitsExtended)
-	if w.err != nil || res.Hex == "" {
-		return
-	}
-	w.signDuration = time.Since(start)
-
-	// Create raw transaction.
-	var buf []byte
-	buf, w.err = hex.DecodeString(res.Hex)
-	if w.err != nil {
-		return
-	}
-	newTx := wire.NewMsgTx()
-	w.err = newTx.FromBytes(buf)
-	if w.err != nil {
-		return
+// voteAll sends the votes of winners with the voter.  When the wallet is only
+// unlocked to vote, it is unlocked first and the votes fail if it can't be.
+func (ctx *appContext) voteAll(wt WinningTicketsForBlock, winners []*voting.Vote) {
+	if ctx.unlocker != nil {
+		if err := ctx.unlocker.unlock(); err != nil {
+			log.Errorf("voteAll: unable to unlock the wallet to vote for "+
+				"block %v: %v", wt.blockHash, err)
+			for _, w := range winners {
+				w.Err = err
+			}
+			return
+		}
+		defer ctx.unlocker.release()
 	}
 
-	// Ask node to transmit raw transaction.
-	startSend := time.Now()
-	tx, err := ctx.nodeConnection.SendRawTransaction(newTx, false)
-	if err != nil {
-		log.Infof("vote err %v", err)
-		w.err = err
-	} else {
-		w.txid = tx
-	}
-	w.sendDuration = time.Since(startSend)
+	ctx.voter.VoteAll(wt.blockHash, wt.blockHeight, wt.received,
+		ctx.votingConfig.VoteBitsExtended, winners)
 }
 
 func (ctx *appContext) processNewTickets(nt NewTicketsForBlock) {
@@ -954,19 +1043,19 @@ func (ctx *appContext) processNewTickets(nt NewTicketsForBlock) {
 		}
 
 		// decode address
-		addr, err := hcutil.DecodeAddress(n.msa)
-		if err != nil {
+		if _, err := hcutil.DecodeAddress(n.msa); err != nil {
 			log.Warnf("invalid address %v", err)
 			continue
 		}
 
-		msgTx := MsgTxFromHex(n.hex)
+		msgTx := voting.MsgTxFromHex(n.hex)
 		if msgTx == nil {
 			log.Warnf("MsgTxFromHex failed for %v", n.hex)
 			continue
 		}
 
-		ticketFeesValid, err := evaluateStakePoolTicket(ctx, msgTx, int32(nt.blockHeight), addr)
+		ticketFeesValid, err := voting.EvaluateStakePoolTicket(msgTx,
+			int32(nt.blockHeight), ctx.feeAddrs, ctx.poolFees, ctx.params)
 		if err != nil {
 			log.Warnf("ignoring ticket %v for msa %v ticketFeesValid %v err %v",
 				n.ticket, n.msa, ticketFeesValid, err)
@@ -978,14 +1067,20 @@ func (ctx *appContext) processNewTickets(nt NewTicketsForBlock) {
 
 	log.Debug("processNewTickets ctx.Lock")
 	ctx.Lock()
+	changes := ctx.ticketChangesForBlock(nt.blockHash, nt.blockHeight)
+
 	// update ignored low fee tickets
 	for ticket, msa := range newIgnoredLowFeeTickets {
 		ctx.ignoredLowFeeTicketsMSA[ticket] = msa
+		ctx.ticketHeights[ticket] = nt.blockHeight
+		changes.addedIgnored[ticket] = msa
 	}
 
 	// update live tickets
 	for ticket, msa := range newLiveTickets {
 		ctx.liveTicketsMSA[ticket] = msa
+		ctx.ticketHeights[ticket] = nt.blockHeight
+		changes.added[ticket] = msa
 	}
 
 	// update counts
@@ -1055,25 +1150,56 @@ func (ctx *appContext) processSpentMissedTickets(smt SpentMissedTicketsForBlock)
 
 		if !sm.spent {
 			missedtickets = append(missedtickets, sm.ticket)
+			ctx.userStats.AddMiss(sm.msa)
+			ctx.addVoteHistory(&rpcserver.VoteHistoryEvent{
+				Ticket:          *sm.ticket,
+				MultiSigAddress: sm.msa,
+				Event:           rpcserver.VoteEventMissed,
+				BlockHash:       *smt.blockHash,
+				BlockHeight:     smt.blockHeight,
+				Time:            time.Now(),
+			})
 			continue
 		}
 
 		spenttickets = append(spenttickets, sm.ticket)
 	}
 
+	ctx.stats.AddPoolMisses(smt.blockHash, smt.blockHeight,
+		len(missedtickets))
+
+	block := &rpcserver.SpentMissedBlock{
+		BlockHash:   *smt.blockHash,
+		BlockHeight: smt.blockHeight,
+		Tickets:     make([]rpcserver.SpentMissedTicket, 0, len(smtickets)),
+	}
+	for _, sm := range smtickets {
+		t := rpcserver.SpentMissedTicket{Ticket: *sm.ticket, Spent: sm.spent}
+		if sm.err == nil {
+			t.MultiSigAddress = sm.msa
+		}
+		block.Tickets = append(block.Tickets, t)
+	}
+	ctx.spentMissedFeed.Publish(block)
+
 	ticketCountNew := 0
 	ticketCountOld := 0
 
 	log.Debug("processSpentMissedTickets ctx.Lock")
 	ctx.Lock()
 	ticketCountOld = len(ctx.liveTicketsMSA)
-	for _, ticket := range missedtickets {
-		delete(ctx.ignoredLowFeeTicketsMSA, *ticket)
-		delete(ctx.liveTicketsMSA, *ticket)
-	}
-	for _, ticket := range spenttickets {
-		delete(ctx.ignoredLowFeeTicketsMSA, *ticket)
-		delete(ctx.liveTicketsMSA, *ticket)
+	changes := ctx.ticketChangesForBlock(smt.blockHash, smt.blockHeight)
+	for _, ticket := range append(missedtickets, spenttickets...) {
+		// The vote is settled, whoever sent it.
+		ctx.pendingVotes.Remove(ticket)
+		if msa, ok := ctx.ignoredLowFeeTicketsMSA[*ticket]; ok {
+			changes.removedIgnored[*ticket] = msa
+			delete(ctx.ignoredLowFeeTicketsMSA, *ticket)
+		}
+		if msa, ok := ctx.liveTicketsMSA[*ticket]; ok {
+			changes.removed[*ticket] = msa
+			delete(ctx.liveTicketsMSA, *ticket)
+		}
 	}
 	ticketCountNew = len(ctx.liveTicketsMSA)
 	ctx.Unlock()
@@ -1104,9 +1230,10 @@ func (ctx *appContext) processWinningTickets(wt WinningTicketsForBlock) {
 	start := time.Now()
 
 	// We use pointer because it is the fastest accessor.
-	winners := make([]*ticketMetadata, 0, len(wt.winningTickets))
-
-	var wg sync.WaitGroup // wait group for go routine exits
+	winners := make([]*voting.Vote, 0, len(wt.winningTickets))
+	var suppressedCount int
+	leading := ctx.leading()
+	divergence, paused := ctx.forks.paused()
 
 	ctx.RLock()
 	for _, ticket := range wt.winningTickets {
@@ -1120,124 +1247,277 @@ func (ctx *appContext) processWinningTickets(wt WinningTicketsForBlock) {
 			continue
 		}
 
-		voteCfg, ok := ctx.userVotingConfig[msa]
-		if !ok {
-			// Use defaults if not found.
-			log.Warnf("vote config not found for %v using defaults",
-				msa)
-			voteCfg = userdata.UserVotingConfig{
-				Userid:          0,
+		// A standby leaves voting to the leader but keeps track of the
+		// votes so it can send the ones still pending if it takes over.
+		// Votes are held back the same way while voting is paused on a
+		// suspected chain fork.
+		if !leading || paused {
+			ctx.pendingVotes.Add(ticket, voting.PendingVote{
+				BlockHash:       *wt.blockHash,
+				BlockHeight:     wt.blockHeight,
 				MultiSigAddress: msa,
-				VoteBits:        ctx.votingConfig.VoteBits,
-				VoteBitsVersion: ctx.votingConfig.VoteVersion,
-			}
-		} else {
-			// If the user's voting config has a vote version that
-			// is different from our global vote version that we
-			// plucked from hcwallet walletinfo then just use the
-			// default votebits.
-			if voteCfg.VoteBitsVersion !=
-				ctx.votingConfig.VoteVersion {
-
-				voteCfg.VoteBits = ctx.votingConfig.VoteBits
-				log.Infof("userid %v multisigaddress %v vote "+
-					"version mismatch user %v stakepoold "+
-					"%v using votebits %d",
-					voteCfg.Userid, voteCfg.MultiSigAddress,
-					voteCfg.VoteBitsVersion,
-					ctx.votingConfig.VoteVersion,
-					voteCfg.VoteBits)
+			})
+			suppressedCount++
+			continue
+		}
+
+		// Another call is voting the ticket on this block already.
+		if !ctx.voteClaims.Claim(ticket, wt.blockHash, wt.blockHeight) {
+			log.Infof("suppressing vote of ticket %v msa %v on block %v "+
+				"(height %v): it is already being voted", ticket, msa,
+				wt.blockHash, wt.blockHeight)
+			suppressedCount++
+			continue
+		}
+
+		// Users without valid voting preferences for the vote version
+		// of the wallet vote with the default vote bits.
+		var prefs *voting.Preferences
+		voteCfg, ok := ctx.userVotingConfig[msa]
+		if ok {
+			prefs = &voting.Preferences{
+				VoteBitsVersion: voteCfg.VoteBitsVersion,
+				VoteBits:        voteCfg.VoteBits,
 			}
 		}
+		voteBits, fallback := voting.SelectVoteBits(ctx.params,
+			ctx.votingConfig.VoteVersion, ctx.votingConfig.VoteBits, prefs)
+		if fallback != "" {
+			ctx.userStats.AddFallback(msa)
+			log.Warnf("userid %v multisigaddress %v %s, voting ticket %v "+
+				"with the default votebits %d", voteCfg.Userid, msa,
+				fallback, ticket, voteBits)
+		}
 
-		w := &ticketMetadata{
-			msa:    msa,
-			ticket: ticket,
-			config: voteCfg,
+		w := &voting.Vote{
+			MultiSigAddress: msa,
+			Ticket:          ticket,
+			VoteBits:        voteBits,
 		}
 		winners = append(winners, w)
+		ctx.pendingVotes.Add(ticket, voting.PendingVote{
+			BlockHash:       *wt.blockHash,
+			BlockHeight:     wt.blockHeight,
+			MultiSigAddress: msa,
+		})
+	}
+	ctx.RUnlock()
 
-		// When testing we don't send the tickets.
-		if ctx.testing {
-			continue
+	if paused {
+		ctx.flagMissed(ctx.pendingVotes.Expire(wt.blockHeight-ctx.maxVoteAge),
+			"voting was paused on a suspected chain fork")
+		log.Warnf("processWinningTickets: height %v block %v voting "+
+			"paused, held back %v votes: %s", wt.blockHeight, wt.blockHash,
+			suppressedCount, divergence)
+		return
+	}
+	if !leading {
+		// The leader was responsible for the votes that are too old now,
+		// so they aren't flagged as missed here.
+		ctx.pendingVotes.Expire(wt.blockHeight - ctx.maxVoteAge)
+		log.Infof("processWinningTickets: height %v block %v standing "+
+			"by, left %v votes to the leader", wt.blockHeight,
+			wt.blockHash, suppressedCount)
+		return
+	}
+
+	// Redundant instances voting with wallets that hold the same scripts
+	// leave the tickets another instance claimed first to it.
+	tickets := make([]*chainhash.Hash, 0, len(winners))
+	for _, w := range winners {
+		tickets = append(tickets, w.Ticket)
+	}
+	// The votes stay pending since the other instance may fail to send
+	// them, and are retried once its claims expire.
+	claimed := ctx.voteClaims.ClaimShared(wt.blockHash, wt.blockHeight,
+		tickets)
+	if len(claimed) > 0 {
+		kept := winners[:0]
+		held := make([]*chainhash.Hash, 0, len(claimed))
+		for _, w := range winners {
+			holder, ok := claimed[*w.Ticket]
+			if !ok {
+				kept = append(kept, w)
+				continue
+			}
+			log.Infof("suppressing vote of ticket %v msa %v on block %v "+
+				"(height %v): it is voted by %v", w.Ticket,
+				w.MultiSigAddress, wt.blockHash, wt.blockHeight, holder)
+			ctx.voteClaims.Forget(w.Ticket)
+			held = append(held, w.Ticket)
+			suppressedCount++
 		}
+		winners = kept
+		if ctx.voteClaimRetry > 0 {
+			blockHash, blockHeight := *wt.blockHash, wt.blockHeight
+			time.AfterFunc(ctx.voteClaimRetry, func() {
+				ctx.retryClaimedVotes(&blockHash, blockHeight, held)
+			})
+		}
+	}
 
-		wg.Add(1)
-		log.Debugf("calling GenerateVote with blockHash %v blockHeight %v "+
-			"ticket %v VoteBits %v VoteBitsExtended %v ",
-			wt.blockHash, wt.blockHeight, w.ticket, w.config.VoteBits,
-			ctx.votingConfig.VoteBitsExtended)
-		go ctx.vote(&wg, wt.blockHash, wt.blockHeight, w)
+	// When testing we don't send the tickets.
+	if !ctx.testing {
+		ctx.voteAll(wt, winners)
 	}
-	ctx.RUnlock()
 
-	wg.Wait()
+	// Votes that made it to the network are no longer pending.  Failed ones
+	// stay pending until they are too old to be mined and may be claimed
+	// again.
+	for _, w := range winners {
+		if w.Err == nil || voting.IsDuplicateVote(w.Err) {
+			ctx.pendingVotes.Remove(w.Ticket)
+			ctx.userStats.AddVote(w.MultiSigAddress, w.Reward)
+		} else {
+			ctx.voteClaims.Release(w.Ticket)
+		}
+		if w.Txid != nil && !wt.received.IsZero() {
+			ctx.recordVoteLatency(w)
+		}
+		ctx.recordVoteHistory(wt, w)
+	}
+	ctx.flagMissed(ctx.pendingVotes.Expire(wt.blockHeight-ctx.maxVoteAge),
+		"the vote was not sent in time")
+	ctx.voteClaims.Prune(wt.blockHeight - ctx.maxVoteAge)
+	ctx.reportDoubleVotes(wt, winners)
 
 	// Log ticket information outside of the handler.
 	go func() {
 		var dupeCount, errorCount, votedCount int
 
 		for _, w := range winners {
-			if w.err == nil {
+			if w.Err == nil {
 				votedCount++
-				w.err = errSuccess
+				w.Err = errSuccess
 			} else {
 				// don't count duplicate votes as errors
-				if strings.HasPrefix(w.err.Error(), errDuplicateVote) {
+				if voting.IsDuplicateVote(w.Err) {
 					// copy the txid into our metadata struct so it gets printed
 					// properly
-					voteErrParts := strings.Split(w.err.Error(), errDuplicateVote)
-					w.txid, _ = chainhash.NewHashFromStr(voteErrParts[1])
+					w.Txid = voting.DuplicateVoteTxid(w.Err)
 					dupeCount++
 				} else {
 					errorCount++
 				}
 			}
 			log.Infof("voted ticket %v (hash: %v bits: %v) msa %v duration %v "+
-				"(%v + %v): %v", w.ticket, w.txid, w.config.VoteBits, w.msa,
-				w.duration, w.signDuration, w.sendDuration, w.err)
+				"(%v + %v): %v", w.Ticket, w.Txid, w.VoteBits,
+				w.MultiSigAddress, w.Duration, w.SignDuration,
+				w.SendDuration, w.Err)
 		}
 		log.Infof("processWinningTickets: height %v block %v "+
-			"duration %v newvotes %v duplicatevotes %v errors %v",
-			wt.blockHeight, wt.blockHash, time.Since(start), votedCount,
-			dupeCount, errorCount)
+			"duration %v newvotes %v duplicatevotes %v suppressed %v "+
+			"errors %v", wt.blockHeight, wt.blockHash, time.Since(start),
+			votedCount, dupeCount, suppressedCount, errorCount)
+		ctx.stats.AddPoolVotes(wt.blockHash, wt.blockHeight,
+			votedCount+dupeCount)
 	}()
 }
 
+// ticketHasStatus returns whether ticket is in the list of status.  It must be
+// called with the lock held.
+func (ctx *appContext) ticketHasStatus(ticket chainhash.Hash, status rpcserver.TicketStatus) bool {
+	var ok bool
+	switch status {
+	case rpcserver.TicketStatusLive:
+		_, ok = ctx.liveTicketsMSA[ticket]
+	case rpcserver.TicketStatusAddedLowFee:
+		_, ok = ctx.addedLowFeeTicketsMSA[ticket]
+	case rpcserver.TicketStatusIgnoredLowFee:
+		_, ok = ctx.ignoredLowFeeTicketsMSA[ticket]
+	default:
+		ok = true
+	}
+	return ok
+}
+
+// purchaseHeight returns the height the ticket was mined at, or 0 when it
+// isn't known.  It must be called with the lock held.
+func (ctx *appContext) purchaseHeight(ticket chainhash.Hash) int64 {
+	return ctx.ticketHeights[ticket]
+}
+
+// pruneTicketHeights forgets the purchase heights of the tickets that are no
+// longer live or ignored.  It must be called with the lock held.
+func (ctx *appContext) pruneTicketHeights() {
+	for ticket := range ctx.ticketHeights {
+		if !ctx.ticketTracked(ticket) {
+			delete(ctx.ticketHeights, ticket)
+		}
+	}
+}
+
 func (ctx *appContext) grpcCommandQueueHandler() {
 	defer ctx.wg.Done()
 
 	for {
 		select {
 		case grpcCommand := <-ctx.grpcCommandQueueChan:
+			_, span := tracing.Start(grpcCommand.Ctx,
+				"handle "+grpcCommand.Command.String(),
+				tracing.SpanKindInternal)
 			switch grpcCommand.Command {
 			case rpcserver.GetAddedLowFeeTickets:
 				ctx.RLock()
-				ticketsMSA := ctx.addedLowFeeTicketsMSA
+				page := rpcserver.PageTickets(ctx.addedLowFeeTicketsMSA,
+					grpcCommand.RequestTicketQuery, ctx.ticketHasStatus,
+					ctx.purchaseHeight)
 				ctx.RUnlock()
-				grpcCommand.ResponseTicketsMSAChan <- ticketsMSA
+				grpcCommand.ResponseTicketPageChan <- page
 			case rpcserver.GetIgnoredLowFeeTickets:
 				ctx.RLock()
-				ticketsMSA := ctx.ignoredLowFeeTicketsMSA
-				grpcCommand.ResponseTicketsMSAChan <- ticketsMSA
+				page := rpcserver.PageTickets(ctx.ignoredLowFeeTicketsMSA,
+					grpcCommand.RequestTicketQuery, ctx.ticketHasStatus,
+					ctx.purchaseHeight)
 				ctx.RUnlock()
+				grpcCommand.ResponseTicketPageChan <- page
 			case rpcserver.GetLiveTickets:
 				ctx.RLock()
-				ticketsMSA := ctx.liveTicketsMSA
+				page := rpcserver.PageTickets(ctx.liveTicketsMSA,
+					grpcCommand.RequestTicketQuery, ctx.ticketHasStatus,
+					ctx.purchaseHeight)
+				ctx.RUnlock()
+				grpcCommand.ResponseTicketPageChan <- page
+			case rpcserver.GetUserVotingStats:
+				stats := ctx.userStats.Get(
+					grpcCommand.RequestMultiSigAddresses)
+				grpcCommand.ResponseUserVotingStatsChan <- stats
+			case rpcserver.GetVoteHistory:
+				events := ctx.voteHistory.Since(
+					grpcCommand.RequestSinceHeight,
+					grpcCommand.RequestMultiSigAddresses)
+				grpcCommand.ResponseVoteHistoryChan <- events
+			case rpcserver.GetVoteLatency:
+				grpcCommand.ResponseVoteLatencyChan <- ctx.voteLatency.Snapshot()
+			case rpcserver.GetPoolStats:
+				stats := ctx.stats.Snapshot()
+				ctx.RLock()
+				stats.LiveTickets = int64(len(ctx.liveTicketsMSA))
 				ctx.RUnlock()
-				grpcCommand.ResponseTicketsMSAChan <- ticketsMSA
+				grpcCommand.ResponsePoolStatsChan <- stats
 			case rpcserver.SetAddedLowFeeTickets:
+				span.SetAttribute("tickets",
+					strconv.Itoa(len(grpcCommand.RequestTicketData)))
 				ctx.updateTicketData(grpcCommand.RequestTicketData)
 				grpcCommand.ResponseEmptyChan <- struct{}{}
 			case rpcserver.SetUserVotingPrefs:
+				span.SetAttribute("users",
+					strconv.Itoa(len(grpcCommand.RequestUserData)))
 				ctx.updateUserData(grpcCommand.RequestUserData)
 				grpcCommand.ResponseEmptyChan <- struct{}{}
+			case rpcserver.BatchSetUserVotingPrefs:
+				span.SetAttribute("users",
+					strconv.Itoa(len(grpcCommand.RequestUserData)))
+				users := ctx.mergeUserData(grpcCommand.RequestUserData)
+				grpcCommand.ResponseCountChan <- users
 			default:
 				err := fmt.Errorf("grpcCommandQueueHandler: ignoring "+
 					"unregistered gRPC command '%v'",
 					grpcCommand.Command.String())
 				log.Warn(err)
+				span.SetError(err)
 			}
+			span.End()
 		case <-ctx.quit:
 			return
 		}
@@ -1250,7 +1530,10 @@ func (ctx *appContext) newTicketHandler() {
 	for {
 		select {
 		case nt := <-ctx.newTicketsChan:
-			go ctx.processNewTickets(nt)
+			go func() {
+				ctx.processNewTickets(nt)
+				ctx.journalDone(nt.seq)
+			}()
 		case <-ctx.quit:
 			return
 		}
@@ -1263,7 +1546,10 @@ func (ctx *appContext) spentmissedTicketHandler() {
 	for {
 		select {
 		case smt := <-ctx.spentmissedTicketsChan:
-			go ctx.processSpentMissedTickets(smt)
+			go func() {
+				ctx.processSpentMissedTickets(smt)
+				ctx.journalDone(smt.seq)
+			}()
 		case <-ctx.quit:
 			return
 		}
@@ -1276,7 +1562,10 @@ func (ctx *appContext) winningTicketHandler() {
 	for {
 		select {
 		case wt := <-ctx.winningTicketsChan:
-			go ctx.processWinningTickets(wt)
+			go func() {
+				ctx.processWinningTickets(wt)
+				ctx.journalDone(wt.seq)
+			}()
 		case <-ctx.quit:
 			return
 		}
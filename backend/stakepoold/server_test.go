@@ -7,66 +7,23 @@ package main
 import (
 	"crypto/rand"
 	"encoding/binary"
-	"fmt"
+	"errors"
 	mrand "math/rand"
+	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/coolsnady/hcd/chaincfg"
 	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
 	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
 )
 
-func TestCalculateFeeAddresses(t *testing.T) {
-	xpubStr := "tpubVpQL1h9UcY9c1BPZYfjYEtw5froRAvqZEo6sn5Tji6VkhcpfMaQ6id9Spf5iNvprRTcpdF5pj7m5Suyu1E8iC4xnb6MkjUnCJureTsmdXfG"
-	firstAddrs := []string{
-		"TsYLznZJn2xhM9F7Vnt7i39NuUFENGx9Hff",
-		"TsiWMbdbmfMaJ9SDb7ig8EKfYp3KU3pvYfu",
-		"TsgTraHPFWes88oTjpPVy7SEroJvgShv1G1",
-	}
-	params := &chaincfg.TestNet2Params
-
-	// calculateFeeAddresses is currently hard-coded to return 10,000 addresses
-	numAddr := 10000
-	addrs, err := calculateFeeAddresses(xpubStr, params)
-	if err != nil {
-		t.Error("calculateFeeAddresses failed with ", err)
-	}
-	if len(addrs) != numAddr {
-		t.Errorf("expected %d addresses, got %d", numAddr, len(addrs))
-	}
-
-	// Check that the first few addresses are in the map. NOTE: don't even think
-	// about doing a range over the map as the order is random
-	for _, addr := range firstAddrs {
-		if _, ok := addrs[addr]; !ok {
-			t.Errorf("Did not find address %s in derived address map", addr)
-		}
-	}
-
-	// empty (i.e. invalid) xpubStr
-	addrs, err = calculateFeeAddresses("", params)
-	if err == nil {
-		t.Error("calculateFeeAddresses did not error with empty extended key")
-	}
-	if len(addrs) != 0 {
-		t.Errorf("expected empty map, actual length %d", len(addrs))
-	}
-
-	// wrong network
-	expectedErr := fmt.Errorf("extended public key is for wrong network")
-	addrs, err = calculateFeeAddresses(xpubStr, &chaincfg.MainNetParams)
-	if err == nil {
-		t.Error("calculateFeeAddresses did not error with wrong network parmas")
-	}
-	if err.Error() != expectedErr.Error() {
-		t.Errorf("expected error %v, got %v", expectedErr, err)
-	}
-	if len(addrs) != 0 {
-		t.Errorf("expected empty map, actual length %d", len(addrs))
-	}
-}
-
 func randomBytes(length int) []byte {
 	b := make([]byte, length)
 	_, err := rand.Read(b)
@@ -94,12 +51,18 @@ func init() {
 
 	c = &appContext{
 		liveTicketsMSA: make(map[chainhash.Hash]string),
+		pendingVotes:   voting.NewPendingVotes(),
+		stats:          voting.NewStats(chaincfg.TestNet2Params.StakeDiffWindowSize),
 		votingConfig: &VotingConfig{
 			VoteBits:         1,
 			VoteBitsExtended: "05000000",
 			VoteVersion:      5,
 		},
+		userStats:        voting.NewUserStats(),
 		userVotingConfig: make(map[string]userdata.UserVotingConfig),
+		voteClaims:       voting.NewVoteClaims(),
+		voteHistory:      voting.NewVoteHistory(10),
+		voteHistoryFeed:  rpcserver.NewVoteHistoryFeed(),
 		testing:          true,
 	}
 
@@ -117,6 +80,8 @@ func init() {
 	}
 
 	// Create a pool of tickets around expected size
+	wt.blockHash = &chainhash.Hash{100}
+	wt.blockHeight = 100
 	ticketCount := 49000
 	for i := 0; i < ticketCount; i++ {
 		b := randomBytes(4)
@@ -137,6 +102,468 @@ func init() {
 
 func BenchmarkProcessWinningTickets(b *testing.B) {
 	for n := 0; n < b.N; n++ {
+		// Let every iteration vote the winners again.
+		b.StopTimer()
+		c.voteClaims = voting.NewVoteClaims()
+		b.StartTimer()
+
 		c.processWinningTickets(wt)
 	}
 }
+
+func TestRevertBlockTicketChanges(t *testing.T) {
+	ctx := &appContext{
+		blockTicketChanges:      make(map[int64]*blockTicketChanges),
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+	}
+	spent := chainhash.Hash{1}
+	added := chainhash.Hash{2}
+	ctx.liveTicketsMSA[spent] = "msa1"
+
+	// Block 100 spends a ticket, block 101 adds one.
+	changes := ctx.ticketChangesForBlock(&chainhash.Hash{100}, 100)
+	changes.removed[spent] = ctx.liveTicketsMSA[spent]
+	delete(ctx.liveTicketsMSA, spent)
+	changes = ctx.ticketChangesForBlock(&chainhash.Hash{101}, 101)
+	changes.added[added] = "msa2"
+	ctx.liveTicketsMSA[added] = "msa2"
+
+	removed, restored := ctx.revertBlockTicketChanges([]int64{100, 101})
+	if removed != 1 || restored != 1 {
+		t.Errorf("expected 1 removed and 1 restored, got %d and %d",
+			removed, restored)
+	}
+	if ctx.liveTicketsMSA[spent] != "msa1" {
+		t.Errorf("spent ticket was not restored")
+	}
+	if _, ok := ctx.liveTicketsMSA[added]; ok {
+		t.Errorf("added ticket was not removed")
+	}
+	if len(ctx.blockTicketChanges) != 0 {
+		t.Errorf("expected no recorded blocks, got %d",
+			len(ctx.blockTicketChanges))
+	}
+
+	// Old records are dropped as new blocks come in.
+	ctx.ticketChangesForBlock(&chainhash.Hash{1}, 1)
+	ctx.ticketChangesForBlock(&chainhash.Hash{2}, 1+ticketChangesHistory)
+	if _, ok := ctx.blockTicketChanges[1]; ok {
+		t.Errorf("record for height 1 was not pruned")
+	}
+}
+
+func TestTicketChangesForReplacedBlock(t *testing.T) {
+	ctx := &appContext{
+		blockTicketChanges:      make(map[int64]*blockTicketChanges),
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+	}
+	spent := chainhash.Hash{1}
+	added := chainhash.Hash{2}
+
+	// Block 100 of the old branch adds a ticket and spends another.
+	changes := ctx.ticketChangesForBlock(&chainhash.Hash{100}, 100)
+	changes.added[added] = "msa2"
+	ctx.liveTicketsMSA[added] = "msa2"
+	changes.removed[spent] = "msa1"
+
+	// The block of the new branch at height 100 undoes them.
+	changes = ctx.ticketChangesForBlock(&chainhash.Hash{200}, 100)
+	if changes.blockHash != (chainhash.Hash{200}) || len(changes.added) != 0 ||
+		len(changes.removed) != 0 {
+		t.Errorf("unexpected record %+v", changes)
+	}
+	if _, ok := ctx.liveTicketsMSA[added]; ok {
+		t.Errorf("ticket added by the replaced block is still live")
+	}
+	if ctx.liveTicketsMSA[spent] != "msa1" {
+		t.Errorf("ticket spent by the replaced block was not restored")
+	}
+}
+
+func TestRevertDisconnected(t *testing.T) {
+	ctx := &appContext{
+		blockTicketChanges:      make(map[int64]*blockTicketChanges),
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+	}
+	for height := int64(100); height <= 101; height++ {
+		ticket := chainhash.Hash{byte(height), 1}
+		changes := ctx.ticketChangesForBlock(&chainhash.Hash{byte(height)}, height)
+		changes.added[ticket] = "msa"
+		ctx.liveTicketsMSA[ticket] = "msa"
+	}
+
+	// Block 101 was found disconnected, but the new branch replaced it
+	// before the changes were reverted.
+	replacement := chainhash.Hash{201, 1}
+	changes := ctx.ticketChangesForBlock(&chainhash.Hash{201}, 101)
+	changes.added[replacement] = "msa"
+	ctx.liveTicketsMSA[replacement] = "msa"
+
+	removed, restored := ctx.revertDisconnected(map[int64]chainhash.Hash{
+		100: {100},
+		101: {101},
+	})
+	if removed != 1 || restored != 0 {
+		t.Errorf("expected 1 removed and 0 restored, got %d and %d",
+			removed, restored)
+	}
+	if _, ok := ctx.liveTicketsMSA[replacement]; !ok {
+		t.Errorf("ticket of the new branch was removed")
+	}
+	if len(ctx.liveTicketsMSA) != 1 {
+		t.Errorf("expected only the new branch ticket, got %v",
+			ctx.liveTicketsMSA)
+	}
+}
+
+func TestProcessReorganization(t *testing.T) {
+	chain := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	ctx := &appContext{
+		blockTicketChanges:      make(map[int64]*blockTicketChanges),
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+		nodeConnection:          chain,
+	}
+
+	// Blocks 100 and 101 each add a ticket.  The reorganization replaces
+	// block 101 and shortens the chain to height 101.
+	for height := int64(100); height <= 102; height++ {
+		hash := chainhash.Hash{byte(height)}
+		ticket := chainhash.Hash{byte(height), 1}
+		changes := ctx.ticketChangesForBlock(&hash, height)
+		changes.added[ticket] = "msa"
+		ctx.liveTicketsMSA[ticket] = "msa"
+		chain.SetBlockHash(height, hash)
+	}
+	chain.SetBlockHash(101, chainhash.Hash{201})
+
+	ctx.processReorganization(Reorganization{
+		oldHash:   &chainhash.Hash{102},
+		oldHeight: 102,
+		newHash:   &chainhash.Hash{201},
+		newHeight: 101,
+	})
+
+	if _, ok := ctx.liveTicketsMSA[chainhash.Hash{100, 1}]; !ok {
+		t.Errorf("ticket of the unaffected block was removed")
+	}
+	for _, height := range []byte{101, 102} {
+		if _, ok := ctx.liveTicketsMSA[chainhash.Hash{height, 1}]; ok {
+			t.Errorf("ticket of disconnected block %d was not removed",
+				height)
+		}
+	}
+}
+
+func TestProcessWinningTicketsVotes(t *testing.T) {
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	ctx := &appContext{
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+		maxVoteAge:              4,
+		nodeConnection:          node,
+		pendingVotes:            voting.NewPendingVotes(),
+		stats:                   voting.NewStats(chaincfg.TestNet2Params.StakeDiffWindowSize),
+		userStats:               voting.NewUserStats(),
+		userVotingConfig:        make(map[string]userdata.UserVotingConfig),
+		voteClaims:              voting.NewVoteClaims(),
+		voteHistory:             voting.NewVoteHistory(10),
+		voteHistoryFeed:         rpcserver.NewVoteHistoryFeed(),
+		voteLatency:             voting.NewVoteLatency(10),
+		votingConfig:            &VotingConfig{VoteBits: 1, VoteVersion: 5},
+		walletConnection:        wallet,
+	}
+	ctx.voter = voting.NewVoter(ctx.wallet, ctx.node, 0)
+
+	voted := chainhash.Hash{1}
+	failed := chainhash.Hash{2}
+	unmanaged := chainhash.Hash{3}
+	ctx.liveTicketsMSA[voted] = "msa1"
+	ctx.liveTicketsMSA[failed] = "msa2"
+	ctx.userVotingConfig["msa1"] = userdata.UserVotingConfig{
+		Userid:          1,
+		MultiSigAddress: "msa1",
+		VoteBits:        5,
+		VoteBitsVersion: 5,
+	}
+	wallet.SetVoteError(&failed, errors.New("wallet locked"))
+
+	ctx.processWinningTickets(WinningTicketsForBlock{
+		blockHash:      &chainhash.Hash{100},
+		blockHeight:    100,
+		winningTickets: []*chainhash.Hash{&voted, &failed, &unmanaged},
+		received:       time.Now(),
+	})
+
+	if votes := wallet.Votes(); len(votes) != 1 || *votes[0] != voted {
+		t.Errorf("expected a vote to be generated for %v only, got %v",
+			voted, votes)
+	}
+	if sent := node.Sent(); len(sent) != 1 {
+		t.Errorf("expected 1 vote to be sent, got %d", len(sent))
+	}
+	pending := ctx.pendingVotes.Snapshot()
+	if _, ok := pending[failed]; !ok || len(pending) != 1 {
+		t.Errorf("expected only the failed vote to be pending, got %v",
+			pending)
+	}
+	if latency := ctx.voteLatency.Snapshot(); latency.Votes != 1 {
+		t.Errorf("expected the latency of 1 vote, got %+v", latency)
+	}
+	stats := ctx.userStats.Get([]string{"msa1", "msa2"})
+	if stats[0].Votes != 1 || stats[0].Reward != rpcclienttest.VoteReward ||
+		stats[1].Votes != 0 {
+		t.Errorf("expected 1 vote of msa1 and none of msa2, got %+v %+v",
+			stats[0], stats[1])
+	}
+	// msa2 has no voting preferences and fell back to the default.
+	if stats[0].Fallbacks != 0 || stats[1].Fallbacks != 1 {
+		t.Errorf("expected 1 fallback of msa2 only, got %+v %+v", stats[0],
+			stats[1])
+	}
+
+	// Both winners were selected but only the vote of msa1 was sent.
+	events := ctx.voteHistory.Since(100, nil)
+	var selected, sent int
+	for _, e := range events {
+		switch e.Event {
+		case rpcserver.VoteEventSelected:
+			selected++
+		case rpcserver.VoteEventVoted:
+			sent++
+			if e.Ticket != voted || e.VoteHash == nil || e.VoteBits != 5 ||
+				e.Reward != rpcclienttest.VoteReward {
+				t.Errorf("unexpected vote event %+v", e)
+			}
+		}
+	}
+	if selected != 2 || sent != 1 {
+		t.Errorf("expected 2 selected and 1 voted events, got %d and %d",
+			selected, sent)
+	}
+}
+
+func TestProcessWinningTicketsDuplicates(t *testing.T) {
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	ctx := &appContext{
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+		maxVoteAge:              4,
+		nodeConnection:          node,
+		pendingVotes:            voting.NewPendingVotes(),
+		stats:                   voting.NewStats(chaincfg.TestNet2Params.StakeDiffWindowSize),
+		userStats:               voting.NewUserStats(),
+		userVotingConfig:        make(map[string]userdata.UserVotingConfig),
+		voteClaims:              voting.NewVoteClaims(),
+		voteHistory:             voting.NewVoteHistory(10),
+		voteHistoryFeed:         rpcserver.NewVoteHistoryFeed(),
+		voteLatency:             voting.NewVoteLatency(10),
+		votingConfig:            &VotingConfig{VoteBits: 1, VoteVersion: 5},
+		walletConnection:        wallet,
+	}
+	ctx.voter = voting.NewVoter(ctx.wallet, ctx.node, 0)
+
+	ticket := chainhash.Hash{1}
+	otherWallet := chainhash.Hash{2}
+	ctx.liveTicketsMSA[ticket] = "msa1"
+	ctx.liveTicketsMSA[otherWallet] = "msa2"
+
+	// The same winning ticket reported twice is voted once.
+	for i := 0; i < 2; i++ {
+		ctx.processWinningTickets(WinningTicketsForBlock{
+			blockHash:      &chainhash.Hash{100},
+			blockHeight:    100,
+			winningTickets: []*chainhash.Hash{&ticket},
+		})
+	}
+	if votes := wallet.Votes(); len(votes) != 1 {
+		t.Errorf("expected 1 vote to be generated, got %d", len(votes))
+	}
+
+	// A vote of a redundant wallet that made it to the network first
+	// counts as voted.
+	node.SetSendError(errors.New("-26: rejected transaction: output " +
+		"0000000000000000000000000000000000000000000000000000000000000002:0 " +
+		"already spent by transaction " +
+		"0000000000000000000000000000000000000000000000000000000000000003 " +
+		"in the memory pool"))
+	ctx.processWinningTickets(WinningTicketsForBlock{
+		blockHash:      &chainhash.Hash{101},
+		blockHeight:    101,
+		winningTickets: []*chainhash.Hash{&otherWallet},
+	})
+	if pending := ctx.pendingVotes.Snapshot(); len(pending) != 0 {
+		t.Errorf("expected no pending votes, got %v", pending)
+	}
+	stats := ctx.userStats.Get([]string{"msa1", "msa2"})
+	if stats[0].Votes != 1 || stats[1].Votes != 1 {
+		t.Errorf("expected 1 vote of msa1 and msa2, got %+v %+v",
+			stats[0], stats[1])
+	}
+}
+
+// sharedClaimsStub are vote claims shared with other instances, which claimed
+// the votes in claimed first.
+type sharedClaimsStub struct {
+	claimed  map[chainhash.Hash]string
+	err      error
+	released []chainhash.Hash
+}
+
+func (s *sharedClaimsStub) ClaimVotes(blockHash *chainhash.Hash,
+	blockHeight int64, tickets []*chainhash.Hash) (map[chainhash.Hash]string, error) {
+	return s.claimed, s.err
+}
+
+func (s *sharedClaimsStub) ReleaseVote(ticket *chainhash.Hash) error {
+	s.released = append(s.released, *ticket)
+	return nil
+}
+
+func (s *sharedClaimsStub) PruneVotes(height int64) error {
+	return nil
+}
+
+func TestProcessWinningTicketsSharedClaims(t *testing.T) {
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	shared := &sharedClaimsStub{}
+	ctx := &appContext{
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+		maxVoteAge:              4,
+		nodeConnection:          node,
+		pendingVotes:            voting.NewPendingVotes(),
+		stats:                   voting.NewStats(chaincfg.TestNet2Params.StakeDiffWindowSize),
+		userStats:               voting.NewUserStats(),
+		userVotingConfig:        make(map[string]userdata.UserVotingConfig),
+		voteClaims:              voting.NewSharedVoteClaims(shared),
+		voteHistory:             voting.NewVoteHistory(10),
+		voteHistoryFeed:         rpcserver.NewVoteHistoryFeed(),
+		voteLatency:             voting.NewVoteLatency(10),
+		votingConfig:            &VotingConfig{VoteBits: 1, VoteVersion: 5},
+		walletConnection:        wallet,
+	}
+	ctx.voter = voting.NewVoter(ctx.wallet, ctx.node, 0)
+
+	mine := chainhash.Hash{1}
+	theirs := chainhash.Hash{2}
+	unclaimed := chainhash.Hash{3}
+	failed := chainhash.Hash{4}
+	for _, ticket := range []chainhash.Hash{mine, theirs, unclaimed, failed} {
+		ctx.liveTicketsMSA[ticket] = "msa"
+	}
+
+	// The ticket another instance claimed first is left to it.
+	shared.claimed = map[chainhash.Hash]string{theirs: "other:1"}
+	ctx.processWinningTickets(WinningTicketsForBlock{
+		blockHash:      &chainhash.Hash{100},
+		blockHeight:    100,
+		winningTickets: []*chainhash.Hash{&mine, &theirs},
+	})
+	if votes := wallet.Votes(); len(votes) != 1 || *votes[0] != mine {
+		t.Errorf("expected a vote to be generated for %v only, got %v",
+			mine, votes)
+	}
+	// The other instance may fail to send its vote, so it stays pending.
+	pending := ctx.pendingVotes.Snapshot()
+	if _, ok := pending[theirs]; len(pending) != 1 || !ok {
+		t.Errorf("expected only %v to be pending, got %v", theirs, pending)
+	}
+
+	// Tickets are voted anyway when the shared claims are unavailable, and
+	// failed votes release their claim.
+	shared.claimed = nil
+	shared.err = errors.New("database unavailable")
+	wallet.SetVoteError(&failed, errors.New("wallet locked"))
+	ctx.processWinningTickets(WinningTicketsForBlock{
+		blockHash:      &chainhash.Hash{101},
+		blockHeight:    101,
+		winningTickets: []*chainhash.Hash{&unclaimed, &failed},
+	})
+	if votes := wallet.Votes(); len(votes) != 2 || *votes[1] != unclaimed {
+		t.Errorf("expected a vote to be generated for %v, got %v",
+			unclaimed, votes)
+	}
+	if !reflect.DeepEqual(shared.released, []chainhash.Hash{failed}) {
+		t.Errorf("released %v, want %v", shared.released, failed)
+	}
+}
+
+func TestRetryClaimedVotes(t *testing.T) {
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	shared := &sharedClaimsStub{}
+	ctx := &appContext{
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+		maxVoteAge:              4,
+		nodeConnection:          node,
+		pendingVotes:            voting.NewPendingVotes(),
+		quit:                    make(chan struct{}),
+		stats:                   voting.NewStats(chaincfg.TestNet2Params.StakeDiffWindowSize),
+		userStats:               voting.NewUserStats(),
+		userVotingConfig:        make(map[string]userdata.UserVotingConfig),
+		voteClaims:              voting.NewSharedVoteClaims(shared),
+		voteHistory:             voting.NewVoteHistory(10),
+		voteHistoryFeed:         rpcserver.NewVoteHistoryFeed(),
+		voteLatency:             voting.NewVoteLatency(10),
+		votingConfig:            &VotingConfig{VoteBits: 1, VoteVersion: 5},
+		walletConnection:        wallet,
+	}
+	ctx.voter = voting.NewVoter(ctx.wallet, ctx.node, 0)
+
+	header := &wire.BlockHeader{Height: 100}
+	node.AddBlock(header)
+	blockHash := header.BlockHash()
+	theirs := chainhash.Hash{1}
+	settled := chainhash.Hash{2}
+	ctx.liveTicketsMSA[theirs] = "msa"
+	ctx.liveTicketsMSA[settled] = "msa"
+
+	shared.claimed = map[chainhash.Hash]string{
+		theirs:  "other:1",
+		settled: "other:1",
+	}
+	ctx.processWinningTickets(WinningTicketsForBlock{
+		blockHash:      &blockHash,
+		blockHeight:    100,
+		winningTickets: []*chainhash.Hash{&theirs, &settled},
+	})
+	if votes := wallet.Votes(); len(votes) != 0 {
+		t.Fatalf("expected no votes to be generated, got %v", votes)
+	}
+
+	// The vote of settled made it to the chain, while the other instance
+	// never voted theirs and its claim expired.
+	ctx.pendingVotes.Remove(&settled)
+	shared.claimed = nil
+	ctx.retryClaimedVotes(&blockHash, 100,
+		[]*chainhash.Hash{&theirs, &settled})
+	if votes := wallet.Votes(); len(votes) != 1 || *votes[0] != theirs {
+		t.Errorf("expected a vote to be generated for %v only, got %v",
+			theirs, votes)
+	}
+	if pending := ctx.pendingVotes.Snapshot(); len(pending) != 0 {
+		t.Errorf("expected no pending votes, got %v", pending)
+	}
+
+	// Votes on a block that was reorganized out are not retried.
+	other := chainhash.Hash{3}
+	ctx.liveTicketsMSA[other] = "msa"
+	ctx.pendingVotes.Add(&other, voting.PendingVote{
+		BlockHash:       chainhash.Hash{100},
+		BlockHeight:     100,
+		MultiSigAddress: "msa",
+	})
+	ctx.retryClaimedVotes(&chainhash.Hash{100}, 100,
+		[]*chainhash.Hash{&other})
+	if votes := wallet.Votes(); len(votes) != 1 {
+		t.Errorf("expected no vote on a reorganized block, got %v", votes)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+)
+
+// snapshotTicket is what looking up a ticket in the wallet found out about it.
+// The fields are exported so snapshots can be saved with encoding/gob.
+// PurchaseHeight is 0 in snapshots saved before it was added.
+type snapshotTicket struct {
+	MultiSigAddress string
+	FeesValid       bool
+	PurchaseHeight  int64
+}
+
+// ticketSnapshot is the pool tickets known at a block.  Starting from it
+// spares looking up every ticket in the wallet again after a restart, which
+// takes long for large pools.
+type ticketSnapshot struct {
+	BlockHash   chainhash.Hash
+	BlockHeight int64
+	Tickets     map[chainhash.Hash]snapshotTicket // [ticket]
+}
+
+// takeTicketSnapshot returns the tickets known at the last block seen, or nil
+// before any block was seen.  The fees of added low fee tickets were never
+// checked, so they are left out.  It must be called with the lock held.
+func (ctx *appContext) takeTicketSnapshot() *ticketSnapshot {
+	if ctx.lastBlockSeenHash == nil {
+		return nil
+	}
+	s := &ticketSnapshot{
+		BlockHash:   *ctx.lastBlockSeenHash,
+		BlockHeight: ctx.lastBlockSeenHeight,
+		Tickets: make(map[chainhash.Hash]snapshotTicket,
+			len(ctx.liveTicketsMSA)+len(ctx.ignoredLowFeeTicketsMSA)),
+	}
+	for ticket, msa := range ctx.liveTicketsMSA {
+		if _, ok := ctx.addedLowFeeTicketsMSA[ticket]; ok {
+			continue
+		}
+		s.Tickets[ticket] = snapshotTicket{
+			MultiSigAddress: msa,
+			FeesValid:       true,
+			PurchaseHeight:  ctx.ticketHeights[ticket],
+		}
+	}
+	for ticket, msa := range ctx.ignoredLowFeeTicketsMSA {
+		s.Tickets[ticket] = snapshotTicket{
+			MultiSigAddress: msa,
+			PurchaseHeight:  ctx.ticketHeights[ticket],
+		}
+	}
+	return s
+}
+
+// loadTicketSnapshot returns the tickets of the most recent snapshot, or nil
+// if there is none or its block was reorganized out since.
+func (ctx *appContext) loadTicketSnapshot() map[chainhash.Hash]snapshotTicket {
+	var s ticketSnapshot
+	path, err := ctx.store.Load("TicketSnapshot", dataVersionTicketSnapshot,
+		&s)
+	if err != nil {
+		log.Warnf("unable to load the ticket snapshot: %v", err)
+		return nil
+	}
+	if path == "" {
+		return nil
+	}
+
+	mainHash, err := ctx.node().GetBlockHash(s.BlockHeight)
+	if err != nil || *mainHash != s.BlockHash {
+		log.Infof("ignoring the ticket snapshot in %s: block %v (height "+
+			"%v) is no longer in the main chain", path, s.BlockHash,
+			s.BlockHeight)
+		return nil
+	}
+	log.Infof("Loaded the snapshot of %v tickets at height %v from %s",
+		len(s.Tickets), s.BlockHeight, path)
+	return s.Tickets
+}
+
+// snapshotHandler saves the in-memory state every interval so a restart after
+// a crash starts from recent data.  It must be run as a goroutine.
+func (ctx *appContext) snapshotHandler(interval time.Duration) {
+	defer ctx.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			saveData(ctx)
+			if err := pruneData(ctx); err != nil {
+				log.Warnf("pruneData error: %v", err)
+			}
+		case <-ctx.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+)
+
+func TestTicketSnapshot(t *testing.T) {
+	live := chainhash.Hash{1}
+	ignored := chainhash.Hash{2}
+	added := chainhash.Hash{3}
+	unknown := chainhash.Hash{4}
+	gone := chainhash.Hash{5}
+
+	ctx := &appContext{
+		addedLowFeeTicketsMSA:   map[chainhash.Hash]string{added: "msa2"},
+		ignoredLowFeeTicketsMSA: map[chainhash.Hash]string{ignored: "msa2"},
+		lastBlockSeenHash:       &chainhash.Hash{100},
+		lastBlockSeenHeight:     100,
+		liveTicketsMSA: map[chainhash.Hash]string{
+			live:  "msa1",
+			added: "msa2",
+			gone:  "msa1",
+		},
+		userVotingConfig: map[string]userdata.UserVotingConfig{
+			"msa1": {MultiSigAddress: "msa1"},
+			"msa2": {MultiSigAddress: "msa2"},
+		},
+	}
+	snapshot := ctx.takeTicketSnapshot()
+	if _, ok := snapshot.Tickets[added]; ok || len(snapshot.Tickets) != 3 {
+		t.Fatalf("expected a snapshot of the live and ignored tickets "+
+			"but the added one, got %v", snapshot.Tickets)
+	}
+
+	// Only the tickets missing from the snapshot are looked up.  The wallet
+	// has no transaction details for them, so they aren't mapped to a user.
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	for _, ticket := range []chainhash.Hash{live, ignored, added, unknown} {
+		ticket := ticket
+		wallet.AddTicket(&ticket, &dcrjson.GetTransactionResult{
+			TxID: ticket.String(),
+		})
+	}
+	ctx.walletConnection = wallet
+	ctx.nodeConnection = rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+
+	ignoredMSA, liveMSA, err := walletGetTickets(ctx, 100, snapshot.Tickets)
+	if err != nil {
+		t.Fatalf("walletGetTickets: %v", err)
+	}
+	wantLive := map[chainhash.Hash]string{live: "msa1"}
+	if !reflect.DeepEqual(liveMSA, wantLive) {
+		t.Errorf("live tickets %v, want %v", liveMSA, wantLive)
+	}
+	wantIgnored := map[chainhash.Hash]string{ignored: "msa2"}
+	if !reflect.DeepEqual(ignoredMSA, wantIgnored) {
+		t.Errorf("ignored tickets %v, want %v", ignoredMSA, wantIgnored)
+	}
+
+	// An ignored ticket the admin added since is live.
+	ctx.addedLowFeeTicketsMSA[ignored] = "msa2"
+	ignoredMSA, liveMSA, err = walletGetTickets(ctx, 100, snapshot.Tickets)
+	if err != nil {
+		t.Fatalf("walletGetTickets: %v", err)
+	}
+	if liveMSA[ignored] != "msa2" || len(ignoredMSA) != 0 {
+		t.Errorf("added ticket not live: live %v ignored %v", liveMSA,
+			ignoredMSA)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+// stakeDiffHistorySize is the number of most recent stake difficulty
+// intervals kept for the GetStakeDifficultyHistory RPC.
+const stakeDiffHistorySize = 1000
+
+// StakeDifficulties implements rpcserver.StakeDifficultyReporter.
+func (ctx *appContext) StakeDifficulties(sinceHeight int64) []*rpcserver.StakeDifficultyInterval {
+	return ctx.stakeDiffs.Since(sinceHeight)
+}
+
+// stakeDifficultyBackfill looks up the stake difficulty of the intervals
+// before stakepoold started from the first block of each, newest first, until
+// the history is full or the chain starts.  It must be run as a goroutine.
+func (ctx *appContext) stakeDifficultyBackfill() {
+	defer ctx.wg.Done()
+
+	node := ctx.node()
+	if node == nil {
+		return
+	}
+	_, tipHeight, err := node.GetBestBlock()
+	if err != nil {
+		log.Warnf("stake difficulty history: unable to get the best "+
+			"block: %v", err)
+		return
+	}
+
+	window := ctx.params.StakeDiffWindowSize
+	start := ctx.stakeDiffs.StartHeight(tipHeight)
+	for i := 0; i < stakeDiffHistorySize && start >= 0; i++ {
+		select {
+		case <-ctx.quit:
+			return
+		default:
+		}
+
+		hash, err := node.GetBlockHash(start)
+		if err != nil {
+			log.Warnf("stake difficulty history: unable to get the block "+
+				"at height %d: %v", start, err)
+			return
+		}
+		header, err := node.GetBlockHeader(hash)
+		if err != nil {
+			log.Warnf("stake difficulty history: unable to get the "+
+				"header of block %v: %v", hash, err)
+			return
+		}
+		ctx.stakeDiffs.AddBlock(header)
+		start -= window
+	}
+	log.Debugf("stake difficulty history: looked up the intervals from "+
+		"height %d on", start+window)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+)
+
+// voteLatencyWindow is the number of most recent votes the vote latency
+// percentiles are calculated over.
+const voteLatencyWindow = 1000
+
+// voteHistorySize is the number of most recent ticket events kept for the
+// frontend to fetch.
+const voteHistorySize = 10000
+
+// processBlockConnected updates the rolling pool statistics with a newly
+// connected block.
+func (ctx *appContext) processBlockConnected(blockHeader []byte) {
+	var header wire.BlockHeader
+	if err := header.Deserialize(bytes.NewReader(blockHeader)); err != nil {
+		log.Errorf("processBlockConnected: failed to deserialize block "+
+			"header: %v", err)
+		return
+	}
+	ctx.stats.ConnectBlock(&header)
+	ctx.stakeDiffs.ConnectBlock(&header)
+	blockHash := header.BlockHash()
+	ctx.setLastBlockSeen(&blockHash, int64(header.Height))
+
+	log.Debugf("processBlockConnected: height %v poolsize %v sbits %v "+
+		"voters %v", header.Height, header.PoolSize, header.SBits,
+		header.Voters)
+}
+
+func (ctx *appContext) blockConnectedHandler() {
+	defer ctx.wg.Done()
+
+	for {
+		select {
+		case header := <-ctx.blockConnectedChan:
+			ctx.processBlockConnected(header)
+		case <-ctx.quit:
+			return
+		}
+	}
+}
+
+// recordVoteLatency records how long it took from the winning tickets
+// notification to sending the vote w and warns when it took longer than the
+// votelatencywarn option allows.
+func (ctx *appContext) recordVoteLatency(w *voting.Vote) {
+	ctx.voteLatency.Record(w.Latency)
+	if ctx.voteLatencyWarn > 0 && w.Latency > ctx.voteLatencyWarn {
+		log.Warnf("vote for ticket %v took %v after the winning tickets "+
+			"notification (generatevote %v, sendrawtransaction %v), "+
+			"more than votelatencywarn %v", w.Ticket, w.Latency,
+			w.SignDuration, w.SendDuration, ctx.voteLatencyWarn)
+	}
+}
+
+// addVoteHistory adds e to the vote history and sends it to the
+// SubscribeVoteHistory clients, so the frontend records it as it happens.
+func (ctx *appContext) addVoteHistory(e *rpcserver.VoteHistoryEvent) {
+	ctx.voteHistory.Add(e)
+	ctx.voteHistoryFeed.Publish(e)
+}
+
+// recordVoteHistory records that the winning ticket w was selected to vote on
+// the block of wt and, when the vote was sent, the vote.
+func (ctx *appContext) recordVoteHistory(wt WinningTicketsForBlock, w *voting.Vote) {
+	now := time.Now()
+	ctx.addVoteHistory(&rpcserver.VoteHistoryEvent{
+		Ticket:          *w.Ticket,
+		MultiSigAddress: w.MultiSigAddress,
+		Event:           rpcserver.VoteEventSelected,
+		BlockHash:       *wt.blockHash,
+		BlockHeight:     wt.blockHeight,
+		VoteBits:        w.VoteBits,
+		Time:            now,
+	})
+
+	voteHash := w.Txid
+	if w.Err != nil {
+		if !voting.IsDuplicateVote(w.Err) {
+			return
+		}
+		voteHash = voting.DuplicateVoteTxid(w.Err)
+	}
+	ctx.addVoteHistory(&rpcserver.VoteHistoryEvent{
+		Ticket:          *w.Ticket,
+		MultiSigAddress: w.MultiSigAddress,
+		Event:           rpcserver.VoteEventVoted,
+		BlockHash:       *wt.blockHash,
+		BlockHeight:     wt.blockHeight,
+		VoteHash:        voteHash,
+		VoteBits:        w.VoteBits,
+		Reward:          w.Reward,
+		Time:            now,
+	})
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package store keeps stakepoold state in versioned gob files so it survives
+// restarts.
+package store
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// filenameTemplate is the name of a save file.  KIND is the lower case kind
+// of data, which allows multiple versions of each kind to coexist.
+const filenameTemplate = "KIND-DATE-VERSION.gob"
+
+// Store saves and loads data in a directory.
+type Store struct {
+	path string
+	keep int
+}
+
+// New returns a Store for the directory at path that keeps the keep most
+// recent files of every kind and version when pruned.
+func New(path string, keep int) *Store {
+	return &Store{path: path, keep: keep}
+}
+
+// files returns the save files for a data kind and version, oldest first.
+func (s *Store) files(kind, version string) ([]string, error) {
+	entries, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for i, file := range entries {
+		log.Debugf("entry %d => %s", i, file.Name())
+		if strings.HasPrefix(file.Name(), strings.ToLower(kind)) &&
+			strings.Contains(file.Name(), version) &&
+			strings.HasSuffix(file.Name(), ".gob") {
+			files = append(files, filepath.Join(s.path, file.Name()))
+		}
+	}
+	return files, nil
+}
+
+// Prune removes the save files for a data kind and version except the most
+// recent ones.
+func (s *Store) Prune(kind, version string) error {
+	files, err := s.files(kind, version)
+	if err != nil {
+		return err
+	}
+	if len(files) <= s.keep {
+		return nil
+	}
+
+	for _, path := range files[:len(files)-s.keep] {
+		if err := os.Remove(path); err != nil {
+			log.Warnf("unable to prune %v: %v", path, err)
+		} else {
+			log.Infof("pruned old data file %v", path)
+		}
+	}
+	return nil
+}
+
+// Load decodes the most recent save file for a data kind and version into v
+// and returns its path.  The path is empty and v is left alone if there is no
+// such file, which isn't an error since the cache might not have been
+// initialized yet or the admin deleted the files to reset it.
+func (s *Store) Load(kind, version string, v interface{}) (string, error) {
+	files, err := s.files(kind, version)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", nil
+	}
+
+	path := files[len(files)-1]
+	r, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	if err := gob.NewDecoder(r).Decode(v); err != nil {
+		return "", fmt.Errorf("failed to decode file %s: %v", path, err)
+	}
+	return path, nil
+}
+
+// Save encodes v to a new save file for a data kind and version and returns
+// its path.
+func (s *Store) Save(kind, version string, v interface{}) (string, error) {
+	filename := strings.Replace(filenameTemplate, "KIND", kind, -1)
+	filename = strings.Replace(filename, "DATE",
+		time.Now().Format("2006_01_02_15_04_05"), -1)
+	filename = strings.Replace(filename, "VERSION", version, -1)
+	path := filepath.Join(s.path, strings.ToLower(filename))
+
+	w, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening file %s: %v", path, err)
+	}
+	defer w.Close()
+
+	if err := gob.NewEncoder(w).Encode(v); err != nil {
+		return "", fmt.Errorf("failed to encode file %s: %v", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type testData struct {
+	Tickets map[string]int64
+}
+
+func testStore(t *testing.T, keep int) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(dir, keep), func() { os.RemoveAll(dir) }
+}
+
+func TestSaveLoad(t *testing.T) {
+	s, cleanup := testStore(t, 2)
+	defer cleanup()
+
+	// Loading without a save file leaves v alone.
+	var empty testData
+	path, err := s.Load("Tickets", "v1", &empty)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if path != "" || empty.Tickets != nil {
+		t.Errorf("expected nothing to be loaded, got %q %v", path, empty)
+	}
+
+	saved := testData{Tickets: map[string]int64{"a": 1, "b": 2}}
+	path, err = s.Save("Tickets", "v1", saved)
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if name := filepath.Base(path); name[:len("tickets-")] != "tickets-" ||
+		filepath.Ext(name) != ".gob" {
+		t.Errorf("unexpected save file name %s", name)
+	}
+
+	var loaded testData
+	loadedPath, err := s.Load("Tickets", "v1", &loaded)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loadedPath != path {
+		t.Errorf("expected %s to be loaded, got %s", path, loadedPath)
+	}
+	if !reflect.DeepEqual(loaded, saved) {
+		t.Errorf("expected %v, got %v", saved, loaded)
+	}
+
+	// Other versions and kinds are not loaded.
+	var other testData
+	if path, err := s.Load("Tickets", "v2", &other); err != nil || path != "" {
+		t.Errorf("expected no v2 file, got %q: %v", path, err)
+	}
+	if path, err := s.Load("Votes", "v1", &other); err != nil || path != "" {
+		t.Errorf("expected no votes file, got %q: %v", path, err)
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	s, cleanup := testStore(t, 2)
+	defer cleanup()
+
+	path := filepath.Join(s.path, "tickets-2018_01_01_00_00_00-v1.gob")
+	if err := ioutil.WriteFile(path, []byte("not a gob"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var v testData
+	if _, err := s.Load("Tickets", "v1", &v); err == nil {
+		t.Errorf("expected an error decoding a corrupt file")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s, cleanup := testStore(t, 2)
+	defer cleanup()
+
+	// Save files are ordered by the date in their names, so write them
+	// directly instead of waiting between saves.
+	names := []string{
+		"tickets-2018_01_01_00_00_00-v1.gob",
+		"tickets-2018_01_02_00_00_00-v1.gob",
+		"tickets-2018_01_03_00_00_00-v1.gob",
+		"tickets-2018_01_01_00_00_00-v2.gob",
+		"votes-2018_01_01_00_00_00-v1.gob",
+	}
+	for _, name := range names {
+		err := ioutil.WriteFile(filepath.Join(s.path, name), nil, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Prune("Tickets", "v1"); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+
+	entries, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remaining []string
+	for _, e := range entries {
+		remaining = append(remaining, e.Name())
+	}
+	want := []string{
+		"tickets-2018_01_01_00_00_00-v2.gob",
+		"tickets-2018_01_02_00_00_00-v1.gob",
+		"tickets-2018_01_03_00_00_00-v1.gob",
+		"votes-2018_01_01_00_00_00-v1.gob",
+	}
+	if !reflect.DeepEqual(remaining, want) {
+		t.Errorf("expected %v to remain, got %v", want, remaining)
+	}
+
+	// Pruning with fewer files than are kept is a no-op.
+	if err := s.Prune("Votes", "v1"); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+}
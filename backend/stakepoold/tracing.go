@@ -0,0 +1,215 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+	"github.com/coolsnady/hcstakepool/tracing"
+)
+
+// tracer records the spans of gRPC requests.  It is nil when tracing is
+// disabled.
+var tracer *tracing.Tracer
+
+// requestLogPrefix returns the prefix of log lines about work done for the
+// frontend request in ctx, which names the request ID like the frontend's
+// request log does.
+func requestLogPrefix(ctx context.Context) string {
+	if reqID := tracing.RequestID(ctx); reqID != "" {
+		return "[" + reqID + "] "
+	}
+	return ""
+}
+
+// traceWallet returns w recording a span for every wallet RPC made on behalf
+// of the traced request in ctx and logging the RPCs with the request ID.  Once
+// ctx is done, e.g. because the frontend request was abandoned or is past its
+// deadline, the RPCs fail with the error of ctx without reaching the wallet.
+// Requests that can't be canceled and are neither traced nor have an ID get w.
+func traceWallet(ctx context.Context, w rpcclient.WalletSource) rpcclient.WalletSource {
+	if ctx.Done() == nil && tracing.SpanFromContext(ctx) == nil &&
+		tracing.RequestID(ctx) == "" {
+		return w
+	}
+	return &tracedWallet{
+		WalletSource: w,
+		ctx:          ctx,
+		prefix:       requestLogPrefix(ctx),
+	}
+}
+
+// tracedWallet records the calls to a wallet as children of the span in ctx
+// and logs them with the request ID in ctx.  Calls made after ctx is done
+// fail.
+type tracedWallet struct {
+	rpcclient.WalletSource
+	ctx    context.Context
+	prefix string
+}
+
+// walletCall is a call to the wallet in progress.
+type walletCall struct {
+	w      *tracedWallet
+	method string
+	span   *tracing.Span
+	start  time.Time
+}
+
+// start starts the span of a call to method, or returns the error of the
+// context of the wallet when it is done.
+func (w *tracedWallet) start(method string) (*walletCall, error) {
+	if err := w.ctx.Err(); err != nil {
+		log.Debugf("%sSkipping hcwallet %s: %v", w.prefix, method, err)
+		return nil, err
+	}
+	_, span := tracing.Start(w.ctx, "hcwallet."+method,
+		tracing.SpanKindClient)
+	span.SetAttribute("rpc.system", "hcwallet")
+	span.SetAttribute("rpc.method", method)
+	return &walletCall{w: w, method: method, span: span,
+		start: time.Now()}, nil
+}
+
+// end finishes the call with its error, if any.
+func (c *walletCall) end(err error) {
+	c.span.SetError(err)
+	c.span.End()
+	if c.w.prefix == "" {
+		return
+	}
+	if err != nil {
+		log.Warnf("%shcwallet %s failed after %v: %v", c.w.prefix,
+			c.method, time.Since(c.start), err)
+		return
+	}
+	log.Infof("%shcwallet %s took %v", c.w.prefix, c.method,
+		time.Since(c.start))
+}
+
+func (w *tracedWallet) GenerateVote(blockHash *chainhash.Hash, height int64,
+	sstxHash *chainhash.Hash, voteBits uint16,
+	voteBitsExt string) (*dcrjson.GenerateVoteResult, error) {
+	call, err := w.start("generatevote")
+	if err != nil {
+		return nil, err
+	}
+	res, err := w.WalletSource.GenerateVote(blockHash, height, sstxHash,
+		voteBits, voteBitsExt)
+	call.end(err)
+	return res, err
+}
+
+func (w *tracedWallet) GetBestBlock() (*chainhash.Hash, int64, error) {
+	call, err := w.start("getbestblock")
+	if err != nil {
+		return nil, 0, err
+	}
+	hash, height, err := w.WalletSource.GetBestBlock()
+	call.end(err)
+	return hash, height, err
+}
+
+func (w *tracedWallet) GetTickets(includeImmature bool) ([]*chainhash.Hash, error) {
+	call, err := w.start("gettickets")
+	if err != nil {
+		return nil, err
+	}
+	tickets, err := w.WalletSource.GetTickets(includeImmature)
+	call.end(err)
+	return tickets, err
+}
+
+func (w *tracedWallet) GetTransaction(txHash *chainhash.Hash) (*dcrjson.GetTransactionResult, error) {
+	call, err := w.start("gettransaction")
+	if err != nil {
+		return nil, err
+	}
+	res, err := w.WalletSource.GetTransaction(txHash)
+	call.end(err)
+	return res, err
+}
+
+// failedFuture is the future of a call that wasn't made.
+type failedFuture struct {
+	err error
+}
+
+func (f failedFuture) Receive() (*dcrjson.GetTransactionResult, error) {
+	return nil, f.err
+}
+
+// GetTransactionAsync isn't traced since the call ends when its result is
+// received, but it isn't made once the context of the wallet is done.
+func (w *tracedWallet) GetTransactionAsync(txHash *chainhash.Hash) rpcclient.TransactionFuture {
+	if err := w.ctx.Err(); err != nil {
+		return failedFuture{err}
+	}
+	return w.WalletSource.GetTransactionAsync(txHash)
+}
+
+func (w *tracedWallet) ImportScriptRescanFrom(script []byte, rescan bool, scanFrom int) error {
+	call, err := w.start("importscript")
+	if err != nil {
+		return err
+	}
+	err = w.WalletSource.ImportScriptRescanFrom(script, rescan, scanFrom)
+	call.end(err)
+	return err
+}
+
+func (w *tracedWallet) ListScripts() ([][]byte, error) {
+	call, err := w.start("listscripts")
+	if err != nil {
+		return nil, err
+	}
+	scripts, err := w.WalletSource.ListScripts()
+	call.end(err)
+	return scripts, err
+}
+
+func (w *tracedWallet) RescanWallet(beginHeight int64) error {
+	call, err := w.start("rescanwallet")
+	if err != nil {
+		return err
+	}
+	err = w.WalletSource.RescanWallet(beginHeight)
+	call.end(err)
+	return err
+}
+
+func (w *tracedWallet) WalletInfo() (*dcrjson.WalletInfoResult, error) {
+	call, err := w.start("walletinfo")
+	if err != nil {
+		return nil, err
+	}
+	info, err := w.WalletSource.WalletInfo()
+	call.end(err)
+	return info, err
+}
+
+func (w *tracedWallet) WalletLock() error {
+	call, err := w.start("walletlock")
+	if err != nil {
+		return err
+	}
+	err = w.WalletSource.WalletLock()
+	call.end(err)
+	return err
+}
+
+func (w *tracedWallet) WalletPassphrase(passphrase string, timeoutSecs int64) error {
+	call, err := w.start("walletpassphrase")
+	if err != nil {
+		return err
+	}
+	err = w.WalletSource.WalletPassphrase(passphrase, timeoutSecs)
+	call.end(err)
+	return err
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+)
+
+func TestTraceWalletCanceled(t *testing.T) {
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	wallet.SetBestBlock(&chainhash.Hash{1}, 100)
+
+	if w := traceWallet(context.Background(), wallet); w != wallet {
+		t.Errorf("untraced wallet of a background context was wrapped")
+	}
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	w := traceWallet(reqCtx, wallet)
+	if _, height, err := w.GetBestBlock(); err != nil || height != 100 {
+		t.Fatalf("GetBestBlock: %v, %v", height, err)
+	}
+	cancel()
+	if _, _, err := w.GetBestBlock(); err != context.Canceled {
+		t.Errorf("GetBestBlock after cancel: got %v, want %v", err,
+			context.Canceled)
+	}
+	if _, err := w.GetTransactionAsync(&chainhash.Hash{2}).Receive(); err != context.Canceled {
+		t.Errorf("GetTransactionAsync after cancel: got %v, want %v", err,
+			context.Canceled)
+	}
+}
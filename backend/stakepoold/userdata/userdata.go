@@ -21,6 +21,8 @@ type UserData struct {
 	sync.RWMutex
 	DBConfig         *DBConfig
 	UserVotingConfig map[string]UserVotingConfig // [multisigaddr]
+
+	voteClaimsDB *sql.DB
 }
 
 // UserVotingConfig contains per-user voting preferences.
@@ -81,15 +83,9 @@ func (u *UserData) MySQLFetchAddedLowFeeTickets() (map[chainhash.Hash]string, er
 
 // MySQLFetchUserVotingConfig fetches the voting preferences of all users
 // who have completed registration of the pool by submitting an address
-// and generating a multisig ticket address.
+// and generating a multisig ticket address, and of the additional voting
+// addresses they registered.
 func (u *UserData) MySQLFetchUserVotingConfig() (map[string]UserVotingConfig, error) {
-	var (
-		userid          int64
-		multiSigAddress string
-		voteBits        int64
-		voteBitsVersion int64
-	)
-
 	userInfo := map[string]UserVotingConfig{}
 
 	db, err := sql.Open("mysql", fmt.Sprint(u.DBConfig.DBUser, ":", u.DBConfig.DBPassword, "@(", u.DBConfig.DBHost, ":", u.DBConfig.DBPort, ")/", u.DBConfig.DBName, "?charset=utf8mb4"))
@@ -112,6 +108,41 @@ func (u *UserData) MySQLFetchUserVotingConfig() (map[string]UserVotingConfig, er
 	}
 
 	defer rows.Close()
+	addVotingConfigs(userInfo, rows)
+
+	// The UserAddress table only exists once the frontend of a version that
+	// supports additional voting addresses started, so its absence isn't
+	// fatal.
+	addressRows, err := db.Query("SELECT UserId, MultiSigAddress, VoteBits, VoteBitsVersion FROM UserAddress")
+	if err != nil {
+		log.Warnf("Unable to query additional voting addresses: %v", err)
+		err = db.Close()
+		return userInfo, err
+	}
+
+	defer addressRows.Close()
+	addVotingConfigs(userInfo, addressRows)
+
+	err = db.Close()
+	return userInfo, err
+}
+
+// votingConfigRows are the rows of a query of the user id, multisig address,
+// VoteBits and VoteBitsVersion of voting addresses.
+type votingConfigRows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+}
+
+// addVotingConfigs adds the voting preferences of rows to userInfo by the
+// multisig addresses they are for.
+func addVotingConfigs(userInfo map[string]UserVotingConfig, rows votingConfigRows) {
+	var (
+		userid          int64
+		multiSigAddress string
+		voteBits        int64
+		voteBitsVersion int64
+	)
 	for rows.Next() {
 		err := rows.Scan(&userid, &multiSigAddress, &voteBits, &voteBitsVersion)
 		if err != nil {
@@ -125,9 +156,6 @@ func (u *UserData) MySQLFetchUserVotingConfig() (map[string]UserVotingConfig, er
 			VoteBitsVersion: uint32(voteBitsVersion),
 		}
 	}
-
-	err = db.Close()
-	return userInfo, err
 }
 
 // DBSetConfig sets the database configuration.
@@ -0,0 +1,50 @@
+package userdata
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testRows are the rows of a voting preferences query.
+type testRows struct {
+	rows []UserVotingConfig
+	next int
+}
+
+func (r *testRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *testRows) Scan(dest ...interface{}) error {
+	row := r.rows[r.next-1]
+	*dest[0].(*int64) = row.Userid
+	*dest[1].(*string) = row.MultiSigAddress
+	*dest[2].(*int64) = int64(row.VoteBits)
+	*dest[3].(*int64) = int64(row.VoteBitsVersion)
+	return nil
+}
+
+func TestAddVotingConfigs(t *testing.T) {
+	users := &testRows{rows: []UserVotingConfig{
+		{Userid: 1, MultiSigAddress: "msa1", VoteBits: 1, VoteBitsVersion: 5},
+		{Userid: 2, MultiSigAddress: "msa2", VoteBits: 5, VoteBitsVersion: 5},
+	}}
+	addresses := &testRows{rows: []UserVotingConfig{
+		{Userid: 2, MultiSigAddress: "msa3", VoteBits: 3, VoteBitsVersion: 5},
+	}}
+
+	userInfo := map[string]UserVotingConfig{}
+	addVotingConfigs(userInfo, users)
+	addVotingConfigs(userInfo, addresses)
+
+	// The additional voting addresses are merged with the first ones.
+	want := map[string]UserVotingConfig{
+		"msa1": users.rows[0],
+		"msa2": users.rows[1],
+		"msa3": addresses.rows[0],
+	}
+	if !reflect.DeepEqual(userInfo, want) {
+		t.Errorf("voting preferences %v, want %v", userInfo, want)
+	}
+}
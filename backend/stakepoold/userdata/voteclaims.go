@@ -0,0 +1,148 @@
+package userdata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+)
+
+// voteClaimTimeout bounds the vote claim queries, which are on the voting
+// path.
+const voteClaimTimeout = 2 * time.Second
+
+// VoteClaimTTL is how long a claim on the vote of a ticket is kept from other
+// holders.  The holder may have crashed before voting when it didn't release
+// its claim by then, and the vote of a holder that sent it is rejected as a
+// duplicate when another holder votes again.  The age of the claims is
+// measured with the clock of the database server, so the clocks of the
+// holders don't need to agree.
+const VoteClaimTTL = 30 * time.Second
+
+// claimsDB returns the database of the vote claims.  Its connections are kept
+// open since the claims are on the voting path.
+func (u *UserData) claimsDB() (*sql.DB, error) {
+	u.Lock()
+	defer u.Unlock()
+
+	if u.voteClaimsDB != nil {
+		return u.voteClaimsDB, nil
+	}
+	db, err := sql.Open("mysql", fmt.Sprint(u.DBConfig.DBUser, ":", u.DBConfig.DBPassword, "@(", u.DBConfig.DBHost, ":", u.DBConfig.DBPort, ")/", u.DBConfig.DBName, "?charset=utf8mb4"))
+	if err != nil {
+		return nil, err
+	}
+	u.voteClaimsDB = db
+	return db, nil
+}
+
+// MySQLClaimVotes claims the votes of tickets on a block for holder in the
+// VoteClaim table, which redundant stakepoold instances voting with wallets
+// that hold the same scripts share, and returns the tickets other holders
+// claimed first along with their holders.  Claims on another block, which
+// must have been reorganized out, and claims older than VoteClaimTTL are
+// taken over.  The table is created by the frontend.
+func (u *UserData) MySQLClaimVotes(holder string, blockHash *chainhash.Hash,
+	blockHeight int64, tickets []*chainhash.Hash) (map[chainhash.Hash]string, error) {
+	if len(tickets) == 0 {
+		return nil, nil
+	}
+	db, err := u.claimsDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), voteClaimTimeout)
+	defer cancel()
+
+	values := make([]string, 0, len(tickets))
+	args := make([]interface{}, 0, 4*len(tickets)+2)
+	for _, ticket := range tickets {
+		values = append(values, "(?, ?, ?, ?, UNIX_TIMESTAMP())")
+		args = append(args, ticket.String(), blockHash.String(),
+			blockHeight, holder)
+	}
+	ttl := int64(VoteClaimTTL / time.Second)
+	args = append(args, ttl, ttl)
+
+	// MySQL assigns the columns in order, so Holder and Claimed are decided
+	// on before BlockHash changes.
+	_, err = db.ExecContext(ctx, "INSERT INTO VoteClaim "+
+		"(TicketHash, BlockHash, BlockHeight, Holder, Claimed) VALUES "+
+		strings.Join(values, ", ")+" ON DUPLICATE KEY UPDATE "+
+		"Holder = IF(BlockHash <> VALUES(BlockHash) OR "+
+		"Claimed < UNIX_TIMESTAMP() - ?, VALUES(Holder), Holder), "+
+		"Claimed = IF(BlockHash <> VALUES(BlockHash) OR "+
+		"Claimed < UNIX_TIMESTAMP() - ?, "+
+		"VALUES(Claimed), Claimed), "+
+		"BlockHeight = VALUES(BlockHeight), "+
+		"BlockHash = VALUES(BlockHash)", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT TicketHash, Holder FROM "+
+		"VoteClaim WHERE BlockHash = ? AND Holder <> ?", blockHash.String(),
+		holder)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wanted := make(map[chainhash.Hash]struct{}, len(tickets))
+	for _, ticket := range tickets {
+		wanted[*ticket] = struct{}{}
+	}
+	claimed := make(map[chainhash.Hash]string)
+	for rows.Next() {
+		var ticketHash, claimHolder string
+		if err := rows.Scan(&ticketHash, &claimHolder); err != nil {
+			return nil, err
+		}
+		ticket, err := chainhash.NewHashFromStr(ticketHash)
+		if err != nil {
+			log.Errorf("invalid vote claim of ticket %v: %v", ticketHash,
+				err)
+			continue
+		}
+		if _, ok := wanted[*ticket]; ok {
+			claimed[*ticket] = claimHolder
+		}
+	}
+	return claimed, rows.Err()
+}
+
+// MySQLReleaseVote gives up the claim of holder on the vote of ticket, which
+// failed, so another holder can vote it.
+func (u *UserData) MySQLReleaseVote(holder string, ticket *chainhash.Hash) error {
+	db, err := u.claimsDB()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), voteClaimTimeout)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, "DELETE FROM VoteClaim WHERE "+
+		"TicketHash = ? AND Holder = ?", ticket.String(), holder)
+	return err
+}
+
+// MySQLPruneVoteClaims deletes the claims on the votes on blocks below height,
+// which are too old to be voted on again.
+func (u *UserData) MySQLPruneVoteClaims(height int64) error {
+	db, err := u.claimsDB()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), voteClaimTimeout)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, "DELETE FROM VoteClaim WHERE "+
+		"BlockHeight < ?", height)
+	return err
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+)
+
+// sharedVoteClaims are the vote claims of this instance, named holder, in the
+// database the redundant instances share.
+type sharedVoteClaims struct {
+	userData *userdata.UserData
+	holder   string
+}
+
+var _ voting.SharedClaims = (*sharedVoteClaims)(nil)
+
+// ClaimVotes claims the votes of tickets on a block and returns the tickets
+// other instances claimed first.
+func (s *sharedVoteClaims) ClaimVotes(blockHash *chainhash.Hash,
+	blockHeight int64, tickets []*chainhash.Hash) (map[chainhash.Hash]string, error) {
+	return s.userData.MySQLClaimVotes(s.holder, blockHash, blockHeight,
+		tickets)
+}
+
+// ReleaseVote gives up the claim on the vote of ticket.
+func (s *sharedVoteClaims) ReleaseVote(ticket *chainhash.Hash) error {
+	return s.userData.MySQLReleaseVote(s.holder, ticket)
+}
+
+// PruneVotes forgets the claims on blocks below height.
+func (s *sharedVoteClaims) PruneVotes(height int64) error {
+	return s.userData.MySQLPruneVoteClaims(height)
+}
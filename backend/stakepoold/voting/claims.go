@@ -0,0 +1,141 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"sync"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+)
+
+// voteClaim is the block a ticket is being voted, or was voted, on.
+type voteClaim struct {
+	blockHash   chainhash.Hash
+	blockHeight int64
+}
+
+// SharedClaims are vote claims shared by redundant stakepoold instances that
+// vote with wallets holding the same scripts, so only one of them votes a
+// winning ticket.
+type SharedClaims interface {
+	// ClaimVotes claims the votes of tickets on a block and returns the
+	// tickets other instances claimed first along with their names.
+	ClaimVotes(blockHash *chainhash.Hash, blockHeight int64,
+		tickets []*chainhash.Hash) (map[chainhash.Hash]string, error)
+
+	// ReleaseVote gives up the claim on the vote of ticket.
+	ReleaseVote(ticket *chainhash.Hash) error
+
+	// PruneVotes forgets the claims on blocks below height.
+	PruneVotes(height int64) error
+}
+
+// VoteClaims makes sure a single vote is generated for a winning ticket on a
+// block, however often the ticket is reported as winning it, for example by
+// the winning tickets notification and the pending votes replayed after a
+// reconnect at the same time.  A vote holds its claim while it is sent and
+// after it made it to the network.  Failed votes release their claim so they
+// can be retried.  With shared claims the votes are also claimed from the
+// other instances voting the same tickets.  It is safe for concurrent access.
+type VoteClaims struct {
+	mtx    sync.Mutex
+	claims map[chainhash.Hash]voteClaim // [ticket]
+	shared SharedClaims
+}
+
+// NewVoteClaims returns a vote claim tracker without claims.
+func NewVoteClaims() *VoteClaims {
+	return &VoteClaims{
+		claims: make(map[chainhash.Hash]voteClaim),
+	}
+}
+
+// NewSharedVoteClaims returns a vote claim tracker without claims that also
+// claims the votes in shared.
+func NewSharedVoteClaims(shared SharedClaims) *VoteClaims {
+	c := NewVoteClaims()
+	c.shared = shared
+	return c
+}
+
+// ClaimShared claims the votes of tickets, which must have been claimed with
+// Claim, from the other instances and returns the tickets they claimed first
+// along with their names.  The tickets are voted anyway when the shared
+// claims are unavailable, since a vote rejected as a duplicate is cheaper
+// than a missed one.
+func (c *VoteClaims) ClaimShared(blockHash *chainhash.Hash, blockHeight int64,
+	tickets []*chainhash.Hash) map[chainhash.Hash]string {
+	if c.shared == nil || len(tickets) == 0 {
+		return nil
+	}
+	claimed, err := c.shared.ClaimVotes(blockHash, blockHeight, tickets)
+	if err != nil {
+		log.Warnf("unable to claim the votes on block %v (height %v) "+
+			"from the other instances, voting anyway: %v", blockHash,
+			blockHeight, err)
+		return nil
+	}
+	return claimed
+}
+
+// Claim claims the vote of ticket on a block.  It returns false when the vote
+// is claimed already.  A claim on another block, which must have been
+// reorganized out, is replaced.
+func (c *VoteClaims) Claim(ticket, blockHash *chainhash.Hash, blockHeight int64) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if claim, ok := c.claims[*ticket]; ok && claim.blockHash == *blockHash {
+		return false
+	}
+	c.claims[*ticket] = voteClaim{
+		blockHash:   *blockHash,
+		blockHeight: blockHeight,
+	}
+	return true
+}
+
+// Release gives up the claim of a vote that failed.
+func (c *VoteClaims) Release(ticket *chainhash.Hash) {
+	c.mtx.Lock()
+	delete(c.claims, *ticket)
+	c.mtx.Unlock()
+
+	if c.shared == nil {
+		return
+	}
+	if err := c.shared.ReleaseVote(ticket); err != nil {
+		log.Warnf("unable to release the shared claim on the vote of "+
+			"ticket %v: %v", ticket, err)
+	}
+}
+
+// Forget gives up the claim of a vote another instance claimed first, so the
+// vote can be claimed again when it is retried.  The shared claim is left
+// alone.
+func (c *VoteClaims) Forget(ticket *chainhash.Hash) {
+	c.mtx.Lock()
+	delete(c.claims, *ticket)
+	c.mtx.Unlock()
+}
+
+// Prune forgets the claims on blocks below height, which are too old to be
+// voted on again.
+func (c *VoteClaims) Prune(height int64) {
+	c.mtx.Lock()
+	for ticket, claim := range c.claims {
+		if claim.blockHeight < height {
+			delete(c.claims, ticket)
+		}
+	}
+	c.mtx.Unlock()
+
+	if c.shared == nil {
+		return
+	}
+	if err := c.shared.PruneVotes(height); err != nil {
+		log.Warnf("unable to prune the shared vote claims: %v", err)
+	}
+}
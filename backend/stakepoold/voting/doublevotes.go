@@ -0,0 +1,56 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"sync"
+
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+// DoubleVotes keeps the last size double votes of the pool tickets.  It is
+// safe for concurrent access.
+type DoubleVotes struct {
+	mtx   sync.Mutex
+	votes []*rpcserver.DoubleVote // ring buffer of the last size double votes
+	next  int
+}
+
+// NewDoubleVotes returns a record of the last size double votes.
+func NewDoubleVotes(size int) *DoubleVotes {
+	return &DoubleVotes{
+		votes: make([]*rpcserver.DoubleVote, 0, size),
+	}
+}
+
+// Add records a double vote, dropping the oldest one when the record is full.
+func (d *DoubleVotes) Add(v *rpcserver.DoubleVote) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if len(d.votes) < cap(d.votes) {
+		d.votes = append(d.votes, v)
+		return
+	}
+	d.votes[d.next] = v
+	d.next = (d.next + 1) % len(d.votes)
+}
+
+// Since returns the double votes at or above height in the order they were
+// added.
+func (d *DoubleVotes) Since(height int64) []*rpcserver.DoubleVote {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	var votes []*rpcserver.DoubleVote
+	for i := range d.votes {
+		v := d.votes[(d.next+i)%len(d.votes)]
+		if v.BlockHeight < height {
+			continue
+		}
+		votes = append(votes, v)
+	}
+	return votes
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"sync"
+
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+// VoteHistory keeps the last size events of the pool tickets for the frontend
+// to build their timelines from.  It is safe for concurrent access.
+type VoteHistory struct {
+	mtx    sync.Mutex
+	events []*rpcserver.VoteHistoryEvent // ring buffer of the last size events
+	next   int
+}
+
+// NewVoteHistory returns a history of the last size ticket events.
+func NewVoteHistory(size int) *VoteHistory {
+	return &VoteHistory{
+		events: make([]*rpcserver.VoteHistoryEvent, 0, size),
+	}
+}
+
+// Add records an event, dropping the oldest one when the history is full.
+func (h *VoteHistory) Add(e *rpcserver.VoteHistoryEvent) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if len(h.events) < cap(h.events) {
+		h.events = append(h.events, e)
+		return
+	}
+	h.events[h.next] = e
+	h.next = (h.next + 1) % len(h.events)
+}
+
+// Since returns the events at or above height in the order they were added.
+// Only the events of the multisig addresses in msas are returned unless it
+// is empty.
+func (h *VoteHistory) Since(height int64, msas []string) []*rpcserver.VoteHistoryEvent {
+	wanted := make(map[string]struct{}, len(msas))
+	for _, msa := range msas {
+		wanted[msa] = struct{}{}
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	var events []*rpcserver.VoteHistoryEvent
+	for i := range h.events {
+		e := h.events[(h.next+i)%len(h.events)]
+		if e.BlockHeight < height {
+			continue
+		}
+		if _, ok := wanted[e.MultiSigAddress]; len(wanted) != 0 && !ok {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events
+}
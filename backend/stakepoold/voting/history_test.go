@@ -0,0 +1,64 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+func TestVoteHistory(t *testing.T) {
+	h := NewVoteHistory(3)
+	if events := h.Since(0, nil); len(events) != 0 {
+		t.Fatalf("empty history returned %d events", len(events))
+	}
+
+	// Only the last 3 of heights 1..5 are kept, alternating between two
+	// users.
+	for height := int64(1); height <= 5; height++ {
+		msa := "msa1"
+		if height%2 == 0 {
+			msa = "msa2"
+		}
+		h.Add(&rpcserver.VoteHistoryEvent{
+			MultiSigAddress: msa,
+			Event:           rpcserver.VoteEventSelected,
+			BlockHeight:     height,
+		})
+	}
+
+	tests := []struct {
+		since   int64
+		msas    []string
+		heights []int64
+	}{
+		{0, nil, []int64{3, 4, 5}},
+		{4, nil, []int64{4, 5}},
+		{6, nil, nil},
+		{0, []string{"msa1"}, []int64{3, 5}},
+		{0, []string{"msa2"}, []int64{4}},
+		{0, []string{"msa3"}, nil},
+	}
+	for _, test := range tests {
+		events := h.Since(test.since, test.msas)
+		var heights []int64
+		for _, e := range events {
+			heights = append(heights, e.BlockHeight)
+		}
+		if len(heights) != len(test.heights) {
+			t.Errorf("since %d %v: heights %v, want %v", test.since,
+				test.msas, heights, test.heights)
+			continue
+		}
+		for i := range heights {
+			if heights[i] != test.heights[i] {
+				t.Errorf("since %d %v: heights %v, want %v",
+					test.since, test.msas, heights, test.heights)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+// VoteLatency keeps the time it took to vote the last windowSize winning
+// tickets, from the winning tickets notification to the vote being sent.  It
+// is safe for concurrent access.
+type VoteLatency struct {
+	mtx     sync.Mutex
+	votes   int64
+	samples []time.Duration // ring buffer of the last windowSize latencies
+	next    int
+}
+
+// NewVoteLatency returns a tracker of the latency of the last windowSize
+// votes.
+func NewVoteLatency(windowSize int) *VoteLatency {
+	return &VoteLatency{
+		samples: make([]time.Duration, 0, windowSize),
+	}
+}
+
+// Record adds the latency of a vote that was sent.
+func (l *VoteLatency) Record(latency time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.votes++
+	if len(l.samples) < cap(l.samples) {
+		l.samples = append(l.samples, latency)
+		return
+	}
+	l.samples[l.next] = latency
+	l.next = (l.next + 1) % len(l.samples)
+}
+
+// percentile returns the nearest-rank p-th percentile of the sorted
+// latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Snapshot returns the latency percentiles of the current window.
+func (l *VoteLatency) Snapshot() *rpcserver.VoteLatencyStats {
+	l.mtx.Lock()
+	sorted := make([]time.Duration, len(l.samples))
+	copy(sorted, l.samples)
+	stats := &rpcserver.VoteLatencyStats{
+		Votes:       l.votes,
+		WindowVotes: int64(len(sorted)),
+	}
+	l.mtx.Unlock()
+
+	if len(sorted) == 0 {
+		return stats
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	stats.P50 = percentile(sorted, 50)
+	stats.P90 = percentile(sorted, 90)
+	stats.P99 = percentile(sorted, 99)
+	stats.Max = sorted[len(sorted)-1]
+	return stats
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVoteLatency(t *testing.T) {
+	l := NewVoteLatency(100)
+	if s := l.Snapshot(); s.Votes != 0 || s.WindowVotes != 0 || s.Max != 0 {
+		t.Fatalf("empty snapshot %+v", s)
+	}
+
+	// Record 1ms..150ms.  Only the last 100, 51ms..150ms, are in the window.
+	for i := 1; i <= 150; i++ {
+		l.Record(time.Duration(i) * time.Millisecond)
+	}
+	s := l.Snapshot()
+	if s.Votes != 150 || s.WindowVotes != 100 {
+		t.Errorf("votes %d window %d, want 150 and 100", s.Votes,
+			s.WindowVotes)
+	}
+	tests := []struct {
+		name      string
+		got, want time.Duration
+	}{
+		{"p50", s.P50, 100 * time.Millisecond},
+		{"p90", s.P90, 140 * time.Millisecond},
+		{"p99", s.P99, 149 * time.Millisecond},
+		{"max", s.Max, 150 * time.Millisecond},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s = %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
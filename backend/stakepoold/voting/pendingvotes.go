@@ -0,0 +1,99 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"sync"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+)
+
+// PendingVote is a winning ticket that has not been voted yet.  The fields are
+// exported so pending votes can be saved with encoding/gob.
+type PendingVote struct {
+	BlockHash       chainhash.Hash
+	BlockHeight     int64
+	MultiSigAddress string
+}
+
+// PendingVotes tracks the winning tickets whose votes have not been sent
+// successfully, so votes that were cut off by a shutdown can be dealt with at
+// the next startup.  It is safe for concurrent access.
+type PendingVotes struct {
+	mtx   sync.Mutex
+	votes map[chainhash.Hash]PendingVote // [ticket]
+}
+
+// NewPendingVotes returns an empty pending vote tracker.
+func NewPendingVotes() *PendingVotes {
+	return &PendingVotes{
+		votes: make(map[chainhash.Hash]PendingVote),
+	}
+}
+
+// Add records a winning ticket whose vote is about to be sent.
+func (p *PendingVotes) Add(ticket *chainhash.Hash, v PendingVote) {
+	p.mtx.Lock()
+	p.votes[*ticket] = v
+	p.mtx.Unlock()
+}
+
+// Remove forgets a winning ticket whose vote was sent.
+func (p *PendingVotes) Remove(ticket *chainhash.Hash) {
+	p.mtx.Lock()
+	delete(p.votes, *ticket)
+	p.mtx.Unlock()
+}
+
+// Expire removes and returns the pending votes for blocks below height.
+func (p *PendingVotes) Expire(height int64) map[chainhash.Hash]PendingVote {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	expired := make(map[chainhash.Hash]PendingVote)
+	for ticket, v := range p.votes {
+		if v.BlockHeight < height {
+			expired[ticket] = v
+			delete(p.votes, ticket)
+		}
+	}
+	return expired
+}
+
+// Take removes and returns all pending votes.
+func (p *PendingVotes) Take() map[chainhash.Hash]PendingVote {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	votes := p.votes
+	p.votes = make(map[chainhash.Hash]PendingVote)
+	return votes
+}
+
+// VoteViable returns whether a vote on a block at blockHeight is still worth
+// sending with the chain tip at tipHeight.
+func VoteViable(blockHeight, tipHeight, maxVoteAge int64) bool {
+	age := tipHeight - blockHeight
+	return age >= 0 && age <= maxVoteAge
+}
+
+// Snapshot returns a copy of the pending votes so they can be saved.
+func (p *PendingVotes) Snapshot() map[chainhash.Hash]PendingVote {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	votes := make(map[chainhash.Hash]PendingVote, len(p.votes))
+	for ticket, v := range p.votes {
+		votes[ticket] = v
+	}
+	return votes
+}
+
+// Restore replaces the pending votes with saved ones.
+func (p *PendingVotes) Restore(votes map[chainhash.Hash]PendingVote) {
+	p.mtx.Lock()
+	p.votes = votes
+	p.mtx.Unlock()
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+// StakeDifficultyHistory keeps the stake difficulty of the last size stake
+// difficulty intervals of windowSize blocks.  It is safe for concurrent
+// access.
+type StakeDifficultyHistory struct {
+	mtx        sync.Mutex
+	windowSize int64
+	size       int
+	intervals  []*rpcserver.StakeDifficultyInterval // oldest first
+}
+
+// NewStakeDifficultyHistory returns a history of the stake difficulty of the
+// last size intervals of windowSize blocks.
+func NewStakeDifficultyHistory(windowSize int64, size int) *StakeDifficultyHistory {
+	return &StakeDifficultyHistory{
+		windowSize: windowSize,
+		size:       size,
+		intervals:  make([]*rpcserver.StakeDifficultyInterval, 0, size),
+	}
+}
+
+// StartHeight returns the height of the first block of the interval of
+// height.
+func (h *StakeDifficultyHistory) StartHeight(height int64) int64 {
+	return height - height%h.windowSize
+}
+
+// interval returns the stake difficulty interval of the block with header.
+func (h *StakeDifficultyHistory) interval(header *wire.BlockHeader) *rpcserver.StakeDifficultyInterval {
+	return &rpcserver.StakeDifficultyInterval{
+		StartHeight:     h.StartHeight(int64(header.Height)),
+		StakeDifficulty: header.SBits,
+		PoolSize:        header.PoolSize,
+		Time:            header.Timestamp,
+	}
+}
+
+// search returns the index of the first interval starting at or above
+// startHeight.
+//
+// This function MUST be called with the history lock held.
+func (h *StakeDifficultyHistory) search(startHeight int64) int {
+	return sort.Search(len(h.intervals), func(i int) bool {
+		return h.intervals[i].StartHeight >= startHeight
+	})
+}
+
+// ConnectBlock records the stake difficulty of the interval of a newly
+// connected block.  The intervals above it were reorganized out and are
+// forgotten.  The pool size is that of the last block connected in the
+// interval and the time that of the first.
+func (h *StakeDifficultyHistory) ConnectBlock(header *wire.BlockHeader) {
+	in := h.interval(header)
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	i := h.search(in.StartHeight)
+	if i < len(h.intervals) && h.intervals[i].StartHeight == in.StartHeight &&
+		h.intervals[i].Time.Before(in.Time) {
+		in.Time = h.intervals[i].Time
+	}
+	h.intervals = append(h.intervals[:i], in)
+	if len(h.intervals) > h.size {
+		h.intervals = append(h.intervals[:0], h.intervals[1:]...)
+	}
+}
+
+// AddBlock records the stake difficulty of the interval of an older block,
+// e.g. the first block of an interval stakepoold wasn't running for.  Nothing
+// is recorded when the interval is known already or older than every interval
+// of a full history.
+func (h *StakeDifficultyHistory) AddBlock(header *wire.BlockHeader) {
+	in := h.interval(header)
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	i := h.search(in.StartHeight)
+	if i < len(h.intervals) && h.intervals[i].StartHeight == in.StartHeight {
+		return
+	}
+	if len(h.intervals) == h.size {
+		if i == 0 {
+			return
+		}
+		h.intervals = append(h.intervals[:0], h.intervals[1:]...)
+		i--
+	}
+	h.intervals = append(h.intervals, nil)
+	copy(h.intervals[i+1:], h.intervals[i:])
+	h.intervals[i] = in
+}
+
+// Since returns the intervals starting at or above height, oldest first.
+func (h *StakeDifficultyHistory) Since(height int64) []*rpcserver.StakeDifficultyInterval {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	i := h.search(height)
+	intervals := make([]*rpcserver.StakeDifficultyInterval, len(h.intervals)-i)
+	copy(intervals, h.intervals[i:])
+	return intervals
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coolsnady/hcd/wire"
+)
+
+func TestStakeDifficultyHistory(t *testing.T) {
+	const window = 10
+	h := NewStakeDifficultyHistory(window, 3)
+	header := func(height uint32, sbits int64) *wire.BlockHeader {
+		return &wire.BlockHeader{
+			Height:    height,
+			SBits:     sbits,
+			PoolSize:  height,
+			Timestamp: time.Unix(int64(height), 0),
+		}
+	}
+	check := func(name string, since int64, starts []int64, sbits []int64) {
+		intervals := h.Since(since)
+		if len(intervals) != len(starts) {
+			t.Fatalf("%s: %d intervals, want %d", name, len(intervals),
+				len(starts))
+		}
+		for i, in := range intervals {
+			if in.StartHeight != starts[i] || in.StakeDifficulty != sbits[i] {
+				t.Errorf("%s: interval %d starts at %d with %d, want %d "+
+					"with %d", name, i, in.StartHeight, in.StakeDifficulty,
+					starts[i], sbits[i])
+			}
+		}
+	}
+
+	// Blocks of the same interval keep the time of the first and the pool
+	// size of the last.
+	h.ConnectBlock(header(25, 200))
+	h.ConnectBlock(header(26, 200))
+	check("connected", 0, []int64{20}, []int64{200})
+	if in := h.Since(0)[0]; in.PoolSize != 26 || in.Time.Unix() != 25 {
+		t.Errorf("pool size %d time %d, want 26 25", in.PoolSize,
+			in.Time.Unix())
+	}
+
+	// Older intervals are filled in, but not replaced.
+	h.AddBlock(header(10, 100))
+	h.AddBlock(header(20, 999))
+	check("added", 0, []int64{10, 20}, []int64{100, 200})
+
+	h.ConnectBlock(header(30, 300))
+	h.ConnectBlock(header(40, 400))
+	check("full", 0, []int64{20, 30, 40}, []int64{200, 300, 400})
+	h.AddBlock(header(10, 100))
+	check("older than a full history", 0, []int64{20, 30, 40},
+		[]int64{200, 300, 400})
+
+	// A reorganization back into an older interval forgets the newer ones.
+	h.ConnectBlock(header(35, 350))
+	check("reorganized", 0, []int64{20, 30}, []int64{200, 350})
+	check("since", 21, []int64{30}, []int64{350})
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"sync"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+// blockStats is what the rolling pool statistics keep for each block.
+type blockStats struct {
+	hash       chainhash.Hash
+	connected  bool
+	poolSize   uint32
+	sbits      int64
+	voters     uint16 // votes on this block, from the header of its child
+	votesKnown bool   // voters is set
+	poolVotes  int    // votes the pool cast on this block
+	poolMisses int    // pool tickets hcd reported missed in this block
+}
+
+// Stats maintains rolling pool statistics over the last windowSize blocks
+// along with the number of missed pool tickets since it was created.  It is
+// safe for concurrent access.
+type Stats struct {
+	mtx         sync.Mutex
+	windowSize  int64
+	tipHeight   int64
+	tipHash     chainhash.Hash
+	blocks      map[int64]*blockStats // [height]
+	totalMisses int64
+}
+
+// NewStats returns statistics over windows of windowSize blocks.
+func NewStats(windowSize int64) *Stats {
+	return &Stats{
+		windowSize: windowSize,
+		blocks:     make(map[int64]*blockStats),
+	}
+}
+
+// block returns the stats for the block, replacing stats kept for another
+// block at the same height.
+//
+// This function MUST be called with the stats lock held.
+func (s *Stats) block(hash *chainhash.Hash, height int64) *blockStats {
+	b, ok := s.blocks[height]
+	if !ok || b.hash != *hash {
+		b = &blockStats{hash: *hash}
+		s.blocks[height] = b
+	}
+	return b
+}
+
+// ConnectBlock records a newly connected block and makes it the tip.
+func (s *Stats) ConnectBlock(header *wire.BlockHeader) {
+	hash := header.BlockHash()
+	height := int64(header.Height)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	b := s.block(&hash, height)
+	b.connected = true
+	b.poolSize = header.PoolSize
+	b.sbits = header.SBits
+
+	// The votes included in a block vote on its parent, which is also the
+	// block AddPoolVotes records the votes of the parent's winning tickets
+	// for.
+	if height > 0 {
+		parent := s.block(&header.PrevBlock, height-1)
+		parent.voters = header.Voters
+		parent.votesKnown = true
+	}
+
+	s.tipHash = hash
+	s.tipHeight = height
+	for h := range s.blocks {
+		if h <= height-s.windowSize || h > height {
+			delete(s.blocks, h)
+		}
+	}
+}
+
+// AddPoolVotes records the number of votes the pool cast on a block, that is
+// the votes of its winning tickets.
+func (s *Stats) AddPoolVotes(hash *chainhash.Hash, height int64, votes int) {
+	s.mtx.Lock()
+	s.block(hash, height).poolVotes += votes
+	s.mtx.Unlock()
+}
+
+// AddPoolMisses records the number of pool tickets hcd reported missed in a
+// block.  Tickets that expired are reported as missed too.
+func (s *Stats) AddPoolMisses(hash *chainhash.Hash, height int64, misses int) {
+	s.mtx.Lock()
+	s.block(hash, height).poolMisses += misses
+	s.totalMisses += int64(misses)
+	s.mtx.Unlock()
+}
+
+// Snapshot returns the statistics for the current window.
+func (s *Stats) Snapshot() *rpcserver.PoolStats {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	stats := &rpcserver.PoolStats{
+		BlockHash:   s.tipHash,
+		BlockHeight: s.tipHeight,
+		WindowSize:  s.windowSize,
+		TotalMisses: s.totalMisses,
+	}
+	if tip, ok := s.blocks[s.tipHeight]; ok {
+		stats.PoolSize = tip.poolSize
+		stats.StakeDifficulty = tip.sbits
+	}
+	// The votes are only compared for blocks whose votes are known, which
+	// leaves out the tip until its child is connected.
+	for _, b := range s.blocks {
+		if b.connected {
+			stats.PoolMisses += int64(b.poolMisses)
+		}
+		if !b.votesKnown {
+			continue
+		}
+		stats.WindowBlocks++
+		stats.NetworkVotes += int64(b.voters)
+		stats.PoolVotes += int64(b.poolVotes)
+	}
+	return stats
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/wire"
+)
+
+func TestStatsVotePairing(t *testing.T) {
+	s := NewStats(10)
+
+	// The pool casts height votes on each block, and each header counts
+	// the votes on its parent.
+	var prev chainhash.Hash
+	connect := func(height uint32, voters uint16) {
+		header := &wire.BlockHeader{
+			PrevBlock: prev,
+			Height:    height,
+			Voters:    voters,
+		}
+		s.ConnectBlock(header)
+		prev = header.BlockHash()
+		s.AddPoolVotes(&prev, int64(height), int(height))
+	}
+	connect(1, 5)
+	connect(2, 4)
+	connect(3, 3)
+
+	// The 0+1+2 pool votes on blocks 0 to 2 are compared with the 5+4+3
+	// votes included in blocks 1 to 3.  The votes on the tip aren't known
+	// yet.
+	stats := s.Snapshot()
+	if stats.WindowBlocks != 3 || stats.PoolVotes != 3 ||
+		stats.NetworkVotes != 12 {
+		t.Fatalf("%d of %d votes in %d blocks, want 3 of 12 in 3 blocks",
+			stats.PoolVotes, stats.NetworkVotes, stats.WindowBlocks)
+	}
+
+	// Connecting a child pairs the votes on the old tip.
+	connect(4, 2)
+	stats = s.Snapshot()
+	if stats.WindowBlocks != 4 || stats.PoolVotes != 6 ||
+		stats.NetworkVotes != 14 {
+		t.Fatalf("%d of %d votes in %d blocks, want 6 of 14 in 4 blocks",
+			stats.PoolVotes, stats.NetworkVotes, stats.WindowBlocks)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2017 The Decred developers
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package voting implements the stakepoold voting engine: validating the fees
+// of stake pool tickets, choosing the vote bits of winning tickets, generating
+// and sending their votes through the wallet and node it is given, tracking
+// the votes that have not been sent yet and the rolling pool statistics.  It
+// can be embedded by other programs that vote for a stake pool.  Deciding
+// which winning tickets to vote, which depends on the leader election and
+// fork checks of stakepoold, is left to the program.
+package voting
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/coolsnady/hcd/blockchain/stake"
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcutil"
+	"github.com/coolsnady/hcutil/hdkeychain"
+	"github.com/coolsnady/hcwallet/wallet/txrules"
+	"github.com/coolsnady/hcwallet/wallet/udb"
+)
+
+// CalculateFeeAddresses decodes the string of stake pool payment addresses
+// to search incoming tickets for. The format for the passed string is:
+// "xpub...:end"
+// where xpub... is the extended public key and end is the last
+// address index to scan to, exclusive. Effectively, it returns the derived
+// addresses for this public key for the address indexes [0,end). The branch
+// used for the derivation is always the external branch.
+func CalculateFeeAddresses(xpubStr string, params *chaincfg.Params) (map[string]struct{}, error) {
+	end := uint32(10000)
+
+	log.Infof("Please wait, deriving %v stake pool fees addresses "+
+		"for extended public key %s", end, xpubStr)
+
+	// Parse the extended public key and ensure it's the right network.
+	key, err := hdkeychain.NewKeyFromString(xpubStr)
+	if err != nil {
+		return nil, err
+	}
+	if !key.IsForNet(params) {
+		return nil, fmt.Errorf("extended public key is for wrong network")
+	}
+
+	// Derive from external branch
+	branchKey, err := key.Child(udb.ExternalBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Derive the addresses from [0, end) for this extended public key.
+	// deriveChildAddresses takes the start index and the count.
+	addrs, err := deriveChildAddresses(branchKey, 0, end, params)
+	if err != nil {
+		return nil, err
+	}
+
+	addrMap := make(map[string]struct{})
+	for i := range addrs {
+		addrMap[addrs[i].EncodeAddress()] = struct{}{}
+	}
+
+	return addrMap, nil
+}
+
+// FeeAddress returns the stake pool fee address with index derived from the
+// external branch of the extended public key, which is one of the addresses
+// CalculateFeeAddresses returns for it.
+func FeeAddress(key *hdkeychain.ExtendedKey, index uint32, params *chaincfg.Params) (hcutil.Address, error) {
+	branchKey, err := key.Child(udb.ExternalBranch)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := deriveChildAddresses(branchKey, index, 1, params)
+	if err != nil {
+		return nil, err
+	}
+	return addrs[0], nil
+}
+
+func deriveChildAddresses(key *hdkeychain.ExtendedKey, startIndex, count uint32, params *chaincfg.Params) ([]hcutil.Address, error) {
+	addresses := make([]hcutil.Address, 0, count)
+	for i := uint32(0); i < count; {
+		child, err := key.Child(startIndex + i)
+		if err == hdkeychain.ErrInvalidChild {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		addrType := uint8(0)
+		if key.GetAlgType() == 4 {
+			addrType = 1
+		}
+		addr, err := child.Address(params, addrType)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, addr)
+		i++
+	}
+	return addresses, nil
+}
+
+// EvaluateStakePoolTicket evaluates a stake pool ticket to see if it's
+// acceptable to the stake pool. The ticket must pay out to one of the stake
+// pool fee addresses in feeAddrs, and must have a sufficient fee.
+func EvaluateStakePoolTicket(tx *wire.MsgTx, blockHeight int32,
+	feeAddrs map[string]struct{}, poolFees float64,
+	params *chaincfg.Params) (bool, error) {
+	// Check the first commitment output (txOuts[1])
+	// and ensure that the address found there exists
+	// in the list of approved addresses. Also ensure
+	// that the fee exists and is of the amount
+	// requested by the pool.
+	commitmentOut := tx.TxOut[1]
+	commitAddr, err := stake.AddrFromSStxPkScrCommitment(
+		commitmentOut.PkScript, params)
+	if err != nil {
+		return false, fmt.Errorf("Failed to parse commit out addr: %s",
+			err.Error())
+	}
+
+	// Extract the fee from the ticket.
+	in := hcutil.Amount(0)
+	for i := range tx.TxOut {
+		if i%2 != 0 {
+			commitAmt, err := stake.AmountFromSStxPkScrCommitment(
+				tx.TxOut[i].PkScript)
+			if err != nil {
+				return false, fmt.Errorf("Failed to parse commit "+
+					"out amt for commit in vout %v: %s", i, err.Error())
+			}
+			in += commitAmt
+		}
+	}
+	out := hcutil.Amount(0)
+	for i := range tx.TxOut {
+		out += hcutil.Amount(tx.TxOut[i].Value)
+	}
+	fees := in - out
+
+	_, exists := feeAddrs[commitAddr.EncodeAddress()]
+	if exists {
+		commitAmt, err := stake.AmountFromSStxPkScrCommitment(
+			commitmentOut.PkScript)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse commit "+
+				"out amt: %s", err.Error())
+		}
+
+		// Calculate the fee required based on the current
+		// height and the required amount from the pool.
+		feeNeeded := txrules.StakePoolTicketFee(hcutil.Amount(
+			tx.TxOut[0].Value), fees, blockHeight, poolFees,
+			params)
+		if commitAmt < feeNeeded {
+			log.Warnf("User %s submitted ticket %v which "+
+				"has less fees than are required to use this "+
+				"stake pool and is being skipped (required: %v"+
+				", found %v)", commitAddr.EncodeAddress(),
+				tx.TxHash(), feeNeeded, commitAmt)
+
+			// Reject the entire transaction if it didn't
+			// pay the pool server fees.
+			return false, nil
+		}
+	} else {
+		log.Warnf("Unknown pool commitment address %s for ticket %v",
+			commitAddr.EncodeAddress(), tx.TxHash())
+		return false, nil
+	}
+
+	log.Debugf("Accepted valid stake pool ticket %v committing %v in fees",
+		tx.TxHash(), tx.TxOut[0].Value)
+
+	return true, nil
+}
+
+// MsgTxFromHex returns a wire.MsgTx struct built from the transaction hex string
+func MsgTxFromHex(txhex string) *wire.MsgTx {
+	txBytes, err := hex.DecodeString(txhex)
+	if err != nil {
+		log.Warnf("DecodeString failed for %v: %v", txhex, err)
+		return nil
+	}
+	msgTx := wire.NewMsgTx()
+	if err = msgTx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		log.Warnf("Deserialize failed for %v: %v", txBytes, err)
+		return nil
+	}
+	return msgTx
+}
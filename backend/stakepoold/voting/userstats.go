@@ -0,0 +1,122 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"sync"
+
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcserver"
+)
+
+// UserVoteStats are the votes and misses of the tickets of a pool user and
+// the total reward, in atoms, of the votes.  Fallbacks are the winning tickets
+// voted with the default vote bits for lack of valid voting preferences.  The
+// fields are exported so the statistics can be saved with encoding/gob.
+type UserVoteStats struct {
+	Votes     int64
+	Misses    int64
+	Reward    int64
+	Fallbacks int64
+}
+
+// UserStats keeps the voting statistics of every pool user.  It is safe for
+// concurrent access.
+type UserStats struct {
+	mtx   sync.Mutex
+	users map[string]*UserVoteStats // [multisigaddr]
+}
+
+// NewUserStats returns empty user voting statistics.
+func NewUserStats() *UserStats {
+	return &UserStats{
+		users: make(map[string]*UserVoteStats),
+	}
+}
+
+// user returns the statistics of msa, adding them if needed.
+//
+// This function MUST be called with the stats lock held.
+func (s *UserStats) user(msa string) *UserVoteStats {
+	u, ok := s.users[msa]
+	if !ok {
+		u = &UserVoteStats{}
+		s.users[msa] = u
+	}
+	return u
+}
+
+// AddVote records a vote of a ticket of msa with a reward in atoms.
+func (s *UserStats) AddVote(msa string, reward int64) {
+	s.mtx.Lock()
+	u := s.user(msa)
+	u.Votes++
+	u.Reward += reward
+	s.mtx.Unlock()
+}
+
+// AddMiss records a missed vote of a ticket of msa.
+func (s *UserStats) AddMiss(msa string) {
+	s.mtx.Lock()
+	s.user(msa).Misses++
+	s.mtx.Unlock()
+}
+
+// AddFallback records that a winning ticket of msa is voted with the default
+// vote bits.
+func (s *UserStats) AddFallback(msa string) {
+	s.mtx.Lock()
+	s.user(msa).Fallbacks++
+	s.mtx.Unlock()
+}
+
+// Get returns the statistics of the users in msas, or of every user when msas
+// is empty.  Users without votes or misses are reported with zero counts.
+func (s *UserStats) Get(msas []string) []*rpcserver.UserVotingStats {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if len(msas) == 0 {
+		msas = make([]string, 0, len(s.users))
+		for msa := range s.users {
+			msas = append(msas, msa)
+		}
+	}
+	stats := make([]*rpcserver.UserVotingStats, 0, len(msas))
+	for _, msa := range msas {
+		userStats := &rpcserver.UserVotingStats{MultiSigAddress: msa}
+		if u, ok := s.users[msa]; ok {
+			userStats.Votes = u.Votes
+			userStats.Misses = u.Misses
+			userStats.Reward = u.Reward
+			userStats.Fallbacks = u.Fallbacks
+		}
+		stats = append(stats, userStats)
+	}
+	return stats
+}
+
+// Snapshot returns a copy of the statistics of every user.
+func (s *UserStats) Snapshot() map[string]UserVoteStats {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	users := make(map[string]UserVoteStats, len(s.users))
+	for msa, u := range s.users {
+		users[msa] = *u
+	}
+	return users
+}
+
+// Restore replaces the statistics with previously saved ones.
+func (s *UserStats) Restore(users map[string]UserVoteStats) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.users = make(map[string]*UserVoteStats, len(users))
+	for msa, u := range users {
+		u := u
+		s.users[msa] = &u
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"github.com/coolsnady/hcd/chaincfg"
+)
+
+// ValidVoteBits returns whether voteBits are valid for the agendas of vote
+// version: the previous block is voted valid, every agenda gets one of its
+// choices and no bits outside of the agendas are set.  The vote bits of vote
+// versions params doesn't know, and any vote bits without params, are valid.
+func ValidVoteBits(params *chaincfg.Params, version uint32, voteBits uint16) bool {
+	if params == nil || params.Deployments == nil {
+		return true
+	}
+	deployments, ok := params.Deployments[version]
+	if !ok {
+		return true
+	}
+	if voteBits&1 == 0 {
+		return false
+	}
+
+	usedBits := uint16(1)
+	for i := range deployments {
+		vote := &deployments[i].Vote
+		masked := voteBits & vote.Mask
+		var valid bool
+		for _, choice := range vote.Choices {
+			usedBits |= choice.Bits
+			if masked == choice.Bits {
+				valid = true
+			}
+		}
+		if !valid {
+			return false
+		}
+	}
+	return voteBits&^usedBits == 0
+}
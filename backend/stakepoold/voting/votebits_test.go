@@ -0,0 +1,52 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+)
+
+func TestValidVoteBits(t *testing.T) {
+	params := &chaincfg.Params{
+		Deployments: map[uint32][]chaincfg.ConsensusDeployment{
+			5: {{
+				Vote: chaincfg.Vote{
+					Id:   "agenda",
+					Mask: 0x0006,
+					Choices: []chaincfg.Choice{
+						{Id: "abstain", Bits: 0x0000, IsAbstain: true},
+						{Id: "no", Bits: 0x0002, IsNo: true},
+						{Id: "yes", Bits: 0x0004},
+					},
+				},
+			}},
+		},
+	}
+
+	tests := []struct {
+		version  uint32
+		voteBits uint16
+		valid    bool
+	}{
+		{5, 0x0001, true},
+		{5, 0x0005, true},
+		{5, 0x0007, false}, // not a choice of the agenda
+		{5, 0x0004, false}, // previous block invalid
+		{5, 0x0009, false}, // bit outside of the agendas
+		{6, 0x0009, true},  // unknown vote version
+	}
+	for _, test := range tests {
+		valid := ValidVoteBits(params, test.version, test.voteBits)
+		if valid != test.valid {
+			t.Errorf("vote bits %#04x of version %d: valid %v, want %v",
+				test.voteBits, test.version, valid, test.valid)
+		}
+	}
+	if !ValidVoteBits(nil, 5, 0x0007) {
+		t.Error("vote bits invalid without params")
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2017 The Decred developers
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+)
+
+// Errors hcd returns for a vote of a ticket that was voted already.
+const (
+	errDuplicateVote   = "-32603: already have transaction "
+	errVoteDoubleSpend = "already spent by transaction "
+)
+
+// Vote is the vote of a winning ticket with the vote bits it is voted with.
+// VoteAll fills in the results.
+type Vote struct {
+	MultiSigAddress string
+	Ticket          *chainhash.Hash
+	VoteBits        uint16
+
+	VoteHash     *chainhash.Hash // hash of the vote generated, even if it was rejected
+	Txid         *chainhash.Hash // hash of the vote sent
+	Reward       int64           // vote reward in atoms
+	Err          error
+	Duration     time.Duration // overall vote duration
+	SignDuration time.Duration // time to generatevote
+	SendDuration time.Duration // time to sendrawtransaction
+	Latency      time.Duration // winning tickets notification to vote sent
+}
+
+// Preferences are the voting preferences of a user.
+type Preferences struct {
+	VoteBitsVersion uint32
+	VoteBits        uint16
+}
+
+// SelectVoteBits returns the vote bits to vote a ticket of a user with on vote
+// version.  Users without valid preferences for the vote version, including
+// users without preferences, whose prefs are nil, vote with defaultBits, in
+// which case the reason is returned as well.
+func SelectVoteBits(params *chaincfg.Params, version uint32, defaultBits uint16,
+	prefs *Preferences) (uint16, string) {
+	switch {
+	case prefs == nil:
+		return defaultBits, "has no voting preferences"
+	case prefs.VoteBitsVersion != version:
+		return defaultBits, fmt.Sprintf("has voting preferences for vote "+
+			"version %v instead of %v", prefs.VoteBitsVersion, version)
+	case !ValidVoteBits(params, version, prefs.VoteBits):
+		return defaultBits, fmt.Sprintf("has invalid vote bits %d for "+
+			"vote version %v", prefs.VoteBits, version)
+	}
+	return prefs.VoteBits, ""
+}
+
+// IsDuplicateVote returns whether err reports that the ticket was voted
+// already, either with the same vote or, when redundant wallets hold the same
+// scripts, with another wallet's vote that made it to the network first.
+func IsDuplicateVote(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, errDuplicateVote) ||
+		strings.Contains(msg, errVoteDoubleSpend)
+}
+
+// DuplicateVoteTxid returns the hash of the vote a duplicate vote error
+// names, or nil if it doesn't name one.
+func DuplicateVoteTxid(err error) *chainhash.Hash {
+	msg := err.Error()
+	for _, prefix := range []string{errDuplicateVote, errVoteDoubleSpend} {
+		i := strings.Index(msg, prefix)
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(msg[i+len(prefix):])
+		if len(fields) == 0 {
+			return nil
+		}
+		txid, _ := chainhash.NewHashFromStr(fields[0])
+		return txid
+	}
+	return nil
+}
+
+// Voter generates the votes of winning tickets with a wallet and sends them to
+// the network through a node.  The wallet and node are looked up for every
+// block so they can be replaced while reconnecting.
+type Voter struct {
+	wallet  func() rpcclient.WalletSource
+	node    func() rpcclient.ChainSource
+	workers int
+}
+
+// NewVoter returns a voter that sends at most workers votes at once, or all
+// votes of a block at once when workers is 0.
+func NewVoter(wallet func() rpcclient.WalletSource,
+	node func() rpcclient.ChainSource, workers int) *Voter {
+	return &Voter{
+		wallet:  wallet,
+		node:    node,
+		workers: workers,
+	}
+}
+
+// VoteAll sends the votes on a block concurrently, with at most the workers of
+// the voter in flight, and waits for all of them.  Blocks rarely select more
+// pool tickets than there are workers, but bounding them keeps a block with
+// many winners from flooding the wallet.  received is when the winning
+// tickets notification arrived.
+func (v *Voter) VoteAll(blockHash *chainhash.Hash, blockHeight int64,
+	received time.Time, voteBitsExt string, votes []*Vote) {
+	workers := v.workers
+	if workers <= 0 || workers > len(votes) {
+		workers = len(votes)
+	}
+
+	var wg sync.WaitGroup // wait group for vote exits
+	queue := make(chan *Vote)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for w := range queue {
+				log.Debugf("calling GenerateVote with blockHash %v "+
+					"blockHeight %v ticket %v VoteBits %v "+
+					"VoteBitsExtended %v ", blockHash, blockHeight,
+					w.Ticket, w.VoteBits, voteBitsExt)
+				v.vote(&wg, blockHash, blockHeight, received,
+					voteBitsExt, w)
+			}
+		}()
+	}
+	for _, w := range votes {
+		wg.Add(1)
+		queue <- w
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// vote generates a vote and sends it off to the network.  It is run by the
+// workers of VoteAll.
+func (v *Voter) vote(wg *sync.WaitGroup, blockHash *chainhash.Hash,
+	blockHeight int64, received time.Time, voteBitsExt string, w *Vote) {
+	start := time.Now()
+
+	defer func() {
+		w.Duration = time.Since(start)
+		wg.Done()
+	}()
+
+	// Ask wallet to generate vote result.
+	var res *dcrjson.GenerateVoteResult
+	res, w.Err = v.wallet().GenerateVote(blockHash, blockHeight,
+		w.Ticket, w.VoteBits, voteBitsExt)
+	if w.Err != nil || res.Hex == "" {
+		return
+	}
+	w.SignDuration = time.Since(start)
+
+	// Create raw transaction.
+	var buf []byte
+	buf, w.Err = hex.DecodeString(res.Hex)
+	if w.Err != nil {
+		return
+	}
+	newTx := wire.NewMsgTx()
+	w.Err = newTx.FromBytes(buf)
+	if w.Err != nil {
+		return
+	}
+	voteHash := newTx.TxHash()
+	w.VoteHash = &voteHash
+
+	// The stakebase input of a vote holds the vote reward.
+	if len(newTx.TxIn) != 0 {
+		w.Reward = newTx.TxIn[0].ValueIn
+	}
+
+	// Ask node to transmit raw transaction.
+	startSend := time.Now()
+	tx, err := v.node().SendRawTransaction(newTx, false)
+	if err != nil {
+		log.Infof("vote err %v", err)
+		w.Err = err
+	} else {
+		w.Txid = tx
+		w.Latency = time.Since(received)
+	}
+	w.SendDuration = time.Since(startSend)
+}
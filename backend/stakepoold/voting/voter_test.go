@@ -0,0 +1,141 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package voting
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+)
+
+// concurrencyWallet records the largest number of votes generated at once.
+type concurrencyWallet struct {
+	rpcclient.WalletSource
+
+	mtx         sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (w *concurrencyWallet) GenerateVote(blockHash *chainhash.Hash,
+	height int64, sstxHash *chainhash.Hash, voteBits uint16,
+	voteBitsExt string) (*dcrjson.GenerateVoteResult, error) {
+	w.mtx.Lock()
+	w.inFlight++
+	if w.inFlight > w.maxInFlight {
+		w.maxInFlight = w.inFlight
+	}
+	w.mtx.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	w.mtx.Lock()
+	w.inFlight--
+	w.mtx.Unlock()
+	return w.WalletSource.GenerateVote(blockHash, height, sstxHash,
+		voteBits, voteBitsExt)
+}
+
+func TestVoteAllBounded(t *testing.T) {
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	wallet := &concurrencyWallet{
+		WalletSource: rpcclienttest.NewWallet(dcrjson.WalletInfoResult{}),
+	}
+	voter := NewVoter(func() rpcclient.WalletSource { return wallet },
+		func() rpcclient.ChainSource { return node }, 2)
+
+	votes := make([]*Vote, 5)
+	for i := range votes {
+		votes[i] = &Vote{Ticket: &chainhash.Hash{byte(i)}, VoteBits: 1}
+	}
+	voter.VoteAll(&chainhash.Hash{100}, 100, time.Now(), "", votes)
+
+	if sent := node.Sent(); len(sent) != len(votes) {
+		t.Errorf("expected %d votes to be sent, got %d", len(votes),
+			len(sent))
+	}
+	if wallet.maxInFlight > 2 {
+		t.Errorf("expected at most 2 votes to be generated at once, "+
+			"got %d", wallet.maxInFlight)
+	}
+	for _, v := range votes {
+		if v.Err != nil || v.Txid == nil || v.VoteHash == nil ||
+			v.Reward != rpcclienttest.VoteReward {
+			t.Errorf("unexpected vote result %+v", v)
+		}
+	}
+}
+
+func TestSelectVoteBits(t *testing.T) {
+	params := &chaincfg.Params{
+		Deployments: map[uint32][]chaincfg.ConsensusDeployment{
+			5: {{
+				Vote: chaincfg.Vote{
+					Id:   "agenda",
+					Mask: 0x0006,
+					Choices: []chaincfg.Choice{
+						{Id: "abstain", Bits: 0x0000},
+						{Id: "no", Bits: 0x0002},
+						{Id: "yes", Bits: 0x0004},
+					},
+				},
+			}},
+		},
+	}
+
+	tests := []struct {
+		prefs    *Preferences
+		bits     uint16
+		fallback bool
+	}{
+		{prefs: &Preferences{VoteBitsVersion: 5, VoteBits: 5}, bits: 5},
+		{prefs: nil, bits: 1, fallback: true},
+		{prefs: &Preferences{VoteBitsVersion: 4, VoteBits: 5}, bits: 1,
+			fallback: true},
+		{prefs: &Preferences{VoteBitsVersion: 5, VoteBits: 7}, bits: 1,
+			fallback: true},
+	}
+	for i, test := range tests {
+		bits, fallback := SelectVoteBits(params, 5, 1, test.prefs)
+		if bits != test.bits || (fallback != "") != test.fallback {
+			t.Errorf("%d: got bits %d fallback %q, want bits %d "+
+				"fallback %v", i, bits, fallback, test.bits,
+				test.fallback)
+		}
+	}
+}
+
+func TestDuplicateVote(t *testing.T) {
+	const txid = "0000000000000000000000000000000000000000000000000000000000000003"
+	tests := []struct {
+		err       error
+		duplicate bool
+		txid      bool
+	}{
+		{errors.New("-32603: already have transaction " + txid), true, true},
+		{errors.New("-26: rejected transaction: output " +
+			"0000000000000000000000000000000000000000000000000000000000000002:0 " +
+			"already spent by transaction " + txid + " in the memory pool"),
+			true, true},
+		{errors.New("-32603: already have transaction "), true, false},
+		{errors.New("wallet locked"), false, false},
+	}
+	for i, test := range tests {
+		if IsDuplicateVote(test.err) != test.duplicate {
+			t.Errorf("%d: expected duplicate %v", i, test.duplicate)
+		}
+		got := DuplicateVoteTxid(test.err)
+		if (got != nil) != test.txid || (got != nil && got.String() != txid) {
+			t.Errorf("%d: unexpected txid %v", i, got)
+		}
+	}
+}
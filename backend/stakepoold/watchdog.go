@@ -0,0 +1,267 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient"
+)
+
+const (
+	// watchdogInterval is how often the hcd and hcwallet connections are
+	// checked.
+	watchdogInterval = 10 * time.Second
+
+	// reconnectMinBackoff and reconnectMaxBackoff bound the time between
+	// reconnection attempts.
+	reconnectMinBackoff = time.Second
+	reconnectMaxBackoff = 5 * time.Minute
+)
+
+// reconnectBackoff returns how long to wait after failed reconnection attempt
+// number attempt, starting at 0.  The wait doubles with every attempt up to
+// reconnectMaxBackoff and up to half of it is taken off at random by jitter,
+// which returns a number in [0,n) like rand.Int63n, so that stakepoold
+// instances sharing a node don't reconnect in lockstep.
+func reconnectBackoff(attempt int, jitter func(n int64) int64) time.Duration {
+	backoff := reconnectMaxBackoff
+	if attempt < 32 {
+		backoff = reconnectMinBackoff << uint(attempt)
+		if backoff > reconnectMaxBackoff || backoff <= 0 {
+			backoff = reconnectMaxBackoff
+		}
+	}
+	return backoff - time.Duration(jitter(int64(backoff/2)+1))
+}
+
+// node returns the hcd connection.
+func (ctx *appContext) node() rpcclient.ChainSource {
+	ctx.connMtx.RLock()
+	defer ctx.connMtx.RUnlock()
+	return ctx.nodeConnection
+}
+
+// wallet returns the hcwallet connection.
+func (ctx *appContext) wallet() rpcclient.WalletSource {
+	ctx.connMtx.RLock()
+	defer ctx.connMtx.RUnlock()
+	return ctx.walletConnection
+}
+
+// connectionWatchdog reconnects to hcd and hcwallet when their connections
+// drop and catches up on what was missed while disconnected.  Each connection
+// is watched on its own so one that stays down doesn't keep the other from
+// being restored.
+func (ctx *appContext) connectionWatchdog(cfg *config) {
+	defer ctx.wg.Done()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ctx.watchConnection("hcwallet", watchdogInterval,
+			func() bool { return ctx.wallet().Disconnected() },
+			ctx.reconnectWallet(cfg))
+	}()
+	go func() {
+		defer wg.Done()
+		ctx.watchConnection("hcd", watchdogInterval,
+			func() bool { return ctx.node().Disconnected() },
+			ctx.reconnectNode(cfg))
+	}()
+	wg.Wait()
+}
+
+// watchConnection checks the connection to name every interval and, when
+// disconnected reports it lost, reconnects with connect and catches up.  A
+// catch up that fails, such as when the other connection is down too, is
+// tried again every interval until it succeeds.  It returns when stakepoold
+// is shut down.
+func (ctx *appContext) watchConnection(name string, interval time.Duration,
+	disconnected func() bool, connect func() error) {
+	jitter := rand.New(rand.NewSource(time.Now().UnixNano())).Int63n
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	caughtUp := true
+	for {
+		select {
+		case <-ticker.C:
+			if disconnected() {
+				log.Warnf("connection to %s lost", name)
+				if !ctx.reconnect(name, connect, jitter) {
+					return
+				}
+				caughtUp = false
+			}
+			if !caughtUp {
+				if err := ctx.catchUp(); err != nil {
+					log.Errorf("catchUp after reconnecting to %s "+
+						"failed: %v", name, err)
+					continue
+				}
+				caughtUp = true
+			}
+		case <-ctx.quit:
+			return
+		}
+	}
+}
+
+// reconnect calls connect until it succeeds, waiting reconnectBackoff with
+// jitter between attempts.  It returns false if stakepoold is shut down in the
+// meantime.
+func (ctx *appContext) reconnect(name string, connect func() error,
+	jitter func(n int64) int64) bool {
+	for attempt := 0; ; attempt++ {
+		err := connect()
+		if err == nil {
+			log.Infof("reconnected to %s", name)
+			return true
+		}
+
+		backoff := reconnectBackoff(attempt, jitter)
+		log.Warnf("reconnecting to %s failed: %v, retrying in %v", name,
+			err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.quit:
+			return false
+		}
+	}
+}
+
+// reconnectWallet returns a function that replaces the hcwallet connection
+// with a new one.
+func (ctx *appContext) reconnectWallet(cfg *config) func() error {
+	return func() error {
+		walletConn, walletVer, err := connectWallet(cfg)
+		if err != nil {
+			return err
+		}
+
+		ctx.connMtx.Lock()
+		old := ctx.walletConnection
+		ctx.walletConnection = walletConn
+		ctx.walletVersion = walletVer
+		ctx.connMtx.Unlock()
+		old.Shutdown()
+		return nil
+	}
+}
+
+// reconnectNode returns a function that replaces the hcd connection with a new
+// one and registers for notifications on it.
+func (ctx *appContext) reconnectNode(cfg *config) func() error {
+	return func() error {
+		nodeConn, _, err := connectNode(cfg, ctx)
+		if err != nil {
+			return err
+		}
+		if err := nodeConn.NotifyChain(); err != nil {
+			nodeConn.Shutdown()
+			return err
+		}
+
+		ctx.connMtx.Lock()
+		old := ctx.nodeConnection
+		ctx.nodeConnection = nodeConn
+		ctx.connMtx.Unlock()
+		old.Shutdown()
+		return nil
+	}
+}
+
+// setLastBlockSeen records the most recent block stakepoold knows about.
+func (ctx *appContext) setLastBlockSeen(hash *chainhash.Hash, height int64) {
+	ctx.Lock()
+	ctx.lastBlockSeenHash = hash
+	ctx.lastBlockSeenHeight = height
+	ctx.Unlock()
+}
+
+// catchUp reconciles stakepoold with the chain after a reconnect.  The
+// notifications for the blocks connected while disconnected were lost, so
+// the blocks that were reorganized out are reverted and the ticket lists are
+// fetched from the wallet again.  The fetched tickets are merged with the
+// ticket lists rather than replacing them, so that changes notifications made
+// while the wallet was asked are kept.  The winning tickets of the missed
+// blocks are unknown and can't be voted, except for those still pending from
+// before the connection dropped.  The hcd and hcwallet watchers may both call
+// it, one at a time.
+func (ctx *appContext) catchUp() error {
+	ctx.catchUpMtx.Lock()
+	defer ctx.catchUpMtx.Unlock()
+
+	bestHash, bestHeight, err := ctx.node().GetBestBlock()
+	if err != nil {
+		return err
+	}
+
+	ctx.RLock()
+	lastHash, lastHeight := ctx.lastBlockSeenHash, ctx.lastBlockSeenHeight
+	ctx.RUnlock()
+	if lastHash == nil || (*lastHash == *bestHash && lastHeight == bestHeight) {
+		log.Infof("catchUp: no blocks missed at height %v", bestHeight)
+		return nil
+	}
+	log.Infof("catchUp: last block seen %v (height %v), best block %v "+
+		"(height %v)", lastHash, lastHeight, bestHash, bestHeight)
+
+	ctx.processReorganization(Reorganization{
+		oldHash:   lastHash,
+		oldHeight: lastHeight,
+		newHash:   bestHash,
+		newHeight: bestHeight,
+	})
+
+	ctx.RLock()
+	liveSnapshot := copyTickets(ctx.liveTicketsMSA)
+	ignoredSnapshot := copyTickets(ctx.ignoredLowFeeTicketsMSA)
+	ctx.RUnlock()
+
+	ignoredLowFeeTicketsMSA, liveTicketsMSA, tipHash, tipHeight, err :=
+		ctx.fetchTickets(nil)
+	if err != nil {
+		return err
+	}
+	ctx.Lock()
+	mergeFetchedTickets(ctx.liveTicketsMSA, liveSnapshot, liveTicketsMSA)
+	mergeFetchedTickets(ctx.ignoredLowFeeTicketsMSA, ignoredSnapshot,
+		ignoredLowFeeTicketsMSA)
+	ctx.pruneTicketHeights()
+	live, ignored := len(ctx.liveTicketsMSA), len(ctx.ignoredLowFeeTicketsMSA)
+	ctx.Unlock()
+	ctx.setLastBlockSeen(tipHash, tipHeight)
+
+	ctx.processPendingVotes(tipHeight)
+
+	log.Infof("catchUp: refreshed tickets at height %v, live %v ignored %v",
+		tipHeight, live, ignored)
+	return nil
+}
+
+// mergeFetchedTickets applies to cached how fetched, the tickets fetched from
+// the wallet, differs from snapshot, the cached tickets when the fetch
+// started.  Tickets added to or removed from cached since the snapshot, by
+// notifications processed during the fetch, are left as they are.
+func mergeFetchedTickets(cached, snapshot, fetched map[chainhash.Hash]string) {
+	d := diffTickets(snapshot, fetched)
+	for ticket, msa := range d.missing {
+		cached[ticket] = msa
+	}
+	for ticket, msa := range d.changed {
+		if _, ok := cached[ticket]; ok {
+			cached[ticket] = msa
+		}
+	}
+	for ticket := range d.stale {
+		delete(cached, ticket)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcd/wire"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/rpcclient/rpcclienttest"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/userdata"
+	"github.com/coolsnady/hcstakepool/backend/stakepoold/voting"
+)
+
+func TestReconnectBackoff(t *testing.T) {
+	noJitter := func(int64) int64 { return 0 }
+	fullJitter := func(n int64) int64 { return n - 1 }
+	tests := []struct {
+		attempt int
+		jitter  func(int64) int64
+		want    time.Duration
+	}{
+		{0, noJitter, reconnectMinBackoff},
+		{3, noJitter, 8 * reconnectMinBackoff},
+		{20, noJitter, reconnectMaxBackoff},
+		{100, noJitter, reconnectMaxBackoff},
+		{1, fullJitter, reconnectMinBackoff},
+	}
+	for _, test := range tests {
+		got := reconnectBackoff(test.attempt, test.jitter)
+		if got != test.want {
+			t.Errorf("attempt %d: backoff %v, want %v", test.attempt, got,
+				test.want)
+		}
+	}
+}
+
+func TestMergeFetchedTickets(t *testing.T) {
+	kept := chainhash.Hash{1}
+	bought := chainhash.Hash{2}
+	spent := chainhash.Hash{3}
+	changed := chainhash.Hash{4}
+	notified := chainhash.Hash{5}
+	spentDuringFetch := chainhash.Hash{6}
+
+	snapshot := map[chainhash.Hash]string{
+		kept:             "msa",
+		spent:            "msa",
+		changed:          "msa",
+		spentDuringFetch: "msa",
+	}
+	// The notifications processed while the wallet was asked added a
+	// ticket and removed one.
+	cached := copyTickets(snapshot)
+	cached[notified] = "msa"
+	delete(cached, spentDuringFetch)
+	fetched := map[chainhash.Hash]string{
+		kept:             "msa",
+		bought:           "msa",
+		changed:          "msa2",
+		spentDuringFetch: "msa",
+	}
+
+	mergeFetchedTickets(cached, snapshot, fetched)
+	want := map[chainhash.Hash]string{
+		kept:     "msa",
+		bought:   "msa",
+		changed:  "msa2",
+		notified: "msa",
+	}
+	if !reflect.DeepEqual(cached, want) {
+		t.Errorf("merged tickets %v, want %v", cached, want)
+	}
+}
+
+// newWatchdogTestContext returns a context whose node is at height 100 and
+// last saw block 99.
+func newWatchdogTestContext(node *rpcclienttest.Node,
+	wallet *rpcclienttest.Wallet) *appContext {
+	params := &chaincfg.TestNet2Params
+	last := &wire.BlockHeader{Height: 99}
+	node.AddBlock(last)
+	node.AddBlock(&wire.BlockHeader{Height: 100, PrevBlock: last.BlockHash()})
+	lastHash := last.BlockHash()
+	return &appContext{
+		addedLowFeeTicketsMSA:   make(map[chainhash.Hash]string),
+		ignoredLowFeeTicketsMSA: make(map[chainhash.Hash]string),
+		lastBlockSeenHash:       &lastHash,
+		lastBlockSeenHeight:     99,
+		liveTicketsMSA:          make(map[chainhash.Hash]string),
+		nodeConnection:          node,
+		params:                  params,
+		pendingVotes:            voting.NewPendingVotes(),
+		quit:                    make(chan struct{}),
+		ticketHeights:           make(map[chainhash.Hash]int64),
+		userVotingConfig: map[string]userdata.UserVotingConfig{
+			testMSA: {MultiSigAddress: testMSA},
+		},
+		walletConnection: wallet,
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	kept := chainhash.Hash{1}
+	bought := chainhash.Hash{2}
+	spent := chainhash.Hash{3}
+
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	ctx := newWatchdogTestContext(node, wallet)
+	ctx.liveTicketsMSA[kept] = testMSA
+	ctx.liveTicketsMSA[spent] = testMSA
+
+	// The tickets are ones an admin added, so their fees aren't checked.
+	best, _, _ := node.GetBestBlock()
+	for _, ticket := range []chainhash.Hash{kept, bought} {
+		ticket := ticket
+		ctx.addedLowFeeTicketsMSA[ticket] = testMSA
+		wallet.AddTicket(&ticket, &dcrjson.GetTransactionResult{
+			TxID:      ticket.String(),
+			BlockHash: best.String(),
+			Details: []dcrjson.GetTransactionDetailsResult{
+				{Address: testMSA},
+			},
+		})
+	}
+
+	if err := ctx.catchUp(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[chainhash.Hash]string{
+		kept:   testMSA,
+		bought: testMSA,
+	}
+	if !reflect.DeepEqual(ctx.liveTicketsMSA, want) {
+		t.Errorf("live tickets %v, want %v", ctx.liveTicketsMSA, want)
+	}
+	if *ctx.lastBlockSeenHash != *best || ctx.lastBlockSeenHeight != 100 {
+		t.Errorf("last block seen %v (height %v), want %v (height 100)",
+			ctx.lastBlockSeenHash, ctx.lastBlockSeenHeight, best)
+	}
+}
+
+func TestWatchConnectionsIndependent(t *testing.T) {
+	node := rpcclienttest.NewNode(chaincfg.TestNet2Params.Net)
+	wallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	ctx := newWatchdogTestContext(node, wallet)
+	node.SetDisconnected(true)
+	wallet.SetDisconnected(true)
+
+	// hcd stays unreachable until released.
+	nodeConnecting := make(chan struct{})
+	releaseNode := make(chan struct{})
+	var connecting, releasing sync.Once
+	release := func() { releasing.Do(func() { close(releaseNode) }) }
+	connectNode := func() error {
+		connecting.Do(func() { close(nodeConnecting) })
+		<-releaseNode
+		node.SetDisconnected(false)
+		return nil
+	}
+
+	newWallet := rpcclienttest.NewWallet(dcrjson.WalletInfoResult{})
+	walletConnected := make(chan struct{})
+	connectWallet := func() error {
+		ctx.connMtx.Lock()
+		ctx.walletConnection = newWallet
+		ctx.connMtx.Unlock()
+		close(walletConnected)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ctx.watchConnection("hcd", time.Millisecond, node.Disconnected,
+			connectNode)
+	}()
+	go func() {
+		defer wg.Done()
+		ctx.watchConnection("hcwallet", time.Millisecond,
+			func() bool { return ctx.wallet().Disconnected() },
+			connectWallet)
+	}()
+	defer func() {
+		release()
+		close(ctx.quit)
+		wg.Wait()
+	}()
+
+	timeout := time.After(5 * time.Second)
+	select {
+	case <-nodeConnecting:
+	case <-timeout:
+		t.Fatal("hcd reconnect was not attempted")
+	}
+	select {
+	case <-walletConnected:
+	case <-timeout:
+		t.Fatal("hcwallet was not reconnected while hcd was down")
+	}
+	if ctx.wallet() != newWallet {
+		t.Error("hcwallet connection was not replaced")
+	}
+	release()
+
+	// Once both are back the watchers catch up to the best block.
+	for {
+		ctx.RLock()
+		height := ctx.lastBlockSeenHeight
+		ctx.RUnlock()
+		if height == 100 && !node.Disconnected() {
+			break
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("not caught up, last block seen at height %v", height)
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -7,50 +7,87 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	flags "github.com/btcsuite/go-flags"
+	"github.com/coolsnady/hcstakepool/controllers"
+	"github.com/coolsnady/hcstakepool/notifier"
+	"github.com/coolsnady/hcstakepool/pricefeed"
+	"github.com/coolsnady/hcstakepool/scrub"
+	"github.com/coolsnady/hcstakepool/system"
+	"github.com/coolsnady/hcstakepool/tracing"
+	"github.com/coolsnady/hcstakepool/version"
 	"github.com/coolsnady/hcutil"
 )
 
 const (
-	defaultBaseURL          = "http://127.0.0.1:8000"
-	defaultClosePoolMsg     = "The stake pool is temporarily closed to new signups."
-	defaultConfigFilename   = "hcstakepool.conf"
-	defaultDataDirname      = "data"
-	defaultLogLevel         = "info"
-	defaultLogDirname       = "logs"
-	defaultLogFilename      = "hcstakepool.log"
-	defaultCookieSecure     = false
-	defaultDBHost           = "localhost"
-	defaultDBName           = "stakepool"
-	defaultDBPort           = "3306"
-	defaultDBUser           = "stakepool"
-	defaultListen           = ":8000"
-	defaultPoolEmail        = "admin@example.com"
-	defaultPoolFees         = 7.5
-	defaultPoolLink         = "https://forum.coolsnady.org/threads/rfp-6-setup-and-operate-10-stake-pools.1361/"
-	defaultPublicPath       = "public"
-	defaultTemplatePath     = "views"
-	defaultRecaptchaSecret  = "6LeIxAcTAAAAAGG-vFI1TnRWxMZNFuojJ4WifJWe"
-	defaultRecaptchaSitekey = "6LeIxAcTAAAAAJcZVRqyHh71UMIEGNQ_MXjiZKhI"
-	defaultSMTPHost         = ""
-	defaultMinServers       = 2
-	defaultMaxVotedAge      = 8640
+	defaultBaseURL           = "http://127.0.0.1:8000"
+	defaultClosePoolMsg      = "The stake pool is temporarily closed to new signups."
+	defaultConfigFilename    = "hcstakepool.conf"
+	defaultDataDirname       = "data"
+	defaultMaintenanceFile   = "maintenance.json"
+	defaultLogLevel          = "info"
+	defaultLogDirname        = "logs"
+	defaultLogFilename       = "hcstakepool.log"
+	defaultCookieSecure      = false
+	defaultHSTSMaxAge        = 0
+	defaultInviteExpiry      = 14 * 24 * time.Hour
+	defaultDBHost            = "localhost"
+	defaultDBName            = "stakepool"
+	defaultDBPort            = "3306"
+	defaultDBUser            = "stakepool"
+	defaultListen            = ":8000"
+	defaultPoolEmail         = "admin@example.com"
+	defaultPoolFees          = 7.5
+	defaultPoolLink          = "https://forum.coolsnady.org/threads/rfp-6-setup-and-operate-10-stake-pools.1361/"
+	defaultPoolName          = "Hcd Stake Pool"
+	defaultPublicPath        = "public"
+	defaultTemplatePath      = "views"
+	defaultLocalePath        = "locales"
+	defaultCaptchaProvider   = "recaptcha"
+	defaultRecaptchaSecret   = "6LeIxAcTAAAAAGG-vFI1TnRWxMZNFuojJ4WifJWe"
+	defaultRecaptchaSitekey  = "6LeIxAcTAAAAAJcZVRqyHh71UMIEGNQ_MXjiZKhI"
+	defaultHCaptchaSecret    = "0x0000000000000000000000000000000000000000"
+	defaultHCaptchaSitekey   = "10000000-ffff-ffff-ffff-000000000001"
+	defaultSMTPHost          = ""
+	defaultSMTPSecurity      = controllers.SMTPSecurityTLS
+	defaultSMTPAuth          = controllers.SMTPAuthPlain
+	defaultMinServers        = 2
+	defaultMaxVotedAge       = 8640
+	defaultExpiryWarning     = 4032
+	defaultMissedVoteBlocks  = 144
+	defaultFeeAddressWarning = 500
+	defaultStakeVersionWarn  = 50
+	defaultPriceFeeds        = "coingecko,cryptocompare"
+	defaultStartupRetryMax   = time.Minute
+	defaultConsistencyCheck  = time.Hour
+	defaultMaxWalletBalance  = 1
+	defaultWalletCheck       = walletCheckWarn
+	defaultACMEDirname       = "acme"
+)
+
+// What to do at startup when a voting wallet isn't voting-only.
+const (
+	walletCheckRefuse = "refuse"
+	walletCheckWarn   = "warn"
 )
 
 var (
-	hxstakepoolHomeDir = hcutil.AppDataDir("hcstakepool", false)
-	defaultConfigFile  = filepath.Join(hxstakepoolHomeDir, defaultConfigFilename)
-	defaultDataDir     = filepath.Join(hxstakepoolHomeDir, defaultDataDirname)
-	defaultLogDir      = filepath.Join(hxstakepoolHomeDir, defaultLogDirname)
+	hxstakepoolHomeDir  = hcutil.AppDataDir("hcstakepool", false)
+	defaultConfigFile   = filepath.Join(hxstakepoolHomeDir, defaultConfigFilename)
+	defaultDataDir      = filepath.Join(hxstakepoolHomeDir, defaultDataDirname)
+	defaultLogDir       = filepath.Join(hxstakepoolHomeDir, defaultLogDirname)
+	defaultACMECacheDir = filepath.Join(hxstakepoolHomeDir, defaultACMEDirname)
 )
 
 // runServiceCommand is only set to a real function on Windows.  It is used
@@ -61,54 +98,103 @@ var runServiceCommand func(string) error
 //
 // See loadConfig for details on the configuration load process.
 type config struct {
-	ShowVersion        bool     `short:"V" long:"version" description:"Display version information and exit"`
-	ConfigFile         string   `short:"C" long:"configfile" description:"Path to configuration file"`
-	DataDir            string   `short:"b" long:"datadir" description:"Directory to store data"`
-	LogDir             string   `long:"logdir" description:"Directory to log output."`
-	Listen             string   `long:"listen" description:"Listen for connections on the specified interface/port (default all interfaces port: 9113, testnet: 19113)"`
-	TestNet            bool     `long:"testnet" description:"Use the test network"`
-	SimNet             bool     `long:"simnet" description:"Use the simulation test network"`
-	Profile            string   `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
-	CPUProfile         string   `long:"cpuprofile" description:"Write CPU profile to the specified file"`
-	MemProfile         string   `long:"memprofile" description:"Write mem profile to the specified file"`
-	DebugLevel         string   `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
-	APISecret          string   `long:"apisecret" description:"Secret string used to encrypt API tokens."`
-	BaseURL            string   `long:"baseurl" description:"BaseURL to use when sending links via email"`
-	ColdWalletExtPub   string   `long:"coldwalletextpub" description:"The extended public key to send user stake pool fees to"`
-	ClosePool          bool     `long:"closepool" description:"Disable user registration actions (sign-ups and submitting addresses)"`
-	ClosePoolMsg       string   `long:"closepoolmsg" description:"Message to display when closepool is set (default: Stake pool is currently oversubscribed)"`
-	CookieSecret       string   `long:"cookiesecret" description:"Secret string used to encrypt session data."`
-	CookieSecure       bool     `long:"cookiesecure" description:"Set whether cookies can be sent in clear text or not."`
-	DBHost             string   `long:"dbhost" description:"Hostname for database connection"`
-	DBUser             string   `long:"dbuser" description:"Username for database connection"`
-	DBPassword         string   `long:"dbpassword" description:"Password for database connection"`
-	DBPort             string   `long:"dbport" description:"Port for database connection"`
-	DBName             string   `long:"dbname" description:"Name of database"`
-	PublicPath         string   `long:"publicpath" description:"Path to the public folder which contains css/fonts/images/javascript."`
-	TemplatePath       string   `long:"templatepath" description:"Path to the views folder which contains html files."`
-	RecaptchaSecret    string   `long:"recaptchasecret" description:"Recaptcha Secret"`
-	RecaptchaSitekey   string   `long:"recaptchasitekey" description:"Recaptcha Sitekey"`
-	PoolEmail          string   `long:"poolemail" description:"Email address to for support inquiries"`
-	PoolFees           float64  `long:"poolfees" description:"The per-ticket fees the user must send to the pool with their tickets"`
-	PoolLink           string   `long:"poollink" description:"URL for support inquiries such as forum, IRC, etc"`
-	RealIPHeader       string   `long:"realipheader" description:"The name of an HTTP request header containing the actual remote client IP address, typically set by a reverse proxy. An empty string (default) indicates to use net/Request.RemodeAddr."`
-	SMTPFrom           string   `long:"smtpfrom" description:"From address to use on outbound mail"`
-	SMTPHost           string   `long:"smtphost" description:"SMTP hostname/ip and port, e.g. mail.example.com:25"`
-	SMTPUsername       string   `long:"smtpusername" description:"SMTP username for authentication if required"`
-	SMTPPassword       string   `long:"smtppassword" description:"SMTP password for authentication if required"`
-	StakepooldHosts    []string `long:"stakepooldhosts" description:"Hostnames for stakepoold servers"`
-	StakepooldCerts    []string `long:"stakepooldcerts" description:"Certificate paths for stakepoold servers"`
-	WalletHosts        []string `long:"wallethosts" description:"Hostnames for wallet servers"`
-	WalletUsers        []string `long:"walletusers" description:"Usernames for wallet servers"`
-	WalletPasswords    []string `long:"walletpasswords" description:"Passwords for wallet servers"`
-	WalletCerts        []string `long:"walletcerts" description:"Certificate paths for wallet servers"`
-	Version            string
-	VotingWalletExtPub string   `long:"votingwalletextpub" description:"The extended public key of the default account of the voting wallet"`
-	AdminIPs           []string `long:"adminips" description:"Expected admin host"`
-	AdminUserIDs       []string `long:"adminuserids" description:"User IDs of users who are allowed to access administrative functions."`
-	MinServers         int      `long:"minservers" description:"Minimum number of wallets connected needed to avoid errors"`
-	EnableStakepoold   bool     `long:"enablestakepoold" description:"Enable communication with stakepoold"`
-	MaxVotedAge        int64    `long:"maxvotedage" description:"Maximum vote age (blocks since vote) to include in voted tickets table"`
+	ShowVersion           bool          `short:"V" long:"version" description:"Display version information and exit"`
+	ShowVersionJSON       bool          `long:"json" description:"Display version information as JSON (with --version)"`
+	ConfigFile            string        `short:"C" long:"configfile" description:"Path to configuration file"`
+	DataDir               string        `short:"b" long:"datadir" description:"Directory to store data"`
+	LogDir                string        `long:"logdir" description:"Directory to log output."`
+	Listen                string        `long:"listen" description:"Listen for connections on the specified interface/port (default all interfaces port: 9113, testnet: 19113)"`
+	TestNet               bool          `long:"testnet" description:"Use the test network"`
+	SimNet                bool          `long:"simnet" description:"Use the simulation test network"`
+	Profile               string        `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
+	CPUProfile            string        `long:"cpuprofile" description:"Write CPU profile to the specified file"`
+	MemProfile            string        `long:"memprofile" description:"Write mem profile to the specified file"`
+	DebugLevel            string        `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
+	APISecret             string        `long:"apisecret" description:"Secret string used to encrypt API tokens."`
+	BaseURL               string        `long:"baseurl" description:"BaseURL to use when sending links via email"`
+	ColdWalletExtPub      string        `long:"coldwalletextpub" description:"The extended public key to send user stake pool fees to"`
+	ClosePool             bool          `long:"closepool" description:"Disable user registration actions (sign-ups and submitting addresses)"`
+	ClosePoolMsg          string        `long:"closepoolmsg" description:"Message to display when closepool is set (default: Stake pool is currently oversubscribed)"`
+	MaintenanceFile       string        `long:"maintenancefile" description:"File the maintenance mode is kept in, so it survives restarts; frontends sharing the file share the mode (default: maintenance.json in the data directory)"`
+	InviteSignup          bool          `long:"invitesignup" description:"Only let people sign up with a single-use invite code issued by the admins"`
+	InviteExpiry          time.Duration `long:"inviteexpiry" description:"How long invite codes stay valid after they are issued"`
+	CookieSecret          string        `long:"cookiesecret" description:"Secret string used to encrypt session data."`
+	CookieSecure          bool          `long:"cookiesecure" description:"Set whether cookies can be sent in clear text or not."`
+	ContentSecurityPolicy string        `long:"contentsecuritypolicy" description:"Content-Security-Policy header sent with every response, set to an empty string to send none"`
+	HSTSMaxAge            time.Duration `long:"hstsmaxage" description:"max-age of the Strict-Transport-Security header sent over HTTPS and with cookiesecure (0 sends none)"`
+	DBHost                string        `long:"dbhost" description:"Hostname for database connection"`
+	DBUser                string        `long:"dbuser" description:"Username for database connection"`
+	DBPassword            string        `long:"dbpassword" description:"Password for database connection"`
+	DBPort                string        `long:"dbport" description:"Port for database connection"`
+	DBName                string        `long:"dbname" description:"Name of database"`
+	PublicPath            string        `long:"publicpath" description:"Path to the public folder which contains css/fonts/images/javascript."`
+	TemplatePath          string        `long:"templatepath" description:"Path to the views folder which contains html files."`
+	LocalePath            string        `long:"localepath" description:"Path to the locales folder which contains the translations of the pages and emails."`
+	OverridePath          string        `long:"overridepath" description:"Path to a folder whose views and public folders contain templates and static files replacing the ones in templatepath and publicpath."`
+	CaptchaProvider       string        `long:"captchaprovider" description:"CAPTCHA solved on the signup, password reset and settings forms (recaptcha, hcaptcha)"`
+	RecaptchaSecret       string        `long:"recaptchasecret" description:"Recaptcha Secret"`
+	RecaptchaSitekey      string        `long:"recaptchasitekey" description:"Recaptcha Sitekey"`
+	HCaptchaSecret        string        `long:"hcaptchasecret" description:"hCaptcha Secret"`
+	HCaptchaSitekey       string        `long:"hcaptchasitekey" description:"hCaptcha Sitekey"`
+	PoolEmail             string        `long:"poolemail" description:"Email address to for support inquiries"`
+	PoolFees              float64       `long:"poolfees" description:"The per-ticket fees the user must send to the pool with their tickets"`
+	PoolLink              string        `long:"poollink" description:"URL for support inquiries such as forum, IRC, etc"`
+	PoolName              string        `long:"poolname" description:"Name of the pool used in emails"`
+	EmailLogoURL          string        `long:"emaillogourl" description:"URL of a logo image shown at the top of HTML emails"`
+	RealIPHeader          string        `long:"realipheader" description:"The name of an HTTP request header containing the actual remote client IP address, typically set by a reverse proxy. An empty string (default) indicates to use net/Request.RemodeAddr."`
+	SMTPFrom              string        `long:"smtpfrom" description:"From address to use on outbound mail"`
+	SMTPHost              string        `long:"smtphost" description:"SMTP hostname/ip and port, e.g. mail.example.com:25"`
+	SMTPUsername          string        `long:"smtpusername" description:"SMTP username for authentication if required"`
+	SMTPPassword          string        `long:"smtppassword" description:"SMTP password for authentication if required"`
+	SMTPSecurity          string        `long:"smtpsecurity" description:"How the connection to the SMTP server is secured (tls, starttls, none)"`
+	SMTPAuth              string        `long:"smtpauth" description:"SMTP AUTH mechanism used with smtpusername (plain, login, cram-md5)"`
+	StakepooldHosts       []string      `long:"stakepooldhosts" description:"Hostnames for stakepoold servers"`
+	StakepooldCerts       []string      `long:"stakepooldcerts" description:"Certificate paths for stakepoold servers"`
+	StakepooldToken       string        `long:"stakepooldtoken" default-mask:"-" description:"Token authenticating to stakepoold servers that set rpcauth"`
+	StakepooldCompress    bool          `long:"stakepooldcompress" description:"Compress the calls to stakepoold with large responses, and their responses, with gzip"`
+	Proxy                 string        `long:"proxy" description:"Connect to stakepoold servers and external services via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
+	ProxyUser             string        `long:"proxyuser" description:"Username for proxy server"`
+	ProxyPass             string        `long:"proxypass" default-mask:"-" description:"Password for proxy server"`
+	WalletHosts           []string      `long:"wallethosts" description:"Hostnames for wallet servers"`
+	WalletUsers           []string      `long:"walletusers" description:"Usernames for wallet servers"`
+	WalletPasswords       []string      `long:"walletpasswords" description:"Passwords for wallet servers"`
+	WalletCerts           []string      `long:"walletcerts" description:"Certificate paths for wallet servers"`
+	Version               string
+	VotingWalletExtPub    string        `long:"votingwalletextpub" description:"The extended public key of the default account of the voting wallet"`
+	AdminIPs              []string      `long:"adminips" description:"Expected admin host"`
+	AdminUserIDs          []string      `long:"adminuserids" description:"User IDs of users who are allowed to access administrative functions."`
+	MinServers            int           `long:"minservers" description:"Minimum number of wallets connected needed to avoid errors"`
+	LockedWallets         bool          `long:"lockedwallets" description:"Accept locked voting wallets, which stakepoold unlocks only to vote with its walletpassfile or walletpassprompt"`
+	WalletCheck           string        `long:"walletcheck" description:"What to do at startup when a voting wallet isn't voting-only: it buys tickets, holds more than maxwalletbalance, doesn't own the votingwalletextpub addresses or owns the coldwalletextpub addresses {refuse, warn}"`
+	MaxWalletBalance      float64       `long:"maxwalletbalance" description:"Most coins a voting wallet may hold spendable, voting needs none"`
+	EnableStakepoold      bool          `long:"enablestakepoold" description:"Enable communication with stakepoold"`
+	MaxVotedAge           int64         `long:"maxvotedage" description:"Maximum vote age (blocks since vote) to include in voted tickets table"`
+	ExpiryWarning         int64         `long:"expirywarning" description:"Warn users by email and on the tickets page about live tickets this many blocks from expiring (0 disables)"`
+	MissedVoteAlert       int64         `long:"missedvotealert" description:"Alert the admins by email when the pool misses more than this many votes within missedvoteblocks blocks (0 disables)"`
+	MissedVoteBlocks      int64         `long:"missedvoteblocks" description:"Number of blocks missed votes are counted over for missedvotealert"`
+	MissedVoteWebhook     string        `long:"missedvotewebhook" description:"Also post missed vote alerts to this URL"`
+	MissedVoteSecret      string        `long:"missedvotesecret" default-mask:"-" description:"Secret the missed vote alert webhook posts are signed with"`
+	MissedVoteBanner      bool          `long:"missedvotebanner" description:"Show a warning on every page while the missed vote alert is raised"`
+	TelegramBotToken      string        `long:"telegrambottoken" default-mask:"-" description:"Token of the Telegram bot that sends notifications to telegramchatids and the users who link their account (empty disables)"`
+	TelegramChatIDs       []string      `long:"telegramchatids" description:"Ids of the Telegram chats operators are notified in of missed votes, wallet disconnects and running out of fee addresses (may be repeated)"`
+	ChatWebhooks          []string      `long:"chatwebhook" default-mask:"-" description:"Post operational events at or above a severity to this Slack or Discord compatible webhook, given as [severity,]URL where severity is info (the default), warning or critical (may be repeated)"`
+	FeeAddressWarning     int64         `long:"feeaddresswarning" description:"Notify operators when no more than this many fee addresses are left for new users (0 disables)"`
+	StakeVersionWarn      int64         `long:"stakeversionwarn" description:"Notify operators when this percentage of the network's votes use a newer vote version than the voting wallets or the users' voting preferences (0 disables)"`
+	ConsistencyCheck      time.Duration `long:"consistencycheck" description:"How often to cross-check the tickets of the database, the voting wallets, stakepoold and hcd, repair trivial inconsistencies and report the rest (0 disables)"`
+	TermsVersion          string        `long:"termsversion" description:"Version of the terms of service shown at /terms that users must accept (empty disables); changing it makes every user accept them again"`
+	PriceFeeds            string        `long:"pricefeeds" description:"Comma separated price feeds to try in order for fiat values {coingecko, cryptocompare}"`
+	PriceFeedCache        time.Duration `long:"pricefeedcache" description:"How long to use fetched prices before asking the price feeds again"`
+	NoPriceFeed           bool          `long:"nopricefeed" description:"Don't show fiat values so the pool never contacts any price feed"`
+	StartupTimeout        time.Duration `long:"startuptimeout" description:"Exit if MySQL or stakepoold are still unavailable this long after starting (0 keeps retrying forever)"`
+	StartupRetryMax       time.Duration `long:"startupretrymax" description:"Maximum delay between attempts to reach MySQL and stakepoold while starting"`
+	TLSListen             string        `long:"tlslisten" description:"Listen for HTTPS connections on the specified interface/port (e.g. :443)"`
+	TLSCert               string        `long:"tlscert" description:"File containing the HTTPS certificate, used when ACME is disabled or can't provide a certificate"`
+	TLSKey                string        `long:"tlskey" description:"File containing the HTTPS certificate key"`
+	ACMEDomains           []string      `long:"acmedomains" description:"Obtain and renew the HTTPS certificate for these domains automatically from an ACME CA such as Let's Encrypt"`
+	ACMEEmail             string        `long:"acmeemail" description:"Contact email address for the ACME account"`
+	ACMEDirectory         string        `long:"acmedirectory" description:"ACME directory URL (default: Let's Encrypt)"`
+	ACMECacheDir          string        `long:"acmecachedir" description:"Directory to store the ACME account key and certificates"`
+	OTLPEndpoint          string        `long:"otlpendpoint" description:"Export request traces to the OpenTelemetry collector at this OTLP/HTTP URL (eg. http://127.0.0.1:4318)"`
 }
 
 // serviceOptions defines the configuration options for the daemon as a service
@@ -283,30 +369,51 @@ func newConfigParser(cfg *config, so *serviceOptions, options flags.Options) *fl
 func loadConfig() (*config, []string, error) {
 	// Default config.
 	cfg := config{
-		BaseURL:          defaultBaseURL,
-		ClosePool:        false,
-		ClosePoolMsg:     defaultClosePoolMsg,
-		ConfigFile:       defaultConfigFile,
-		DebugLevel:       defaultLogLevel,
-		DataDir:          defaultDataDir,
-		LogDir:           defaultLogDir,
-		CookieSecure:     defaultCookieSecure,
-		DBHost:           defaultDBHost,
-		DBName:           defaultDBName,
-		DBPort:           defaultDBPort,
-		DBUser:           defaultDBUser,
-		Listen:           defaultListen,
-		PoolEmail:        defaultPoolEmail,
-		PoolFees:         defaultPoolFees,
-		PoolLink:         defaultPoolLink,
-		PublicPath:       defaultPublicPath,
-		TemplatePath:     defaultTemplatePath,
-		RecaptchaSecret:  defaultRecaptchaSecret,
-		RecaptchaSitekey: defaultRecaptchaSitekey,
-		SMTPHost:         defaultSMTPHost,
-		Version:          version(),
-		MinServers:       defaultMinServers,
-		MaxVotedAge:      defaultMaxVotedAge,
+		BaseURL:               defaultBaseURL,
+		ClosePool:             false,
+		ClosePoolMsg:          defaultClosePoolMsg,
+		ConfigFile:            defaultConfigFile,
+		DebugLevel:            defaultLogLevel,
+		DataDir:               defaultDataDir,
+		LogDir:                defaultLogDir,
+		CookieSecure:          defaultCookieSecure,
+		ContentSecurityPolicy: system.DefaultContentSecurityPolicy,
+		HSTSMaxAge:            defaultHSTSMaxAge,
+		InviteExpiry:          defaultInviteExpiry,
+		DBHost:                defaultDBHost,
+		DBName:                defaultDBName,
+		DBPort:                defaultDBPort,
+		DBUser:                defaultDBUser,
+		Listen:                defaultListen,
+		PoolEmail:             defaultPoolEmail,
+		PoolFees:              defaultPoolFees,
+		PoolLink:              defaultPoolLink,
+		PoolName:              defaultPoolName,
+		PublicPath:            defaultPublicPath,
+		TemplatePath:          defaultTemplatePath,
+		LocalePath:            defaultLocalePath,
+		CaptchaProvider:       defaultCaptchaProvider,
+		RecaptchaSecret:       defaultRecaptchaSecret,
+		RecaptchaSitekey:      defaultRecaptchaSitekey,
+		HCaptchaSecret:        defaultHCaptchaSecret,
+		HCaptchaSitekey:       defaultHCaptchaSitekey,
+		SMTPHost:              defaultSMTPHost,
+		SMTPSecurity:          defaultSMTPSecurity,
+		SMTPAuth:              defaultSMTPAuth,
+		Version:               version.String(),
+		MinServers:            defaultMinServers,
+		MaxVotedAge:           defaultMaxVotedAge,
+		ExpiryWarning:         defaultExpiryWarning,
+		MissedVoteBlocks:      defaultMissedVoteBlocks,
+		FeeAddressWarning:     defaultFeeAddressWarning,
+		StakeVersionWarn:      defaultStakeVersionWarn,
+		ConsistencyCheck:      defaultConsistencyCheck,
+		WalletCheck:           defaultWalletCheck,
+		MaxWalletBalance:      defaultMaxWalletBalance,
+		PriceFeeds:            defaultPriceFeeds,
+		PriceFeedCache:        pricefeed.DefaultCacheDuration,
+		StartupRetryMax:       defaultStartupRetryMax,
+		ACMECacheDir:          defaultACMECacheDir,
 	}
 
 	// Service options which are only added on Windows.
@@ -331,7 +438,16 @@ func loadConfig() (*config, []string, error) {
 	appName = strings.TrimSuffix(appName, filepath.Ext(appName))
 	usageMessage := fmt.Sprintf("Use %s -h to show usage", appName)
 	if preCfg.ShowVersion {
-		fmt.Println(appName, "version", version())
+		if preCfg.ShowVersionJSON {
+			info, err := json.Marshal(version.Get())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return nil, nil, err
+			}
+			fmt.Println(string(info))
+			os.Exit(0)
+		}
+		fmt.Println(appName, "version", version.String())
 		os.Exit(0)
 	}
 
@@ -408,6 +524,15 @@ func loadConfig() (*config, []string, error) {
 		// Also disable dns seeding on the simulation test network.
 		activeNetParams = &simNetParams
 	}
+	if numNets == 0 {
+		// Use the network the binary was built for by default.
+		switch version.Network {
+		case "testnet":
+			activeNetParams = &testNet2Params
+		case "simnet":
+			activeNetParams = &simNetParams
+		}
+	}
 	if numNets > 1 {
 		str := "%s: The testnet and simnet params can't be " +
 			"used together -- choose one of the three"
@@ -426,6 +551,12 @@ func loadConfig() (*config, []string, error) {
 	cfg.DataDir = cleanAndExpandPath(cfg.DataDir)
 	cfg.DataDir = filepath.Join(cfg.DataDir, netName(activeNetParams))
 
+	if cfg.MaintenanceFile == "" {
+		cfg.MaintenanceFile = filepath.Join(cfg.DataDir, defaultMaintenanceFile)
+	} else {
+		cfg.MaintenanceFile = cleanAndExpandPath(cfg.MaintenanceFile)
+	}
+
 	// Append the network type to the log directory so it is "namespaced"
 	// per network in the same fashion as the data directory.
 	cfg.LogDir = cleanAndExpandPath(cfg.LogDir)
@@ -591,6 +722,13 @@ func loadConfig() (*config, []string, error) {
 		}
 	}
 
+	if cfg.Proxy == "" && (cfg.ProxyUser != "" || cfg.ProxyPass != "") {
+		str := "%s: proxyuser and proxypass require proxy to be set"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
 	if cfg.EnableStakepoold {
 		if len(cfg.StakepooldHosts) == 0 {
 			str := "%s: stakepooldhosts is not set in config"
@@ -647,6 +785,216 @@ func loadConfig() (*config, []string, error) {
 		}
 	}
 
+	if cfg.StartupTimeout < 0 {
+		str := "%s: startuptimeout may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.ExpiryWarning < 0 {
+		str := "%s: expirywarning may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	switch cfg.CaptchaProvider {
+	case controllers.CaptchaRecaptcha, controllers.CaptchaHCaptcha:
+	default:
+		str := "%s: captchaprovider %q is not recaptcha or hcaptcha"
+		err := fmt.Errorf(str, funcName, cfg.CaptchaProvider)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	switch cfg.SMTPSecurity {
+	case controllers.SMTPSecurityTLS, controllers.SMTPSecurityStartTLS,
+		controllers.SMTPSecurityNone:
+	default:
+		str := "%s: smtpsecurity %q is not tls, starttls or none"
+		err := fmt.Errorf(str, funcName, cfg.SMTPSecurity)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	switch cfg.SMTPAuth {
+	case controllers.SMTPAuthPlain, controllers.SMTPAuthLogin,
+		controllers.SMTPAuthCRAMMD5:
+	default:
+		str := "%s: smtpauth %q is not plain, login or cram-md5"
+		err := fmt.Errorf(str, funcName, cfg.SMTPAuth)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.MissedVoteAlert < 0 {
+		str := "%s: missedvotealert may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.MissedVoteAlert > 0 {
+		if !cfg.EnableStakepoold {
+			str := "%s: missedvotealert requires enablestakepoold"
+			err := fmt.Errorf(str, funcName)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+		if cfg.MissedVoteBlocks < 1 {
+			str := "%s: missedvoteblocks must be at least 1"
+			err := fmt.Errorf(str, funcName)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+		if cfg.MissedVoteWebhook != "" {
+			u, err := url.Parse(cfg.MissedVoteWebhook)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				str := "%s: missedvotewebhook %q is not an http(s) URL"
+				err := fmt.Errorf(str, funcName, cfg.MissedVoteWebhook)
+				fmt.Fprintln(os.Stderr, err)
+				return nil, nil, err
+			}
+		}
+	}
+	if _, err := controllers.ParseTelegramChatIDs(cfg.TelegramChatIDs); err != nil {
+		str := "%s: telegramchatids: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	for _, s := range cfg.ChatWebhooks {
+		webhook, err := notifier.ParseWebhook(s)
+		if err != nil {
+			str := "%s: chatwebhook: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+		scrub.AddSecrets(webhook.URL())
+	}
+	if cfg.FeeAddressWarning < 0 {
+		str := "%s: feeaddresswarning may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.StakeVersionWarn < 0 || cfg.StakeVersionWarn > 100 {
+		str := "%s: stakeversionwarn must be between 0 and 100"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.ConsistencyCheck < 0 {
+		str := "%s: consistencycheck may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.InviteExpiry <= 0 {
+		str := "%s: inviteexpiry must be positive"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.HSTSMaxAge < 0 {
+		str := "%s: hstsmaxage may not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	switch cfg.WalletCheck {
+	case walletCheckRefuse, walletCheckWarn:
+	default:
+		str := "%s: walletcheck %q is not refuse or warn"
+		err := fmt.Errorf(str, funcName, cfg.WalletCheck)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if _, err := hcutil.NewAmount(cfg.MaxWalletBalance); err != nil ||
+		cfg.MaxWalletBalance < 0 {
+		str := "%s: maxwalletbalance must be a non-negative amount"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if !cfg.NoPriceFeed {
+		if cfg.PriceFeeds == "" {
+			str := "%s: pricefeeds is empty, set nopricefeed to disable " +
+				"fiat values"
+			err := fmt.Errorf(str, funcName)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+		for _, name := range strings.Split(cfg.PriceFeeds, ",") {
+			if _, err := pricefeed.NewProvider(name, nil); err != nil {
+				str := "%s: %v"
+				err := fmt.Errorf(str, funcName, err)
+				fmt.Fprintln(os.Stderr, err)
+				return nil, nil, err
+			}
+		}
+		if cfg.PriceFeedCache < time.Minute {
+			str := "%s: pricefeedcache must be at least 1m"
+			err := fmt.Errorf(str, funcName)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+	if cfg.StartupRetryMax < time.Second {
+		str := "%s: startupretrymax must be at least 1s"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	// HTTPS needs a certificate from either ACME or the certificate files.
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		str := "%s: tlscert and tlskey must be set together"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.TLSListen == "" && (len(cfg.ACMEDomains) != 0 || cfg.TLSCert != "") {
+		str := "%s: acmedomains and tlscert require tlslisten to be set"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.TLSListen != "" && len(cfg.ACMEDomains) == 0 && cfg.TLSCert == "" {
+		str := "%s: tlslisten requires acmedomains or tlscert and tlskey"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if len(cfg.ACMEDomains) == 1 {
+		cfg.ACMEDomains = strings.Split(cfg.ACMEDomains[0], ",")
+	}
+	if cfg.TLSCert != "" {
+		cfg.TLSCert = cleanAndExpandPath(cfg.TLSCert)
+		cfg.TLSKey = cleanAndExpandPath(cfg.TLSKey)
+	}
+	cfg.ACMECacheDir = cleanAndExpandPath(cfg.ACMECacheDir)
+
+	if cfg.OverridePath != "" {
+		cfg.OverridePath = cleanAndExpandPath(cfg.OverridePath)
+		if fi, err := os.Stat(cfg.OverridePath); err != nil || !fi.IsDir() {
+			str := "%s: overridepath %v is not a directory"
+			err := fmt.Errorf(str, funcName, cfg.OverridePath)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
+	if cfg.OTLPEndpoint != "" {
+		if err := tracing.ValidateEndpoint(cfg.OTLPEndpoint); err != nil {
+			err := fmt.Errorf("%s: otlpendpoint: %v", funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
+	// Keep configured credentials out of the logs and API responses.
+	scrub.AddSecrets(cfg.APISecret, cfg.CookieSecret, cfg.DBPassword,
+		cfg.ProxyPass, cfg.RecaptchaSecret, cfg.HCaptchaSecret,
+		cfg.SMTPPassword, cfg.StakepooldToken, cfg.TelegramBotToken)
+	scrub.AddSecrets(cfg.WalletPasswords...)
+
 	// Warn about missing config file only after all other configuration is
 	// done.  This prevents the warning on help messages and invalid
 	// options.  Note this should go directly before the return.
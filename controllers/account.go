@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+)
+
+// accountData collects the personal data the pool holds about user.  Secrets
+// only used to authenticate the user, such as their password hash and
+// tokens, are left out.
+func (controller *MainController) accountData(dbMap *gorp.DbMap,
+	user *models.User, now time.Time) (*poolapi.AccountData, error) {
+	prefs := controller.getPreferences(dbMap, user.Id)
+	data := &poolapi.AccountData{
+		Exported:       now.Unix(),
+		User:           apiUser(user),
+		TermsVersion:   user.TermsVersion,
+		Preferences:    apiPreferences(prefs),
+		TelegramLinked: prefs.TelegramChatID != 0,
+		LoginIPs:       make([]poolapi.AccountLoginIP, 0),
+		Devices:        make([]poolapi.AccountDevice, 0),
+		SecurityEvents: make([]poolapi.SecurityEvent, 0),
+		VoteHistory:    make([]poolapi.AccountVoteEvent, 0),
+	}
+
+	webhook, err := models.GetUserWebhook(dbMap, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook != nil {
+		data.Webhook = &poolapi.Webhook{
+			URL:    webhook.URL,
+			Secret: webhook.Secret,
+		}
+	}
+
+	data.Addresses, err = controller.apiUserAddresses(dbMap, user)
+	if err != nil {
+		return nil, err
+	}
+
+	loginIPs, err := models.GetUserLoginIPs(dbMap, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range loginIPs {
+		data.LoginIPs = append(data.LoginIPs, poolapi.AccountLoginIP{
+			IP:        l.IP,
+			FirstSeen: l.FirstSeen,
+			LastSeen:  l.LastSeen,
+		})
+	}
+
+	tokens, err := models.GetUserRememberTokens(dbMap, user.Id, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		data.Devices = append(data.Devices, poolapi.AccountDevice{
+			Device:   t.Device,
+			IP:       t.IP,
+			Created:  t.Created,
+			LastUsed: t.LastUsed,
+			Expires:  t.Expires,
+		})
+	}
+
+	securityEvents, err := models.GetAllUserSecurityEvents(dbMap, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range securityEvents {
+		data.SecurityEvents = append(data.SecurityEvents, poolapi.SecurityEvent{
+			Event:     e.Event,
+			Details:   e.Details,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			Created:   e.Created,
+		})
+	}
+
+	events, err := models.GetUserVoteHistory(dbMap, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		data.VoteHistory = append(data.VoteHistory, poolapi.AccountVoteEvent{
+			Ticket:      e.TicketHash,
+			Event:       e.Event,
+			BlockHeight: e.BlockHeight,
+			BlockHash:   e.BlockHash,
+			VoteHash:    e.VoteHash,
+			VoteBits:    uint16(e.VoteBits),
+			Reward:      e.Reward,
+			Time:        e.Time,
+		})
+	}
+
+	entries, err := models.GetUserAuditLog(dbMap, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	data.AuditLog = auditLogEntries(entries)
+	return data, nil
+}
+
+// AccountExport downloads the personal data the pool holds about the user as
+// JSON.
+func (controller *MainController) AccountExport(c web.C, r *http.Request) (string, int) {
+	session := controller.GetSession(c)
+	dbMap := controller.GetDbMap(c)
+
+	if session.Values["UserId"] == nil {
+		return "/", http.StatusSeeOther
+	}
+	user, err := models.GetUserById(dbMap, session.Values["UserId"].(int64))
+	if err != nil {
+		log.Errorf("GetUserById failed: %v", err)
+		return "/error", http.StatusSeeOther
+	}
+
+	now := time.Now()
+	data, err := controller.accountData(dbMap, user, now)
+	if err != nil {
+		log.Errorf("accountData failed for userid %v: %v", user.Id, err)
+		session.AddFlash("Unable to export your data, please try again "+
+			"later", "settingsError")
+		return "/settings", http.StatusSeeOther
+	}
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		log.Errorf("json.MarshalIndent failed: %v", err)
+		return "", http.StatusInternalServerError
+	}
+	log.Infof("userid %v exported their account data", user.Id)
+
+	c.Env["Content-Type"] = "application/json"
+	c.Env["ResponseHeaderMap"] = map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=\"account-%s.json\"",
+			now.UTC().Format(exportDateFormat)),
+	}
+	return string(b), http.StatusOK
+}
+
+// deleteAccount deletes the account of user and signs them out.  Their
+// personal data is removed while their tickets keep voting with the voting
+// preferences they chose and the records of the tickets are kept for the
+// accounting of the pool.  The deletion is audited without the IP address
+// it was made from.
+func (controller *MainController) deleteAccount(dbMap *gorp.DbMap, c web.C,
+	user *models.User) error {
+	err := models.DeleteUserAccount(dbMap, user, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	controller.auditFrom(dbMap, "", user.Id, AuditActionAccountDelete,
+		strconv.FormatInt(user.Id, 10), nil, nil)
+	log.Infof("userid %v deleted their account", user.Id)
+
+	controller.GetSession(c).Values["UserId"] = nil
+	controller.setRememberMe(c, "")
+	return nil
+}
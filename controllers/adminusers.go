@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcstakepool/stakepooldclient"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// userSearchLimit is the most users a search returns.
+const userSearchLimit = 50
+
+// ticketOwnerAddress returns the multisig address of the user owning the
+// ticket with hash, or the empty string when no stakepoold knows it as live.
+func (controller *MainController) ticketOwnerAddress(hash *chainhash.Hash) string {
+	for i, conn := range controller.stakepooldConnections() {
+		liveTickets, err := stakepooldclient.StakepooldGetLiveTickets(conn)
+		if err != nil {
+			log.Warnf("stakepoold host %d GetLiveTickets failed: %v", i, err)
+			continue
+		}
+		return liveTickets[*hash]
+	}
+	return ""
+}
+
+// searchUsers returns the users matching term, which is either part of
+// their email address, one of their addresses or the hash of one of their
+// tickets.  Tickets are found while they are live or once they have voted
+// or missed.
+func (controller *MainController) searchUsers(dbMap *gorp.DbMap,
+	term string) ([]models.User, error) {
+	hash, err := chainhash.NewHashFromStr(term)
+	if err != nil || len(term) != 2*chainhash.HashSize {
+		return models.SearchUsers(dbMap, term, userSearchLimit)
+	}
+
+	if msa := controller.ticketOwnerAddress(hash); msa != "" {
+		return models.SearchUsers(dbMap, msa, userSearchLimit)
+	}
+	userID, err := models.GetVoteHistoryUserID(dbMap, hash.String())
+	if err != nil {
+		// No user has a ticket with this hash.
+		return nil, nil
+	}
+	user, err := models.GetUserById(dbMap, userID)
+	if err != nil {
+		return nil, err
+	}
+	return []models.User{*user}, nil
+}
+
+// APIUserSearch lists the users whose email address contains the q
+// parameter, or whose addresses or tickets it is.  It is only available to
+// admins.
+func (controller *MainController) APIUserSearch(c web.C,
+	r *http.Request) ([]poolapi.User, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "usersearch error", errors.New("not an admin")
+	}
+	term := strings.TrimSpace(r.FormValue("q"))
+	if term == "" {
+		return nil, codes.InvalidArgument, "usersearch error", errors.New("no search term")
+	}
+
+	users, err := controller.searchUsers(dbMap, term)
+	if err != nil {
+		log.Errorf("searchUsers failed: %v", err)
+		return nil, codes.Internal, "usersearch error", errors.New("unable to search users")
+	}
+	found := make([]poolapi.User, 0, len(users))
+	for i := range users {
+		found = append(found, apiUser(&users[i]))
+	}
+	return found, codes.OK, "users successfully searched", nil
+}
+
+// apiUserParam returns the user with the id in the UserID parameter.
+func apiUserParam(dbMap *gorp.DbMap, r *http.Request) (*models.User, codes.Code, error) {
+	userID, err := strconv.ParseInt(r.FormValue("UserID"), 10, 64)
+	if err != nil {
+		return nil, codes.InvalidArgument, errors.New("invalid user id")
+	}
+	user, err := models.GetUserById(dbMap, userID)
+	if err != nil {
+		return nil, codes.NotFound, errors.New("unknown user")
+	}
+	return user, codes.OK, nil
+}
+
+// APIUser returns the user with the id in the UserID parameter along with
+// their voting preferences and tickets.  It is only available to admins.
+func (controller *MainController) APIUser(c web.C,
+	r *http.Request) (*poolapi.UserDetail, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "user error", errors.New("not an admin")
+	}
+	user, code, err := apiUserParam(dbMap, r)
+	if err != nil {
+		return nil, code, "user error", err
+	}
+
+	detail := &poolapi.UserDetail{
+		User:        apiUser(user),
+		VotingPrefs: *controller.votingPrefs(user),
+	}
+	if len(user.MultiSigAddress) != 0 {
+		detail.Tickets, code, err = controller.userTickets(dbMap, user)
+		if err != nil {
+			return nil, code, "user error", err
+		}
+	}
+	return detail, codes.OK, "user successfully retrieved", nil
+}
+
+// APIUserVerifyEmail sends the user with the id in the UserID parameter
+// another email with the link verifying their email address.  It is only
+// available to admins.
+func (controller *MainController) APIUserVerifyEmail(c web.C,
+	r *http.Request) (*poolapi.User, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "userverifyemail error", errors.New("not an admin")
+	}
+	user, code, err := apiUserParam(dbMap, r)
+	if err != nil {
+		return nil, code, "userverifyemail error", err
+	}
+	if user.EmailVerified != 0 {
+		return nil, codes.FailedPrecondition, "userverifyemail error",
+			errors.New("email address already verified")
+	}
+
+	token := user.EmailToken
+	if token == "" {
+		token = randToken()
+		if err = models.SetUserEmailToken(dbMap, user.Id, token); err != nil {
+			log.Errorf("SetUserEmailToken failed: %v", err)
+			return nil, codes.Internal, "userverifyemail error",
+				errors.New("unable to update user")
+		}
+	}
+
+	data := controller.emailData(controller.emailLanguage(dbMap, c, user.Id))
+	data["RemoteIP"] = getClientIP(r, controller.realIPHeader)
+	data["Token"] = token
+	err = controller.sendEmail(user.Email, emailSignup, data)
+	if err != nil {
+		log.Errorf("error sending verification email %v", err)
+		return nil, codes.Unavailable, "userverifyemail error",
+			errors.New("unable to send email")
+	}
+
+	controller.audit(dbMap, c, r, AuditActionVerifyEmailResend,
+		strconv.FormatInt(user.Id, 10), nil, user.Email)
+	u := apiUser(user)
+	return &u, codes.OK, "verification email successfully sent", nil
+}
+
+// errVotingSuspended is the error of users changing their voting preferences
+// while admins suspended changes to them.
+var errVotingSuspended = errors.New("changes to the voting preferences " +
+	"are suspended")
+
+// APIUserSuspendVoting suspends changes to the voting preferences of the user
+// with the id in the UserID parameter, or lifts the suspension when Suspend
+// is false.  Their tickets keep voting with the preferences they had.  It is
+// only available to admins.
+func (controller *MainController) APIUserSuspendVoting(c web.C,
+	r *http.Request) (*poolapi.User, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "usersuspendvoting error", errors.New("not an admin")
+	}
+	user, code, err := apiUserParam(dbMap, r)
+	if err != nil {
+		return nil, code, "usersuspendvoting error", err
+	}
+	suspend, err := strconv.ParseBool(r.FormValue("Suspend"))
+	if err != nil {
+		return nil, codes.InvalidArgument, "usersuspendvoting error", errors.New("invalid suspend")
+	}
+
+	before := user.VotingSuspended
+	action := AuditActionVotingResume
+	user.VotingSuspended = 0
+	if suspend {
+		action = AuditActionVotingSuspend
+		user.VotingSuspended = before
+		if before == 0 {
+			user.VotingSuspended = time.Now().Unix()
+		}
+	}
+	if user.VotingSuspended != before {
+		err = models.SetUserVotingSuspended(dbMap, user.Id, user.VotingSuspended)
+		if err != nil {
+			log.Errorf("SetUserVotingSuspended failed: %v", err)
+			return nil, codes.Internal, "usersuspendvoting error",
+				errors.New("unable to update user")
+		}
+		controller.audit(dbMap, c, r, action, strconv.FormatInt(user.Id, 10),
+			before, user.VotingSuspended)
+	}
+
+	u := apiUser(user)
+	return &u, codes.OK, "voting suspension successfully updated", nil
+}
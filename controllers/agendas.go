@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/dcrjson"
+)
+
+// defaultVoteBits are the vote bits assigned to users who have not chosen
+// any agenda preferences (last block valid, abstain on everything).
+const defaultVoteBits = uint16(1)
+
+// agendaVoter is a single set of voting preferences along with the number of
+// votes it represents.  The weight is the number of live tickets when that is
+// known, or one per user otherwise.
+type agendaVoter struct {
+	voteBits uint16
+	weight   int
+}
+
+// AgendaChoiceTally is the vote count for a single choice of an agenda, both
+// with the current user preferences and with the simulated pool defaults.
+type AgendaChoiceTally struct {
+	ID           string
+	Description  string
+	Bits         uint16
+	IsAbstain    bool
+	Current      int
+	CurrentPct   float64
+	Simulated    int
+	SimulatedPct float64
+}
+
+// AgendaOutcome is the aggregate vote of the pool for a single agenda.
+type AgendaOutcome struct {
+	ID              string
+	Description     string
+	SimulatedBits   uint16
+	Choices         []AgendaChoiceTally
+	CurrentLeader   string
+	SimulatedLeader string
+}
+
+// simulateAgendaOutcomes tallies the pool's aggregate vote on each agenda.
+// Voters still on defaultVoteBits have never expressed a preference and so are
+// assumed to follow the pool default, which for the simulated tally is taken
+// from simulatedDefaults (keyed by the index of the agenda in deployments).
+// Agendas missing from simulatedDefaults keep the current default.
+func simulateAgendaOutcomes(deployments []chaincfg.ConsensusDeployment,
+	voters []agendaVoter, simulatedDefaults map[int]uint16) []AgendaOutcome {
+
+	outcomes := make([]AgendaOutcome, 0, len(deployments))
+	for i := range deployments {
+		d := &deployments[i]
+		simulatedBits, ok := simulatedDefaults[i]
+		if !ok {
+			simulatedBits = defaultVoteBits & d.Vote.Mask
+		}
+
+		outcome := AgendaOutcome{
+			ID:            d.Vote.Id,
+			Description:   d.Vote.Description,
+			SimulatedBits: simulatedBits,
+			Choices:       make([]AgendaChoiceTally, len(d.Vote.Choices)),
+		}
+		for j, choice := range d.Vote.Choices {
+			outcome.Choices[j] = AgendaChoiceTally{
+				ID:          choice.Id,
+				Description: choice.Description,
+				Bits:        choice.Bits,
+				IsAbstain:   choice.IsAbstain,
+			}
+		}
+
+		var total int
+		for _, v := range voters {
+			current := v.voteBits & d.Vote.Mask
+			simulated := current
+			if v.voteBits == defaultVoteBits {
+				simulated = simulatedBits
+			}
+			for j := range outcome.Choices {
+				if outcome.Choices[j].Bits == current {
+					outcome.Choices[j].Current += v.weight
+				}
+				if outcome.Choices[j].Bits == simulated {
+					outcome.Choices[j].Simulated += v.weight
+				}
+			}
+			total += v.weight
+		}
+
+		var currentMax, simulatedMax int
+		for j := range outcome.Choices {
+			tally := &outcome.Choices[j]
+			if total > 0 {
+				tally.CurrentPct = float64(tally.Current) * 100 / float64(total)
+				tally.SimulatedPct = float64(tally.Simulated) * 100 / float64(total)
+			}
+			if tally.Current > currentMax {
+				currentMax = tally.Current
+				outcome.CurrentLeader = tally.ID
+			}
+			if tally.Simulated > simulatedMax {
+				simulatedMax = tally.Simulated
+				outcome.SimulatedLeader = tally.ID
+			}
+		}
+
+		outcomes = append(outcomes, outcome)
+	}
+
+	return outcomes
+}
+
+// sameVoteSemantics returns whether two agendas use the same bits for the
+// same choices, so a choice made for one means the same for the other.
+func sameVoteSemantics(a, b *chaincfg.Vote) bool {
+	if a.Mask != b.Mask || len(a.Choices) != len(b.Choices) {
+		return false
+	}
+	for i := range a.Choices {
+		ca, cb := &a.Choices[i], &b.Choices[i]
+		if ca.Id != cb.Id || ca.Bits != cb.Bits ||
+			ca.IsAbstain != cb.IsAbstain || ca.IsNo != cb.IsNo {
+			return false
+		}
+	}
+	return true
+}
+
+// migrateVoteBits carries the agenda choices in voteBits, which were made for
+// the from agendas, over to the to agendas.  Choices on agendas that are in
+// both with the same semantics are kept.  Agendas only in to start out on
+// abstain since the user never chose anything for them, and choices on
+// agendas that were dropped are discarded.  Agendas whose semantics changed
+// are set to abstain and their IDs returned as conflicts so the user can be
+// asked to choose again.
+func migrateVoteBits(voteBits uint16, from,
+	to []chaincfg.ConsensusDeployment) (uint16, []string) {
+
+	migrated := defaultVoteBits
+	var conflicts []string
+	for i := range to {
+		newVote := &to[i].Vote
+		for j := range from {
+			oldVote := &from[j].Vote
+			if oldVote.Id != newVote.Id {
+				continue
+			}
+
+			choice := voteBits & oldVote.Mask
+			var chosen bool
+			for k := range oldVote.Choices {
+				if oldVote.Choices[k].Bits == choice {
+					chosen = !oldVote.Choices[k].IsAbstain
+				}
+			}
+			if !chosen {
+				break
+			}
+			if sameVoteSemantics(oldVote, newVote) {
+				migrated |= choice
+			} else {
+				conflicts = append(conflicts, newVote.Id)
+			}
+			break
+		}
+	}
+
+	return migrated, conflicts
+}
+
+// VotingChoice is a choice of an agenda on the voting page.  ProgressPct is
+// the share of the votes cast in the current voting interval for it.
+type VotingChoice struct {
+	ID          string
+	Description string
+	Bits        uint16
+	IsAbstain   bool
+	IsNo        bool
+	ProgressPct float64
+}
+
+// VotingAgenda is an agenda of the vote version as hcd reports it, along with
+// the ID of the choice the user made on it.
+type VotingAgenda struct {
+	ID          string
+	Description string
+	Status      string
+	QuorumPct   float64
+	Choices     []VotingChoice
+	Selected    string
+}
+
+// votingAgendas returns the agendas of vi with the choices made by voteBits
+// selected.  Agendas voteBits makes no valid choice on have their abstain
+// choice selected.
+func votingAgendas(vi *dcrjson.GetVoteInfoResult, voteBits uint16) []VotingAgenda {
+	agendas := make([]VotingAgenda, 0, len(vi.Agendas))
+	for i := range vi.Agendas {
+		a := &vi.Agendas[i]
+		agenda := VotingAgenda{
+			ID:          a.Id,
+			Description: a.Description,
+			Status:      a.Status,
+			QuorumPct:   a.QuorumProgress * 100,
+			Choices:     make([]VotingChoice, len(a.Choices)),
+		}
+		var abstain string
+		for j := range a.Choices {
+			choice := &a.Choices[j]
+			agenda.Choices[j] = VotingChoice{
+				ID:          choice.Id,
+				Description: choice.Description,
+				Bits:        choice.Bits,
+				IsAbstain:   choice.IsAbstain,
+				IsNo:        choice.IsNo,
+				ProgressPct: choice.Progress * 100,
+			}
+			if choice.IsAbstain {
+				abstain = choice.Id
+			}
+			if voteBits&a.Mask == choice.Bits {
+				agenda.Selected = choice.Id
+			}
+		}
+		if agenda.Selected == "" {
+			agenda.Selected = abstain
+		}
+		agendas = append(agendas, agenda)
+	}
+
+	return agendas
+}
+
+// voteBitsForChoices returns the vote bits that make the choices, keyed by
+// agenda ID, on the agendas of vi.  There must be a choice of the agenda for
+// every agenda and no choice for an agenda vi doesn't have.
+func voteBitsForChoices(vi *dcrjson.GetVoteInfoResult,
+	choices map[string]string) (uint16, error) {
+
+	voteBits := defaultVoteBits
+	for i := range vi.Agendas {
+		a := &vi.Agendas[i]
+		id, ok := choices[a.Id]
+		if !ok {
+			return 0, fmt.Errorf("no choice for agenda %s", a.Id)
+		}
+		var found bool
+		for j := range a.Choices {
+			if a.Choices[j].Id == id {
+				voteBits |= a.Choices[j].Bits
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid choice %q for agenda %s", id,
+				a.Id)
+		}
+	}
+	if len(choices) != len(vi.Agendas) {
+		return 0, fmt.Errorf("choices for unknown agendas")
+	}
+
+	return voteBits, nil
+}
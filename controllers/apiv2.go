@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcstakepool/system"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// APIv2 handles version 2 API requests.  On top of the version 1 commands,
+// which it serves unchanged, it has the purchase info under a consistent name,
+// lets users both get and set their voting preferences, lists and adds their
+// voting addresses, verifies their redeem scripts and estimates the rewards
+// of tickets.  Failures are answered with an error object by
+// system.APIv2Handler.
+func (controller *MainController) APIv2(c web.C, r *http.Request) *system.APIResponse {
+	var code codes.Code
+	var response string
+	var data interface{}
+	var err error
+
+	// The version 1 commands record the use of the API token themselves.
+	switch command := c.URLParams["command"]; {
+	case r.Method == "GET" && command == "addresses":
+		data, code, response, err = controller.APIAddresses(c, r)
+	case r.Method == "POST" && command == "addresses":
+		data, code, response, err = controller.APIAddressesPost(c, r)
+	case r.Method == "GET" && command == "purchaseinfo":
+		data, code, response, err = controller.APIPurchaseInfo(c, r)
+	case r.Method == "GET" && command == "rewardestimate":
+		data, code, response, err = controller.APIRewardEstimate(c, r)
+	case r.Method == "GET" && command == "votingprefs":
+		data, code, response, err = controller.APIVotingPrefs(c, r)
+	case r.Method == "POST" && command == "votingprefs":
+		data, code, response, err = controller.APIVotingPrefsPost(c, r)
+	case r.Method == "POST" && command == "verifyscript":
+		data, code, response, err = controller.APIVerifyScript(c, r)
+	default:
+		return controller.API(c, r)
+	}
+	controller.recordAPITokenUse(c, r)
+
+	return apiResponse(data, code, response, err)
+}
+
+// votingPrefs returns the voting preferences of user.
+func (controller *MainController) votingPrefs(user *models.User) *poolapi.VotingPrefs {
+	voteBits := uint16(user.VoteBits)
+	prefs := &poolapi.VotingPrefs{
+		VoteBits:          voteBits,
+		VoteBitsVersion:   uint32(user.VoteBitsVersion),
+		VoteBitsReconfirm: user.VoteBitsReconfirm != 0,
+		VotingSuspended:   user.VotingSuspended != 0,
+		Choices:           make(map[string]string),
+	}
+	for _, d := range controller.getAgendas() {
+		for _, choice := range d.Vote.Choices {
+			if voteBits&d.Vote.Mask == choice.Bits {
+				prefs.Choices[d.Vote.Id] = choice.Id
+			}
+		}
+	}
+	return prefs
+}
+
+// APIVotingPrefs returns the voting preferences of the user.
+func (controller *MainController) APIVotingPrefs(c web.C,
+	r *http.Request) (*poolapi.VotingPrefs, codes.Code, string, error) {
+	if c.Env["APIUserID"] == nil {
+		return nil, codes.Unauthenticated, "votingprefs error", errors.New("invalid api token")
+	}
+
+	user, err := models.GetUserById(controller.GetDbMap(c), c.Env["APIUserID"].(int64))
+	if err != nil {
+		log.Errorf("GetUserById failed: %v", err)
+		return nil, codes.Internal, "votingprefs error", errors.New("unable to fetch user")
+	}
+	return controller.votingPrefs(user), codes.OK,
+		"voting preferences successfully retrieved", nil
+}
+
+// APIVotingPrefsPost sets the vote bits of the user to VoteBits and returns
+// their updated voting preferences.
+func (controller *MainController) APIVotingPrefsPost(c web.C,
+	r *http.Request) (*poolapi.VotingPrefs, codes.Code, string, error) {
+	_, code, response, err := controller.APIVoting(c, r)
+	if err != nil {
+		return nil, code, "votingprefs error", err
+	}
+	prefs, _, _, err := controller.APIVotingPrefs(c, r)
+	if err != nil {
+		return nil, codes.Internal, "votingprefs error", err
+	}
+	return prefs, code, response, nil
+}
@@ -0,0 +1,328 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Administrative actions recorded in the audit log.
+const (
+	AuditActionInviteIssue        = "invite.issue"
+	AuditActionInviteRevoke       = "invite.revoke"
+	AuditActionLowFeeTicketAdd    = "lowfeeticket.add"
+	AuditActionLowFeeTicketRemove = "lowfeeticket.remove"
+	AuditActionLoginUnlock        = "login.unlock"
+	AuditActionMaintenanceEnable  = "maintenance.enable"
+	AuditActionMaintenanceDisable = "maintenance.disable"
+	AuditActionStakepooldAdd      = "stakepoold.add"
+	AuditActionStakepooldRemove   = "stakepoold.remove"
+	AuditActionStakepooldRescan   = "stakepoold.rescan"
+	AuditActionVerifyEmailResend  = "user.verifyemail"
+	AuditActionVotingSuspend      = "user.votingsuspend"
+	AuditActionVotingResume       = "user.votingresume"
+)
+
+// Account changes recorded in the audit log.  The actor is the user whose
+// account changed.
+const (
+	AuditActionAccountDelete = "user.delete"
+	AuditActionEmailChange   = "user.email"
+	AuditActionTermsAccept   = "user.terms"
+)
+
+// auditTimeFormat is the format of the audit log times shown to admins.
+const auditTimeFormat = "2006-01-02 15:04:05 MST"
+
+// auditColumns are the columns of a CSV export of the audit log.
+var auditColumns = []string{"ID", "Created", "ActorUserID", "ActorIP",
+	"Action", "Target", "Before", "After"}
+
+// auditActor returns the user id of the admin making a request, whether they
+// are logged in or use an API token.
+func (controller *MainController) auditActor(c web.C) int64 {
+	if uid, ok := c.Env["APIUserID"].(int64); ok {
+		return uid
+	}
+	uid, _ := controller.GetSession(c).Values["UserId"].(int64)
+	return uid
+}
+
+// auditValue encodes v for the Before or After column of the audit log.  A
+// nil v is stored as the empty string.
+func auditValue(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// audit records that the admin making r performed action on target, changing
+// it from before to after.  before is nil when the action created target and
+// after is nil when it removed it.  The action has already happened, so a
+// failure to record it is only logged.
+func (controller *MainController) audit(dbMap *gorp.DbMap, c web.C,
+	r *http.Request, action, target string, before, after interface{}) {
+	controller.auditAs(dbMap, r, controller.auditActor(c), action, target,
+		before, after)
+}
+
+// auditAs is audit for requests made on behalf of the user with id actor
+// without them being logged in, such as following a link sent by email.
+func (controller *MainController) auditAs(dbMap *gorp.DbMap, r *http.Request,
+	actor int64, action, target string, before, after interface{}) {
+	controller.auditFrom(dbMap, getClientIP(r, controller.realIPHeader),
+		actor, action, target, before, after)
+}
+
+// auditFrom is auditAs for a request from ip, which is empty when it may not
+// be kept, as when users delete their accounts.
+func (controller *MainController) auditFrom(dbMap *gorp.DbMap, ip string,
+	actor int64, action, target string, before, after interface{}) {
+	entry := &models.AuditLog{
+		ActorUid: actor,
+		ActorIP:  ip,
+		Action:   action,
+		Target:   target,
+		Created:  time.Now().Unix(),
+	}
+
+	var err error
+	if entry.Before, err = auditValue(before); err == nil {
+		entry.After, err = auditValue(after)
+	}
+	if err == nil {
+		err = models.InsertAuditLog(dbMap, entry)
+	}
+	if err != nil {
+		log.Errorf("unable to record %s of %s by userid %d in the audit log: %v",
+			action, target, entry.ActorUid, err)
+	}
+}
+
+// auditLogEntries converts audit log rows to their API form.
+func auditLogEntries(entries []models.AuditLog) []poolapi.AuditLogEntry {
+	apiEntries := make([]poolapi.AuditLogEntry, 0, len(entries))
+	for _, e := range entries {
+		apiEntry := poolapi.AuditLogEntry{
+			ID:          e.Id,
+			ActorUserID: e.ActorUid,
+			ActorIP:     e.ActorIP,
+			Action:      e.Action,
+			Target:      e.Target,
+			Created:     e.Created,
+		}
+		if e.Before != "" {
+			apiEntry.Before = json.RawMessage(e.Before)
+		}
+		if e.After != "" {
+			apiEntry.After = json.RawMessage(e.After)
+		}
+		apiEntries = append(apiEntries, apiEntry)
+	}
+	return apiEntries
+}
+
+// auditLogAll returns every audit log entry matching the filters of q, in
+// the order of q, for exports.
+func auditLogAll(dbMap *gorp.DbMap, q *models.ListQuery) ([]models.AuditLog, error) {
+	page := *q
+	page.Limit = models.MaxListLimit
+	page.Offset = 0
+
+	var all []models.AuditLog
+	for {
+		entries, total, err := models.ListAuditLog(dbMap, &page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, entries...)
+		page.Offset += len(entries)
+		if len(entries) == 0 || int64(page.Offset) >= total {
+			return all, nil
+		}
+	}
+}
+
+// auditCSV formats audit log entries as CSV with a header row.
+func auditCSV(entries []models.AuditLog) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(auditColumns)
+	for _, e := range entries {
+		w.Write([]string{
+			strconv.FormatInt(e.Id, 10),
+			time.Unix(e.Created, 0).UTC().Format(time.RFC3339),
+			strconv.FormatInt(e.ActorUid, 10),
+			e.ActorIP,
+			e.Action,
+			e.Target,
+			e.Before,
+			e.After,
+		})
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+// auditLogURL returns the URL of the audit log page at offset with the
+// filters of q, or of its export in format when format isn't empty.
+func auditLogURL(q *models.ListQuery, offset int, format string) string {
+	v := url.Values{}
+	for field, value := range q.Filters {
+		v.Set(field, value)
+	}
+	if format != "" {
+		v.Set("format", format)
+	} else if offset > 0 {
+		v.Set("offset", strconv.Itoa(offset))
+	}
+	if len(v) == 0 {
+		return "/adminaudit"
+	}
+	return "/adminaudit?" + v.Encode()
+}
+
+// auditLogRow is an audit log entry as shown on the admin audit page.
+type auditLogRow struct {
+	models.AuditLog
+	Time string
+}
+
+// AdminAudit renders the audit log of administrative actions, newest first.
+// When format is csv or json it instead downloads every entry matching the
+// filters.
+func (controller *MainController) AdminAudit(c web.C, r *http.Request) (string, int) {
+	t := controller.GetTemplate(c)
+	dbMap := controller.GetDbMap(c)
+
+	isAdmin, err := controller.isAdmin(c, r)
+	if !isAdmin {
+		log.Warnf("isAdmin check failed: %v", err)
+		return "", http.StatusUnauthorized
+	}
+
+	q, err := parseListQuery(r, models.AuditLogListFields)
+	if err != nil {
+		c.Env["FlashError"] = []string{"Invalid audit log query: " + err.Error()}
+		q = &models.ListQuery{
+			Limit:   models.DefaultListLimit,
+			Sort:    models.AuditLogListFields.DefaultSort,
+			Filters: make(map[string]string),
+		}
+	}
+
+	switch format := r.FormValue("format"); format {
+	case "csv", "json":
+		entries, err := auditLogAll(dbMap, q)
+		if err != nil {
+			log.Errorf("ListAuditLog failed: %v", err)
+			return "", http.StatusInternalServerError
+		}
+		var body string
+		if format == "json" {
+			b, err := json.Marshal(auditLogEntries(entries))
+			if err != nil {
+				log.Errorf("json.Marshal failed: %v", err)
+				return "", http.StatusInternalServerError
+			}
+			body = string(b)
+			c.Env["Content-Type"] = "application/json"
+		} else {
+			body, err = auditCSV(entries)
+			if err != nil {
+				log.Errorf("auditCSV failed: %v", err)
+				return "", http.StatusInternalServerError
+			}
+			c.Env["Content-Type"] = "text/csv"
+		}
+		c.Env["ResponseHeaderMap"] = map[string]string{
+			"Content-Disposition": fmt.Sprintf("attachment; filename=\"audit-%s.%s\"",
+				time.Now().UTC().Format(exportDateFormat), format),
+		}
+		return body, http.StatusOK
+	}
+
+	if r.FormValue("sort") == "" {
+		q.Desc = true
+	}
+	entries, total, err := models.ListAuditLog(dbMap, q)
+	if err != nil {
+		log.Errorf("ListAuditLog failed: %v", err)
+		return "/error", http.StatusSeeOther
+	}
+
+	rows := make([]auditLogRow, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, auditLogRow{
+			AuditLog: e,
+			Time:     time.Unix(e.Created, 0).UTC().Format(auditTimeFormat),
+		})
+	}
+
+	c.Env["Admin"] = isAdmin
+	c.Env["IsAdminAudit"] = true
+	c.Env["AuditLog"] = rows
+	c.Env["Total"] = total
+	c.Env["Filters"] = q.Filters
+	if q.Offset > 0 {
+		prev := q.Offset - q.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		c.Env["PrevURL"] = auditLogURL(q, prev, "")
+	}
+	if int64(q.Offset+len(entries)) < total {
+		c.Env["NextURL"] = auditLogURL(q, q.Offset+q.Limit, "")
+	}
+	c.Env["ExportCSVURL"] = auditLogURL(q, 0, "csv")
+	c.Env["ExportJSONURL"] = auditLogURL(q, 0, "json")
+
+	widgets := controller.Parse(t, "admin/audit", c.Env)
+
+	c.Env["Title"] = "Hcd Stake Pool - Audit Log (Admin)"
+	c.Env["Content"] = template.HTML(widgets)
+
+	return controller.Parse(t, "main", c.Env), http.StatusOK
+}
+
+// APIAuditLog lists the administrative actions recorded in the audit log.  It
+// is only available to admins.
+func (controller *MainController) APIAuditLog(c web.C,
+	r *http.Request) (*poolapi.List, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "auditlog error", errors.New("not an admin")
+	}
+	q, err := parseListQuery(r, models.AuditLogListFields)
+	if err != nil {
+		return nil, codes.InvalidArgument, "auditlog error", err
+	}
+
+	entries, total, err := models.ListAuditLog(dbMap, q)
+	if err != nil {
+		log.Errorf("ListAuditLog failed: %v", err)
+		return nil, codes.Internal, "auditlog error", errors.New("unable to fetch audit log")
+	}
+	return newList(auditLogEntries(entries), total, q), codes.OK,
+		"auditlog successfully retrieved", nil
+}
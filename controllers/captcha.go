@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CAPTCHA providers users solve on the signup, password reset and settings
+// forms.
+const (
+	CaptchaRecaptcha = "recaptcha"
+	CaptchaHCaptcha  = "hcaptcha"
+)
+
+// captchaVerifyTimeout is how long the provider has to verify a response.
+const captchaVerifyTimeout = 10 * time.Second
+
+// captchaService describes how forms embed the widget of a CAPTCHA provider
+// and how its responses are verified.
+type captchaService struct {
+	scriptURL     string
+	widgetClass   string
+	responseField string
+	verifyURL     string
+}
+
+// captchaServices are the supported CAPTCHA providers.
+var captchaServices = map[string]captchaService{
+	CaptchaRecaptcha: {
+		scriptURL:     "https://www.google.com/recaptcha/api.js",
+		widgetClass:   "g-recaptcha",
+		responseField: "g-recaptcha-response",
+		verifyURL:     "https://www.google.com/recaptcha/api/siteverify",
+	},
+	CaptchaHCaptcha: {
+		scriptURL:     "https://js.hcaptcha.com/1/api.js",
+		widgetClass:   "h-captcha",
+		responseField: "h-captcha-response",
+		verifyURL:     "https://hcaptcha.com/siteverify",
+	},
+}
+
+// Captcha verifies the CAPTCHA users solve on the forms bots abuse.  It is
+// safe for concurrent access.
+type Captcha struct {
+	service captchaService
+	secret  string
+	siteKey string
+	client  *http.Client
+}
+
+// NewCaptcha returns a Captcha of provider using the secret and site key
+// registered with it.  Responses are verified through transport, or
+// http.DefaultTransport when it is nil.
+func NewCaptcha(provider, secret, siteKey string,
+	transport http.RoundTripper) (*Captcha, error) {
+	service, ok := captchaServices[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown captcha provider %q", provider)
+	}
+	return &Captcha{
+		service: service,
+		secret:  secret,
+		siteKey: siteKey,
+		client: &http.Client{
+			Timeout:   captchaVerifyTimeout,
+			Transport: transport,
+		},
+	}, nil
+}
+
+// ScriptURL is the script forms load to render the widget.
+func (captcha *Captcha) ScriptURL() string {
+	return captcha.service.scriptURL
+}
+
+// WidgetClass is the class of the element the widget is rendered in.
+func (captcha *Captcha) WidgetClass() string {
+	return captcha.service.widgetClass
+}
+
+// SiteKey is the site key the widget is rendered with.
+func (captcha *Captcha) SiteKey() string {
+	return captcha.siteKey
+}
+
+// Verify asks the provider whether the CAPTCHA response posted with r was
+// solved by a user at remoteIP, returning an error when it wasn't.
+func (captcha *Captcha) Verify(r *http.Request, remoteIP string) error {
+	response := r.FormValue(captcha.service.responseField)
+	if response == "" {
+		return fmt.Errorf("no %s", captcha.service.responseField)
+	}
+
+	resp, err := captcha.client.PostForm(captcha.service.verifyURL, url.Values{
+		"secret":   {captcha.secret},
+		"response": {response},
+		"remoteip": {remoteIP},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("verification failed: %s", resp.Status)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid verification response: %v", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("not solved: %s",
+			strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCaptchaVerify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("secret") != "secret" || r.FormValue("remoteip") != "192.0.2.1" {
+			t.Errorf("unexpected verification request %v", r.Form)
+		}
+		if r.FormValue("response") == "solved" {
+			fmt.Fprint(w, `{"success": true}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": false, "error-codes": ["invalid-input-response"]}`)
+	}))
+	defer srv.Close()
+
+	captcha, err := NewCaptcha(CaptchaHCaptcha, "secret", "sitekey", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	captcha.service.verifyURL = srv.URL
+	post := func(form url.Values) *http.Request {
+		r := httptest.NewRequest("POST", "/signup",
+			strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+
+	tests := []struct {
+		form  url.Values
+		valid bool
+	}{
+		{url.Values{"h-captcha-response": {"solved"}}, true},
+		{url.Values{"h-captcha-response": {"guessed"}}, false},
+		{url.Values{"g-recaptcha-response": {"solved"}}, false},
+		{url.Values{}, false},
+	}
+	for _, test := range tests {
+		err := captcha.Verify(post(test.form), "192.0.2.1")
+		if (err == nil) != test.valid {
+			t.Errorf("Verify(%v) = %v, want valid %v", test.form, err,
+				test.valid)
+		}
+	}
+
+	if _, err := NewCaptcha("none", "", "", nil); err == nil {
+		t.Error("NewCaptcha accepted an unknown provider")
+	}
+}
@@ -0,0 +1,366 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/notifier"
+	"github.com/coolsnady/hcstakepool/stakepooldclient"
+	"github.com/coolsnady/hcutil"
+	"github.com/go-gorp/gorp"
+)
+
+// consistencyReportProblems is how many problems of a consistency check are
+// listed in the notification to the operators.
+const consistencyReportProblems = 5
+
+// ConsistencyReport is the outcome of cross-checking the tickets in the
+// database, the voting wallets, the stakepoold backends and the ticket pool of
+// hcd.  Repaired lists the inconsistencies that were fixed and Problems those
+// that need the attention of an operator.
+type ConsistencyReport struct {
+	Checked  time.Time
+	Repaired []string
+	Problems []string
+}
+
+// consistencyState is the last consistency report.
+type consistencyState struct {
+	mtx  sync.Mutex
+	last *ConsistencyReport
+}
+
+func (r *ConsistencyReport) repaired(format string, args ...interface{}) {
+	text := fmt.Sprintf(format, args...)
+	log.Infof("Consistency check: %s", text)
+	r.Repaired = append(r.Repaired, text)
+}
+
+func (r *ConsistencyReport) problem(format string, args ...interface{}) {
+	text := fmt.Sprintf(format, args...)
+	log.Warnf("Consistency check: %s", text)
+	r.Problems = append(r.Problems, text)
+}
+
+// duplicateLowFeeTickets returns the rows of tickets added to the low fee
+// tickets more than once, except for the oldest row of each ticket.
+func duplicateLowFeeTickets(rows []models.LowFeeTicket) []models.LowFeeTicket {
+	oldest := make(map[string]models.LowFeeTicket, len(rows))
+	var dups []models.LowFeeTicket
+	for _, row := range rows {
+		kept, ok := oldest[row.TicketHash]
+		switch {
+		case !ok:
+			oldest[row.TicketHash] = row
+		case row.Id < kept.Id:
+			oldest[row.TicketHash] = row
+			dups = append(dups, kept)
+		default:
+			dups = append(dups, row)
+		}
+	}
+	return dups
+}
+
+// lowFeeTicketsDiffer returns whether stakepoold votes for a different set of
+// low fee tickets than the votable ones in the database.
+func lowFeeTicketsDiffer(votable []models.LowFeeTicket,
+	added map[chainhash.Hash]string) bool {
+	seen := make(map[chainhash.Hash]struct{}, len(votable))
+	for _, ticket := range votable {
+		hash, err := chainhash.NewHashFromStr(ticket.TicketHash)
+		if err != nil {
+			// Invalid rows are never sent to stakepoold.
+			continue
+		}
+		if _, ok := added[*hash]; !ok {
+			return true
+		}
+		seen[*hash] = struct{}{}
+	}
+	return len(seen) != len(added)
+}
+
+// checkLowFeeTickets removes duplicate low fee ticket rows and sends the
+// votable low fee tickets again to the stakepoold backends voting for others.
+func (controller *MainController) checkLowFeeTickets(ctx context.Context,
+	dbMap *gorp.DbMap, report *ConsistencyReport) {
+	rows, err := models.GetAllLowFeeTickets(dbMap)
+	if err != nil {
+		report.problem("unable to fetch the low fee tickets: %v", err)
+		return
+	}
+	for i := range rows {
+		_, err := chainhash.NewHashFromStr(rows[i].TicketHash)
+		if err != nil {
+			report.problem("low fee ticket row %d has the invalid ticket "+
+				"hash %q", rows[i].Id, rows[i].TicketHash)
+		}
+	}
+	for _, row := range duplicateLowFeeTickets(rows) {
+		row := row
+		if _, err := dbMap.Delete(&row); err != nil {
+			report.problem("unable to remove duplicate low fee ticket row "+
+				"%d of ticket %s: %v", row.Id, row.TicketHash, err)
+			continue
+		}
+		report.repaired("removed duplicate low fee ticket row %d of ticket "+
+			"%s", row.Id, row.TicketHash)
+	}
+
+	if !controller.enableStakepoold {
+		return
+	}
+	votable, err := models.GetVotableLowFeeTickets(dbMap)
+	if err != nil {
+		report.problem("unable to fetch the votable low fee tickets: %v", err)
+		return
+	}
+	hosts, conns := controller.stakepooldBackends()
+	for i, conn := range conns {
+		added, err := stakepooldclient.StakepooldGetAddedLowFeeTickets(conn)
+		if err != nil {
+			report.problem("unable to list the low fee tickets of "+
+				"stakepoold %s: %v", hosts[i], err)
+			continue
+		}
+		if !lowFeeTicketsDiffer(votable, added) {
+			continue
+		}
+		_, err = stakepooldclient.StakepooldSetAddedLowFeeTickets(ctx, conn,
+			votable)
+		if err != nil {
+			report.problem("stakepoold %s votes for %d low fee tickets "+
+				"instead of %d and updating them failed: %v", hosts[i],
+				len(added), len(votable), err)
+			continue
+		}
+		report.repaired("sent the %d votable low fee tickets to stakepoold "+
+			"%s, which voted for %d", len(votable), hosts[i], len(added))
+	}
+}
+
+// walletLiveTickets returns the live and immature tickets of the pool users
+// according to the voting wallets, by the multisig address they were bought
+// to, which is any of the voting addresses of their users.
+func (controller *MainController) walletLiveTickets(dbMap *gorp.DbMap) (map[chainhash.Hash]string, error) {
+	addresses, err := models.GetAllVotingAddresses(dbMap)
+	if err != nil {
+		return nil, err
+	}
+	tickets := make(map[chainhash.Hash]string)
+	for _, a := range addresses {
+		addr, err := hcutil.DecodeAddress(a.MultiSigAddress)
+		if err != nil {
+			log.Warnf("Invalid multisig address %q of userid %d: %v",
+				a.MultiSigAddress, a.UserId, err)
+			continue
+		}
+		hashes, err := controller.rpcServers.GetUnspentUserTickets(addr)
+		if err != nil {
+			return nil, err
+		}
+		for _, hash := range hashes {
+			tickets[*hash] = a.MultiSigAddress
+		}
+	}
+	return tickets, nil
+}
+
+// compareLiveTickets compares the live tickets of a stakepoold with those of
+// the voting wallets listed before and after them.  It returns how many
+// tickets stakepoold tracks that the voting wallets know in neither listing
+// and the tickets of both wallet listings stakepoold misses, sorted.  Tickets
+// bought, voted or missed while stakepoold was asked are only in one of the
+// wallet listings and aren't counted.
+func compareLiveTickets(walletBefore, walletAfter,
+	live map[chainhash.Hash]string) (int, []chainhash.Hash) {
+	var unknown int
+	for hash := range live {
+		_, before := walletBefore[hash]
+		_, after := walletAfter[hash]
+		if !before && !after {
+			unknown++
+		}
+	}
+
+	var missing []chainhash.Hash
+	for hash := range walletBefore {
+		_, after := walletAfter[hash]
+		_, tracked := live[hash]
+		if after && !tracked {
+			missing = append(missing, hash)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].String() < missing[j].String()
+	})
+	return unknown, missing
+}
+
+// checkLiveTickets compares the live tickets of every stakepoold backend with
+// those of the voting wallets, which are listed before and after the
+// backends so tickets that change in between aren't reported.  stakepoold is
+// asked to track the wallet tickets it misses after checking them against its
+// wallet and the ticket pool of hcd.  Tickets stakepoold tracks that aren't
+// live in the voting wallets are reported.
+func (controller *MainController) checkLiveTickets(ctx context.Context,
+	dbMap *gorp.DbMap, report *ConsistencyReport) {
+	walletBefore, err := controller.walletLiveTickets(dbMap)
+	if err != nil {
+		report.problem("unable to list the tickets of the voting wallets: "+
+			"%v", err)
+		return
+	}
+
+	hosts, conns := controller.stakepooldBackends()
+	lives := make([]map[chainhash.Hash]string, len(conns))
+	listed := make([]bool, len(conns))
+	for i, conn := range conns {
+		live, err := stakepooldclient.StakepooldGetLiveTickets(conn)
+		if err != nil {
+			report.problem("unable to list the live tickets of stakepoold "+
+				"%s: %v", hosts[i], err)
+			continue
+		}
+		lives[i], listed[i] = live, true
+	}
+
+	walletTickets, err := controller.walletLiveTickets(dbMap)
+	if err != nil {
+		report.problem("unable to list the tickets of the voting wallets: "+
+			"%v", err)
+		return
+	}
+
+	for i, conn := range conns {
+		if !listed[i] {
+			continue
+		}
+		unknown, missing := compareLiveTickets(walletBefore, walletTickets,
+			lives[i])
+		if unknown > 0 {
+			report.problem("stakepoold %s tracks %d live tickets the voting "+
+				"wallets don't know", hosts[i], unknown)
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		version, err := stakepooldclient.StakepooldVersion(conn)
+		if err != nil || !version.Supports(stakepooldclient.CapabilityMissingTickets) {
+			report.problem("stakepoold %s misses %d live tickets of the "+
+				"voting wallets and can't add them", hosts[i], len(missing))
+			continue
+		}
+		results, err := stakepooldclient.StakepooldAddMissingTickets(ctx,
+			conn, missing)
+		if err != nil {
+			report.problem("stakepoold %s misses %d live tickets of the "+
+				"voting wallets and adding them failed: %v", hosts[i],
+				len(missing), err)
+			continue
+		}
+		var added int
+		for _, r := range results {
+			switch r.Status {
+			case pb.MissingTicketStatus_MISSING_TICKET_ADDED:
+				added++
+			case pb.MissingTicketStatus_MISSING_TICKET_ALREADY_TRACKED,
+				pb.MissingTicketStatus_MISSING_TICKET_IGNORED_LOW_FEE,
+				pb.MissingTicketStatus_MISSING_TICKET_NOT_LIVE:
+				// Immature tickets aren't in the ticket pool of hcd
+				// yet, and the ticket may have been voted or been
+				// seen by stakepoold since it was listed.
+			case pb.MissingTicketStatus_MISSING_TICKET_NOT_IN_WALLET:
+				report.problem("ticket %v of %s is live in the voting "+
+					"wallets but not in the wallet of stakepoold %s",
+					r.Ticket, walletTickets[r.Ticket], hosts[i])
+			case pb.MissingTicketStatus_MISSING_TICKET_NOT_POOL_TICKET:
+				report.problem("stakepoold %s doesn't consider ticket %v "+
+					"of %s a pool ticket", hosts[i], r.Ticket,
+					walletTickets[r.Ticket])
+			default:
+				report.problem("stakepoold %s failed to add ticket %v: %s",
+					hosts[i], r.Ticket, r.Error)
+			}
+		}
+		if added > 0 {
+			report.repaired("stakepoold %s now tracks %d live tickets of "+
+				"the voting wallets it missed", hosts[i], added)
+		}
+	}
+}
+
+// CheckConsistency cross-checks the tickets in the database, the voting
+// wallets, the stakepoold backends and the ticket pool of hcd, repairs the
+// inconsistencies it can and returns a report of what it did and what needs
+// the attention of an operator.
+func (controller *MainController) CheckConsistency(ctx context.Context,
+	dbMap *gorp.DbMap) *ConsistencyReport {
+	report := &ConsistencyReport{Checked: time.Now()}
+	controller.checkLowFeeTickets(ctx, dbMap, report)
+	if controller.enableStakepoold {
+		controller.checkLiveTickets(ctx, dbMap, report)
+	}
+
+	controller.consistency.mtx.Lock()
+	controller.consistency.last = report
+	controller.consistency.mtx.Unlock()
+	return report
+}
+
+// LastConsistencyReport returns the report of the last consistency check, or
+// nil when none ran yet.
+func (controller *MainController) LastConsistencyReport() *ConsistencyReport {
+	controller.consistency.mtx.Lock()
+	defer controller.consistency.mtx.Unlock()
+	return controller.consistency.last
+}
+
+// notifyConsistencyReport tells the operators about the problems a
+// consistency check found and what it repaired.
+func (controller *MainController) notifyConsistencyReport(report *ConsistencyReport) {
+	if len(report.Repaired) > 0 {
+		text := fmt.Sprintf("The consistency check of the stake pool at %s "+
+			"repaired %d inconsistencies.", controller.baseURL,
+			len(report.Repaired))
+		controller.notifyOperators(notifier.SeverityInfo, text)
+	}
+	if len(report.Problems) == 0 {
+		return
+	}
+	problems := report.Problems
+	if len(problems) > consistencyReportProblems {
+		problems = problems[:consistencyReportProblems]
+	}
+	text := fmt.Sprintf("The consistency check of the stake pool at %s "+
+		"found %d problems that need attention: %s", controller.baseURL,
+		len(report.Problems), strings.Join(problems, "; "))
+	if len(problems) < len(report.Problems) {
+		text += "; see the admin status page for the rest"
+	}
+	controller.notifyOperators(notifier.SeverityWarning, text+".")
+}
+
+// ConsistencyCheckHandler runs a consistency check every interval and
+// notifies the operators of its outcome.  It never returns.
+func (controller *MainController) ConsistencyCheckHandler(dbMap *gorp.DbMap,
+	interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		report := controller.CheckConsistency(context.Background(), dbMap)
+		log.Infof("Consistency check repaired %d inconsistencies and found "+
+			"%d problems", len(report.Repaired), len(report.Problems))
+		controller.notifyConsistencyReport(report)
+	}
+}
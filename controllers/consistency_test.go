@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/models"
+)
+
+func TestDuplicateLowFeeTickets(t *testing.T) {
+	a := chainhash.Hash{1}.String()
+	b := chainhash.Hash{2}.String()
+	rows := []models.LowFeeTicket{
+		{Id: 3, TicketHash: a},
+		{Id: 1, TicketHash: a},
+		{Id: 2, TicketHash: b},
+		{Id: 4, TicketHash: a},
+	}
+	dups := duplicateLowFeeTickets(rows)
+	if len(dups) != 2 || dups[0].Id != 3 || dups[1].Id != 4 {
+		t.Errorf("duplicates %v, want rows 3 and 4", dups)
+	}
+}
+
+func TestLowFeeTicketsDiffer(t *testing.T) {
+	a, b := chainhash.Hash{1}, chainhash.Hash{2}
+	votable := []models.LowFeeTicket{
+		{TicketHash: a.String(), TicketAddress: "msa"},
+		{TicketHash: "invalid"},
+		{TicketHash: b.String(), TicketAddress: "msa"},
+	}
+	tests := []struct {
+		added map[chainhash.Hash]string
+		want  bool
+	}{
+		{map[chainhash.Hash]string{a: "msa", b: "msa"}, false},
+		{map[chainhash.Hash]string{a: "msa"}, true},
+		{map[chainhash.Hash]string{a: "msa", b: "msa", {3}: "msa"}, true},
+		{nil, true},
+	}
+	for i, test := range tests {
+		if got := lowFeeTicketsDiffer(votable, test.added); got != test.want {
+			t.Errorf("test %d: got %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestCompareLiveTickets(t *testing.T) {
+	kept, bought, voted := chainhash.Hash{1}, chainhash.Hash{2}, chainhash.Hash{3}
+	missed, stray := chainhash.Hash{4}, chainhash.Hash{5}
+	// bought was bought and voted was voted while stakepoold was asked.
+	before := map[chainhash.Hash]string{kept: "msa", voted: "msa",
+		missed: "msa"}
+	after := map[chainhash.Hash]string{kept: "msa", bought: "msa",
+		missed: "msa"}
+
+	tests := []struct {
+		name    string
+		live    map[chainhash.Hash]string
+		unknown int
+		missing []chainhash.Hash
+	}{
+		{"in sync", map[chainhash.Hash]string{kept: "msa", missed: "msa"},
+			0, nil},
+		{"tickets changing in between", map[chainhash.Hash]string{
+			kept: "msa", missed: "msa", bought: "msa", voted: "msa"}, 0, nil},
+		{"missing", map[chainhash.Hash]string{voted: "msa"}, 0,
+			[]chainhash.Hash{kept, missed}},
+		{"unknown", map[chainhash.Hash]string{kept: "msa", missed: "msa",
+			stray: "msa"}, 1, nil},
+		{"none", nil, 0, []chainhash.Hash{kept, missed}},
+	}
+	for _, test := range tests {
+		unknown, missing := compareLiveTickets(before, after, test.live)
+		if unknown != test.unknown || !reflect.DeepEqual(missing, test.missing) {
+			t.Errorf("%s: got %d unknown and missing %v, want %d and %v",
+				test.name, unknown, missing, test.unknown, test.missing)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Emails sent by the pool.  The subject and plain text body of an email are
+// the email/<name>/subject and email/<name>/text templates of the txt files in
+// the email folder of the views, and its HTML body is the email/<name>/html
+// template of the html files.  The bodies are then wrapped in the
+// email/layout/text and email/layout/html templates holding what every email
+// shares.  Operators override them like the page templates.
+const (
+	emailSignup              = "signup"
+	emailPasswordReset       = "passwordreset"
+	emailPasswordChange      = "passwordchange"
+	emailChangeNew           = "emailchangenew"
+	emailChangeOld           = "emailchangeold"
+	emailTicketNotifications = "ticketnotifications"
+	emailNewLogin            = "newlogin"
+	emailMissedVoteAlert     = "missedvotealert"
+	emailMissedVoteResolved  = "missedvoteresolved"
+)
+
+// emailData returns the template data every email has: the language lang it
+// is written in and the branding of the pool.  Callers add the values of the
+// email they send.
+func (controller *MainController) emailData(lang string) map[string]interface{} {
+	return map[string]interface{}{
+		"Lang":      lang,
+		"PoolName":  controller.poolName,
+		"BaseURL":   controller.baseURL,
+		"PoolEmail": controller.poolEmail,
+		"PoolLink":  controller.poolLink,
+		"LogoURL":   controller.emailLogoURL,
+	}
+}
+
+// renderEmail executes the templates of the email name with data and returns
+// its subject and its plain text and HTML bodies.  The HTML body is empty when
+// there is no HTML template for the email.
+func (controller *MainController) renderEmail(name string,
+	data map[string]interface{}) (subject, text, html string, err error) {
+	textTemplates := controller.templates.TextTemplate
+	prefix := "email/" + name + "/"
+
+	var buf bytes.Buffer
+	if err = textTemplates.ExecuteTemplate(&buf, prefix+"subject", data); err != nil {
+		return "", "", "", err
+	}
+	// Header lines can't break.
+	subject = strings.Join(strings.Fields(buf.String()), " ")
+	data["Subject"] = subject
+
+	buf.Reset()
+	if err = textTemplates.ExecuteTemplate(&buf, prefix+"text", data); err != nil {
+		return "", "", "", err
+	}
+	data["Content"] = buf.String()
+	buf.Reset()
+	if err = textTemplates.ExecuteTemplate(&buf, "email/layout/text", data); err != nil {
+		return "", "", "", err
+	}
+	text = buf.String()
+
+	htmlTemplates := controller.templates.Template
+	if htmlTemplates.Lookup(prefix+"html") == nil {
+		return subject, text, "", nil
+	}
+	buf.Reset()
+	if err = htmlTemplates.ExecuteTemplate(&buf, prefix+"html", data); err != nil {
+		return "", "", "", err
+	}
+	data["Content"] = template.HTML(buf.String())
+	buf.Reset()
+	if err = htmlTemplates.ExecuteTemplate(&buf, "email/layout/html", data); err != nil {
+		return "", "", "", err
+	}
+	return subject, text, buf.String(), nil
+}
+
+// sendEmail renders the email name with data and sends it to address.
+func (controller *MainController) sendEmail(address, name string,
+	data map[string]interface{}) error {
+	if controller.mailer == nil {
+		return errors.New("no mail server configured")
+	}
+	subject, text, html, err := controller.renderEmail(name, data)
+	if err != nil {
+		return fmt.Errorf("unable to render %s email: %v", name, err)
+	}
+	msg, err := composeEmail(controller.mailer.from, address, subject, text,
+		html, time.Now())
+	if err != nil {
+		return err
+	}
+	return controller.mailer.Send(address, msg)
+}
+
+// writeQuotedPrintable writes body to w in the quoted-printable encoding,
+// which also turns its line breaks into the CRLF line breaks of emails.
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qw, body); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// composeEmail returns the message of an email from from to to.  When html
+// isn't empty the message is multipart/alternative with both the text and
+// html bodies, and otherwise it only has the text body.
+func composeEmail(from, to, subject, text, html string, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+	header("To", to)
+	header("From", from)
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if html == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+	// Clients show the last part they support, so HTML comes last.
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	}
+	for _, part := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
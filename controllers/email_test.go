@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/i18n"
+	"github.com/coolsnady/hcstakepool/system"
+)
+
+func TestRenderEmails(t *testing.T) {
+	app := &system.Application{Funcs: (*i18n.Catalog)(nil).Funcs()}
+	if err := app.LoadTemplates("../views"); err != nil {
+		t.Fatal(err)
+	}
+	controller := &MainController{
+		baseURL:   "https://pool.example.com",
+		poolEmail: "admin@example.com",
+		poolName:  "Example & Co Pool",
+		templates: app,
+	}
+
+	emails := []string{emailSignup, emailPasswordReset, emailPasswordChange,
+		emailChangeNew, emailChangeOld, emailTicketNotifications,
+		emailNewLogin, emailMissedVoteAlert, emailMissedVoteResolved}
+	for _, name := range emails {
+		data := controller.emailData("en")
+		data["RemoteIP"] = "192.0.2.1"
+		data["Token"] = "0123abcd"
+		data["OldEmail"] = "old@example.com"
+		data["NewEmail"] = "new@example.com"
+		data["Tickets"] = []expiryEmailTicket{{Ticket: "ab12",
+			ExpiryHeight: 1000, Expires: "2018-01-01 00:00"}}
+		data["Voted"] = []ticketEmailEvent{{Ticket: "cd34", Height: 990}}
+		data["Missed"] = []ticketEmailEvent{{Ticket: "ef56"}}
+		data["Time"] = "2018-01-01 00:00 UTC"
+		data["Misses"], data["Blocks"] = int64(3), int64(144)
+		data["Height"], data["Threshold"] = int64(1000), int64(2)
+
+		subject, text, html, err := controller.renderEmail(name, data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if subject == "" || strings.Contains(subject, "\n") {
+			t.Errorf("%s: bad subject %q", name, subject)
+		}
+		if !strings.Contains(text, "Example & Co Pool") {
+			t.Errorf("%s: text body without pool name:\n%s", name, text)
+		}
+		if !strings.Contains(html, "Example &amp; Co Pool") {
+			t.Errorf("%s: html body without escaped pool name:\n%s", name, html)
+		}
+		if strings.Contains(text+html, "%!") {
+			t.Errorf("%s: bad format arguments:\n%s\n%s", name, text, html)
+		}
+	}
+}
+
+func TestComposeEmail(t *testing.T) {
+	date := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	text := "Verificación\nhttps://pool.example.com/emailverify?t=1\n"
+	html := "<p>Verificación</p>\n"
+
+	msg, err := composeEmail("pool@example.com", "user@example.com",
+		"Verificación de correo", text, html, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != "Verificación de correo" {
+		t.Errorf("subject %q, %v", subject, err)
+	}
+	if d, err := m.Header.Date(); err != nil || !d.Equal(date) {
+		t.Errorf("date %v, %v", d, err)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type %q, %v", mediaType, err)
+	}
+
+	r := multipart.NewReader(m.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", strings.Replace(text, "\n", "\r\n", -1)},
+		{"text/html; charset=utf-8", strings.Replace(html, "\n", "\r\n", -1)},
+	} {
+		p, err := r.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct := p.Header.Get("Content-Type"); ct != want.contentType {
+			t.Errorf("part content type %q, want %q", ct, want.contentType)
+		}
+		// The reader decodes quoted-printable parts.
+		b, err := ioutil.ReadAll(p)
+		if err != nil || string(b) != want.body {
+			t.Errorf("part body %q, %v, want %q", b, err, want.body)
+		}
+	}
+	if _, err := r.NextPart(); err == nil {
+		t.Error("unexpected part")
+	}
+
+	msg, err = composeEmail("pool@example.com", "user@example.com",
+		"Verification", text, "", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := m.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("content type %q", ct)
+	}
+}
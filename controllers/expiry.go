@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"sort"
+	"time"
+)
+
+// ExpiringTicket is a live ticket that will expire within the configured
+// warning window unless it is selected to vote first.
+type ExpiringTicket struct {
+	Ticket          string
+	ExpiryHeight    int64
+	BlocksLeft      int64
+	EstimatedExpiry time.Time
+}
+
+// expiryEmailTicket is a ticket listed in the ticket expiry warning email,
+// with its estimated expiry time in the user's time zone.
+type expiryEmailTicket struct {
+	Ticket       string
+	ExpiryHeight int64
+	Expires      string
+}
+
+// ticketExpiryHeight returns the height at which a ticket mined at
+// ticketHeight expires.
+func (controller *MainController) ticketExpiryHeight(ticketHeight uint32) int64 {
+	return int64(ticketHeight) + int64(controller.params.TicketMaturity) +
+		int64(controller.params.TicketExpiry)
+}
+
+// estimateBlockTime estimates when the block blocksLeft blocks after the
+// current tip will be mined.
+func (controller *MainController) estimateBlockTime(blocksLeft int64) time.Time {
+	return time.Now().Add(time.Duration(blocksLeft) *
+		controller.params.TargetTimePerBlock)
+}
+
+// expiringTickets returns the tickets that expire within expiryWarning blocks
+// of height, soonest first.
+func (controller *MainController) expiringTickets(tickets []TicketInfoLive,
+	height int64) []ExpiringTicket {
+	if controller.expiryWarning <= 0 {
+		return nil
+	}
+
+	var expiring []ExpiringTicket
+	for _, t := range tickets {
+		expiryHeight := controller.ticketExpiryHeight(t.TicketHeight)
+		blocksLeft := expiryHeight - height
+		if blocksLeft > controller.expiryWarning {
+			continue
+		}
+		expiring = append(expiring, ExpiringTicket{
+			Ticket:          t.Ticket,
+			ExpiryHeight:    expiryHeight,
+			BlocksLeft:      blocksLeft,
+			EstimatedExpiry: controller.estimateBlockTime(blocksLeft),
+		})
+	}
+	sort.Slice(expiring, func(i, j int) bool {
+		return expiring[i].BlocksLeft < expiring[j].BlocksLeft
+	})
+
+	return expiring
+}
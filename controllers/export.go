@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/coolsnady/hcd/blockchain/stake"
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcutil"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// exportDateFormat is the format of the from and to dates of an export.
+const exportDateFormat = "2006-01-02"
+
+// exportColumns are the columns of a CSV export.
+var exportColumns = []string{"UserID", "Username", "MultiSigAddress",
+	"Ticket", "Status", "TicketHeight", "SpentBy", "SpentByHeight", "PoolFee",
+	"PoolFeeFiat", "FiatCurrency"}
+
+// estimateBlockHeight estimates the height of the block mined at t.  It is the
+// inverse of estimateBlockTime.
+func (controller *MainController) estimateBlockHeight(t time.Time,
+	tipHeight int64) int64 {
+	return tipHeight + int64(time.Until(t)/controller.params.TargetTimePerBlock)
+}
+
+// exportRange reads the block range of an export request.  The range is given
+// either by the fromheight and toheight parameters or, as blocks don't carry
+// dates in the wallet records, by the from and to dates, which are converted
+// to estimated heights.  Missing bounds leave the range open.
+func (controller *MainController) exportRange(r *http.Request,
+	tipHeight int64) (int64, int64, error) {
+	from, to := int64(0), tipHeight
+	for _, bound := range []struct {
+		height, date string
+		value        *int64
+		dayOffset    time.Duration
+	}{
+		{"fromheight", "from", &from, 0},
+		{"toheight", "to", &to, 24 * time.Hour},
+	} {
+		if v := r.FormValue(bound.height); v != "" {
+			height, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid %s", bound.height)
+			}
+			*bound.value = height
+		} else if v := r.FormValue(bound.date); v != "" {
+			date, err := time.Parse(exportDateFormat, v)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid %s date, must be "+
+					"YYYY-MM-DD", bound.date)
+			}
+			// The to date includes the whole day.
+			*bound.value = controller.estimateBlockHeight(
+				date.Add(bound.dayOffset), tipHeight)
+		}
+	}
+	if from > to {
+		return 0, 0, errors.New("range starts after it ends")
+	}
+	return from, to, nil
+}
+
+// inExportRange returns whether a ticket was bought or spent in the block
+// range.
+func inExportRange(t *poolapi.ExportRecord, from, to int64) bool {
+	bought := int64(t.TicketHeight)
+	spent := int64(t.SpentByHeight)
+	return (bought >= from && bought <= to) ||
+		(spent != 0 && spent >= from && spent <= to)
+}
+
+// ticketPoolFee returns the pool fee paid by a ticket, which is the amount of
+// its pool fee commitment output.
+func (controller *MainController) ticketPoolFee(ticket string) (float64, error) {
+	hash, err := chainhash.NewHashFromStr(ticket)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := controller.rpcServers.fetchTransaction(hash)
+	if err != nil {
+		return 0, err
+	}
+	msgTx := tx.MsgTx()
+	if len(msgTx.TxOut) < 2 {
+		return 0, errors.New("ticket has no pool fee commitment")
+	}
+	amt, err := stake.AmountFromSStxPkScrCommitment(msgTx.TxOut[1].PkScript)
+	if err != nil {
+		return 0, err
+	}
+	return amt.ToCoin(), nil
+}
+
+// exportRecords returns the tickets of all users that were bought or spent in
+// the block range, ordered by user and ticket height.
+func (controller *MainController) exportRecords(dbMap *gorp.DbMap, from,
+	to int64) ([]poolapi.ExportRecord, error) {
+	users, err := models.GetAllCurrentMultiSigScripts(dbMap)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]poolapi.ExportRecord, 0)
+	for _, u := range users {
+		addr, err := hcutil.DecodeAddress(u.MultiSigAddress)
+		if err != nil {
+			log.Warnf("Invalid address %v in database: %v",
+				u.MultiSigAddress, err)
+			continue
+		}
+		spui, err := controller.rpcServers.StakePoolUserInfo(addr, true)
+		if err != nil {
+			return nil, err
+		}
+		if spui == nil {
+			continue
+		}
+		for _, t := range spui.Tickets {
+			record := poolapi.ExportRecord{
+				UserID:          u.Id,
+				Username:        u.Username,
+				MultiSigAddress: u.MultiSigAddress,
+				Ticket:          t.Ticket,
+				Status:          t.Status,
+				TicketHeight:    t.TicketHeight,
+				SpentBy:         t.SpentBy,
+				SpentByHeight:   t.SpentByHeight,
+			}
+			if !inExportRange(&record, from, to) {
+				continue
+			}
+			record.PoolFee, err = controller.ticketPoolFee(t.Ticket)
+			if err != nil {
+				log.Warnf("unable to get pool fee of ticket %v: %v",
+					t.Ticket, err)
+			}
+			records = append(records, record)
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].UserID != records[j].UserID {
+			return records[i].UserID < records[j].UserID
+		}
+		return records[i].TicketHeight < records[j].TicketHeight
+	})
+	return records, nil
+}
+
+// exportCSV formats records as CSV with a header row.
+func exportCSV(records []poolapi.ExportRecord) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(exportColumns)
+	for _, r := range records {
+		var feeFiat string
+		if r.FiatCurrency != "" {
+			feeFiat = strconv.FormatFloat(r.PoolFeeFiat, 'f', 2, 64)
+		}
+		w.Write([]string{
+			strconv.FormatInt(r.UserID, 10),
+			r.Username,
+			r.MultiSigAddress,
+			r.Ticket,
+			r.Status,
+			strconv.FormatUint(uint64(r.TicketHeight), 10),
+			r.SpentBy,
+			strconv.FormatUint(uint64(r.SpentByHeight), 10),
+			strconv.FormatFloat(r.PoolFee, 'f', -1, 64),
+			feeFiat,
+			r.FiatCurrency,
+		})
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+// export fetches the records in the range of an export request.
+func (controller *MainController) export(c web.C,
+	r *http.Request) ([]poolapi.ExportRecord, codes.Code, error) {
+	if controller.RPCIsStopped() {
+		return nil, codes.Unavailable, errors.New("RPC server stopped")
+	}
+	_, height, err := controller.rpcServers.GetBestBlock()
+	if err != nil {
+		log.Infof("RPC GetBestBlock failed: %v", err)
+		return nil, codes.Unavailable, errors.New("RPC server error")
+	}
+	from, to, err := controller.exportRange(r, height)
+	if err != nil {
+		return nil, codes.InvalidArgument, err
+	}
+
+	records, err := controller.exportRecords(controller.GetDbMap(c), from, to)
+	if err != nil {
+		log.Errorf("exportRecords failed: %v", err)
+		return nil, codes.Unavailable, errors.New("unable to fetch tickets")
+	}
+
+	// Fees are converted at the current price as the feed has no history.
+	currency := controller.requestCurrency(c, r)
+	for i := range records {
+		if fiat := controller.fiatValue(records[i].PoolFee, currency); fiat != nil {
+			records[i].PoolFeeFiat = fiat.Amount
+			records[i].FiatCurrency = fiat.Currency
+		}
+	}
+	log.Infof("exported %d tickets for heights %d to %d", len(records),
+		from, to)
+	return records, codes.OK, nil
+}
+
+// AdminExport downloads the tickets, votes, misses and pool fees of all users
+// for a range of blocks as CSV, or JSON when format is json.
+func (controller *MainController) AdminExport(c web.C, r *http.Request) (string, int) {
+	isAdmin, err := controller.isAdmin(c, r)
+	if !isAdmin {
+		log.Warnf("isAdmin check failed: %v", err)
+		return "", http.StatusUnauthorized
+	}
+
+	records, _, err := controller.export(c, r)
+	if err != nil {
+		log.Warnf("export failed: %v", err)
+		session := controller.GetSession(c)
+		session.AddFlash("Export failed: "+err.Error(), "adminstatus")
+		return "/status", http.StatusSeeOther
+	}
+
+	var body, ext string
+	switch r.FormValue("format") {
+	case "json":
+		b, err := json.Marshal(records)
+		if err != nil {
+			log.Errorf("json.Marshal failed: %v", err)
+			return "", http.StatusInternalServerError
+		}
+		body, ext = string(b), "json"
+		c.Env["Content-Type"] = "application/json"
+	default:
+		body, err = exportCSV(records)
+		if err != nil {
+			log.Errorf("exportCSV failed: %v", err)
+			return "", http.StatusInternalServerError
+		}
+		ext = "csv"
+		c.Env["Content-Type"] = "text/csv"
+	}
+
+	c.Env["ResponseHeaderMap"] = map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=\"export-%s.%s\"",
+			time.Now().UTC().Format(exportDateFormat), ext),
+	}
+	return body, http.StatusOK
+}
+
+// APIExport returns the tickets, votes, misses and pool fees of all users for
+// a range of blocks.  It is only available to admins.
+func (controller *MainController) APIExport(c web.C,
+	r *http.Request) ([]poolapi.ExportRecord, codes.Code, string, error) {
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "export error", errors.New("not an admin")
+	}
+
+	records, code, err := controller.export(c, r)
+	if err != nil {
+		return nil, code, "export error", err
+	}
+	return records, codes.OK, "export successfully retrieved", nil
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcstakepool/poolapi"
+)
+
+func TestExportCSV(t *testing.T) {
+	records := []poolapi.ExportRecord{{
+		UserID:          1,
+		Username:        "alice",
+		MultiSigAddress: "HcMsa1",
+		Ticket:          "ticket1",
+		Status:          "voted",
+		TicketHeight:    100,
+		SpentBy:         "vote1",
+		SpentByHeight:   300,
+		PoolFee:         0.0125,
+		PoolFeeFiat:     0.5,
+		FiatCurrency:    "USD",
+	}, {
+		UserID:          2,
+		Username:        "bob, jr",
+		MultiSigAddress: "HcMsa2",
+		Ticket:          "ticket2",
+		Status:          "live",
+		TicketHeight:    200,
+		PoolFee:         0.01,
+	}}
+
+	out, err := exportCSV(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+	want := [][]string{
+		exportColumns,
+		{"1", "alice", "HcMsa1", "ticket1", "voted", "100", "vote1", "300",
+			"0.0125", "0.50", "USD"},
+		// Without a price the fiat columns are left empty.
+		{"2", "bob, jr", "HcMsa2", "ticket2", "live", "200", "", "0",
+			"0.01", "", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("export\n%q\nwant\n%q", rows, want)
+	}
+
+	// An empty export still has the header.
+	out, err = exportCSV(nil)
+	if err != nil || out != strings.Join(exportColumns, ",")+"\n" {
+		t.Errorf("empty export %q (err %v)", out, err)
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	b, err := json.Marshal(poolapi.ExportRecord{
+		UserID:        1,
+		Ticket:        "ticket1",
+		Status:        "live",
+		TicketHeight:  100,
+		SpentByHeight: 0,
+		PoolFee:       0.01,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"UserID":1,"Username":"","MultiSigAddress":"",` +
+		`"Ticket":"ticket1","Status":"live","TicketHeight":100,` +
+		`"SpentBy":"","SpentByHeight":0,"PoolFee":0.01}`
+	if string(b) != want {
+		t.Errorf("export record %s, want %s", b, want)
+	}
+}
+
+func TestInExportRange(t *testing.T) {
+	tests := []struct {
+		bought, spent uint32
+		want          bool
+	}{
+		{bought: 150, want: true},
+		{bought: 50, want: false},
+		{bought: 50, spent: 150, want: true},
+		{bought: 50, spent: 250, want: false},
+		{bought: 100, spent: 200, want: true},
+		{bought: 250, want: false},
+	}
+	for _, test := range tests {
+		r := &poolapi.ExportRecord{
+			TicketHeight:  test.bought,
+			SpentByHeight: test.spent,
+		}
+		if got := inExportRange(r, 100, 200); got != test.want {
+			t.Errorf("bought %d spent %d: got %v, want %v", test.bought,
+				test.spent, got, test.want)
+		}
+	}
+}
+
+func TestExportRange(t *testing.T) {
+	controller := &MainController{params: &chaincfg.MainNetParams}
+	const tip = 10000
+	blocksPerDay := int64(24 * time.Hour / controller.params.TargetTimePerBlock)
+
+	tests := []struct {
+		query    string
+		from, to int64
+		err      bool
+	}{
+		{query: "", from: 0, to: tip},
+		{query: "fromheight=100&toheight=200", from: 100, to: 200},
+		{query: "fromheight=100", from: 100, to: tip},
+		{query: "fromheight=x", err: true},
+		{query: "fromheight=300&toheight=200", err: true},
+		{query: "from=2018-13-01", err: true},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/admin/export?"+test.query, nil)
+		from, to, err := controller.exportRange(r, tip)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", test.query)
+			}
+			continue
+		}
+		if err != nil || from != test.from || to != test.to {
+			t.Errorf("%q: got %d to %d (err %v), want %d to %d",
+				test.query, from, to, err, test.from, test.to)
+		}
+	}
+
+	// Dates are estimated heights and the to date includes the whole day.
+	day := time.Now().UTC().AddDate(0, 0, -2).Format(exportDateFormat)
+	r := httptest.NewRequest("GET", "/admin/export?from="+day+"&to="+day, nil)
+	from, to, err := controller.exportRange(r, tip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := to - from; diff < blocksPerDay-1 || diff > blocksPerDay+1 {
+		t.Errorf("a day spans %d blocks, want about %d", diff, blocksPerDay)
+	}
+	if to > tip || from < tip-3*blocksPerDay {
+		t.Errorf("got %d to %d for two days ago at tip %d", from, to, tip)
+	}
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/zenazn/goji/web"
+)
+
+// FiatValue is an amount of HC converted to a fiat currency for display.
+type FiatValue struct {
+	Amount   float64
+	Currency string
+}
+
+// fiatValue converts amount to currency.  It returns nil when the price feed
+// is disabled or has no price for the currency, in which case no fiat value
+// should be shown.
+func (controller *MainController) fiatValue(amount float64,
+	currency string) *FiatValue {
+	if controller.priceFeed == nil {
+		return nil
+	}
+	value, err := controller.priceFeed.Value(amount, currency)
+	if err != nil {
+		log.Debugf("no fiat value in %s: %v", currency, err)
+		return nil
+	}
+	return &FiatValue{Amount: value, Currency: currency}
+}
+
+// requestCurrency returns the currency to show fiat values in for a request.
+// Signed in users get their preferred currency.  Anyone else can choose one
+// with the currency parameter.
+func (controller *MainController) requestCurrency(c web.C,
+	r *http.Request) string {
+	userID := c.Env["APIUserID"]
+	if userID == nil {
+		userID = controller.GetSession(c).Values["UserId"]
+	}
+	if userID != nil {
+		dbMap := controller.GetDbMap(c)
+		return controller.getPreferences(dbMap, userID.(int64)).Currency
+	}
+
+	if currency := r.FormValue("currency"); currency != "" &&
+		stringSliceContains(supportedCurrencies, currency) {
+		return currency
+	}
+	return defaultCurrency
+}
@@ -31,6 +31,10 @@ const (
 	connectedFn
 	stakePoolUserInfoFn
 	getBestBlockFn
+	getStakeInfoAllFn
+	getVoteInfoFn
+	getTicketPriceFn
+	getStakeVersionInfoFn
 )
 
 var (
@@ -44,6 +48,11 @@ var (
 	// instead of returning cached ticket information.
 	cacheTimerGetTickets = 20 * time.Second
 
+	// cacheTimerTicketPrice is the duration of time after which to ask
+	// hcd for the ticket price and fees again instead of returning the
+	// cached ones.
+	cacheTimerTicketPrice = time.Minute
+
 	// defaultAccountName is the account name for the default wallet
 	// account as a string.
 	defaultAccountName = "default"
@@ -122,6 +131,17 @@ type getStakeInfoMsg struct {
 	reply chan getStakeInfoResponse
 }
 
+// getStakeInfoAllResponse
+type getStakeInfoAllResponse struct {
+	stakeInfos []*dcrjson.GetStakeInfoResult
+	errs       []error
+}
+
+// getStakeInfoAllMsg
+type getStakeInfoAllMsg struct {
+	reply chan getStakeInfoAllResponse
+}
+
 // connectedResponse
 type connectedResponse struct {
 	walletInfo []*dcrjson.WalletInfoResult
@@ -158,6 +178,49 @@ type getBestBlockMsg struct {
 	reply chan getBestBlockResponse
 }
 
+// getVoteInfoResponse
+type getVoteInfoResponse struct {
+	voteInfo *dcrjson.GetVoteInfoResult
+	err      error
+}
+
+// getVoteInfoMsg
+type getVoteInfoMsg struct {
+	version uint32
+	reply   chan getVoteInfoResponse
+}
+
+// getStakeVersionInfoResponse
+type getStakeVersionInfoResponse struct {
+	info *dcrjson.GetStakeVersionInfoResult
+	err  error
+}
+
+// getStakeVersionInfoMsg
+type getStakeVersionInfoMsg struct {
+	count int32
+	reply chan getStakeVersionInfoResponse
+}
+
+// ticketPriceInfo is what hcd reports about the ticket price and the ticket
+// fees.
+type ticketPriceInfo struct {
+	stakeDiff *dcrjson.GetStakeDifficultyResult
+	estimate  *dcrjson.EstimateStakeDiffResult
+	feeInfo   *dcrjson.TicketFeeInfoResult
+}
+
+// getTicketPriceResponse
+type getTicketPriceResponse struct {
+	priceInfo *ticketPriceInfo
+	err       error
+}
+
+// getTicketPriceMsg
+type getTicketPriceMsg struct {
+	reply chan getTicketPriceResponse
+}
+
 // connectionError is an error relating to the connection,
 // so that connection failures can be handled without
 // crashing the server.
@@ -194,6 +257,10 @@ out:
 				resp := w.executeInSequence(getStakeInfoFn, msg)
 				respTyped := resp.(*getStakeInfoResponse)
 				msg.reply <- *respTyped
+			case getStakeInfoAllMsg:
+				resp := w.executeInSequence(getStakeInfoAllFn, msg)
+				respTyped := resp.(*getStakeInfoAllResponse)
+				msg.reply <- *respTyped
 			case connectedMsg:
 				resp := w.executeInSequence(connectedFn, msg)
 				respTyped := resp.(*connectedResponse)
@@ -206,6 +273,18 @@ out:
 				resp := w.executeInSequence(getBestBlockFn, msg)
 				respTyped := resp.(*getBestBlockResponse)
 				msg.reply <- *respTyped
+			case getVoteInfoMsg:
+				resp := w.executeInSequence(getVoteInfoFn, msg)
+				respTyped := resp.(*getVoteInfoResponse)
+				msg.reply <- *respTyped
+			case getTicketPriceMsg:
+				resp := w.executeInSequence(getTicketPriceFn, msg)
+				respTyped := resp.(*getTicketPriceResponse)
+				msg.reply <- *respTyped
+			case getStakeVersionInfoMsg:
+				resp := w.executeInSequence(getStakeVersionInfoFn, msg)
+				respTyped := resp.(*getStakeVersionInfoResponse)
+				msg.reply <- *respTyped
 			default:
 				log.Infof("Invalid message type in wallet RPC "+
 					"handler: %T", msg)
@@ -513,6 +592,27 @@ func (w *walletSvrManager) executeInSequence(fn functionName, msg interface{}) i
 		}
 		return resp
 
+	// getStakeInfoAllFn queries every wallet and keeps all of the results,
+	// including failures, so they can be compared.
+	case getStakeInfoAllFn:
+		resp := new(getStakeInfoAllResponse)
+		resp.stakeInfos = make([]*dcrjson.GetStakeInfoResult, w.serversLen)
+		resp.errs = make([]error, w.serversLen)
+		for i, s := range w.servers {
+			if w.servers[i] == nil {
+				resp.errs[i] = hcrpcclient.ErrClientDisconnect
+				continue
+			}
+			gsir, err := s.GetStakeInfo()
+			if err != nil {
+				log.Infof("getStakeInfoAllFn failure on server %v: %v", i, err)
+				resp.errs[i] = err
+				continue
+			}
+			resp.stakeInfos[i] = gsir
+		}
+		return resp
+
 	// connectedFn actually requests walletinfo from the wallet and makes
 	// sure the daemon is connected and the wallet is unlocked.
 	case connectedFn:
@@ -558,7 +658,7 @@ func (w *walletSvrManager) executeInSequence(fn functionName, msg interface{}) i
 			}
 
 			// Check to make sure the wallet is unlocked.
-			if !wirs[i].Unlocked {
+			if !wirs[i].Unlocked && !w.lockedWallets {
 				log.Infof("wallet svr %v not unlocked", i)
 				return fmt.Errorf("wallet server %v locked", i)
 			}
@@ -656,6 +756,88 @@ func (w *walletSvrManager) executeInSequence(fn functionName, msg interface{}) i
 		resp.err = fmt.Errorf("unable to get best block")
 		return resp
 
+	case getVoteInfoFn:
+		gvim := msg.(getVoteInfoMsg)
+		resp := new(getVoteInfoResponse)
+		for i, s := range w.servers {
+			if w.servers[i] == nil {
+				continue
+			}
+			// The wallet passes getvoteinfo through to its hcd.
+			vi, err := s.GetVoteInfo(gvim.version)
+			if err != nil && (err != hcrpcclient.ErrClientDisconnect &&
+				err != hcrpcclient.ErrClientShutdown) {
+				log.Infof("getVoteInfoFn failure on server %v: %v", i, err)
+				resp.err = err
+				return resp
+			} else if err != nil && (err == hcrpcclient.ErrClientDisconnect ||
+				err == hcrpcclient.ErrClientShutdown) {
+				continue
+			}
+			resp.voteInfo = vi
+			return resp
+		}
+		log.Errorf("Unable to check any servers for getVoteInfoFn")
+		resp.err = fmt.Errorf("unable to get vote info")
+		return resp
+
+	case getStakeVersionInfoFn:
+		gsvim := msg.(getStakeVersionInfoMsg)
+		resp := new(getStakeVersionInfoResponse)
+		for i, s := range w.servers {
+			if w.servers[i] == nil {
+				continue
+			}
+			// The wallet passes getstakeversioninfo through to its hcd.
+			info, err := s.GetStakeVersionInfo(gsvim.count)
+			if err != nil && (err != hcrpcclient.ErrClientDisconnect &&
+				err != hcrpcclient.ErrClientShutdown) {
+				log.Infof("getStakeVersionInfoFn failure on server %v: %v", i, err)
+				resp.err = err
+				return resp
+			} else if err != nil && (err == hcrpcclient.ErrClientDisconnect ||
+				err == hcrpcclient.ErrClientShutdown) {
+				continue
+			}
+			resp.info = info
+			return resp
+		}
+		log.Errorf("Unable to check any servers for getStakeVersionInfoFn")
+		resp.err = fmt.Errorf("unable to get stake version info")
+		return resp
+
+	case getTicketPriceFn:
+		resp := new(getTicketPriceResponse)
+		for i, s := range w.servers {
+			if w.servers[i] == nil {
+				continue
+			}
+			// The wallet passes these calls through to its hcd.
+			info := new(ticketPriceInfo)
+			var err error
+			info.stakeDiff, err = s.GetStakeDifficulty()
+			if err == nil {
+				info.estimate, err = s.EstimateStakeDiff(nil)
+			}
+			if err == nil {
+				info.feeInfo, err = s.TicketFeeInfo(nil, nil)
+			}
+			if err != nil && (err != hcrpcclient.ErrClientDisconnect &&
+				err != hcrpcclient.ErrClientShutdown) {
+				log.Infof("getTicketPriceFn failure on server %v: %v", i, err)
+				resp.err = err
+				return resp
+			} else if err != nil && (err == hcrpcclient.ErrClientDisconnect ||
+				err == hcrpcclient.ErrClientShutdown) {
+				continue
+			}
+			resp.priceInfo = info
+			return resp
+		}
+		log.Errorf("Unable to check any servers for getTicketPriceFn")
+		resp.err = fmt.Errorf("unable to get ticket price")
+		return resp
+
 	}
 
 	return nil
@@ -920,6 +1102,58 @@ func (w *walletSvrManager) GetBestBlock() (*chainhash.Hash, int64, error) {
 	return response.bestBlockHash, response.bestBlockHeight, response.err
 }
 
+// GetVoteInfo gets the agendas of a vote version and their voting progress
+// from the hcd of the first wallet asked.
+func (w *walletSvrManager) GetVoteInfo(version uint32) (*dcrjson.GetVoteInfoResult, error) {
+	reply := make(chan getVoteInfoResponse)
+	w.msgChan <- getVoteInfoMsg{
+		version: version,
+		reply:   reply,
+	}
+	response := <-reply
+
+	return response.voteInfo, response.err
+}
+
+// GetStakeVersionInfo returns the stake and vote versions of the last count
+// stake version intervals from the hcd of the first wallet asked.
+func (w *walletSvrManager) GetStakeVersionInfo(count int32) (*dcrjson.GetStakeVersionInfoResult, error) {
+	reply := make(chan getStakeVersionInfoResponse)
+	w.msgChan <- getStakeVersionInfoMsg{
+		count: count,
+		reply: reply,
+	}
+	response := <-reply
+
+	return response.info, response.err
+}
+
+// TicketPriceInfo returns the ticket price, its estimates for the next stake
+// difficulty window and the ticket fees from the hcd of the first wallet
+// asked.  The answer is cached for cacheTimerTicketPrice.
+func (w *walletSvrManager) TicketPriceInfo() (*ticketPriceInfo, error) {
+	w.cachedTicketPriceMutex.Lock()
+	defer w.cachedTicketPriceMutex.Unlock()
+
+	if time.Since(w.cachedTicketPriceTimer) < cacheTimerTicketPrice {
+		return w.cachedTicketPrice, nil
+	}
+
+	reply := make(chan getTicketPriceResponse)
+	w.msgChan <- getTicketPriceMsg{
+		reply: reply,
+	}
+	response := <-reply
+	if response.err != nil {
+		return nil, response.err
+	}
+
+	w.cachedTicketPrice = response.priceInfo
+	w.cachedTicketPriceTimer = time.Now()
+
+	return response.priceInfo, nil
+}
+
 // getStakeInfo returns the cached current stake statistics about the wallet if
 // it has been less than five minutes. If it has been longer than five minutes,
 // a new request for stake information is piped through the RPC client handler
@@ -964,6 +1198,18 @@ func (w *walletSvrManager) GetStakeInfo() (*dcrjson.GetStakeInfoResult, error) {
 	return w.getStakeInfo()
 }
 
+// StakeInfoAll queries the stake information of every wallet.  Unlike
+// GetStakeInfo, it is not cached and does not fail when the wallets disagree,
+// so it can be used to find out which wallets are out of sync.
+func (w *walletSvrManager) StakeInfoAll() ([]*dcrjson.GetStakeInfoResult, []error) {
+	reply := make(chan getStakeInfoAllResponse)
+	w.msgChan <- getStakeInfoAllMsg{
+		reply: reply,
+	}
+	response := <-reply
+	return response.stakeInfos, response.errs
+}
+
 // getTicketsCacheData is a TicketsForAddressResult that also contains a time
 // at which TicketsForAddress was last called. The results should only update.
 type getTicketsCacheData struct {
@@ -1000,6 +1246,13 @@ type walletSvrManager struct {
 	cachedStakeInfoTimer time.Time
 	cachedStakeInfoMutex sync.Mutex
 
+	// cachedTicketPrice is the ticket price information last asked for.
+	// It is shown on every tickets page, so it is only asked for again
+	// once cacheTimerTicketPrice has passed.
+	cachedTicketPrice      *ticketPriceInfo
+	cachedTicketPriceTimer time.Time
+	cachedTicketPriceMutex sync.Mutex
+
 	// cachedGetTicketsMap caches TicketsForAddress responses and
 	// is used to only provide new calls to the wallet RPC after a
 	// cooldown period to prevent DoS attacks.
@@ -1009,6 +1262,10 @@ type walletSvrManager struct {
 	// minServers is the minimum number of servers required before alerting
 	minServers int
 
+	// lockedWallets accepts locked wallets, which stakepoold unlocks
+	// only to vote.
+	lockedWallets bool
+
 	started  int32
 	shutdown int32
 	msgChan  chan interface{}
@@ -1061,7 +1318,7 @@ func (w *walletSvrManager) CheckServers() error {
 		if !wi.DaemonConnected {
 			return fmt.Errorf("Wallet on svr %d not connected\n", i)
 		}
-		if !wi.Unlocked {
+		if !wi.Unlocked && !w.lockedWallets {
 			return fmt.Errorf("Wallet on svr %d not unlocked.\n", i)
 		}
 	}
@@ -1128,8 +1385,8 @@ func getWalletVoteVersion(client *hcrpcclient.Client) (uint32, error) {
 }
 
 // checkIfWalletConnected checks to see if the passed wallet's client is connected
-// and if the wallet is unlocked.
-func checkIfWalletConnected(client *hcrpcclient.Client) error {
+// and, unless lockedWallets is set, if the wallet is unlocked.
+func checkIfWalletConnected(client *hcrpcclient.Client, lockedWallets bool) error {
 	wi, err := client.WalletInfo()
 	if err != nil {
 		return err
@@ -1137,7 +1394,7 @@ func checkIfWalletConnected(client *hcrpcclient.Client) error {
 	if !wi.DaemonConnected {
 		return fmt.Errorf("wallet not connected")
 	}
-	if !wi.Unlocked {
+	if !wi.Unlocked && !lockedWallets {
 		return fmt.Errorf("wallet not unlocked")
 	}
 
@@ -1224,7 +1481,7 @@ func walletSvrsSync(wsm *walletSvrManager, multiSigScripts []models.User) error
 		if wsm.servers[i] == nil {
 			continue
 		}
-		err := checkIfWalletConnected(wsm.servers[i])
+		err := checkIfWalletConnected(wsm.servers[i], wsm.lockedWallets)
 		if err != nil {
 			return fmt.Errorf("failure on startup sync: %s",
 				err.Error())
@@ -1410,7 +1667,8 @@ func connectWalletRPC(walletHost string, walletCert string, walletUser string, w
 // newWalletSvrManager returns a new coolsnady wallet server manager.
 // Use Start to begin processing asynchronous block and inv updates.
 func newWalletSvrManager(walletHosts []string, walletCerts []string,
-	walletUsers []string, walletPasswords []string, minServers int) (*walletSvrManager, error) {
+	walletUsers []string, walletPasswords []string, minServers int,
+	lockedWallets bool) (*walletSvrManager, error) {
 
 	var err error
 	localServers := make([]*hcrpcclient.Client, len(walletHosts))
@@ -1434,6 +1692,7 @@ func newWalletSvrManager(walletHosts []string, walletCerts []string,
 		msgChan:              make(chan interface{}, 500),
 		quit:                 make(chan struct{}),
 		minServers:           minServers,
+		lockedWallets:        lockedWallets,
 	}
 
 	return &wsm, nil
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+)
+
+const (
+	// invitesShown is how many of the last invite codes are listed on the
+	// invites page.
+	invitesShown = 200
+
+	// maxInvitesIssued is the most invite codes issued at once.
+	maxInvitesIssued = 50
+
+	// maxInviteNoteLen is the longest note kept with an invite code.
+	maxInviteNoteLen = 255
+)
+
+// Statuses of invite codes.
+const (
+	InviteStatusUnused  = "Unused"
+	InviteStatusUsed    = "Used"
+	InviteStatusExpired = "Expired"
+	InviteStatusRevoked = "Revoked"
+)
+
+// inviteCodeEncoding encodes the random bytes of invite codes in letters and
+// digits that are easy to read out.
+var inviteCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newInviteCode returns a random invite code.
+func newInviteCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return inviteCodeEncoding.EncodeToString(b), nil
+}
+
+// normalizeInviteCode returns the invite code a user entered as it is stored.
+func normalizeInviteCode(code string) string {
+	code = strings.Join(strings.Fields(code), "")
+	return strings.ToUpper(strings.Replace(code, "-", "", -1))
+}
+
+// inviteStatus returns the status of code at now.
+func inviteStatus(code *models.InviteCode, now int64) string {
+	switch {
+	case code.Used != 0:
+		return InviteStatusUsed
+	case code.Revoked != 0:
+		return InviteStatusRevoked
+	case code.Expires <= now:
+		return InviteStatusExpired
+	}
+	return InviteStatusUnused
+}
+
+// inviteCodeRow is an invite code as listed on the invites page.
+type inviteCodeRow struct {
+	models.InviteCode
+	Status      string
+	CreatedTime string
+	ExpiresTime string
+	UsedTime    string
+}
+
+// claimInviteCode claims the invite code a user signing up entered so no one
+// else can use it, or returns an error that can be shown to them.
+func claimInviteCode(dbMap *gorp.DbMap, code string, now time.Time) error {
+	if code == "" {
+		return errors.New("an invite code is required to sign up")
+	}
+	claimed, err := models.ClaimInviteCode(dbMap, code, now.Unix())
+	if err != nil {
+		log.Errorf("ClaimInviteCode failed: %v", err)
+		return errors.New("unable to check the invite code, please try " +
+			"again later")
+	}
+	if !claimed {
+		return errors.New("the invite code is invalid, expired or used")
+	}
+	return nil
+}
+
+// AdminInvites renders the invite codes issued by admins.
+func (controller *MainController) AdminInvites(c web.C, r *http.Request) (string, int) {
+	t := controller.GetTemplate(c)
+	session := controller.GetSession(c)
+	dbMap := controller.GetDbMap(c)
+
+	isAdmin, err := controller.isAdmin(c, r)
+	if !isAdmin {
+		log.Warnf("isAdmin check failed: %v", err)
+		return "", http.StatusUnauthorized
+	}
+
+	codes, err := models.GetInviteCodes(dbMap, invitesShown)
+	if err != nil {
+		log.Errorf("GetInviteCodes failed: %v", err)
+		return "/error", http.StatusSeeOther
+	}
+	now := time.Now()
+	rows := make([]inviteCodeRow, 0, len(codes))
+	for i := range codes {
+		code := &codes[i]
+		row := inviteCodeRow{
+			InviteCode:  *code,
+			Status:      inviteStatus(code, now.Unix()),
+			CreatedTime: time.Unix(code.Created, 0).UTC().Format(auditTimeFormat),
+			ExpiresTime: time.Unix(code.Expires, 0).UTC().Format(auditTimeFormat),
+		}
+		if code.Used != 0 {
+			row.UsedTime = time.Unix(code.Used, 0).UTC().Format(auditTimeFormat)
+		}
+		rows = append(rows, row)
+	}
+
+	c.Env["Admin"] = isAdmin
+	c.Env["IsAdminInvites"] = true
+	c.Env["InviteCodes"] = rows
+	c.Env["InviteSignup"] = controller.inviteSignup
+	c.Env["InviteExpiry"] = controller.inviteExpiry.String()
+	c.Env["MaxInvitesIssued"] = maxInvitesIssued
+	c.Env["FlashError"] = session.Flashes("adminInvitesError")
+	c.Env["FlashSuccess"] = session.Flashes("adminInvitesSuccess")
+
+	widgets := controller.Parse(t, "admin/invites", c.Env)
+
+	c.Env["Title"] = "Hcd Stake Pool - Invite Codes (Admin)"
+	c.Env["Content"] = template.HTML(widgets)
+
+	return controller.Parse(t, "main", c.Env), http.StatusOK
+}
+
+// AdminInvitesPost issues count invite codes with the note note, or revokes
+// the invite code with id revoke.
+func (controller *MainController) AdminInvitesPost(c web.C, r *http.Request) (string, int) {
+	session := controller.GetSession(c)
+	dbMap := controller.GetDbMap(c)
+
+	isAdmin, err := controller.isAdmin(c, r)
+	if !isAdmin {
+		log.Warnf("isAdmin check failed: %v", err)
+		return "", http.StatusUnauthorized
+	}
+	now := time.Now()
+
+	if revoke := r.FormValue("revoke"); revoke != "" {
+		id, err := strconv.ParseInt(revoke, 10, 64)
+		if err != nil {
+			session.AddFlash("Invalid invite code", "adminInvitesError")
+			return "/admininvites", http.StatusSeeOther
+		}
+		revoked, err := models.RevokeInviteCode(dbMap, id, now.Unix())
+		if err != nil {
+			log.Errorf("RevokeInviteCode failed: %v", err)
+			session.AddFlash("Unable to revoke the invite code",
+				"adminInvitesError")
+			return "/admininvites", http.StatusSeeOther
+		}
+		if !revoked {
+			session.AddFlash("The invite code was already used or revoked",
+				"adminInvitesError")
+			return "/admininvites", http.StatusSeeOther
+		}
+		controller.audit(dbMap, c, r, AuditActionInviteRevoke, revoke, nil, nil)
+		session.AddFlash("Revoked the invite code", "adminInvitesSuccess")
+		return "/admininvites", http.StatusSeeOther
+	}
+
+	count, err := strconv.Atoi(r.FormValue("count"))
+	if err != nil || count < 1 || count > maxInvitesIssued {
+		session.AddFlash(fmt.Sprintf("Between 1 and %d invite codes can be "+
+			"issued at once", maxInvitesIssued), "adminInvitesError")
+		return "/admininvites", http.StatusSeeOther
+	}
+	note := strings.TrimSpace(r.FormValue("note"))
+	if len(note) > maxInviteNoteLen {
+		note = note[:maxInviteNoteLen]
+	}
+
+	issued := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		code, err := newInviteCode()
+		if err == nil {
+			err = models.InsertInviteCode(dbMap, &models.InviteCode{
+				Code:      code,
+				Note:      note,
+				CreatedBy: controller.auditActor(c),
+				Created:   now.Unix(),
+				Expires:   now.Add(controller.inviteExpiry).Unix(),
+			})
+		}
+		if err != nil {
+			log.Errorf("unable to issue invite code: %v", err)
+			session.AddFlash("Unable to issue all invite codes",
+				"adminInvitesError")
+			break
+		}
+		issued = append(issued, code)
+	}
+	if len(issued) == 0 {
+		return "/admininvites", http.StatusSeeOther
+	}
+
+	controller.audit(dbMap, c, r, AuditActionInviteIssue,
+		strconv.Itoa(len(issued)), nil, note)
+	session.AddFlash(fmt.Sprintf("Issued %d invite codes: %s", len(issued),
+		strings.Join(issued, ", ")), "adminInvitesSuccess")
+	return "/admininvites", http.StatusSeeOther
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coolsnady/hcstakepool/models"
+)
+
+func TestInviteStatus(t *testing.T) {
+	const now = 1000
+	tests := []struct {
+		code   models.InviteCode
+		status string
+	}{
+		{models.InviteCode{Expires: now + 1}, InviteStatusUnused},
+		{models.InviteCode{Expires: now}, InviteStatusExpired},
+		{models.InviteCode{Expires: now + 1, Revoked: now - 1}, InviteStatusRevoked},
+		{models.InviteCode{Expires: now - 1, Used: now - 2, UsedBy: 7}, InviteStatusUsed},
+	}
+	for i, test := range tests {
+		if status := inviteStatus(&test.code, now); status != test.status {
+			t.Errorf("%d: status %s, want %s", i, status, test.status)
+		}
+	}
+}
+
+func TestNewInviteCode(t *testing.T) {
+	code, err := newInviteCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 16 || normalizeInviteCode(code) != code {
+		t.Errorf("unexpected invite code %q", code)
+	}
+	entered := " " + code[:8] + "-" + code[8:] + "\n"
+	for _, c := range []string{entered, strings.ToLower(entered)} {
+		if normalizeInviteCode(c) != code {
+			t.Errorf("%q normalized to %q, want %q", c,
+				normalizeInviteCode(c), code)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+)
+
+// ApplyLanguage sets Lang in the template environment to the language pages
+// are shown in: the language the user chose in their preferences, or else the
+// first known language their browser accepts.
+func (controller *MainController) ApplyLanguage(c *web.C, h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		lang := ""
+		if uid, ok := controller.GetSession(*c).Values["UserId"].(int64); ok {
+			lang = controller.userLanguage(controller.GetDbMap(*c), uid)
+		}
+		if lang == "" {
+			lang = controller.locales.Match(r.Header.Get("Accept-Language"))
+		}
+		c.Env["Lang"] = lang
+		h.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// userLanguage returns the known language the user chose in their
+// preferences, or the empty string when they didn't choose one.
+func (controller *MainController) userLanguage(dbMap *gorp.DbMap, userID int64) string {
+	prefs, err := models.GetUserPreferences(dbMap, userID)
+	if err != nil || !controller.locales.Has(prefs.Language) {
+		return ""
+	}
+	return strings.ToLower(prefs.Language)
+}
+
+// language returns the language of the page being rendered for c.
+func language(c web.C) string {
+	lang, _ := c.Env["Lang"].(string)
+	return lang
+}
+
+// emailLanguage returns the language emails to the user are written in: the
+// language they chose in their preferences, or else the language of the page
+// being rendered for c.
+func (controller *MainController) emailLanguage(dbMap *gorp.DbMap, c web.C,
+	userID int64) string {
+	if lang := controller.userLanguage(dbMap, userID); lang != "" {
+		return lang
+	}
+	return language(c)
+}
@@ -0,0 +1,327 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcutil"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// ticketListFields are the fields a user's tickets can be listed by.  The
+// tickets come from the wallets rather than the database, so the columns
+// name the poolapi.Ticket fields and the query is applied in memory.
+var ticketListFields = &models.ListFields{
+	Sort: map[string]string{
+		"height":        "TicketHeight",
+		"spentbyheight": "SpentByHeight",
+		"ticket":        "Ticket",
+	},
+	Filter: map[string]string{
+		"status": "Status",
+	},
+	DefaultSort: "height",
+}
+
+// parseListQuery reads the paging, sorting and filtering parameters of a list
+// request.  The parameters are limit, offset and sort, where sort is a field
+// name that may be prefixed with "-" to sort in descending order.  Any other
+// parameter that names a filter field of the list selects rows with that
+// value.
+func parseListQuery(r *http.Request, fields *models.ListFields) (*models.ListQuery, error) {
+	q := &models.ListQuery{
+		Limit:   models.DefaultListLimit,
+		Sort:    fields.DefaultSort,
+		Filters: make(map[string]string),
+	}
+
+	var err error
+	if v := r.FormValue("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, errors.New("invalid limit")
+		}
+	}
+	if v := r.FormValue("offset"); v != "" {
+		if q.Offset, err = strconv.Atoi(v); err != nil {
+			return nil, errors.New("invalid offset")
+		}
+	}
+	if v := r.FormValue("sort"); v != "" {
+		q.Desc = strings.HasPrefix(v, "-")
+		q.Sort = strings.TrimPrefix(v, "-")
+	}
+	for field := range fields.Filter {
+		if v := r.FormValue(field); v != "" {
+			q.Filters[field] = v
+		}
+	}
+
+	if err := fields.Validate(q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// newList wraps a page of items in the response envelope shared by all list
+// endpoints.
+func newList(items interface{}, total int64, q *models.ListQuery) *poolapi.List {
+	s := q.Sort
+	if q.Desc {
+		s = "-" + s
+	}
+	return &poolapi.List{
+		Items:  items,
+		Total:  total,
+		Limit:  q.Limit,
+		Offset: q.Offset,
+		Sort:   s,
+	}
+}
+
+// pageTickets applies a query on ticketListFields to tickets.
+func pageTickets(tickets []poolapi.Ticket, q *models.ListQuery) ([]poolapi.Ticket, int64) {
+	filtered := make([]poolapi.Ticket, 0, len(tickets))
+	for _, t := range tickets {
+		if status, ok := q.Filters["status"]; ok && t.Status != status {
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+
+	less := map[string]func(a, b *poolapi.Ticket) bool{
+		"height":        func(a, b *poolapi.Ticket) bool { return a.TicketHeight < b.TicketHeight },
+		"spentbyheight": func(a, b *poolapi.Ticket) bool { return a.SpentByHeight < b.SpentByHeight },
+		"ticket":        func(a, b *poolapi.Ticket) bool { return a.Ticket < b.Ticket },
+	}[q.Sort]
+	sort.SliceStable(filtered, func(i, j int) bool {
+		a, b := &filtered[i], &filtered[j]
+		if q.Desc {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.Ticket < b.Ticket
+	})
+
+	total := int64(len(filtered))
+	if q.Offset >= len(filtered) {
+		return []poolapi.Ticket{}, total
+	}
+	end := q.Offset + q.Limit
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+	return filtered[q.Offset:end], total
+}
+
+// isAdminAPI is isAdmin for API requests, which are authenticated by their
+// API token rather than a session.
+func (controller *MainController) isAdminAPI(c web.C, r *http.Request) bool {
+	if c.Env["APIUserID"] == nil {
+		return false
+	}
+	uidstr := strconv.FormatInt(c.Env["APIUserID"].(int64), 10)
+	remoteIP := getClientIP(r, controller.realIPHeader)
+	return stringSliceContains(controller.adminIPs, remoteIP) &&
+		stringSliceContains(controller.adminUserIDs, uidstr)
+}
+
+// userTickets returns the tickets of user, who has submitted an address.
+// The code is that of the failure when err is not nil.
+func (controller *MainController) userTickets(dbMap *gorp.DbMap,
+	user *models.User) ([]poolapi.Ticket, codes.Code, error) {
+	addr, err := hcutil.DecodeAddress(user.MultiSigAddress)
+	if err != nil {
+		return nil, codes.Internal, errors.New("invalid address")
+	}
+
+	if controller.RPCIsStopped() {
+		return nil, codes.Unavailable, errors.New("RPC server stopped")
+	}
+	spui, err := controller.rpcServers.StakePoolUserInfo(addr, true)
+	if err != nil {
+		log.Infof("RPC StakePoolUserInfo failed: %v", err)
+		return nil, codes.Unavailable, errors.New("RPC server error")
+	}
+	_, height, err := controller.rpcServers.GetBestBlock()
+	if err != nil {
+		log.Infof("RPC GetBestBlock failed: %v", err)
+		return nil, codes.Unavailable, errors.New("RPC server error")
+	}
+	prefs := controller.getPreferences(dbMap, user.Id)
+
+	var tickets []poolapi.Ticket
+	if spui != nil {
+		for _, t := range spui.Tickets {
+			ticket := poolapi.Ticket{
+				Ticket:        t.Ticket,
+				Status:        t.Status,
+				TicketHeight:  t.TicketHeight,
+				SpentBy:       t.SpentBy,
+				SpentByHeight: t.SpentByHeight,
+			}
+			if t.Status == "live" || t.Status == "immature" {
+				ticket.ExpiryHeight = controller.ticketExpiryHeight(t.TicketHeight)
+				ticket.EstimatedExpiry = prefs.formatTime(
+					controller.estimateBlockTime(ticket.ExpiryHeight - height))
+			}
+			tickets = append(tickets, ticket)
+		}
+		for _, t := range spui.InvalidTickets {
+			tickets = append(tickets, poolapi.Ticket{
+				Ticket: t,
+				Status: ticketStatusInvalid,
+			})
+		}
+	}
+	return tickets, codes.OK, nil
+}
+
+// APITickets lists the tickets of the user.
+func (controller *MainController) APITickets(c web.C,
+	r *http.Request) (*poolapi.List, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if c.Env["APIUserID"] == nil {
+		return nil, codes.Unauthenticated, "tickets error", errors.New("invalid api token")
+	}
+	q, err := parseListQuery(r, ticketListFields)
+	if err != nil {
+		return nil, codes.InvalidArgument, "tickets error", err
+	}
+
+	user, err := models.GetUserById(dbMap, c.Env["APIUserID"].(int64))
+	if err != nil {
+		return nil, codes.Internal, "tickets error", errors.New("unable to fetch user")
+	}
+	if len(user.MultiSigAddress) == 0 {
+		return nil, codes.FailedPrecondition, "tickets error", errors.New("no address submitted")
+	}
+	tickets, code, err := controller.userTickets(dbMap, user)
+	if err != nil {
+		return nil, code, "tickets error", err
+	}
+
+	page, total := pageTickets(tickets, q)
+	return newList(page, total, q), codes.OK, "tickets successfully retrieved", nil
+}
+
+// APINotifications lists the last status of each ticket the user was emailed
+// about.
+func (controller *MainController) APINotifications(c web.C,
+	r *http.Request) (*poolapi.List, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if c.Env["APIUserID"] == nil {
+		return nil, codes.Unauthenticated, "notifications error", errors.New("invalid api token")
+	}
+	q, err := parseListQuery(r, models.NotificationListFields)
+	if err != nil {
+		return nil, codes.InvalidArgument, "notifications error", err
+	}
+
+	statuses, total, err := models.ListNotifications(dbMap,
+		c.Env["APIUserID"].(int64), q)
+	if err != nil {
+		log.Errorf("ListNotifications failed: %v", err)
+		return nil, codes.Internal, "notifications error", errors.New("unable to fetch notifications")
+	}
+
+	page := make([]poolapi.TicketNotification, 0, len(statuses))
+	for _, s := range statuses {
+		page = append(page, poolapi.TicketNotification{
+			Ticket: s.TicketHash,
+			Status: s.Status,
+		})
+	}
+	return newList(page, total, q), codes.OK, "notifications successfully retrieved", nil
+}
+
+// apiUser returns user as shown to admins.
+func apiUser(user *models.User) poolapi.User {
+	return poolapi.User{
+		UserID:           user.Id,
+		Email:            user.Email,
+		Username:         user.Username,
+		MultiSigAddress:  user.MultiSigAddress,
+		UserFeeAddr:      user.UserFeeAddr,
+		HeightRegistered: user.HeightRegistered,
+		EmailVerified:    user.EmailVerified != 0,
+		VoteBits:         uint16(user.VoteBits),
+		VoteBitsVersion:  uint32(user.VoteBitsVersion),
+		VotingSuspended:  user.VotingSuspended,
+		Deleted:          user.Deleted,
+	}
+}
+
+// APIUsers lists the users of the pool.  It is only available to admins.
+func (controller *MainController) APIUsers(c web.C,
+	r *http.Request) (*poolapi.List, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "users error", errors.New("not an admin")
+	}
+	q, err := parseListQuery(r, models.UserListFields)
+	if err != nil {
+		return nil, codes.InvalidArgument, "users error", err
+	}
+
+	users, total, err := models.ListUsers(dbMap, q)
+	if err != nil {
+		log.Errorf("ListUsers failed: %v", err)
+		return nil, codes.Internal, "users error", errors.New("unable to fetch users")
+	}
+
+	page := make([]poolapi.User, 0, len(users))
+	for i := range users {
+		page = append(page, apiUser(&users[i]))
+	}
+	return newList(page, total, q), codes.OK, "users successfully retrieved", nil
+}
+
+// APILowFeeTickets lists the low fee tickets admins added.  It is only
+// available to admins.
+func (controller *MainController) APILowFeeTickets(c web.C,
+	r *http.Request) (*poolapi.List, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "lowfeetickets error", errors.New("not an admin")
+	}
+	q, err := parseListQuery(r, models.LowFeeTicketListFields)
+	if err != nil {
+		return nil, codes.InvalidArgument, "lowfeetickets error", err
+	}
+
+	tickets, total, err := models.ListLowFeeTickets(dbMap, q)
+	if err != nil {
+		log.Errorf("ListLowFeeTickets failed: %v", err)
+		return nil, codes.Internal, "lowfeetickets error", errors.New("unable to fetch low fee tickets")
+	}
+
+	page := make([]poolapi.LowFeeTicket, 0, len(tickets))
+	for _, t := range tickets {
+		page = append(page, poolapi.LowFeeTicket{
+			TicketHash:    t.TicketHash,
+			TicketAddress: t.TicketAddress,
+			AddedByUserID: t.AddedByUid,
+			Voted:         t.Voted != 0,
+			Created:       t.Created,
+			Expires:       t.Expires,
+		})
+	}
+	return newList(page, total, q), codes.OK, "lowfeetickets successfully retrieved", nil
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+)
+
+func TestParseListQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  *models.ListQuery
+	}{
+		{"", &models.ListQuery{
+			Limit:   models.DefaultListLimit,
+			Sort:    "height",
+			Filters: map[string]string{},
+		}},
+		{"limit=10&offset=20&sort=-ticket&status=voted&foo=bar", &models.ListQuery{
+			Limit:   10,
+			Offset:  20,
+			Sort:    "ticket",
+			Desc:    true,
+			Filters: map[string]string{"status": "voted"},
+		}},
+		{"limit=x", nil},
+		{"offset=x", nil},
+		{"limit=0", nil},
+		{"offset=-1", nil},
+		{"sort=Password", nil},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/api/v1/tickets?"+test.query, nil)
+		q, err := parseListQuery(r, ticketListFields)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("%q: no error", test.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(q, test.want) {
+			t.Errorf("%q: got %+v, want %+v", test.query, q, test.want)
+		}
+	}
+}
+
+func TestPageTickets(t *testing.T) {
+	tickets := []poolapi.Ticket{
+		{Ticket: "c", Status: "live", TicketHeight: 30},
+		{Ticket: "a", Status: "voted", TicketHeight: 10, SpentByHeight: 300},
+		{Ticket: "d", Status: "voted", TicketHeight: 10, SpentByHeight: 200},
+		{Ticket: "b", Status: "missed", TicketHeight: 20},
+	}
+	names := func(page []poolapi.Ticket) []string {
+		s := make([]string, 0, len(page))
+		for _, t := range page {
+			s = append(s, t.Ticket)
+		}
+		return s
+	}
+
+	tests := []struct {
+		q     models.ListQuery
+		want  []string
+		total int64
+	}{
+		// Ties on the sort field are broken by the ticket hash.
+		{models.ListQuery{Limit: 50, Sort: "height"},
+			[]string{"a", "d", "b", "c"}, 4},
+		{models.ListQuery{Limit: 50, Sort: "height", Desc: true},
+			[]string{"c", "b", "d", "a"}, 4},
+		{models.ListQuery{Limit: 2, Offset: 1, Sort: "ticket"},
+			[]string{"b", "c"}, 4},
+		{models.ListQuery{Limit: 50, Sort: "spentbyheight",
+			Filters: map[string]string{"status": "voted"}},
+			[]string{"d", "a"}, 2},
+		{models.ListQuery{Limit: 1, Offset: 1, Sort: "ticket",
+			Filters: map[string]string{"status": "voted"}},
+			[]string{"d"}, 2},
+		{models.ListQuery{Limit: 50, Offset: 4, Sort: "ticket"},
+			[]string{}, 4},
+	}
+	for i, test := range tests {
+		page, total := pageTickets(tickets, &test.q)
+		if got := names(page); !reflect.DeepEqual(got, test.want) || total != test.total {
+			t.Errorf("test %d: got %v (%d total), want %v (%d total)", i,
+				got, total, test.want, test.total)
+		}
+	}
+}
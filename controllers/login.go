@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+)
+
+const (
+	// loginFailureWindow is how long failed sign ins are remembered for
+	// after the last one.
+	loginFailureWindow = time.Hour
+
+	// loginDelayFailures is how many failed sign ins are allowed before
+	// every further attempt has to wait, twice as long as the previous one
+	// up to loginMaxDelay.
+	loginDelayFailures = 3
+	loginMaxDelay      = time.Minute
+
+	// loginLockout is how long an email address is locked out for after
+	// loginLockoutEmailFailures failed sign ins, and an IP address after
+	// loginLockoutIPFailures.  IP addresses get more tries since many
+	// users may share one.
+	loginLockout              = 15 * time.Minute
+	loginLockoutEmailFailures = 10
+	loginLockoutIPFailures    = 50
+)
+
+// loginWait returns how long after now sign ins counted by attempt are
+// refused for, or 0 when they are allowed.
+func loginWait(attempt *models.LoginAttempt, now time.Time) time.Duration {
+	if attempt == nil {
+		return 0
+	}
+	if wait := time.Unix(attempt.LockedUntil, 0).Sub(now); wait > 0 {
+		return wait
+	}
+	if attempt.Failures < loginDelayFailures {
+		return 0
+	}
+	delay := loginMaxDelay
+	if n := attempt.Failures - loginDelayFailures; n < 6 {
+		delay = time.Second << uint(n)
+		if delay > loginMaxDelay {
+			delay = loginMaxDelay
+		}
+	}
+	wait := time.Unix(attempt.LastFailure, 0).Add(delay).Sub(now)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// loginAttempts returns the failed sign ins for email and from remoteIP.
+func loginAttempts(dbMap *gorp.DbMap, email, remoteIP string) (*models.LoginAttempt,
+	*models.LoginAttempt, error) {
+	emailAttempt, err := models.GetLoginAttempt(dbMap,
+		models.LoginAttemptEmail, strings.ToLower(email))
+	if err != nil {
+		return nil, nil, err
+	}
+	ipAttempt, err := models.GetLoginAttempt(dbMap, models.LoginAttemptIP,
+		remoteIP)
+	if err != nil {
+		return nil, nil, err
+	}
+	return emailAttempt, ipAttempt, nil
+}
+
+// loginThrottled returns how long sign ins for email from remoteIP are
+// refused for, or 0 when they are allowed.
+func loginThrottled(dbMap *gorp.DbMap, email, remoteIP string, now time.Time) (time.Duration, error) {
+	emailAttempt, ipAttempt, err := loginAttempts(dbMap, email, remoteIP)
+	if err != nil {
+		return 0, err
+	}
+	wait := loginWait(emailAttempt, now)
+	if ipWait := loginWait(ipAttempt, now); ipWait > wait {
+		wait = ipWait
+	}
+	return wait, nil
+}
+
+// recordLoginFailure counts a failed sign in for email from remoteIP.  It is
+// counted whether or not there is an account for email so failures don't
+// reveal which addresses are registered.
+func recordLoginFailure(dbMap *gorp.DbMap, email, remoteIP string, now time.Time) {
+	windowStart := now.Add(-loginFailureWindow).Unix()
+	lockedUntil := now.Add(loginLockout)
+	attempts := []struct {
+		kind            string
+		subject         string
+		lockoutFailures int64
+	}{
+		{models.LoginAttemptEmail, strings.ToLower(email),
+			loginLockoutEmailFailures},
+		{models.LoginAttemptIP, remoteIP, loginLockoutIPFailures},
+	}
+	for _, a := range attempts {
+		locked, err := models.RecordLoginFailure(dbMap, a.kind, a.subject,
+			now.Unix(), windowStart, a.lockoutFailures, lockedUntil.Unix())
+		if err != nil {
+			log.Errorf("unable to record failed sign in for %s %v: %v",
+				a.kind, a.subject, err)
+			continue
+		}
+		if locked {
+			log.Warnf("%s %v locked out of signing in until %v", a.kind,
+				a.subject, lockedUntil)
+		}
+	}
+
+	err := models.PruneLoginAttempts(dbMap, windowStart)
+	if err != nil {
+		log.Errorf("unable to prune failed sign ins: %v", err)
+	}
+}
+
+// loginWaitMessage describes how long a user has to wait to sign in.
+func loginWaitMessage(wait time.Duration) string {
+	if wait > time.Minute {
+		return fmt.Sprintf("Too many failed sign in attempts, try again "+
+			"in %d minutes", (wait+time.Minute-1)/time.Minute)
+	}
+	return fmt.Sprintf("Too many failed sign in attempts, try again in "+
+		"%d seconds", (wait+time.Second-1)/time.Second)
+}
+
+// loginLockoutRow is a locked out email or IP address as shown on the admin
+// lockouts page.
+type loginLockoutRow struct {
+	models.LoginAttempt
+	Until string
+}
+
+// AdminLockouts renders the email and IP addresses currently locked out of
+// signing in.
+func (controller *MainController) AdminLockouts(c web.C, r *http.Request) (string, int) {
+	t := controller.GetTemplate(c)
+	session := controller.GetSession(c)
+	dbMap := controller.GetDbMap(c)
+
+	isAdmin, err := controller.isAdmin(c, r)
+	if !isAdmin {
+		log.Warnf("isAdmin check failed: %v", err)
+		return "", http.StatusUnauthorized
+	}
+
+	locked, err := models.GetLockedLoginAttempts(dbMap, time.Now().Unix())
+	if err != nil {
+		log.Errorf("GetLockedLoginAttempts failed: %v", err)
+		return "/error", http.StatusSeeOther
+	}
+	rows := make([]loginLockoutRow, 0, len(locked))
+	for _, a := range locked {
+		rows = append(rows, loginLockoutRow{
+			LoginAttempt: a,
+			Until:        time.Unix(a.LockedUntil, 0).UTC().Format(auditTimeFormat),
+		})
+	}
+
+	c.Env["Admin"] = isAdmin
+	c.Env["IsAdminLockouts"] = true
+	c.Env["Lockouts"] = rows
+	c.Env["FlashError"] = session.Flashes("adminLockoutsError")
+	c.Env["FlashSuccess"] = session.Flashes("adminLockoutsSuccess")
+
+	widgets := controller.Parse(t, "admin/lockouts", c.Env)
+
+	c.Env["Title"] = "Hcd Stake Pool - Sign In Lockouts (Admin)"
+	c.Env["Content"] = template.HTML(widgets)
+
+	return controller.Parse(t, "main", c.Env), http.StatusOK
+}
+
+// AdminLockoutsPost unlocks the email or IP address posted from
+// AdminLockouts.
+func (controller *MainController) AdminLockoutsPost(c web.C, r *http.Request) (string, int) {
+	session := controller.GetSession(c)
+	dbMap := controller.GetDbMap(c)
+
+	isAdmin, err := controller.isAdmin(c, r)
+	if !isAdmin {
+		log.Warnf("isAdmin check failed: %v", err)
+		return "", http.StatusUnauthorized
+	}
+
+	kind, subject := r.FormValue("kind"), r.FormValue("subject")
+	if kind != models.LoginAttemptEmail && kind != models.LoginAttemptIP {
+		session.AddFlash("Invalid lockout", "adminLockoutsError")
+		return "/adminlockouts", http.StatusSeeOther
+	}
+	attempt, err := models.GetLoginAttempt(dbMap, kind, subject)
+	if err == nil && attempt != nil {
+		err = models.DeleteLoginAttempt(dbMap, kind, subject)
+	}
+	if err != nil {
+		log.Errorf("unable to unlock %s %v: %v", kind, subject, err)
+		session.AddFlash("Unable to unlock "+subject, "adminLockoutsError")
+		return "/adminlockouts", http.StatusSeeOther
+	}
+
+	if attempt != nil {
+		controller.audit(dbMap, c, r, AuditActionLoginUnlock,
+			kind+":"+subject, attempt, nil)
+	}
+	session.AddFlash("Unlocked "+subject, "adminLockoutsSuccess")
+	return "/adminlockouts", http.StatusSeeOther
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	attempt := &models.LoginAttempt{LastFailure: now.Unix()}
+
+	// The first failures are free, then every attempt waits twice as long
+	// as the previous one.
+	attempt.Failures = loginDelayFailures - 1
+	if wait := loginWait(attempt, now); wait != 0 {
+		t.Errorf("waiting %v after %d failures", wait, attempt.Failures)
+	}
+	attempt.Failures = loginDelayFailures
+	if wait := loginWait(attempt, now); wait != time.Second {
+		t.Errorf("waiting %v after %d failures, want 1s", wait,
+			attempt.Failures)
+	}
+	attempt.Failures++
+	if wait := loginWait(attempt, now.Add(time.Second)); wait != time.Second {
+		t.Errorf("waiting %v after %d failures, want 1s left", wait,
+			attempt.Failures)
+	}
+	if wait := loginWait(attempt, now.Add(2*time.Second)); wait != 0 {
+		t.Errorf("waiting %v once the delay passed", wait)
+	}
+
+	attempt.Failures = loginLockoutEmailFailures - 1
+	if wait := loginWait(attempt, now); wait != loginMaxDelay {
+		t.Errorf("waiting %v after %d failures, want %v", wait,
+			attempt.Failures, loginMaxDelay)
+	}
+
+	// A lockout resets the failures.
+	attempt.Failures = 0
+	attempt.LockedUntil = now.Add(loginLockout).Unix()
+	if wait := loginWait(attempt, now); wait != loginLockout {
+		t.Errorf("locked out for %v, want %v", wait, loginLockout)
+	}
+	if wait := loginWait(attempt, now.Add(loginLockout)); wait != 0 {
+		t.Errorf("waiting %v once the lockout ended", wait)
+	}
+}
@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How the connection to the mail server is secured.
+const (
+	// SMTPSecurityTLS connects with TLS from the start (implicit TLS),
+	// usually on port 465.
+	SMTPSecurityTLS = "tls"
+
+	// SMTPSecurityStartTLS connects in plain text, usually on port 587, and
+	// requires the server to upgrade the connection with STARTTLS.
+	SMTPSecurityStartTLS = "starttls"
+
+	// SMTPSecurityNone connects in plain text, usually on port 25, and only
+	// upgrades the connection when the server offers STARTTLS.
+	SMTPSecurityNone = "none"
+)
+
+// SMTP AUTH mechanisms used with the configured username and password.
+const (
+	SMTPAuthPlain   = "plain"
+	SMTPAuthLogin   = "login"
+	SMTPAuthCRAMMD5 = "cram-md5"
+)
+
+// smtpDefaultPorts are the ports of the mail server when its host has none.
+var smtpDefaultPorts = map[string]string{
+	SMTPSecurityTLS:      "465",
+	SMTPSecurityStartTLS: "587",
+	SMTPSecurityNone:     "25",
+}
+
+const (
+	// mailerTimeout is how long connecting to the mail server or sending
+	// an email may take.
+	mailerTimeout = 30 * time.Second
+
+	// mailerMaxIdle is the most connections kept open for later emails.
+	mailerMaxIdle = 2
+
+	// mailerIdleTimeout is how long an open connection is reused.  Servers
+	// close idle connections after a few minutes.
+	mailerIdleTimeout = time.Minute
+
+	// mailerAttempts is how many times an email is tried, waiting
+	// mailerRetryDelay longer before each retry.
+	mailerAttempts   = 3
+	mailerRetryDelay = 2 * time.Second
+)
+
+// mailerConn is a connection to the mail server.
+type mailerConn struct {
+	conn   net.Conn
+	client *smtp.Client
+	idle   time.Time
+}
+
+// close ends the SMTP session and closes the connection.
+func (c *mailerConn) close() {
+	c.conn.SetDeadline(time.Now().Add(mailerTimeout))
+	c.client.Quit()
+	c.client.Close()
+}
+
+// Mailer sends emails through the mail server.  Connections are reused for
+// the emails sent shortly after each other, and sending is retried on
+// temporary failures.  It is safe for concurrent access.
+type Mailer struct {
+	host       string
+	serverName string
+	from       string
+	sender     string
+	security   string
+	auth       smtp.Auth
+
+	mtx  sync.Mutex
+	idle []*mailerConn
+}
+
+// NewMailer returns a Mailer sending emails from from through the mail server
+// at host, which connects as security says and, when username is set,
+// authenticates with authMechanism.  The port of host defaults to the usual
+// one for security.
+func NewMailer(host, from, username, password, security,
+	authMechanism string) (*Mailer, error) {
+	port, ok := smtpDefaultPorts[security]
+	if !ok {
+		return nil, fmt.Errorf("unknown SMTP security %q", security)
+	}
+	serverName, _, err := net.SplitHostPort(host)
+	if err != nil {
+		serverName = host
+		host = net.JoinHostPort(host, port)
+	}
+
+	m := &Mailer{
+		host:       host,
+		serverName: serverName,
+		from:       from,
+		sender:     username,
+		security:   security,
+	}
+	if username != "" {
+		switch authMechanism {
+		case SMTPAuthPlain:
+			m.auth = smtp.PlainAuth("", username, password, serverName)
+		case SMTPAuthLogin:
+			m.auth = &loginAuth{username, password, serverName}
+		case SMTPAuthCRAMMD5:
+			m.auth = smtp.CRAMMD5Auth(username, password)
+		default:
+			return nil, fmt.Errorf("unknown SMTP auth mechanism %q",
+				authMechanism)
+		}
+	}
+	// The envelope sender is the authenticated user, which servers often
+	// require, or else the from address.
+	if m.sender == "" {
+		if addr, err := mail.ParseAddress(from); err == nil {
+			m.sender = addr.Address
+		}
+	}
+	return m, nil
+}
+
+// dial connects and authenticates to the mail server.
+func (m *Mailer) dial() (*mailerConn, error) {
+	tlsConfig := &tls.Config{ServerName: m.serverName}
+	dialer := &net.Dialer{Timeout: mailerTimeout}
+	var conn net.Conn
+	var err error
+	if m.security == SMTPSecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", m.host, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", m.host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(mailerTimeout))
+
+	client, err := smtp.NewClient(conn, m.serverName)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c := &mailerConn{conn: conn, client: client}
+	if m.security != SMTPSecurityTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, err
+			}
+		} else if m.security == SMTPSecurityStartTLS {
+			c.close()
+			return nil, errors.New("mail server does not offer STARTTLS")
+		}
+	}
+	if m.auth != nil {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err = client.Auth(m.auth); err != nil {
+				c.close()
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// conn returns an idle connection to the mail server that still works, or
+// else a new one.
+func (m *Mailer) conn() (*mailerConn, error) {
+	for {
+		m.mtx.Lock()
+		if len(m.idle) == 0 {
+			m.mtx.Unlock()
+			return m.dial()
+		}
+		c := m.idle[len(m.idle)-1]
+		m.idle = m.idle[:len(m.idle)-1]
+		m.mtx.Unlock()
+
+		if time.Since(c.idle) < mailerIdleTimeout {
+			c.conn.SetDeadline(time.Now().Add(mailerTimeout))
+			if c.client.Reset() == nil {
+				return c, nil
+			}
+		}
+		c.close()
+	}
+}
+
+// put keeps c open for later emails, unless enough connections are.
+func (m *Mailer) put(c *mailerConn) {
+	c.idle = time.Now()
+	m.mtx.Lock()
+	if len(m.idle) < mailerMaxIdle {
+		m.idle = append(m.idle, c)
+		c = nil
+	}
+	m.mtx.Unlock()
+	if c != nil {
+		c.close()
+	}
+}
+
+// deliver sends msg to to over c.
+func (m *Mailer) deliver(c *smtp.Client, to string, msg []byte) error {
+	if err := c.Mail(m.sender); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// send sends msg to to once.
+func (m *Mailer) send(to string, msg []byte) error {
+	c, err := m.conn()
+	if err != nil {
+		return err
+	}
+	if err = m.deliver(c.client, to, msg); err != nil {
+		c.close()
+		return err
+	}
+	m.put(c)
+	return nil
+}
+
+// temporarySMTPError returns whether sending an email that failed with err
+// may work when retried.  The mail server rejecting the email for good is
+// reported with 5xx codes.
+func temporarySMTPError(err error) bool {
+	if smtpErr, ok := err.(*textproto.Error); ok {
+		return smtpErr.Code < 500
+	}
+	return true
+}
+
+// Send sends the email message msg, composed by composeEmail, to to.
+func (m *Mailer) Send(to string, msg []byte) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = m.send(to, msg)
+		if err == nil || attempt == mailerAttempts || !temporarySMTPError(err) {
+			return err
+		}
+		log.Warnf("Sending email to %s failed, retrying: %v", to, err)
+		time.Sleep(time.Duration(attempt) * mailerRetryDelay)
+	}
+}
+
+// Close closes the idle connections to the mail server.
+func (m *Mailer) Close() {
+	m.mtx.Lock()
+	idle := m.idle
+	m.idle = nil
+	m.mtx.Unlock()
+	for _, c := range idle {
+		c.close()
+	}
+}
+
+// loginAuth is the LOGIN SMTP AUTH mechanism, which some servers offer
+// instead of PLAIN.  Like smtp.PlainAuth, it only sends the credentials over
+// TLS or to localhost.
+type loginAuth struct {
+	username, password, host string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	local := server.Name == "localhost" || server.Name == "127.0.0.1" ||
+		server.Name == "::1"
+	if !server.TLS && !local {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTPServer accepts plain text SMTP sessions and records the emails it
+// is sent.  Recipients in reject are refused with code 550 and the others in
+// busy are refused once with code 451.
+type fakeSMTPServer struct {
+	listener net.Listener
+
+	mtx    sync.Mutex
+	conns  int
+	emails []string
+	reject map[string]bool
+	busy   map[string]bool
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{listener: l, reject: make(map[string]bool),
+		busy: make(map[string]bool)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mtx.Lock()
+			s.conns++
+			s.mtx.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.Fields(line + " x")[0])
+		switch cmd {
+		case "EHLO", "HELO", "MAIL", "RSET", "NOOP":
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			to := strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>")
+			s.mtx.Lock()
+			rejected, busy := s.reject[to], s.busy[to]
+			delete(s.busy, to)
+			s.mtx.Unlock()
+			switch {
+			case rejected:
+				tp.PrintfLine("550 no such user")
+			case busy:
+				tp.PrintfLine("451 try again later")
+			default:
+				tp.PrintfLine("250 OK")
+			}
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			b, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mtx.Lock()
+			s.emails = append(s.emails, string(b))
+			s.mtx.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+func TestMailer(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	defer server.listener.Close()
+	server.reject["gone@example.com"] = true
+	server.busy["busy@example.com"] = true
+
+	m, err := NewMailer(server.listener.Addr().String(),
+		"Pool <pool@example.com>", "", "", SMTPSecurityNone, SMTPAuthPlain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if m.sender != "pool@example.com" {
+		t.Errorf("envelope sender %q", m.sender)
+	}
+
+	for _, to := range []string{"a@example.com", "b@example.com",
+		"busy@example.com"} {
+		if err := m.Send(to, []byte("Subject: test\r\n\r\nhi\r\n")); err != nil {
+			t.Fatalf("sending to %s: %v", to, err)
+		}
+	}
+	if err := m.Send("gone@example.com", []byte("hi\r\n")); err == nil {
+		t.Error("sending to a rejected address succeeded")
+	}
+
+	server.mtx.Lock()
+	defer server.mtx.Unlock()
+	if len(server.emails) != 3 {
+		t.Errorf("server received %d emails, want 3", len(server.emails))
+	}
+	// The connection is reused until the temporary failure drops it.
+	if server.conns != 2 {
+		t.Errorf("mailer opened %d connections, want 2", server.conns)
+	}
+}
+
+func TestNewMailer(t *testing.T) {
+	tests := []struct {
+		host, security, wantHost string
+	}{
+		{"mail.example.com", SMTPSecurityTLS, "mail.example.com:465"},
+		{"mail.example.com", SMTPSecurityStartTLS, "mail.example.com:587"},
+		{"mail.example.com:2525", SMTPSecurityNone, "mail.example.com:2525"},
+	}
+	for _, test := range tests {
+		m, err := NewMailer(test.host, "pool@example.com", "user", "pass",
+			test.security, SMTPAuthLogin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.host != test.wantHost || m.serverName != "mail.example.com" ||
+			m.sender != "user" {
+			t.Errorf("NewMailer(%q, %q) has host %q, server name %q and "+
+				"sender %q", test.host, test.security, m.host,
+				m.serverName, m.sender)
+		}
+	}
+	if _, err := NewMailer("mail.example.com", "", "", "", "ssl",
+		SMTPAuthPlain); err == nil {
+		t.Error("unknown security accepted")
+	}
+	if _, err := NewMailer("mail.example.com", "", "user", "pass",
+		SMTPSecurityTLS, "digest-md5"); err == nil {
+		t.Error("unknown auth mechanism accepted")
+	}
+}
+
+func TestLoginAuth(t *testing.T) {
+	a := &loginAuth{"user", "pass", "mail.example.com"}
+	if _, _, err := a.Start(&smtp.ServerInfo{Name: "mail.example.com"}); err == nil {
+		t.Error("LOGIN without TLS accepted")
+	}
+	mech, _, err := a.Start(&smtp.ServerInfo{Name: "mail.example.com", TLS: true})
+	if err != nil || mech != "LOGIN" {
+		t.Fatalf("Start = %q, %v", mech, err)
+	}
+	for challenge, want := range map[string]string{
+		"Username:": "user",
+		"Password:": "pass",
+	} {
+		resp, err := a.Next([]byte(challenge), true)
+		if err != nil || string(resp) != want {
+			t.Errorf("Next(%q) = %q, %v", challenge, resp, err)
+		}
+	}
+	if _, err := a.Next([]byte("Realm:"), true); err == nil {
+		t.Error("unknown challenge accepted")
+	}
+}
@@ -1,18 +1,18 @@
 package controllers
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"html/template"
-	"crypto/tls"
 	"net"
 	"net/http"
-	"net/smtp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coolsnady/hcd/chaincfg"
@@ -21,31 +21,29 @@ import (
 	"github.com/coolsnady/hcutil"
 	"github.com/coolsnady/hcutil/hdkeychain"
 	"github.com/coolsnady/hcstakepool/helpers"
+	"github.com/coolsnady/hcstakepool/i18n"
 	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/notifier"
 	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcstakepool/pricefeed"
+	"github.com/coolsnady/hcstakepool/scrub"
 	"github.com/coolsnady/hcstakepool/stakepooldclient"
 	"github.com/coolsnady/hcstakepool/system"
+	"github.com/coolsnady/hcstakepool/tracing"
+	"github.com/coolsnady/hcstakepool/version"
 	"github.com/coolsnady/hcwallet/wallet/udb"
 	"github.com/go-gorp/gorp"
-	"github.com/haisum/recaptcha"
 	"github.com/zenazn/goji/web"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/connectivity"
 )
 
 var (
 	// MaxUsers is the maximum number of users supported by a stake pool.
 	// This is an artificial limit and can be increased by adjusting the
 	// ticket/fee address indexes above 10000.
-	MaxUsers            = 10000
-	signupEmailSubject  = "Stake pool email verification"
-	signupEmailTemplate = "A request for an account for __URL__\r\n" +
-		"was made from __REMOTEIP__ for this email address.\r\n\n" +
-		"If you made this request, follow the link below:\r\n\n" +
-		"__URL__/emailverify?t=__TOKEN__\r\n\n" +
-		"to verify your email address and finalize registration.\r\n\n"
+	MaxUsers                    = 10000
 	StakepooldUpdateKindAll     = "ALL"
 	StakepooldUpdateKindUsers   = "USERS"
 	StakepooldUpdateKindTickets = "TICKETS"
@@ -62,27 +60,45 @@ type MainController struct {
 	APISecret            string
 	APIVersionsSupported []int
 	baseURL              string
+	captcha              *Captcha
 	closePool            bool
 	closePoolMsg         string
+	emailLogoURL         string
 	enableStakepoold     bool
 	feeXpub              *hdkeychain.ExtendedKey
 	grpcConnections      []*grpc.ClientConn
+	grpcConnectionsMtx   sync.RWMutex
+	inviteSignup         bool
+	inviteExpiry         time.Duration
+	stakepooldHosts      []string
+	stakepooldDialer     StakepooldDialer
+	locales              *i18n.Catalog
+	maintenance          maintenanceState
 	poolEmail            string
 	poolFees             float64
 	poolLink             string
+	poolName             string
 	params               *chaincfg.Params
 	rpcServers           *walletSvrManager
 	realIPHeader         string
-	recaptchaSecret      string
-	recaptchaSiteKey     string
-	smtpFrom             string
-	smtpHost             string
-	smtpUsername         string
-	smtpPassword         string
+	mailer               *Mailer
+	templates            *system.Application
+	termsVersion         string
+	ticketWaiters        chan struct{}
 	version              string
 	voteVersion          uint32
+	voteVersionMtx       sync.RWMutex
 	votingXpub           *hdkeychain.ExtendedKey
 	maxVotedAge          int64
+	expiryWarning        int64
+	priceFeed            *pricefeed.Feed
+	missedVoteAlert      *MissedVoteAlert
+	telegram             *Telegram
+	notifier             *notifier.Notifier
+	feeAddressWarning    int64
+	stakeVersionWarn     int64
+	operatorAlerts       operatorAlerts
+	consistency          consistencyState
 }
 
 func randToken() string {
@@ -117,11 +133,19 @@ func NewMainController(params *chaincfg.Params, adminIPs []string,
 	adminUserIDs []string, APISecret string, APIVersionsSupported []int,
 	baseURL string, closePool bool, closePoolMsg string, enablestakepoold bool,
 	feeXpubStr string,
-	grpcConnections []*grpc.ClientConn, poolFees float64, poolEmail, poolLink,
-	recaptchaSecret, recaptchaSiteKey string, smtpFrom, smtpHost, smtpUsername,
-	smtpPassword, version string, walletHosts, walletCerts, walletUsers,
-	walletPasswords []string, minServers int, realIPHeader,
-	votingXpubStr string, maxVotedAge int64) (*MainController, error) {
+	grpcConnections []*grpc.ClientConn, stakepooldHosts []string,
+	stakepooldDialer StakepooldDialer, poolFees float64, poolEmail, poolLink,
+	poolName, emailLogoURL string, captcha *Captcha, mailer *Mailer,
+	version string, walletHosts, walletCerts, walletUsers,
+	walletPasswords []string, minServers int, lockedWallets bool, realIPHeader,
+	votingXpubStr string, maxVotedAge, expiryWarning int64,
+	priceFeed *pricefeed.Feed,
+	missedVoteAlert *MissedVoteAlert, telegram *Telegram,
+	chatNotifier *notifier.Notifier, feeAddressWarning int64,
+	stakeVersionWarn int64, termsVersion string,
+	inviteSignup bool, inviteExpiry time.Duration, maintenanceFile string,
+	locales *i18n.Catalog,
+	templates *system.Application) (*MainController, error) {
 
 	// Parse the extended public key and the pool fees.
 	feeKey, err := hdkeychain.NewKeyFromString(feeXpubStr)
@@ -141,7 +165,8 @@ func NewMainController(params *chaincfg.Params, adminIPs []string,
 		return nil, fmt.Errorf("voting extended public key is for wrong network")
 	}
 
-	rpcs, err := newWalletSvrManager(walletHosts, walletCerts, walletUsers, walletPasswords, minServers)
+	rpcs, err := newWalletSvrManager(walletHosts, walletCerts, walletUsers, walletPasswords, minServers,
+		lockedWallets)
 	if err != nil {
 		return nil, err
 	}
@@ -152,26 +177,40 @@ func NewMainController(params *chaincfg.Params, adminIPs []string,
 		APISecret:            APISecret,
 		APIVersionsSupported: APIVersionsSupported,
 		baseURL:              baseURL,
+		captcha:              captcha,
 		closePool:            closePool,
 		closePoolMsg:         closePoolMsg,
+		emailLogoURL:         emailLogoURL,
 		enableStakepoold:     enablestakepoold,
 		feeXpub:              feeKey,
 		grpcConnections:      grpcConnections,
+		inviteSignup:         inviteSignup,
+		inviteExpiry:         inviteExpiry,
+		maintenance:          maintenanceState{path: maintenanceFile},
+		stakepooldHosts:      stakepooldHosts,
+		stakepooldDialer:     stakepooldDialer,
+		locales:              locales,
 		poolEmail:            poolEmail,
 		poolFees:             poolFees,
 		poolLink:             poolLink,
+		poolName:             poolName,
 		params:               params,
-		recaptchaSecret:      recaptchaSecret,
-		recaptchaSiteKey:     recaptchaSiteKey,
 		rpcServers:           rpcs,
 		realIPHeader:         realIPHeader,
-		smtpFrom:             smtpFrom,
-		smtpHost:             smtpHost,
-		smtpUsername:         smtpUsername,
-		smtpPassword:         smtpPassword,
+		mailer:               mailer,
+		templates:            templates,
+		termsVersion:         termsVersion,
+		ticketWaiters:        make(chan struct{}, maxTicketWaiters),
 		version:              version,
 		votingXpub:           voteKey,
 		maxVotedAge:          maxVotedAge,
+		expiryWarning:        expiryWarning,
+		priceFeed:            priceFeed,
+		missedVoteAlert:      missedVoteAlert,
+		telegram:             telegram,
+		notifier:             chatNotifier,
+		feeAddressWarning:    feeAddressWarning,
+		stakeVersionWarn:     stakeVersionWarn,
 	}
 
 	voteVersion, err := mc.GetVoteVersion()
@@ -199,10 +238,11 @@ func (controller *MainController) getNetworkName() string {
 // API is the main frontend that handles all API requests.
 func (controller *MainController) API(c web.C, r *http.Request) *system.APIResponse {
 	command := c.URLParams["command"]
+	controller.recordAPITokenUse(c, r)
 
 	// poolapi.Response comprises a status, code, message, and a data struct
 	var code codes.Code
-	var response, status string
+	var response string
 	var data interface{}
 
 	var err error
@@ -210,10 +250,40 @@ func (controller *MainController) API(c web.C, r *http.Request) *system.APIRespo
 	switch r.Method {
 	case "GET":
 		switch command {
+		case "auditlog":
+			data, code, response, err = controller.APIAuditLog(c, r)
+		case "export":
+			data, code, response, err = controller.APIExport(c, r)
 		case "getpurchaseinfo":
 			data, code, response, err = controller.APIPurchaseInfo(c, r)
+		case "lowfeetickets":
+			data, code, response, err = controller.APILowFeeTickets(c, r)
+		case "maintenance":
+			data, code, response, err = controller.APIMaintenance(c, r)
+		case "notifications":
+			data, code, response, err = controller.APINotifications(c, r)
+		case "preferences":
+			data, code, response, err = controller.APIPreferences(c, r)
+		case "stakeinfo":
+			data, code, response, err = controller.APIStakeInfo(c, r)
+		case "stakepoold":
+			data, code, response, err = controller.APIStakepoold(c, r)
 		case "stats":
 			data, code, response, err = controller.APIStats(c, r)
+		case "tickets":
+			data, code, response, err = controller.APITickets(c, r)
+		case "user":
+			data, code, response, err = controller.APIUser(c, r)
+		case "ticketstatus":
+			data, code, response, err = controller.APITicketStatus(c, r)
+		case "users":
+			data, code, response, err = controller.APIUsers(c, r)
+		case "usersearch":
+			data, code, response, err = controller.APIUserSearch(c, r)
+		case "version":
+			data, code, response, err = controller.APIVersion(c, r)
+		case "webhook":
+			data, code, response, err = controller.APIWebhook(c, r)
 		default:
 			return nil
 		}
@@ -221,18 +291,38 @@ func (controller *MainController) API(c web.C, r *http.Request) *system.APIRespo
 		switch command {
 		case "address":
 			_, code, response, err = controller.APIAddress(c, r)
+		case "maintenance":
+			data, code, response, err = controller.APIMaintenancePost(c, r)
+		case "preferences":
+			data, code, response, err = controller.APIPreferencesPost(c, r)
+		case "stakepooldadd":
+			data, code, response, err = controller.APIStakepooldAdd(c, r)
+		case "stakepooldremove":
+			data, code, response, err = controller.APIStakepooldRemove(c, r)
+		case "usersuspendvoting":
+			data, code, response, err = controller.APIUserSuspendVoting(c, r)
+		case "userverifyemail":
+			data, code, response, err = controller.APIUserVerifyEmail(c, r)
 		case "voting":
 			_, code, response, err = controller.APIVoting(c, r)
+		case "webhook":
+			data, code, response, err = controller.APIWebhookPost(c, r)
 		default:
 			return nil
 		}
 	}
 
+	return apiResponse(data, code, response, err)
+}
+
+// apiResponse returns the response to an API call that returned data, code,
+// response and err.
+func apiResponse(data interface{}, code codes.Code, response string,
+	err error) *system.APIResponse {
+	status := "success"
 	if err != nil {
 		status = "error"
-		response = response + " - " + err.Error()
-	} else {
-		status = "success"
+		response = response + " - " + scrub.String(err.Error())
 	}
 
 	return system.NewAPIResponse(status, code, response, data)
@@ -245,6 +335,9 @@ func (controller *MainController) APIAddress(c web.C, r *http.Request) ([]string
 	if c.Env["APIUserID"] == nil {
 		return nil, codes.Unauthenticated, "address error", errors.New("invalid api token")
 	}
+	if controller.inMaintenance() {
+		return nil, codes.Unavailable, "address error", errMaintenance
+	}
 
 	user, _ := models.GetUserById(dbMap, c.Env["APIUserID"].(int64))
 
@@ -340,7 +433,9 @@ func (controller *MainController) APIAddress(c web.C, r *http.Request) ([]string
 		userFeeAddr.EncodeAddress(), bestBlockHeight)
 
 	log.Infof("successfully create multisigaddress for user %d", c.Env["APIUserID"])
-	controller.StakepooldUpdateAll(dbMap, StakepooldUpdateKindUsers)
+	controller.recordSecurityEvent(dbMap, r, user.Id,
+		models.SecurityEventAddress, createMultiSig.Address, time.Now())
+	controller.StakepooldUpdateAll(r.Context(), dbMap, StakepooldUpdateKindUsers)
 
 	return nil, codes.OK, "address successfully imported", nil
 }
@@ -361,11 +456,12 @@ func (controller *MainController) APIPurchaseInfo(c web.C,
 	}
 
 	purchaseInfo := &poolapi.PurchaseInfo{
-		PoolAddress:   user.UserFeeAddr,
-		PoolFees:      controller.poolFees,
-		Script:        user.MultiSigScript,
-		TicketAddress: user.MultiSigAddress,
-		VoteBits:      uint16(user.VoteBits),
+		PoolAddress:       user.UserFeeAddr,
+		PoolFees:          controller.poolFees,
+		Script:            user.MultiSigScript,
+		TicketAddress:     user.MultiSigAddress,
+		VoteBits:          uint16(user.VoteBits),
+		VoteBitsReconfirm: user.VoteBitsReconfirm != 0,
 	}
 
 	return purchaseInfo, codes.OK, "purchaseinfo successfully retrieved", nil
@@ -418,11 +514,36 @@ func (controller *MainController) APIStats(c web.C,
 		UserCount:            userCount,
 		UserCountActive:      userCountActive,
 		Version:              controller.version,
+		Commit:               version.Commit,
+		BuildDate:            version.BuildDate,
+	}
+
+	currency := controller.requestCurrency(c, r)
+	if fiat := controller.fiatValue(gsi.TotalSubsidy, currency); fiat != nil {
+		stats.FiatCurrency = fiat.Currency
+		stats.TotalSubsidyFiat = fiat.Amount
 	}
 
 	return stats, codes.OK, "stats successfully retrieved", nil
 }
 
+// APIVersion returns the version and build information of the frontend.  It
+// is available without an API token so tooling can check deployments.
+func (controller *MainController) APIVersion(c web.C,
+	r *http.Request) (*poolapi.Version, codes.Code, string, error) {
+	info := version.Get()
+	v := &poolapi.Version{
+		Version:              controller.version,
+		Commit:               info.Commit,
+		BuildDate:            info.BuildDate,
+		GoVersion:            info.GoVersion,
+		Network:              controller.params.Name,
+		APIVersionsSupported: controller.APIVersionsSupported,
+	}
+
+	return v, codes.OK, "version successfully retrieved", nil
+}
+
 // APIVotingPost is the API version of VotingPost
 func (controller *MainController) APIVoting(c web.C, r *http.Request) ([]string, codes.Code, string, error) {
 	dbMap := controller.GetDbMap(c)
@@ -432,6 +553,12 @@ func (controller *MainController) APIVoting(c web.C, r *http.Request) ([]string,
 	}
 
 	user, _ := models.GetUserById(dbMap, c.Env["APIUserID"].(int64))
+	if user.VotingSuspended != 0 {
+		return nil, codes.PermissionDenied, "voting error", errVotingSuspended
+	}
+	if controller.inMaintenance() {
+		return nil, codes.Unavailable, "voting error", errMaintenance
+	}
 	oldVoteBits := user.VoteBits
 
 	vb := r.FormValue("VoteBits")
@@ -451,7 +578,7 @@ func (controller *MainController) APIVoting(c web.C, r *http.Request) ([]string,
 	}
 
 	if uint16(oldVoteBits) != userVoteBits {
-		controller.StakepooldUpdateAll(dbMap, StakepooldUpdateKindUsers)
+		controller.StakepooldUpdateAll(r.Context(), dbMap, StakepooldUpdateKindUsers)
 	}
 
 	log.Infof("updated voteBits for user %d from %d to %d",
@@ -483,74 +610,6 @@ func (controller *MainController) isAdmin(c web.C, r *http.Request) (bool, error
 
 	return true, nil
 }
-//return a smtp client
-func Dial(addr string) (*smtp.Client, error) {
-    conn, err := tls.Dial("tcp", addr, nil)
-    if err != nil {
-		log.Errorf("Dialing Error:", err)
-		return nil,err
-    }
-    //分解主机端口字符串
-    host, _, _ := net.SplitHostPort(addr)
-    return smtp.NewClient(conn, host)
-}
-
-func (controller *MainController) SendMailUsingTLS(emailaddress string , subject string, body string) (err error) {
-	hostname := controller.smtpHost
-
-	if strings.Contains(controller.smtpHost, ":") {
-		parts := strings.Split(controller.smtpHost, ":")
-		hostname = parts[0]
-	}
-
-	auth := smtp.PlainAuth( "", controller.smtpUsername, controller.smtpPassword,hostname)
-
-	//create smtp client
-	c, err := Dial(controller.smtpHost)
-	if err != nil {
-		log.Errorf("Create smpt client error:", err)
-		return err
-	}
-   defer c.Close()
-
-   if auth != nil {
-		if ok, _ := c.Extension("AUTH"); ok {
-			if err = c.Auth(auth); err != nil {
-				return err
-			}
-		}
-	}
-
-	msg := []byte("To: " + emailaddress + "\r\n" +
-		"From: " + controller.smtpFrom + "\r\n" +
-		"Subject: " + subject + "\r\n" +
-		"\r\n" +
-		body + "\r\n")
-    
-	if err = c.Mail(controller.smtpUsername); err != nil {
-		return err
-	}
-
-	if err = c.Rcpt(emailaddress); err != nil {
-		return err
-	}
-
-	w, err := c.Data()
-	if err != nil {
-		return err
-	}
-
-	_, err = w.Write(msg)
-	if err != nil {
-		return err
-	}
-
-	err = w.Close()
-	if err != nil {
-		return err
-	}
-	return c.Quit()
-}
 // StakepooldGetIgnoredLowFeeTickets performs a gRPC GetIgnoredLowFeeTickets
 // request against all stakepoold instances and returns the first result fetched
 // without errors
@@ -559,8 +618,8 @@ func (controller *MainController) StakepooldGetIgnoredLowFeeTickets() (map[chain
 	ignoredLowFeeTickets := make(map[chainhash.Hash]string)
 
 	// TODO need some better code here
-	for i := range controller.grpcConnections {
-		ignoredLowFeeTickets, err = stakepooldclient.StakepooldGetIgnoredLowFeeTickets(controller.grpcConnections[i])
+	for i, conn := range controller.stakepooldConnections() {
+		ignoredLowFeeTickets, err = stakepooldclient.StakepooldGetIgnoredLowFeeTickets(conn)
 		// take the first non-error result
 		if err == nil {
 			return ignoredLowFeeTickets, err
@@ -571,12 +630,17 @@ func (controller *MainController) StakepooldGetIgnoredLowFeeTickets() (map[chain
 }
 
 // StakepooldUpdateAll attempts to trigger all connected stakepoold
-// instances to pull a data update of the specified kind.
-func (controller *MainController) StakepooldUpdateAll(dbMap *gorp.DbMap, updateKind string) error {
+// instances to pull a data update of the specified kind.  The updates are
+// traced as part of the request in ctx.
+func (controller *MainController) StakepooldUpdateAll(ctx context.Context, dbMap *gorp.DbMap, updateKind string) error {
 	var votableLowFeeTickets []models.LowFeeTicket
 	var allUsers map[int64]*models.User
 	var err error
 
+	ctx, span := tracing.Start(ctx, "StakepooldUpdateAll", tracing.SpanKindInternal)
+	defer span.End()
+	span.SetAttribute("update.kind", updateKind)
+
 	switch updateKind {
 	case StakepooldUpdateKindAll, StakepooldUpdateKindTickets, StakepooldUpdateKindUsers:
 		// valid
@@ -604,13 +668,13 @@ func (controller *MainController) StakepooldUpdateAll(dbMap *gorp.DbMap, updateK
 	}
 
 	successCount := 0
-	for i := range controller.grpcConnections {
+	for i, conn := range controller.stakepooldConnections() {
 		var err error
 		var success bool
 
 		switch updateKind {
 		case StakepooldUpdateKindAll, StakepooldUpdateKindTickets:
-			success, err = stakepooldclient.StakepooldSetAddedLowFeeTickets(controller.grpcConnections[i], votableLowFeeTickets)
+			success, err = stakepooldclient.StakepooldSetAddedLowFeeTickets(ctx, conn, votableLowFeeTickets)
 			if err != nil {
 				log.Errorf("stakepoold host %d unable to update manual "+
 					"tickets grpc error: %v", i, err)
@@ -625,7 +689,7 @@ func (controller *MainController) StakepooldUpdateAll(dbMap *gorp.DbMap, updateK
 
 		switch updateKind {
 		case StakepooldUpdateKindAll, StakepooldUpdateKindUsers:
-			success, err = stakepooldclient.StakepooldSetUserVotingPrefs(controller.grpcConnections[i], allUsers)
+			success, err = stakepooldclient.StakepooldSetUserVotingPrefs(ctx, conn, allUsers)
 			if err != nil {
 				log.Errorf("stakepoold host %d unable to update voting config "+
 					"grpc error: %v", i, err)
@@ -645,6 +709,7 @@ func (controller *MainController) StakepooldUpdateAll(dbMap *gorp.DbMap, updateK
 		}
 	}
 
+	span.SetAttribute("stakepoold.updated", strconv.Itoa(successCount))
 	if successCount == 0 {
 		log.Warn("no stakepoold connections alive/working?")
 	}
@@ -652,6 +717,37 @@ func (controller *MainController) StakepooldUpdateAll(dbMap *gorp.DbMap, updateK
 	return nil
 }
 
+// StakepooldUpdateUsers sends the voting preferences of users to all
+// connected stakepoold instances in one call each, leaving those of other
+// users alone.  stakepoold instances that don't implement the batch call are
+// sent the preferences of all users instead.
+func (controller *MainController) StakepooldUpdateUsers(ctx context.Context, dbMap *gorp.DbMap, users map[int64]*models.User) error {
+	ctx, span := tracing.Start(ctx, "StakepooldUpdateUsers", tracing.SpanKindInternal)
+	defer span.End()
+	span.SetAttribute("users", strconv.Itoa(len(users)))
+
+	var allUsers map[int64]*models.User
+	for i, conn := range controller.stakepooldConnections() {
+		_, err := stakepooldclient.StakepooldBatchSetUserVotingPrefs(ctx,
+			conn, users)
+		if grpc.Code(err) == codes.Unimplemented {
+			if allUsers == nil {
+				allUsers, err = controller.CheckAndResetUserVoteBits(dbMap)
+				if err != nil {
+					return err
+				}
+			}
+			_, err = stakepooldclient.StakepooldSetUserVotingPrefs(ctx,
+				conn, allUsers)
+		}
+		if err != nil {
+			log.Errorf("stakepoold host %d unable to update voting config "+
+				"grpc error: %v", i, err)
+		}
+	}
+	return nil
+}
+
 // FeeAddressForUserID generates a unique payout address per used ID for
 // fees for an individual pool user.
 func (controller *MainController) FeeAddressForUserID(uid int) (hcutil.Address,
@@ -741,10 +837,12 @@ func (controller *MainController) GetVoteVersion() (uint32, error) {
 	return voteVersion, err
 }
 
-// CheckAndResetUserVoteBits reset users VoteBits if the VoteVersion has
-// changed or if the stored VoteBits are somehow invalid.
+// CheckAndResetUserVoteBits migrates users' VoteBits to the current vote
+// version if it has changed and resets the stored VoteBits if they are somehow
+// invalid, for their additional voting addresses too.  The users are returned
+// with their additional voting addresses.
 func (controller *MainController) CheckAndResetUserVoteBits(dbMap *gorp.DbMap) (map[int64]*models.User, error) {
-	defaultVoteBits := uint16(1)
+	voteVersion := controller.currentVoteVersion()
 	userMax := models.GetUserMax(dbMap)
 	for userid := int64(1); userid <= userMax; userid++ {
 		// may have gaps due to users deleted from the database
@@ -753,29 +851,31 @@ func (controller *MainController) CheckAndResetUserVoteBits(dbMap *gorp.DbMap) (
 			continue
 		}
 
-		// Reset the user's voting preferences if the Vote Version changed
-		// since they no longer apply
-		if uint32(user.VoteBitsVersion) != controller.voteVersion {
+		// Carry the user's voting preferences over to the new Vote Version
+		// for the agendas whose meaning didn't change and have them
+		// confirm their choices again for the agendas that did.
+		if uint32(user.VoteBitsVersion) != voteVersion {
+			oldVoteBits := user.VoteBits
 			oldVoteBitsVersion := user.VoteBitsVersion
-			_, err := helpers.UpdateVoteBitsVersionByID(dbMap, userid, controller.voteVersion)
+			from, known := controller.params.Deployments[uint32(oldVoteBitsVersion)]
+			voteBits, conflicts := migrateVoteBits(uint16(oldVoteBits), from,
+				controller.params.Deployments[voteVersion])
+			reconfirm := user.VoteBitsReconfirm != 0 || len(conflicts) > 0 ||
+				(!known && uint16(oldVoteBits) != defaultVoteBits)
+
+			_, err := helpers.MigrateVoteBitsByID(dbMap, userid, voteBits,
+				voteVersion, reconfirm)
 			if err != nil {
-				return nil, fmt.Errorf("failed to update VoteBitsVersion for uid %v: %v",
+				return nil, fmt.Errorf("failed to migrate VoteBits for uid %v: %v",
 					userid, err)
 			}
 
-			log.Infof("updated VoteBitsVersion from %v to %v for uid %v",
-				oldVoteBitsVersion, controller.voteVersion, userid)
-
-			if uint16(user.VoteBits) != defaultVoteBits {
-				oldVoteBits := user.VoteBits
-				_, err = helpers.UpdateVoteBitsByID(dbMap, userid, defaultVoteBits)
-				if err != nil {
-					return nil, fmt.Errorf("failed to update VoteBits for uid %v: %v",
-						userid, err)
-				}
-
-				log.Infof("updated VoteBits from %v to %v for uid %v",
-					oldVoteBits, defaultVoteBits, userid)
+			log.Infof("migrated VoteBits %v version %v to VoteBits %v "+
+				"version %v for uid %v", oldVoteBits, oldVoteBitsVersion,
+				voteBits, voteVersion, userid)
+			if reconfirm {
+				log.Warnf("uid %v needs to confirm their voting preferences "+
+					"again, changed agendas: %v", userid, conflicts)
 			}
 		} else {
 			// Validate that the votebits are valid for the agendas of the current
@@ -794,6 +894,11 @@ func (controller *MainController) CheckAndResetUserVoteBits(dbMap *gorp.DbMap) (
 		}
 	}
 
+	addresses, err := controller.checkUserAddressVoteBits(dbMap)
+	if err != nil {
+		return nil, err
+	}
+
 	allUsers := make(map[int64]*models.User)
 	for userid := int64(1); userid <= userMax; userid++ {
 		// may have gaps due to users deleted from the database
@@ -802,6 +907,7 @@ func (controller *MainController) CheckAndResetUserVoteBits(dbMap *gorp.DbMap) (
 			continue
 		}
 
+		user.Addresses = addresses[user.Id]
 		allUsers[user.Id] = user
 	}
 
@@ -854,6 +960,16 @@ func (controller *MainController) Address(c web.C, r *http.Request) (string, int
 	c.Env["Network"] = controller.getNetworkName()
 
 	c.Env["Flash"] = session.Flashes("address")
+	c.Env["FlashSuccess"] = session.Flashes("addressSuccess")
+
+	dbMap := controller.GetDbMap(c)
+	addresses, err := models.GetUserAddresses(dbMap,
+		session.Values["UserId"].(int64))
+	if err != nil {
+		log.Errorf("GetUserAddresses failed: %v", err)
+	}
+	c.Env["UserAddresses"] = addresses
+	c.Env["CanAddAddress"] = err == nil && len(addresses) < maxUserAddresses
 	widgets := controller.Parse(t, "address", c.Env)
 
 	c.Env["Title"] = "Hcd Stake Pool - Address"
@@ -872,15 +988,35 @@ func (controller *MainController) AddressPost(c web.C, r *http.Request) (string,
 	}
 	uid64 := session.Values["UserId"].(int64)
 
-	// Only accept address if user does not already have a PubKeyAddr set.
-	dbMap := controller.GetDbMap(c)
-	user, _ := models.GetUserById(dbMap, session.Values["UserId"].(int64))
-	if len(user.UserPubKeyAddr) > 0 {
-		session.AddFlash("Stake pool is currently limited to one address per account", "address")
+	if controller.inMaintenance() {
+		session.AddFlash(errMaintenance.Error(), "address")
 		return controller.Address(c, r)
 	}
 
+	// Users who already set their first PubKeyAddr add another one.
+	dbMap := controller.GetDbMap(c)
+	user, _ := models.GetUserById(dbMap, session.Values["UserId"].(int64))
 	userPubKeyAddr := r.FormValue("UserPubKeyAddr")
+	if len(user.UserPubKeyAddr) > 0 {
+		log.Infof("Address POST from %v, additional pubkeyaddr %v", remoteIP,
+			userPubKeyAddr)
+		address, err := controller.addUserAddress(dbMap, user, userPubKeyAddr)
+		if err == errRPCUnavailable {
+			return "/error", http.StatusSeeOther
+		}
+		if err != nil {
+			session.AddFlash("Unable to add address: "+err.Error(), "address")
+			return controller.Address(c, r)
+		}
+		controller.recordSecurityEvent(dbMap, r, user.Id,
+			models.SecurityEventAddress, address.MultiSigAddress, time.Now())
+		user.Addresses = []models.UserAddress{*address}
+		controller.StakepooldUpdateUsers(r.Context(), dbMap,
+			map[int64]*models.User{user.Id: user})
+		session.AddFlash("Address added, buy tickets for it with the ticket "+
+			"address and script below", "addressSuccess")
+		return "/address", http.StatusSeeOther
+	}
 
 	log.Infof("Address POST from %v, pubkeyaddr %v", remoteIP, userPubKeyAddr)
 
@@ -984,11 +1120,14 @@ func (controller *MainController) AddressPost(c web.C, r *http.Request) (string,
 
 	// Update the user's DB entry with multisig, user and pool pubkey
 	// addresses, and the fee address
-	models.UpdateUserByID(dbMap, uid64, createMultiSig.Address,
+	user = models.UpdateUserByID(dbMap, uid64, createMultiSig.Address,
 		createMultiSig.RedeemScript, poolPubKeyAddr, userPubKeyAddr,
 		userFeeAddr.EncodeAddress(), bestBlockHeight)
+	controller.recordSecurityEvent(dbMap, r, uid64,
+		models.SecurityEventAddress, createMultiSig.Address, time.Now())
 
-	controller.StakepooldUpdateAll(dbMap, StakepooldUpdateKindUsers)
+	controller.StakepooldUpdateUsers(r.Context(), dbMap,
+		map[int64]*models.User{user.Id: user})
 
 	return "/tickets", http.StatusSeeOther
 }
@@ -1002,28 +1141,28 @@ func (controller *MainController) AdminStatus(c web.C, r *http.Request) (string,
 	}
 
 	type stakepooldInfoPage struct {
-		Status string
-	}
-
-	stakepooldPageInfo := make([]stakepooldInfoPage, len(controller.grpcConnections))
-
-	for i, conn := range controller.grpcConnections {
-		grpcStatus := "Unknown"
-		state := conn.GetState()
-		switch state {
-		case connectivity.Idle:
-			grpcStatus = "Idle"
-		case connectivity.Shutdown:
-			grpcStatus = "Shutdown"
-		case connectivity.Ready:
-			grpcStatus = "Ready"
-		case connectivity.Connecting:
-			grpcStatus = "Connecting"
-		case connectivity.TransientFailure:
-			grpcStatus = "TransientFailure"
+		Status      string
+		VoteLatency *stakepooldclient.VoteLatency
+		Wallet      *stakepooldclient.WalletInfo
+	}
+
+	conns := controller.stakepooldConnections()
+	stakepooldPageInfo := make([]stakepooldInfoPage, len(conns))
+
+	for i, conn := range conns {
+		voteLatency, err := stakepooldclient.StakepooldGetVoteLatency(conn)
+		if err != nil {
+			log.Warnf("stakepoold host %d GetVoteLatency failed: %v", i, err)
+		}
+		wallet, err := stakepooldclient.StakepooldGetWalletInfo(r.Context(),
+			conn)
+		if err != nil {
+			log.Warnf("stakepoold host %d GetWalletInfo failed: %v", i, err)
 		}
 		stakepooldPageInfo[i] = stakepooldInfoPage{
-			Status: grpcStatus,
+			Status:      grpcConnectionState(conn),
+			VoteLatency: voteLatency,
+			Wallet:      wallet,
 		}
 	}
 
@@ -1090,6 +1229,14 @@ func (controller *MainController) AdminStatus(c web.C, r *http.Request) (string,
 	c.Env["StakepooldInfo"] = stakepooldPageInfo
 	c.Env["WalletInfo"] = walletPageInfo
 	c.Env["RPCStatus"] = rpcstatus
+	c.Env["Consistency"] = controller.LastConsistencyReport()
+
+	stakeInfo, err := controller.StakeInfoAll()
+	if err != nil {
+		log.Warnf("StakeInfoAll failed: %v", err)
+	}
+	c.Env["StakeInfo"] = stakeInfo
+	c.Env["Flash"] = controller.GetSession(c).Flashes("adminstatus")
 
 	widgets := controller.Parse(t, "admin/status", c.Env)
 	c.Env["Content"] = template.HTML(widgets)
@@ -1101,6 +1248,104 @@ func (controller *MainController) AdminStatus(c web.C, r *http.Request) (string,
 	return controller.Parse(t, "main", c.Env), http.StatusOK
 }
 
+// AdminAgendas renders the agenda outcome simulation page.  It shows how the
+// pool would vote on each agenda with the current user preferences and how
+// that would change if users who never chose a preference were switched to
+// the hypothetical defaults passed in the query string.
+func (controller *MainController) AdminAgendas(c web.C, r *http.Request) (string, int) {
+	t := controller.GetTemplate(c)
+	dbMap := controller.GetDbMap(c)
+
+	isAdmin, err := controller.isAdmin(c, r)
+	if !isAdmin {
+		log.Warnf("isAdmin check failed: %v", err)
+		return "", http.StatusUnauthorized
+	}
+
+	deployments := controller.getAgendas()
+
+	// Parse the hypothetical defaults.  Choices that are not valid for the
+	// agenda are ignored so the current default is used instead.
+	var flashErrors []string
+	simulatedDefaults := make(map[int]uint16)
+	for i := range deployments {
+		agendaVal := r.URL.Query().Get("agenda" + strconv.Itoa(i))
+		if agendaVal == "" {
+			continue
+		}
+		avi, err := strconv.Atoi(agendaVal)
+		if err != nil {
+			flashErrors = append(flashErrors, "invalid agenda choice for "+
+				deployments[i].Vote.Id)
+			continue
+		}
+		var valid bool
+		for _, choice := range deployments[i].Vote.Choices {
+			if uint16(avi) == choice.Bits {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			flashErrors = append(flashErrors, "invalid agenda choice for "+
+				deployments[i].Vote.Id)
+			continue
+		}
+		simulatedDefaults[i] = uint16(avi)
+	}
+
+	addresses, err := models.GetAllVotingAddresses(dbMap)
+	if err != nil {
+		log.Errorf("GetAllVotingAddresses failed: %v", err)
+		return "/error", http.StatusSeeOther
+	}
+
+	// Weight each voting address, with the voting preferences of its own, by
+	// its live ticket count if stakepoold can tell us, otherwise fall back to
+	// counting each address once.
+	liveTicketsPerMSA := make(map[string]int)
+	weightedByTickets := false
+	for i, conn := range controller.stakepooldConnections() {
+		liveTickets, err := stakepooldclient.StakepooldGetLiveTickets(conn)
+		if err != nil {
+			log.Warnf("stakepoold host %d GetLiveTickets failed: %v", i, err)
+			continue
+		}
+		for _, msa := range liveTickets {
+			liveTicketsPerMSA[msa]++
+		}
+		weightedByTickets = true
+		break
+	}
+
+	voters := make([]agendaVoter, 0, len(addresses))
+	for _, a := range addresses {
+		weight := 1
+		if weightedByTickets {
+			weight = liveTicketsPerMSA[a.MultiSigAddress]
+		}
+		voters = append(voters, agendaVoter{
+			voteBits: uint16(a.VoteBits),
+			weight:   weight,
+		})
+	}
+
+	c.Env["Admin"] = isAdmin
+	c.Env["IsAdminAgendas"] = true
+	c.Env["FlashError"] = flashErrors
+	c.Env["Outcomes"] = simulateAgendaOutcomes(deployments, voters,
+		simulatedDefaults)
+	c.Env["VoteVersion"] = controller.currentVoteVersion()
+	c.Env["WeightedByTickets"] = weightedByTickets
+
+	widgets := controller.Parse(t, "admin/agendas", c.Env)
+
+	c.Env["Title"] = "Hcd Stake Pool - Agenda Outcomes (Admin)"
+	c.Env["Content"] = template.HTML(widgets)
+
+	return controller.Parse(t, "main", c.Env), http.StatusOK
+}
+
 // AdminTickets renders the administrative tickets page.
 func (controller *MainController) AdminTickets(c web.C, r *http.Request) (string, int) {
 	t := controller.GetTemplate(c)
@@ -1216,19 +1461,25 @@ func (controller *MainController) AdminTicketsPost(c web.C, r *http.Request) (st
 				log.Warnf("Adding ticket %v failed: %v", tickethash, err)
 				return "/admintickets", http.StatusSeeOther
 			}
+			controller.audit(dbMap, c, r, AuditActionLowFeeTicketAdd,
+				ticketToAddString, nil, lowFeeTicket)
 		}
 	case "Remove":
 		actionVerb = "removed"
-		query := "DELETE FROM LowFeeTicket WHERE TicketHash IN (\"" +
-			strings.Join(r.PostForm["tickets[]"], ",") + "\")"
-		_, err := dbMap.Exec(query)
-		if err != nil {
-			session.AddFlash("failed to execute delete query: "+err.Error(), "adminTicketsError")
-			return "/admintickets", http.StatusSeeOther
+		for _, ticketToRemoveString := range r.PostForm["tickets[]"] {
+			removed, err := models.DeleteLowFeeTicket(dbMap, ticketToRemoveString)
+			if err != nil {
+				session.AddFlash("failed to remove ticket "+ticketToRemoveString+": "+err.Error(), "adminTicketsError")
+				return "/admintickets", http.StatusSeeOther
+			}
+			for i := range removed {
+				controller.audit(dbMap, c, r, AuditActionLowFeeTicketRemove,
+					ticketToRemoveString, removed[i], nil)
+			}
 		}
 	}
 
-	err = controller.StakepooldUpdateAll(dbMap, StakepooldUpdateKindTickets)
+	err = controller.StakepooldUpdateAll(r.Context(), dbMap, StakepooldUpdateKindTickets)
 	if err != nil {
 		session.AddFlash("StakepooldUpdateAll error: "+err.Error(), "adminTicketsError")
 	}
@@ -1242,7 +1493,9 @@ func (controller *MainController) AdminTicketsPost(c web.C, r *http.Request) (st
 	return "/admintickets", http.StatusSeeOther
 }
 
-// EmailUpdate validates the passed token and updates the user's email address.
+// EmailUpdate validates the passed token and confirms the email change it was
+// sent for.  The user's email address is updated once the links sent to both
+// the old and the new address have been followed.
 func (controller *MainController) EmailUpdate(c web.C, r *http.Request) (string, int) {
 	t := controller.GetTemplate(c)
 	session := controller.GetSession(c)
@@ -1268,6 +1521,38 @@ func (controller *MainController) EmailUpdate(c web.C, r *http.Request) (string,
 			}
 		}
 
+		// changes requested before the old address had to confirm them
+		// have no old token and can't be completed.
+		if !failed && emailChange.OldToken == "" {
+			session.AddFlash("Email change token has expired",
+				"emailupdateError")
+			failed = true
+		}
+
+		if !failed {
+			err := helpers.EmailChangeConfirm(dbMap, emailChange,
+				token == emailChange.OldToken, time.Now().Unix())
+			if err != nil {
+				session.AddFlash("Error occurred while confirming email "+
+					"address change", "emailupdateError")
+				log.Errorf("EmailChangeConfirm failed %v", err)
+				failed = true
+			}
+		}
+
+		// nothing failed but the change waits for the other address.
+		if !failed && emailChange.OldConfirmed == 0 {
+			session.AddFlash("New email address confirmed.  Follow the "+
+				"link sent to your current email address to complete "+
+				"the change", "emailupdateSuccess")
+			failed = true
+		} else if !failed && emailChange.NewConfirmed == 0 {
+			session.AddFlash("Current email address confirmed.  Follow "+
+				"the link sent to your new email address to complete "+
+				"the change", "emailupdateSuccess")
+			failed = true
+		}
+
 		// possible that someone signed up with this email in the time between
 		// when the token was generated and now.
 		if !failed {
@@ -1278,18 +1563,39 @@ func (controller *MainController) EmailUpdate(c web.C, r *http.Request) (string,
 			}
 		}
 
+		var user *models.User
 		if !failed {
-			err := helpers.EmailChangeComplete(dbMap, token)
+			user, err = models.GetUserById(dbMap, emailChange.UserId)
+			if err != nil {
+				session.AddFlash("Error occurred while changing email address",
+					"emailupdateError")
+				log.Errorf("GetUserById failed %v", err)
+				failed = true
+			}
+		}
+
+		if !failed {
+			err := helpers.EmailChangeComplete(dbMap, emailChange)
 			if err != nil {
 				session.AddFlash("Error occurred while changing email address",
 					"emailupdateError")
 				log.Errorf("EmailChangeComplete failed %v", err)
 			} else {
+				controller.auditAs(dbMap, r, user.Id,
+					AuditActionEmailChange,
+					strconv.FormatInt(user.Id, 10), user.Email,
+					emailChange.NewEmail)
 				// Logout the user to force them to sign in with their new
 				// email address
 				session.Values["UserId"] = nil
+				err = models.DeleteUserRememberTokens(dbMap, user.Id)
+				if err != nil {
+					log.Errorf("DeleteUserRememberTokens failed: %v", err)
+				}
+				controller.setRememberMe(c, "")
 				session.AddFlash("Email successfully updated",
 					"emailupdateSuccess")
+				c.Env["EmailUpdated"] = true
 			}
 		}
 	} else {
@@ -1404,8 +1710,8 @@ func (controller *MainController) PasswordReset(c web.C, r *http.Request) (strin
 	c.Env["FlashError"] = session.Flashes("passwordresetError")
 	c.Env["FlashSuccess"] = session.Flashes("passwordresetSuccess")
 	c.Env["IsPasswordReset"] = true
-	c.Env["RecaptchaSiteKey"] = controller.recaptchaSiteKey
-	if controller.smtpHost == "" {
+	c.Env["Captcha"] = controller.captcha
+	if controller.mailer == nil {
 		c.Env["SMTPDisabled"] = true
 	}
 
@@ -1422,18 +1728,13 @@ func (controller *MainController) PasswordResetPost(c web.C, r *http.Request) (s
 	session := controller.GetSession(c)
 	dbMap := controller.GetDbMap(c)
 
-	re := recaptcha.R{
-		Secret: controller.recaptchaSecret,
-	}
-
-	isValid := re.Verify(*r)
-	if !isValid {
-		log.Errorf("Recaptcha error %v", re.LastError())
-		session.AddFlash("Recaptcha error", "passwordresetError")
+	remoteIP := getClientIP(r, controller.realIPHeader)
+	if err := controller.captcha.Verify(r, remoteIP); err != nil {
+		log.Errorf("Captcha error %v", err)
+		session.AddFlash("Captcha error", "passwordresetError")
 		return controller.PasswordReset(c, r)
 	}
 
-	remoteIP := getClientIP(r, controller.realIPHeader)
 	user, err := helpers.EmailExists(dbMap, email)
 	if err == nil {
 		log.Infof("PasswordReset POST from %v, email %v", remoteIP,
@@ -1456,15 +1757,10 @@ func (controller *MainController) PasswordResetPost(c web.C, r *http.Request) (s
 			return controller.PasswordReset(c, r)
 		}
 
-		body := "A request to reset your password was made from IP address: " +
-			remoteIP + "\r\n\n" +
-			"If you made this request, follow the link below:\r\n\n" +
-			controller.baseURL + "/passwordupdate?t=" + token + "\r\n\n" +
-			"The above link expires an hour after this email was sent.\r\n\n" +
-			"If you did not make this request, you may safely ignore this " +
-			"email.\r\n" + "However, you may want to look into how this " +
-			"happened.\r\n"
-		err := controller.SendMailUsingTLS(user.Email, "Stake pool password reset", body)
+		data := controller.emailData(controller.emailLanguage(dbMap, c, user.Id))
+		data["RemoteIP"] = remoteIP
+		data["Token"] = token
+		err := controller.sendEmail(user.Email, emailPasswordReset, data)
 		if err != nil {
 			session.AddFlash("Unable to send password reset email", "passwordresetError")
 			log.Errorf("error sending password reset email %v", err)
@@ -1578,6 +1874,14 @@ func (controller *MainController) PasswordUpdatePost(c web.C, r *http.Request) (
 	if err != nil {
 		log.Errorf("error deleting token %v", err)
 	}
+	controller.recordSecurityEvent(dbMap, r, user.Id,
+		models.SecurityEventPassword, "password reset", time.Now())
+
+	// Devices remembered with the old password must sign in again.
+	err = models.DeleteUserRememberTokens(dbMap, user.Id)
+	if err != nil {
+		log.Errorf("DeleteUserRememberTokens failed: %v", err)
+	}
 
 	session.AddFlash("Password successfully updated", "passwordupdateSuccess")
 	return controller.PasswordUpdate(c, r)
@@ -1609,18 +1913,38 @@ func (controller *MainController) Settings(c web.C, r *http.Request) (string, in
 
 	t := controller.GetTemplate(c)
 
+	prefs := controller.getPreferences(dbMap, user.Id)
+
 	c.Env["Admin"], _ = controller.isAdmin(c, r)
 	c.Env["APIToken"] = user.APIToken
+	c.Env["Currencies"] = supportedCurrencies
+	c.Env["Languages"] = controller.locales.Languages()
+	c.Env["Preferences"] = apiPreferences(prefs)
+	c.Env["EmailEvents"] = emailEventOptions(prefs)
 	c.Env["FlashError"] = session.Flashes("settingsError")
 	c.Env["FlashSuccess"] = session.Flashes("settingsSuccess")
 	c.Env["IsSettings"] = true
-	c.Env["RecaptchaSiteKey"] = controller.recaptchaSiteKey
+	c.Env["Captcha"] = controller.captcha
 	if user.MultiSigAddress == "" {
 		c.Env["ShowInstructions"] = true
 	}
-	if controller.smtpHost == "" {
+	if controller.mailer == nil {
 		c.Env["SMTPDisabled"] = true
 	}
+	c.Env["Telegram"] = controller.telegram != nil
+	c.Env["TelegramLinked"] = prefs.TelegramChatID != 0
+
+	devices, err := controller.rememberDevices(dbMap, r, prefs)
+	if err != nil {
+		log.Errorf("rememberDevices failed for userid %v: %v", user.Id, err)
+	}
+	c.Env["Devices"] = devices
+
+	events, err := controller.securityEvents(dbMap, prefs)
+	if err != nil {
+		log.Errorf("securityEvents failed for userid %v: %v", user.Id, err)
+	}
+	c.Env["SecurityEvents"] = events
 
 	widgets := controller.Parse(t, "settings", c.Env)
 	c.Env["Title"] = "Hcd Stake Pool - Settings"
@@ -1629,7 +1953,8 @@ func (controller *MainController) Settings(c web.C, r *http.Request) (string, in
 	return controller.Parse(t, "main", c.Env), http.StatusOK
 }
 
-// SettingsPost handles changing the user's email address or password.
+// SettingsPost handles changing the user's email address, password or
+// preferences, and deleting their account.
 func (controller *MainController) SettingsPost(c web.C, r *http.Request) (string, int) {
 	session := controller.GetSession(c)
 	dbMap := controller.GetDbMap(c)
@@ -1639,6 +1964,62 @@ func (controller *MainController) SettingsPost(c web.C, r *http.Request) (string
 		return "/", http.StatusSeeOther
 	}
 
+	if r.FormValue("updatePreferences") == "true" {
+		_, err := controller.updatePreferences(dbMap,
+			session.Values["UserId"].(int64), r)
+		if err != nil {
+			session.AddFlash(err.Error(), "settingsError")
+		} else {
+			session.AddFlash("Preferences successfully updated",
+				"settingsSuccess")
+		}
+		return controller.Settings(c, r)
+	}
+
+	switch {
+	case controller.telegram == nil:
+	case r.FormValue("updateTelegram") == "link":
+		link, err := controller.telegramLink(dbMap,
+			session.Values["UserId"].(int64))
+		if err != nil {
+			log.Errorf("Unable to link Telegram for userid %v: %v",
+				session.Values["UserId"], err)
+			session.AddFlash("Unable to link Telegram, please try again "+
+				"later", "settingsError")
+			return controller.Settings(c, r)
+		}
+		return link, http.StatusSeeOther
+	case r.FormValue("updateTelegram") == "unlink":
+		p := controller.getPreferences(dbMap,
+			session.Values["UserId"].(int64))
+		p.TelegramChatID = 0
+		if err := models.SetUserPreferences(dbMap, p.UserPreferences); err != nil {
+			log.Errorf("Unable to unlink Telegram for userid %v: %v",
+				p.UserId, err)
+			session.AddFlash("Unable to unlink Telegram", "settingsError")
+		} else {
+			session.AddFlash("Telegram notifications stopped",
+				"settingsSuccess")
+		}
+		return controller.Settings(c, r)
+	}
+
+	if id := r.FormValue("revokeDevice"); id != "" {
+		found, err := revokeDevice(dbMap, session.Values["UserId"].(int64),
+			id)
+		switch {
+		case err != nil:
+			log.Errorf("Unable to forget device for userid %v: %v",
+				session.Values["UserId"], err)
+			session.AddFlash("Unable to forget device", "settingsError")
+		case !found:
+			session.AddFlash("Device not found", "settingsError")
+		default:
+			session.AddFlash("Device forgotten", "settingsSuccess")
+		}
+		return controller.Settings(c, r)
+	}
+
 	password, updateEmail, updatePassword := r.FormValue("password"),
 		r.FormValue("updateEmail"), r.FormValue("updatePassword")
 
@@ -1654,14 +2035,9 @@ func (controller *MainController) SettingsPost(c web.C, r *http.Request) (string
 		newEmail := r.FormValue("email")
 		log.Infof("user requested email change from %v to %v", user.Email, newEmail)
 
-		re := recaptcha.R{
-			Secret: controller.recaptchaSecret,
-		}
-
-		isValid := re.Verify(*r)
-		if !isValid {
-			session.AddFlash("Recaptcha error", "settingsError")
-			log.Errorf("Recaptcha error %v", re.LastError())
+		if err := controller.captcha.Verify(r, remoteIP); err != nil {
+			session.AddFlash("Captcha error", "settingsError")
+			log.Errorf("Captcha error %v", err)
 			return controller.Settings(c, r)
 		}
 
@@ -1675,13 +2051,14 @@ func (controller *MainController) SettingsPost(c web.C, r *http.Request) (string
 		t := time.Now()
 		expires := t.Add(time.Hour * 1)
 
-		token := randToken()
+		token, oldToken := randToken(), randToken()
 		emailChange := &models.EmailChange{
 			UserId:   user.Id,
 			NewEmail: newEmail,
 			Token:    token,
 			Created:  t.Unix(),
 			Expires:  expires.Unix(),
+			OldToken: oldToken,
 		}
 
 		if err := models.InsertEmailChange(dbMap, emailChange); err != nil {
@@ -1690,40 +2067,36 @@ func (controller *MainController) SettingsPost(c web.C, r *http.Request) (string
 			return controller.Settings(c, r)
 		}
 
-		bodyNew := "A request was made to change the email address\r\n" +
-			"for a stake pool account at " + controller.baseURL + "\r\n" +
-			"from " + user.Email + " to " + newEmail + "\r\n\n" +
-			"The request was made from IP address " + remoteIP + "\r\n\n" +
-			"If you made this request, follow the link below:\r\n\n" +
-			controller.baseURL + "/emailupdate?t=" + token + "\r\n\n" +
-			"The above link expires an hour after this email was sent.\r\n\n" +
-			"If you did not make this request, you may safely ignore this " +
-			"email.\r\n" + "However, you may want to look into how this " +
-			"happened.\r\n"
-		err = controller.SendMailUsingTLS(newEmail, "Stake pool email change", bodyNew)
+		lang := controller.emailLanguage(dbMap, c, user.Id)
+		data := controller.emailData(lang)
+		data["OldEmail"] = user.Email
+		data["NewEmail"] = newEmail
+		data["RemoteIP"] = remoteIP
+		data["Token"] = token
+		err = controller.sendEmail(newEmail, emailChangeNew, data)
 		if err != nil {
 			session.AddFlash("Unable to send email change token.",
 				"settingsError")
 			log.Errorf("error sending email change token to new address %v %v",
 				newEmail, err)
-		} else {
-			session.AddFlash("Verification token sent to new email address",
-				"settingsSuccess")
+			return controller.Settings(c, r)
 		}
 
-		bodyOld := "A request was made to change the email address\r\n" +
-			"for your stake pool account at " + controller.baseURL + "\r\n" +
-			"from " + user.Email + " to " + newEmail + "\r\n\n" +
-			"The request was made from IP address " + remoteIP + "\r\n\n" +
-			"If you did not make this request, please contact the \r\n" +
-			"stake pool administrator immediately.\r\n"
-		err = controller.SendMailUsingTLS(user.Email, "Stake pool email change",
-			bodyOld)
-		// this likely has the same status as the above email so don't
-		// inform the user.
+		data = controller.emailData(lang)
+		data["OldEmail"] = user.Email
+		data["NewEmail"] = newEmail
+		data["RemoteIP"] = remoteIP
+		data["Token"] = oldToken
+		err = controller.sendEmail(user.Email, emailChangeOld, data)
 		if err != nil {
+			session.AddFlash("Unable to send email change token.",
+				"settingsError")
 			log.Errorf("error sending email change token to old address %v %v",
 				user.Email, err)
+		} else {
+			session.AddFlash("Verification tokens sent to the current and "+
+				"new email addresses.  Follow both links to complete the "+
+				"change", "settingsSuccess")
 		}
 	} else if updatePassword == "true" {
 		newPassword, newPasswordRepeat := r.FormValue("newpassword"),
@@ -1741,20 +2114,41 @@ func (controller *MainController) SettingsPost(c web.C, r *http.Request) (string
 			session.AddFlash("Unable to update password", "settingsError")
 			return controller.Settings(c, r)
 		}
+		controller.recordSecurityEvent(dbMap, r, user.Id,
+			models.SecurityEventPassword, "settings", time.Now())
+
+		// Other devices remembered with the old password must sign in
+		// again.
+		err = models.DeleteUserRememberTokens(dbMap, user.Id)
+		if err != nil {
+			log.Errorf("DeleteUserRememberTokens failed: %v", err)
+		}
+		controller.setRememberMe(c, "")
 
 		// send a confirmation email.
-		body := "Your stake pool password for " + controller.baseURL + "\r\n" +
-			"was just changed by IP Address " + remoteIP + "\r\n\n" +
-			"If you did not make this request, please contact the \r\n" +
-			"stake pool administrator immediately.\r\n"
-		err = controller.SendMailUsingTLS(user.Email, "Stake pool password change",
-			body)
+		data := controller.emailData(controller.emailLanguage(dbMap, c, user.Id))
+		data["RemoteIP"] = remoteIP
+		err = controller.sendEmail(user.Email, emailPasswordChange, data)
 		if err != nil {
 			log.Errorf("error sending password change confirmation %v %v",
 				user.Email, err)
 		}
 
 		session.AddFlash("Password successfully updated", "settingsSuccess")
+	} else if r.FormValue("deleteAccount") == "true" {
+		if r.FormValue("confirmDelete") == "" {
+			session.AddFlash("Confirm that you want to delete your account",
+				"settingsError")
+			return controller.Settings(c, r)
+		}
+		if err := controller.deleteAccount(dbMap, c, user); err != nil {
+			log.Errorf("Unable to delete account of userid %v: %v", user.Id,
+				err)
+			session.AddFlash("Unable to delete your account, please try "+
+				"again later", "settingsError")
+			return controller.Settings(c, r)
+		}
+		return "/", http.StatusSeeOther
 	}
 
 	return controller.Settings(c, r)
@@ -1786,22 +2180,66 @@ func (controller *MainController) SignInPost(c web.C, r *http.Request) (string,
 	dbMap := controller.GetDbMap(c)
 	remoteIP := getClientIP(r, controller.realIPHeader)
 
+	// Refuse sign ins while too many recent ones for the email address or
+	// from the IP address failed.
+	now := time.Now()
+	wait, err := loginThrottled(dbMap, email, remoteIP, now)
+	if err != nil {
+		log.Errorf("loginThrottled failed: %v", err)
+		session.AddFlash("Unable to sign in, please try again later", "auth")
+		return controller.SignIn(c, r)
+	}
+	if wait > 0 {
+		log.Infof(email+" login throttled for %v, %v", wait, remoteIP)
+		session.AddFlash(loginWaitMessage(wait), "auth")
+		return controller.SignIn(c, r)
+	}
+
 	// Validate email and password combination.
 	user, err := helpers.Login(dbMap, email, password)
 	if err != nil {
 		log.Infof(email+" login failed %v, %v", err, remoteIP)
-		session.AddFlash("Invalid Email or Password", "auth")
+		recordLoginFailure(dbMap, email, remoteIP, now)
+		session.AddFlash(controller.locales.T(language(c),
+			"Invalid Email or Password"), "auth")
 		return controller.SignIn(c, r)
 	}
 
+	// Failures from the IP address are still counted so one account can't
+	// be used to reset them.
+	err = models.DeleteLoginAttempt(dbMap, models.LoginAttemptEmail,
+		strings.ToLower(email))
+	if err != nil {
+		log.Errorf("unable to clear failed sign ins for %v: %v", email, err)
+	}
+
 	log.Infof("SignIn POST from %v, email %v", remoteIP, user.Email)
 
 	if user.EmailVerified == 0 {
-		session.AddFlash("You must validate your email address", "auth")
+		session.AddFlash(controller.locales.T(language(c),
+			"You must validate your email address"), "auth")
 		return controller.SignIn(c, r)
 	}
 
 	session.Values["UserId"] = user.Id
+	delete(session.Values, rememberSelectorKey)
+	controller.recordSecurityEvent(dbMap, r, user.Id,
+		models.SecurityEventLogin, "password", now)
+
+	if r.FormValue("remember") != "" {
+		value, err := helpers.NewRememberToken(dbMap, user.Id,
+			r.UserAgent(), remoteIP, now)
+		if err != nil {
+			log.Errorf("NewRememberToken failed for userid %v: %v", user.Id,
+				err)
+		} else {
+			controller.setRememberMe(c, value)
+			session.Values[rememberSelectorKey] =
+				helpers.RememberTokenSelector(value)
+		}
+	}
+
+	controller.notifyNewLogin(dbMap, user, remoteIP, now)
 
 	// Go to Settings page if multisig script not yet set up.
 	// GUI users can copy and paste their API Token from here
@@ -1821,17 +2259,26 @@ func (controller *MainController) SignUp(c web.C, r *http.Request) (string, int)
 
 	// Tell main.html what route is being rendered
 	c.Env["IsSignUp"] = true
-	if controller.smtpHost == "" {
+	if controller.mailer == nil {
 		c.Env["SMTPDisabled"] = true
 	}
 	if controller.closePool {
 		c.Env["IsClosed"] = true
 		c.Env["ClosePoolMsg"] = controller.closePoolMsg
+	} else if controller.inMaintenance() {
+		c.Env["IsClosed"] = true
+		c.Env["ClosePoolMsg"] = "Sign ups are disabled while the pool is " +
+			"undergoing maintenance."
+	}
+	if controller.inviteSignup {
+		// Invite links fill in the code.
+		c.Env["InviteSignup"] = true
+		c.Env["InviteCode"] = normalizeInviteCode(r.FormValue("invite"))
 	}
 
 	c.Env["FlashError"] = session.Flashes("signupError")
 	c.Env["FlashSuccess"] = session.Flashes("signupSuccess")
-	c.Env["RecaptchaSiteKey"] = controller.recaptchaSiteKey
+	c.Env["Captcha"] = controller.captcha
 
 	widgets := controller.Parse(t, "auth/signup", c.Env)
 
@@ -1849,11 +2296,11 @@ func (controller *MainController) SignUpPost(c web.C, r *http.Request) (string,
 		return "/error?r=/signup", http.StatusSeeOther
 	}
 
-	re := recaptcha.R{
-		Secret: controller.recaptchaSecret,
-	}
-
 	session := controller.GetSession(c)
+	if controller.inMaintenance() {
+		session.AddFlash(errMaintenance.Error(), "signupError")
+		return controller.SignUp(c, r)
+	}
 	remoteIP := getClientIP(r, controller.realIPHeader)
 
 	email, password, passwordRepeat := r.FormValue("email"),
@@ -1874,10 +2321,14 @@ func (controller *MainController) SignUpPost(c web.C, r *http.Request) (string,
 		return controller.SignUp(c, r)
 	}
 
-	isValid := re.Verify(*r)
-	if !isValid {
-		session.AddFlash("Recaptcha error", "signupError")
-		log.Errorf("Recaptcha error %v", re.LastError())
+	if controller.termsVersion != "" && r.FormValue("terms") == "" {
+		session.AddFlash("you must accept the terms of service", "signupError")
+		return controller.SignUp(c, r)
+	}
+
+	if err := controller.captcha.Verify(r, remoteIP); err != nil {
+		session.AddFlash("Captcha error", "signupError")
+		log.Errorf("Captcha error %v", err)
 		return controller.SignUp(c, r)
 	}
 
@@ -1889,6 +2340,18 @@ func (controller *MainController) SignUpPost(c web.C, r *http.Request) (string,
 		return controller.SignUp(c, r)
 	}
 
+	// The invite code is claimed before the user is added so no one else
+	// can sign up with it meanwhile.
+	inviteCode := normalizeInviteCode(r.FormValue("invite"))
+	if controller.inviteSignup {
+		if err := claimInviteCode(dbMap, inviteCode, time.Now()); err != nil {
+			log.Infof("SignUp POST from %v, email %v with invite code %q: %v",
+				remoteIP, email, inviteCode, err)
+			session.AddFlash(err.Error(), "signupError")
+			return controller.SignUp(c, r)
+		}
+	}
+
 	token := randToken()
 	user = &models.User{
 		Username:        email,
@@ -1896,7 +2359,8 @@ func (controller *MainController) SignUpPost(c web.C, r *http.Request) (string,
 		EmailToken:      token,
 		EmailVerified:   0,
 		VoteBits:        1,
-		VoteBitsVersion: int64(controller.voteVersion),
+		VoteBitsVersion: int64(controller.currentVoteVersion()),
+		TermsVersion:    controller.termsVersion,
 	}
 	user.HashPassword(password)
 
@@ -1905,15 +2369,27 @@ func (controller *MainController) SignUpPost(c web.C, r *http.Request) (string,
 	if err := models.InsertUser(dbMap, user); err != nil {
 		session.AddFlash("Database error occurred while adding user", "signupError")
 		log.Errorf("Error while registering user: %v", err)
+		if controller.inviteSignup {
+			if err := models.ReleaseInviteCode(dbMap, inviteCode); err != nil {
+				log.Errorf("ReleaseInviteCode failed: %v", err)
+			}
+		}
 		return controller.SignUp(c, r)
 	}
+	if controller.inviteSignup {
+		if err := models.SetInviteCodeUser(dbMap, inviteCode, user.Id); err != nil {
+			log.Errorf("SetInviteCodeUser failed for userid %v: %v", user.Id, err)
+		}
+	}
+	if controller.termsVersion != "" {
+		controller.auditAs(dbMap, r, user.Id, AuditActionTermsAccept,
+			controller.termsVersion, nil, controller.termsVersion)
+	}
 
-	body := signupEmailTemplate
-	body = strings.Replace(body, "__URL__", controller.baseURL, -1)
-	body = strings.Replace(body, "__REMOTEIP__", remoteIP, -1)
-	body = strings.Replace(body, "__TOKEN__", token, -1)
-
-	err := controller.SendMailUsingTLS(user.Email, signupEmailSubject, body)
+	data := controller.emailData(language(c))
+	data["RemoteIP"] = remoteIP
+	data["Token"] = token
+	err := controller.sendEmail(user.Email, emailSignup, data)
 	if err != nil {
 		session.AddFlash("Unable to send signup email", "signupError")
 		log.Errorf("error sending verification email %v", err)
@@ -1945,18 +2421,50 @@ func (controller *MainController) Stats(c web.C, r *http.Request) (string, int)
 		return "/error?r=/stats", http.StatusSeeOther
 	}
 
+	for i, conn := range controller.stakepooldConnections() {
+		poolStats, err := stakepooldclient.StakepooldGetPoolStats(conn)
+		if err != nil {
+			log.Warnf("stakepoold host %d GetPoolStats failed: %v", i, err)
+			continue
+		}
+		c.Env["PoolStats"] = poolStats
+		break
+	}
+
 	c.Env["Network"] = controller.params.Name
 	if controller.closePool {
 		c.Env["PoolStatus"] = "Closed"
+	} else if controller.inviteSignup {
+		c.Env["PoolStatus"] = "Invite Only"
 	} else {
 		c.Env["PoolStatus"] = "Open"
 	}
 	c.Env["PoolEmail"] = controller.poolEmail
 	c.Env["PoolFees"] = controller.poolFees
 	c.Env["StakeInfo"] = gsi
+	currency := controller.requestCurrency(c, r)
+	c.Env["TotalSubsidyFiat"] = controller.fiatValue(gsi.TotalSubsidy, currency)
 	c.Env["UserCount"] = userCount
 	c.Env["UserCountActive"] = userCountActive
 
+	poolGrowth, err := controller.PoolGrowth(dbMap)
+	if err != nil {
+		log.Warnf("PoolGrowth failed: %v", err)
+	}
+	c.Env["PoolGrowth"] = poolGrowth
+
+	if amount, err := parseRewardAmount(r); err != nil {
+		c.Env["RewardEstimateError"] = err.Error()
+	} else if estimate, err := controller.RewardEstimate(dbMap, amount); err != nil {
+		log.Warnf("RewardEstimate failed: %v", err)
+	} else {
+		c.Env["RewardEstimate"] = estimate
+		c.Env["UserRewardFiat"] = controller.fiatValue(
+			estimate.UserReward.ToCoin(), currency)
+		c.Env["TotalRewardFiat"] = controller.fiatValue(
+			estimate.TotalReward.ToCoin(), currency)
+	}
+
 	widgets := controller.Parse(t, "stats", c.Env)
 	c.Env["Content"] = template.HTML(widgets)
 
@@ -2049,15 +2557,26 @@ func (controller *MainController) Tickets(c web.C, r *http.Request) (string, int
 		return "/error", http.StatusSeeOther
 	}
 
+	// The tickets of an additional voting address are shown when one is
+	// chosen.
+	address, err := userAddressParam(dbMap, r, user.Id)
+	if err != nil {
+		session.AddFlash(err.Error(), "tickets")
+		return "/tickets", http.StatusSeeOther
+	}
+	ticketAddress, ticketScript := user.MultiSigAddress, user.MultiSigScript
+	if address != nil {
+		ticketAddress, ticketScript = address.MultiSigAddress, address.MultiSigScript
+	}
+
 	// Get P2SH Address
-	multisig, err := hcutil.DecodeAddress(user.MultiSigAddress)
+	multisig, err := hcutil.DecodeAddress(ticketAddress)
 	if err != nil {
-		log.Infof("Invalid address %v in database: %v", user.MultiSigAddress, err)
+		log.Infof("Invalid address %v in database: %v", ticketAddress, err)
 		return "/error", http.StatusSeeOther
 	}
 
-	log.Infof("Tickets GET from %v, multisig %v", remoteIP,
-		user.MultiSigAddress)
+	log.Infof("Tickets GET from %v, multisig %v", remoteIP, ticketAddress)
 
 	w := controller.rpcServers
 
@@ -2073,7 +2592,7 @@ func (controller *MainController) Tickets(c web.C, r *http.Request) (string, int
 	}
 
 	log.Debugf(":: StakePoolUserInfo (msa = %v) execution time: %v",
-		user.MultiSigAddress, time.Since(start))
+		ticketAddress, time.Since(start))
 
 	// Compute the oldest (min) ticket spend height to include in the table
 	_, height, err := w.GetBestBlock()
@@ -2085,6 +2604,15 @@ func (controller *MainController) Tickets(c web.C, r *http.Request) (string, int
 	}
 	minVotedHeight := height - controller.maxVotedAge
 
+	// The ticket price is only informational, so the page is shown without
+	// it when hcd can't be asked.
+	priceInfo, err := w.TicketPriceInfo()
+	if err != nil {
+		log.Warnf("RPC TicketPriceInfo failed: %v", err)
+	} else {
+		c.Env["TicketPrice"] = controller.ticketPrice(priceInfo, height)
+	}
+
 	// If the user has tickets, get their info
 	if spui != nil && len(spui.Tickets) > 0 {
 		for _, ticket := range spui.Tickets {
@@ -2135,6 +2663,22 @@ func (controller *MainController) Tickets(c web.C, r *http.Request) (string, int
 	sort.Sort(BySpentByHeight(ticketInfoVoted))
 	sort.Sort(BySpentByHeight(ticketInfoMissed))
 
+	for i, conn := range controller.stakepooldConnections() {
+		votingStats, err := stakepooldclient.StakepooldGetUserVotingStats(
+			conn, ticketAddress)
+		if err != nil {
+			log.Warnf("stakepoold host %d GetUserVotingStats failed: %v", i, err)
+			continue
+		}
+		c.Env["VotingStats"] = votingStats
+		currency := controller.requestCurrency(c, r)
+		c.Env["AverageRewardFiat"] = controller.fiatValue(
+			votingStats.AverageReward.ToCoin(), currency)
+		c.Env["TotalRewardFiat"] = controller.fiatValue(
+			votingStats.TotalReward.ToCoin(), currency)
+		break
+	}
+
 	c.Env["Admin"], _ = controller.isAdmin(c, r)
 	c.Env["TicketsInvalid"] = ticketInfoInvalid
 	c.Env["TicketsLive"] = ticketInfoLive
@@ -2143,6 +2687,11 @@ func (controller *MainController) Tickets(c web.C, r *http.Request) (string, int
 	c.Env["TicketsVotedCount"] = numVoted
 	c.Env["TicketsVotedArchivedCount"] = numVoted - len(ticketInfoVoted)
 	c.Env["TicketsVoted"] = ticketInfoVoted
+	c.Env["TicketsExpiring"] = controller.expiringTickets(ticketInfoLive, height)
+	c.Env["TimeLocation"] = controller.getPreferences(dbMap, user.Id).location
+	c.Env["TicketAddress"] = ticketAddress
+	c.Env["TicketScript"] = ticketScript
+	c.Env["VotingAddresses"] = votingAddressChoices(dbMap, user, address)
 	widgets := controller.Parse(t, "tickets", c.Env)
 
 	c.Env["Content"] = template.HTML(widgets)
@@ -2151,7 +2700,8 @@ func (controller *MainController) Tickets(c web.C, r *http.Request) (string, int
 	return controller.Parse(t, "main", c.Env), http.StatusOK
 }
 
-// Voting renders the voting page.
+// Voting renders the voting page.  The agendas of the current vote version
+// and the progress of their vote come from hcd.
 func (controller *MainController) Voting(c web.C, r *http.Request) (string, int) {
 	session := controller.GetSession(c)
 	dbMap := controller.GetDbMap(c)
@@ -2164,18 +2714,40 @@ func (controller *MainController) Voting(c web.C, r *http.Request) (string, int)
 
 	t := controller.GetTemplate(c)
 
-	choicesSelected := controller.choicesForAgendas(uint16(user.VoteBits))
+	// The preferences of an additional voting address are shown when one is
+	// chosen.
+	address, err := userAddressParam(dbMap, r, user.Id)
+	if err != nil {
+		session.AddFlash(err.Error(), "votingError")
+		return "/voting", http.StatusSeeOther
+	}
+	voteBits, reconfirm := uint16(user.VoteBits), user.VoteBitsReconfirm != 0
+	if address != nil {
+		voteBits, reconfirm = uint16(address.VoteBits), address.VoteBitsReconfirm != 0
+		c.Env["Address"] = address.Id
+	}
 
-	for k, v := range choicesSelected {
-		strk := strconv.Itoa(k)
-		c.Env["Agenda"+strk+"Selected"] = v
+	voteVersion := controller.currentVoteVersion()
+	flashError := session.Flashes("votingError")
+	vi, err := controller.voteInfo(voteVersion)
+	if err != nil {
+		log.Errorf("unable to get the agendas of vote version %v: %v",
+			voteVersion, err)
+		flashError = append(flashError,
+			"Unable to retrieve the agendas from hcd, please try again later")
+		c.Env["AgendasUnavailable"] = true
+	} else {
+		c.Env["Agendas"] = votingAgendas(vi, voteBits)
 	}
+
 	c.Env["Admin"], _ = controller.isAdmin(c, r)
-	c.Env["Agendas"] = controller.getAgendas()
-	c.Env["FlashError"] = session.Flashes("votingError")
+	c.Env["FlashError"] = flashError
 	c.Env["FlashSuccess"] = session.Flashes("votingSuccess")
 	c.Env["IsVoting"] = true
-	c.Env["VoteVersion"] = controller.voteVersion
+	c.Env["VoteBitsReconfirm"] = reconfirm
+	c.Env["VoteVersion"] = voteVersion
+	c.Env["VotingAddresses"] = votingAddressChoices(dbMap, user, address)
+	c.Env["VotingSuspended"] = user.VotingSuspended != 0
 
 	widgets := controller.Parse(t, "voting", c.Env)
 	c.Env["Title"] = "Hcd Stake Pool - Voting"
@@ -2184,7 +2756,9 @@ func (controller *MainController) Voting(c web.C, r *http.Request) (string, int)
 	return controller.Parse(t, "main", c.Env), http.StatusOK
 }
 
-// VotingPost form submit route.
+// VotingPost form submit route.  The choices are checked against the agendas
+// hcd reports for the vote version the form was rendered for, which must
+// still be the current one.
 func (controller *MainController) VotingPost(c web.C, r *http.Request) (string, int) {
 	session := controller.GetSession(c)
 	dbMap := controller.GetDbMap(c)
@@ -2193,46 +2767,96 @@ func (controller *MainController) VotingPost(c web.C, r *http.Request) (string,
 		return "/", http.StatusSeeOther
 	}
 
-	var generatedVoteBits uint16
-
 	user, _ := models.GetUserById(dbMap, session.Values["UserId"].(int64))
+	if user.VotingSuspended != 0 {
+		session.AddFlash(errVotingSuspended.Error(), "votingError")
+		return "/voting", http.StatusSeeOther
+	}
+	if controller.inMaintenance() {
+		session.AddFlash(errMaintenance.Error(), "votingError")
+		return "/voting", http.StatusSeeOther
+	}
 
-	// last block valid
-	generatedVoteBits |= 1
+	address, err := userAddressParam(dbMap, r, user.Id)
+	if err != nil {
+		session.AddFlash(err.Error(), "votingError")
+		return "/voting", http.StatusSeeOther
+	}
+	redirect := "/voting"
+	if address != nil {
+		redirect = fmt.Sprintf("/voting?address=%d", address.Id)
+	}
 
-	deployments := controller.getAgendas()
+	voteVersion := controller.currentVoteVersion()
+	if r.FormValue("VoteVersion") != strconv.FormatUint(uint64(voteVersion), 10) {
+		session.AddFlash("the vote version changed, please review your "+
+			"choices on its agendas", "votingError")
+		return redirect, http.StatusSeeOther
+	}
 
-	for i := range deployments {
-		agendaVal := r.FormValue("agenda" + strconv.Itoa(i))
-		avi, err := strconv.Atoi(agendaVal)
-		if err != nil {
-			session.AddFlash("invalid agenda choice", "votingError")
-			return "/voting", http.StatusSeeOther
+	vi, err := controller.voteInfo(voteVersion)
+	if err != nil {
+		log.Errorf("unable to get the agendas of vote version %v: %v",
+			voteVersion, err)
+		session.AddFlash("unable to retrieve the agendas from hcd", "votingError")
+		return redirect, http.StatusSeeOther
+	}
+
+	choices := make(map[string]string)
+	for key, values := range r.PostForm {
+		if strings.HasPrefix(key, "agenda_") && len(values) > 0 {
+			choices[strings.TrimPrefix(key, "agenda_")] = values[0]
 		}
-		generatedVoteBits |= uint16(avi)
+	}
+	generatedVoteBits, err := voteBitsForChoices(vi, choices)
+	if err != nil {
+		session.AddFlash(err.Error(), "votingError")
+		return redirect, http.StatusSeeOther
 	}
 
 	isValid := controller.IsValidVoteBits(generatedVoteBits)
 	if !isValid {
 		session.AddFlash("generated votebits were invalid", "votingError")
-		return "/voting", http.StatusSeeOther
+		return redirect, http.StatusSeeOther
+	}
+
+	if address != nil {
+		oldVoteBits := address.VoteBits
+		err = models.SetUserAddressVoteBits(dbMap, address.Id,
+			generatedVoteBits, voteVersion, false)
+		if err != nil {
+			session.AddFlash("unable to save new voting preferences", "votingError")
+			return redirect, http.StatusSeeOther
+		}
+		log.Infof("updated voteBits for address %v of user %d from %d to %d",
+			address.MultiSigAddress, user.Id, oldVoteBits, generatedVoteBits)
+		if uint16(oldVoteBits) != generatedVoteBits {
+			address.VoteBits = int64(generatedVoteBits)
+			address.VoteBitsVersion = int64(voteVersion)
+			user.Addresses = []models.UserAddress{*address}
+			controller.StakepooldUpdateUsers(r.Context(), dbMap,
+				map[int64]*models.User{user.Id: user})
+		}
+		session.AddFlash("successfully updated voting preferences", "votingSuccess")
+		return redirect, http.StatusSeeOther
 	}
 
 	oldVoteBits := user.VoteBits
-	user, err := helpers.UpdateVoteBitsByID(dbMap, user.Id, generatedVoteBits)
+	user, err = helpers.UpdateVoteBitsByID(dbMap, user.Id, generatedVoteBits)
 	if err != nil {
 		session.AddFlash("unable to save new voting preferences", "votingError")
-		return "/voting", http.StatusSeeOther
+		return redirect, http.StatusSeeOther
 	}
 
 	log.Infof("updated voteBits for user %d from %d to %d",
 		user.Id, oldVoteBits, generatedVoteBits)
 	if uint16(oldVoteBits) != generatedVoteBits {
-		controller.StakepooldUpdateAll(dbMap, StakepooldUpdateKindUsers)
+		controller.StakepooldUpdateUsers(r.Context(), dbMap,
+			map[int64]*models.User{user.Id: user})
 	}
 
 	session.AddFlash("successfully updated voting preferences", "votingSuccess")
-	return "/voting", http.StatusSeeOther
+	return redirect, http.StatusSeeOther
 }
 
 // Logout the user.
@@ -2240,31 +2864,18 @@ func (controller *MainController) Logout(c web.C, r *http.Request) (string, int)
 	session := controller.GetSession(c)
 
 	session.Values["UserId"] = nil
+	delete(session.Values, rememberSelectorKey)
 
-	return "/", http.StatusSeeOther
-}
-
-func (controller *MainController) choicesForAgendas(userVoteBits uint16) map[int]uint16 {
-	choicesSelected := make(map[int]uint16)
-
-	deployments := controller.getAgendas()
-
-	for i := range deployments {
-		d := &deployments[i]
-		masked := userVoteBits & d.Vote.Mask
-		var valid bool
-		for choice := range d.Vote.Choices {
-			if masked == d.Vote.Choices[choice].Bits {
-				valid = true
-				choicesSelected[i] = d.Vote.Choices[choice].Bits
-			}
-		}
-		if !valid {
-			choicesSelected[i] = uint16(0)
+	if cookie, err := r.Cookie(system.RememberMeCookie); err == nil {
+		err = helpers.RevokeRememberToken(controller.GetDbMap(c),
+			cookie.Value)
+		if err != nil {
+			log.Errorf("RevokeRememberToken failed: %v", err)
 		}
+		controller.setRememberMe(c, "")
 	}
 
-	return choicesSelected
+	return "/", http.StatusSeeOther
 }
 
 func (controller *MainController) getAgendas() []chaincfg.ConsensusDeployment {
@@ -2272,7 +2883,7 @@ func (controller *MainController) getAgendas() []chaincfg.ConsensusDeployment {
 		return nil
 	}
 
-	return controller.params.Deployments[controller.voteVersion]
+	return controller.params.Deployments[controller.currentVoteVersion()]
 
 }
 
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/models"
 )
 
 func TestGetNetworkName(t *testing.T) {
@@ -26,3 +28,275 @@ func TestGetNetworkName(t *testing.T) {
 			netName)
 	}
 }
+
+func TestSimulateAgendaOutcomes(t *testing.T) {
+	deployments := []chaincfg.ConsensusDeployment{{
+		Vote: chaincfg.Vote{
+			Id:          "testagenda",
+			Description: "test agenda",
+			Mask:        0x0006,
+			Choices: []chaincfg.Choice{
+				{Id: "abstain", Bits: 0x0000, IsAbstain: true},
+				{Id: "no", Bits: 0x0002},
+				{Id: "yes", Bits: 0x0004},
+			},
+		},
+	}}
+
+	// Two tickets on the default preferences, one explicit no and one
+	// explicit yes.
+	voters := []agendaVoter{
+		{voteBits: defaultVoteBits, weight: 2},
+		{voteBits: 0x0003, weight: 1},
+		{voteBits: 0x0005, weight: 1},
+	}
+
+	outcomes := simulateAgendaOutcomes(deployments, voters,
+		map[int]uint16{0: 0x0004})
+	if len(outcomes) != 1 {
+		t.Fatalf("expected 1 outcome, got %d", len(outcomes))
+	}
+
+	tests := []struct {
+		current, simulated int
+	}{
+		{2, 0}, // abstain
+		{1, 1}, // no
+		{1, 3}, // yes
+	}
+	for i, test := range tests {
+		choice := outcomes[0].Choices[i]
+		if choice.Current != test.current {
+			t.Errorf("choice %s: expected %d current votes, got %d",
+				choice.ID, test.current, choice.Current)
+		}
+		if choice.Simulated != test.simulated {
+			t.Errorf("choice %s: expected %d simulated votes, got %d",
+				choice.ID, test.simulated, choice.Simulated)
+		}
+	}
+
+	if outcomes[0].CurrentLeader != "abstain" {
+		t.Errorf("expected current leader abstain, got %s",
+			outcomes[0].CurrentLeader)
+	}
+	if outcomes[0].SimulatedLeader != "yes" {
+		t.Errorf("expected simulated leader yes, got %s",
+			outcomes[0].SimulatedLeader)
+	}
+}
+
+func TestMigrateVoteBits(t *testing.T) {
+	vote := func(id string, mask uint16, bits ...uint16) chaincfg.ConsensusDeployment {
+		v := chaincfg.Vote{
+			Id:   id,
+			Mask: mask,
+			Choices: []chaincfg.Choice{
+				{Id: "abstain", Bits: 0, IsAbstain: true},
+			},
+		}
+		for i, b := range bits {
+			v.Choices = append(v.Choices, chaincfg.Choice{
+				Id:   string('a' + rune(i)),
+				Bits: b,
+			})
+		}
+		return chaincfg.ConsensusDeployment{Vote: v}
+	}
+
+	from := []chaincfg.ConsensusDeployment{
+		vote("kept", 0x0006, 0x0002, 0x0004),
+		vote("changed", 0x0018, 0x0008, 0x0010),
+		vote("abstained", 0x0060, 0x0020, 0x0040),
+		vote("dropped", 0x0180, 0x0080, 0x0100),
+	}
+	to := []chaincfg.ConsensusDeployment{
+		vote("kept", 0x0006, 0x0002, 0x0004),
+		vote("changed", 0x0018, 0x0010, 0x0008),
+		vote("abstained", 0x0060, 0x0040, 0x0020),
+		vote("new", 0x0180, 0x0080, 0x0100),
+	}
+
+	tests := []struct {
+		name      string
+		voteBits  uint16
+		migrated  uint16
+		conflicts int
+	}{
+		{"default", defaultVoteBits, defaultVoteBits, 0},
+		{"kept choice", 0x0005, 0x0005, 0},
+		{"changed agenda", 0x0009, defaultVoteBits, 1},
+		{"abstained on changed agenda", 0x0001, defaultVoteBits, 0},
+		{"dropped agenda", 0x0101, defaultVoteBits, 0},
+		{"mixed", 0x0005 | 0x0010 | 0x0080, 0x0005, 1},
+	}
+	for _, test := range tests {
+		migrated, conflicts := migrateVoteBits(test.voteBits, from, to)
+		if migrated != test.migrated {
+			t.Errorf("%s: expected votebits %#x, got %#x", test.name,
+				test.migrated, migrated)
+		}
+		if len(conflicts) != test.conflicts {
+			t.Errorf("%s: expected %d conflicts, got %v", test.name,
+				test.conflicts, conflicts)
+		}
+	}
+}
+
+func TestVotingAgendas(t *testing.T) {
+	vi := &dcrjson.GetVoteInfoResult{
+		VoteVersion: 5,
+		Agendas: []dcrjson.Agenda{{
+			Id:   "first",
+			Mask: 0x0006,
+			Choices: []dcrjson.Choice{
+				{Id: "abstain", Bits: 0x0000, IsAbstain: true},
+				{Id: "no", Bits: 0x0002, IsNo: true},
+				{Id: "yes", Bits: 0x0004, Progress: 0.5},
+			},
+		}, {
+			Id:   "second",
+			Mask: 0x0018,
+			Choices: []dcrjson.Choice{
+				{Id: "abstain", Bits: 0x0000, IsAbstain: true},
+				{Id: "no", Bits: 0x0008, IsNo: true},
+				{Id: "yes", Bits: 0x0010},
+			},
+		}},
+	}
+
+	// Bits that are no choice of the second agenda leave it on abstain.
+	agendas := votingAgendas(vi, 0x0005|0x0018)
+	if len(agendas) != 2 {
+		t.Fatalf("expected 2 agendas, got %d", len(agendas))
+	}
+	if agendas[0].Selected != "yes" || agendas[1].Selected != "abstain" {
+		t.Errorf("expected choices yes and abstain, got %s and %s",
+			agendas[0].Selected, agendas[1].Selected)
+	}
+	if agendas[0].Choices[2].ProgressPct != 50 {
+		t.Errorf("expected progress 50%%, got %v%%",
+			agendas[0].Choices[2].ProgressPct)
+	}
+
+	tests := []struct {
+		name     string
+		choices  map[string]string
+		voteBits uint16
+		valid    bool
+	}{
+		{"abstain", map[string]string{"first": "abstain", "second": "abstain"},
+			defaultVoteBits, true},
+		{"choices", map[string]string{"first": "yes", "second": "no"},
+			0x000d, true},
+		{"missing agenda", map[string]string{"first": "yes"}, 0, false},
+		{"unknown choice", map[string]string{"first": "yes", "second": "maybe"},
+			0, false},
+		{"unknown agenda", map[string]string{"first": "yes", "second": "no",
+			"third": "yes"}, 0, false},
+	}
+	for _, test := range tests {
+		voteBits, err := voteBitsForChoices(vi, test.choices)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %v, got error %v", test.name,
+				test.valid, err)
+			continue
+		}
+		if voteBits != test.voteBits {
+			t.Errorf("%s: expected votebits %#x, got %#x", test.name,
+				test.voteBits, voteBits)
+		}
+	}
+}
+
+func TestMergeStakeInfo(t *testing.T) {
+	if merged, _ := mergeStakeInfo([]*dcrjson.GetStakeInfoResult{nil}); merged != nil {
+		t.Errorf("expected no merged result without any wallet results")
+	}
+
+	infos := []*dcrjson.GetStakeInfoResult{
+		{BlockHeight: 100, PoolSize: 40, Live: 10, Voted: 3, Missed: 1},
+		nil,
+		{BlockHeight: 101, PoolSize: 50, Live: 8, Voted: 3, Missed: 1},
+	}
+	merged, disagreements := mergeStakeInfo(infos)
+	if merged.BlockHeight != 101 || merged.PoolSize != 50 {
+		t.Errorf("expected chain state of the best wallet, got height %d "+
+			"pool size %d", merged.BlockHeight, merged.PoolSize)
+	}
+	if merged.Live != 10 {
+		t.Errorf("expected 10 live tickets, got %d", merged.Live)
+	}
+	if merged.ProportionLive != 0.2 || merged.ProportionMissed != 0.25 {
+		t.Errorf("unexpected proportions live %v missed %v",
+			merged.ProportionLive, merged.ProportionMissed)
+	}
+	if len(disagreements) != 1 || disagreements[0] != "Live" {
+		t.Errorf("expected disagreement on Live, got %v", disagreements)
+	}
+	if infos[2].Live != 8 {
+		t.Errorf("merging modified the wallet results")
+	}
+}
+
+func TestMissedVoteAlert(t *testing.T) {
+	a := NewMissedVoteAlert(2, 10, "", "", true)
+
+	tests := []struct {
+		host          int
+		height, total int64
+		changed       bool
+		misses        int64
+	}{
+		{0, 100, 5, false, 0},
+		{0, 105, 7, false, 2},
+		{0, 108, 8, true, 3},
+		// The misses at height 100 and 105 leave the window.
+		{0, 116, 8, true, 0},
+		// A restarted stakepoold starts counting over.
+		{0, 117, 1, false, 0},
+		{0, 118, 4, true, 3},
+		// Another stakepoold's total can't be compared.
+		{1, 119, 50, true, 0},
+	}
+	for i, test := range tests {
+		changed, misses := a.update(test.host, test.height, test.total)
+		if changed != test.changed || misses != test.misses {
+			t.Errorf("#%d: changed %v misses %d, want %v %d", i, changed,
+				misses, test.changed, test.misses)
+		}
+	}
+}
+
+func TestEmailEvents(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{[]string{"", "votes", "NewLogin"}, "votes,newlogin"},
+		{[]string{"misses, expiring,misses"}, "misses,expiring"},
+		{[]string{""}, EmailEventsNone},
+		{[]string{"none"}, EmailEventsNone},
+	}
+	for _, test := range tests {
+		events, err := parseEmailEvents(test.values)
+		if err != nil || events != test.want {
+			t.Errorf("parseEmailEvents(%q) = %q, %v, want %q", test.values,
+				events, err, test.want)
+		}
+	}
+	if _, err := parseEmailEvents([]string{"votes,logins"}); err == nil {
+		t.Error("unknown email event accepted")
+	}
+
+	// Empty preferences get the defaults, which include new sign ins but
+	// not votes.
+	p := newPreferences(&models.UserPreferences{})
+	if !p.emailEvent(EmailEventNewLogin) || p.emailEvent(EmailEventVotes) {
+		t.Errorf("default email events %q", p.EmailEvents)
+	}
+	p = newPreferences(&models.UserPreferences{EmailEvents: EmailEventsNone})
+	if len(p.emailEventList()) != 0 {
+		t.Errorf("no email events gave %q", p.emailEventList())
+	}
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// maxMaintenanceMsgLen is the longest message shown in the maintenance banner.
+const maxMaintenanceMsgLen = 500
+
+// errMaintenance is the error of users signing up, registering addresses or
+// changing their voting preferences while the pool is in maintenance mode.
+var errMaintenance = errors.New("the pool is undergoing maintenance, " +
+	"please try again later")
+
+// maintenanceState is the maintenance mode admins switch on while they
+// maintain the database.  Sign ups, address registrations and changes of
+// voting preferences are refused while it is enabled, but stakepoold keeps
+// voting the tickets with the preferences it has.  The mode is kept in the
+// file at path, so it survives restarts and every frontend sharing the file
+// sees it; it is only kept in memory when path is empty.
+type maintenanceState struct {
+	mtx  sync.Mutex
+	path string
+	mode poolapi.Maintenance
+	// file is the file the mode was last read from.
+	file os.FileInfo
+}
+
+// load reads the mode from the file when it changed since it was last read.
+// A missing file disables the mode, and the mode is kept when the file can't
+// be read.  The caller must hold the mutex.
+func (m *maintenanceState) load() error {
+	if m.path == "" {
+		return nil
+	}
+	fi, err := os.Stat(m.path)
+	if os.IsNotExist(err) {
+		m.mode, m.file = poolapi.Maintenance{}, nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if m.file != nil && os.SameFile(fi, m.file) &&
+		fi.ModTime().Equal(m.file.ModTime()) && fi.Size() == m.file.Size() {
+		return nil
+	}
+	b, err := ioutil.ReadFile(m.path)
+	if err != nil {
+		return err
+	}
+	var mode poolapi.Maintenance
+	if err = json.Unmarshal(b, &mode); err != nil {
+		return fmt.Errorf("%s: %v", m.path, err)
+	}
+	m.mode, m.file = mode, fi
+	return nil
+}
+
+// save writes the mode to a temporary file that replaces the file, so other
+// frontends never read a partial write.  The caller must hold the mutex.
+func (m *maintenanceState) save(mode poolapi.Maintenance) error {
+	if m.path == "" {
+		m.mode = mode
+		return nil
+	}
+	b, err := json.Marshal(mode)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(m.path), 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(m.path), filepath.Base(m.path))
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), m.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	m.mode, m.file = mode, nil
+	return m.load()
+}
+
+// get returns the maintenance mode.
+func (m *maintenanceState) get() poolapi.Maintenance {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if err := m.load(); err != nil {
+		log.Errorf("Unable to read the maintenance mode: %v", err)
+	}
+	return m.mode
+}
+
+// set enables the maintenance mode with the banner message, or disables it,
+// and returns the mode before.  Changing the message of the enabled mode
+// keeps the time it was enabled.
+func (m *maintenanceState) set(enabled bool, message string,
+	now int64) (poolapi.Maintenance, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if err := m.load(); err != nil {
+		return m.mode, err
+	}
+	before := m.mode
+	mode := poolapi.Maintenance{Enabled: enabled}
+	switch {
+	case !enabled:
+	case !before.Enabled:
+		mode.Message, mode.Since = message, now
+	default:
+		mode.Message, mode.Since = message, before.Since
+	}
+	return before, m.save(mode)
+}
+
+// inMaintenance returns whether the pool is in maintenance mode.
+func (controller *MainController) inMaintenance() bool {
+	return controller.maintenance.get().Enabled
+}
+
+// ApplyMaintenance sets Maintenance in the template environment while the
+// pool is in maintenance mode, so every page shows a banner, and
+// MaintenanceMsg to the message admins set for it, if any.
+func (controller *MainController) ApplyMaintenance(c *web.C, h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if m := controller.maintenance.get(); m.Enabled {
+			c.Env["Maintenance"] = true
+			c.Env["MaintenanceMsg"] = m.Message
+		}
+		h.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// APIMaintenance returns the maintenance mode of the pool.  It is only
+// available to admins.
+func (controller *MainController) APIMaintenance(c web.C,
+	r *http.Request) (*poolapi.Maintenance, codes.Code, string, error) {
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "maintenance error", errors.New("not an admin")
+	}
+	m := controller.maintenance.get()
+	return &m, codes.OK, "maintenance mode successfully retrieved", nil
+}
+
+// APIMaintenancePost enables the maintenance mode of the pool, showing the
+// banner Message if it is set, or disables it when Enabled is false.  It is
+// only available to admins.
+func (controller *MainController) APIMaintenancePost(c web.C,
+	r *http.Request) (*poolapi.Maintenance, codes.Code, string, error) {
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "maintenance error", errors.New("not an admin")
+	}
+	enabled, err := strconv.ParseBool(r.FormValue("Enabled"))
+	if err != nil {
+		return nil, codes.InvalidArgument, "maintenance error", errors.New("invalid enabled")
+	}
+	message := strings.TrimSpace(r.FormValue("Message"))
+	if len(message) > maxMaintenanceMsgLen {
+		return nil, codes.InvalidArgument, "maintenance error", errors.New("message too long")
+	}
+
+	before, err := controller.maintenance.set(enabled, message, time.Now().Unix())
+	if err != nil {
+		log.Errorf("Unable to save the maintenance mode: %v", err)
+		return nil, codes.Internal, "maintenance error", errors.New("unable to save the maintenance mode")
+	}
+	m := controller.maintenance.get()
+	if m != before {
+		action := AuditActionMaintenanceDisable
+		if enabled {
+			action = AuditActionMaintenanceEnable
+			log.Infof("Maintenance mode enabled: %q", message)
+		} else {
+			log.Infof("Maintenance mode disabled")
+		}
+		// The audit log may be unavailable during database maintenance,
+		// which audit only logs.
+		controller.audit(controller.GetDbMap(c), c, r, action, "pool",
+			before, m)
+	}
+	return &m, codes.OK, "maintenance mode successfully updated", nil
+}
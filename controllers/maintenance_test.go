@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+	"google.golang.org/grpc/codes"
+)
+
+func TestMaintenanceState(t *testing.T) {
+	var m maintenanceState
+	tests := []struct {
+		enabled bool
+		message string
+		now     int64
+		want    poolapi.Maintenance
+	}{
+		{true, "", 10, poolapi.Maintenance{Enabled: true, Since: 10}},
+		{true, "database upgrade", 20, poolapi.Maintenance{Enabled: true,
+			Message: "database upgrade", Since: 10}},
+		{false, "ignored", 30, poolapi.Maintenance{}},
+		{true, "again", 40, poolapi.Maintenance{Enabled: true,
+			Message: "again", Since: 40}},
+	}
+	var before poolapi.Maintenance
+	for i, test := range tests {
+		got, err := m.set(test.enabled, test.message, test.now)
+		if err != nil {
+			t.Fatalf("%d: set: %v", i, err)
+		}
+		if got != before {
+			t.Errorf("%d: mode before %+v, want %+v", i, got, before)
+		}
+		if got := m.get(); got != test.want {
+			t.Errorf("%d: mode %+v, want %+v", i, got, test.want)
+		}
+		before = test.want
+	}
+}
+
+func TestMaintenanceStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "testnet", "maintenance.json")
+
+	// A missing file is the disabled mode.
+	m := maintenanceState{path: path}
+	if got := m.get(); got != (poolapi.Maintenance{}) {
+		t.Fatalf("mode %+v without a file", got)
+	}
+	if _, err := m.set(true, "database upgrade", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another frontend, or this one after a restart, sees the mode and
+	// its changes.
+	want := poolapi.Maintenance{Enabled: true, Message: "database upgrade",
+		Since: 10}
+	other := maintenanceState{path: path}
+	if got := other.get(); got != want {
+		t.Fatalf("other frontend mode %+v, want %+v", got, want)
+	}
+	before, err := other.set(false, "", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before != want {
+		t.Errorf("mode before %+v, want %+v", before, want)
+	}
+	if got := m.get(); got.Enabled {
+		t.Errorf("mode %+v after the other frontend disabled it", got)
+	}
+
+	// The mode is kept when the file can't be read.
+	if _, err := m.set(true, "", 30); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.get(); !got.Enabled {
+		t.Errorf("mode %+v after the file became unreadable", got)
+	}
+}
+
+func TestAPIAddressMaintenance(t *testing.T) {
+	controller := &MainController{}
+	if _, err := controller.maintenance.set(true, "", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// The v1 API refuses addresses before looking the user up, as the v2
+	// API does.
+	c := web.C{Env: map[interface{}]interface{}{
+		"APIUserID": int64(1),
+		"DbMap":     (*gorp.DbMap)(nil),
+	}}
+	r := httptest.NewRequest("POST", "/api/v1/address", nil)
+	_, code, _, err := controller.APIAddress(c, r)
+	if code != codes.Unavailable || err != errMaintenance {
+		t.Errorf("APIAddress in maintenance returned %v, %v", code, err)
+	}
+}
@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/notifier"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcstakepool/stakepooldclient"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+)
+
+// Missed vote alert events posted to the alert webhook.
+const (
+	MissedVoteEventRaised   = "raised"
+	MissedVoteEventResolved = "resolved"
+)
+
+// missedVoteAlertInterval is how often stakepoold is asked for the missed
+// votes of the pool.
+const missedVoteAlertInterval = time.Minute
+
+// MissedVoteAlert is raised when the pool misses more than a threshold of
+// votes within a number of blocks.  Admins are emailed and the alert webhook,
+// if any, is posted to when it is raised and when it resolves.  Expired
+// tickets count as missed votes.  It is safe for concurrent access.
+type MissedVoteAlert struct {
+	threshold int64
+	blocks    int64
+	webhook   *models.UserWebhook
+	banner    bool
+
+	mtx     sync.Mutex
+	host    int
+	samples map[int64]int64 // [height]total misses reported by stakepoold
+	active  bool
+	misses  int64
+}
+
+// NewMissedVoteAlert returns an alert raised when more than threshold votes
+// are missed within blocks blocks.  An empty webhookURL posts no webhook.
+// banner shows a warning on every page while the alert is raised.
+func NewMissedVoteAlert(threshold, blocks int64, webhookURL,
+	webhookSecret string, banner bool) *MissedVoteAlert {
+	a := &MissedVoteAlert{
+		threshold: threshold,
+		blocks:    blocks,
+		banner:    banner,
+		samples:   make(map[int64]int64),
+	}
+	if webhookURL != "" {
+		a.webhook = &models.UserWebhook{
+			URL:    webhookURL,
+			Secret: webhookSecret,
+		}
+	}
+	return a
+}
+
+// record adds the total misses stakepoold host reported at height and returns
+// the misses within the last blocks blocks.  The totals of different hosts
+// or of a restarted stakepoold can't be compared, so the samples start over.
+//
+// This function MUST be called with the alert lock held.
+func (a *MissedVoteAlert) record(host int, height, totalMisses int64) int64 {
+	if host != a.host {
+		a.host = host
+		a.samples = make(map[int64]int64)
+	}
+	for h, total := range a.samples {
+		// Blocks above height were reorganized away.
+		if h > height || h < height-a.blocks || total > totalMisses {
+			delete(a.samples, h)
+		}
+	}
+	a.samples[height] = totalMisses
+
+	oldest := height
+	for h := range a.samples {
+		if h < oldest {
+			oldest = h
+		}
+	}
+	return totalMisses - a.samples[oldest]
+}
+
+// update records the total misses and returns whether the alert was raised or
+// resolved by them, and the misses within the last blocks blocks.
+func (a *MissedVoteAlert) update(host int, height, totalMisses int64) (changed bool, misses int64) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.misses = a.record(host, height, totalMisses)
+	active := a.misses > a.threshold
+	changed = active != a.active
+	a.active = active
+	return changed, a.misses
+}
+
+// Active returns whether the alert is raised and the misses that raised it.
+func (a *MissedVoteAlert) Active() (bool, int64) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.active, a.misses
+}
+
+// notifyMissedVoteAlert emails the admins, notifies the operators on Telegram
+// and the chat webhooks, messages the users on Telegram and posts to the alert
+// webhook about event.
+func (controller *MainController) notifyMissedVoteAlert(dbMap *gorp.DbMap,
+	event string, height, misses int64) {
+	a := controller.missedVoteAlert
+
+	email := emailMissedVoteAlert
+	if event == MissedVoteEventResolved {
+		email = emailMissedVoteResolved
+	}
+
+	for _, uidstr := range controller.adminUserIDs {
+		userID, err := strconv.ParseInt(uidstr, 10, 64)
+		if err != nil {
+			log.Warnf("Invalid admin userid %q: %v", uidstr, err)
+			continue
+		}
+		user, err := models.GetUserById(dbMap, userID)
+		if err != nil {
+			log.Warnf("Unable to fetch admin userid %v: %v", userID, err)
+			continue
+		}
+		if user.Email == "" {
+			continue
+		}
+		data := controller.emailData(controller.userLanguage(dbMap, userID))
+		data["Misses"] = misses
+		data["Blocks"] = a.blocks
+		data["Height"] = height
+		data["Threshold"] = a.threshold
+		err = controller.sendEmail(user.Email, email, data)
+		if err != nil {
+			log.Errorf("Error sending missed vote alert to admin userid "+
+				"%v: %v", userID, err)
+		}
+	}
+
+	text := fmt.Sprintf("The stake pool at %s missed %d votes within the "+
+		"last %d blocks.", controller.baseURL, misses, a.blocks)
+	severity := notifier.SeverityCritical
+	if event == MissedVoteEventResolved {
+		text = fmt.Sprintf("The stake pool at %s is voting again, it missed "+
+			"%d votes within the last %d blocks.", controller.baseURL, misses,
+			a.blocks)
+		severity = notifier.SeverityInfo
+	}
+	controller.notifyOperators(severity, text)
+	controller.notifyTelegramUsers(dbMap, text)
+
+	if a.webhook == nil {
+		return
+	}
+	payload, err := json.Marshal(&poolapi.MissedVoteAlert{
+		Event:       event,
+		Time:        time.Now().Unix(),
+		BlockHeight: height,
+		Misses:      misses,
+		Blocks:      a.blocks,
+		Threshold:   a.threshold,
+	})
+	if err != nil {
+		log.Errorf("Unable to encode missed vote alert: %v", err)
+		return
+	}
+	if err := postWebhook(a.webhook, payload); err != nil {
+		log.Errorf("Missed vote alert webhook failed: %v", err)
+	}
+}
+
+// CheckMissedVotes updates the missed vote alert with the pool statistics of
+// the first stakepoold that returns them and notifies the admins when the
+// alert is raised or resolved.
+func (controller *MainController) CheckMissedVotes(dbMap *gorp.DbMap) error {
+	var poolStats *stakepooldclient.PoolStats
+	var host int
+	for i, conn := range controller.stakepooldConnections() {
+		stats, err := stakepooldclient.StakepooldGetPoolStats(conn)
+		if err != nil {
+			log.Warnf("StakepooldGetPoolStats failed on host %d: %v", i, err)
+			continue
+		}
+		poolStats, host = stats, i
+		break
+	}
+	if poolStats == nil {
+		return errors.New("no stakepoold returned pool stats")
+	}
+
+	changed, misses := controller.missedVoteAlert.update(host,
+		poolStats.BlockHeight, poolStats.TotalMisses)
+	if !changed {
+		return nil
+	}
+
+	event := MissedVoteEventResolved
+	if misses > controller.missedVoteAlert.threshold {
+		event = MissedVoteEventRaised
+		log.Errorf("Missed vote alert: the pool missed %d votes within "+
+			"the last %d blocks", misses, controller.missedVoteAlert.blocks)
+	} else {
+		log.Infof("Missed vote alert resolved: the pool missed %d votes "+
+			"within the last %d blocks", misses,
+			controller.missedVoteAlert.blocks)
+	}
+	controller.notifyMissedVoteAlert(dbMap, event, poolStats.BlockHeight,
+		misses)
+	return nil
+}
+
+// MissedVoteAlertHandler runs CheckMissedVotes every missedVoteAlertInterval.
+// It never returns.
+func (controller *MainController) MissedVoteAlertHandler(dbMap *gorp.DbMap) {
+	ticker := time.NewTicker(missedVoteAlertInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := controller.CheckMissedVotes(dbMap); err != nil {
+			log.Errorf("CheckMissedVotes failed: %v", err)
+		}
+	}
+}
+
+// ApplyMissedVoteAlert sets MissedVoteAlert in the template environment to the
+// misses that raised the alert while it is raised, so every page shows a
+// warning banner.
+func (controller *MainController) ApplyMissedVoteAlert(c *web.C, h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		a := controller.missedVoteAlert
+		if a != nil && a.banner {
+			if active, misses := a.Active(); active {
+				c.Env["MissedVoteAlert"] = misses
+				c.Env["MissedVoteAlertBlocks"] = a.blocks
+			}
+		}
+		h.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
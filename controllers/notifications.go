@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcutil"
+	"github.com/go-gorp/gorp"
+)
+
+// notificationInterval is how often users' tickets are checked for votes,
+// misses and upcoming expiry to email them about.
+const notificationInterval = 15 * time.Minute
+
+// ticketEmailEvent is a vote or miss of a ticket listed in the ticket
+// notification email.
+type ticketEmailEvent struct {
+	Ticket string
+	Height uint32
+}
+
+// ticketNotifications are the events of a user's tickets they haven't been
+// emailed about yet.
+type ticketNotifications struct {
+	voted    []ticketEmailEvent
+	missed   []ticketEmailEvent
+	expiring []ExpiringTicket
+
+	// statuses are the ticket statuses to record once the user was
+	// emailed.
+	statuses []*models.EmailTicketStatus
+}
+
+// ticketNotifications returns the events of the user's tickets that prefs
+// ask to be emailed about, or nil when they don't want any.  height is the
+// current block height.
+func (controller *MainController) ticketNotifications(dbMap *gorp.DbMap,
+	user *models.User, prefs *preferences, height int64) (*ticketNotifications, error) {
+	wantVotes := prefs.emailEvent(EmailEventVotes)
+	wantMisses := prefs.emailEvent(EmailEventMisses)
+	wantExpiring := prefs.emailEvent(EmailEventExpiring) &&
+		controller.expiryWarning > 0
+	if !wantVotes && !wantMisses && !wantExpiring {
+		return nil, nil
+	}
+
+	addr, err := hcutil.DecodeAddress(user.MultiSigAddress)
+	if err != nil {
+		log.Warnf("Invalid address %v in database: %v",
+			user.MultiSigAddress, err)
+		return nil, nil
+	}
+	spui, err := controller.rpcServers.StakePoolUserInfo(addr, true)
+	if err != nil {
+		return nil, err
+	}
+	if spui == nil {
+		return nil, nil
+	}
+
+	n := new(ticketNotifications)
+	if wantVotes || wantMisses {
+		emailed, err := models.GetEmailTicketStatuses(dbMap, user.Id)
+		if err != nil {
+			return nil, err
+		}
+		// The tickets of users who were never emailed about their
+		// tickets are only recorded, so they aren't sent their whole
+		// history.
+		record := len(emailed) == 0
+		for _, t := range spui.Tickets {
+			status, ok := emailed[t.Ticket]
+			if !ok {
+				status = &models.EmailTicketStatus{
+					UserId:     user.Id,
+					TicketHash: t.Ticket,
+				}
+			}
+			if status.Status == t.Status {
+				continue
+			}
+			event, ok := ticketStatusEvents[t.Status]
+			if !record && ok && event != ticketStatusEvents[status.Status] {
+				e := ticketEmailEvent{Ticket: t.Ticket, Height: t.SpentByHeight}
+				switch {
+				case event == TicketEventVoted && wantVotes:
+					n.voted = append(n.voted, e)
+				case event == TicketEventMissed && wantMisses:
+					n.missed = append(n.missed, e)
+				}
+			}
+			status.Status = t.Status
+			n.statuses = append(n.statuses, status)
+		}
+	}
+
+	if wantExpiring {
+		var tickets []TicketInfoLive
+		for _, t := range spui.Tickets {
+			if t.Status == "live" || t.Status == "immature" {
+				tickets = append(tickets, TicketInfoLive{
+					TicketHeight: t.TicketHeight,
+					Ticket:       t.Ticket,
+				})
+			}
+		}
+		expiring := controller.expiringTickets(tickets, height)
+		if len(expiring) > 0 {
+			warned, err := models.GetTicketExpiryWarnings(dbMap, user.Id)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range expiring {
+				if _, ok := warned[t.Ticket]; !ok {
+					n.expiring = append(n.expiring, t)
+				}
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// SendTicketNotifications emails every user with a verified email address
+// about the votes, misses and upcoming expiry of their tickets they chose to
+// be emailed about.  Each event is only mentioned once and users are emailed
+// no more often than their digest frequency allows.
+func (controller *MainController) SendTicketNotifications(dbMap *gorp.DbMap) error {
+	if controller.RPCIsStopped() {
+		return errors.New("RPC server stopped")
+	}
+	_, height, err := controller.rpcServers.GetBestBlock()
+	if err != nil {
+		return err
+	}
+
+	err = models.PruneTicketExpiryWarnings(dbMap, height)
+	if err != nil {
+		log.Warnf("PruneTicketExpiryWarnings failed: %v", err)
+	}
+
+	users, err := models.GetAllNotifiableUsers(dbMap)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var notifiedUsers int
+	for i := range users {
+		user := &users[i]
+		prefs := controller.getPreferences(dbMap, user.Id)
+		if !prefs.digestDue(now) {
+			continue
+		}
+
+		n, err := controller.ticketNotifications(dbMap, user, prefs, height)
+		if err != nil {
+			log.Errorf("Unable to get ticket notifications for userid "+
+				"%v: %v", user.Id, err)
+			continue
+		}
+		if n == nil {
+			continue
+		}
+
+		if len(n.voted) > 0 || len(n.missed) > 0 || len(n.expiring) > 0 {
+			expiring := make([]expiryEmailTicket, 0, len(n.expiring))
+			for _, t := range n.expiring {
+				expiring = append(expiring, expiryEmailTicket{
+					Ticket:       t.Ticket,
+					ExpiryHeight: t.ExpiryHeight,
+					Expires:      prefs.formatTime(t.EstimatedExpiry),
+				})
+			}
+			data := controller.emailData(controller.userLanguage(dbMap,
+				user.Id))
+			data["Voted"] = n.voted
+			data["Missed"] = n.missed
+			data["Tickets"] = expiring
+			err = controller.sendEmail(user.Email, emailTicketNotifications,
+				data)
+			if err != nil {
+				log.Errorf("Error sending ticket notifications to userid "+
+					"%v: %v", user.Id, err)
+				continue
+			}
+
+			for _, t := range n.expiring {
+				err = models.AddTicketExpiryWarning(dbMap, user.Id, t.Ticket,
+					t.ExpiryHeight)
+				if err != nil {
+					log.Errorf("Unable to record ticket expiry warning for "+
+						"userid %v: %v", user.Id, err)
+				}
+			}
+			prefs.LastDigest = now.Unix()
+			err = models.SetUserPreferences(dbMap, prefs.UserPreferences)
+			if err != nil {
+				log.Errorf("Unable to record digest time for userid %v: %v",
+					user.Id, err)
+			}
+			notifiedUsers++
+		}
+
+		for _, status := range n.statuses {
+			if err := models.SetEmailTicketStatus(dbMap, status); err != nil {
+				log.Errorf("Unable to record emailed ticket status for "+
+					"userid %v: %v", user.Id, err)
+				break
+			}
+		}
+	}
+
+	log.Infof("Sent ticket notifications to %d users", notifiedUsers)
+	return nil
+}
+
+// TicketNotificationHandler runs SendTicketNotifications every
+// notificationInterval.  It never returns.
+func (controller *MainController) TicketNotificationHandler(dbMap *gorp.DbMap) {
+	ticker := time.NewTicker(notificationInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := controller.SendTicketNotifications(dbMap); err != nil {
+			log.Errorf("SendTicketNotifications failed: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// notifyNewLogin records that the user signed in from remoteIP and emails
+// them when it was the first time, unless it is the first IP address they
+// signed in from or they chose not to be emailed about it.  Only the email is
+// sent in the background, so signing in doesn't wait for the mail server.
+func (controller *MainController) notifyNewLogin(dbMap *gorp.DbMap,
+	user *models.User, remoteIP string, now time.Time) {
+	first, err := models.RecordUserLoginIP(dbMap, user.Id, remoteIP,
+		now.Unix())
+	if err != nil {
+		log.Errorf("RecordUserLoginIP failed for userid %v: %v", user.Id,
+			err)
+		return
+	}
+	if !first {
+		return
+	}
+
+	known, err := models.CountUserLoginIPs(dbMap, user.Id)
+	if err != nil {
+		log.Errorf("CountUserLoginIPs failed for userid %v: %v", user.Id, err)
+		return
+	}
+	prefs := controller.getPreferences(dbMap, user.Id)
+	if known <= 1 || !prefs.emailEvent(EmailEventNewLogin) ||
+		controller.mailer == nil {
+		return
+	}
+	data := controller.emailData(controller.userLanguage(dbMap, user.Id))
+	data["RemoteIP"] = remoteIP
+	data["Time"] = prefs.formatTime(now)
+	go func() {
+		err := controller.sendEmail(user.Email, emailNewLogin, data)
+		if err != nil {
+			log.Errorf("Error sending new sign in email to userid %v: %v",
+				user.Id, err)
+		}
+	}()
+}
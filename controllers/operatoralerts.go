@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/notifier"
+	"github.com/coolsnady/hcstakepool/stakepooldclient"
+	"github.com/go-gorp/gorp"
+)
+
+const (
+	// operatorAlertInterval is how often the backends, the best block and
+	// the fee addresses left are checked.
+	operatorAlertInterval = time.Minute
+
+	// reorgHistory is how many of the best blocks seen are remembered to
+	// detect reorganizations.
+	reorgHistory = 64
+)
+
+// operatorAlerts is the state of the problems operators are notified about,
+// so they are only notified when a problem starts or ends.
+type operatorAlerts struct {
+	mtx             sync.Mutex
+	walletUp        []bool
+	stakepooldDown  map[string]bool
+	bestBlocks      map[int64]chainhash.Hash
+	feeAddressesLow bool
+	stakeVersionLag string
+
+	// doubleVotes are the double votes each stakepoold reported at or
+	// above doubleVoteHeights, keyed by vote hash.
+	doubleVotes       map[string]map[string]bool
+	doubleVoteHeights map[string]int64
+}
+
+// notifyOperators tells the operators about an event of severity, such as a
+// problem of the pool or that it was resolved, on Telegram and the chat
+// webhooks.
+func (controller *MainController) notifyOperators(severity notifier.Severity,
+	text string) {
+	if controller.telegram != nil {
+		controller.telegram.NotifyOperators(text)
+	}
+	if controller.notifier != nil {
+		controller.notifier.Notify(severity, text)
+	}
+}
+
+// checkWalletConnections notifies the operators when a voting wallet stops
+// responding or loses its connection to hcd, and when it recovers.
+func (controller *MainController) checkWalletConnections() {
+	walletInfo, _ := controller.WalletStatus()
+
+	a := &controller.operatorAlerts
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if len(a.walletUp) != len(walletInfo) {
+		a.walletUp = make([]bool, len(walletInfo))
+		for i := range a.walletUp {
+			a.walletUp[i] = true
+		}
+	}
+
+	for i, wi := range walletInfo {
+		up := wi != nil && wi.DaemonConnected
+		if up == a.walletUp[i] {
+			continue
+		}
+		a.walletUp[i] = up
+		var text string
+		switch {
+		case up:
+			text = fmt.Sprintf("Voting wallet %d of the stake pool at %s "+
+				"is connected again.", i, controller.baseURL)
+			log.Info(text)
+			controller.notifyOperators(notifier.SeverityInfo, text)
+			continue
+		case wi == nil:
+			text = fmt.Sprintf("Voting wallet %d of the stake pool at %s "+
+				"is disconnected.", i, controller.baseURL)
+		default:
+			text = fmt.Sprintf("Voting wallet %d of the stake pool at %s "+
+				"lost its connection to hcd.", i, controller.baseURL)
+		}
+		log.Error(text)
+		controller.notifyOperators(notifier.SeverityCritical, text)
+	}
+}
+
+// checkStakepooldBackends notifies the operators when a stakepoold backend
+// stops responding, loses its connection to hcd or hcwallet or pauses voting
+// on a suspected chain fork, and when it recovers.
+func (controller *MainController) checkStakepooldBackends() {
+	hosts, conns := controller.stakepooldBackends()
+	problems := make(map[string]string)
+	for i, conn := range conns {
+		status, err := stakepooldclient.StakepooldGetStatus(
+			context.Background(), conn)
+		switch {
+		case err != nil:
+			problems[hosts[i]] = fmt.Sprintf("is not responding: %v", err)
+		case !status.NodeConnected:
+			problems[hosts[i]] = "lost its connection to hcd"
+		case !status.WalletConnected:
+			problems[hosts[i]] = "lost its connection to hcwallet"
+		case status.VotingPaused:
+			problems[hosts[i]] = fmt.Sprintf("paused voting on a "+
+				"suspected chain fork: %s", status.Fork)
+		}
+	}
+
+	a := &controller.operatorAlerts
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	down := make(map[string]bool, len(problems))
+	for _, host := range hosts {
+		problem, isDown := problems[host]
+		down[host] = isDown
+		if isDown == a.stakepooldDown[host] {
+			continue
+		}
+		if !isDown {
+			text := fmt.Sprintf("stakepoold %s of the stake pool at %s is "+
+				"working again.", host, controller.baseURL)
+			log.Info(text)
+			controller.notifyOperators(notifier.SeverityInfo, text)
+			continue
+		}
+		text := fmt.Sprintf("stakepoold %s of the stake pool at %s %s.",
+			host, controller.baseURL, problem)
+		log.Error(text)
+		controller.notifyOperators(notifier.SeverityCritical, text)
+	}
+	// Retired backends are forgotten.
+	a.stakepooldDown = down
+}
+
+// checkDoubleVotes notifies the operators of the double votes the stakepoold
+// backends report.  The double votes reported before the first check are only
+// logged by stakepoold.
+func (controller *MainController) checkDoubleVotes() {
+	hosts, conns := controller.stakepooldBackends()
+
+	a := &controller.operatorAlerts
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.doubleVotes == nil {
+		a.doubleVotes = make(map[string]map[string]bool)
+		a.doubleVoteHeights = make(map[string]int64)
+	}
+	for i, conn := range conns {
+		host := hosts[i]
+		version, err := stakepooldclient.StakepooldVersion(conn)
+		if err != nil || !version.Supports(
+			stakepooldclient.CapabilityDoubleVotes) {
+			// Unresponsive backends are reported by
+			// checkStakepooldBackends.
+			continue
+		}
+		votes, err := stakepooldclient.StakepooldGetDoubleVotes(
+			context.Background(), conn, a.doubleVoteHeights[host])
+		if err != nil {
+			log.Warnf("Unable to get the double votes of stakepoold %v: %v",
+				host, err)
+			continue
+		}
+
+		seen, known := a.doubleVotes[host]
+		current := make(map[string]bool, len(votes))
+		for _, v := range votes {
+			current[v.VoteHash] = true
+			if v.BlockHeight > a.doubleVoteHeights[host] {
+				a.doubleVoteHeights[host] = v.BlockHeight
+			}
+			if !known || seen[v.VoteHash] {
+				continue
+			}
+			controller.notifyDoubleVote(host, &v)
+		}
+		a.doubleVotes[host] = current
+	}
+}
+
+// notifyDoubleVote notifies the operators of a double vote stakepoold host
+// reported, as critical when the conflicting vote was signed outside of the
+// pool.
+func (controller *MainController) notifyDoubleVote(host string,
+	v *stakepooldclient.DoubleVote) {
+	text := fmt.Sprintf("stakepoold %s of the stake pool at %s double voted "+
+		"ticket %s of %s on block %s (height %d): its vote %s with vote "+
+		"bits %d conflicts with vote %s", host, controller.baseURL,
+		v.Ticket, v.MultiSigAddress, v.BlockHash, v.BlockHeight, v.VoteHash,
+		v.VoteBits, v.ConflictingVoteHash)
+	switch v.Source {
+	case stakepooldclient.DoubleVoteSourceExternal:
+		text += fmt.Sprintf(" with vote bits %d signed outside of the "+
+			"pool.  The voting key of the ticket may be compromised.",
+			v.ConflictingVoteBits)
+		log.Error(text)
+		controller.notifyOperators(notifier.SeverityCritical, text)
+	case stakepooldclient.DoubleVoteSourcePool:
+		text += fmt.Sprintf(" with vote bits %d of another voting wallet.  "+
+			"The voting preferences of the voting wallets are out of sync.",
+			v.ConflictingVoteBits)
+		log.Warn(text)
+		controller.notifyOperators(notifier.SeverityWarning, text)
+	default:
+		text += " which stakepoold couldn't look up."
+		log.Warn(text)
+		controller.notifyOperators(notifier.SeverityWarning, text)
+	}
+}
+
+// checkReorg notifies the operators when the best block at a height that was
+// seen before changed, or the chain got shorter, which means the chain was
+// reorganized.
+func (controller *MainController) checkReorg() {
+	hash, height, err := controller.rpcServers.GetBestBlock()
+	if err != nil {
+		// Disconnected wallets are reported by checkWalletConnections.
+		return
+	}
+
+	a := &controller.operatorAlerts
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.bestBlocks == nil {
+		a.bestBlocks = make(map[int64]chainhash.Hash)
+	}
+	var tip int64
+	for h := range a.bestBlocks {
+		if h > tip {
+			tip = h
+		}
+	}
+
+	reorgHeight := int64(-1)
+	if seen, ok := a.bestBlocks[height]; ok && seen != *hash {
+		reorgHeight = height
+	} else if height < tip {
+		reorgHeight = height + 1
+	}
+	if reorgHeight >= 0 {
+		// Blocks from the reorganized height on were replaced.
+		for h := range a.bestBlocks {
+			if h >= reorgHeight {
+				delete(a.bestBlocks, h)
+			}
+		}
+		text := fmt.Sprintf("The chain seen by the stake pool at %s was "+
+			"reorganized from height %d on, the best block is now %v at "+
+			"height %d.", controller.baseURL, reorgHeight, hash, height)
+		log.Warn(text)
+		controller.notifyOperators(notifier.SeverityWarning, text)
+	}
+
+	a.bestBlocks[height] = *hash
+	delete(a.bestBlocks, height-reorgHistory)
+}
+
+// checkFeeAddresses notifies the operators when no more than
+// feeAddressWarning of the MaxUsers fee addresses are left for new users, and
+// when there are more again.
+func (controller *MainController) checkFeeAddresses(dbMap *gorp.DbMap) {
+	left := int64(MaxUsers) - models.GetUserMax(dbMap)
+	low := left <= controller.feeAddressWarning
+
+	a := &controller.operatorAlerts
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if low == a.feeAddressesLow {
+		return
+	}
+	a.feeAddressesLow = low
+	if low {
+		text := fmt.Sprintf("Only %d of the %d fee addresses of the stake "+
+			"pool at %s are left for new users.", left, MaxUsers,
+			controller.baseURL)
+		log.Warn(text)
+		controller.notifyOperators(notifier.SeverityWarning, text)
+	} else {
+		text := fmt.Sprintf("%d of the %d fee addresses of the stake pool "+
+			"at %s are left for new users again.", left, MaxUsers,
+			controller.baseURL)
+		log.Info(text)
+		controller.notifyOperators(notifier.SeverityInfo, text)
+	}
+}
+
+// OperatorAlertHandler checks the voting wallets, the stakepoold backends and
+// their double votes, the best block, the fee addresses left and the vote
+// version of the network every operatorAlertInterval.  It never returns.
+func (controller *MainController) OperatorAlertHandler(dbMap *gorp.DbMap) {
+	ticker := time.NewTicker(operatorAlertInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		controller.checkWalletConnections()
+		if controller.enableStakepoold {
+			controller.checkStakepooldBackends()
+			controller.checkDoubleVotes()
+		}
+		controller.checkReorg()
+		if controller.feeAddressWarning > 0 {
+			controller.checkFeeAddresses(dbMap)
+		}
+		if controller.stakeVersionWarn > 0 {
+			controller.checkStakeVersion(dbMap)
+		}
+	}
+}
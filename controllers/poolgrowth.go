@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/go-gorp/gorp"
+)
+
+const (
+	// poolGrowthInterval is how often the best block is checked to record
+	// the state of the pool at every new block.
+	poolGrowthInterval = time.Minute
+
+	// poolGrowthDays is how many days the pool growth charts on the stats
+	// page cover.
+	poolGrowthDays = 90
+)
+
+// PoolGrowthDay is a day of the pool growth charts on the stats page: the
+// tickets the pool bought and voted during the day, its live tickets at the
+// end of the day and their share of the network's ticket pool in percent.
+type PoolGrowthDay struct {
+	Date       string
+	Purchased  int64
+	Live       int64
+	Votes      int64
+	Proportion float64
+}
+
+// poolGrowthChart returns the days of the pool growth charts from the pool
+// growth rows.  The counts of wallets that were rescanned can go down, which
+// isn't shown as negative purchases or votes.
+func poolGrowthChart(rows []models.PoolGrowth) []PoolGrowthDay {
+	nonNegative := func(n int64) int64 {
+		if n < 0 {
+			return 0
+		}
+		return n
+	}
+
+	days := make([]PoolGrowthDay, 0, len(rows))
+	for _, row := range rows {
+		day := PoolGrowthDay{
+			Date:      time.Unix(row.Day, 0).UTC().Format("2006-01-02"),
+			Purchased: nonNegative(row.Tickets - row.TicketsStart),
+			Live:      row.LiveTickets,
+			Votes:     nonNegative(row.Votes - row.VotesStart),
+		}
+		if row.NetworkPoolSize > 0 {
+			day.Proportion = float64(row.LiveTickets) * 100 /
+				float64(row.NetworkPoolSize)
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+// PoolGrowth returns the days of the pool growth charts.
+func (controller *MainController) PoolGrowth(dbMap *gorp.DbMap) ([]PoolGrowthDay, error) {
+	since := time.Now().UTC().Truncate(24*time.Hour).
+		AddDate(0, 0, -poolGrowthDays+1)
+	rows, err := models.GetPoolGrowth(dbMap, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	return poolGrowthChart(rows), nil
+}
+
+// blockConnected records the state of the pool at the newly connected block
+// at height in the row of the current day.
+func (controller *MainController) blockConnected(dbMap *gorp.DbMap, height int64) error {
+	wsi, err := controller.StakeInfoAll()
+	if err != nil {
+		return err
+	}
+	info := wsi.Merged
+	tickets := int64(info.Immature) + int64(info.Live) + int64(info.Voted) +
+		int64(info.Missed) + int64(info.Expired)
+	day := time.Now().UTC().Truncate(24 * time.Hour).Unix()
+	return models.RecordPoolGrowth(dbMap, day, &models.PoolGrowth{
+		BlockHeight:     height,
+		Tickets:         tickets,
+		Votes:           int64(info.Voted),
+		LiveTickets:     int64(info.Live),
+		NetworkPoolSize: int64(info.PoolSize),
+	})
+}
+
+// PoolGrowthHandler checks the best block every poolGrowthInterval and
+// records the state of the pool when a block was connected.  It never
+// returns.
+func (controller *MainController) PoolGrowthHandler(dbMap *gorp.DbMap) {
+	ticker := time.NewTicker(poolGrowthInterval)
+	defer ticker.Stop()
+
+	var lastHash string
+	for range ticker.C {
+		if controller.RPCIsStopped() {
+			continue
+		}
+		hash, height, err := controller.rpcServers.GetBestBlock()
+		if err != nil || hash.String() == lastHash {
+			continue
+		}
+		if err := controller.blockConnected(dbMap, height); err != nil {
+			log.Errorf("Recording the pool growth at height %d failed: %v",
+				height, err)
+			continue
+		}
+		lastHash = hash.String()
+	}
+}
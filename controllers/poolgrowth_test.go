@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcstakepool/models"
+)
+
+func TestPoolGrowthChart(t *testing.T) {
+	rows := []models.PoolGrowth{
+		{Day: 86400, TicketsStart: 10, Tickets: 15, VotesStart: 2,
+			Votes: 5, LiveTickets: 8, NetworkPoolSize: 80},
+		// A rescanned wallet counts fewer tickets.
+		{Day: 2 * 86400, TicketsStart: 15, Tickets: 12, VotesStart: 5,
+			Votes: 5, LiveTickets: 6},
+	}
+	days := poolGrowthChart(rows)
+	want := []PoolGrowthDay{
+		{Date: "1970-01-02", Purchased: 5, Live: 8, Votes: 3, Proportion: 10},
+		{Date: "1970-01-03", Purchased: 0, Live: 6, Votes: 0, Proportion: 0},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("%d days, want %d", len(days), len(want))
+	}
+	for i := range want {
+		if days[i] != want[i] {
+			t.Errorf("day %d is %+v, want %+v", i, days[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcstakepool/pricefeed"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Notification digest frequencies.  Immediate sends each notification as soon
+// as there is something to report while the others batch them.
+const (
+	DigestImmediate = "immediate"
+	DigestDaily     = "daily"
+	DigestWeekly    = "weekly"
+	DigestNever     = "never"
+)
+
+// digestIntervals is the minimum time between two notification emails for each
+// digest frequency.
+var digestIntervals = map[string]time.Duration{
+	DigestImmediate: 0,
+	DigestDaily:     24 * time.Hour,
+	DigestWeekly:    7 * 24 * time.Hour,
+}
+
+// Events users can choose to be emailed about.  EmailEventsNone is stored
+// when they chose none so the defaults don't apply.
+const (
+	EmailEventVotes    = "votes"
+	EmailEventMisses   = "misses"
+	EmailEventExpiring = "expiring"
+	EmailEventNewLogin = "newlogin"
+	EmailEventsNone    = "none"
+)
+
+// emailEvents are the events users can be emailed about.
+var emailEvents = []string{EmailEventVotes, EmailEventMisses,
+	EmailEventExpiring, EmailEventNewLogin}
+
+const (
+	defaultCurrency        = "USD"
+	defaultDigestFrequency = DigestImmediate
+	defaultEmailEvents     = EmailEventMisses + "," + EmailEventExpiring +
+		"," + EmailEventNewLogin
+
+	// userTimeFormat is how times are shown to users, in their time zone.
+	userTimeFormat = "2006-01-02 15:04 MST"
+)
+
+// supportedCurrencies are the currencies fiat estimates can be shown in.
+var supportedCurrencies = pricefeed.Currencies
+
+// preferences are a user's preferences with the pool defaults filled in.
+type preferences struct {
+	*models.UserPreferences
+	location *time.Location
+}
+
+// newPreferences fills in the defaults for the unset fields of prefs.
+func newPreferences(prefs *models.UserPreferences) *preferences {
+	p := &preferences{UserPreferences: prefs, location: time.UTC}
+	if prefs.TimeZone != "" {
+		loc, err := time.LoadLocation(prefs.TimeZone)
+		if err == nil {
+			p.location = loc
+		}
+	}
+	if prefs.Currency == "" {
+		prefs.Currency = defaultCurrency
+	}
+	if prefs.DigestFrequency == "" {
+		prefs.DigestFrequency = defaultDigestFrequency
+	}
+	if prefs.EmailEvents == "" {
+		prefs.EmailEvents = defaultEmailEvents
+	}
+	return p
+}
+
+// emailEventList returns the events the user wants to be emailed about.
+func (p *preferences) emailEventList() []string {
+	if p.EmailEvents == EmailEventsNone {
+		return []string{}
+	}
+	return strings.Split(p.EmailEvents, ",")
+}
+
+// emailEvent returns whether the user wants to be emailed about event.
+func (p *preferences) emailEvent(event string) bool {
+	return stringSliceContains(p.emailEventList(), event)
+}
+
+// emailEventOption is an event users can choose to be emailed about, as shown
+// on the settings page.
+type emailEventOption struct {
+	Event       string
+	Description string
+	Checked     bool
+}
+
+// emailEventDescriptions describe the events users can be emailed about.
+var emailEventDescriptions = map[string]string{
+	EmailEventVotes:    "Votes cast by my tickets",
+	EmailEventMisses:   "Missed votes and expired tickets",
+	EmailEventExpiring: "Tickets about to expire",
+	EmailEventNewLogin: "Sign ins from a new IP address (sent immediately)",
+}
+
+// emailEventOptions returns the events users can be emailed about, checked
+// when the user chose to be.
+func emailEventOptions(p *preferences) []emailEventOption {
+	options := make([]emailEventOption, 0, len(emailEvents))
+	for _, event := range emailEvents {
+		options = append(options, emailEventOption{
+			Event:       event,
+			Description: emailEventDescriptions[event],
+			Checked:     p.emailEvent(event),
+		})
+	}
+	return options
+}
+
+// parseEmailEvents returns the events in the comma separated lists values as
+// stored in the preferences, or an error if one is unknown.
+func parseEmailEvents(values []string) (string, error) {
+	var events []string
+	for _, v := range values {
+		for _, event := range strings.Split(v, ",") {
+			event = strings.ToLower(strings.TrimSpace(event))
+			if event == "" || event == EmailEventsNone ||
+				stringSliceContains(events, event) {
+				continue
+			}
+			if !stringSliceContains(emailEvents, event) {
+				return "", fmt.Errorf("unknown email event %q, must be "+
+					"one of %s", event, strings.Join(emailEvents, ", "))
+			}
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return EmailEventsNone, nil
+	}
+	return strings.Join(events, ","), nil
+}
+
+// formatTime formats t in the user's time zone.
+func (p *preferences) formatTime(t time.Time) string {
+	return t.In(p.location).Format(userTimeFormat)
+}
+
+// digestDue returns whether a notification email may be sent at now.
+func (p *preferences) digestDue(now time.Time) bool {
+	interval, ok := digestIntervals[p.DigestFrequency]
+	if !ok {
+		return false
+	}
+	return now.Sub(time.Unix(p.LastDigest, 0)) >= interval
+}
+
+// validatePreferences checks that the time zone, currency and digest frequency
+// are known.
+func validatePreferences(timeZone, currency, digestFrequency string) error {
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return fmt.Errorf("unknown time zone %q", timeZone)
+	}
+	if !stringSliceContains(supportedCurrencies, currency) {
+		return fmt.Errorf("unsupported currency %q, must be one of %s",
+			currency, strings.Join(supportedCurrencies, ", "))
+	}
+	if _, ok := digestIntervals[digestFrequency]; !ok &&
+		digestFrequency != DigestNever {
+		return fmt.Errorf("unknown digest frequency %q", digestFrequency)
+	}
+	return nil
+}
+
+// getPreferences returns the preferences of the user.  The defaults are used
+// if they can't be fetched.
+func (controller *MainController) getPreferences(dbMap *gorp.DbMap,
+	userID int64) *preferences {
+	prefs, err := models.GetUserPreferences(dbMap, userID)
+	if err != nil {
+		log.Errorf("GetUserPreferences failed for userid %v: %v", userID, err)
+		prefs = &models.UserPreferences{UserId: userID}
+	}
+	return newPreferences(prefs)
+}
+
+// updatePreferences validates and stores the preferences in the request.
+// Preferences missing from the request are left unchanged.
+func (controller *MainController) updatePreferences(dbMap *gorp.DbMap,
+	userID int64, r *http.Request) (*preferences, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	p := controller.getPreferences(dbMap, userID)
+
+	timeZone := p.TimeZone
+	if v, ok := r.Form["TimeZone"]; ok {
+		timeZone = v[0]
+	}
+	currency, digestFrequency, lang := p.Currency, p.DigestFrequency, p.Language
+	if v := r.FormValue("Currency"); v != "" {
+		currency = strings.ToUpper(v)
+	}
+	if v := r.FormValue("DigestFrequency"); v != "" {
+		digestFrequency = strings.ToLower(v)
+	}
+	if v, ok := r.Form["Language"]; ok {
+		lang = strings.ToLower(v[0])
+	}
+	// The settings page always posts an empty value so unchecking every
+	// event is seen.
+	events := p.EmailEvents
+	if v, ok := r.Form["EmailEvents"]; ok {
+		var err error
+		if events, err = parseEmailEvents(v); err != nil {
+			return nil, err
+		}
+	}
+	if err := validatePreferences(timeZone, currency, digestFrequency); err != nil {
+		return nil, err
+	}
+	if lang != "" && !controller.locales.Has(lang) {
+		return nil, fmt.Errorf("unsupported language %q, must be one of %s",
+			lang, strings.Join(controller.locales.Languages(), ", "))
+	}
+
+	p.TimeZone = timeZone
+	p.Currency = currency
+	p.DigestFrequency = digestFrequency
+	p.Language = lang
+	p.EmailEvents = events
+	if err := models.SetUserPreferences(dbMap, p.UserPreferences); err != nil {
+		log.Errorf("SetUserPreferences failed for userid %v: %v", userID, err)
+		return nil, errors.New("unable to save preferences")
+	}
+
+	return newPreferences(p.UserPreferences), nil
+}
+
+// apiPreferences converts preferences to their API representation.
+func apiPreferences(p *preferences) *poolapi.Preferences {
+	timeZone := p.TimeZone
+	if timeZone == "" {
+		timeZone = time.UTC.String()
+	}
+	return &poolapi.Preferences{
+		TimeZone:        timeZone,
+		Currency:        p.Currency,
+		DigestFrequency: p.DigestFrequency,
+		Language:        p.Language,
+		EmailEvents:     p.emailEventList(),
+	}
+}
+
+// APIPreferences returns the preferences of the user.
+func (controller *MainController) APIPreferences(c web.C,
+	r *http.Request) (*poolapi.Preferences, codes.Code, string, error) {
+	if c.Env["APIUserID"] == nil {
+		return nil, codes.Unauthenticated, "preferences error", errors.New("invalid api token")
+	}
+
+	p := controller.getPreferences(controller.GetDbMap(c),
+		c.Env["APIUserID"].(int64))
+	return apiPreferences(p), codes.OK, "preferences successfully retrieved", nil
+}
+
+// APIPreferencesPost updates the preferences of the user.
+func (controller *MainController) APIPreferencesPost(c web.C,
+	r *http.Request) (*poolapi.Preferences, codes.Code, string, error) {
+	if c.Env["APIUserID"] == nil {
+		return nil, codes.Unauthenticated, "preferences error", errors.New("invalid api token")
+	}
+
+	p, err := controller.updatePreferences(controller.GetDbMap(c),
+		c.Env["APIUserID"].(int64), r)
+	if err != nil {
+		return nil, codes.InvalidArgument, "preferences error", err
+	}
+	return apiPreferences(p), codes.OK, "preferences successfully updated", nil
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/helpers"
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/system"
+	"github.com/go-gorp/gorp"
+	"github.com/gorilla/sessions"
+	"github.com/zenazn/goji/web"
+)
+
+// rememberSelectorKey is the session value holding the selector of the
+// remember me token the session was signed in with, if any.
+const rememberSelectorKey = "RememberSelector"
+
+// rememberDevice is a device remembered for a user as listed on the settings
+// page.
+type rememberDevice struct {
+	ID       int64
+	Device   string
+	IP       string
+	Created  string
+	LastUsed string
+	Current  bool
+}
+
+// ApplyRememberMe signs browsers without a signed in session back in with
+// their remember me token, which is rotated on every use.  Invalid tokens are
+// deleted from the browser, and sessions signed in with a token that was
+// revoked since, e.g. because it was stolen, are signed out.  Assets are
+// served without looking at the token.
+func (controller *MainController) ApplyRememberMe(c *web.C, h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		isAPI, _ := c.Env["IsAPI"].(bool)
+		if isAPI || strings.HasPrefix(r.URL.Path, "/assets/") {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		session := controller.GetSession(*c)
+		dbMap := controller.GetDbMap(*c)
+		remoteIP := getClientIP(r, controller.realIPHeader)
+		_, signedIn := session.Values["UserId"].(int64)
+		if signedIn {
+			if !controller.rememberedSessionValid(dbMap, session) {
+				log.Infof("Signing out session from %v of a revoked "+
+					"remember me token", remoteIP)
+				session.Values["UserId"] = nil
+				delete(session.Values, rememberSelectorKey)
+				c.Env["User"] = nil
+				http.SetCookie(w, controller.templates.RememberMe("", 0))
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+		cookie, err := r.Cookie(system.RememberMeCookie)
+		if err != nil || cookie.Value == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		userID, value, err := helpers.UseRememberToken(dbMap, cookie.Value,
+			r.UserAgent(), remoteIP, time.Now())
+		if err != nil {
+			log.Infof("Remember me sign in from %v failed: %v", remoteIP, err)
+			http.SetCookie(w, controller.templates.RememberMe("", 0))
+			h.ServeHTTP(w, r)
+			return
+		}
+		user, err := models.GetUserById(dbMap, userID)
+		if err != nil {
+			log.Errorf("GetUserById failed for userid %v: %v", userID, err)
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		log.Infof("Remember me sign in from %v, email %v", remoteIP,
+			user.Email)
+		session.Values["UserId"] = user.Id
+		session.Values[rememberSelectorKey] =
+			helpers.RememberTokenSelector(cookie.Value)
+		c.Env["User"] = user
+		controller.recordSecurityEvent(dbMap, r, user.Id,
+			models.SecurityEventLogin, "remembered device", time.Now())
+		// A parallel request rotated the token and sets the new cookie.
+		if value != "" {
+			http.SetCookie(w, controller.templates.RememberMe(value,
+				int(helpers.RememberTokenLifetime/time.Second)))
+		}
+		h.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// rememberedSessionValid returns whether the remember me token the session was
+// signed in with, if any, still exists.  Errors looking it up keep the
+// session.
+func (controller *MainController) rememberedSessionValid(dbMap *gorp.DbMap,
+	session *sessions.Session) bool {
+	selector, ok := session.Values[rememberSelectorKey].(string)
+	if !ok {
+		return true
+	}
+	token, err := models.GetRememberToken(dbMap, selector)
+	if err != nil {
+		log.Errorf("GetRememberToken failed: %v", err)
+		return true
+	}
+	return token != nil
+}
+
+// setRememberMe sets the remember me cookie of the response to value, or
+// deletes it when value is empty.
+func (controller *MainController) setRememberMe(c web.C, value string) {
+	cookies, _ := c.Env["Cookies"].([]*http.Cookie)
+	c.Env["Cookies"] = append(cookies, controller.templates.RememberMe(value,
+		int(helpers.RememberTokenLifetime/time.Second)))
+}
+
+// rememberDevices returns the devices remembered for the user.  The device
+// presenting the remember me cookie of r is marked current.
+func (controller *MainController) rememberDevices(dbMap *gorp.DbMap,
+	r *http.Request, prefs *preferences) ([]rememberDevice, error) {
+	tokens, err := models.GetUserRememberTokens(dbMap, prefs.UserId,
+		time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	var selector string
+	if cookie, err := r.Cookie(system.RememberMeCookie); err == nil {
+		selector = helpers.RememberTokenSelector(cookie.Value)
+	}
+	devices := make([]rememberDevice, 0, len(tokens))
+	for _, t := range tokens {
+		devices = append(devices, rememberDevice{
+			ID:       t.Id,
+			Device:   t.Device,
+			IP:       t.IP,
+			Created:  prefs.formatTime(time.Unix(t.Created, 0)),
+			LastUsed: prefs.formatTime(time.Unix(t.LastUsed, 0)),
+			Current:  t.Selector == selector,
+		})
+	}
+	return devices, nil
+}
+
+// revokeDevice forgets the device with the remember me token id of the user,
+// or all of their devices when id is "all".
+func revokeDevice(dbMap *gorp.DbMap, userID int64, id string) (bool, error) {
+	if id == "all" {
+		return true, models.DeleteUserRememberTokens(dbMap, userID)
+	}
+	tokenID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	return models.DeleteRememberToken(dbMap, userID, tokenID)
+}
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcstakepool/stakepooldclient"
+	"github.com/coolsnady/hcutil"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// rewardEstimateVotes is how many of the most recent votes of the pool the
+// vote reward is averaged over.
+const rewardEstimateVotes = 100
+
+// RewardEstimate is what the reward calculator expects of the tickets Amount
+// buys at TicketPrice, at least one, while PoolSize tickets are live in the
+// network.  A ticket votes before it expires with VoteProbability and, if it
+// does, in MeanVoteTime on average after it was bought, or within
+// MedianVoteTime half of the time.  VoteReward is the average reward of the
+// recent votes of the pool and UserReward what is left of it after the pool
+// fee.  They are zero while the pool hasn't voted yet.  TotalReward is the
+// expected reward of all the tickets and ReturnPercent the expected reward of
+// a ticket in percent of its price.
+type RewardEstimate struct {
+	BlockHeight     int64
+	PoolSize        uint32
+	TicketPrice     hcutil.Amount
+	PoolFees        float64
+	Amount          hcutil.Amount
+	Tickets         int64
+	VoteProbability float64
+	MeanVoteTime    time.Duration
+	MedianVoteTime  time.Duration
+	VoteReward      hcutil.Amount
+	UserReward      hcutil.Amount
+	TotalReward     hcutil.Amount
+	ReturnPercent   float64
+}
+
+// VotePercent returns VoteProbability in percent.
+func (e *RewardEstimate) VotePercent() float64 {
+	return e.VoteProbability * 100
+}
+
+// MeanVoteDays returns MeanVoteTime in days.
+func (e *RewardEstimate) MeanVoteDays() float64 {
+	return e.MeanVoteTime.Hours() / 24
+}
+
+// MedianVoteDays returns MedianVoteTime in days.
+func (e *RewardEstimate) MedianVoteDays() float64 {
+	return e.MedianVoteTime.Hours() / 24
+}
+
+// estimateReward estimates when the tickets amount buys at price vote and
+// what they earn.  Every block draws TicketsPerBlock of the poolSize live
+// tickets, so a live ticket is drawn with p = TicketsPerBlock/poolSize in each
+// block until it expires.
+func estimateReward(params *chaincfg.Params, poolSize uint32,
+	price, amount, voteReward hcutil.Amount, poolFees float64) *RewardEstimate {
+	e := &RewardEstimate{
+		PoolSize:    poolSize,
+		TicketPrice: price,
+		PoolFees:    poolFees,
+		Amount:      amount,
+		Tickets:     1,
+		VoteReward:  voteReward,
+	}
+	if price > 0 && int64(amount/price) > 1 {
+		e.Tickets = int64(amount / price)
+	}
+	if poolSize == 0 || price <= 0 {
+		return e
+	}
+
+	p := float64(params.TicketsPerBlock) / float64(poolSize)
+	if p > 1 {
+		p = 1
+	}
+	expiry := float64(params.TicketExpiry)
+	miss := math.Pow(1-p, expiry)
+	e.VoteProbability = 1 - miss
+
+	// The mean of the blocks a ticket waits, given that it is drawn within
+	// expiry blocks, and the blocks within which it is drawn half of the
+	// time.
+	meanBlocks := expiry
+	medianBlocks := expiry
+	if p == 1 {
+		meanBlocks, medianBlocks = 1, 1
+	} else if e.VoteProbability > 0 {
+		meanBlocks = 1/p - expiry*miss/e.VoteProbability
+		medianBlocks = math.Ceil(math.Log(0.5) / math.Log(1-p))
+		if medianBlocks > expiry {
+			medianBlocks = expiry
+		}
+	}
+	maturity := float64(params.TicketMaturity)
+	blockTime := float64(params.TargetTimePerBlock)
+	e.MeanVoteTime = time.Duration((maturity + meanBlocks) * blockTime)
+	e.MedianVoteTime = time.Duration((maturity + medianBlocks) * blockTime)
+
+	e.UserReward = hcutil.Amount(float64(voteReward) * (1 - poolFees/100))
+	expected := float64(e.UserReward) * e.VoteProbability
+	e.TotalReward = hcutil.Amount(expected * float64(e.Tickets))
+	e.ReturnPercent = expected * 100 / float64(price)
+	return e
+}
+
+// RewardEstimate estimates the reward of the tickets amount buys from the
+// pool size and the ticket price stakepoold reports, or the voting wallets
+// when no stakepoold answers, and the rewards of the recent votes of the pool.
+func (controller *MainController) RewardEstimate(dbMap *gorp.DbMap,
+	amount hcutil.Amount) (*RewardEstimate, error) {
+	var height int64
+	var poolSize uint32
+	var price hcutil.Amount
+	for i, conn := range controller.stakepooldConnections() {
+		poolStats, err := stakepooldclient.StakepooldGetPoolStats(conn)
+		if err != nil {
+			log.Warnf("stakepoold host %d GetPoolStats failed: %v", i, err)
+			continue
+		}
+		height = poolStats.BlockHeight
+		poolSize = poolStats.PoolSize
+		price = poolStats.StakeDifficulty
+		break
+	}
+	if poolSize == 0 {
+		if controller.RPCIsStopped() {
+			return nil, errors.New("RPC server stopped")
+		}
+		gsi, err := controller.rpcServers.GetStakeInfo()
+		if err != nil {
+			return nil, err
+		}
+		height = gsi.BlockHeight
+		poolSize = gsi.PoolSize
+		price, err = hcutil.NewAmount(gsi.Difficulty)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	voteReward, err := models.GetRecentVoteReward(dbMap, rewardEstimateVotes)
+	if err != nil {
+		return nil, err
+	}
+
+	e := estimateReward(controller.params, poolSize, price, amount,
+		hcutil.Amount(voteReward), controller.poolFees)
+	e.BlockHeight = height
+	return e, nil
+}
+
+// parseRewardAmount returns the amount of coins in the amount form value, or
+// zero when it's empty.
+func parseRewardAmount(r *http.Request) (hcutil.Amount, error) {
+	v := r.FormValue("amount")
+	if v == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) {
+		return 0, errors.New("invalid amount")
+	}
+	return hcutil.NewAmount(f)
+}
+
+// APIRewardEstimate estimates the reward of the tickets the amount form value
+// buys, or of a single ticket.
+func (controller *MainController) APIRewardEstimate(c web.C,
+	r *http.Request) (*poolapi.RewardEstimate, codes.Code, string, error) {
+	amount, err := parseRewardAmount(r)
+	if err != nil {
+		return nil, codes.InvalidArgument, "rewardestimate error", err
+	}
+	e, err := controller.RewardEstimate(controller.GetDbMap(c), amount)
+	if err != nil {
+		log.Infof("RewardEstimate failed: %v", err)
+		return nil, codes.Unavailable, "rewardestimate error",
+			errors.New("RPC server error")
+	}
+
+	estimate := &poolapi.RewardEstimate{
+		BlockHeight:     e.BlockHeight,
+		PoolSize:        e.PoolSize,
+		TicketPrice:     e.TicketPrice.ToCoin(),
+		PoolFees:        e.PoolFees,
+		Amount:          e.Amount.ToCoin(),
+		Tickets:         e.Tickets,
+		VoteProbability: e.VoteProbability,
+		MeanVoteTime:    int64(e.MeanVoteTime / time.Second),
+		MedianVoteTime:  int64(e.MedianVoteTime / time.Second),
+		VoteReward:      e.VoteReward.ToCoin(),
+		UserReward:      e.UserReward.ToCoin(),
+		TotalReward:     e.TotalReward.ToCoin(),
+		ReturnPercent:   e.ReturnPercent,
+	}
+	currency := controller.requestCurrency(c, r)
+	if fiat := controller.fiatValue(estimate.UserReward, currency); fiat != nil {
+		estimate.FiatCurrency = fiat.Currency
+		estimate.UserRewardFiat = fiat.Amount
+	}
+	if fiat := controller.fiatValue(estimate.TotalReward, currency); fiat != nil {
+		estimate.FiatCurrency = fiat.Currency
+		estimate.TotalRewardFiat = fiat.Amount
+	}
+
+	return estimate, codes.OK, "reward estimate successfully calculated", nil
+}
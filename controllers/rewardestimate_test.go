@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcutil"
+)
+
+func TestEstimateReward(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	poolSize := uint32(params.TicketsPerBlock) * 1000
+	price := hcutil.Amount(100 * 1e8)
+
+	e := estimateReward(params, poolSize, price, 250*1e8, 2*1e8, 5)
+	if e.Tickets != 2 {
+		t.Errorf("%d tickets, want 2", e.Tickets)
+	}
+	// A ticket is drawn with p = 1/1000 in each block.
+	wantProbability := 1 - math.Pow(0.999, float64(params.TicketExpiry))
+	if math.Abs(e.VoteProbability-wantProbability) > 1e-9 {
+		t.Errorf("vote probability %v, want %v", e.VoteProbability,
+			wantProbability)
+	}
+	maturity := time.Duration(params.TicketMaturity) * params.TargetTimePerBlock
+	if e.MeanVoteTime <= maturity || e.MedianVoteTime <= maturity ||
+		e.MeanVoteTime > maturity+1000*params.TargetTimePerBlock {
+		t.Errorf("mean vote time %v median %v", e.MeanVoteTime,
+			e.MedianVoteTime)
+	}
+	if d := e.UserReward - hcutil.Amount(1.9*1e8); d < -1 || d > 1 {
+		t.Errorf("user reward %v, want 1.9", e.UserReward)
+	}
+	wantTotal := hcutil.Amount(1.9 * 1e8 * wantProbability * 2)
+	if d := e.TotalReward - wantTotal; d < -1 || d > 1 {
+		t.Errorf("total reward %v, want %v", e.TotalReward, wantTotal)
+	}
+
+	// Less than a ticket is estimated for one ticket, and no pool size
+	// gives no estimate.
+	e = estimateReward(params, 0, price, 0, 2*1e8, 5)
+	if e.Tickets != 1 || e.VoteProbability != 0 || e.TotalReward != 0 {
+		t.Errorf("estimate without a pool size: %+v", e)
+	}
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+)
+
+const (
+	// securityEventsShown is how many of the last security events of a
+	// user are listed on the settings page.
+	securityEventsShown = 20
+
+	// securityEventRetention is how long security events are kept.
+	securityEventRetention = 365 * 24 * time.Hour
+
+	// apiTokenUseInterval is how long the uses of an API token from the
+	// same IP address and user agent are recorded as one event.
+	apiTokenUseInterval = time.Hour
+
+	// maxUserAgentLen is the longest user agent stored for an event.
+	maxUserAgentLen = 255
+)
+
+// securityEventDescriptions describe the kinds of security events to users.
+var securityEventDescriptions = map[string]string{
+	models.SecurityEventLogin:    "Signed in",
+	models.SecurityEventPassword: "Password changed",
+	models.SecurityEventAPIToken: "API token used",
+	models.SecurityEventAddress:  "Voting address submitted",
+}
+
+// securityEventRow is a security event as listed on the settings page.
+type securityEventRow struct {
+	Event     string
+	Details   string
+	IP        string
+	UserAgent string
+	Time      string
+}
+
+// securityUserAgent returns the user agent of r as it is stored for an event.
+func securityUserAgent(r *http.Request) string {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLen {
+		userAgent = userAgent[:maxUserAgentLen]
+	}
+	return userAgent
+}
+
+// recordSecurityEvent records an event of kind of the user with userID caused
+// by r at now, and forgets the events older than securityEventRetention.  The
+// event already happened, so failures are only logged.
+func (controller *MainController) recordSecurityEvent(dbMap *gorp.DbMap,
+	r *http.Request, userID int64, kind, details string, now time.Time) {
+	err := models.InsertSecurityEvent(dbMap, &models.SecurityEvent{
+		UserId:    userID,
+		Event:     kind,
+		IP:        getClientIP(r, controller.realIPHeader),
+		UserAgent: securityUserAgent(r),
+		Details:   details,
+		Created:   now.Unix(),
+	})
+	if err != nil {
+		log.Errorf("unable to record %s security event of userid %v: %v",
+			kind, userID, err)
+		return
+	}
+	err = models.PruneSecurityEvents(dbMap, userID,
+		now.Add(-securityEventRetention).Unix())
+	if err != nil {
+		log.Errorf("unable to prune security events of userid %v: %v",
+			userID, err)
+	}
+}
+
+// recordAPITokenUse records that the API token of the user making the API
+// request r was used, once per apiTokenUseInterval for each IP address and
+// user agent so every call doesn't add an event.
+func (controller *MainController) recordAPITokenUse(c web.C, r *http.Request) {
+	userID, ok := c.Env["APIUserID"].(int64)
+	if !ok {
+		return
+	}
+	dbMap := controller.GetDbMap(c)
+	now := time.Now()
+	last, err := models.GetLastSecurityEvent(dbMap, userID,
+		models.SecurityEventAPIToken, getClientIP(r, controller.realIPHeader),
+		securityUserAgent(r))
+	if err != nil {
+		log.Errorf("GetLastSecurityEvent failed for userid %v: %v", userID,
+			err)
+		return
+	}
+	if last != nil && now.Sub(time.Unix(last.Created, 0)) < apiTokenUseInterval {
+		return
+	}
+	controller.recordSecurityEvent(dbMap, r, userID,
+		models.SecurityEventAPIToken, c.URLParams["command"], now)
+}
+
+// securityEvents returns the last security events of the user of prefs, with
+// the times in their time zone.
+func (controller *MainController) securityEvents(dbMap *gorp.DbMap,
+	prefs *preferences) ([]securityEventRow, error) {
+	events, err := models.GetUserSecurityEvents(dbMap, prefs.UserId,
+		securityEventsShown)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]securityEventRow, 0, len(events))
+	for _, e := range events {
+		description, ok := securityEventDescriptions[e.Event]
+		if !ok {
+			description = e.Event
+		}
+		rows = append(rows, securityEventRow{
+			Event:     description,
+			Details:   e.Details,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			Time:      prefs.formatTime(time.Unix(e.Created, 0)),
+		})
+	}
+	return rows, nil
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcstakepool/scrub"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// WalletsStakeInfo is the stake information of all voting wallets, both per
+// wallet and merged.
+type WalletsStakeInfo struct {
+	Merged *dcrjson.GetStakeInfoResult
+	// Wallets and Errors are indexed by wallet.  A wallet has either a
+	// result or an error.
+	Wallets []*dcrjson.GetStakeInfoResult
+	Errors  []error
+	// Disagreements names the ticket counts the wallets don't agree on.
+	Disagreements []string
+}
+
+// stakeInfoCounts are the ticket counts every wallet should agree on.
+var stakeInfoCounts = []struct {
+	name  string
+	field func(*dcrjson.GetStakeInfoResult) *uint32
+}{
+	{"Live", func(r *dcrjson.GetStakeInfoResult) *uint32 { return &r.Live }},
+	{"Immature", func(r *dcrjson.GetStakeInfoResult) *uint32 { return &r.Immature }},
+	{"OwnMempoolTix", func(r *dcrjson.GetStakeInfoResult) *uint32 { return &r.OwnMempoolTix }},
+	{"Voted", func(r *dcrjson.GetStakeInfoResult) *uint32 { return &r.Voted }},
+	{"Missed", func(r *dcrjson.GetStakeInfoResult) *uint32 { return &r.Missed }},
+	{"Revoked", func(r *dcrjson.GetStakeInfoResult) *uint32 { return &r.Revoked }},
+	{"Expired", func(r *dcrjson.GetStakeInfoResult) *uint32 { return &r.Expired }},
+}
+
+// mergeStakeInfo merges the stake information of the wallets that returned
+// any.  The chain state is taken from the wallet with the best block and each
+// ticket count is the highest any wallet reported, since a ticket counted by
+// one wallet exists even if the others missed it.  The names of the counts the
+// wallets disagree on are returned along with the merged result, which is nil
+// if there are no results.
+func mergeStakeInfo(infos []*dcrjson.GetStakeInfoResult) (*dcrjson.GetStakeInfoResult, []string) {
+	var best *dcrjson.GetStakeInfoResult
+	for _, info := range infos {
+		if info != nil && (best == nil || info.BlockHeight > best.BlockHeight) {
+			best = info
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	merged := *best
+
+	var disagreements []string
+	for _, c := range stakeInfoCounts {
+		mergedCount := c.field(&merged)
+		var disagree bool
+		for _, info := range infos {
+			if info == nil {
+				continue
+			}
+			count := *c.field(info)
+			if count != *c.field(best) {
+				disagree = true
+			}
+			if count > *mergedCount {
+				*mergedCount = count
+			}
+		}
+		if disagree {
+			disagreements = append(disagreements, c.name)
+		}
+	}
+
+	merged.ProportionLive = 0
+	if merged.PoolSize > 0 {
+		merged.ProportionLive = float64(merged.Live) / float64(merged.PoolSize)
+	}
+	merged.ProportionMissed = 0
+	if merged.Voted+merged.Missed > 0 {
+		merged.ProportionMissed = float64(merged.Missed) /
+			float64(merged.Voted+merged.Missed)
+	}
+
+	return &merged, disagreements
+}
+
+// StakeInfoAll returns the stake information of every voting wallet.
+func (controller *MainController) StakeInfoAll() (*WalletsStakeInfo, error) {
+	if controller.RPCIsStopped() {
+		return nil, errors.New("RPC server stopped")
+	}
+
+	infos, errs := controller.rpcServers.StakeInfoAll()
+	merged, disagreements := mergeStakeInfo(infos)
+	if merged == nil {
+		return nil, errors.New("no wallet returned stake info")
+	}
+	if len(disagreements) > 0 {
+		log.Warnf("wallets disagree about ticket counts: %v", disagreements)
+	}
+
+	return &WalletsStakeInfo{
+		Merged:        merged,
+		Wallets:       infos,
+		Errors:        errs,
+		Disagreements: disagreements,
+	}, nil
+}
+
+// apiWalletStakeInfo converts the stake information of a wallet to its API
+// representation.
+func apiWalletStakeInfo(wallet int, info *dcrjson.GetStakeInfoResult,
+	err error) poolapi.WalletStakeInfo {
+	if info == nil {
+		wsi := poolapi.WalletStakeInfo{Wallet: wallet}
+		if err != nil {
+			wsi.Error = scrub.String(err.Error())
+		}
+		return wsi
+	}
+	return poolapi.WalletStakeInfo{
+		Wallet:           wallet,
+		Connected:        true,
+		BlockHeight:      info.BlockHeight,
+		Live:             info.Live,
+		Immature:         info.Immature,
+		OwnMempoolTix:    info.OwnMempoolTix,
+		Voted:            info.Voted,
+		Missed:           info.Missed,
+		Revoked:          info.Revoked,
+		Expired:          info.Expired,
+		ProportionLive:   info.ProportionLive,
+		ProportionMissed: info.ProportionMissed,
+	}
+}
+
+// APIStakeInfo returns the merged and per wallet stake information of the
+// voting wallets.  It is only available to admins.
+func (controller *MainController) APIStakeInfo(c web.C,
+	r *http.Request) (*poolapi.StakeInfo, codes.Code, string, error) {
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "stakeinfo error", errors.New("not an admin")
+	}
+
+	wsi, err := controller.StakeInfoAll()
+	if err != nil {
+		log.Infof("StakeInfoAll failed: %v", err)
+		return nil, codes.Unavailable, "stakeinfo error", errors.New("RPC server error")
+	}
+
+	stakeInfo := &poolapi.StakeInfo{
+		Merged:        apiWalletStakeInfo(-1, wsi.Merged, nil),
+		Wallets:       make([]poolapi.WalletStakeInfo, len(wsi.Wallets)),
+		Disagreements: wsi.Disagreements,
+	}
+	for i := range wsi.Wallets {
+		stakeInfo.Wallets[i] = apiWalletStakeInfo(i, wsi.Wallets[i],
+			wsi.Errors[i])
+	}
+
+	return stakeInfo, codes.OK, "stakeinfo successfully retrieved", nil
+}
@@ -0,0 +1,413 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/notifier"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcstakepool/stakepooldclient"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// minStakepooldBackends is the fewest stakepoold backends the pool runs with,
+// as required by the stakepooldhosts config option.
+const minStakepooldBackends = 2
+
+// StakepooldDialer connects to the stakepoold at host, a host:port address,
+// whose TLS certificate is at the path cert.  The connection uses the
+// stakepoold options of the config, such as the token and proxy.
+type StakepooldDialer func(host, cert string) (*grpc.ClientConn, error)
+
+// stakepooldConnections returns the connections to the stakepoold backends
+// currently in rotation.  Backends added or retired afterwards don't change
+// the returned slice.
+func (controller *MainController) stakepooldConnections() []*grpc.ClientConn {
+	_, conns := controller.stakepooldBackends()
+	return conns
+}
+
+// stakepooldBackends returns the addresses of the stakepoold backends in
+// rotation along with the connections to them.
+func (controller *MainController) stakepooldBackends() ([]string, []*grpc.ClientConn) {
+	controller.grpcConnectionsMtx.RLock()
+	defer controller.grpcConnectionsMtx.RUnlock()
+
+	hosts := make([]string, len(controller.stakepooldHosts))
+	copy(hosts, controller.stakepooldHosts)
+	conns := make([]*grpc.ClientConn, len(controller.grpcConnections))
+	copy(conns, controller.grpcConnections)
+	return hosts, conns
+}
+
+// stakepooldHealthy returns why a new stakepoold reporting status can't be
+// put into rotation, or nil if it can.  height is the highest block height
+// reported by the backends already in rotation.
+func stakepooldHealthy(status *stakepooldclient.Status, height int64) error {
+	switch {
+	case !status.NodeConnected:
+		return errors.New("stakepoold is not connected to hcd")
+	case !status.WalletConnected:
+		return errors.New("stakepoold is not connected to hcwallet")
+	case status.BlockHeight < height:
+		return fmt.Errorf("hcd is at height %d, behind the other backends "+
+			"at %d", status.BlockHeight, height)
+	case status.WalletHeight != status.BlockHeight:
+		return fmt.Errorf("hcwallet is at height %d, behind hcd at %d",
+			status.WalletHeight, status.BlockHeight)
+	}
+	return nil
+}
+
+// AddStakepoold connects to the stakepoold at host using the certificate at
+// cert and puts it into rotation once it is healthy and has been sent the
+// voting preferences of the users and the low fee tickets.  The backend is
+// only kept until the frontend restarts unless it is also added to the
+// config.
+func (controller *MainController) AddStakepoold(ctx context.Context,
+	dbMap *gorp.DbMap, host, cert string) error {
+	if controller.stakepooldDialer == nil {
+		return errors.New("stakepoold is not enabled")
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return fmt.Errorf("invalid host %q: %v", host, err)
+	}
+	hosts, conns := controller.stakepooldBackends()
+	if stringSliceContains(hosts, host) {
+		return fmt.Errorf("stakepoold %v is already in rotation", host)
+	}
+
+	var height int64
+	for i, conn := range conns {
+		status, err := stakepooldclient.StakepooldGetStatus(ctx, conn)
+		if err != nil {
+			log.Warnf("stakepoold host %d GetStatus failed: %v", i, err)
+			continue
+		}
+		if status.BlockHeight > height {
+			height = status.BlockHeight
+		}
+	}
+
+	conn, err := controller.stakepooldDialer(host, cert)
+	if err != nil {
+		return err
+	}
+	err = controller.prepareStakepoold(ctx, dbMap, conn, height)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	controller.grpcConnectionsMtx.Lock()
+	if stringSliceContains(controller.stakepooldHosts, host) {
+		controller.grpcConnectionsMtx.Unlock()
+		conn.Close()
+		return fmt.Errorf("stakepoold %v is already in rotation", host)
+	}
+	controller.stakepooldHosts = append(controller.stakepooldHosts, host)
+	controller.grpcConnections = append(controller.grpcConnections, conn)
+	controller.grpcConnectionsMtx.Unlock()
+
+	log.Infof("Added stakepoold %v to rotation", host)
+	return nil
+}
+
+// prepareStakepoold checks the health of the new stakepoold connected to by
+// conn and sends it the data the backends in rotation already have.
+func (controller *MainController) prepareStakepoold(ctx context.Context,
+	dbMap *gorp.DbMap, conn *grpc.ClientConn, height int64) error {
+	status, err := stakepooldclient.StakepooldGetStatus(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err = stakepooldHealthy(status, height); err != nil {
+		return err
+	}
+	if _, err = controller.VerifyColdWalletExtPub(conn); err != nil {
+		return err
+	}
+
+	votableLowFeeTickets, err := models.GetVotableLowFeeTickets(dbMap)
+	if err != nil {
+		return err
+	}
+	success, err := stakepooldclient.StakepooldSetAddedLowFeeTickets(ctx,
+		conn, votableLowFeeTickets)
+	if err != nil {
+		return err
+	}
+	if !success {
+		return errors.New("unable to set the low fee tickets, stakepoold " +
+			"update would have blocked")
+	}
+
+	allUsers, err := controller.CheckAndResetUserVoteBits(dbMap)
+	if err != nil {
+		return err
+	}
+	success, err = stakepooldclient.StakepooldSetUserVotingPrefs(ctx, conn,
+		allUsers)
+	if err != nil {
+		return err
+	}
+	if !success {
+		return errors.New("unable to set the voting preferences, " +
+			"stakepoold update would have blocked")
+	}
+	return nil
+}
+
+// VerifyColdWalletExtPub checks that the stakepoold of conn accepts the stake
+// pool fees of tickets paying the fee addresses of the frontend, so users
+// aren't given fee addresses whose tickets stakepoold won't vote, and returns
+// the first fee address.  stakepoold must be configured with the same
+// coldwalletextpub and derive the same first fee address from it.  Versions
+// that can't be asked are refused too.
+func (controller *MainController) VerifyColdWalletExtPub(conn *grpc.ClientConn) (string, error) {
+	feeAddr, err := controller.FeeAddressForUserID(0)
+	if err != nil {
+		return "", err
+	}
+	addr, err := stakepooldclient.StakepooldVerifyColdWalletExtPub(conn,
+		controller.feeXpub.String())
+	if grpc.Code(err) == codes.Unimplemented {
+		return "", errors.New("stakepoold can't verify the " +
+			"coldwalletextpub, upgrade it to API 4.10.0 or later")
+	}
+	if err != nil {
+		return "", err
+	}
+	if addr != feeAddr.EncodeAddress() {
+		return "", fmt.Errorf("stakepoold derives fee address %s from "+
+			"coldwalletextpub but hcstakepool derives %s", addr,
+			feeAddr.EncodeAddress())
+	}
+	return addr, nil
+}
+
+// RemoveStakepoold takes the stakepoold at host out of rotation and closes
+// the connection to it.  The pool keeps at least minStakepooldBackends
+// backends.
+func (controller *MainController) RemoveStakepoold(host string) error {
+	controller.grpcConnectionsMtx.Lock()
+	i := -1
+	for j, h := range controller.stakepooldHosts {
+		if h == host {
+			i = j
+			break
+		}
+	}
+	if i == -1 {
+		controller.grpcConnectionsMtx.Unlock()
+		return fmt.Errorf("stakepoold %v is not in rotation", host)
+	}
+	if len(controller.stakepooldHosts) <= minStakepooldBackends {
+		controller.grpcConnectionsMtx.Unlock()
+		return fmt.Errorf("at least %d stakepoold backends are required",
+			minStakepooldBackends)
+	}
+	conn := controller.grpcConnections[i]
+
+	// Build new slices since callers may still be using the old ones.
+	hosts := make([]string, 0, len(controller.stakepooldHosts)-1)
+	hosts = append(hosts, controller.stakepooldHosts[:i]...)
+	controller.stakepooldHosts = append(hosts,
+		controller.stakepooldHosts[i+1:]...)
+	conns := make([]*grpc.ClientConn, 0, len(controller.grpcConnections)-1)
+	conns = append(conns, controller.grpcConnections[:i]...)
+	controller.grpcConnections = append(conns,
+		controller.grpcConnections[i+1:]...)
+	controller.grpcConnectionsMtx.Unlock()
+
+	log.Infof("Removed stakepoold %v from rotation", host)
+	return conn.Close()
+}
+
+// RescanStakepooldWallet makes the wallet of the stakepoold at host rescan
+// the chain from height, e.g. after it was restored from seed.  The rescan
+// runs in the background with its progress logged, and the operators are
+// notified when it is done.
+func (controller *MainController) RescanStakepooldWallet(host string,
+	height int64) error {
+	hosts, conns := controller.stakepooldBackends()
+	var conn *grpc.ClientConn
+	for i, h := range hosts {
+		if h == host {
+			conn = conns[i]
+			break
+		}
+	}
+	if conn == nil {
+		return fmt.Errorf("stakepoold %v is not in rotation", host)
+	}
+	version, err := stakepooldclient.StakepooldVersion(conn)
+	if err != nil {
+		return err
+	}
+	if !version.Supports(stakepooldclient.CapabilityWalletRescan) {
+		return fmt.Errorf("stakepoold %v can't rescan its wallet, it "+
+			"runs API version %v", host, version.API)
+	}
+
+	go func() {
+		err := stakepooldclient.StakepooldRescanWallet(context.Background(),
+			conn, height, func(p *stakepooldclient.RescanProgress) {
+				if !p.Done {
+					log.Infof("Wallet of stakepoold %v is rescanning "+
+						"from height %d to %d (%v so far)", host,
+						p.BeginHeight, p.BestHeight, p.Elapsed)
+					return
+				}
+				text := fmt.Sprintf("The wallet of stakepoold %s of the "+
+					"stake pool at %s was rescanned from height %d in "+
+					"%v.  stakepoold tracks %d live tickets.", host,
+					controller.baseURL, p.BeginHeight, p.Elapsed,
+					p.LiveTickets)
+				severity := notifier.SeverityInfo
+				if len(p.FlaggedTickets) > 0 {
+					text += fmt.Sprintf("  %d live tickets of users are "+
+						"still unknown to the wallet.",
+						len(p.FlaggedTickets))
+					severity = notifier.SeverityWarning
+				}
+				log.Info(text)
+				controller.notifyOperators(severity, text)
+			})
+		if err != nil {
+			text := fmt.Sprintf("The wallet rescan of stakepoold %s of the "+
+				"stake pool at %s failed: %v", host, controller.baseURL, err)
+			log.Error(text)
+			controller.notifyOperators(notifier.SeverityWarning, text)
+		}
+	}()
+	return nil
+}
+
+// AdminStakepooldPost adds, retires or rescans the wallet of the stakepoold
+// backend posted from AdminStakepoold.
+func (controller *MainController) AdminStakepooldPost(c web.C, r *http.Request) (string, int) {
+	session := controller.GetSession(c)
+	dbMap := controller.GetDbMap(c)
+
+	isAdmin, err := controller.isAdmin(c, r)
+	if !isAdmin {
+		log.Warnf("isAdmin check failed: %v", err)
+		return "", http.StatusUnauthorized
+	}
+
+	host := r.FormValue("host")
+	switch r.FormValue("action") {
+	case "add":
+		err = controller.AddStakepoold(r.Context(), dbMap, host,
+			r.FormValue("cert"))
+		if err == nil {
+			controller.audit(dbMap, c, r, AuditActionStakepooldAdd, host,
+				nil, host)
+			session.AddFlash("Added "+host, "adminStakepooldSuccess")
+		}
+	case "remove":
+		err = controller.RemoveStakepoold(host)
+		if err == nil {
+			controller.audit(dbMap, c, r, AuditActionStakepooldRemove, host,
+				host, nil)
+			session.AddFlash("Retired "+host, "adminStakepooldSuccess")
+		}
+	case "rescan":
+		var height int64
+		height, err = strconv.ParseInt(r.FormValue("height"), 10, 64)
+		if err != nil || height < 0 {
+			err = errors.New("invalid rescan height")
+			break
+		}
+		err = controller.RescanStakepooldWallet(host, height)
+		if err == nil {
+			controller.audit(dbMap, c, r, AuditActionStakepooldRescan,
+				host, nil, height)
+			session.AddFlash(fmt.Sprintf("Rescanning the wallet of %s "+
+				"from height %d", host, height), "adminStakepooldSuccess")
+		}
+	default:
+		err = errors.New("invalid action")
+	}
+	if err != nil {
+		log.Warnf("unable to change stakepoold %v: %v", host, err)
+		session.AddFlash("Unable to change stakepoold "+host+": "+
+			err.Error(), "adminStakepooldError")
+	}
+	return "/adminstakepoold", http.StatusSeeOther
+}
+
+// apiStakepooldBackends returns the stakepoold backends in rotation.
+func (controller *MainController) apiStakepooldBackends() []poolapi.StakepooldBackend {
+	hosts, conns := controller.stakepooldBackends()
+	backends := make([]poolapi.StakepooldBackend, 0, len(conns))
+	for i, conn := range conns {
+		backends = append(backends, poolapi.StakepooldBackend{
+			Host:       hosts[i],
+			Connection: grpcConnectionState(conn),
+		})
+	}
+	return backends
+}
+
+// APIStakepoold lists the stakepoold backends in rotation.  It is only
+// available to admins.
+func (controller *MainController) APIStakepoold(c web.C,
+	r *http.Request) ([]poolapi.StakepooldBackend, codes.Code, string, error) {
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "stakepoold error", errors.New("not an admin")
+	}
+	return controller.apiStakepooldBackends(), codes.OK,
+		"stakepoold successfully retrieved", nil
+}
+
+// APIStakepooldAdd puts the stakepoold at Host, using the certificate at the
+// path Cert, into rotation and returns the backends in rotation.  It is only
+// available to admins.
+func (controller *MainController) APIStakepooldAdd(c web.C,
+	r *http.Request) ([]poolapi.StakepooldBackend, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "stakepooldadd error", errors.New("not an admin")
+	}
+	host := r.FormValue("Host")
+	err := controller.AddStakepoold(r.Context(), dbMap, host,
+		r.FormValue("Cert"))
+	if err != nil {
+		log.Warnf("unable to add stakepoold %v: %v", host, err)
+		return nil, codes.FailedPrecondition, "stakepooldadd error", err
+	}
+	controller.audit(dbMap, c, r, AuditActionStakepooldAdd, host, nil, host)
+	return controller.apiStakepooldBackends(), codes.OK,
+		"stakepoold successfully added", nil
+}
+
+// APIStakepooldRemove retires the stakepoold at Host and returns the backends
+// left in rotation.  It is only available to admins.
+func (controller *MainController) APIStakepooldRemove(c web.C,
+	r *http.Request) ([]poolapi.StakepooldBackend, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if !controller.isAdminAPI(c, r) {
+		return nil, codes.PermissionDenied, "stakepooldremove error", errors.New("not an admin")
+	}
+	host := r.FormValue("Host")
+	err := controller.RemoveStakepoold(host)
+	if err != nil {
+		log.Warnf("unable to remove stakepoold %v: %v", host, err)
+		return nil, codes.FailedPrecondition, "stakepooldremove error", err
+	}
+	controller.audit(dbMap, c, r, AuditActionStakepooldRemove, host, host, nil)
+	return controller.apiStakepooldBackends(), codes.OK,
+		"stakepoold successfully removed", nil
+}
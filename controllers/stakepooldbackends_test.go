@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcstakepool/stakepooldclient"
+)
+
+func TestStakepooldHealthy(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  stakepooldclient.Status
+		height  int64
+		healthy bool
+	}{
+		{"synced", stakepooldclient.Status{NodeConnected: true,
+			WalletConnected: true, BlockHeight: 100, WalletHeight: 100},
+			100, true},
+		{"first backend", stakepooldclient.Status{NodeConnected: true,
+			WalletConnected: true, BlockHeight: 100, WalletHeight: 100},
+			0, true},
+		{"no hcd", stakepooldclient.Status{WalletConnected: true,
+			BlockHeight: 100, WalletHeight: 100}, 100, false},
+		{"no hcwallet", stakepooldclient.Status{NodeConnected: true,
+			BlockHeight: 100, WalletHeight: 100}, 100, false},
+		{"hcd behind", stakepooldclient.Status{NodeConnected: true,
+			WalletConnected: true, BlockHeight: 99, WalletHeight: 99},
+			100, false},
+		{"hcwallet behind", stakepooldclient.Status{NodeConnected: true,
+			WalletConnected: true, BlockHeight: 100, WalletHeight: 98},
+			100, false},
+	}
+	for _, test := range tests {
+		err := stakepooldHealthy(&test.status, test.height)
+		if (err == nil) != test.healthy {
+			t.Errorf("%s: expected healthy %v, got error %v", test.name,
+				test.healthy, err)
+		}
+	}
+}
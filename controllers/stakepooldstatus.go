@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/coolsnady/hcstakepool/stakepooldclient"
+	"github.com/zenazn/goji/web"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// StakepooldBackend is a stakepoold as shown on the admin stakepoold page.
+// Status and Version are nil when the calls for them failed, with the reason
+// in Error.  Mismatches holds the names of the fields that differ from the
+// other backends.
+type StakepooldBackend struct {
+	Host       int
+	Address    string
+	Connection string
+	Status     *stakepooldclient.Status
+	Version    *stakepooldclient.Version
+	Error      string
+	Mismatches map[string]bool
+}
+
+// grpcConnectionState returns the name of the state of conn.
+func grpcConnectionState(conn *grpc.ClientConn) string {
+	switch conn.GetState() {
+	case connectivity.Idle:
+		return "Idle"
+	case connectivity.Shutdown:
+		return "Shutdown"
+	case connectivity.Ready:
+		return "Ready"
+	case connectivity.Connecting:
+		return "Connecting"
+	case connectivity.TransientFailure:
+		return "TransientFailure"
+	default:
+		return "Unknown"
+	}
+}
+
+// stakepooldFields returns the values of the fields of b that are compared
+// between backends, leaving out those of the calls that failed.
+func stakepooldFields(b *StakepooldBackend) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if s := b.Status; s != nil {
+		fields["BlockHeight"] = s.BlockHeight
+		fields["WalletHeight"] = s.WalletHeight
+		fields["Users"] = s.Users
+		fields["LiveTickets"] = s.LiveTickets
+		fields["AddedLowFeeTickets"] = s.AddedLowFeeTickets
+		fields["IgnoredLowFeeTickets"] = s.IgnoredLowFeeTickets
+	}
+	if v := b.Version; v != nil {
+		fields["API"] = v.API
+		fields["App"] = v.App
+	}
+	return fields
+}
+
+// stakepooldMismatches sets the Mismatches of the backends.  A field of a
+// backend mismatches when its value differs from the value most backends
+// have, or when no value is more common than the others.  The wallet height
+// also mismatches when it differs from the block height of the same backend,
+// which means the wallet isn't synced.
+func stakepooldMismatches(backends []StakepooldBackend) {
+	values := make([]map[string]interface{}, len(backends))
+	counts := make(map[string]map[interface{}]int)
+	for i := range backends {
+		values[i] = stakepooldFields(&backends[i])
+		for name, v := range values[i] {
+			if counts[name] == nil {
+				counts[name] = make(map[interface{}]int)
+			}
+			counts[name][v]++
+		}
+	}
+
+	for i := range backends {
+		backends[i].Mismatches = make(map[string]bool)
+		for name, v := range values[i] {
+			n := counts[name][v]
+			for other, m := range counts[name] {
+				if other != v && m >= n {
+					backends[i].Mismatches[name] = true
+				}
+			}
+		}
+		s := backends[i].Status
+		if s != nil && s.WalletHeight != s.BlockHeight {
+			backends[i].Mismatches["WalletHeight"] = true
+		}
+	}
+}
+
+// AdminStakepoold renders the state of every stakepoold backend, with the
+// values that differ between them highlighted, and the forms to add and
+// retire backends.
+func (controller *MainController) AdminStakepoold(c web.C, r *http.Request) (string, int) {
+	isAdmin, err := controller.isAdmin(c, r)
+	if !isAdmin {
+		log.Warnf("isAdmin check failed: %v", err)
+		return "", http.StatusUnauthorized
+	}
+
+	hosts, conns := controller.stakepooldBackends()
+	backends := make([]StakepooldBackend, len(conns))
+	for i, conn := range conns {
+		b := StakepooldBackend{
+			Host:       i,
+			Address:    hosts[i],
+			Connection: grpcConnectionState(conn),
+		}
+		var errs []error
+		b.Version, err = stakepooldclient.StakepooldVersion(conn)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		b.Status, err = stakepooldclient.StakepooldGetStatus(r.Context(),
+			conn)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if len(errs) > 0 {
+			log.Warnf("stakepoold host %d status failed: %v", i, errs)
+			b.Error = fmt.Sprint(errs)
+		}
+		backends[i] = b
+	}
+	stakepooldMismatches(backends)
+
+	t := controller.GetTemplate(c)
+	session := controller.GetSession(c)
+	c.Env["Admin"] = isAdmin
+	c.Env["IsAdminStakepoold"] = true
+	c.Env["Backends"] = backends
+	c.Env["FlashError"] = session.Flashes("adminStakepooldError")
+	c.Env["FlashSuccess"] = session.Flashes("adminStakepooldSuccess")
+
+	widgets := controller.Parse(t, "admin/stakepoold", c.Env)
+
+	c.Env["Title"] = "Hcd Stake Pool - Stakepoold (Admin)"
+	c.Env["Content"] = template.HTML(widgets)
+
+	return controller.Parse(t, "main", c.Env), http.StatusOK
+}
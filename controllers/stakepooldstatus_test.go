@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcstakepool/stakepooldclient"
+)
+
+func TestStakepooldMismatches(t *testing.T) {
+	status := func(height, walletHeight int64, live uint32) *stakepooldclient.Status {
+		return &stakepooldclient.Status{
+			BlockHeight:  height,
+			WalletHeight: walletHeight,
+			Users:        10,
+			LiveTickets:  live,
+		}
+	}
+	version := &stakepooldclient.Version{API: "4.9.0", App: "1.2.0"}
+	backends := []StakepooldBackend{
+		{Status: status(100, 100, 5), Version: version},
+		{Status: status(100, 99, 5), Version: version},
+		{Status: status(99, 100, 4),
+			Version: &stakepooldclient.Version{API: "4.8.0", App: "1.2.0"}},
+		{Error: "unavailable"},
+	}
+	stakepooldMismatches(backends)
+
+	tests := []struct {
+		backend    int
+		mismatches []string
+	}{
+		{0, nil},
+		{1, []string{"WalletHeight"}},
+		{2, []string{"BlockHeight", "WalletHeight", "LiveTickets", "API"}},
+		{3, nil},
+	}
+	for _, test := range tests {
+		m := backends[test.backend].Mismatches
+		if len(m) != len(test.mismatches) {
+			t.Errorf("backend %d: expected mismatches %v, got %v",
+				test.backend, test.mismatches, m)
+			continue
+		}
+		for _, name := range test.mismatches {
+			if !m[name] {
+				t.Errorf("backend %d: expected mismatches %v, got %v",
+					test.backend, test.mismatches, m)
+			}
+		}
+	}
+
+	// Without a most common value both backends mismatch.
+	backends = backends[:2]
+	backends[1].Status = status(100, 100, 6)
+	stakepooldMismatches(backends)
+	for i := range backends {
+		if !backends[i].Mismatches["LiveTickets"] {
+			t.Errorf("backend %d: expected live tickets mismatch, got %v",
+				i, backends[i].Mismatches)
+		}
+	}
+}
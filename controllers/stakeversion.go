@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/notifier"
+	"github.com/go-gorp/gorp"
+)
+
+// stakeVersionIntervals is how many of the last stake version intervals the
+// versions of the network are counted over.
+const stakeVersionIntervals = 2
+
+// leadingVersion returns the highest version of counts that has at least
+// percent of the total count, and its share in percent.  ok is false when
+// there is no such version.
+func leadingVersion(counts []dcrjson.VersionCount, percent int64) (version uint32, share int64, ok bool) {
+	totals := make(map[uint32]int64)
+	var total int64
+	for _, c := range counts {
+		totals[c.Version] += int64(c.Count)
+		total += int64(c.Count)
+	}
+	if total == 0 {
+		return 0, 0, false
+	}
+
+	versions := make([]uint32, 0, len(totals))
+	for v := range totals {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	for _, v := range versions {
+		if totals[v]*100 >= percent*total {
+			return v, totals[v] * 100 / total, true
+		}
+	}
+	return 0, 0, false
+}
+
+// stakeVersionLag describes how the voting wallets and the users' voting
+// preferences lag the vote version the network is moving to, or is empty when
+// they don't.
+func (controller *MainController) stakeVersionLag(dbMap *gorp.DbMap) (string, error) {
+	info, err := controller.rpcServers.GetStakeVersionInfo(stakeVersionIntervals)
+	if err != nil {
+		return "", err
+	}
+	var voteVersions, stakeVersions []dcrjson.VersionCount
+	for _, interval := range info.Intervals {
+		voteVersions = append(voteVersions, interval.VoterVersions...)
+		stakeVersions = append(stakeVersions, interval.PoSVersions...)
+	}
+	voteVersion, voteShare, ok := leadingVersion(voteVersions,
+		controller.stakeVersionWarn)
+	if !ok {
+		return "", nil
+	}
+
+	var lagging []string
+	walletInfo, _ := controller.WalletStatus()
+	for i, wi := range walletInfo {
+		// Disconnected wallets are reported by checkWalletConnections.
+		if wi != nil && wi.VoteVersion < voteVersion {
+			lagging = append(lagging, fmt.Sprintf("voting wallet %d votes "+
+				"with vote version %d", i, wi.VoteVersion))
+		}
+	}
+	users := models.GetUserCountVoteBitsBelow(dbMap, int64(voteVersion))
+	if users > 0 {
+		lagging = append(lagging, fmt.Sprintf("the voting preferences of %d "+
+			"users are for an older vote version", users))
+	}
+	if len(lagging) == 0 {
+		return "", nil
+	}
+
+	lag := fmt.Sprintf("%d%% of the votes of the last %d stake version "+
+		"intervals up to height %d are cast with vote version %d", voteShare,
+		stakeVersionIntervals, info.CurrentHeight, voteVersion)
+	if stakeVersion, stakeShare, ok := leadingVersion(stakeVersions,
+		controller.stakeVersionWarn); ok {
+		lag += fmt.Sprintf(" and %d%% of the voters run stake version %d",
+			stakeShare, stakeVersion)
+	}
+	return lag + ", but " + strings.Join(lagging, " and "), nil
+}
+
+// checkStakeVersion notifies the operators when the voting wallets or the
+// users' voting preferences lag a vote version the network is moving to, so
+// they are upgraded before their votes count as abstaining, and when they
+// caught up.
+func (controller *MainController) checkStakeVersion(dbMap *gorp.DbMap) {
+	lag, err := controller.stakeVersionLag(dbMap)
+	if err != nil {
+		log.Warnf("Unable to get the stake version info: %v", err)
+		return
+	}
+
+	a := &controller.operatorAlerts
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if lag == a.stakeVersionLag {
+		return
+	}
+	wasLagging := a.stakeVersionLag != ""
+	a.stakeVersionLag = lag
+	if lag == "" {
+		text := fmt.Sprintf("The stake pool at %s caught up with the vote "+
+			"version of the network.", controller.baseURL)
+		log.Info(text)
+		controller.notifyOperators(notifier.SeverityInfo, text)
+		return
+	}
+	if wasLagging {
+		// Only the shares changed, which isn't worth another notification.
+		log.Warnf("The stake pool at %s lags the network: %s.",
+			controller.baseURL, lag)
+		return
+	}
+	text := fmt.Sprintf("The stake pool at %s lags the network: %s.  "+
+		"Upgrade the voting wallets before their votes count as abstaining.",
+		controller.baseURL, lag)
+	log.Warn(text)
+	controller.notifyOperators(notifier.SeverityWarning, text)
+}
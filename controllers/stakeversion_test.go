@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcd/dcrjson"
+)
+
+func TestLeadingVersion(t *testing.T) {
+	counts := []dcrjson.VersionCount{
+		{Version: 5, Count: 40},
+		{Version: 6, Count: 30},
+		{Version: 5, Count: 10},
+		{Version: 7, Count: 20},
+	}
+
+	tests := []struct {
+		percent int64
+		version uint32
+		share   int64
+		ok      bool
+	}{
+		{10, 7, 20, true},
+		{25, 6, 30, true},
+		{50, 5, 50, true},
+		{60, 0, 0, false},
+	}
+	for _, test := range tests {
+		version, share, ok := leadingVersion(counts, test.percent)
+		if version != test.version || share != test.share || ok != test.ok {
+			t.Errorf("%d%%: version %d share %d ok %v, want %d %d %v",
+				test.percent, version, share, ok, test.version, test.share,
+				test.ok)
+		}
+	}
+
+	if _, _, ok := leadingVersion(nil, 0); ok {
+		t.Error("found a leading version without votes")
+	}
+}
@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/go-gorp/gorp"
+)
+
+// telegramAPIURL is the Telegram Bot API, followed by the bot token and the
+// method called.
+const telegramAPIURL = "https://api.telegram.org/bot"
+
+const (
+	// telegramTimeout is how long Bot API calls other than long polls may
+	// take.
+	telegramTimeout = 10 * time.Second
+
+	// telegramPollTimeout is how long the bot waits for messages in one
+	// long poll.
+	telegramPollTimeout = time.Minute
+
+	// telegramRetryDelay is how long polling waits after failing.
+	telegramRetryDelay = 30 * time.Second
+)
+
+// Telegram sends notifications through a Telegram bot to the operators'
+// chats and to the chats users linked to their account.  It is safe for
+// concurrent access.
+type Telegram struct {
+	token         string
+	apiURL        string
+	operatorChats []int64
+	transport     http.RoundTripper
+
+	mtx     sync.Mutex
+	botName string
+}
+
+// NewTelegram returns a Telegram sending through the bot with token, which
+// notifies operatorChats of pool problems.  The Bot API is called through
+// transport, or http.DefaultTransport when it is nil.
+func NewTelegram(token string, operatorChats []int64,
+	transport http.RoundTripper) *Telegram {
+	return &Telegram{
+		token:         token,
+		apiURL:        telegramAPIURL,
+		operatorChats: operatorChats,
+		transport:     transport,
+	}
+}
+
+// ParseTelegramChatIDs parses the ids of Telegram chats.
+func ParseTelegramChatIDs(ids []string) ([]int64, error) {
+	chatIDs := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		chatID, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+		if err != nil || chatID == 0 {
+			return nil, fmt.Errorf("invalid Telegram chat id %q", id)
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	return chatIDs, nil
+}
+
+// telegramResponse is the envelope of Bot API responses.
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+// call calls the Bot API method with params and decodes its result into
+// result, unless it is nil.
+func (t *Telegram) call(method string, params url.Values, timeout time.Duration,
+	result interface{}) error {
+	client := &http.Client{Timeout: timeout, Transport: t.transport}
+	resp, err := client.PostForm(t.apiURL+t.token+"/"+method, params)
+	if err != nil {
+		// The URL holds the token.
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %s failed: %v", method, err)
+	}
+	defer resp.Body.Close()
+
+	var tr telegramResponse
+	if err = json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return fmt.Errorf("telegram %s failed: %s", method, resp.Status)
+	}
+	if !tr.OK {
+		return fmt.Errorf("telegram %s failed: %s", method, tr.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(tr.Result, result)
+}
+
+// Send sends the message text to the chat.
+func (t *Telegram) Send(chatID int64, text string) error {
+	return t.call("sendMessage", url.Values{
+		"chat_id":                  {strconv.FormatInt(chatID, 10)},
+		"text":                     {text},
+		"disable_web_page_preview": {"true"},
+	}, telegramTimeout, nil)
+}
+
+// NotifyOperators sends the message text to the operators' chats.
+func (t *Telegram) NotifyOperators(text string) {
+	for _, chatID := range t.operatorChats {
+		if err := t.Send(chatID, text); err != nil {
+			log.Errorf("Unable to notify operator chat %d: %v", chatID, err)
+		}
+	}
+}
+
+// BotName returns the username of the bot, or the empty string when it
+// can't be looked up.
+func (t *Telegram) BotName() string {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.botName != "" {
+		return t.botName
+	}
+
+	var me struct {
+		Username string `json:"username"`
+	}
+	if err := t.call("getMe", nil, telegramTimeout, &me); err != nil {
+		log.Warnf("Unable to look up the Telegram bot: %v", err)
+		return ""
+	}
+	t.botName = me.Username
+	return t.botName
+}
+
+// LinkURL returns the URL which opens a chat with the bot that links it to
+// the account given token, or the empty string when the bot is unknown.
+func (t *Telegram) LinkURL(token string) string {
+	botName := t.BotName()
+	if botName == "" {
+		return ""
+	}
+	return "https://t.me/" + botName + "?start=" + url.QueryEscape(token)
+}
+
+// telegramUpdate is a message sent to the bot.
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// updates long polls for the messages sent to the bot from offset on.
+func (t *Telegram) updates(offset int64) ([]telegramUpdate, error) {
+	var updates []telegramUpdate
+	err := t.call("getUpdates", url.Values{
+		"offset":          {strconv.FormatInt(offset, 10)},
+		"timeout":         {strconv.Itoa(int(telegramPollTimeout.Seconds()))},
+		"allowed_updates": {`["message"]`},
+	}, telegramPollTimeout+telegramTimeout, &updates)
+	return updates, err
+}
+
+// telegramCommand returns the command of a message sent to the bot and its
+// argument.  Commands sent in groups can carry the name of the bot.
+func telegramCommand(text string) (command, arg string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	command = strings.ToLower(fields[0])
+	if i := strings.Index(command, "@"); i >= 0 {
+		command = command[:i]
+	}
+	if len(fields) > 1 {
+		arg = fields[1]
+	}
+	return command, arg
+}
+
+// handleTelegramMessage answers a message sent to the bot from a chat.
+// /start with the token of a user links the chat to their account, and /stop
+// unlinks it from every account.
+func (controller *MainController) handleTelegramMessage(dbMap *gorp.DbMap,
+	chatID int64, text string) {
+	var reply string
+	switch command, arg := telegramCommand(text); command {
+	case "/start":
+		if arg == "" {
+			reply = "Follow the Telegram link on the settings page of " +
+				"your account at " + controller.baseURL + " to receive " +
+				"its notifications here."
+			break
+		}
+		prefs, err := models.GetUserPreferencesByTelegramToken(dbMap, arg)
+		if err == sql.ErrNoRows {
+			reply = "This link is invalid or was already used.  Get a " +
+				"new one from the settings page of your account at " +
+				controller.baseURL + "."
+			break
+		}
+		if err == nil {
+			prefs.TelegramChatID = chatID
+			prefs.TelegramToken = ""
+			err = models.SetUserPreferences(dbMap, prefs)
+		}
+		if err != nil {
+			log.Errorf("Unable to link Telegram chat %d: %v", chatID, err)
+			reply = "Something went wrong, please try again later."
+			break
+		}
+		log.Infof("Linked Telegram chat %d to userid %d", chatID,
+			prefs.UserId)
+		reply = "Notifications of your account at " + controller.baseURL +
+			" will be sent to this chat.  Send /stop to stop them."
+	case "/stop":
+		n, err := models.UnlinkTelegramChat(dbMap, chatID)
+		if err != nil {
+			log.Errorf("Unable to unlink Telegram chat %d: %v", chatID, err)
+			reply = "Something went wrong, please try again later."
+			break
+		}
+		reply = "No notifications are sent to this chat."
+		if n > 0 {
+			reply = "Notifications won't be sent to this chat anymore."
+		}
+	default:
+		return
+	}
+	if err := controller.telegram.Send(chatID, reply); err != nil {
+		log.Warnf("Unable to answer Telegram chat %d: %v", chatID, err)
+	}
+}
+
+// TelegramHandler answers the messages sent to the Telegram bot.  It never
+// returns.
+func (controller *MainController) TelegramHandler(dbMap *gorp.DbMap) {
+	var offset int64
+	for {
+		updates, err := controller.telegram.updates(offset)
+		if err != nil {
+			log.Warnf("Unable to fetch Telegram messages: %v", err)
+			time.Sleep(telegramRetryDelay)
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message != nil {
+				controller.handleTelegramMessage(dbMap, u.Message.Chat.ID,
+					u.Message.Text)
+			}
+		}
+	}
+}
+
+// notifyTelegramUsers sends the message text to the Telegram chats users
+// linked to their account.
+func (controller *MainController) notifyTelegramUsers(dbMap *gorp.DbMap,
+	text string) {
+	if controller.telegram == nil {
+		return
+	}
+	chatIDs, err := models.GetTelegramChatIDs(dbMap)
+	if err != nil {
+		log.Errorf("GetTelegramChatIDs failed: %v", err)
+		return
+	}
+	sent := make(map[int64]bool, len(chatIDs))
+	for _, chatID := range chatIDs {
+		if sent[chatID] {
+			continue
+		}
+		sent[chatID] = true
+		if err := controller.telegram.Send(chatID, text); err != nil {
+			log.Warnf("Unable to notify Telegram chat %d: %v", chatID, err)
+		}
+	}
+}
+
+// telegramLink returns the URL linking a Telegram chat to the account of the
+// user, with a new token.
+func (controller *MainController) telegramLink(dbMap *gorp.DbMap,
+	userID int64) (string, error) {
+	p := controller.getPreferences(dbMap, userID)
+	p.TelegramToken = randToken()
+	if err := models.SetUserPreferences(dbMap, p.UserPreferences); err != nil {
+		return "", err
+	}
+	link := controller.telegram.LinkURL(p.TelegramToken)
+	if link == "" {
+		return "", errors.New("the Telegram bot is unavailable")
+	}
+	return link, nil
+}
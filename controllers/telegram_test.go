@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTelegramCommand(t *testing.T) {
+	tests := []struct {
+		text, command, arg string
+	}{
+		{"/start abc123", "/start", "abc123"},
+		{"/Stop@PoolBot", "/stop", ""},
+		{"  ", "", ""},
+		{"hello there", "hello", "there"},
+	}
+	for _, test := range tests {
+		command, arg := telegramCommand(test.text)
+		if command != test.command || arg != test.arg {
+			t.Errorf("telegramCommand(%q) = %q, %q, want %q, %q", test.text,
+				command, arg, test.command, test.arg)
+		}
+	}
+}
+
+func TestParseTelegramChatIDs(t *testing.T) {
+	ids, err := ParseTelegramChatIDs([]string{"123", " -1001234567890"})
+	if err != nil || len(ids) != 2 || ids[0] != 123 || ids[1] != -1001234567890 {
+		t.Errorf("ParseTelegramChatIDs = %v, %v", ids, err)
+	}
+	for _, id := range []string{"", "0", "@channel"} {
+		if _, err := ParseTelegramChatIDs([]string{id}); err == nil {
+			t.Errorf("chat id %q accepted", id)
+		}
+	}
+}
+
+func TestTelegramSend(t *testing.T) {
+	var chatID, text string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		switch r.URL.Path {
+		case "/botsecret/sendMessage":
+			chatID, text = r.FormValue("chat_id"), r.FormValue("text")
+			w.Write([]byte(`{"ok":true,"result":{}}`))
+		case "/botsecret/getMe":
+			w.Write([]byte(`{"ok":true,"result":{"username":"PoolBot"}}`))
+		default:
+			w.Write([]byte(`{"ok":false,"description":"Not Found"}`))
+		}
+	}))
+	defer server.Close()
+
+	tg := NewTelegram("secret", []int64{42}, nil)
+	tg.apiURL = server.URL + "/bot"
+	if err := tg.Send(42, "hi"); err != nil || chatID != "42" || text != "hi" {
+		t.Errorf("Send sent %q to %q: %v", text, chatID, err)
+	}
+	if link := tg.LinkURL("abc"); link != "https://t.me/PoolBot?start=abc" {
+		t.Errorf("LinkURL = %q", link)
+	}
+	if err := tg.call("getFoo", nil, telegramTimeout, nil); err == nil {
+		t.Error("failed call succeeded")
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/zenazn/goji/web"
+)
+
+// termsExemptPaths are the pages signed in users can reach before accepting
+// the terms of service.
+var termsExemptPaths = map[string]bool{
+	"/error":       true,
+	"/favicon.ico": true,
+	"/logout":      true,
+	"/robots.txt":  true,
+	"/terms":       true,
+}
+
+// termsExempt returns whether signed in users can reach path before
+// accepting the terms of service.  The assets are exempt so the terms page
+// gets its stylesheets and scripts.
+func termsExempt(path string) bool {
+	return termsExemptPaths[path] || strings.HasPrefix(path, "/assets/")
+}
+
+// ApplyTerms sets TermsVersion in the template environment when users must
+// accept the terms of service, and sends signed in users who haven't accepted
+// the current version to the terms page.  API requests aren't redirected.
+func (controller *MainController) ApplyTerms(c *web.C, h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if controller.termsVersion == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		c.Env["TermsVersion"] = controller.termsVersion
+
+		isAPI, _ := c.Env["IsAPI"].(bool)
+		uid, ok := controller.GetSession(*c).Values["UserId"].(int64)
+		if ok && !isAPI && !termsExempt(r.URL.Path) {
+			user, err := models.GetUserById(controller.GetDbMap(*c), uid)
+			if err == nil && user.TermsVersion != controller.termsVersion {
+				http.Redirect(w, r, "/terms", http.StatusSeeOther)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// Terms renders the terms of service along with the form to accept them for
+// signed in users who haven't accepted the current version.
+func (controller *MainController) Terms(c web.C, r *http.Request) (string, int) {
+	if controller.termsVersion == "" {
+		return "/", http.StatusSeeOther
+	}
+	t := controller.GetTemplate(c)
+	session := controller.GetSession(c)
+
+	if uid, ok := session.Values["UserId"].(int64); ok {
+		user, err := models.GetUserById(controller.GetDbMap(c), uid)
+		if err != nil {
+			log.Errorf("GetUserById failed: %v", err)
+			return "/error", http.StatusSeeOther
+		}
+		c.Env["TermsPending"] = user.TermsVersion != controller.termsVersion
+		c.Env["TermsChanged"] = user.TermsVersion != "" &&
+			user.TermsVersion != controller.termsVersion
+	}
+
+	c.Env["Admin"], _ = controller.isAdmin(c, r)
+	c.Env["FlashError"] = session.Flashes("termsError")
+	c.Env["IsTerms"] = true
+
+	widgets := controller.Parse(t, "terms", c.Env)
+	c.Env["Title"] = "Hcd Stake Pool - Terms of Service"
+	c.Env["Content"] = template.HTML(widgets)
+
+	return controller.Parse(t, "main", c.Env), http.StatusOK
+}
+
+// TermsPost records that the signed in user accepted the current version of
+// the terms of service.
+func (controller *MainController) TermsPost(c web.C, r *http.Request) (string, int) {
+	session := controller.GetSession(c)
+	dbMap := controller.GetDbMap(c)
+
+	uid, ok := session.Values["UserId"].(int64)
+	if !ok || controller.termsVersion == "" {
+		return "/", http.StatusSeeOther
+	}
+	if r.FormValue("TermsVersion") != controller.termsVersion {
+		session.AddFlash("the terms of service changed, please review them "+
+			"again", "termsError")
+		return "/terms", http.StatusSeeOther
+	}
+	if r.FormValue("accept") == "" {
+		session.AddFlash("you must accept the terms of service to use the "+
+			"pool", "termsError")
+		return "/terms", http.StatusSeeOther
+	}
+
+	user, err := models.GetUserById(dbMap, uid)
+	if err != nil {
+		log.Errorf("GetUserById failed: %v", err)
+		return "/error", http.StatusSeeOther
+	}
+	if user.TermsVersion != controller.termsVersion {
+		err = models.SetUserTermsVersion(dbMap, uid, controller.termsVersion)
+		if err != nil {
+			log.Errorf("SetUserTermsVersion failed: %v", err)
+			return "/error", http.StatusSeeOther
+		}
+		controller.audit(dbMap, c, r, AuditActionTermsAccept,
+			controller.termsVersion, user.TermsVersion,
+			controller.termsVersion)
+	}
+	return "/tickets", http.StatusSeeOther
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import "testing"
+
+func TestTermsExempt(t *testing.T) {
+	tests := []struct {
+		path   string
+		exempt bool
+	}{
+		{"/terms", true},
+		{"/robots.txt", true},
+		{"/favicon.ico", true},
+		{"/assets/css/main.css", true},
+		{"/assets/js/main.js", true},
+		{"/assets", false},
+		{"/tickets", false},
+		{"/", false},
+	}
+	for _, test := range tests {
+		if got := termsExempt(test.path); got != test.exempt {
+			t.Errorf("termsExempt(%q) = %v, want %v", test.path, got,
+				test.exempt)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/coolsnady/hcutil"
+)
+
+// ticketPriceChangeWarning is how many blocks before the ticket price changes
+// users are warned that tickets they buy may not be mined at the current
+// price.
+const ticketPriceChangeWarning = 12
+
+// TicketPrice is the ticket price and the ticket fees shown on the tickets
+// page.  The price changes at ChangeHeight, ChangeBlocks blocks after the
+// current tip, which is expected around ChangeTime.  Next is only known, and
+// NextKnown set, when the next block changes the price.  Otherwise the
+// Estimate fields are what hcd expects the next price to be.  FeeMedian is
+// the median fee per kB of the tickets in the mempool.
+type TicketPrice struct {
+	Current          hcutil.Amount
+	Next             hcutil.Amount
+	NextKnown        bool
+	EstimateMin      hcutil.Amount
+	EstimateMax      hcutil.Amount
+	EstimateExpected hcutil.Amount
+	ChangeHeight     int64
+	ChangeBlocks     int64
+	ChangeTime       time.Time
+	ChangeSoon       bool
+	FeeMedian        hcutil.Amount
+	MempoolTickets   uint32
+}
+
+// ticketPrice returns the ticket price at height from what hcd reported.
+func (controller *MainController) ticketPrice(info *ticketPriceInfo,
+	height int64) *TicketPrice {
+	amount := func(f float64) hcutil.Amount {
+		a, _ := hcutil.NewAmount(f)
+		return a
+	}
+
+	window := controller.params.StakeDiffWindowSize
+	changeHeight := (height/window + 1) * window
+	price := &TicketPrice{
+		Current:      amount(info.stakeDiff.CurrentStakeDifficulty),
+		ChangeHeight: changeHeight,
+		ChangeBlocks: changeHeight - height,
+		ChangeTime:   controller.estimateBlockTime(changeHeight - height),
+		ChangeSoon:   changeHeight-height <= ticketPriceChangeWarning,
+	}
+	if price.ChangeBlocks == 1 {
+		price.Next = amount(info.stakeDiff.NextStakeDifficulty)
+		price.NextKnown = true
+	}
+	if info.estimate != nil {
+		price.EstimateMin = amount(info.estimate.Min)
+		price.EstimateMax = amount(info.estimate.Max)
+		price.EstimateExpected = amount(info.estimate.Expected)
+	}
+	if info.feeInfo != nil {
+		price.FeeMedian = amount(info.feeInfo.FeeInfoMempool.Median)
+		price.MempoolTickets = info.feeInfo.FeeInfoMempool.Number
+	}
+
+	return price
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcutil"
+)
+
+func TestTicketPrice(t *testing.T) {
+	controller := &MainController{params: &chaincfg.MainNetParams}
+	window := controller.params.StakeDiffWindowSize
+	info := &ticketPriceInfo{
+		stakeDiff: &dcrjson.GetStakeDifficultyResult{
+			CurrentStakeDifficulty: 50,
+			NextStakeDifficulty:    60,
+		},
+		estimate: &dcrjson.EstimateStakeDiffResult{
+			Min:      40,
+			Max:      70,
+			Expected: 55,
+		},
+		feeInfo: &dcrjson.TicketFeeInfoResult{
+			FeeInfoMempool: dcrjson.FeeInfoMempool{
+				Number: 3,
+				Median: 0.01,
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		height    int64
+		blocks    int64
+		nextKnown bool
+		soon      bool
+	}{
+		{"window start", 2 * window, window, false, false},
+		{"window middle", 2*window + window/2, window - window/2, false, false},
+		{"warning", 3*window - ticketPriceChangeWarning, ticketPriceChangeWarning,
+			false, true},
+		{"last block", 3*window - 1, 1, true, true},
+	}
+	for _, test := range tests {
+		price := controller.ticketPrice(info, test.height)
+		if price.ChangeBlocks != test.blocks ||
+			price.ChangeHeight != test.height+test.blocks {
+			t.Errorf("%s: expected change in %d blocks, got %d at %d",
+				test.name, test.blocks, price.ChangeBlocks,
+				price.ChangeHeight)
+		}
+		if price.NextKnown != test.nextKnown || price.ChangeSoon != test.soon {
+			t.Errorf("%s: expected next known %v and change soon %v, "+
+				"got %v and %v", test.name, test.nextKnown, test.soon,
+				price.NextKnown, price.ChangeSoon)
+		}
+		if price.NextKnown && price.Next != 60*hcutil.AtomsPerCoin {
+			t.Errorf("%s: expected next price 60, got %v", test.name,
+				price.Next)
+		}
+		if price.Current != 50*hcutil.AtomsPerCoin ||
+			price.EstimateExpected != 55*hcutil.AtomsPerCoin ||
+			price.FeeMedian != hcutil.AtomsPerCoin/100 ||
+			price.MempoolTickets != 3 {
+			t.Errorf("%s: unexpected price %+v", test.name, price)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coolsnady/hcd/chaincfg/chainhash"
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcutil"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// ticketStatusUnseen is reported for tickets the pool's wallets don't
+	// know about yet.
+	ticketStatusUnseen = "unseen"
+
+	// ticketStatusInvalid is reported for tickets the pool's wallets have
+	// seen but will not vote, e.g. because of an insufficient pool fee.
+	ticketStatusInvalid = "invalid"
+
+	// defaultTicketWaitTimeout and maxTicketWaitTimeout bound how long a
+	// single ticketstatus long-poll request is held open.
+	defaultTicketWaitTimeout = 30 * time.Second
+	maxTicketWaitTimeout     = 120 * time.Second
+
+	// ticketWaitPollInterval is how often the wallets are asked about the
+	// ticket while a request is waiting.
+	ticketWaitPollInterval = 5 * time.Second
+
+	// maxTicketWaiters is the number of ticketstatus requests that may be
+	// waiting at the same time.
+	maxTicketWaiters = 100
+)
+
+// ticketResumeToken is the state a client hands back to continue waiting on a
+// ticket where a previous request left off.
+type ticketResumeToken struct {
+	userID     int64
+	ticketHash string
+	status     string
+}
+
+// signTicketResumeToken serializes the token and appends an HMAC so clients
+// can't forge tokens for other users' tickets.
+func signTicketResumeToken(secret string, t *ticketResumeToken) string {
+	payload := strconv.FormatInt(t.userID, 10) + ":" + t.ticketHash + ":" +
+		t.status
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString([]byte(payload)) + "." +
+		hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseTicketResumeToken verifies and deserializes a token created by
+// signTicketResumeToken.
+func parseTicketResumeToken(secret, token string) (*ticketResumeToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, errors.New("malformed resume token")
+	}
+	payload, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed resume token")
+	}
+	sig, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed resume token")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errors.New("invalid resume token")
+	}
+
+	fields := strings.Split(string(payload), ":")
+	if len(fields) != 3 {
+		return nil, errors.New("malformed resume token")
+	}
+	userID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("malformed resume token")
+	}
+
+	return &ticketResumeToken{
+		userID:     userID,
+		ticketHash: fields[1],
+		status:     fields[2],
+	}, nil
+}
+
+// ticketStatus asks the wallets what they know about the ticket.
+func (controller *MainController) ticketStatus(multiSigAddress string,
+	ticketHash string) (string, error) {
+	addr, err := hcutil.DecodeAddress(multiSigAddress)
+	if err != nil {
+		return "", err
+	}
+	spui, err := controller.rpcServers.StakePoolUserInfo(addr, true)
+	if err != nil {
+		return "", err
+	}
+	if spui == nil {
+		return "", errors.New("no stake pool user info")
+	}
+
+	for _, ticket := range spui.Tickets {
+		if ticket.Ticket == ticketHash {
+			return ticket.Status, nil
+		}
+	}
+	for _, ticket := range spui.InvalidTickets {
+		if ticket == ticketHash {
+			return ticketStatusInvalid, nil
+		}
+	}
+
+	return ticketStatusUnseen, nil
+}
+
+// APITicketStatus is a long-poll API call for wallets waiting for the pool to
+// acknowledge a just-purchased ticket.  Without a resume token it returns as
+// soon as the pool has seen the ticket.  With the resume token from a previous
+// response it returns as soon as the ticket's status differs from the status
+// in that response.  Either way it returns the current status once the
+// timeout expires, along with a token to continue waiting.
+func (controller *MainController) APITicketStatus(c web.C,
+	r *http.Request) (*poolapi.TicketStatus, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if c.Env["APIUserID"] == nil {
+		return nil, codes.Unauthenticated, "ticketstatus error", errors.New("invalid api token")
+	}
+	userID := c.Env["APIUserID"].(int64)
+
+	user, err := models.GetUserById(dbMap, userID)
+	if err != nil {
+		return nil, codes.Internal, "ticketstatus error", errors.New("unable to fetch user")
+	}
+	if len(user.MultiSigAddress) == 0 {
+		return nil, codes.FailedPrecondition, "ticketstatus error", errors.New("no address submitted")
+	}
+
+	ticketHash := r.FormValue("TicketHash")
+	lastStatus := ticketStatusUnseen
+	if resume := r.FormValue("ResumeToken"); resume != "" {
+		token, err := parseTicketResumeToken(controller.APISecret, resume)
+		if err != nil {
+			return nil, codes.InvalidArgument, "ticketstatus error", err
+		}
+		if token.userID != userID {
+			return nil, codes.PermissionDenied, "ticketstatus error", errors.New("resume token is for another user")
+		}
+		if ticketHash != "" && ticketHash != token.ticketHash {
+			return nil, codes.InvalidArgument, "ticketstatus error", errors.New("resume token is for another ticket")
+		}
+		ticketHash = token.ticketHash
+		lastStatus = token.status
+	}
+
+	hash, err := chainhash.NewHashFromStr(ticketHash)
+	if err != nil {
+		return nil, codes.InvalidArgument, "ticketstatus error", errors.New("invalid ticket hash")
+	}
+	ticketHash = hash.String()
+
+	timeout := defaultTicketWaitTimeout
+	if t := r.FormValue("Timeout"); t != "" {
+		secs, err := strconv.Atoi(t)
+		if err != nil || secs < 0 {
+			return nil, codes.InvalidArgument, "ticketstatus error", errors.New("invalid timeout")
+		}
+		timeout = time.Duration(secs) * time.Second
+		if timeout > maxTicketWaitTimeout {
+			timeout = maxTicketWaitTimeout
+		}
+	}
+
+	select {
+	case controller.ticketWaiters <- struct{}{}:
+		defer func() { <-controller.ticketWaiters }()
+	default:
+		return nil, codes.ResourceExhausted, "ticketstatus error", errors.New("too many waiting requests, try again later")
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(ticketWaitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if controller.RPCIsStopped() {
+			return nil, codes.Unavailable, "ticketstatus error", errors.New("RPC server stopped")
+		}
+		status, err := controller.ticketStatus(user.MultiSigAddress, ticketHash)
+		if err != nil {
+			log.Infof("ticketstatus for user %d ticket %s failed: %v",
+				userID, ticketHash, err)
+			return nil, codes.Unavailable, "ticketstatus error", errors.New("RPC server error")
+		}
+
+		changed := status != lastStatus
+		if changed {
+			ts := &poolapi.TicketStatus{
+				TicketHash: ticketHash,
+				Status:     status,
+				Changed:    true,
+				ResumeToken: signTicketResumeToken(controller.APISecret,
+					&ticketResumeToken{userID, ticketHash, status}),
+			}
+			return ts, codes.OK, "ticket status changed", nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			ts := &poolapi.TicketStatus{
+				TicketHash: ticketHash,
+				Status:     status,
+				ResumeToken: signTicketResumeToken(controller.APISecret,
+					&ticketResumeToken{userID, ticketHash, status}),
+			}
+			return ts, codes.OK, "timed out waiting for ticket status change", nil
+		case <-r.Context().Done():
+			return nil, codes.Canceled, "ticketstatus error", errors.New("request canceled")
+		}
+	}
+}
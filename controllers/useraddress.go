@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcutil"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// maxUserAddresses is how many voting addresses a user can register on top of
+// their first one.
+const maxUserAddresses = 9
+
+// errRPCUnavailable is returned when a voting address can't be registered
+// because the voting wallets can't be used.
+var errRPCUnavailable = errors.New("unable to process wallet commands")
+
+// decodeUserPubKeyAddr decodes the public key address a user submits to
+// register a voting address.
+func decodeUserPubKeyAddr(userPubKeyAddr string) (hcutil.Address, error) {
+	if len(userPubKeyAddr) < 40 {
+		return nil, errors.New("address too short")
+	}
+	if len(userPubKeyAddr) > 65 {
+		return nil, errors.New("address too long")
+	}
+	u, err := hcutil.DecodeAddress(userPubKeyAddr)
+	if err != nil {
+		return nil, errors.New("couldn't decode address")
+	}
+	if _, ok := u.(*hcutil.AddressSecpPubKey); !ok {
+		return nil, errors.New("incorrect address type")
+	}
+	return u, nil
+}
+
+// checkUserAddressLimits returns why user can't register userPubKeyAddr as
+// an additional voting address, if they can't.
+func checkUserAddressLimits(dbMap *gorp.DbMap, user *models.User,
+	userPubKeyAddr string) error {
+	addresses, err := models.GetUserAddresses(dbMap, user.Id)
+	if err != nil {
+		log.Errorf("GetUserAddresses failed for userid %v: %v", user.Id, err)
+		return errRPCUnavailable
+	}
+	if len(addresses) >= maxUserAddresses {
+		return fmt.Errorf("at most %d addresses can be registered",
+			maxUserAddresses+1)
+	}
+	registered, err := models.CountUserPubKeyAddr(dbMap, userPubKeyAddr)
+	if err != nil {
+		log.Errorf("CountUserPubKeyAddr failed: %v", err)
+		return errRPCUnavailable
+	}
+	if registered > 0 {
+		return errors.New("address already registered")
+	}
+	return nil
+}
+
+// addUserAddress registers userPubKeyAddr as an additional voting address of
+// user.  Its multisig script binds it to the same pool address as the first
+// voting address of the user and is imported into the voting wallets.  Errors
+// other than errRPCUnavailable can be shown to the user.
+func (controller *MainController) addUserAddress(dbMap *gorp.DbMap,
+	user *models.User, userPubKeyAddr string) (*models.UserAddress, error) {
+	if user.MultiSigAddress == "" {
+		return nil, errors.New("submit your first address before adding " +
+			"more")
+	}
+	u, err := decodeUserPubKeyAddr(userPubKeyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUserAddressLimits(dbMap, user, userPubKeyAddr); err != nil {
+		return nil, err
+	}
+
+	p, err := hcutil.DecodeAddress(user.PoolPubKeyAddr)
+	if err != nil {
+		log.Errorf("invalid pool pubkey address %v of userid %v: %v",
+			user.PoolPubKeyAddr, user.Id, err)
+		return nil, errRPCUnavailable
+	}
+	if controller.RPCIsStopped() {
+		return nil, errRPCUnavailable
+	}
+	createMultiSig, err := controller.rpcServers.CreateMultisig(1, []hcutil.Address{p, u})
+	if err != nil {
+		controller.handlePotentialFatalError("CreateMultisig", err)
+		return nil, errRPCUnavailable
+	}
+	_, bestBlockHeight, err := controller.rpcServers.GetBestBlock()
+	if err != nil {
+		controller.handlePotentialFatalError("GetBestBlock", err)
+		return nil, errRPCUnavailable
+	}
+	serializedScript, err := hex.DecodeString(createMultiSig.RedeemScript)
+	if err != nil {
+		controller.handlePotentialFatalError("CreateMultisig DecodeString", err)
+		return nil, errRPCUnavailable
+	}
+	err = controller.rpcServers.ImportScript(serializedScript, int(bestBlockHeight))
+	if err != nil {
+		controller.handlePotentialFatalError("ImportScript", err)
+		return nil, errRPCUnavailable
+	}
+
+	address := &models.UserAddress{
+		UserId:           user.Id,
+		MultiSigAddress:  createMultiSig.Address,
+		MultiSigScript:   createMultiSig.RedeemScript,
+		PoolPubKeyAddr:   user.PoolPubKeyAddr,
+		UserPubKeyAddr:   userPubKeyAddr,
+		HeightRegistered: bestBlockHeight,
+		VoteBits:         int64(defaultVoteBits),
+		VoteBitsVersion:  int64(controller.currentVoteVersion()),
+		Created:          time.Now().Unix(),
+	}
+	inserted, err := models.InsertUserAddress(dbMap, address,
+		maxUserAddresses)
+	if err != nil {
+		log.Errorf("InsertUserAddress failed for userid %v: %v", user.Id, err)
+		return nil, errRPCUnavailable
+	}
+	if !inserted {
+		// A concurrent registration got past the checks above first.
+		if err := checkUserAddressLimits(dbMap, user, userPubKeyAddr); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("address already registered")
+	}
+	log.Infof("added voting address %v for userid %v", address.MultiSigAddress,
+		user.Id)
+	return address, nil
+}
+
+// userAddressParam returns the additional voting address of the user chosen
+// by the address form value, or nil for their first one.
+func userAddressParam(dbMap *gorp.DbMap, r *http.Request,
+	userID int64) (*models.UserAddress, error) {
+	v := r.FormValue("address")
+	if v == "" || v == "0" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid address")
+	}
+	address, err := models.GetUserAddress(dbMap, userID, id)
+	if err != nil {
+		return nil, errors.New("unknown address")
+	}
+	return address, nil
+}
+
+// votingAddressChoice is a voting address the tickets and voting pages can be
+// switched to.
+type votingAddressChoice struct {
+	ID       int64
+	Address  string
+	Selected bool
+}
+
+// votingAddressChoices returns the voting addresses of user with the one
+// chosen by userAddressParam selected, or nil when the user only has their
+// first one so there is nothing to choose.
+func votingAddressChoices(dbMap *gorp.DbMap, user *models.User,
+	selected *models.UserAddress) []votingAddressChoice {
+	addresses, err := models.GetUserAddresses(dbMap, user.Id)
+	if err != nil {
+		log.Errorf("GetUserAddresses failed for userid %v: %v", user.Id, err)
+		return nil
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+	choices := make([]votingAddressChoice, 0, len(addresses)+1)
+	choices = append(choices, votingAddressChoice{
+		Address:  user.MultiSigAddress,
+		Selected: selected == nil,
+	})
+	for _, a := range addresses {
+		choices = append(choices, votingAddressChoice{
+			ID:       a.Id,
+			Address:  a.MultiSigAddress,
+			Selected: selected != nil && selected.Id == a.Id,
+		})
+	}
+	return choices
+}
+
+// checkUserAddressVoteBits carries the voting preferences of the additional
+// voting addresses over to the current vote version, like
+// CheckAndResetUserVoteBits does for the first ones, and returns the
+// addresses by user.
+func (controller *MainController) checkUserAddressVoteBits(dbMap *gorp.DbMap) (map[int64][]models.UserAddress, error) {
+	addresses, err := models.GetAllUserAddresses(dbMap)
+	if err != nil {
+		return nil, err
+	}
+
+	voteVersion := controller.currentVoteVersion()
+	byUser := make(map[int64][]models.UserAddress)
+	for _, a := range addresses {
+		before := a
+		if controller.migrateUserAddressVoteBits(&a, voteVersion) {
+			err := models.SetUserAddressVoteBits(dbMap, a.Id,
+				uint16(a.VoteBits), voteVersion, a.VoteBitsReconfirm != 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to migrate VoteBits for "+
+					"address %v of uid %v: %v", a.MultiSigAddress,
+					a.UserId, err)
+			}
+			log.Infof("migrated VoteBits %v version %v to VoteBits %v "+
+				"version %v for address %v of uid %v", before.VoteBits,
+				before.VoteBitsVersion, a.VoteBits, voteVersion,
+				a.MultiSigAddress, a.UserId)
+		}
+		byUser[a.UserId] = append(byUser[a.UserId], a)
+	}
+	return byUser, nil
+}
+
+// migrateUserAddressVoteBits carries the voting preferences of a over to
+// voteVersion, or resets invalid ones, and returns whether they changed.
+// Preferences that can't be carried over are flagged for the user to
+// confirm.
+func (controller *MainController) migrateUserAddressVoteBits(a *models.UserAddress,
+	voteVersion uint32) bool {
+	voteBits := uint16(a.VoteBits)
+	reconfirm := a.VoteBitsReconfirm != 0
+	switch {
+	case uint32(a.VoteBitsVersion) != voteVersion:
+		from, known := controller.params.Deployments[uint32(a.VoteBitsVersion)]
+		var conflicts []string
+		voteBits, conflicts = migrateVoteBits(voteBits, from,
+			controller.params.Deployments[voteVersion])
+		reconfirm = reconfirm || len(conflicts) > 0 ||
+			(!known && uint16(a.VoteBits) != defaultVoteBits)
+	case !controller.IsValidVoteBits(voteBits):
+		voteBits = defaultVoteBits
+	default:
+		return false
+	}
+	a.VoteBits = int64(voteBits)
+	a.VoteBitsVersion = int64(voteVersion)
+	a.VoteBitsReconfirm = 0
+	if reconfirm {
+		a.VoteBitsReconfirm = 1
+	}
+	return true
+}
+
+// apiUserAddresses returns the voting addresses of user, their first one
+// with ID 0 first.
+func (controller *MainController) apiUserAddresses(dbMap *gorp.DbMap,
+	user *models.User) ([]poolapi.VotingAddress, error) {
+	addresses, err := models.GetUserAddresses(dbMap, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	apiAddresses := make([]poolapi.VotingAddress, 0, len(addresses)+1)
+	if user.MultiSigAddress != "" {
+		apiAddresses = append(apiAddresses, poolapi.VotingAddress{
+			UserPubKeyAddr:   user.UserPubKeyAddr,
+			TicketAddress:    user.MultiSigAddress,
+			Script:           user.MultiSigScript,
+			HeightRegistered: user.HeightRegistered,
+			VoteBits:         uint16(user.VoteBits),
+			VoteBitsVersion:  uint32(user.VoteBitsVersion),
+		})
+	}
+	for _, a := range addresses {
+		apiAddresses = append(apiAddresses, poolapi.VotingAddress{
+			ID:               a.Id,
+			UserPubKeyAddr:   a.UserPubKeyAddr,
+			TicketAddress:    a.MultiSigAddress,
+			Script:           a.MultiSigScript,
+			HeightRegistered: a.HeightRegistered,
+			VoteBits:         uint16(a.VoteBits),
+			VoteBitsVersion:  uint32(a.VoteBitsVersion),
+		})
+	}
+	return apiAddresses, nil
+}
+
+// APIAddresses returns the voting addresses of the user.
+func (controller *MainController) APIAddresses(c web.C,
+	r *http.Request) ([]poolapi.VotingAddress, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if c.Env["APIUserID"] == nil {
+		return nil, codes.Unauthenticated, "addresses error", errors.New("invalid api token")
+	}
+	user, err := models.GetUserById(dbMap, c.Env["APIUserID"].(int64))
+	if err != nil {
+		log.Errorf("GetUserById failed: %v", err)
+		return nil, codes.Internal, "addresses error", errors.New("unable to fetch user")
+	}
+	addresses, err := controller.apiUserAddresses(dbMap, user)
+	if err != nil {
+		log.Errorf("apiUserAddresses failed: %v", err)
+		return nil, codes.Internal, "addresses error", errors.New("unable to fetch addresses")
+	}
+	return addresses, codes.OK, "addresses successfully retrieved", nil
+}
+
+// APIAddressesPost registers the public key address UserPubKeyAddr as an
+// additional voting address of the user and returns it.
+func (controller *MainController) APIAddressesPost(c web.C,
+	r *http.Request) (*poolapi.VotingAddress, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if c.Env["APIUserID"] == nil {
+		return nil, codes.Unauthenticated, "addresses error", errors.New("invalid api token")
+	}
+	user, err := models.GetUserById(dbMap, c.Env["APIUserID"].(int64))
+	if err != nil {
+		log.Errorf("GetUserById failed: %v", err)
+		return nil, codes.Internal, "addresses error", errors.New("unable to fetch user")
+	}
+	if controller.inMaintenance() {
+		return nil, codes.Unavailable, "addresses error", errMaintenance
+	}
+	address, err := controller.addUserAddress(dbMap, user,
+		r.FormValue("UserPubKeyAddr"))
+	if err == errRPCUnavailable {
+		return nil, codes.Unavailable, "system error", err
+	}
+	if err != nil {
+		return nil, codes.InvalidArgument, "addresses error", err
+	}
+	controller.recordSecurityEvent(dbMap, r, user.Id,
+		models.SecurityEventAddress, address.MultiSigAddress, time.Now())
+	user.Addresses = []models.UserAddress{*address}
+	controller.StakepooldUpdateUsers(r.Context(), dbMap,
+		map[int64]*models.User{user.Id: user})
+
+	return &poolapi.VotingAddress{
+		ID:               address.Id,
+		UserPubKeyAddr:   address.UserPubKeyAddr,
+		TicketAddress:    address.MultiSigAddress,
+		Script:           address.MultiSigScript,
+		HeightRegistered: address.HeightRegistered,
+		VoteBits:         uint16(address.VoteBits),
+		VoteBitsVersion:  uint32(address.VoteBitsVersion),
+	}, codes.OK, "address successfully imported", nil
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcstakepool/models"
+)
+
+func TestMigrateUserAddressVoteBits(t *testing.T) {
+	agenda := chaincfg.ConsensusDeployment{
+		Vote: chaincfg.Vote{
+			Id:   "testagenda",
+			Mask: 0x0006,
+			Choices: []chaincfg.Choice{
+				{Id: "abstain", Bits: 0x0000, IsAbstain: true},
+				{Id: "no", Bits: 0x0002, IsNo: true},
+				{Id: "yes", Bits: 0x0004},
+			},
+		},
+	}
+	params := chaincfg.TestNet2Params
+	params.Deployments = map[uint32][]chaincfg.ConsensusDeployment{
+		1: {agenda},
+		2: {agenda},
+	}
+	controller := &MainController{params: &params, voteVersion: 2}
+
+	tests := []struct {
+		name      string
+		address   models.UserAddress
+		changed   bool
+		voteBits  int64
+		reconfirm int64
+	}{
+		{"current", models.UserAddress{VoteBits: 0x0005, VoteBitsVersion: 2},
+			false, 0x0005, 0},
+		{"invalid", models.UserAddress{VoteBits: 0x0007, VoteBitsVersion: 2},
+			true, 0x0001, 0},
+		{"carried over", models.UserAddress{VoteBits: 0x0005, VoteBitsVersion: 1},
+			true, 0x0005, 0},
+		{"unknown version", models.UserAddress{VoteBits: 0x0005, VoteBitsVersion: 9},
+			true, 0x0001, 1},
+	}
+	for _, test := range tests {
+		a := test.address
+		changed := controller.migrateUserAddressVoteBits(&a, 2)
+		if changed != test.changed {
+			t.Errorf("%s: changed %v, want %v", test.name, changed,
+				test.changed)
+		}
+		if a.VoteBits != test.voteBits || a.VoteBitsVersion != 2 ||
+			a.VoteBitsReconfirm != test.reconfirm {
+			t.Errorf("%s: VoteBits %#x version %d reconfirm %d, want %#x "+
+				"version 2 reconfirm %d", test.name, a.VoteBits,
+				a.VoteBitsVersion, a.VoteBitsReconfirm, test.voteBits,
+				test.reconfirm)
+		}
+	}
+}
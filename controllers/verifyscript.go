@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/txscript"
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcutil"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// maxRedeemScriptLen is the longest redeem script accepted for verification,
+// that of two uncompressed public keys.
+const maxRedeemScriptLen = 3 + 2*(1+65)
+
+// decodeRedeemScript decodes a hex encoded redeem script pasted by a user.
+func decodeRedeemScript(scriptHex string) ([]byte, error) {
+	scriptHex = strings.Join(strings.Fields(scriptHex), "")
+	if scriptHex == "" {
+		return nil, errors.New("no script submitted")
+	}
+	if len(scriptHex) > 2*maxRedeemScriptLen {
+		return nil, errors.New("script too long")
+	}
+	script, err := hex.DecodeString(scriptHex)
+	if err != nil {
+		return nil, errors.New("script is not hex encoded")
+	}
+	return script, nil
+}
+
+// multisigAddrs returns the public key addresses of a 1-of-2 multisig script.
+func multisigAddrs(script []byte, params *chaincfg.Params) ([]hcutil.Address, error) {
+	class, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		txscript.DefaultScriptVersion, script, params)
+	if err != nil || class != txscript.MultiSigTy {
+		return nil, errors.New("script is not a multisig script")
+	}
+	numPubKeys, numSigs, err := txscript.CalcMultiSigStats(script)
+	if err != nil {
+		return nil, errors.New("script is not a multisig script")
+	}
+	if numSigs != 1 {
+		return nil, errors.New("script does not require exactly one signature")
+	}
+	if numPubKeys != 2 || len(addrs) != 2 {
+		return nil, errors.New("script is not a multisig script of two " +
+			"valid keys")
+	}
+	return addrs, nil
+}
+
+// scriptHasKey returns whether pubKeyAddr is the address of one of the keys of
+// addrs.
+func scriptHasKey(addrs []hcutil.Address, pubKeyAddr string) bool {
+	addr, err := hcutil.DecodeAddress(pubKeyAddr)
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if bytes.Equal(a.ScriptAddress(), addr.ScriptAddress()) {
+			return true
+		}
+	}
+	return false
+}
+
+// derivedRedeemScript returns the 1-of-2 multisig script of the public key
+// addresses poolPubKeyAddr and userPubKeyAddr, in the order the pool creates
+// the scripts of ticket addresses in.
+func derivedRedeemScript(poolPubKeyAddr, userPubKeyAddr string) ([]byte, error) {
+	keys := make([]*hcutil.AddressSecpPubKey, 0, 2)
+	for _, pubKeyAddr := range []string{poolPubKeyAddr, userPubKeyAddr} {
+		addr, err := hcutil.DecodeAddress(pubKeyAddr)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := addr.(*hcutil.AddressSecpPubKey)
+		if !ok {
+			return nil, errors.New(pubKeyAddr + " is no public key address")
+		}
+		keys = append(keys, key)
+	}
+	return txscript.MultiSigScript(keys, 1)
+}
+
+// verifyRedeemScript checks that script is a 1-of-2 multisig script of the
+// pool's voting key and a public key address of user, that it is the script
+// the pool stored for one of the user's ticket addresses and that it is the
+// script derived from the public key addresses of that ticket address.  It also
+// checks that the fee address of the user derives from the cold wallet key of
+// the pool, so the user knows where their fees go before buying tickets.
+func (controller *MainController) verifyRedeemScript(dbMap *gorp.DbMap,
+	user *models.User, script []byte) (*poolapi.ScriptVerification, error) {
+	addresses, err := models.GetUserAddresses(dbMap, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	registered := append([]models.UserAddress{{
+		MultiSigAddress: user.MultiSigAddress,
+		MultiSigScript:  user.MultiSigScript,
+		PoolPubKeyAddr:  user.PoolPubKeyAddr,
+		UserPubKeyAddr:  user.UserPubKeyAddr,
+	}}, addresses...)
+
+	v := &poolapi.ScriptVerification{}
+	check := func(passed bool, check, details string) {
+		v.Checks = append(v.Checks, poolapi.ScriptCheck{
+			Check:   check,
+			Passed:  passed,
+			Details: details,
+		})
+	}
+
+	addrs, err := multisigAddrs(script, controller.params)
+	if err != nil {
+		check(false, "The script is a 1-of-2 multisig script", err.Error())
+	} else {
+		check(true, "The script is a 1-of-2 multisig script", "")
+	}
+
+	var match *models.UserAddress
+	p2sh, err := hcutil.NewAddressScriptHash(script, controller.params)
+	if err != nil {
+		check(false, "The script pays to one of your ticket addresses",
+			err.Error())
+	} else {
+		v.TicketAddress = p2sh.EncodeAddress()
+		for i := range registered {
+			if registered[i].MultiSigAddress == v.TicketAddress {
+				match = &registered[i]
+				break
+			}
+		}
+		check(match != nil, "The script pays to one of your ticket addresses",
+			v.TicketAddress)
+	}
+
+	if match == nil {
+		const details = "no ticket address of yours matches the script"
+		check(false, "The script is the one the pool stored", details)
+		check(false, "The script derives from the voting key of the pool "+
+			"and your public key address", details)
+		check(false, "The script includes the voting key of the pool", details)
+		check(false, "The script includes your public key address", details)
+	} else {
+		check(match.MultiSigScript == hex.EncodeToString(script),
+			"The script is the one the pool stored", match.MultiSigScript)
+		derived, err := derivedRedeemScript(match.PoolPubKeyAddr,
+			match.UserPubKeyAddr)
+		if err != nil {
+			check(false, "The script derives from the voting key of the "+
+				"pool and your public key address", err.Error())
+		} else {
+			check(bytes.Equal(derived, script), "The script derives from "+
+				"the voting key of the pool and your public key address",
+				hex.EncodeToString(derived))
+		}
+		check(scriptHasKey(addrs, match.PoolPubKeyAddr),
+			"The script includes the voting key of the pool",
+			match.PoolPubKeyAddr)
+		check(scriptHasKey(addrs, match.UserPubKeyAddr),
+			"The script includes your public key address",
+			match.UserPubKeyAddr)
+	}
+
+	feeAddr, err := controller.FeeAddressForUserID(int(user.Id))
+	if err != nil {
+		check(false, "Your fee address derives from the cold wallet key of "+
+			"the pool", err.Error())
+	} else {
+		check(feeAddr.EncodeAddress() == user.UserFeeAddr,
+			"Your fee address derives from the cold wallet key of the pool",
+			user.UserFeeAddr)
+	}
+
+	v.Valid = true
+	for _, c := range v.Checks {
+		v.Valid = v.Valid && c.Passed
+	}
+	return v, nil
+}
+
+// VerifyScript renders the page users verify their redeem scripts on.
+func (controller *MainController) VerifyScript(c web.C, r *http.Request) (string, int) {
+	t := controller.GetTemplate(c)
+	session := controller.GetSession(c)
+
+	if session.Values["UserId"] == nil {
+		return "/", http.StatusSeeOther
+	}
+
+	c.Env["Admin"], _ = controller.isAdmin(c, r)
+	c.Env["IsAddress"] = true
+	c.Env["Title"] = "Hcd Stake Pool - Verify Script"
+	c.Env["FlashError"] = session.Flashes("verifyScriptError")
+
+	widgets := controller.Parse(t, "verifyscript", c.Env)
+	c.Env["Content"] = template.HTML(widgets)
+
+	return controller.Parse(t, "main", c.Env), http.StatusOK
+}
+
+// VerifyScriptPost verifies the redeem script Script and renders the result.
+func (controller *MainController) VerifyScriptPost(c web.C, r *http.Request) (string, int) {
+	session := controller.GetSession(c)
+	dbMap := controller.GetDbMap(c)
+
+	if session.Values["UserId"] == nil {
+		return "/", http.StatusSeeOther
+	}
+	user, err := models.GetUserById(dbMap, session.Values["UserId"].(int64))
+	if err != nil {
+		log.Errorf("GetUserById failed: %v", err)
+		return "/error", http.StatusSeeOther
+	}
+	if user.MultiSigAddress == "" {
+		return "/address", http.StatusSeeOther
+	}
+
+	script, err := decodeRedeemScript(r.FormValue("Script"))
+	if err != nil {
+		session.AddFlash(err.Error(), "verifyScriptError")
+		return "/verifyscript", http.StatusSeeOther
+	}
+	v, err := controller.verifyRedeemScript(dbMap, user, script)
+	if err != nil {
+		log.Errorf("verifyRedeemScript failed for userid %v: %v", user.Id, err)
+		session.AddFlash("Unable to verify the script, please try again later",
+			"verifyScriptError")
+		return "/verifyscript", http.StatusSeeOther
+	}
+
+	c.Env["Script"] = hex.EncodeToString(script)
+	c.Env["Verification"] = v
+	return controller.VerifyScript(c, r)
+}
+
+// APIVerifyScript verifies the redeem script Script of the user.
+func (controller *MainController) APIVerifyScript(c web.C,
+	r *http.Request) (*poolapi.ScriptVerification, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if c.Env["APIUserID"] == nil {
+		return nil, codes.Unauthenticated, "verifyscript error", errors.New("invalid api token")
+	}
+	user, err := models.GetUserById(dbMap, c.Env["APIUserID"].(int64))
+	if err != nil {
+		log.Errorf("GetUserById failed: %v", err)
+		return nil, codes.Internal, "verifyscript error", errors.New("unable to fetch user")
+	}
+	if user.MultiSigAddress == "" {
+		return nil, codes.FailedPrecondition, "verifyscript error", errors.New("no address submitted")
+	}
+
+	script, err := decodeRedeemScript(r.FormValue("Script"))
+	if err != nil {
+		return nil, codes.InvalidArgument, "verifyscript error", err
+	}
+	v, err := controller.verifyRedeemScript(dbMap, user, script)
+	if err != nil {
+		log.Errorf("verifyRedeemScript failed for userid %v: %v", user.Id, err)
+		return nil, codes.Internal, "verifyscript error", errors.New("unable to verify script")
+	}
+	return v, codes.OK, "script successfully verified", nil
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcutil"
+)
+
+// Compressed public keys of the private keys 1 and 2.
+const (
+	testPubKey1 = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	testPubKey2 = "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5"
+)
+
+func TestMultisigAddrs(t *testing.T) {
+	params := &chaincfg.TestNet2Params
+	key1, _ := hex.DecodeString(testPubKey1)
+	key2, _ := hex.DecodeString(testPubKey2)
+	script := []byte{0x51, 33} // OP_1, push 33 bytes
+	script = append(script, key1...)
+	script = append(script, 33)
+	script = append(script, key2...)
+	script = append(script, 0x52, 0xae) // OP_2 OP_CHECKMULTISIG
+
+	addrs, err := multisigAddrs(script, params)
+	if err != nil {
+		t.Fatalf("multisigAddrs failed: %v", err)
+	}
+	if len(addrs) != 2 || !bytes.Equal(addrs[0].ScriptAddress(), key1) ||
+		!bytes.Equal(addrs[1].ScriptAddress(), key2) {
+		t.Errorf("multisigAddrs returned %v, want the addresses of %x and %x",
+			addrs, key1, key2)
+	}
+
+	invalid := map[string][]byte{
+		"empty":       nil,
+		"2-of-2":      append([]byte{0x52}, script[1:]...),
+		"one key":     append(append([]byte{0x51, 33}, key1...), 0x51, 0xae),
+		"short key":   append(append([]byte{0x51, 33}, key1[:20]...), 0x52, 0xae),
+		"no checksig": script[:len(script)-1],
+	}
+	for name, script := range invalid {
+		if _, err := multisigAddrs(script, params); err == nil {
+			t.Errorf("%s: multisigAddrs accepted %x", name, script)
+		}
+	}
+}
+
+func TestDerivedRedeemScript(t *testing.T) {
+	params := &chaincfg.TestNet2Params
+	var pubKeyAddrs []string
+	for _, k := range []string{testPubKey1, testPubKey2} {
+		key, _ := hex.DecodeString(k)
+		addr, err := hcutil.NewAddressSecpPubKey(key, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubKeyAddrs = append(pubKeyAddrs, addr.EncodeAddress())
+	}
+
+	script, err := derivedRedeemScript(pubKeyAddrs[0], pubKeyAddrs[1])
+	if err != nil {
+		t.Fatalf("derivedRedeemScript failed: %v", err)
+	}
+	want := "5121" + testPubKey1 + "21" + testPubKey2 + "52ae"
+	if hex.EncodeToString(script) != want {
+		t.Errorf("derivedRedeemScript returned %x, want %s", script, want)
+	}
+	addrs, err := multisigAddrs(script, params)
+	if err != nil {
+		t.Fatalf("multisigAddrs failed on the derived script: %v", err)
+	}
+	if !scriptHasKey(addrs, pubKeyAddrs[0]) || !scriptHasKey(addrs, pubKeyAddrs[1]) {
+		t.Error("derived script lacks a key")
+	}
+}
+
+func TestDecodeRedeemScript(t *testing.T) {
+	script, err := decodeRedeemScript(" 51ae\n52 ")
+	if err != nil || !bytes.Equal(script, []byte{0x51, 0xae, 0x52}) {
+		t.Errorf("decodeRedeemScript returned %x, %v", script, err)
+	}
+	for _, s := range []string{"", "5g", string(bytes.Repeat([]byte("51"), maxRedeemScriptLen+1))} {
+		if _, err := decodeRedeemScript(s); err == nil {
+			t.Errorf("decodeRedeemScript accepted %q", s)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/stakepooldclient"
+	"github.com/coolsnady/hcutil"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+	"google.golang.org/grpc"
+)
+
+const (
+	// voteHistoryInterval is how often the vote history is fetched from
+	// stakepoold versions that can't stream it.
+	voteHistoryInterval = 5 * time.Minute
+
+	// voteHistoryRetry is how long after its stream failed the vote
+	// history of a stakepoold is followed again.
+	voteHistoryRetry = 30 * time.Second
+
+	// voteHistoryOverlap is how many blocks below the newest stored event
+	// the vote history is fetched from again, so events stakepoold records
+	// for a block after the fetch aren't skipped.
+	voteHistoryOverlap = 16
+)
+
+// Steps of the ticket timeline.
+const (
+	TicketStepPurchased = "Purchased"
+	TicketStepLive      = "Live"
+	TicketStepSelected  = "Selected"
+	TicketStepVoted     = "Voted"
+	TicketStepMissed    = "Missed"
+	TicketStepExpired   = "Expired"
+	TicketStepRevoked   = "Revoked"
+)
+
+// TicketTimelineEntry is a step in the life of a ticket shown on the ticket
+// page.  Height is 0 when it isn't known.  Tx is the transaction of the step,
+// if any.  VoteBits and Reward are only set for the steps of a vote.
+type TicketTimelineEntry struct {
+	Step     string
+	Height   int64
+	Tx       string
+	VoteBits string
+	Reward   hcutil.Amount
+}
+
+// ticketTimeline returns the steps ticket went through up to height, from the
+// wallet's view of the ticket and the vote history stakepoold reported for
+// it.
+func (controller *MainController) ticketTimeline(ticket dcrjson.PoolUserTicket,
+	history []models.VoteHistory, height int64) []TicketTimelineEntry {
+	events := make(map[string]models.VoteHistory, len(history))
+	for _, e := range history {
+		events[e.Event] = e
+	}
+	voteBits := func(e models.VoteHistory) string {
+		return fmt.Sprintf("%#04x", e.VoteBits)
+	}
+
+	timeline := []TicketTimelineEntry{{
+		Step:   TicketStepPurchased,
+		Height: int64(ticket.TicketHeight),
+		Tx:     ticket.Ticket,
+	}}
+	liveHeight := int64(ticket.TicketHeight) +
+		int64(controller.params.TicketMaturity)
+	if liveHeight <= height {
+		timeline = append(timeline, TicketTimelineEntry{
+			Step:   TicketStepLive,
+			Height: liveHeight,
+		})
+	}
+	if e, ok := events[models.VoteHistorySelected]; ok {
+		timeline = append(timeline, TicketTimelineEntry{
+			Step:     TicketStepSelected,
+			Height:   e.BlockHeight,
+			VoteBits: voteBits(e),
+		})
+	}
+
+	vote, voted := events[models.VoteHistoryVoted]
+	switch {
+	case ticket.Status == "voted":
+		entry := TicketTimelineEntry{
+			Step:   TicketStepVoted,
+			Height: int64(ticket.SpentByHeight),
+			Tx:     ticket.SpentBy,
+		}
+		if voted {
+			entry.VoteBits = voteBits(vote)
+			entry.Reward = hcutil.Amount(vote.Reward)
+		}
+		timeline = append(timeline, entry)
+	case voted:
+		// The vote was sent but isn't mined yet.
+		timeline = append(timeline, TicketTimelineEntry{
+			Step:     TicketStepVoted,
+			Height:   vote.BlockHeight,
+			Tx:       vote.VoteHash,
+			VoteBits: voteBits(vote),
+			Reward:   hcutil.Amount(vote.Reward),
+		})
+	}
+
+	switch ticket.Status {
+	case "missed", "expired":
+		step := TicketStepMissed
+		var missedHeight int64
+		if ticket.Status == "expired" {
+			step = TicketStepExpired
+			missedHeight = controller.ticketExpiryHeight(ticket.TicketHeight)
+		}
+		if e, ok := events[models.VoteHistoryMissed]; ok {
+			missedHeight = e.BlockHeight
+		}
+		timeline = append(timeline, TicketTimelineEntry{
+			Step:   step,
+			Height: missedHeight,
+		})
+		if ticket.SpentBy != "" {
+			timeline = append(timeline, TicketTimelineEntry{
+				Step:   TicketStepRevoked,
+				Height: int64(ticket.SpentByHeight),
+				Tx:     ticket.SpentBy,
+			})
+		}
+	}
+
+	return timeline
+}
+
+// voteHistoryUsers maps the multisig addresses of the voting users, their
+// first and additional ones, to their ids for storing the events of their
+// tickets.
+type voteHistoryUsers struct {
+	ids    map[string]int64
+	loaded time.Time
+}
+
+// userID returns the id of the user of the multisig address msa and whether
+// there is one.  The users are loaded again when msa is unknown, e.g. for a
+// user who just signed up, but at most every voteHistoryRetry.
+func (u *voteHistoryUsers) userID(dbMap *gorp.DbMap, msa string) (int64, bool, error) {
+	if id, ok := u.ids[msa]; ok {
+		return id, true, nil
+	}
+	if time.Since(u.loaded) < voteHistoryRetry {
+		return 0, false, nil
+	}
+	addresses, err := models.GetAllVotingAddresses(dbMap)
+	if err != nil {
+		return 0, false, err
+	}
+	u.ids = make(map[string]int64, len(addresses))
+	for _, a := range addresses {
+		u.ids[a.MultiSigAddress] = a.UserId
+	}
+	u.loaded = time.Now()
+	id, ok := u.ids[msa]
+	return id, ok, nil
+}
+
+// store stores the event e of a user's ticket unless the ticket already has an
+// event of its kind and returns whether it was stored.  Events of tickets
+// that aren't a user's are skipped.
+func (u *voteHistoryUsers) store(dbMap *gorp.DbMap,
+	e *stakepooldclient.VoteHistoryEvent) (bool, error) {
+	if e.Event == "" {
+		return false, nil
+	}
+	userID, ok, err := u.userID(dbMap, e.MultiSigAddress)
+	if err != nil || !ok {
+		return false, err
+	}
+	return models.AddVoteHistory(dbMap, &models.VoteHistory{
+		UserId:      userID,
+		TicketHash:  e.Ticket,
+		Event:       e.Event,
+		BlockHeight: e.BlockHeight,
+		BlockHash:   e.BlockHash,
+		VoteHash:    e.VoteHash,
+		VoteBits:    int64(e.VoteBits),
+		Reward:      int64(e.Reward),
+		Time:        e.Time,
+	})
+}
+
+// UpdateVoteHistory stores the ticket events the stakepoold backends of conns
+// report since the newest stored one.  Events of tickets that already have an
+// event of the same kind are skipped, so the overlapping fetches and the
+// redundant stakepoold hosts don't store them twice.
+func (controller *MainController) UpdateVoteHistory(dbMap *gorp.DbMap,
+	conns []*grpc.ClientConn) error {
+	height, err := models.GetVoteHistoryHeight(dbMap)
+	if err != nil {
+		return err
+	}
+
+	users := new(voteHistoryUsers)
+	var fetched bool
+	var added int
+	for i, conn := range conns {
+		events, err := stakepooldclient.StakepooldGetVoteHistory(
+			conn, height-voteHistoryOverlap)
+		if err != nil {
+			log.Warnf("stakepoold host %d GetVoteHistory failed: %v", i, err)
+			continue
+		}
+		fetched = true
+
+		for j := range events {
+			inserted, err := users.store(dbMap, &events[j])
+			if err != nil {
+				return err
+			}
+			if inserted {
+				added++
+			}
+		}
+	}
+	if !fetched {
+		return errors.New("no stakepoold returned the vote history")
+	}
+
+	if added > 0 {
+		log.Debugf("stored %d vote history events", added)
+	}
+	return nil
+}
+
+// followVoteHistory stores the ticket events the stakepoold of conn streams as
+// they happen, starting with those since the newest stored one, until the
+// stream fails.
+func (controller *MainController) followVoteHistory(dbMap *gorp.DbMap,
+	conn *grpc.ClientConn) error {
+	height, err := models.GetVoteHistoryHeight(dbMap)
+	if err != nil {
+		return err
+	}
+	users := new(voteHistoryUsers)
+	return stakepooldclient.StakepooldSubscribeVoteHistory(
+		context.Background(), conn, height-voteHistoryOverlap,
+		func(e *stakepooldclient.VoteHistoryEvent) error {
+			_, err := users.store(dbMap, e)
+			return err
+		})
+}
+
+// VoteHistoryHandler stores the ticket events of the stakepoold backends as
+// they happen.  It follows the vote history stream of every backend that
+// supports it, again after voteHistoryRetry when the stream fails, and
+// fetches the vote history of the others every voteHistoryInterval.  It never
+// returns.
+func (controller *MainController) VoteHistoryHandler(dbMap *gorp.DbMap) {
+	var mtx sync.Mutex
+	following := make(map[*grpc.ClientConn]bool)
+	var polled time.Time
+
+	ticker := time.NewTicker(voteHistoryRetry)
+	defer ticker.Stop()
+
+	for {
+		var poll []*grpc.ClientConn
+		hosts, conns := controller.stakepooldBackends()
+		for i, conn := range conns {
+			mtx.Lock()
+			followed := following[conn]
+			mtx.Unlock()
+			if followed {
+				continue
+			}
+			version, err := stakepooldclient.StakepooldVersion(conn)
+			if err != nil {
+				log.Warnf("stakepoold %v Version failed: %v", hosts[i], err)
+				continue
+			}
+			if !version.Supports(stakepooldclient.CapabilityVoteHistoryStream) {
+				poll = append(poll, conn)
+				continue
+			}
+
+			mtx.Lock()
+			following[conn] = true
+			mtx.Unlock()
+			go func(host string, conn *grpc.ClientConn) {
+				err := controller.followVoteHistory(dbMap, conn)
+				log.Warnf("Vote history stream of stakepoold %v ended: %v",
+					host, err)
+				mtx.Lock()
+				delete(following, conn)
+				mtx.Unlock()
+			}(hosts[i], conn)
+		}
+
+		if len(poll) != 0 && time.Since(polled) >= voteHistoryInterval {
+			polled = time.Now()
+			if err := controller.UpdateVoteHistory(dbMap, poll); err != nil {
+				log.Errorf("UpdateVoteHistory failed: %v", err)
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// Ticket renders the timeline of one of the user's tickets.
+func (controller *MainController) Ticket(c web.C, r *http.Request) (string, int) {
+	t := controller.GetTemplate(c)
+	session := controller.GetSession(c)
+
+	if session.Values["UserId"] == nil {
+		return "/", http.StatusSeeOther
+	}
+
+	dbMap := controller.GetDbMap(c)
+	user, _ := models.GetUserById(dbMap, session.Values["UserId"].(int64))
+	if user.MultiSigAddress == "" {
+		return "/address", http.StatusSeeOther
+	}
+
+	if controller.RPCIsStopped() {
+		return "/error", http.StatusSeeOther
+	}
+
+	multisig, err := hcutil.DecodeAddress(user.MultiSigAddress)
+	if err != nil {
+		log.Infof("Invalid address %v in database: %v", user.MultiSigAddress, err)
+		return "/error", http.StatusSeeOther
+	}
+
+	w := controller.rpcServers
+	spui, err := w.StakePoolUserInfo(multisig, true)
+	if err != nil {
+		log.Infof("RPC StakePoolUserInfo failed: %v", err)
+		session.AddFlash("Unable to retrieve stake pool user info", "tickets")
+		return "/tickets", http.StatusSeeOther
+	}
+	_, height, err := w.GetBestBlock()
+	if err != nil {
+		log.Infof("RPC GetBestBlock failed: %v", err)
+		session.AddFlash("Unable to get best block height", "tickets")
+		return "/tickets", http.StatusSeeOther
+	}
+
+	hash := c.URLParams["ticket"]
+	var ticket *dcrjson.PoolUserTicket
+	if spui != nil {
+		for i := range spui.Tickets {
+			if spui.Tickets[i].Ticket == hash {
+				ticket = &spui.Tickets[i]
+				break
+			}
+		}
+	}
+	if ticket == nil {
+		session.AddFlash("Unknown ticket "+hash, "tickets")
+		return "/tickets", http.StatusSeeOther
+	}
+
+	history, err := models.GetVoteHistory(dbMap, user.Id, hash)
+	if err != nil {
+		log.Errorf("GetVoteHistory failed: %v", err)
+		return "/error", http.StatusSeeOther
+	}
+
+	c.Env["Admin"], _ = controller.isAdmin(c, r)
+	c.Env["IsTickets"] = true
+	c.Env["Network"] = controller.getNetworkName()
+	c.Env["Ticket"] = ticket
+	c.Env["Timeline"] = controller.ticketTimeline(*ticket, history, height)
+	widgets := controller.Parse(t, "ticket", c.Env)
+
+	c.Env["Title"] = "Hcd Stake Pool - Ticket"
+	c.Env["Content"] = template.HTML(widgets)
+
+	return controller.Parse(t, "main", c.Env), http.StatusOK
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/coolsnady/hcd/chaincfg"
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/coolsnady/hcstakepool/models"
+)
+
+func TestTicketTimeline(t *testing.T) {
+	mc := MainController{params: &chaincfg.TestNet2Params}
+	maturity := int64(mc.params.TicketMaturity)
+
+	steps := func(timeline []TicketTimelineEntry) []string {
+		var s []string
+		for _, e := range timeline {
+			s = append(s, e.Step)
+		}
+		return s
+	}
+	history := []models.VoteHistory{
+		{Event: models.VoteHistorySelected, BlockHeight: 500, VoteBits: 5},
+		{Event: models.VoteHistoryVoted, BlockHeight: 500, VoteHash: "vote",
+			VoteBits: 5, Reward: 100},
+	}
+
+	tests := []struct {
+		name    string
+		ticket  dcrjson.PoolUserTicket
+		history []models.VoteHistory
+		height  int64
+		steps   []string
+	}{
+		{"immature", dcrjson.PoolUserTicket{Status: "immature",
+			TicketHeight: 100}, nil, 100 + maturity - 1,
+			[]string{TicketStepPurchased}},
+		{"live", dcrjson.PoolUserTicket{Status: "live",
+			TicketHeight: 100}, nil, 100 + maturity,
+			[]string{TicketStepPurchased, TicketStepLive}},
+		{"vote sent", dcrjson.PoolUserTicket{Status: "live",
+			TicketHeight: 100}, history, 500,
+			[]string{TicketStepPurchased, TicketStepLive,
+				TicketStepSelected, TicketStepVoted}},
+		{"voted", dcrjson.PoolUserTicket{Status: "voted",
+			TicketHeight: 100, SpentBy: "vote", SpentByHeight: 501},
+			history, 600, []string{TicketStepPurchased, TicketStepLive,
+				TicketStepSelected, TicketStepVoted}},
+		{"missed and revoked", dcrjson.PoolUserTicket{Status: "missed",
+			TicketHeight: 100, SpentBy: "revocation", SpentByHeight: 510},
+			history[:1], 600, []string{TicketStepPurchased,
+				TicketStepLive, TicketStepSelected, TicketStepMissed,
+				TicketStepRevoked}},
+	}
+	for _, test := range tests {
+		timeline := mc.ticketTimeline(test.ticket, test.history, test.height)
+		got := steps(timeline)
+		if len(got) != len(test.steps) {
+			t.Errorf("%s: steps %v, want %v", test.name, got, test.steps)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.steps[i] {
+				t.Errorf("%s: steps %v, want %v", test.name, got,
+					test.steps)
+				break
+			}
+		}
+	}
+
+	// The vote step carries the vote bits and reward from the history and
+	// the height of the block the vote was mined in.
+	timeline := mc.ticketTimeline(tests[3].ticket, history, 600)
+	vote := timeline[len(timeline)-1]
+	if vote.Height != 501 || vote.Tx != "vote" || vote.VoteBits != "0x0005" ||
+		vote.Reward != 100 {
+		t.Errorf("unexpected vote step %+v", vote)
+	}
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coolsnady/hcd/dcrjson"
+	"github.com/go-gorp/gorp"
+)
+
+// voteVersionCheckInterval is how often the wallets are asked for their vote
+// version to detect stake version upgrades.
+const voteVersionCheckInterval = 10 * time.Minute
+
+// currentVoteVersion returns the vote version the wallets are voting with.
+func (controller *MainController) currentVoteVersion() uint32 {
+	controller.voteVersionMtx.RLock()
+	defer controller.voteVersionMtx.RUnlock()
+	return controller.voteVersion
+}
+
+// CheckVoteVersion fetches the vote version from the wallets.  When it has
+// changed, the users' voting preferences are migrated to the new version and
+// pushed to stakepoold.
+func (controller *MainController) CheckVoteVersion(dbMap *gorp.DbMap) error {
+	if controller.RPCIsStopped() {
+		return errors.New("RPC server stopped")
+	}
+	voteVersion, err := controller.GetVoteVersion()
+	if err != nil {
+		return err
+	}
+	if voteVersion == 0 {
+		return errors.New("wallets reported vote version 0")
+	}
+
+	controller.voteVersionMtx.Lock()
+	oldVoteVersion := controller.voteVersion
+	controller.voteVersion = voteVersion
+	controller.voteVersionMtx.Unlock()
+	if voteVersion == oldVoteVersion {
+		return nil
+	}
+
+	log.Infof("Vote Version changed from %v to %v, migrating voting "+
+		"preferences", oldVoteVersion, voteVersion)
+
+	// StakepooldUpdateAll migrates the users' VoteBits before sending them.
+	return controller.StakepooldUpdateAll(context.Background(), dbMap, StakepooldUpdateKindUsers)
+}
+
+// voteInfo fetches the agendas of voteVersion and the progress of their vote
+// from hcd.  It fails when hcd doesn't know the vote version, so choices are
+// never made on the agendas of another one.
+func (controller *MainController) voteInfo(voteVersion uint32) (*dcrjson.GetVoteInfoResult, error) {
+	if controller.RPCIsStopped() {
+		return nil, errors.New("RPC server stopped")
+	}
+	vi, err := controller.rpcServers.GetVoteInfo(voteVersion)
+	if err != nil {
+		return nil, err
+	}
+	if vi.VoteVersion != voteVersion {
+		return nil, fmt.Errorf("hcd reported the agendas of vote version %v "+
+			"instead of %v", vi.VoteVersion, voteVersion)
+	}
+
+	return vi, nil
+}
+
+// VoteVersionHandler runs CheckVoteVersion every voteVersionCheckInterval.
+// It never returns.
+func (controller *MainController) VoteVersionHandler(dbMap *gorp.DbMap) {
+	ticker := time.NewTicker(voteVersionCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := controller.CheckVoteVersion(dbMap); err != nil {
+			log.Errorf("CheckVoteVersion failed: %v", err)
+		}
+	}
+}
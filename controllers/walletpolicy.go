@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/coolsnady/hcutil"
+)
+
+// CheckVotingWallets returns how the voting wallets aren't set up as
+// voting-only wallets: they must not buy tickets, must not hold more than
+// maxBalance spendable, must own the voting addresses derived from
+// votingwalletextpub and must not own the fee addresses derived from
+// coldwalletextpub.  A wallet failing these is likely a hot wallet attached by
+// mistake.  It must be called before RPCStart.
+func (controller *MainController) CheckVotingWallets(maxBalance hcutil.Amount) ([]string, error) {
+	votingAddr, err := controller.TicketAddressForUserID(0)
+	if err != nil {
+		return nil, err
+	}
+	feeAddr, err := controller.FeeAddressForUserID(0)
+	if err != nil {
+		return nil, err
+	}
+	return controller.rpcServers.votingOnlyProblems(votingAddr, feeAddr,
+		maxBalance)
+}
+
+// votingWallet is what CheckVotingWallets learns about a voting wallet.
+type votingWallet struct {
+	ticketPurchasing bool
+	spendable        hcutil.Amount
+	ownsVotingAddr   bool
+	ownsFeeAddr      bool
+}
+
+// problems returns how wallet server i isn't set up as a voting-only wallet.
+func (v *votingWallet) problems(i int, votingAddr, feeAddr hcutil.Address,
+	maxBalance hcutil.Amount) []string {
+	var problems []string
+	if v.ticketPurchasing {
+		problems = append(problems, fmt.Sprintf("wallet server %v "+
+			"buys tickets, disable its ticket buyer", i))
+	}
+	if v.spendable > maxBalance {
+		problems = append(problems, fmt.Sprintf("wallet server %v "+
+			"holds %v spendable, more than maxwalletbalance %v", i,
+			v.spendable, maxBalance))
+	}
+	if !v.ownsVotingAddr {
+		problems = append(problems, fmt.Sprintf("wallet server %v "+
+			"doesn't own voting address %v derived from "+
+			"votingwalletextpub", i, votingAddr))
+	}
+	if v.ownsFeeAddr {
+		problems = append(problems, fmt.Sprintf("wallet server %v owns "+
+			"fee address %v derived from coldwalletextpub, whose "+
+			"keys belong in a cold wallet", i, feeAddr))
+	}
+	return problems
+}
+
+// votingOnlyProblems asks every wallet server directly, as the wallet RPC
+// handler isn't running yet at startup.
+func (w *walletSvrManager) votingOnlyProblems(votingAddr, feeAddr hcutil.Address,
+	maxBalance hcutil.Amount) ([]string, error) {
+	var problems []string
+	for i, s := range w.servers {
+		if s == nil {
+			continue
+		}
+
+		wi, err := s.WalletInfo()
+		if err != nil {
+			return nil, fmt.Errorf("walletinfo failed on server %v: %v", i, err)
+		}
+		balance, err := s.GetBalance("*")
+		if err != nil {
+			return nil, fmt.Errorf("getbalance failed on server %v: %v", i, err)
+		}
+		spendable, err := hcutil.NewAmount(balance.TotalSpendable)
+		if err != nil {
+			return nil, fmt.Errorf("invalid balance of server %v: %v", i, err)
+		}
+		voting, err := s.ValidateAddress(votingAddr)
+		if err != nil {
+			return nil, fmt.Errorf("validateaddress failed on server %v: %v",
+				i, err)
+		}
+		fee, err := s.ValidateAddress(feeAddr)
+		if err != nil {
+			return nil, fmt.Errorf("validateaddress failed on server %v: %v",
+				i, err)
+		}
+
+		v := votingWallet{
+			ticketPurchasing: wi.TicketPurchasing,
+			spendable:        spendable,
+			ownsVotingAddr:   voting.IsMine,
+			ownsFeeAddr:      fee.IsMine,
+		}
+		problems = append(problems, v.problems(i, votingAddr, feeAddr,
+			maxBalance)...)
+	}
+	return problems, nil
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coolsnady/hcutil"
+)
+
+func TestVotingWalletProblems(t *testing.T) {
+	const maxBalance = hcutil.Amount(1e8)
+	tests := []struct {
+		name   string
+		wallet votingWallet
+		want   []string
+	}{
+		{
+			name:   "voting-only",
+			wallet: votingWallet{spendable: maxBalance, ownsVotingAddr: true},
+		},
+		{
+			name: "hot wallet",
+			wallet: votingWallet{
+				ticketPurchasing: true,
+				spendable:        maxBalance + 1,
+				ownsFeeAddr:      true,
+			},
+			want: []string{"buys tickets", "more than maxwalletbalance",
+				"doesn't own voting address", "owns fee address"},
+		},
+	}
+	for _, test := range tests {
+		problems := test.wallet.problems(0, nil, nil, maxBalance)
+		if len(problems) != len(test.want) {
+			t.Errorf("%s: problems %q, want %d", test.name, problems,
+				len(test.want))
+			continue
+		}
+		for i, want := range test.want {
+			if !strings.Contains(problems[i], want) {
+				t.Errorf("%s: problem %q, want it to mention %q",
+					test.name, problems[i], want)
+			}
+		}
+	}
+}
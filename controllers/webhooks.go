@@ -0,0 +1,402 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/poolapi"
+	"github.com/coolsnady/hcutil"
+	"github.com/go-gorp/gorp"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Ticket events posted to user webhooks.
+const (
+	TicketEventSeen   = "seen"
+	TicketEventLive   = "live"
+	TicketEventVoted  = "voted"
+	TicketEventMissed = "missed"
+)
+
+const (
+	// webhookInterval is how often the users' tickets are checked for
+	// events.
+	webhookInterval = 5 * time.Minute
+
+	// webhookTimeout is how long a webhook may take to respond.
+	webhookTimeout = 10 * time.Second
+
+	// webhookSignatureHeader carries the hex encoded HMAC-SHA256 of the
+	// request body keyed with the webhook secret.
+	webhookSignatureHeader = "X-Stakepool-Signature"
+
+	// minWebhookSecretLen is the minimum length of a secret chosen by a
+	// user.
+	minWebhookSecretLen = 16
+)
+
+// ticketStatusEvents maps wallet ticket statuses to the events reported for
+// them.  Expired tickets are reported as missed since they didn't vote
+// either.  Statuses without an event, such as revoked, are not reported.
+var ticketStatusEvents = map[string]string{
+	"live":    TicketEventLive,
+	"voted":   TicketEventVoted,
+	"missed":  TicketEventMissed,
+	"expired": TicketEventMissed,
+}
+
+// webhookBlockedNets are the networks webhooks may not point into so users
+// can't make the pool probe its own network.
+var webhookBlockedNets = parseCIDRs("0.0.0.0/8", "10.0.0.0/8",
+	"100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+	"192.168.0.0/16", "::1/128", "fc00::/7", "fe80::/10")
+
+// webhookClient posts to webhooks.  It checks the addresses it connects to
+// against webhookBlockedNets, as a host name may resolve to another address
+// than when the webhook was registered, and doesn't follow redirects or use
+// proxies, which could lead into them too.
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext:         webhookDialContext,
+		TLSHandshakeTimeout: webhookTimeout,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// isBlockedWebhookIP returns whether ip is in webhookBlockedNets.
+func isBlockedWebhookIP(ip net.IP) bool {
+	for _, n := range webhookBlockedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookDialContext resolves the host of addr itself and connects to one of
+// its addresses, refusing hosts with any address in webhookBlockedNets.  The
+// address checked is the address dialed, so the host can't resolve to a
+// blocked address in between.  TLS still verifies the host name of the URL.
+func webhookDialContext(ctx context.Context, network,
+	addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if isBlockedWebhookIP(ip.IP) {
+			return nil, fmt.Errorf("webhook host %q resolves to %v, "+
+				"which is not reachable from the internet", host, ip.IP)
+		}
+	}
+
+	dialer := net.Dialer{Timeout: webhookTimeout}
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = dialer.DialContext(ctx, network,
+			net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("no addresses for webhook host %q", host)
+	}
+	return nil, err
+}
+
+// ticketEvents returns the events to report for a ticket whose status changed
+// from oldStatus, which is empty for tickets not reported before, to status.
+func ticketEvents(oldStatus, status string) []string {
+	var events []string
+	if oldStatus == "" {
+		events = append(events, TicketEventSeen)
+	}
+	if event, ok := ticketStatusEvents[status]; ok &&
+		event != ticketStatusEvents[oldStatus] {
+		events = append(events, event)
+	}
+	return events
+}
+
+// validateWebhookURL checks that a webhook URL uses https and doesn't point
+// at a host in webhookBlockedNets.  Host names are resolved when the webhook
+// is registered to reject them early, and again by webhookClient for every
+// delivery.
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return errors.New("invalid webhook URL")
+	}
+	if u.Scheme != "https" {
+		return errors.New("webhook URL must use https")
+	}
+
+	host := u.Hostname()
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		addrs, err := net.LookupIP(host)
+		if err != nil {
+			return fmt.Errorf("unable to resolve webhook host %q", host)
+		}
+		ips = addrs
+	}
+	for _, ip := range ips {
+		if isBlockedWebhookIP(ip) {
+			return fmt.Errorf("webhook host %q is not reachable "+
+				"from the internet", host)
+		}
+	}
+	return nil
+}
+
+// signWebhookPayload returns the value of webhookSignatureHeader for payload.
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWebhook posts payload to the webhook.  Responses other than 2xx are
+// errors.
+func postWebhook(webhook *models.UserWebhook, payload []byte) error {
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookSignatureHeader,
+		signWebhookPayload(webhook.Secret, payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// deliverTicketWebhook posts the events of the user's tickets since the last
+// delivery to their webhook.  The delivered statuses are only recorded once
+// the webhook accepted the events, so they are posted again next time if it
+// didn't.
+func (controller *MainController) deliverTicketWebhook(dbMap *gorp.DbMap,
+	webhook *models.UserWebhook) error {
+	user, err := models.GetUserById(dbMap, webhook.UserId)
+	if err != nil {
+		return err
+	}
+	if user.MultiSigAddress == "" {
+		return nil
+	}
+	addr, err := hcutil.DecodeAddress(user.MultiSigAddress)
+	if err != nil {
+		return err
+	}
+	spui, err := controller.rpcServers.StakePoolUserInfo(addr, true)
+	if err != nil {
+		return err
+	}
+	if spui == nil {
+		return nil
+	}
+
+	delivered, err := models.GetWebhookTicketStatuses(dbMap, user.Id)
+	if err != nil {
+		return err
+	}
+
+	var events []poolapi.TicketEvent
+	var changed []*models.WebhookTicketStatus
+	for _, t := range spui.Tickets {
+		status, ok := delivered[t.Ticket]
+		if !ok {
+			status = &models.WebhookTicketStatus{
+				UserId:     user.Id,
+				TicketHash: t.Ticket,
+			}
+		}
+		if status.Status == t.Status {
+			continue
+		}
+		for _, event := range ticketEvents(status.Status, t.Status) {
+			events = append(events, poolapi.TicketEvent{
+				Event:         event,
+				Ticket:        t.Ticket,
+				Status:        t.Status,
+				TicketHeight:  t.TicketHeight,
+				SpentBy:       t.SpentBy,
+				SpentByHeight: t.SpentByHeight,
+			})
+		}
+		status.Status = t.Status
+		changed = append(changed, status)
+	}
+
+	if len(events) > 0 {
+		payload, err := json.Marshal(&poolapi.TicketEvents{
+			UserID: user.Id,
+			Time:   time.Now().Unix(),
+			Events: events,
+		})
+		if err != nil {
+			return err
+		}
+		if err := postWebhook(webhook, payload); err != nil {
+			return err
+		}
+		log.Debugf("posted %d ticket events to the webhook of userid %v",
+			len(events), user.Id)
+	}
+
+	for _, status := range changed {
+		if err := models.SetWebhookTicketStatus(dbMap, status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverTicketWebhooks posts the ticket events of every user with a webhook.
+func (controller *MainController) DeliverTicketWebhooks(dbMap *gorp.DbMap) error {
+	if controller.RPCIsStopped() {
+		return errors.New("RPC server stopped")
+	}
+	webhooks, err := models.GetAllUserWebhooks(dbMap)
+	if err != nil {
+		return err
+	}
+
+	for i := range webhooks {
+		err := controller.deliverTicketWebhook(dbMap, &webhooks[i])
+		if err != nil {
+			log.Infof("ticket webhook for userid %v failed: %v",
+				webhooks[i].UserId, err)
+		}
+	}
+	return nil
+}
+
+// WebhookHandler runs DeliverTicketWebhooks every webhookInterval.  It never
+// returns.
+func (controller *MainController) WebhookHandler(dbMap *gorp.DbMap) {
+	ticker := time.NewTicker(webhookInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := controller.DeliverTicketWebhooks(dbMap); err != nil {
+			log.Errorf("DeliverTicketWebhooks failed: %v", err)
+		}
+	}
+}
+
+// APIWebhook returns the ticket webhook of the user.
+func (controller *MainController) APIWebhook(c web.C,
+	r *http.Request) (*poolapi.Webhook, codes.Code, string, error) {
+	if c.Env["APIUserID"] == nil {
+		return nil, codes.Unauthenticated, "webhook error", errors.New("invalid api token")
+	}
+
+	webhook, err := models.GetUserWebhook(controller.GetDbMap(c),
+		c.Env["APIUserID"].(int64))
+	if err != nil {
+		log.Errorf("GetUserWebhook failed: %v", err)
+		return nil, codes.Internal, "webhook error", errors.New("unable to fetch webhook")
+	}
+	if webhook == nil {
+		return &poolapi.Webhook{}, codes.OK, "no webhook registered", nil
+	}
+	return &poolapi.Webhook{URL: webhook.URL, Secret: webhook.Secret},
+		codes.OK, "webhook successfully retrieved", nil
+}
+
+// APIWebhookPost registers the URL the user's ticket events are posted to.  A
+// secret is generated unless one is given.  An empty URL removes the webhook.
+func (controller *MainController) APIWebhookPost(c web.C,
+	r *http.Request) (*poolapi.Webhook, codes.Code, string, error) {
+	dbMap := controller.GetDbMap(c)
+
+	if c.Env["APIUserID"] == nil {
+		return nil, codes.Unauthenticated, "webhook error", errors.New("invalid api token")
+	}
+	userID := c.Env["APIUserID"].(int64)
+
+	webhookURL := strings.TrimSpace(r.FormValue("URL"))
+	if webhookURL == "" {
+		if err := models.DeleteUserWebhook(dbMap, userID); err != nil {
+			log.Errorf("DeleteUserWebhook failed: %v", err)
+			return nil, codes.Internal, "webhook error", errors.New("unable to remove webhook")
+		}
+		return &poolapi.Webhook{}, codes.OK, "webhook successfully removed", nil
+	}
+	if err := validateWebhookURL(webhookURL); err != nil {
+		return nil, codes.InvalidArgument, "webhook error", err
+	}
+	secret := r.FormValue("Secret")
+	if secret != "" && len(secret) < minWebhookSecretLen {
+		return nil, codes.InvalidArgument, "webhook error",
+			fmt.Errorf("secret must be at least %d characters", minWebhookSecretLen)
+	}
+
+	webhook, err := models.GetUserWebhook(dbMap, userID)
+	if err != nil {
+		log.Errorf("GetUserWebhook failed: %v", err)
+		return nil, codes.Internal, "webhook error", errors.New("unable to fetch webhook")
+	}
+	if webhook == nil {
+		webhook = &models.UserWebhook{
+			UserId:  userID,
+			Secret:  randToken(),
+			Created: time.Now().Unix(),
+		}
+	}
+	webhook.URL = webhookURL
+	if secret != "" {
+		webhook.Secret = secret
+	}
+	if err := models.SetUserWebhook(dbMap, webhook); err != nil {
+		log.Errorf("SetUserWebhook failed: %v", err)
+		return nil, codes.Internal, "webhook error", errors.New("unable to save webhook")
+	}
+
+	return &poolapi.Webhook{URL: webhook.URL, Secret: webhook.Secret},
+		codes.OK, "webhook successfully registered", nil
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coolsnady/hcstakepool/models"
+)
+
+func TestIsBlockedWebhookIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"fd00::1", true},
+		{"8.8.8.8", false},
+		{"2001:4860:4860::8888", false},
+	}
+	for _, test := range tests {
+		if got := isBlockedWebhookIP(net.ParseIP(test.ip)); got != test.blocked {
+			t.Errorf("%s: blocked %v, want %v", test.ip, got, test.blocked)
+		}
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for _, u := range []string{
+		"",
+		"http://8.8.8.8/hook",
+		"https://127.0.0.1/hook",
+		"https://[::1]:8443/hook",
+		"https://10.0.0.1/hook",
+	} {
+		if err := validateWebhookURL(u); err == nil {
+			t.Errorf("%q: expected an error", u)
+		}
+	}
+	if err := validateWebhookURL("https://8.8.8.8/hook"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestPostWebhookBlocked checks that deliveries don't rely on the checks at
+// registration, as the host of a webhook may resolve to another address by
+// the time it is posted to.
+func TestPostWebhookBlocked(t *testing.T) {
+	called := false
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{
+		srv.URL,
+		"https://localhost:" + port,
+	} {
+		webhook := &models.UserWebhook{URL: u, Secret: "secret"}
+		err := postWebhook(webhook, []byte("{}"))
+		if err == nil || !strings.Contains(err.Error(), "not reachable") {
+			t.Errorf("%s: expected the address to be refused, got %v", u, err)
+		}
+	}
+	if called {
+		t.Error("the webhook was posted to a blocked address")
+	}
+}
@@ -16,27 +16,42 @@ func AddPasswordResetToken(dbMap *gorp.DbMap, email string) (*models.User, error
 	return &user, err
 }
 
-func EmailChangeComplete(dbMap *gorp.DbMap, token string) error {
-	var emailChange models.EmailChange
-
-	err := dbMap.SelectOne(&emailChange, "SELECT * FROM EmailChange WHERE Token = ?", token)
-	if err != nil {
-		return err
-	}
-
-	_, err = dbMap.Exec("UPDATE Users SET Email = ? WHERE UserId = ?",
+// EmailChangeComplete changes the email address of the user of emailChange
+// and removes it.
+func EmailChangeComplete(dbMap *gorp.DbMap, emailChange *models.EmailChange) error {
+	_, err := dbMap.Exec("UPDATE Users SET Email = ? WHERE UserId = ?",
 		emailChange.NewEmail, emailChange.UserId)
 	if err != nil {
 		return err
 	}
 
-	_, err = dbMap.Exec("DELETE FROM EmailChange WHERE Token = ?", token)
+	_, err = dbMap.Exec("DELETE FROM EmailChange WHERE EmailChangeID = ?",
+		emailChange.Id)
+	return err
+}
+
+// EmailChangeConfirm records that the link sent to the old address, when old
+// is set, or to the new address of emailChange was followed at now.
+func EmailChangeConfirm(dbMap *gorp.DbMap, emailChange *models.EmailChange,
+	old bool, now int64) error {
+	column := "NewConfirmed"
+	if old {
+		column = "OldConfirmed"
+		emailChange.OldConfirmed = now
+	} else {
+		emailChange.NewConfirmed = now
+	}
+	_, err := dbMap.Exec("UPDATE EmailChange SET "+column+" = ? WHERE "+
+		"EmailChangeID = ?", now, emailChange.Id)
 	return err
 }
 
+// EmailChangeTokenExists returns the email change with token as the token
+// sent to either its new or its old address.
 func EmailChangeTokenExists(dbMap *gorp.DbMap, token string) (*models.EmailChange, error) {
 	var emailChange models.EmailChange
-	err := dbMap.SelectOne(&emailChange, "SELECT * FROM EmailChange WHERE Token = ?", token)
+	err := dbMap.SelectOne(&emailChange, "SELECT * FROM EmailChange WHERE "+
+		"Token = ? OR OldToken = ?", token, token)
 	if err != nil {
 		return nil, err
 	}
@@ -124,6 +139,32 @@ func UpdateVoteBitsByID(dbMap *gorp.DbMap, id int64, voteBits uint16) (*models.U
 	}
 
 	user.VoteBits = int64(voteBits)
+	user.VoteBitsReconfirm = 0
+
+	_, err = dbMap.Update(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, err
+}
+
+// MigrateVoteBitsByID stores voting preferences that were carried over to a
+// new vote version.  reconfirm flags that the user needs to confirm them.
+func MigrateVoteBitsByID(dbMap *gorp.DbMap, id int64, voteBits uint16,
+	voteVersion uint32, reconfirm bool) (*models.User, error) {
+	var user models.User
+	err := dbMap.SelectOne(&user, "SELECT * FROM Users WHERE UserId = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.VoteBits = int64(voteBits)
+	user.VoteBitsVersion = int64(voteVersion)
+	user.VoteBitsReconfirm = 0
+	if reconfirm {
+		user.VoteBitsReconfirm = 1
+	}
 
 	_, err = dbMap.Update(&user)
 	if err != nil {
@@ -0,0 +1,165 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/models"
+	"github.com/go-gorp/gorp"
+)
+
+// RememberTokenLifetime is how long a browser stays signed in after it last
+// used its remember me token.
+const RememberTokenLifetime = 30 * 24 * time.Hour
+
+// rememberGracePeriod is how long the previous secret of a remember me token
+// is still accepted after it was rotated, so parallel requests presenting the
+// same cookie don't look like the use of a stolen token.
+const rememberGracePeriod = time.Minute
+
+// maxDeviceLen is the longest device description stored for a token.
+const maxDeviceLen = 255
+
+// ErrRememberTokenInvalid is returned for remember me tokens that are
+// unknown, expired or don't match.
+var ErrRememberTokenInvalid = errors.New("invalid remember me token")
+
+// randHex returns n random bytes hex encoded.
+func randHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashRememberSecret returns the hex encoded SHA-256 hash of secret, which is
+// what the database stores.
+func hashRememberSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// setRememberSecret gives token a new secret valid for RememberTokenLifetime
+// from now, saves it and returns the cookie value the browser presents.  The
+// secret of an existing token is only rotated when no parallel request
+// rotated it first, otherwise the value is empty.
+func setRememberSecret(dbMap *gorp.DbMap, token *models.RememberToken,
+	device, ip string, now time.Time) (string, error) {
+	secret, err := randHex(32)
+	if err != nil {
+		return "", err
+	}
+	if len(device) > maxDeviceLen {
+		device = device[:maxDeviceLen]
+	}
+	prevHash := token.TokenHash
+	token.TokenHash = hashRememberSecret(secret)
+	token.Device = device
+	token.IP = ip
+	token.LastUsed = now.Unix()
+	token.Expires = now.Add(RememberTokenLifetime).Unix()
+	if token.Id == 0 {
+		err = models.SaveRememberToken(dbMap, token)
+		if err != nil {
+			return "", err
+		}
+		return token.Selector + ":" + secret, nil
+	}
+
+	token.PrevTokenHash = prevHash
+	token.Rotated = now.Unix()
+	rotated, err := models.RotateRememberToken(dbMap, token, prevHash)
+	if err != nil || !rotated {
+		return "", err
+	}
+	return token.Selector + ":" + secret, nil
+}
+
+// rememberSecretMatches returns whether hash is the hash of the secret of a
+// token, hashed by hashRememberSecret.
+func rememberSecretMatches(hash, tokenHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(tokenHash)) == 1
+}
+
+// NewRememberToken creates a remember me token for the user signing in from
+// the device, described by its user agent, at ip.  It returns the cookie value
+// the browser presents.  Expired tokens of every user are removed.
+func NewRememberToken(dbMap *gorp.DbMap, userID int64, device, ip string,
+	now time.Time) (string, error) {
+	if err := models.PruneRememberTokens(dbMap, now.Unix()); err != nil {
+		return "", err
+	}
+	selector, err := randHex(16)
+	if err != nil {
+		return "", err
+	}
+	token := &models.RememberToken{
+		UserId:   userID,
+		Selector: selector,
+		Created:  now.Unix(),
+	}
+	return setRememberSecret(dbMap, token, device, ip, now)
+}
+
+// UseRememberToken checks the remember me token in the cookie value and
+// rotates its secret.  It returns the user of the token and the new cookie
+// value, which is empty when a parallel request presenting the same cookie
+// rotated the secret and sets the new cookie instead.  The previous secret is
+// accepted for rememberGracePeriod after the rotation for the same reason.  A
+// token presented with any other secret may have been stolen and is revoked.
+func UseRememberToken(dbMap *gorp.DbMap, value, device, ip string,
+	now time.Time) (int64, string, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return 0, "", ErrRememberTokenInvalid
+	}
+	token, err := models.GetRememberToken(dbMap, parts[0])
+	if err != nil {
+		return 0, "", err
+	}
+	if token == nil || token.Expires < now.Unix() {
+		return 0, "", ErrRememberTokenInvalid
+	}
+	hash := hashRememberSecret(parts[1])
+	graceEnd := token.Rotated + int64(rememberGracePeriod/time.Second)
+	switch {
+	case rememberSecretMatches(hash, token.TokenHash):
+	case rememberSecretMatches(hash, token.PrevTokenHash) &&
+		now.Unix() <= graceEnd:
+		return token.UserId, "", nil
+	default:
+		_, err = models.DeleteRememberToken(dbMap, token.UserId, token.Id)
+		if err != nil {
+			return 0, "", err
+		}
+		return 0, "", ErrRememberTokenInvalid
+	}
+
+	value, err = setRememberSecret(dbMap, token, device, ip, now)
+	if err != nil {
+		return 0, "", err
+	}
+	return token.UserId, value, nil
+}
+
+// RememberTokenSelector returns the selector of the remember me token in the
+// cookie value, which identifies the token without its secret.
+func RememberTokenSelector(value string) string {
+	return strings.SplitN(value, ":", 2)[0]
+}
+
+// RevokeRememberToken removes the remember me token in the cookie value.
+func RevokeRememberToken(dbMap *gorp.DbMap, value string) error {
+	token, err := models.GetRememberToken(dbMap, RememberTokenSelector(value))
+	if err != nil || token == nil {
+		return err
+	}
+	_, err = models.DeleteRememberToken(dbMap, token.UserId, token.Id)
+	return err
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package i18n translates the text of the frontend templates and emails.
+// Messages are identified by their English text, which is also what is shown
+// when a locale has no translation for a message, so untranslated text falls
+// back to English.
+//
+// A locale is a JSON file in the locale directory named after its language
+// tag, such as es.json or pt-br.json, holding an object of English messages to
+// their translations.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultLanguage is the language of the messages themselves.
+const DefaultLanguage = "en"
+
+// Catalog holds the translations of every locale.  It is not changed after
+// it is loaded so it is safe for concurrent access.  A nil Catalog only knows
+// English.
+type Catalog struct {
+	locales map[string]map[string]string
+}
+
+// Load reads the locales in dir.  A missing dir results in a catalog that only
+// knows English.
+func Load(dir string) (*Catalog, error) {
+	c := &Catalog{locales: make(map[string]map[string]string)}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return c, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(b, &messages); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		lang := strings.ToLower(strings.TrimSuffix(filepath.Base(file),
+			".json"))
+		c.locales[lang] = messages
+	}
+	return c, nil
+}
+
+// Languages returns the tags of the known languages, English first.
+func (c *Catalog) Languages() []string {
+	var langs []string
+	if c != nil {
+		for lang := range c.locales {
+			if lang != DefaultLanguage {
+				langs = append(langs, lang)
+			}
+		}
+	}
+	sort.Strings(langs)
+	return append([]string{DefaultLanguage}, langs...)
+}
+
+// Has returns whether lang is a known language.
+func (c *Catalog) Has(lang string) bool {
+	lang = strings.ToLower(lang)
+	if lang == DefaultLanguage {
+		return true
+	}
+	if c == nil {
+		return false
+	}
+	_, ok := c.locales[lang]
+	return ok
+}
+
+// T translates msg to lang, or returns it as is when lang has no translation
+// for it.  When args are passed, the result is used as their format.
+func (c *Catalog) T(lang, msg string, args ...interface{}) string {
+	if c != nil {
+		if t, ok := c.locales[strings.ToLower(lang)][msg]; ok && t != "" {
+			msg = t
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Funcs returns the template functions of c.  T translates a message to a
+// language, as in {{T .Lang "Sign in"}}, and uses English when the language
+// is missing from the template data.
+func (c *Catalog) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"T": func(lang interface{}, msg string, args ...interface{}) string {
+			l, _ := lang.(string)
+			return c.T(l, msg, args...)
+		},
+	}
+}
+
+// Match returns the first known language of an Accept-Language header, or
+// DefaultLanguage when none is known.  A language with a region, such as
+// es-AR, also matches the language without one.
+func (c *Catalog) Match(acceptLanguage string) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		if i := strings.Index(tag, ";"); i >= 0 {
+			tag = tag[:i]
+		}
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if c.Has(tag) {
+			return tag
+		}
+		if i := strings.Index(tag, "-"); i > 0 && c.Has(tag[:i]) {
+			return tag[:i]
+		}
+	}
+	return DefaultLanguage
+}
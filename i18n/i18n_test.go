@@ -0,0 +1,82 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package i18n
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "i18n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "es.json"),
+		[]byte(`{"Sign in": "Iniciar sesión", "Hello, %s": "Hola, %s"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if langs := c.Languages(); !reflect.DeepEqual(langs, []string{"en", "es"}) {
+		t.Errorf("Languages() = %v", langs)
+	}
+	tests := []struct {
+		lang, msg string
+		args      []interface{}
+		want      string
+	}{
+		{"es", "Sign in", nil, "Iniciar sesión"},
+		{"ES", "Sign in", nil, "Iniciar sesión"},
+		{"es", "Hello, %s", []interface{}{"ana"}, "Hola, ana"},
+		{"es", "Sign up", nil, "Sign up"},
+		{"en", "Sign in", nil, "Sign in"},
+		{"de", "Hello, %s", []interface{}{"ana"}, "Hello, ana"},
+	}
+	for _, test := range tests {
+		if got := c.T(test.lang, test.msg, test.args...); got != test.want {
+			t.Errorf("T(%q, %q) = %q, want %q", test.lang, test.msg,
+				got, test.want)
+		}
+	}
+
+	matches := map[string]string{
+		"es-AR,es;q=0.9,en;q=0.8": "es",
+		"de-DE,de;q=0.9":          "en",
+		"fr, es;q=0.5":            "es",
+		"":                        "en",
+	}
+	for header, want := range matches {
+		if got := c.Match(header); got != want {
+			t.Errorf("Match(%q) = %q, want %q", header, got, want)
+		}
+	}
+
+	tmpl := template.Must(template.New("").Funcs(c.Funcs()).Parse(
+		`{{T .Lang "Sign in"}} {{T .Missing "Sign in"}}`))
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]interface{}{"Lang": "es"}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "Iniciar sesión Sign in" {
+		t.Errorf("template rendered %q", buf.String())
+	}
+
+	// Without locales only English is known.
+	if c, err := Load(filepath.Join(dir, "missing")); err != nil ||
+		c.Has("es") || c.Match("es") != DefaultLanguage {
+		t.Errorf("Load of a missing dir = %v, %v", c, err)
+	}
+}
@@ -13,8 +13,12 @@ import (
 	"github.com/btcsuite/btclog"
 	"github.com/coolsnady/hcstakepool/controllers"
 	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/notifier"
+	"github.com/coolsnady/hcstakepool/pricefeed"
+	"github.com/coolsnady/hcstakepool/scrub"
 	"github.com/coolsnady/hcstakepool/stakepooldclient"
 	"github.com/coolsnady/hcstakepool/system"
+	"github.com/coolsnady/hcstakepool/tracing"
 	"github.com/jrick/logrotate/rotator"
 )
 
@@ -23,9 +27,11 @@ import (
 type logWriter struct{}
 
 func (logWriter) Write(p []byte) (n int, err error) {
+	n = len(p)
+	p = scrub.Bytes(p)
 	os.Stdout.Write(p)
 	logRotator.Write(p)
-	return len(p), nil
+	return n, nil
 }
 
 // Loggers per subsystem.  A single backend logger is created and all subsytem
@@ -50,16 +56,22 @@ var (
 	controllersLog      = backendLog.Logger("CNTL")
 	log                 = backendLog.Logger("HXS")
 	modelsLog           = backendLog.Logger("MODL")
+	notifierLog         = backendLog.Logger("NTFY")
+	pricefeedLog        = backendLog.Logger("PRCE")
 	stakepooldclientLog = backendLog.Logger("GRPC")
 	systemLog           = backendLog.Logger("SYTM")
+	tracingLog          = backendLog.Logger("TRCE")
 )
 
 // Initialize package-global logger variables.
 func init() {
 	controllers.UseLogger(controllersLog)
 	models.UseLogger(modelsLog)
+	notifier.UseLogger(notifierLog)
+	pricefeed.UseLogger(pricefeedLog)
 	stakepooldclient.UseLogger(stakepooldclientLog)
 	system.UseLogger(systemLog)
+	tracing.UseLogger(tracingLog)
 }
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
@@ -68,7 +80,10 @@ var subsystemLoggers = map[string]btclog.Logger{
 	"CNTL": controllersLog,
 	"GRPC": stakepooldclientLog,
 	"MODL": modelsLog,
+	"NTFY": notifierLog,
+	"PRCE": pricefeedLog,
 	"SYTM": systemLog,
+	"TRCE": tracingLog,
 }
 
 // initLogRotator initializes the logging rotater to write logs to logFile and
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-gorp/gorp"
+)
+
+// personalDataTables are the tables with personal data of users that is
+// removed along with their accounts.  The vote history and the audit log are
+// kept as records of the pool, the latter without the personal data.
+var personalDataTables = []string{
+	"EmailChange",
+	"EmailTicketStatus",
+	"PasswordReset",
+	"RememberToken",
+	"SecurityEvent",
+	"TicketExpiryWarning",
+	"UserLoginIP",
+	"UserPreferences",
+	"UserWebhook",
+	"WebhookTicketStatus",
+}
+
+// DeletedUserEmail is what the email address and username of the user with id
+// are replaced with when they delete their account.  It can't be signed up
+// with as it is no email address.
+func DeletedUserEmail(id int64) string {
+	return fmt.Sprintf("deleted-%d", id)
+}
+
+// DeleteUserAccount removes the personal data of user and marks the account
+// deleted at now.  The user can no longer sign in or use their API token, but
+// the addresses and voting preferences their tickets vote with are kept so
+// the tickets still vote and can be accounted for.  The deletion is done in a
+// single transaction so a failed deletion changes nothing and can be retried.
+func DeleteUserAccount(dbMap *gorp.DbMap, user *User, now int64) error {
+	tx, err := dbMap.Begin()
+	if err != nil {
+		return err
+	}
+	if err := deleteUserAccount(tx, user, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func deleteUserAccount(tx gorp.SqlExecutor, user *User, now int64) error {
+	for _, table := range personalDataTables {
+		_, err := tx.Exec("DELETE FROM "+table+" WHERE UserId = ?",
+			user.Id)
+		if err != nil {
+			return err
+		}
+	}
+	err := DeleteLoginAttempt(tx, LoginAttemptEmail,
+		strings.ToLower(user.Email))
+	if err != nil {
+		return err
+	}
+	if err := scrubUserAuditLog(tx, user); err != nil {
+		return err
+	}
+
+	deleted := DeletedUserEmail(user.Id)
+	_, err = tx.Exec("UPDATE Users SET Email = ?, Username = ?, "+
+		"Password = ?, EmailVerified = 0, EmailToken = '', APIToken = '', "+
+		"Deleted = ? WHERE UserId = ?", deleted, deleted, []byte{}, now,
+		user.Id)
+	return err
+}
+
+// scrubUserAuditLog removes the personal data of user from the audit log: the
+// IP addresses of their actions, the values changed by them or on their
+// account, like their email addresses, and their email address in the
+// unlocks of their sign ins.  The entries themselves are kept.  BEFORE and
+// AFTER are reserved words in MySQL so the columns are quoted.
+func scrubUserAuditLog(tx gorp.SqlExecutor, user *User) error {
+	_, err := tx.Exec("UPDATE AuditLog SET ActorIP = '', `Before` = '', "+
+		"`After` = '' WHERE ActorUid = ?", user.Id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE AuditLog SET `Before` = '', `After` = '' "+
+		"WHERE Target = ?", strconv.FormatInt(user.Id, 10))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE AuditLog SET Target = ?, `Before` = '', "+
+		"`After` = '' WHERE Target = ?",
+		LoginAttemptEmail+":"+DeletedUserEmail(user.Id),
+		LoginAttemptEmail+":"+strings.ToLower(user.Email))
+	return err
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-gorp/gorp"
+)
+
+// recordingDriver is a database/sql driver that records the statements run
+// on it and fails those containing failOn.
+type recordingDriver struct {
+	mtx    sync.Mutex
+	stmts  []string
+	args   [][]driver.Value
+	failOn string
+}
+
+var testDriver = &recordingDriver{}
+
+func init() {
+	sql.Register("modelstest", testDriver)
+}
+
+func (d *recordingDriver) reset(failOn string) {
+	d.mtx.Lock()
+	d.stmts, d.args, d.failOn = nil, nil, failOn
+	d.mtx.Unlock()
+}
+
+func (d *recordingDriver) record(stmt string, args []driver.Value) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.stmts = append(d.stmts, stmt)
+	d.args = append(d.args, args)
+	if d.failOn != "" && strings.Contains(stmt, d.failOn) {
+		return errors.New("statement failed")
+	}
+	return nil
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	return &recordingConn{d}, nil
+}
+
+type recordingConn struct {
+	d *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return c, c.d.record("BEGIN", nil)
+}
+
+func (c *recordingConn) Commit() error { return c.d.record("COMMIT", nil) }
+
+func (c *recordingConn) Rollback() error { return c.d.record("ROLLBACK", nil) }
+
+func (c *recordingConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	if err := c.d.record(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func testDbMap(t *testing.T, failOn string) *gorp.DbMap {
+	db, err := sql.Open("modelstest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDriver.reset(failOn)
+	return &gorp.DbMap{Db: db, Dialect: gorp.MySQLDialect{Engine: "InnoDB", Encoding: "UTF8MB4"}}
+}
+
+func TestDeleteUserAccount(t *testing.T) {
+	dbMap := testDbMap(t, "")
+	defer dbMap.Db.Close()
+	user := &User{Id: 7, Email: "Alice@Example.com"}
+	if err := DeleteUserAccount(dbMap, user, 1500000000); err != nil {
+		t.Fatalf("DeleteUserAccount: %v", err)
+	}
+
+	stmts := testDriver.stmts
+	if stmts[0] != "BEGIN" || stmts[len(stmts)-1] != "COMMIT" {
+		t.Errorf("deletion not run in a transaction: %q", stmts)
+	}
+	if n := len(stmts) - 2; n != len(personalDataTables)+5 {
+		t.Errorf("ran %d statements, want %d", n, len(personalDataTables)+5)
+	}
+	if !strings.HasPrefix(stmts[len(stmts)-2], "UPDATE Users ") {
+		t.Errorf("user row not changed last: %q", stmts[len(stmts)-2])
+	}
+	var scrubs int
+	for i, stmt := range stmts {
+		if !strings.HasPrefix(stmt, "UPDATE AuditLog ") {
+			continue
+		}
+		scrubs++
+		if !strings.Contains(stmt, "`Before` = ''") ||
+			!strings.Contains(stmt, "`After` = ''") {
+			t.Errorf("audit log columns not quoted: %q", stmt)
+		}
+		if strings.Contains(stmt, "SET Target = ?") {
+			args := testDriver.args[i]
+			if args[0] != "email:deleted-7" ||
+				args[1] != "email:alice@example.com" {
+				t.Errorf("unlock targets %q, want the deleted and "+
+					"lowercased email", args)
+			}
+		}
+	}
+	if scrubs != 3 {
+		t.Errorf("scrubbed the audit log with %d statements, want 3", scrubs)
+	}
+}
+
+func TestDeleteUserAccountRollback(t *testing.T) {
+	dbMap := testDbMap(t, "UPDATE AuditLog")
+	defer dbMap.Db.Close()
+	user := &User{Id: 7, Email: "alice@example.com"}
+	if err := DeleteUserAccount(dbMap, user, 1500000000); err == nil {
+		t.Fatal("DeleteUserAccount succeeded with a failing statement")
+	}
+
+	stmts := testDriver.stmts
+	if stmts[len(stmts)-1] != "ROLLBACK" {
+		t.Errorf("failed deletion not rolled back: %q", stmts)
+	}
+	for _, stmt := range stmts {
+		if stmt == "COMMIT" || strings.HasPrefix(stmt, "UPDATE Users ") {
+			t.Errorf("failed deletion ran %q", stmt)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strconv"
+
+	"github.com/go-gorp/gorp"
+)
+
+// auditValueMaxSize makes gorp create the Before and After columns as TEXT so
+// they can hold more than a short string.
+const auditValueMaxSize = 65535
+
+// AuditLog is an administrative action or a change of account recovery
+// details, such as the email address.  Before and After are the JSON encoded
+// values of what the action changed, and are empty when it created or removed
+// something.  There is no way to change or remove entries, so the table is the
+// complete history of what admins did.
+type AuditLog struct {
+	Id       int64 `db:"AuditLogID"`
+	ActorUid int64
+	ActorIP  string
+	Action   string
+	Target   string
+	Before   string
+	After    string
+	Created  int64
+}
+
+// AuditLogListFields are the fields the audit log can be listed by.
+var AuditLogListFields = &ListFields{
+	Sort: map[string]string{
+		"id":      "AuditLogID",
+		"created": "Created",
+	},
+	Filter: map[string]string{
+		"action": "Action",
+		"actor":  "ActorUid",
+		"target": "Target",
+	},
+	DefaultSort: "id",
+}
+
+// InsertAuditLog appends an entry to the audit log.
+func InsertAuditLog(dbMap *gorp.DbMap, entry *AuditLog) error {
+	return dbMap.Insert(entry)
+}
+
+// GetUserAuditLog returns the entries of the actions of the user and of the
+// changes of their account, oldest first.
+func GetUserAuditLog(dbMap *gorp.DbMap, userID int64) ([]AuditLog, error) {
+	var entries []AuditLog
+	_, err := dbMap.Select(&entries, "SELECT * FROM AuditLog WHERE "+
+		"ActorUid = ? OR (Action LIKE 'user.%' AND Target = ?) ORDER BY "+
+		"AuditLogID", userID, strconv.FormatInt(userID, 10))
+	return entries, err
+}
+
+// ListAuditLog returns a page of the audit log along with the total number of
+// entries matching the query.
+func ListAuditLog(dbMap *gorp.DbMap, q *ListQuery) ([]AuditLog, int64, error) {
+	var entries []AuditLog
+	total, err := AuditLogListFields.list(dbMap, &entries, "*", "AuditLog",
+		"AuditLogID", q, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"github.com/go-gorp/gorp"
+)
+
+// inviteCodeMaxSize is the size of the InviteCode column holding the codes.
+const inviteCodeMaxSize = 32
+
+// InviteCode is a single-use code admins issue to let someone sign up while
+// the pool only accepts sign ups by invite.  Used is when it was redeemed,
+// by the user UsedBy, and Revoked when admins withdrew it, or 0.
+type InviteCode struct {
+	Id        int64 `db:"InviteCodeID"`
+	Code      string
+	Note      string
+	CreatedBy int64
+	Created   int64
+	Expires   int64
+	UsedBy    int64
+	Used      int64
+	Revoked   int64
+}
+
+// InsertInviteCode inserts an invite code.
+func InsertInviteCode(dbMap *gorp.DbMap, code *InviteCode) error {
+	return dbMap.Insert(code)
+}
+
+// GetInviteCodes returns the last limit invite codes issued, newest first.
+func GetInviteCodes(dbMap *gorp.DbMap, limit int) ([]InviteCode, error) {
+	var codes []InviteCode
+	_, err := dbMap.Select(&codes, "SELECT * FROM InviteCode ORDER BY "+
+		"InviteCodeID DESC LIMIT ?", limit)
+	return codes, err
+}
+
+// ClaimInviteCode marks code used at now if it is still unused, unrevoked and
+// unexpired, and returns whether it was.  Only one of concurrent claims of a
+// code succeeds.  SetInviteCodeUser records the user who signed up with it.
+func ClaimInviteCode(dbMap *gorp.DbMap, code string, now int64) (bool, error) {
+	res, err := dbMap.Exec("UPDATE InviteCode SET Used = ? WHERE Code = ? "+
+		"AND Used = 0 AND Revoked = 0 AND Expires > ?", now, code, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReleaseInviteCode makes a claimed code usable again after the sign up it
+// was claimed for failed.
+func ReleaseInviteCode(dbMap *gorp.DbMap, code string) error {
+	_, err := dbMap.Exec("UPDATE InviteCode SET Used = 0 WHERE Code = ? AND "+
+		"UsedBy = 0", code)
+	return err
+}
+
+// SetInviteCodeUser records the user with userID signed up with code.
+func SetInviteCodeUser(dbMap *gorp.DbMap, code string, userID int64) error {
+	_, err := dbMap.Exec("UPDATE InviteCode SET UsedBy = ? WHERE Code = ?",
+		userID, code)
+	return err
+}
+
+// RevokeInviteCode withdraws the unused invite code with id at now and returns
+// whether it was unused.
+func RevokeInviteCode(dbMap *gorp.DbMap, id, now int64) (bool, error) {
+	res, err := dbMap.Exec("UPDATE InviteCode SET Revoked = ? WHERE "+
+		"InviteCodeID = ? AND Used = 0 AND Revoked = 0", now, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,225 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-gorp/gorp"
+)
+
+const (
+	// DefaultListLimit is the page size of list endpoints when the client
+	// doesn't ask for one.
+	DefaultListLimit = 50
+
+	// MaxListLimit is the largest page size list endpoints return.
+	MaxListLimit = 500
+)
+
+// ListQuery selects a page of a list.  Sort and the Filters keys are field
+// names as exposed to clients, not database columns.
+type ListQuery struct {
+	Limit   int
+	Offset  int
+	Sort    string
+	Desc    bool
+	Filters map[string]string // [field]value
+}
+
+// ListFields maps the field names a list exposes for sorting and filtering to
+// their database columns.  Only these fields can be used, which keeps client
+// input out of the SQL.
+type ListFields struct {
+	Sort        map[string]string
+	Filter      map[string]string
+	DefaultSort string
+}
+
+// UserListFields are the fields users can be listed by.
+var UserListFields = &ListFields{
+	Sort: map[string]string{
+		"id":         "UserId",
+		"email":      "Email",
+		"registered": "HeightRegistered",
+	},
+	Filter: map[string]string{
+		"email":           "Email",
+		"emailverified":   "EmailVerified",
+		"multisigaddress": "MultiSigAddress",
+	},
+	DefaultSort: "id",
+}
+
+// LowFeeTicketListFields are the fields low fee tickets can be listed by.
+var LowFeeTicketListFields = &ListFields{
+	Sort: map[string]string{
+		"id":      "LowFeeTicketID",
+		"created": "Created",
+		"expires": "Expires",
+	},
+	Filter: map[string]string{
+		"address": "TicketAddress",
+		"voted":   "Voted",
+	},
+	DefaultSort: "id",
+}
+
+// Validate checks that the query only uses known fields and a page size
+// within bounds.
+func (f *ListFields) Validate(q *ListQuery) error {
+	if q.Limit <= 0 || q.Limit > MaxListLimit {
+		return fmt.Errorf("limit must be between 1 and %d", MaxListLimit)
+	}
+	if q.Offset < 0 {
+		return fmt.Errorf("offset may not be negative")
+	}
+	if _, ok := f.Sort[q.Sort]; !ok {
+		return fmt.Errorf("can't sort by %q", q.Sort)
+	}
+	for field := range q.Filters {
+		if _, ok := f.Filter[field]; !ok {
+			return fmt.Errorf("can't filter by %q", field)
+		}
+	}
+	return nil
+}
+
+// where returns the WHERE clause for the query's filters and its arguments.
+// scope, when not empty, is a condition with scopeArgs that limits the rows to
+// those the caller may see, such as those of a single user.
+func (f *ListFields) where(q *ListQuery, scope string,
+	scopeArgs []interface{}) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if scope != "" {
+		conds = append(conds, scope)
+		args = append(args, scopeArgs...)
+	}
+	fields := make([]string, 0, len(q.Filters))
+	for field := range q.Filters {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		conds = append(conds, f.Filter[field]+" = ?")
+		args = append(args, q.Filters[field])
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// page returns the ORDER BY and LIMIT clauses for the query.  The primary
+// key is used as a tie breaker so pages don't overlap.
+func (f *ListFields) page(q *ListQuery, key string) string {
+	dir := "ASC"
+	if q.Desc {
+		dir = "DESC"
+	}
+	order := f.Sort[q.Sort] + " " + dir
+	if f.Sort[q.Sort] != key {
+		order += ", " + key + " " + dir
+	}
+	return fmt.Sprintf(" ORDER BY %s LIMIT %d OFFSET %d", order, q.Limit,
+		q.Offset)
+}
+
+// list selects a page of rows from table into rows and returns the total
+// number of rows matching the query's filters.  scope and scopeArgs are passed
+// to where.
+func (f *ListFields) list(dbMap *gorp.DbMap, rows interface{}, columns,
+	table, key string, q *ListQuery, scope string,
+	scopeArgs ...interface{}) (int64, error) {
+	if err := f.Validate(q); err != nil {
+		return 0, err
+	}
+
+	where, args := f.where(q, scope, scopeArgs)
+	total, err := dbMap.SelectInt("SELECT COUNT(*) FROM "+table+where,
+		args...)
+	if err != nil {
+		return 0, err
+	}
+	_, err = dbMap.Select(rows, "SELECT "+columns+" FROM "+table+where+
+		f.page(q, key), args...)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// userListColumns are the columns of users returned to admins, which leave
+// out their credentials.
+const userListColumns = "UserId, Email, Username, MultiSigAddress, " +
+	"UserFeeAddr, HeightRegistered, EmailVerified, VoteBits, " +
+	"VoteBitsVersion, VotingSuspended"
+
+// ListUsers returns a page of users without their credentials along with the
+// total number of users matching the query.
+func ListUsers(dbMap *gorp.DbMap, q *ListQuery) ([]User, int64, error) {
+	var users []User
+	total, err := UserListFields.list(dbMap, &users, userListColumns,
+		"Users", "UserId", q, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SearchUsers returns up to limit users, without their credentials, whose
+// email address contains term or whose multisig, fee or public key address is
+// term.
+func SearchUsers(dbMap *gorp.DbMap, term string, limit int) ([]User, error) {
+	var users []User
+	_, err := dbMap.Select(&users, "SELECT "+userListColumns+" FROM Users "+
+		"WHERE Email LIKE ? OR MultiSigAddress = ? OR UserFeeAddr = ? OR "+
+		"UserPubKeyAddr = ? ORDER BY UserId LIMIT ?",
+		"%"+likeEscaper.Replace(term)+"%", term, term, term, limit)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ListLowFeeTickets returns a page of low fee tickets along with the total
+// number of low fee tickets matching the query.
+func ListLowFeeTickets(dbMap *gorp.DbMap, q *ListQuery) ([]LowFeeTicket, int64, error) {
+	var tickets []LowFeeTicket
+	total, err := LowFeeTicketListFields.list(dbMap, &tickets, "*",
+		"LowFeeTicket", "LowFeeTicketID", q, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	return tickets, total, nil
+}
+
+// NotificationListFields are the fields the ticket notifications of a user can
+// be listed by.
+var NotificationListFields = &ListFields{
+	Sort: map[string]string{
+		"id":     "EmailTicketStatusID",
+		"ticket": "TicketHash",
+	},
+	Filter: map[string]string{
+		"status": "Status",
+	},
+	DefaultSort: "id",
+}
+
+// ListNotifications returns a page of the last ticket statuses the user was
+// emailed about along with the total number of them matching the query.
+func ListNotifications(dbMap *gorp.DbMap, userID int64, q *ListQuery) ([]EmailTicketStatus, int64, error) {
+	var statuses []EmailTicketStatus
+	total, err := NotificationListFields.list(dbMap, &statuses, "*",
+		"EmailTicketStatus", "EmailTicketStatusID", q, "UserId = ?",
+		userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return statuses, total, nil
+}
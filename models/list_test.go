@@ -0,0 +1,77 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListFieldsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		q    ListQuery
+		ok   bool
+	}{
+		{"default", ListQuery{Limit: DefaultListLimit, Sort: "id"}, true},
+		{"max limit", ListQuery{Limit: MaxListLimit, Sort: "email"}, true},
+		{"filter", ListQuery{Limit: 1, Sort: "id",
+			Filters: map[string]string{"email": "a@example.com"}}, true},
+		{"zero limit", ListQuery{Sort: "id"}, false},
+		{"large limit", ListQuery{Limit: MaxListLimit + 1, Sort: "id"}, false},
+		{"negative offset", ListQuery{Limit: 1, Offset: -1, Sort: "id"}, false},
+		{"unknown sort", ListQuery{Limit: 1, Sort: "Password"}, false},
+		{"unknown filter", ListQuery{Limit: 1, Sort: "id",
+			Filters: map[string]string{"password": "x"}}, false},
+	}
+	for _, test := range tests {
+		err := UserListFields.Validate(&test.q)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: got error %v, want ok %v", test.name, err, test.ok)
+		}
+	}
+}
+
+func TestListFieldsWhere(t *testing.T) {
+	q := &ListQuery{Filters: map[string]string{
+		"multisigaddress": "HcMsa1",
+		"email":           "a@example.com",
+	}}
+	where, args := UserListFields.where(q, "", nil)
+	if want := " WHERE Email = ? AND MultiSigAddress = ?"; where != want {
+		t.Errorf("got %q, want %q", where, want)
+	}
+	if want := []interface{}{"a@example.com", "HcMsa1"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("got args %v, want %v", args, want)
+	}
+
+	q = &ListQuery{Filters: map[string]string{"status": "voted"}}
+	where, args = NotificationListFields.where(q, "UserId = ?",
+		[]interface{}{int64(7)})
+	if want := " WHERE UserId = ? AND Status = ?"; where != want {
+		t.Errorf("got %q, want %q", where, want)
+	}
+	if want := []interface{}{int64(7), "voted"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("got args %v, want %v", args, want)
+	}
+
+	where, args = UserListFields.where(&ListQuery{}, "", nil)
+	if where != "" || len(args) != 0 {
+		t.Errorf("got %q %v without filters", where, args)
+	}
+}
+
+func TestListFieldsPage(t *testing.T) {
+	tests := []struct {
+		q    ListQuery
+		want string
+	}{
+		{ListQuery{Limit: 50, Sort: "id"},
+			" ORDER BY UserId ASC LIMIT 50 OFFSET 0"},
+		{ListQuery{Limit: 10, Offset: 20, Sort: "registered", Desc: true},
+			" ORDER BY HeightRegistered DESC, UserId DESC LIMIT 10 OFFSET 20"},
+	}
+	for _, test := range tests {
+		if got := UserListFields.page(&test.q, "UserId"); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
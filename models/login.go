@@ -0,0 +1,231 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/go-gorp/gorp"
+)
+
+// Kinds of subjects whose failed sign ins are counted.
+const (
+	LoginAttemptEmail = "email"
+	LoginAttemptIP    = "ip"
+)
+
+// LoginAttempt counts the recent failed sign ins for an email address or from
+// an IP address.  LastFailure is when the last one happened and LockedUntil is
+// when a lockout ends, or 0.
+type LoginAttempt struct {
+	Id          int64 `db:"LoginAttemptID"`
+	Kind        string
+	Subject     string
+	Failures    int64
+	LastFailure int64
+	LockedUntil int64
+}
+
+// GetLoginAttempt returns the failed sign ins for subject, or nil when there
+// were none.
+func GetLoginAttempt(dbMap *gorp.DbMap, kind, subject string) (*LoginAttempt, error) {
+	var attempt LoginAttempt
+	err := dbMap.SelectOne(&attempt, "SELECT * FROM LoginAttempt WHERE "+
+		"Kind = ? AND Subject = ?", kind, subject)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// RecordLoginFailure counts a failed sign in at now for subject, forgetting
+// the failures from before windowStart first, and locks it out until
+// lockedUntil once it reaches lockoutFailures failures.  It returns whether
+// this failure locked it out.  Each step is a single statement on the unique
+// (Kind, Subject) key, so concurrent failures are neither lost nor recorded
+// twice.
+func RecordLoginFailure(dbMap *gorp.DbMap, kind, subject string, now,
+	windowStart, lockoutFailures, lockedUntil int64) (bool, error) {
+	// MySQL assigns the columns in order, so Failures sees the previous
+	// LastFailure.
+	_, err := dbMap.Exec("INSERT INTO LoginAttempt (Kind, Subject, "+
+		"Failures, LastFailure, LockedUntil) VALUES (?, ?, 1, ?, 0) "+
+		"ON DUPLICATE KEY UPDATE "+
+		"Failures = IF(LastFailure < ?, 0, Failures) + 1, "+
+		"LastFailure = VALUES(LastFailure)", kind, subject, now,
+		windowStart)
+	if err != nil {
+		return false, err
+	}
+	res, err := dbMap.Exec("UPDATE LoginAttempt SET Failures = 0, "+
+		"LockedUntil = ? WHERE Kind = ? AND Subject = ? AND Failures >= ?",
+		lockedUntil, kind, subject, lockoutFailures)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteLoginAttempt forgets the failed sign ins for subject, which also ends
+// any lockout.
+func DeleteLoginAttempt(dbMap gorp.SqlExecutor, kind, subject string) error {
+	_, err := dbMap.Exec("DELETE FROM LoginAttempt WHERE Kind = ? AND "+
+		"Subject = ?", kind, subject)
+	return err
+}
+
+// PruneLoginAttempts removes the failed sign ins last seen before before that
+// no longer lock anything out.
+func PruneLoginAttempts(dbMap *gorp.DbMap, before int64) error {
+	_, err := dbMap.Exec("DELETE FROM LoginAttempt WHERE LastFailure < ? "+
+		"AND LockedUntil < ?", before, before)
+	return err
+}
+
+// GetLockedLoginAttempts returns the email and IP addresses locked out at now,
+// the ones locked out the longest first.
+func GetLockedLoginAttempts(dbMap *gorp.DbMap, now int64) ([]LoginAttempt, error) {
+	var attempts []LoginAttempt
+	_, err := dbMap.Select(&attempts, "SELECT * FROM LoginAttempt WHERE "+
+		"LockedUntil > ? ORDER BY LockedUntil DESC", now)
+	return attempts, err
+}
+
+// UserLoginIP is an IP address a user signed in from.
+type UserLoginIP struct {
+	Id        int64 `db:"UserLoginIPID"`
+	UserId    int64
+	IP        string
+	FirstSeen int64
+	LastSeen  int64
+}
+
+// RecordUserLoginIP records that the user signed in from ip at now and
+// returns whether it was the first time.  It is a single upsert on the unique
+// (UserId, IP) key, so concurrent sign ins from the same address record it
+// once.
+func RecordUserLoginIP(dbMap *gorp.DbMap, userID int64, ip string,
+	now int64) (bool, error) {
+	res, err := dbMap.Exec("INSERT INTO UserLoginIP (UserId, IP, "+
+		"FirstSeen, LastSeen) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE "+
+		"LastSeen = VALUES(LastSeen)", userID, ip, now, now)
+	if err != nil {
+		return false, err
+	}
+	// MySQL reports 1 affected row for an insert and 2, or 0 when nothing
+	// changed, for an update.
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// GetUserLoginIPs returns the IP addresses the user signed in from, the most
+// recently seen first.
+func GetUserLoginIPs(dbMap *gorp.DbMap, userID int64) ([]UserLoginIP, error) {
+	var loginIPs []UserLoginIP
+	_, err := dbMap.Select(&loginIPs, "SELECT * FROM UserLoginIP WHERE "+
+		"UserId = ? ORDER BY LastSeen DESC", userID)
+	return loginIPs, err
+}
+
+// CountUserLoginIPs returns how many IP addresses the user signed in from.
+func CountUserLoginIPs(dbMap *gorp.DbMap, userID int64) (int64, error) {
+	return dbMap.SelectInt("SELECT COUNT(*) FROM UserLoginIP WHERE "+
+		"UserId = ?", userID)
+}
+
+// RememberToken lets a browser stay signed in to a user's account.  The
+// browser holds Selector and a secret whose SHA-256 hash is TokenHash.
+// PrevTokenHash is the hash of the secret before it was last rotated at
+// Rotated.  Device and IP describe the browser when it last used the token.
+type RememberToken struct {
+	Id            int64 `db:"RememberTokenID"`
+	UserId        int64
+	Selector      string
+	TokenHash     string
+	PrevTokenHash string
+	Device        string
+	IP            string
+	Created       int64
+	LastUsed      int64
+	Expires       int64
+	Rotated       int64
+}
+
+// GetRememberToken returns the remember me token with selector, or nil when
+// there is none.
+func GetRememberToken(dbMap *gorp.DbMap, selector string) (*RememberToken, error) {
+	var token RememberToken
+	err := dbMap.SelectOne(&token, "SELECT * FROM RememberToken WHERE "+
+		"Selector = ?", selector)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// SaveRememberToken inserts or updates token.
+func SaveRememberToken(dbMap *gorp.DbMap, token *RememberToken) error {
+	if token.Id == 0 {
+		return dbMap.Insert(token)
+	}
+	_, err := dbMap.Update(token)
+	return err
+}
+
+// RotateRememberToken saves the new secret of token, keeping prevHash as the
+// previous one, unless the secret was rotated since token was read, and
+// returns whether it was saved.  Of concurrent rotations of a token only one
+// succeeds.
+func RotateRememberToken(dbMap *gorp.DbMap, token *RememberToken,
+	prevHash string) (bool, error) {
+	res, err := dbMap.Exec("UPDATE RememberToken SET TokenHash = ?, "+
+		"PrevTokenHash = ?, Device = ?, IP = ?, LastUsed = ?, Expires = ?, "+
+		"Rotated = ? WHERE RememberTokenID = ? AND TokenHash = ?",
+		token.TokenHash, prevHash, token.Device, token.IP, token.LastUsed,
+		token.Expires, token.Rotated, token.Id, prevHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetUserRememberTokens returns the remember me tokens of the user that
+// didn't expire before now, the most recently used first.
+func GetUserRememberTokens(dbMap *gorp.DbMap, userID, now int64) ([]RememberToken, error) {
+	var tokens []RememberToken
+	_, err := dbMap.Select(&tokens, "SELECT * FROM RememberToken WHERE "+
+		"UserId = ? AND Expires > ? ORDER BY LastUsed DESC", userID, now)
+	return tokens, err
+}
+
+// DeleteRememberToken removes the remember me token with id of the user and
+// returns whether there was one.
+func DeleteRememberToken(dbMap *gorp.DbMap, userID, id int64) (bool, error) {
+	res, err := dbMap.Exec("DELETE FROM RememberToken WHERE UserId = ? AND "+
+		"RememberTokenID = ?", userID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteUserRememberTokens removes every remember me token of the user.
+func DeleteUserRememberTokens(dbMap *gorp.DbMap, userID int64) error {
+	_, err := dbMap.Exec("DELETE FROM RememberToken WHERE UserId = ?", userID)
+	return err
+}
+
+// PruneRememberTokens removes the remember me tokens that expired before
+// before.
+func PruneRememberTokens(dbMap *gorp.DbMap, before int64) error {
+	_, err := dbMap.Exec("DELETE FROM RememberToken WHERE Expires < ?",
+		before)
+	return err
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/go-gorp/gorp"
+)
+
+// PoolGrowth is the state of the pool on a day, taken from the stake info of
+// the voting wallets every time a block is connected.  Day is the unix time
+// of the start of the day in UTC.  Tickets is the number of tickets the pool
+// ever had and Votes the number of tickets it voted, as of the last block of
+// the day at BlockHeight, while TicketsStart and VotesStart are how many
+// there were as of the last block of the day before.  LiveTickets and
+// NetworkPoolSize are the live pool tickets and the ticket pool size of the
+// network at the last block.
+type PoolGrowth struct {
+	Id              int64 `db:"PoolGrowthID"`
+	Day             int64
+	BlockHeight     int64
+	TicketsStart    int64
+	Tickets         int64
+	VotesStart      int64
+	Votes           int64
+	LiveTickets     int64
+	NetworkPoolSize int64
+}
+
+// RecordPoolGrowth updates the row of day with the state of the pool at a
+// connected block.  The first block of a day starts its row from the counts
+// of the day before.  It is an upsert on the unique Day, as every frontend
+// records the blocks it sees.
+func RecordPoolGrowth(dbMap *gorp.DbMap, day int64, state *PoolGrowth) error {
+	ticketsStart, votesStart := state.Tickets, state.Votes
+	var last PoolGrowth
+	err := dbMap.SelectOne(&last, "SELECT * FROM PoolGrowth WHERE "+
+		"Day < ? ORDER BY Day DESC LIMIT 1", day)
+	if err == nil {
+		ticketsStart, votesStart = last.Tickets, last.Votes
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	// A frontend behind the others doesn't overwrite the counts of a later
+	// block.  MySQL assigns the columns in order, so BlockHeight goes last.
+	_, err = dbMap.Exec("INSERT INTO PoolGrowth (Day, BlockHeight, "+
+		"TicketsStart, Tickets, VotesStart, Votes, LiveTickets, "+
+		"NetworkPoolSize) VALUES (?, ?, ?, ?, ?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE "+
+		"Tickets = IF(VALUES(BlockHeight) >= BlockHeight, "+
+		"VALUES(Tickets), Tickets), "+
+		"Votes = IF(VALUES(BlockHeight) >= BlockHeight, "+
+		"VALUES(Votes), Votes), "+
+		"LiveTickets = IF(VALUES(BlockHeight) >= BlockHeight, "+
+		"VALUES(LiveTickets), LiveTickets), "+
+		"NetworkPoolSize = IF(VALUES(BlockHeight) >= BlockHeight, "+
+		"VALUES(NetworkPoolSize), NetworkPoolSize), "+
+		"BlockHeight = GREATEST(BlockHeight, VALUES(BlockHeight))", day,
+		state.BlockHeight, ticketsStart, state.Tickets, votesStart,
+		state.Votes, state.LiveTickets, state.NetworkPoolSize)
+	return err
+}
+
+// GetPoolGrowth returns the rows of the days starting at or after since,
+// oldest first.
+func GetPoolGrowth(dbMap *gorp.DbMap, since int64) ([]PoolGrowth, error) {
+	var rows []PoolGrowth
+	_, err := dbMap.Select(&rows, "SELECT * FROM PoolGrowth WHERE Day >= ? "+
+		"ORDER BY Day", since)
+	return rows, err
+}
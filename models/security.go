@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/go-gorp/gorp"
+)
+
+// Kinds of security events.
+const (
+	SecurityEventLogin    = "login"
+	SecurityEventPassword = "password"
+	SecurityEventAPIToken = "apitoken"
+	SecurityEventAddress  = "address"
+)
+
+// SecurityEvent is a security relevant use or change of a user's account,
+// such as a sign in, shown to the user so they notice what they didn't do.
+// IP and UserAgent are of the request that caused it and Details says more
+// about it, such as how the user signed in.
+type SecurityEvent struct {
+	Id        int64 `db:"SecurityEventID"`
+	UserId    int64
+	Event     string
+	IP        string
+	UserAgent string
+	Details   string
+	Created   int64
+}
+
+// InsertSecurityEvent records event.
+func InsertSecurityEvent(dbMap *gorp.DbMap, event *SecurityEvent) error {
+	return dbMap.Insert(event)
+}
+
+// GetUserSecurityEvents returns the last limit security events of the user,
+// newest first.
+func GetUserSecurityEvents(dbMap *gorp.DbMap, userID int64, limit int) ([]SecurityEvent, error) {
+	var events []SecurityEvent
+	_, err := dbMap.Select(&events, "SELECT * FROM SecurityEvent WHERE "+
+		"UserId = ? ORDER BY Created DESC, SecurityEventID DESC LIMIT ?",
+		userID, limit)
+	return events, err
+}
+
+// GetAllUserSecurityEvents returns every security event of the user, newest
+// first.
+func GetAllUserSecurityEvents(dbMap *gorp.DbMap, userID int64) ([]SecurityEvent, error) {
+	var events []SecurityEvent
+	_, err := dbMap.Select(&events, "SELECT * FROM SecurityEvent WHERE "+
+		"UserId = ? ORDER BY Created DESC, SecurityEventID DESC", userID)
+	return events, err
+}
+
+// GetLastSecurityEvent returns the newest event of kind of the user caused
+// from ip with userAgent, or nil when there is none.
+func GetLastSecurityEvent(dbMap *gorp.DbMap, userID int64, kind, ip,
+	userAgent string) (*SecurityEvent, error) {
+	var event SecurityEvent
+	err := dbMap.SelectOne(&event, "SELECT * FROM SecurityEvent WHERE "+
+		"UserId = ? AND Event = ? AND IP = ? AND UserAgent = ? ORDER BY "+
+		"Created DESC, SecurityEventID DESC LIMIT 1", userID, kind, ip,
+		userAgent)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// PruneSecurityEvents removes the security events of the user from before
+// before.
+func PruneSecurityEvents(dbMap *gorp.DbMap, userID, before int64) error {
+	_, err := dbMap.Exec("DELETE FROM SecurityEvent WHERE UserId = ? AND "+
+		"Created < ?", userID, before)
+	return err
+}
@@ -11,13 +11,20 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// EmailChange is a pending change of the email address of a user.  The links
+// with Token, sent to the new address, and with OldToken, sent to the old
+// one, must both be followed before it expires.  NewConfirmed and
+// OldConfirmed are when they were, or 0.
 type EmailChange struct {
-	Id       int64 `db:"EmailChangeID"`
-	UserId   int64
-	NewEmail string
-	Token    string
-	Created  int64
-	Expires  int64
+	Id           int64 `db:"EmailChangeID"`
+	UserId       int64
+	NewEmail     string
+	Token        string
+	Created      int64
+	Expires      int64
+	OldToken     string
+	NewConfirmed int64
+	OldConfirmed int64
 }
 
 type LowFeeTicket struct {
@@ -39,22 +46,88 @@ type PasswordReset struct {
 	Expires int64
 }
 
+// TicketExpiryWarning records that a user was warned about a ticket nearing
+// expiry so the warning is only sent once.
+type TicketExpiryWarning struct {
+	Id           int64 `db:"TicketExpiryWarningID"`
+	UserId       int64
+	TicketHash   string
+	ExpiryHeight int64
+	Created      int64
+}
+
+// UserPreferences are the display and notification preferences of a user.
+// Empty fields mean the pool defaults apply.
+type UserPreferences struct {
+	Id              int64 `db:"UserPreferencesID"`
+	UserId          int64
+	TimeZone        string
+	Currency        string
+	DigestFrequency string
+	LastDigest      int64
+	Language        string
+	TelegramChatID  int64
+	TelegramToken   string
+	EmailEvents     string
+}
+
+// UserWebhook is the URL a user wants their ticket events posted to.  The
+// events are signed with the secret so the receiver can verify them.
+type UserWebhook struct {
+	Id      int64 `db:"UserWebhookID"`
+	UserId  int64
+	URL     string
+	Secret  string
+	Created int64
+}
+
+// EmailTicketStatus is the last status of a ticket that a user was emailed
+// about, or that was seen before they chose to be emailed about it.
+type EmailTicketStatus struct {
+	Id         int64 `db:"EmailTicketStatusID"`
+	UserId     int64
+	TicketHash string
+	Status     string
+}
+
+// WebhookTicketStatus is the last status of a ticket that was delivered to a
+// user's webhook.
+type WebhookTicketStatus struct {
+	Id         int64 `db:"WebhookTicketStatusID"`
+	UserId     int64
+	TicketHash string
+	Status     string
+}
+
 type User struct {
-	Id               int64 `db:"UserId"`
-	Email            string
-	Username         string
-	Password         []byte
-	MultiSigAddress  string
-	MultiSigScript   string
-	PoolPubKeyAddr   string
-	UserPubKeyAddr   string
-	UserFeeAddr      string
-	HeightRegistered int64
-	EmailVerified    int64
-	EmailToken       string
-	APIToken         string
-	VoteBits         int64
-	VoteBitsVersion  int64
+	Id                int64 `db:"UserId"`
+	Email             string
+	Username          string
+	Password          []byte
+	MultiSigAddress   string
+	MultiSigScript    string
+	PoolPubKeyAddr    string
+	UserPubKeyAddr    string
+	UserFeeAddr       string
+	HeightRegistered  int64
+	EmailVerified     int64
+	EmailToken        string
+	APIToken          string
+	VoteBits          int64
+	VoteBitsVersion   int64
+	VoteBitsReconfirm int64
+	// VotingSuspended is when an admin suspended changes to the voting
+	// preferences of the user, or 0.
+	VotingSuspended int64
+	// TermsVersion is the version of the terms of service the user last
+	// accepted.
+	TermsVersion string
+	// Deleted is when the user deleted their account, or 0.  Only what the
+	// pool needs to vote their tickets and account for them is kept.
+	Deleted int64
+	// Addresses are the additional voting addresses of the user when they
+	// are sent to stakepoold along with the one above.
+	Addresses []UserAddress `db:"-"`
 }
 
 func (user *User) HashPassword(password string) {
@@ -116,6 +189,19 @@ func GetUserCountActive(dbMap *gorp.DbMap) int64 {
 	return userCountActive
 }
 
+// GetUserCountVoteBitsBelow gives a count of the users who have submitted an
+// address and whose voting preferences were stored for a vote version below
+// voteVersion
+func GetUserCountVoteBitsBelow(dbMap *gorp.DbMap, voteVersion int64) int64 {
+	userCount, err := dbMap.SelectInt("SELECT COUNT(*) FROM Users "+
+		"WHERE MultiSigAddress <> '' AND VoteBitsVersion < ?", voteVersion)
+	if err != nil {
+		return int64(0)
+	}
+
+	return userCount
+}
+
 func InsertEmailChange(dbMap *gorp.DbMap, emailChange *EmailChange) error {
 	return dbMap.Insert(emailChange)
 }
@@ -134,6 +220,30 @@ func InsertPasswordReset(dbMap *gorp.DbMap, passwordReset *PasswordReset) error
 	return dbMap.Insert(passwordReset)
 }
 
+// SetUserEmailToken sets the token of the link verifying the email address
+// of the user with id.
+func SetUserEmailToken(dbMap *gorp.DbMap, id int64, token string) error {
+	_, err := dbMap.Exec("UPDATE Users SET EmailToken = ? WHERE UserId = ?",
+		token, id)
+	return err
+}
+
+// SetUserVotingSuspended sets when changes to the voting preferences of the
+// user with id were suspended, where 0 lifts the suspension.
+func SetUserVotingSuspended(dbMap *gorp.DbMap, id int64, suspended int64) error {
+	_, err := dbMap.Exec("UPDATE Users SET VotingSuspended = ? WHERE "+
+		"UserId = ?", suspended, id)
+	return err
+}
+
+// SetUserTermsVersion records that the user with id accepted version of the
+// terms of service.
+func SetUserTermsVersion(dbMap *gorp.DbMap, id int64, version string) error {
+	_, err := dbMap.Exec("UPDATE Users SET TermsVersion = ? WHERE UserId = ?",
+		version, id)
+	return err
+}
+
 // SetUserAPIToken generates and saves a unique API Token for a user.
 func SetUserAPIToken(dbMap *gorp.DbMap, APISecret string, baseURL string,
 	id int64) error {
@@ -201,6 +311,220 @@ func GetAllCurrentMultiSigScripts(dbMap *gorp.DbMap) ([]User, error) {
 	return multiSigs, nil
 }
 
+// GetAllVotingUsers returns the multisig address and voting preferences of
+// every user who has submitted an address.
+func GetAllVotingUsers(dbMap *gorp.DbMap) ([]User, error) {
+	var users []User
+	_, err := dbMap.Select(&users, "SELECT UserId, MultiSigAddress, VoteBits, "+
+		"VoteBitsVersion FROM Users WHERE MultiSigAddress <> ''")
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetAllNotifiableUsers returns the email and multisig address of every user
+// who has verified their email address and submitted an address.
+func GetAllNotifiableUsers(dbMap *gorp.DbMap) ([]User, error) {
+	var users []User
+	_, err := dbMap.Select(&users, "SELECT UserId, Email, MultiSigAddress "+
+		"FROM Users WHERE MultiSigAddress <> '' AND EmailVerified > 0")
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetUserPreferences returns the preferences of a user.  Users who never saved
+// any get empty preferences.
+func GetUserPreferences(dbMap *gorp.DbMap, userID int64) (*UserPreferences, error) {
+	var prefs UserPreferences
+	err := dbMap.SelectOne(&prefs, "SELECT * FROM UserPreferences WHERE "+
+		"UserId = ?", userID)
+	if err == sql.ErrNoRows {
+		return &UserPreferences{UserId: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// SetUserPreferences inserts or updates the preferences of a user.
+func SetUserPreferences(dbMap *gorp.DbMap, prefs *UserPreferences) error {
+	if prefs.Id == 0 {
+		return dbMap.Insert(prefs)
+	}
+	_, err := dbMap.Update(prefs)
+	return err
+}
+
+// GetUserPreferencesByTelegramToken returns the preferences of the user who
+// was given token to link a Telegram chat.
+func GetUserPreferencesByTelegramToken(dbMap *gorp.DbMap, token string) (*UserPreferences, error) {
+	var prefs UserPreferences
+	err := dbMap.SelectOne(&prefs, "SELECT * FROM UserPreferences WHERE "+
+		"TelegramToken = ?", token)
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// GetTelegramChatIDs returns the Telegram chats linked by users.
+func GetTelegramChatIDs(dbMap *gorp.DbMap) ([]int64, error) {
+	var chatIDs []int64
+	_, err := dbMap.Select(&chatIDs, "SELECT TelegramChatID FROM "+
+		"UserPreferences WHERE TelegramChatID != 0")
+	if err != nil {
+		return nil, err
+	}
+	return chatIDs, nil
+}
+
+// UnlinkTelegramChat stops notifications to the Telegram chat for every user
+// who linked it.
+func UnlinkTelegramChat(dbMap *gorp.DbMap, chatID int64) (int64, error) {
+	res, err := dbMap.Exec("UPDATE UserPreferences SET TelegramChatID = 0 "+
+		"WHERE TelegramChatID = ?", chatID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetTicketExpiryWarnings returns the hashes of the tickets the user has
+// already been warned about.
+func GetTicketExpiryWarnings(dbMap *gorp.DbMap, userID int64) (map[string]struct{}, error) {
+	var warnings []TicketExpiryWarning
+	_, err := dbMap.Select(&warnings, "SELECT * FROM TicketExpiryWarning "+
+		"WHERE UserId = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	warned := make(map[string]struct{}, len(warnings))
+	for _, w := range warnings {
+		warned[w.TicketHash] = struct{}{}
+	}
+	return warned, nil
+}
+
+// AddTicketExpiryWarning records that the user was warned about the ticket.
+func AddTicketExpiryWarning(dbMap *gorp.DbMap, userID int64, ticketHash string,
+	expiryHeight int64) error {
+	return dbMap.Insert(&TicketExpiryWarning{
+		UserId:       userID,
+		TicketHash:   ticketHash,
+		ExpiryHeight: expiryHeight,
+		Created:      time.Now().Unix(),
+	})
+}
+
+// PruneTicketExpiryWarnings deletes the warnings about tickets that expired
+// before height.
+func PruneTicketExpiryWarnings(dbMap *gorp.DbMap, height int64) error {
+	_, err := dbMap.Exec("DELETE FROM TicketExpiryWarning WHERE "+
+		"ExpiryHeight < ?", height)
+	return err
+}
+
+// GetUserWebhook returns the webhook of a user or nil if they have none.
+func GetUserWebhook(dbMap *gorp.DbMap, userID int64) (*UserWebhook, error) {
+	var webhook UserWebhook
+	err := dbMap.SelectOne(&webhook, "SELECT * FROM UserWebhook WHERE "+
+		"UserId = ?", userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// GetAllUserWebhooks returns the webhooks of all users.
+func GetAllUserWebhooks(dbMap *gorp.DbMap) ([]UserWebhook, error) {
+	var webhooks []UserWebhook
+	_, err := dbMap.Select(&webhooks, "SELECT * FROM UserWebhook")
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// SetUserWebhook inserts or updates the webhook of a user.
+func SetUserWebhook(dbMap *gorp.DbMap, webhook *UserWebhook) error {
+	if webhook.Id == 0 {
+		return dbMap.Insert(webhook)
+	}
+	_, err := dbMap.Update(webhook)
+	return err
+}
+
+// DeleteUserWebhook removes the webhook of a user along with the ticket
+// statuses delivered to it.
+func DeleteUserWebhook(dbMap *gorp.DbMap, userID int64) error {
+	_, err := dbMap.Exec("DELETE FROM UserWebhook WHERE UserId = ?", userID)
+	if err != nil {
+		return err
+	}
+	_, err = dbMap.Exec("DELETE FROM WebhookTicketStatus WHERE UserId = ?",
+		userID)
+	return err
+}
+
+// GetWebhookTicketStatuses returns the last delivered status of each of the
+// user's tickets.
+func GetWebhookTicketStatuses(dbMap *gorp.DbMap, userID int64) (map[string]*WebhookTicketStatus, error) {
+	var statuses []WebhookTicketStatus
+	_, err := dbMap.Select(&statuses, "SELECT * FROM WebhookTicketStatus "+
+		"WHERE UserId = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	delivered := make(map[string]*WebhookTicketStatus, len(statuses))
+	for i := range statuses {
+		delivered[statuses[i].TicketHash] = &statuses[i]
+	}
+	return delivered, nil
+}
+
+// SetWebhookTicketStatus inserts or updates the last delivered status of a
+// ticket.
+func SetWebhookTicketStatus(dbMap *gorp.DbMap, status *WebhookTicketStatus) error {
+	if status.Id == 0 {
+		return dbMap.Insert(status)
+	}
+	_, err := dbMap.Update(status)
+	return err
+}
+
+// GetEmailTicketStatuses returns the last status the user was emailed about
+// of each of their tickets.
+func GetEmailTicketStatuses(dbMap *gorp.DbMap, userID int64) (map[string]*EmailTicketStatus, error) {
+	var statuses []EmailTicketStatus
+	_, err := dbMap.Select(&statuses, "SELECT * FROM EmailTicketStatus "+
+		"WHERE UserId = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	emailed := make(map[string]*EmailTicketStatus, len(statuses))
+	for i := range statuses {
+		emailed[statuses[i].TicketHash] = &statuses[i]
+	}
+	return emailed, nil
+}
+
+// SetEmailTicketStatus inserts or updates the last status of a ticket the
+// user was emailed about.
+func SetEmailTicketStatus(dbMap *gorp.DbMap, status *EmailTicketStatus) error {
+	if status.Id == 0 {
+		return dbMap.Insert(status)
+	}
+	_, err := dbMap.Update(status)
+	return err
+}
+
 func GetAllLowFeeTickets(dbMap *gorp.DbMap) ([]LowFeeTicket, error) {
 	var lowFeeTickets []LowFeeTicket
 	_, err := dbMap.Select(&lowFeeTickets, "SELECT * FROM LowFeeTicket")
@@ -219,6 +543,23 @@ func GetVotableLowFeeTickets(dbMap *gorp.DbMap) ([]LowFeeTicket, error) {
 	return votableLowFeeTickets, nil
 }
 
+// DeleteLowFeeTicket removes a ticket from the low fee tickets and returns
+// the removed rows.
+func DeleteLowFeeTicket(dbMap *gorp.DbMap, ticketHash string) ([]LowFeeTicket, error) {
+	var lowFeeTickets []LowFeeTicket
+	_, err := dbMap.Select(&lowFeeTickets,
+		"SELECT * FROM LowFeeTicket WHERE TicketHash = ?", ticketHash)
+	if err != nil {
+		return nil, err
+	}
+	_, err = dbMap.Exec("DELETE FROM LowFeeTicket WHERE TicketHash = ?",
+		ticketHash)
+	if err != nil {
+		return nil, err
+	}
+	return lowFeeTickets, nil
+}
+
 func GetDbMap(APISecret, baseURL, user, password, hostname, port, database string) *gorp.DbMap {
 	// connect to db using standard Go database/sql API
 	// use whatever database/sql driver you wish
@@ -232,6 +573,8 @@ func GetDbMap(APISecret, baseURL, user, password, hostname, port, database strin
 	// Verify that the data source name is valid with Ping:
 	if err = db.Ping(); err != nil {
 		log.Critical("Unable to establish connection to database: ", err)
+		// The caller retries at startup, so don't leak the pool.
+		db.Close()
 		return nil
 	}
 
@@ -240,10 +583,31 @@ func GetDbMap(APISecret, baseURL, user, password, hostname, port, database strin
 
 	// add a table, setting the table name and specifying that
 	// the Id property is an auto incrementing primary key
+	auditLog := dbMap.AddTableWithName(AuditLog{}, "AuditLog").SetKeys(true, "Id")
+	auditLog.ColMap("Before").SetMaxSize(auditValueMaxSize)
+	auditLog.ColMap("After").SetMaxSize(auditValueMaxSize)
 	dbMap.AddTableWithName(EmailChange{}, "EmailChange").SetKeys(true, "Id")
+	dbMap.AddTableWithName(EmailTicketStatus{}, "EmailTicketStatus").SetKeys(true, "Id")
+	inviteCode := dbMap.AddTableWithName(InviteCode{}, "InviteCode").SetKeys(true, "Id")
+	inviteCode.ColMap("Code").SetMaxSize(inviteCodeMaxSize).SetUnique(true)
+	dbMap.AddTableWithName(LoginAttempt{}, "LoginAttempt").SetKeys(true, "Id")
 	dbMap.AddTableWithName(LowFeeTicket{}, "LowFeeTicket").SetKeys(true, "Id")
 	dbMap.AddTableWithName(PasswordReset{}, "PasswordReset").SetKeys(true, "Id")
+	dbMap.AddTableWithName(PoolGrowth{}, "PoolGrowth").SetKeys(true, "Id")
+	dbMap.AddTableWithName(RememberToken{}, "RememberToken").SetKeys(true, "Id")
+	dbMap.AddTableWithName(SecurityEvent{}, "SecurityEvent").SetKeys(true, "Id")
+	dbMap.AddTableWithName(TicketExpiryWarning{}, "TicketExpiryWarning").SetKeys(true, "Id")
 	dbMap.AddTableWithName(User{}, "Users").SetKeys(true, "Id")
+	userAddress := dbMap.AddTableWithName(UserAddress{}, "UserAddress").SetKeys(true, "Id")
+	userAddress.ColMap("MultiSigAddress").SetMaxSize(addressMaxSize).SetUnique(true)
+	userAddress.ColMap("UserPubKeyAddr").SetMaxSize(addressMaxSize).SetUnique(true)
+	dbMap.AddTableWithName(UserLoginIP{}, "UserLoginIP").SetKeys(true, "Id")
+	dbMap.AddTableWithName(UserPreferences{}, "UserPreferences").SetKeys(true, "Id")
+	dbMap.AddTableWithName(UserWebhook{}, "UserWebhook").SetKeys(true, "Id")
+	dbMap.AddTableWithName(VoteHistory{}, "VoteHistory").SetKeys(true, "Id")
+	voteClaim := dbMap.AddTableWithName(VoteClaim{}, "VoteClaim").SetKeys(true, "Id")
+	voteClaim.ColMap("TicketHash").SetMaxSize(ticketHashMaxSize).SetUnique(true)
+	dbMap.AddTableWithName(WebhookTicketStatus{}, "WebhookTicketStatus").SetKeys(true, "Id")
 
 	// create the table. in a production system you'd generally
 	// use a migration tool, or create the tables via scripts
@@ -326,6 +690,103 @@ func GetDbMap(APISecret, baseURL, user, password, hostname, port, database strin
 	// and it will be upgraded when talking to stakepoold
 	addColumn(dbMap, database, "Users", "VoteBitsVersion", "bigint(20) NULL", "VoteBits", "UPDATE Users SET VoteBitsVersion = 3")
 
+	// add VoteBitsReconfirm column for flagging users whose voting
+	// preferences could not be carried over to a new vote version as is.
+	addColumn(dbMap, database, "Users", "VoteBitsReconfirm", "bigint(20) NULL", "VoteBitsVersion", "UPDATE Users SET VoteBitsReconfirm = 0")
+
+	// add OldToken, NewConfirmed and OldConfirmed so email changes are
+	// confirmed by both the old and the new address.  Pending changes only
+	// confirmed by the new address are dropped, they expire within an hour
+	// anyway.
+	addColumn(dbMap, database, "EmailChange", "OldToken", "varchar(255) NULL", "Expires", "DELETE FROM EmailChange")
+	addColumn(dbMap, database, "EmailChange", "NewConfirmed", "bigint(20) NULL", "OldToken", "")
+	addColumn(dbMap, database, "EmailChange", "OldConfirmed", "bigint(20) NULL", "NewConfirmed", "")
+
+	// add Language so pages and emails can be shown in the user's language.
+	// An empty Language follows the language of the browser.
+	addColumn(dbMap, database, "UserPreferences", "Language", "varchar(255) NULL", "LastDigest", "UPDATE UserPreferences SET Language = ''")
+
+	// add VotingSuspended so admins can freeze the voting preferences of a
+	// user.
+	addColumn(dbMap, database, "Users", "VotingSuspended", "bigint(20) NULL", "VoteBitsReconfirm", "UPDATE Users SET VotingSuspended = 0")
+
+	// add TermsVersion so users accept the terms of service again when they
+	// change.  Existing users haven't accepted any.
+	addColumn(dbMap, database, "Users", "TermsVersion", "varchar(255) NULL", "VotingSuspended", "UPDATE Users SET TermsVersion = ''")
+
+	// add TelegramChatID and TelegramToken so users can receive
+	// notifications from the Telegram bot.  TelegramToken links a chat to the
+	// user once and a zero TelegramChatID means no chat is linked.
+	addColumn(dbMap, database, "UserPreferences", "TelegramChatID", "bigint(20) NULL", "Language", "UPDATE UserPreferences SET TelegramChatID = 0")
+	addColumn(dbMap, database, "UserPreferences", "TelegramToken", "varchar(255) NULL", "TelegramChatID", "UPDATE UserPreferences SET TelegramToken = ''")
+
+	// add EmailEvents so users can choose which events they are emailed
+	// about.  Empty means the pool defaults.
+	addColumn(dbMap, database, "UserPreferences", "EmailEvents", "varchar(255) NULL", "TelegramToken", "UPDATE UserPreferences SET EmailEvents = ''")
+
+	// add Deleted so users can delete their accounts while the records of
+	// their tickets are kept.
+	addColumn(dbMap, database, "Users", "Deleted", "bigint(20) NULL", "TermsVersion", "UPDATE Users SET Deleted = 0")
+
+	// add PrevTokenHash and Rotated so the previous secret of a remember me
+	// token is still accepted shortly after it was rotated by a parallel
+	// request.
+	addColumn(dbMap, database, "RememberToken", "PrevTokenHash", "varchar(255) NULL", "TokenHash", "UPDATE RememberToken SET PrevTokenHash = ''")
+	addColumn(dbMap, database, "RememberToken", "Rotated", "bigint(20) NULL", "Expires", "UPDATE RememberToken SET Rotated = 0")
+
+	// add a unique key on the failed sign ins of a subject so they are
+	// counted with an upsert.  Concurrent failures used to insert duplicate
+	// rows, of which only the newest is kept.
+	addIndex(dbMap, database, "LoginAttempt", "LoginAttemptSubject", true,
+		"`Kind`, `Subject`",
+		"DELETE a FROM LoginAttempt a JOIN LoginAttempt b ON "+
+			"a.Kind = b.Kind AND a.Subject = b.Subject AND "+
+			"a.LoginAttemptID < b.LoginAttemptID")
+
+	// add a unique key on the addresses users signed in from so sign ins
+	// are recorded with an upsert.  Concurrent sign ins used to insert
+	// duplicate rows, of which only the first is kept.
+	addIndex(dbMap, database, "UserLoginIP", "UserLoginIPAddress", true,
+		"`UserId`, `IP`(64)",
+		"DELETE a FROM UserLoginIP a JOIN UserLoginIP b ON "+
+			"a.UserId = b.UserId AND a.IP = b.IP AND "+
+			"a.UserLoginIPID > b.UserLoginIPID")
+
+	// add a unique key on the events of a ticket so the events every
+	// stakepoold streams at the same time are only stored once.
+	addIndex(dbMap, database, "VoteHistory", "VoteHistoryTicketEvent", true,
+		"`TicketHash`(64), `Event`(16)",
+		"DELETE a FROM VoteHistory a JOIN VoteHistory b ON "+
+			"a.TicketHash = b.TicketHash AND a.Event = b.Event AND "+
+			"a.VoteHistoryID > b.VoteHistoryID")
+
+	// add a unique key on the day of the pool growth so every frontend
+	// records it with an upsert.  Concurrent frontends used to insert
+	// duplicate rows, of which only the first is kept.
+	addIndex(dbMap, database, "PoolGrowth", "PoolGrowthDay", true, "`Day`",
+		"DELETE a FROM PoolGrowth a JOIN PoolGrowth b ON "+
+			"a.Day = b.Day AND a.PoolGrowthID > b.PoolGrowthID")
+
+	// add the unique keys of the additional voting addresses to the tables
+	// created before they were unique, named like those of new tables.
+	// Concurrent registrations could add an address twice, of which only
+	// the first is kept.
+	addIndex(dbMap, database, "UserAddress", "MultiSigAddress", true,
+		"`MultiSigAddress`(128)",
+		"DELETE a FROM UserAddress a JOIN UserAddress b ON "+
+			"a.MultiSigAddress = b.MultiSigAddress AND "+
+			"a.UserAddressID > b.UserAddressID")
+	addIndex(dbMap, database, "UserAddress", "UserPubKeyAddr", true,
+		"`UserPubKeyAddr`(128)",
+		"DELETE a FROM UserAddress a JOIN UserAddress b ON "+
+			"a.UserPubKeyAddr = b.UserPubKeyAddr AND "+
+			"a.UserAddressID > b.UserAddressID")
+
+	// add an index on the security events of a user by event, IP address and
+	// user agent, which every API call looks up its last API token use by.
+	addIndex(dbMap, database, "SecurityEvent", "SecurityEventSource", false,
+		"`UserId`, `Event`(16), `IP`(64), `UserAgent`(64)", "")
+
 	return dbMap
 }
 
@@ -350,6 +811,31 @@ func addColumn(dbMap *gorp.DbMap, db string, table string, columnToAdd string,
 	}
 }
 
+// addIndex checks if an index exists and adds it on columns if it doesn't.
+// cleanupQry, if set, runs before a unique index is added to remove the rows
+// it would reject.
+func addIndex(dbMap *gorp.DbMap, db string, table string, indexToAdd string,
+	unique bool, columns string, cleanupQry string) {
+	s, err := dbMap.SelectStr("SELECT DISTINCT index_name FROM " +
+		"information_schema.statistics WHERE table_schema = '" + db +
+		"' AND table_name = '" + table + "' AND index_name = '" +
+		indexToAdd + "'")
+	checkErr(err, "checking whether index "+indexToAdd+" exists failed")
+	if s == "" {
+		if cleanupQry != "" {
+			_, err = dbMap.Exec(cleanupQry)
+			checkErr(err, cleanupQry+" failed")
+		}
+		kind := "INDEX"
+		if unique {
+			kind = "UNIQUE INDEX"
+		}
+		_, err = dbMap.Exec("ALTER TABLE `" + table + "` ADD " + kind +
+			" `" + indexToAdd + "` (" + columns + ")")
+		checkErr(err, "adding new index "+indexToAdd+" failed")
+	}
+}
+
 func checkErr(err error, msg string) {
 	if err != nil {
 		log.Critical(msg, err)
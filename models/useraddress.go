@@ -0,0 +1,136 @@
+package models
+
+import (
+	"github.com/go-gorp/gorp"
+)
+
+// addressMaxSize is the size of the MultiSigAddress and UserPubKeyAddr
+// columns of UserAddress, which are unique.
+const addressMaxSize = 128
+
+// UserAddress is a voting address a user registered on top of the one of
+// their User row, for buying tickets from another wallet.  Like that one, it
+// has its own multisig ticket address and script, shared with the pool's
+// PoolPubKeyAddr, and its own voting preferences.  The pool fees of its
+// tickets go to the fee address of the user.
+type UserAddress struct {
+	Id                int64 `db:"UserAddressID"`
+	UserId            int64
+	MultiSigAddress   string
+	MultiSigScript    string
+	PoolPubKeyAddr    string
+	UserPubKeyAddr    string
+	HeightRegistered  int64
+	VoteBits          int64
+	VoteBitsVersion   int64
+	VoteBitsReconfirm int64
+	Created           int64
+}
+
+// InsertUserAddress inserts a voting address of a user, unless the user has
+// limit additional voting addresses already or its public key address is
+// registered already, and returns whether it was inserted.  The checks are
+// part of the insert, so concurrent registrations can't get past them.
+func InsertUserAddress(dbMap *gorp.DbMap, address *UserAddress,
+	limit int) (bool, error) {
+	res, err := dbMap.Exec("INSERT IGNORE INTO UserAddress (UserId, "+
+		"MultiSigAddress, MultiSigScript, PoolPubKeyAddr, UserPubKeyAddr, "+
+		"HeightRegistered, VoteBits, VoteBitsVersion, VoteBitsReconfirm, "+
+		"Created) SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ? FROM DUAL WHERE "+
+		"(SELECT COUNT(*) FROM UserAddress WHERE UserId = ?) < ? AND "+
+		"NOT EXISTS (SELECT 1 FROM Users WHERE UserPubKeyAddr = ?)",
+		address.UserId, address.MultiSigAddress, address.MultiSigScript,
+		address.PoolPubKeyAddr, address.UserPubKeyAddr,
+		address.HeightRegistered, address.VoteBits, address.VoteBitsVersion,
+		address.VoteBitsReconfirm, address.Created, address.UserId, limit,
+		address.UserPubKeyAddr)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	address.Id, err = res.LastInsertId()
+	return true, err
+}
+
+// GetUserAddresses returns the additional voting addresses of the user in
+// the order they were registered.
+func GetUserAddresses(dbMap *gorp.DbMap, userID int64) ([]UserAddress, error) {
+	var addresses []UserAddress
+	_, err := dbMap.Select(&addresses, "SELECT * FROM UserAddress WHERE "+
+		"UserId = ? ORDER BY UserAddressID", userID)
+	return addresses, err
+}
+
+// GetUserAddress returns the additional voting address with id of the user.
+func GetUserAddress(dbMap *gorp.DbMap, userID, id int64) (*UserAddress, error) {
+	var address UserAddress
+	err := dbMap.SelectOne(&address, "SELECT * FROM UserAddress WHERE "+
+		"UserId = ? AND UserAddressID = ?", userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// GetAllUserAddresses returns the additional voting addresses of all users.
+func GetAllUserAddresses(dbMap *gorp.DbMap) ([]UserAddress, error) {
+	var addresses []UserAddress
+	_, err := dbMap.Select(&addresses, "SELECT * FROM UserAddress ORDER BY "+
+		"UserAddressID")
+	return addresses, err
+}
+
+// GetAllVotingAddresses returns the voting addresses of all users who
+// submitted one: the first ones of their User rows, with Id 0, followed by
+// the additional ones.  Only the user, multisig address and voting
+// preferences of the first ones are set.
+func GetAllVotingAddresses(dbMap *gorp.DbMap) ([]UserAddress, error) {
+	users, err := GetAllVotingUsers(dbMap)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := GetAllUserAddresses(dbMap)
+	if err != nil {
+		return nil, err
+	}
+	all := make([]UserAddress, 0, len(users)+len(addresses))
+	for _, user := range users {
+		all = append(all, UserAddress{
+			UserId:          user.Id,
+			MultiSigAddress: user.MultiSigAddress,
+			VoteBits:        user.VoteBits,
+			VoteBitsVersion: user.VoteBitsVersion,
+		})
+	}
+	return append(all, addresses...), nil
+}
+
+// CountUserPubKeyAddr returns how many voting addresses of any user were
+// registered with the public key address userPubKeyAddr.
+func CountUserPubKeyAddr(dbMap *gorp.DbMap, userPubKeyAddr string) (int64, error) {
+	users, err := dbMap.SelectInt("SELECT COUNT(*) FROM Users WHERE "+
+		"UserPubKeyAddr = ?", userPubKeyAddr)
+	if err != nil {
+		return 0, err
+	}
+	addresses, err := dbMap.SelectInt("SELECT COUNT(*) FROM UserAddress "+
+		"WHERE UserPubKeyAddr = ?", userPubKeyAddr)
+	return users + addresses, err
+}
+
+// SetUserAddressVoteBits stores the voting preferences of a voting address
+// for voteVersion.  reconfirm flags that the user needs to confirm them.
+func SetUserAddressVoteBits(dbMap *gorp.DbMap, id int64, voteBits uint16,
+	voteVersion uint32, reconfirm bool) error {
+	var reconfirmFlag int64
+	if reconfirm {
+		reconfirmFlag = 1
+	}
+	_, err := dbMap.Exec("UPDATE UserAddress SET VoteBits = ?, "+
+		"VoteBitsVersion = ?, VoteBitsReconfirm = ? WHERE UserAddressID = ?",
+		voteBits, voteVersion, reconfirmFlag, id)
+	return err
+}
@@ -0,0 +1,19 @@
+package models
+
+// ticketHashMaxSize is the size of the TicketHash column of VoteClaim, which
+// must be short enough to be unique.
+const ticketHashMaxSize = 64
+
+// VoteClaim is the claim of a stakepoold instance on the vote of a ticket on
+// a block.  Redundant stakepoold instances that vote with wallets holding the
+// same scripts claim the votes of the winning tickets here before voting, so
+// a single vote is generated for each.  The table is created for stakepoold,
+// which maintains its rows.
+type VoteClaim struct {
+	Id          int64 `db:"VoteClaimID"`
+	TicketHash  string
+	BlockHash   string
+	BlockHeight int64
+	Holder      string
+	Claimed     int64
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"github.com/go-gorp/gorp"
+)
+
+// Events of the vote history of a ticket.
+const (
+	VoteHistorySelected = "selected"
+	VoteHistoryVoted    = "voted"
+	VoteHistoryMissed   = "missed"
+)
+
+// VoteHistory is an event of a user's ticket reported by stakepoold.
+// BlockHeight and BlockHash are the block the ticket was selected to vote on,
+// or the block that missed it.  VoteHash and Reward, in atoms, are only set
+// for voted events.  Time is when stakepoold saw the event.  Every event is
+// only kept once per ticket.
+type VoteHistory struct {
+	Id          int64 `db:"VoteHistoryID"`
+	UserId      int64
+	TicketHash  string
+	Event       string
+	BlockHeight int64
+	BlockHash   string
+	VoteHash    string
+	VoteBits    int64
+	Reward      int64
+	Time        int64
+}
+
+// AddVoteHistory inserts event unless the ticket already has an event of its
+// kind and returns whether it was inserted.  The unique key on the ticket and
+// event makes the insert safe when every stakepoold reports the event at the
+// same time.
+func AddVoteHistory(dbMap *gorp.DbMap, event *VoteHistory) (bool, error) {
+	res, err := dbMap.Exec("INSERT IGNORE INTO VoteHistory (UserId, "+
+		"TicketHash, Event, BlockHeight, BlockHash, VoteHash, VoteBits, "+
+		"Reward, Time) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", event.UserId,
+		event.TicketHash, event.Event, event.BlockHeight, event.BlockHash,
+		event.VoteHash, event.VoteBits, event.Reward, event.Time)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n != 0, err
+}
+
+// GetVoteHistory returns the events of a ticket of the user, oldest first.
+func GetVoteHistory(dbMap *gorp.DbMap, userID int64, ticketHash string) ([]VoteHistory, error) {
+	var events []VoteHistory
+	_, err := dbMap.Select(&events, "SELECT * FROM VoteHistory WHERE "+
+		"UserId = ? AND TicketHash = ? ORDER BY BlockHeight, VoteHistoryID",
+		userID, ticketHash)
+	return events, err
+}
+
+// GetUserVoteHistory returns the events of all tickets of the user, oldest
+// first.
+func GetUserVoteHistory(dbMap *gorp.DbMap, userID int64) ([]VoteHistory, error) {
+	var events []VoteHistory
+	_, err := dbMap.Select(&events, "SELECT * FROM VoteHistory WHERE "+
+		"UserId = ? ORDER BY BlockHeight, VoteHistoryID", userID)
+	return events, err
+}
+
+// GetVoteHistoryHeight returns the height of the newest event, or 0 when
+// there are none.
+func GetVoteHistoryHeight(dbMap *gorp.DbMap) (int64, error) {
+	return dbMap.SelectInt("SELECT COALESCE(MAX(BlockHeight), 0) FROM " +
+		"VoteHistory")
+}
+
+// GetVoteHistoryUserID returns the id of the user owning the ticket with an
+// event in the vote history.
+func GetVoteHistoryUserID(dbMap *gorp.DbMap, ticketHash string) (int64, error) {
+	return dbMap.SelectInt("SELECT UserId FROM VoteHistory WHERE "+
+		"TicketHash = ? LIMIT 1", ticketHash)
+}
+
+// GetRecentVoteReward returns the average reward, in atoms, of the last count
+// votes in the vote history, or 0 when there are none.
+func GetRecentVoteReward(dbMap *gorp.DbMap, count int) (int64, error) {
+	reward, err := dbMap.SelectFloat("SELECT COALESCE(AVG(Reward), 0) FROM "+
+		"(SELECT Reward FROM VoteHistory WHERE Event = ? ORDER BY "+
+		"BlockHeight DESC LIMIT ?) AS Recent", VoteHistoryVoted, count)
+	return int64(reward), err
+}
@@ -0,0 +1,160 @@
+// Package notifier posts operational events of the pool, such as a backend
+// going down, a chain reorganization or missed votes, to Slack and Discord
+// compatible chat webhooks.  Each webhook only receives the events at or above
+// its minimum severity.
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Severity is how urgently operators need to act on an event.
+type Severity int
+
+// Severities of events, from least to most urgent.
+const (
+	// SeverityInfo events need no action, such as a problem being resolved.
+	SeverityInfo Severity = iota
+
+	// SeverityWarning events need attention soon.
+	SeverityWarning
+
+	// SeverityCritical events affect voting and need attention now.
+	SeverityCritical
+)
+
+var severityNames = []string{"info", "warning", "critical"}
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity returns the severity called name.
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(name, n) {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q, must be one of %s", name,
+		strings.Join(severityNames, ", "))
+}
+
+// Webhook is a Slack or Discord compatible incoming webhook.
+type Webhook struct {
+	url         string
+	discord     bool
+	minSeverity Severity
+}
+
+// ParseWebhook parses a webhook given as [severity,]URL.  The webhook receives
+// the events at or above severity, or every event when it is omitted.
+// Webhooks of discord.com are sent Discord messages and the others Slack
+// messages.
+func ParseWebhook(s string) (*Webhook, error) {
+	w := &Webhook{url: s}
+	if i := strings.Index(s, ","); i >= 0 {
+		severity, err := ParseSeverity(strings.TrimSpace(s[:i]))
+		if err != nil {
+			return nil, err
+		}
+		w.url, w.minSeverity = strings.TrimSpace(s[i+1:]), severity
+	}
+	u, err := url.Parse(w.url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		u.Host == "" {
+		return nil, fmt.Errorf("webhook %q is not an http(s) URL", w.url)
+	}
+	// Discord webhooks ending in /slack take Slack messages.
+	switch strings.ToLower(u.Hostname()) {
+	case "discord.com", "discordapp.com", "canary.discord.com",
+		"ptb.discord.com":
+		w.discord = !strings.HasSuffix(u.Path, "/slack")
+	}
+	return w, nil
+}
+
+// URL returns the URL of the webhook.  It holds the credentials to post to
+// the channel.
+func (w *Webhook) URL() string {
+	return w.url
+}
+
+// payload returns the JSON message posting text to the webhook.
+func (w *Webhook) payload(text string) ([]byte, error) {
+	if w.discord {
+		return json.Marshal(struct {
+			Content string `json:"content"`
+		}{text})
+	}
+	return json.Marshal(struct {
+		Text string `json:"text"`
+	}{text})
+}
+
+// Notifier posts events to webhooks.  It is safe for concurrent access.
+type Notifier struct {
+	webhooks []*Webhook
+	client   *http.Client
+}
+
+// New returns a notifier posting to the webhooks through transport.  A nil
+// transport uses http.DefaultTransport.
+func New(webhooks []*Webhook, transport http.RoundTripper) *Notifier {
+	return &Notifier{
+		webhooks: webhooks,
+		client: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: transport,
+		},
+	}
+}
+
+// post posts the message in body to the webhook w.
+func (n *Notifier) post(w *Webhook, body []byte) error {
+	resp, err := n.client.Post(w.url, "application/json",
+		bytes.NewReader(body))
+	if err != nil {
+		// The URL holds the credentials of the webhook.
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Notify posts text, describing an event of severity, to the webhooks that
+// receive it.  Failures are logged.
+func (n *Notifier) Notify(severity Severity, text string) {
+	text = "[" + strings.ToUpper(severity.String()) + "] " + text
+	for i, w := range n.webhooks {
+		if severity < w.minSeverity {
+			continue
+		}
+		body, err := w.payload(text)
+		if err != nil {
+			log.Errorf("unable to encode webhook message: %v", err)
+			return
+		}
+		if err = n.post(w, body); err != nil {
+			log.Warnf("chat webhook %d failed: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestParseWebhook(t *testing.T) {
+	tests := []struct {
+		s           string
+		url         string
+		discord     bool
+		minSeverity Severity
+	}{
+		{"https://hooks.slack.com/services/T0/B0/x", "https://hooks.slack.com/services/T0/B0/x", false, SeverityInfo},
+		{"critical,https://discord.com/api/webhooks/1/x", "https://discord.com/api/webhooks/1/x", true, SeverityCritical},
+		{"Warning, https://discord.com/api/webhooks/1/x/slack", "https://discord.com/api/webhooks/1/x/slack", false, SeverityWarning},
+	}
+	for _, test := range tests {
+		w, err := ParseWebhook(test.s)
+		if err != nil {
+			t.Errorf("ParseWebhook(%q): %v", test.s, err)
+			continue
+		}
+		if w.URL() != test.url || w.discord != test.discord ||
+			w.minSeverity != test.minSeverity {
+			t.Errorf("ParseWebhook(%q) = %+v", test.s, *w)
+		}
+	}
+	for _, s := range []string{"", "hooks.slack.com/x", "urgent,https://x"} {
+		if _, err := ParseWebhook(s); err == nil {
+			t.Errorf("ParseWebhook(%q) succeeded", s)
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var mtx sync.Mutex
+	received := make(map[string]map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		var msg map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mtx.Lock()
+		received[r.URL.Path] = msg
+		mtx.Unlock()
+	}))
+	defer server.Close()
+
+	all, _ := ParseWebhook(server.URL + "/all")
+	critical, _ := ParseWebhook("critical," + server.URL + "/critical")
+	discord, _ := ParseWebhook(server.URL + "/discord")
+	discord.discord = true
+	n := New([]*Webhook{all, critical, discord}, nil)
+
+	n.Notify(SeverityWarning, "reorg")
+	if len(received) != 2 || received["/all"]["text"] != "[WARNING] reorg" ||
+		received["/discord"]["content"] != "[WARNING] reorg" {
+		t.Errorf("warning posted %v", received)
+	}
+	n.Notify(SeverityCritical, "down")
+	if received["/critical"]["text"] != "[CRITICAL] down" {
+		t.Errorf("critical posted %v", received)
+	}
+}
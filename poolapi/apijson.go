@@ -8,17 +8,241 @@ type Response struct {
 	Status  string           `json:"status"`
 	Message string           `json:"message"`
 	Data    *json.RawMessage `json:"data,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+// Error describes why a version 2 API call failed.  Code is the name of the
+// gRPC code of the failure, such as Unauthenticated.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 // TODO: make JSON tags lower-case and add "_" between words
 
+// AccountData is the personal data the pool holds about a user, as they
+// export it.  Addresses are all their voting addresses, the first one of User
+// included.  AuditLog holds the entries of their actions and of the changes
+// of their account.
+type AccountData struct {
+	Exported       int64              `json:"Exported"`
+	User           User               `json:"User"`
+	TermsVersion   string             `json:"TermsVersion"`
+	Preferences    *Preferences       `json:"Preferences"`
+	TelegramLinked bool               `json:"TelegramLinked"`
+	Webhook        *Webhook           `json:"Webhook,omitempty"`
+	Addresses      []VotingAddress    `json:"Addresses"`
+	LoginIPs       []AccountLoginIP   `json:"LoginIPs"`
+	Devices        []AccountDevice    `json:"Devices"`
+	SecurityEvents []SecurityEvent    `json:"SecurityEvents"`
+	VoteHistory    []AccountVoteEvent `json:"VoteHistory"`
+	AuditLog       []AuditLogEntry    `json:"AuditLog"`
+}
+
+// AccountDevice is a device remembered to stay signed in.
+type AccountDevice struct {
+	Device   string `json:"Device"`
+	IP       string `json:"IP"`
+	Created  int64  `json:"Created"`
+	LastUsed int64  `json:"LastUsed"`
+	Expires  int64  `json:"Expires"`
+}
+
+// AccountLoginIP is an IP address a user signed in from.
+type AccountLoginIP struct {
+	IP        string `json:"IP"`
+	FirstSeen int64  `json:"FirstSeen"`
+	LastSeen  int64  `json:"LastSeen"`
+}
+
+// AccountVoteEvent is an event of a user's ticket.  Reward is in atoms.
+type AccountVoteEvent struct {
+	Ticket      string `json:"Ticket"`
+	Event       string `json:"Event"`
+	BlockHeight int64  `json:"BlockHeight"`
+	BlockHash   string `json:"BlockHash"`
+	VoteHash    string `json:"VoteHash"`
+	VoteBits    uint16 `json:"VoteBits"`
+	Reward      int64  `json:"Reward"`
+	Time        int64  `json:"Time"`
+}
+
+// AuditLogEntry is an administrative action.  Before and After are JSON
+// values of what the action changed.
+type AuditLogEntry struct {
+	ID          int64           `json:"ID"`
+	ActorUserID int64           `json:"ActorUserID"`
+	ActorIP     string          `json:"ActorIP"`
+	Action      string          `json:"Action"`
+	Target      string          `json:"Target"`
+	Before      json.RawMessage `json:"Before,omitempty"`
+	After       json.RawMessage `json:"After,omitempty"`
+	Created     int64           `json:"Created"`
+}
+
+// List is the envelope of every list endpoint.  Items holds the requested
+// page and Total the number of items matching the filters.
+type List struct {
+	Items  interface{} `json:"Items"`
+	Total  int64       `json:"Total"`
+	Limit  int         `json:"Limit"`
+	Offset int         `json:"Offset"`
+	Sort   string      `json:"Sort"`
+}
+
+// ExportRecord is a ticket of a user in an export.  PoolFee is the amount of
+// the pool fee commitment of the ticket and PoolFeeFiat its value in
+// FiatCurrency at the time of the export, if a price is known.
+type ExportRecord struct {
+	UserID          int64   `json:"UserID"`
+	Username        string  `json:"Username"`
+	MultiSigAddress string  `json:"MultiSigAddress"`
+	Ticket          string  `json:"Ticket"`
+	Status          string  `json:"Status"`
+	TicketHeight    uint32  `json:"TicketHeight"`
+	SpentBy         string  `json:"SpentBy"`
+	SpentByHeight   uint32  `json:"SpentByHeight"`
+	PoolFee         float64 `json:"PoolFee"`
+	PoolFeeFiat     float64 `json:"PoolFeeFiat,omitempty"`
+	FiatCurrency    string  `json:"FiatCurrency,omitempty"`
+}
+
+type LowFeeTicket struct {
+	TicketHash    string `json:"TicketHash"`
+	TicketAddress string `json:"TicketAddress"`
+	AddedByUserID int64  `json:"AddedByUserID"`
+	Voted         bool   `json:"Voted"`
+	Created       int64  `json:"Created"`
+	Expires       int64  `json:"Expires"`
+}
+
+// TicketNotification is the last status of a ticket that a user was emailed
+// about.
+type TicketNotification struct {
+	Ticket string `json:"Ticket"`
+	Status string `json:"Status"`
+}
+
+type Preferences struct {
+	TimeZone        string   `json:"TimeZone"`
+	Currency        string   `json:"Currency"`
+	DigestFrequency string   `json:"DigestFrequency"`
+	Language        string   `json:"Language"`
+	EmailEvents     []string `json:"EmailEvents"`
+}
+
+type MissedVoteAlert struct {
+	Event       string `json:"Event"`
+	Time        int64  `json:"Time"`
+	BlockHeight int64  `json:"BlockHeight"`
+	Misses      int64  `json:"Misses"`
+	Blocks      int64  `json:"Blocks"`
+	Threshold   int64  `json:"Threshold"`
+}
+
 type PurchaseInfo struct {
-	PoolAddress     string  `json:"PoolAddress"`
+	PoolAddress       string  `json:"PoolAddress"`
+	PoolFees          float64 `json:"PoolFees"`
+	Script            string  `json:"Script"`
+	TicketAddress     string  `json:"TicketAddress"`
+	VoteBits          uint16  `json:"VoteBits"`
+	VoteBitsVersion   uint32  `json:"VoteBitsVersion"`
+	VoteBitsReconfirm bool    `json:"VoteBitsReconfirm"`
+}
+
+// VotingPrefs are a user's vote bits along with the choice they make on each
+// agenda of the current vote version, by agenda ID.  VotingSuspended is set
+// when admins suspended changes to them.
+type VotingPrefs struct {
+	VoteBits          uint16            `json:"VoteBits"`
+	VoteBitsVersion   uint32            `json:"VoteBitsVersion"`
+	VoteBitsReconfirm bool              `json:"VoteBitsReconfirm"`
+	VotingSuspended   bool              `json:"VotingSuspended"`
+	Choices           map[string]string `json:"Choices"`
+}
+
+// SecurityEvent is a security relevant use or change of a user's account.
+type SecurityEvent struct {
+	Event     string `json:"Event"`
+	Details   string `json:"Details"`
+	IP        string `json:"IP"`
+	UserAgent string `json:"UserAgent"`
+	Created   int64  `json:"Created"`
+}
+
+// VotingAddress is a voting address of a user with the ticket address and
+// script its tickets are bought with.  ID is 0 for the first voting address
+// of the user.
+type VotingAddress struct {
+	ID               int64  `json:"ID"`
+	UserPubKeyAddr   string `json:"UserPubKeyAddr"`
+	TicketAddress    string `json:"TicketAddress"`
+	Script           string `json:"Script"`
+	HeightRegistered int64  `json:"HeightRegistered"`
+	VoteBits         uint16 `json:"VoteBits"`
+	VoteBitsVersion  uint32 `json:"VoteBitsVersion"`
+}
+
+// ScriptVerification is the result of checking a redeem script a user pasted
+// against the voting addresses the pool registered for them.  Valid is set
+// when every check passed.
+type ScriptVerification struct {
+	Valid         bool          `json:"Valid"`
+	TicketAddress string        `json:"TicketAddress"`
+	Checks        []ScriptCheck `json:"Checks"`
+}
+
+// ScriptCheck is one check of a ScriptVerification.
+type ScriptCheck struct {
+	Check   string `json:"Check"`
+	Passed  bool   `json:"Passed"`
+	Details string `json:"Details"`
+}
+
+type StakeInfo struct {
+	Merged        WalletStakeInfo   `json:"Merged"`
+	Wallets       []WalletStakeInfo `json:"Wallets"`
+	Disagreements []string          `json:"Disagreements"`
+}
+
+// StakepooldBackend is a stakepoold in rotation.  Connection is the state
+// of the gRPC connection to it, such as Ready.
+type StakepooldBackend struct {
+	Host       string `json:"Host"`
+	Connection string `json:"Connection"`
+}
+
+// Maintenance is the maintenance mode of the pool.  While it is enabled, sign
+// ups and changes of voting preferences are refused but tickets keep voting.
+// Message is shown in the banner of every page and Since is when it was
+// enabled.
+type Maintenance struct {
+	Enabled bool   `json:"Enabled"`
+	Message string `json:"Message"`
+	Since   int64  `json:"Since"`
+}
+
+// RewardEstimate is what the tickets Amount buys are expected to earn.
+// MeanVoteTime and MedianVoteTime are in seconds from the purchase.  The
+// rewards are zero while the pool hasn't voted yet.  The fiat values are in
+// FiatCurrency and left out when no price is known.
+type RewardEstimate struct {
+	BlockHeight     int64   `json:"BlockHeight"`
+	PoolSize        uint32  `json:"PoolSize"`
+	TicketPrice     float64 `json:"TicketPrice"`
 	PoolFees        float64 `json:"PoolFees"`
-	Script          string  `json:"Script"`
-	TicketAddress   string  `json:"TicketAddress"`
-	VoteBits        uint16  `json:"VoteBits"`
-	VoteBitsVersion uint32  `json:"VoteBitsVersion"`
+	Amount          float64 `json:"Amount"`
+	Tickets         int64   `json:"Tickets"`
+	VoteProbability float64 `json:"VoteProbability"`
+	MeanVoteTime    int64   `json:"MeanVoteTime"`
+	MedianVoteTime  int64   `json:"MedianVoteTime"`
+	VoteReward      float64 `json:"VoteReward"`
+	UserReward      float64 `json:"UserReward"`
+	TotalReward     float64 `json:"TotalReward"`
+	ReturnPercent   float64 `json:"ReturnPercent"`
+	FiatCurrency    string  `json:"FiatCurrency,omitempty"`
+	UserRewardFiat  float64 `json:"UserRewardFiat,omitempty"`
+	TotalRewardFiat float64 `json:"TotalRewardFiat,omitempty"`
 }
 
 type Stats struct {
@@ -44,4 +268,92 @@ type Stats struct {
 	UserCount            int64   `json:"UserCount"`
 	UserCountActive      int64   `json:"UserCountActive"`
 	Version              string  `json:"Version"`
+	Commit               string  `json:"Commit"`
+	BuildDate            string  `json:"BuildDate"`
+	FiatCurrency         string  `json:"FiatCurrency"`
+	TotalSubsidyFiat     float64 `json:"TotalSubsidyFiat"`
+}
+
+type Ticket struct {
+	Ticket          string `json:"Ticket"`
+	Status          string `json:"Status"`
+	TicketHeight    uint32 `json:"TicketHeight"`
+	SpentBy         string `json:"SpentBy"`
+	SpentByHeight   uint32 `json:"SpentByHeight"`
+	ExpiryHeight    int64  `json:"ExpiryHeight"`
+	EstimatedExpiry string `json:"EstimatedExpiry"`
+}
+
+type TicketEvent struct {
+	Event         string `json:"Event"`
+	Ticket        string `json:"Ticket"`
+	Status        string `json:"Status"`
+	TicketHeight  uint32 `json:"TicketHeight"`
+	SpentBy       string `json:"SpentBy"`
+	SpentByHeight uint32 `json:"SpentByHeight"`
+}
+
+type TicketEvents struct {
+	UserID int64         `json:"UserID"`
+	Time   int64         `json:"Time"`
+	Events []TicketEvent `json:"Events"`
+}
+
+type TicketStatus struct {
+	TicketHash  string `json:"TicketHash"`
+	Status      string `json:"Status"`
+	Changed     bool   `json:"Changed"`
+	ResumeToken string `json:"ResumeToken"`
+}
+
+type User struct {
+	UserID           int64  `json:"UserID"`
+	Email            string `json:"Email"`
+	Username         string `json:"Username"`
+	MultiSigAddress  string `json:"MultiSigAddress"`
+	UserFeeAddr      string `json:"UserFeeAddr"`
+	HeightRegistered int64  `json:"HeightRegistered"`
+	EmailVerified    bool   `json:"EmailVerified"`
+	VoteBits         uint16 `json:"VoteBits"`
+	VoteBitsVersion  uint32 `json:"VoteBitsVersion"`
+	VotingSuspended  int64  `json:"VotingSuspended"`
+	Deleted          int64  `json:"Deleted"`
+}
+
+// UserDetail is a user as shown to admins looking them up, along with their
+// voting preferences and tickets.
+type UserDetail struct {
+	User        User        `json:"User"`
+	VotingPrefs VotingPrefs `json:"VotingPrefs"`
+	Tickets     []Ticket    `json:"Tickets"`
+}
+
+type WalletStakeInfo struct {
+	Wallet           int     `json:"Wallet"`
+	Connected        bool    `json:"Connected"`
+	Error            string  `json:"Error"`
+	BlockHeight      int64   `json:"BlockHeight"`
+	Live             uint32  `json:"Live"`
+	Immature         uint32  `json:"Immature"`
+	OwnMempoolTix    uint32  `json:"OwnMempoolTix"`
+	Voted            uint32  `json:"Voted"`
+	Missed           uint32  `json:"Missed"`
+	Revoked          uint32  `json:"Revoked"`
+	Expired          uint32  `json:"Expired"`
+	ProportionLive   float64 `json:"ProportionLive"`
+	ProportionMissed float64 `json:"ProportionMissed"`
+}
+
+type Version struct {
+	Version              string `json:"Version"`
+	Commit               string `json:"Commit"`
+	BuildDate            string `json:"BuildDate"`
+	GoVersion            string `json:"GoVersion"`
+	Network              string `json:"Network"`
+	APIVersionsSupported []int  `json:"APIVersionsSupported"`
+}
+
+type Webhook struct {
+	URL    string `json:"URL"`
+	Secret string `json:"Secret"`
 }
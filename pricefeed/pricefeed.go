@@ -0,0 +1,247 @@
+// Package pricefeed fetches the fiat price of HC from public price feed
+// providers.  Prices are cached and, when every provider fails, the last known
+// prices are used until they are too old to be meaningful.
+package pricefeed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheDuration is how long fetched prices are used before the
+	// providers are asked again.
+	DefaultCacheDuration = 10 * time.Minute
+
+	// maxStaleness is how long the last known prices are used when no
+	// provider can be reached.
+	maxStaleness = 6 * time.Hour
+
+	requestTimeout = 10 * time.Second
+)
+
+// Currencies are the currencies prices are fetched in.
+var Currencies = []string{"USD", "EUR", "GBP", "JPY", "CNY", "BTC"}
+
+// ErrNoPrice is returned when no price is known for a currency.
+var ErrNoPrice = errors.New("no price available")
+
+// Provider is a source of HC prices.
+type Provider interface {
+	// Name identifies the provider in configuration and logs.
+	Name() string
+
+	// Prices returns the price of one HC in each of the currencies, keyed
+	// by the upper case currency code.  Currencies the provider doesn't
+	// know are left out.
+	Prices(currencies []string) (map[string]float64, error)
+}
+
+// providers are the known providers by name.
+var providers = map[string]func(*http.Client) Provider{
+	"coingecko":     newCoinGecko,
+	"cryptocompare": newCryptoCompare,
+}
+
+// Providers returns the names of the known providers.
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider returns the provider called name, which connects through
+// transport.  A nil transport uses http.DefaultTransport.
+func NewProvider(name string, transport http.RoundTripper) (Provider, error) {
+	newProvider, ok := providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown price feed %q, must be one of %s",
+			name, strings.Join(Providers(), ", "))
+	}
+	return newProvider(&http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+	}), nil
+}
+
+// Feed supplies prices from the first of its providers that answers.  The
+// providers are asked in the background, so a slow provider never delays the
+// callers, which get the last known prices in the meantime.  It is safe for
+// concurrent access.
+type Feed struct {
+	providers     []Provider
+	cacheDuration time.Duration
+
+	mtx      sync.Mutex
+	prices   map[string]float64
+	fetched  time.Time
+	tried    time.Time
+	fetching bool
+}
+
+// New returns a feed that tries the providers in order.
+func New(providers []Provider, cacheDuration time.Duration) *Feed {
+	return &Feed{
+		providers:     providers,
+		cacheDuration: cacheDuration,
+	}
+}
+
+// Refresh fetches the prices now unless a fetch is already in progress.  It
+// blocks until the providers answered and is meant to fill the feed at
+// startup.
+func (f *Feed) Refresh() {
+	f.mtx.Lock()
+	if f.fetching {
+		f.mtx.Unlock()
+		return
+	}
+	f.fetching = true
+	f.tried = time.Now()
+	f.mtx.Unlock()
+
+	f.fetch()
+}
+
+// Price returns the last known price of one HC in currency.  When the prices
+// are older than the cache duration, new ones are fetched in the background.
+func (f *Feed) Price(currency string) (float64, error) {
+	currency = strings.ToUpper(currency)
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	// Don't hammer the providers while they are failing; retry once per
+	// cache period like a successful fetch would.
+	now := time.Now()
+	if !f.fetching && now.Sub(f.tried) >= f.cacheDuration {
+		f.fetching = true
+		f.tried = now
+		go f.fetch()
+	}
+
+	price, ok := f.prices[currency]
+	if !ok || now.Sub(f.fetched) > maxStaleness {
+		return 0, ErrNoPrice
+	}
+	return price, nil
+}
+
+// Value converts an amount of HC to currency.
+func (f *Feed) Value(amount float64, currency string) (float64, error) {
+	price, err := f.Price(currency)
+	if err != nil {
+		return 0, err
+	}
+	return amount * price, nil
+}
+
+// fetch updates the prices from the first provider that answers.  The
+// previous prices are kept when all of them fail.  The providers are asked
+// without holding the feed lock.
+//
+// This function MUST only be called by the caller that set fetching.
+func (f *Feed) fetch() {
+	defer func() {
+		f.mtx.Lock()
+		f.fetching = false
+		f.mtx.Unlock()
+	}()
+
+	for _, p := range f.providers {
+		prices, err := p.Prices(Currencies)
+		if err != nil {
+			log.Warnf("price feed %s failed: %v", p.Name(), err)
+			continue
+		}
+		if len(prices) == 0 {
+			log.Warnf("price feed %s returned no prices", p.Name())
+			continue
+		}
+		f.mtx.Lock()
+		f.prices = prices
+		f.fetched = time.Now()
+		f.mtx.Unlock()
+		log.Debugf("fetched prices from %s: %v", p.Name(), prices)
+		return
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.prices != nil {
+		log.Warnf("all price feeds failed, using prices from %v",
+			f.fetched.Format(time.RFC3339))
+	}
+}
+
+// getJSON fetches url and decodes the JSON response into v.
+func getJSON(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// coinGecko fetches prices from the CoinGecko simple price API.
+type coinGecko struct {
+	client *http.Client
+}
+
+func newCoinGecko(client *http.Client) Provider {
+	return &coinGecko{client: client}
+}
+
+func (p *coinGecko) Name() string { return "coingecko" }
+
+func (p *coinGecko) Prices(currencies []string) (map[string]float64, error) {
+	url := "https://api.coingecko.com/api/v3/simple/price?ids=hypercash" +
+		"&vs_currencies=" + strings.ToLower(strings.Join(currencies, ","))
+	var resp map[string]map[string]float64
+	if err := getJSON(p.client, url, &resp); err != nil {
+		return nil, err
+	}
+	prices := make(map[string]float64)
+	for currency, price := range resp["hypercash"] {
+		prices[strings.ToUpper(currency)] = price
+	}
+	return prices, nil
+}
+
+// cryptoCompare fetches prices from the CryptoCompare price API.
+type cryptoCompare struct {
+	client *http.Client
+}
+
+func newCryptoCompare(client *http.Client) Provider {
+	return &cryptoCompare{client: client}
+}
+
+func (p *cryptoCompare) Name() string { return "cryptocompare" }
+
+func (p *cryptoCompare) Prices(currencies []string) (map[string]float64, error) {
+	url := "https://min-api.cryptocompare.com/data/price?fsym=HC&tsyms=" +
+		strings.ToUpper(strings.Join(currencies, ","))
+	var resp map[string]float64
+	if err := getJSON(p.client, url, &resp); err != nil {
+		return nil, err
+	}
+	prices := make(map[string]float64)
+	for currency, price := range resp {
+		prices[strings.ToUpper(currency)] = price
+	}
+	return prices, nil
+}
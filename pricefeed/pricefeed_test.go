@@ -0,0 +1,107 @@
+package pricefeed
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type testProvider struct {
+	prices map[string]float64
+	err    error
+	calls  int
+	block  chan struct{}
+}
+
+func (p *testProvider) Name() string { return "test" }
+
+func (p *testProvider) Prices(currencies []string) (map[string]float64, error) {
+	p.calls++
+	if p.block != nil {
+		<-p.block
+	}
+	return p.prices, p.err
+}
+
+func TestFeed(t *testing.T) {
+	down := &testProvider{err: errors.New("down")}
+	up := &testProvider{prices: map[string]float64{"USD": 2}}
+	feed := New([]Provider{down, up}, time.Hour)
+
+	// The second provider is used when the first fails.
+	feed.Refresh()
+	value, err := feed.Value(10, "usd")
+	if err != nil || value != 20 {
+		t.Fatalf("expected value 20, got %v (err %v)", value, err)
+	}
+	if _, err := feed.Price("EUR"); err != ErrNoPrice {
+		t.Errorf("expected ErrNoPrice for EUR, got %v", err)
+	}
+
+	// Prices are cached.
+	if _, err := feed.Price("USD"); err != nil || up.calls != 1 {
+		t.Errorf("expected a cached price, got %d calls (err %v)",
+			up.calls, err)
+	}
+
+	// The last known prices are used while all providers fail.
+	up.err = errors.New("down")
+	feed.Refresh()
+	if price, err := feed.Price("USD"); err != nil || price != 2 {
+		t.Errorf("expected last known price 2, got %v (err %v)", price, err)
+	}
+
+	// Until they get too old.
+	feed.fetched = time.Now().Add(-maxStaleness - time.Minute)
+	if _, err := feed.Price("USD"); err != ErrNoPrice {
+		t.Errorf("expected ErrNoPrice for stale prices, got %v", err)
+	}
+}
+
+func TestFeedBackground(t *testing.T) {
+	slow := &testProvider{
+		prices: map[string]float64{"USD": 2},
+		block:  make(chan struct{}),
+	}
+	feed := New([]Provider{slow}, time.Hour)
+
+	// Price doesn't wait for the provider.
+	done := make(chan error)
+	go func() {
+		_, err := feed.Price("USD")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != ErrNoPrice {
+			t.Errorf("expected ErrNoPrice while fetching, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Price blocked on the provider")
+	}
+
+	// The price is served once the fetch finished.
+	close(slow.block)
+	for i := 0; i < 100; i++ {
+		if price, err := feed.Price("USD"); err == nil {
+			if price != 2 {
+				t.Errorf("expected price 2, got %v", price)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("the price was never fetched")
+}
+
+func TestNewProvider(t *testing.T) {
+	for _, name := range Providers() {
+		p, err := NewProvider(name, nil)
+		if err != nil || p.Name() != name {
+			t.Errorf("NewProvider(%q) failed: %v", name, err)
+		}
+	}
+	if _, err := NewProvider("nosuchfeed", nil); err == nil {
+		t.Errorf("expected an error for an unknown price feed")
+	}
+}
@@ -0,0 +1,35 @@
+// Package proxy connects HTTP clients to external services through the
+// SOCKS5 proxy an operator configured, so that a pool run over Tor doesn't
+// reveal itself to price feeds, CAPTCHA providers, chat services or trace
+// collectors.
+package proxy
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/btcsuite/go-socks/socks"
+)
+
+// HTTPTransport returns a transport connecting through the SOCKS5 proxy at
+// addr with the credentials user and pass, which may be empty.  Hostnames are
+// handed to the proxy unresolved so no DNS lookups leak outside of it.  It
+// returns nil, which makes HTTP clients use http.DefaultTransport, when addr is
+// empty.
+func HTTPTransport(addr, user, pass string) http.RoundTripper {
+	if addr == "" {
+		return nil
+	}
+	p := &socks.Proxy{
+		Addr:     addr,
+		Username: user,
+		Password: pass,
+	}
+	return &http.Transport{
+		Dial:                  p.Dial,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
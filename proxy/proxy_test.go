@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serveSOCKS5 accepts one connection on ln as a SOCKS5 proxy without
+// authentication, sends the host it was asked to connect to on hosts and
+// connects the client to target instead.
+func serveSOCKS5(t *testing.T, ln net.Listener, target string, hosts chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	// Greeting: version, number of methods, methods.
+	buf := make([]byte, 262)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		t.Error(err)
+		return
+	}
+	conn.Write([]byte{5, 0})
+
+	// Request: version, connect, reserved, domain name type, length, name
+	// and port.
+	if _, err := io.ReadFull(conn, buf[:5]); err != nil {
+		t.Error(err)
+		return
+	}
+	if buf[3] != 3 {
+		t.Errorf("got address type %d, want a domain name", buf[3])
+		return
+	}
+	n := int(buf[4])
+	if _, err := io.ReadFull(conn, buf[:n+2]); err != nil {
+		t.Error(err)
+		return
+	}
+	hosts <- string(buf[:n])
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func TestHTTPTransport(t *testing.T) {
+	if tr := HTTPTransport("", "", ""); tr != nil {
+		t.Errorf("got transport %v without a proxy", tr)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	hosts := make(chan string, 1)
+	go serveSOCKS5(t, ln, srv.Listener.Addr().String(), hosts)
+
+	client := &http.Client{
+		Transport: HTTPTransport(ln.Addr().String(), "", ""),
+	}
+	resp, err := client.Get("http://feed.example.invalid/prices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "ok" {
+		t.Errorf("got body %q", body)
+	}
+	if host := <-hosts; host != "feed.example.invalid" {
+		t.Errorf("proxy was asked for %q, want the unresolved host", host)
+	}
+}
@@ -0,0 +1,61 @@
+// Draws the pool growth charts of the stats page with d3.  Each chart is a
+// line of one field of the days, one point per day.
+function poolGrowthChart(id, days, field, color) {
+    var el = document.getElementById(id);
+    if (!el || !days || days.length === 0) {
+        return;
+    }
+
+    var margin = {top: 10, right: 20, bottom: 30, left: 50},
+        width = (el.clientWidth || 400) - margin.left - margin.right,
+        height = 200 - margin.top - margin.bottom;
+
+    var parseDate = d3.time.format("%Y-%m-%d").parse;
+    var data = days.map(function(d) {
+        return {date: parseDate(d.Date), value: d[field]};
+    });
+
+    var x = d3.time.scale()
+        .range([0, width])
+        .domain(d3.extent(data, function(d) { return d.date; }));
+    var y = d3.scale.linear()
+        .range([height, 0])
+        .domain([0, d3.max(data, function(d) { return d.value; }) || 1])
+        .nice();
+
+    var svg = d3.select(el).append("svg")
+        .attr("width", width + margin.left + margin.right)
+        .attr("height", height + margin.top + margin.bottom)
+      .append("g")
+        .attr("transform", "translate(" + margin.left + "," + margin.top + ")");
+
+    svg.append("g")
+        .attr("class", "x axis")
+        .attr("transform", "translate(0," + height + ")")
+        .call(d3.svg.axis().scale(x).orient("bottom").ticks(5));
+    svg.append("g")
+        .attr("class", "y axis")
+        .call(d3.svg.axis().scale(y).orient("left").ticks(5));
+
+    var line = d3.svg.line()
+        .x(function(d) { return x(d.date); })
+        .y(function(d) { return y(d.value); });
+    svg.append("path")
+        .datum(data)
+        .attr("fill", "none")
+        .attr("stroke", color)
+        .attr("stroke-width", 2)
+        .attr("d", line);
+    svg.selectAll("circle")
+        .data(data)
+      .enter().append("circle")
+        .attr("cx", function(d) { return x(d.date); })
+        .attr("cy", function(d) { return y(d.value); })
+        .attr("r", 2.5)
+        .attr("fill", color)
+      .append("title")
+        .text(function(d) {
+            return d3.time.format("%Y-%m-%d")(d.date) + ": " +
+                (field === "Proportion" ? d.value.toFixed(2) + "%" : d.value);
+        });
+}
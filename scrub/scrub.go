@@ -0,0 +1,96 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package scrub redacts sensitive values such as passphrases, API tokens,
+// session IDs and email addresses from text before it is logged or returned
+// to a client.
+package scrub
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces every sensitive value.
+const Redacted = "[redacted]"
+
+// minSecretLen is the shortest secret AddSecrets accepts.  Shorter values
+// would redact ordinary words.
+const minSecretLen = 4
+
+var (
+	// emailRE matches email addresses.
+	emailRE = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+	// jwtRE matches JSON web tokens, which is what API tokens are.
+	jwtRE = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`)
+
+	// bearerRE matches the credentials of an Authorization header.
+	bearerRE = regexp.MustCompile(`(?i)(bearer\s+)[^\s"',;]+`)
+
+	// keyValueRE matches values assigned to keys that name a secret, as in
+	// query strings, config dumps, cookies and JSON.  Keys start at a word
+	// boundary, so bypass= and compass: are left alone, but may carry the
+	// prefix of a config option, as dbpassword= and smtp_pass= do.
+	keyValueRE = regexp.MustCompile(`(?i)(\b(?:(?:api|cookie|db|hcd|` +
+		`hcaptcha|missedvote|proxy|recaptcha|rpc|smtp|stakepoold|` +
+		`telegrambot|wallet)_?)?(?:pass(?:word|phrase|wd)?s?|secret|` +
+		`api_?token|api_?key|session(?:_?id)?|csrf(?:_?token)?|token)` +
+		`["']?\s*[:=]\s*["']?)[^\s"'&,;]+`)
+)
+
+var (
+	secretsMtx sync.RWMutex
+	secrets    []string
+	replacer   = strings.NewReplacer()
+)
+
+// AddSecrets registers values, typically configured passwords and signing
+// secrets, that are redacted wherever they appear.  Empty and very short
+// values are ignored.
+func AddSecrets(values ...string) {
+	secretsMtx.Lock()
+	defer secretsMtx.Unlock()
+
+	for _, v := range values {
+		if len(v) < minSecretLen {
+			continue
+		}
+		secrets = append(secrets, v, Redacted)
+	}
+	replacer = strings.NewReplacer(secrets...)
+}
+
+// String returns s with all sensitive values replaced by Redacted.
+func String(s string) string {
+	secretsMtx.RLock()
+	s = replacer.Replace(s)
+	secretsMtx.RUnlock()
+
+	s = jwtRE.ReplaceAllString(s, Redacted)
+	s = bearerRE.ReplaceAllString(s, "${1}"+Redacted)
+	s = keyValueRE.ReplaceAllString(s, "${1}"+Redacted)
+	return emailRE.ReplaceAllString(s, Redacted)
+}
+
+// Bytes is String for byte slices.
+func Bytes(p []byte) []byte {
+	return []byte(String(string(p)))
+}
+
+// Error returns an error with the scrubbed message of err, or err itself if
+// there is nothing to redact.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	scrubbed := String(msg)
+	if scrubbed == msg {
+		return err
+	}
+	return errors.New(scrubbed)
+}
@@ -1,20 +1,33 @@
 package main
 
 import (
+	stdcontext "context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"google.golang.org/grpc"
 
+	"github.com/go-gorp/gorp"
 	"github.com/gorilla/context"
 
 	"github.com/coolsnady/hcrpcclient"
 	"github.com/coolsnady/hcstakepool/controllers"
+	"github.com/coolsnady/hcstakepool/i18n"
+	"github.com/coolsnady/hcstakepool/notifier"
+	"github.com/coolsnady/hcstakepool/pricefeed"
+	"github.com/coolsnady/hcstakepool/proxy"
 	"github.com/coolsnady/hcstakepool/stakepooldclient"
 	"github.com/coolsnady/hcstakepool/system"
+	"github.com/coolsnady/hcstakepool/systemd"
+	"github.com/coolsnady/hcstakepool/tracing"
+	"github.com/coolsnady/hcstakepool/version"
+	"github.com/coolsnady/hcutil"
 
 	"github.com/zenazn/goji/graceful"
 	"github.com/zenazn/goji/web"
@@ -41,6 +54,31 @@ func listenTo(bind string) (net.Listener, error) {
 	return nil, fmt.Errorf("error while parsing bind arg %v", bind)
 }
 
+// listenersFor returns the sockets passed by systemd for a server, or a
+// listener bound to bind when there are none.
+func listenersFor(bind string, activated []net.Listener) ([]net.Listener, error) {
+	if len(activated) != 0 {
+		return activated, nil
+	}
+	listener, err := listenTo(bind)
+	if err != nil {
+		return nil, err
+	}
+	return []net.Listener{listener}, nil
+}
+
+// publicFS returns the static files in dir of publicpath, overridden by the
+// ones in the same dir of the public folder of overridepath.
+func publicFS(dir string) http.FileSystem {
+	fs := system.OverlayFS{http.Dir(filepath.Join(cfg.PublicPath, dir))}
+	if cfg.OverridePath != "" {
+		override := http.Dir(filepath.Join(cfg.OverridePath,
+			system.OverridePublic, dir))
+		fs = append(system.OverlayFS{override}, fs...)
+	}
+	return fs
+}
+
 func runMain() int {
 	// Load configuration and parse command line.  This function also
 	// initializes logging and configures it accordingly.
@@ -49,7 +87,7 @@ func runMain() int {
 		return 1
 	}
 	cfg = loadedCfg
-	log.Infof("Version: %s", version())
+	log.Infof("Version: %s", version.Summary())
 	log.Infof("Network: %s", activeNetParams.Params.Name)
 
 	defer func() {
@@ -58,15 +96,23 @@ func runMain() int {
 		}
 	}()
 
-	var application = &system.Application{}
-
-	application.Init(cfg.APISecret, cfg.BaseURL, cfg.CookieSecret,
-		cfg.CookieSecure, cfg.DBHost, cfg.DBName, cfg.DBPassword, cfg.DBPort,
-		cfg.DBUser)
-	if application.DbMap == nil {
-		log.Critical("Failed to open database.")
-		return 7
+	locales, err := i18n.Load(cfg.LocalePath)
+	if err != nil {
+		log.Criticalf("Failed to load locales: %v", err)
+		return 2
+	}
+	log.Infof("Languages: %s", strings.Join(locales.Languages(), ", "))
+
+	var application = &system.Application{
+		Funcs:        locales.Funcs(),
+		OverridePath: cfg.OverridePath,
+		Security: system.SecurityOptions{
+			ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+			HSTSMaxAge:            cfg.HSTSMaxAge,
+			CookieSecure:          cfg.CookieSecure,
+		},
 	}
+
 	if err = application.LoadTemplates(cfg.TemplatePath); err != nil {
 		log.Criticalf("Failed to load templates: %v", err)
 		return 2
@@ -80,15 +126,106 @@ func runMain() int {
 
 	// Setup static files
 	assetHandler := http.StripPrefix("/assets/",
-		http.FileServer(http.Dir(cfg.PublicPath)))
+		http.FileServer(publicFS("")))
+
+	// Start serving static pages and the startup status right away so the
+	// pool doesn't appear to be down while waiting on its dependencies.
+	status := newStartupStatus()
+	handler := &switchHandler{
+		handler: degradedHandler(application, assetHandler, status),
+	}
+	var https *httpsConfig
+	if cfg.TLSListen != "" {
+		https, err = newHTTPSConfig()
+		if err != nil {
+			log.Errorf("could not configure HTTPS: %v", err)
+			return 5
+		}
+	}
+
+	// With systemd socket activation, the sockets named https in the
+	// socket unit serve HTTPS and the others HTTP, instead of binding to
+	// the listen and tlslisten addresses.
+	activated, err := systemd.Listeners()
+	if err != nil {
+		log.Errorf("could not use the sockets passed by systemd: %v", err)
+		return 5
+	}
+	activatedHTTPS := activated["https"]
+	delete(activated, "https")
+	var activatedHTTP []net.Listener
+	for _, ls := range activated {
+		activatedHTTP = append(activatedHTTP, ls...)
+	}
+	if https == nil && len(activatedHTTPS) != 0 {
+		log.Errorf("systemd passed https sockets but tlslisten is not set")
+		return 5
+	}
+
+	server := &http.Server{Handler: https.httpHandler(handler)}
+	listeners, err := listenersFor(cfg.Listen, activatedHTTP)
+	if err != nil {
+		log.Errorf("could not bind %v", err)
+		return 5
+	}
+	serveErr := make(chan error, len(listeners)+len(activatedHTTPS)+1)
+	for _, listener := range listeners {
+		listener := listener
+		go func() {
+			serveErr <- server.Serve(listener)
+		}()
+		log.Infof("listening on %v while starting", listener.Addr())
+	}
+	if https != nil {
+		tlsListeners, err := listenersFor(cfg.TLSListen, activatedHTTPS)
+		if err != nil {
+			log.Errorf("could not bind %v", err)
+			return 5
+		}
+		tlsServer := &http.Server{Handler: handler}
+		for _, tlsListener := range tlsListeners {
+			tlsListener := tlsListener
+			go func() {
+				serveErr <- tlsServer.Serve(tls.NewListener(
+					tlsListener, https.tlsConfig))
+			}()
+			log.Infof("listening for HTTPS on %v", tlsListener.Addr())
+		}
+	}
+
+	err = retryStartup(status, "MySQL", func() error {
+		application.Init(cfg.APISecret, cfg.BaseURL, cfg.CookieSecret,
+			cfg.DBHost, cfg.DBName, cfg.DBPassword,
+			cfg.DBPort, cfg.DBUser)
+		if application.DbMap == nil {
+			return errors.New("failed to open database")
+		}
+		return nil
+	})
+	if err != nil {
+		log.Criticalf("Failed to open database: %v", err)
+		return 7
+	}
 
 	// Apply middleware
 	app := web.New()
 	app.Handle("/assets/*", assetHandler)
 
+	// External services are reached through the proxy when one is set.
+	transport := proxy.HTTPTransport(cfg.Proxy, cfg.ProxyUser, cfg.ProxyPass)
+
+	// Spans of requests are exported when otlpendpoint is set.
+	tracer := tracing.NewTracer("hcstakepool", cfg.OTLPEndpoint, transport)
+	if tracer != nil {
+		log.Infof("Exporting traces to %s", cfg.OTLPEndpoint)
+	}
+
 	app.Use(middleware.RequestID)
+	app.Use(application.ApplyRequestID)
+	app.Use(tracer.Middleware)
 	app.Use(middleware.Logger) // TODO: reimplement to use our logger
 	app.Use(middleware.Recoverer)
+	app.Use(application.ApplySecurityHeaders)
 
 	// Execute various middleware functions.  The order is very important
 	// as each function establishes part of the application environment/context
@@ -106,10 +243,25 @@ func runMain() int {
 	APIVersionsSupported := []int{1, 2}
 
 	grpcConnections := make([]*grpc.ClientConn, len(cfg.StakepooldHosts))
+	var dialStakepoold controllers.StakepooldDialer
 
 	if cfg.EnableStakepoold {
+		dialStakepoold = func(host, cert string) (*grpc.ClientConn, error) {
+			return stakepooldclient.ConnectStakepooldGRPC([]string{host},
+				[]string{cert}, 0, cfg.StakepooldToken,
+				cfg.StakepooldCompress, cfg.Proxy, cfg.ProxyUser,
+				cfg.ProxyPass)
+		}
+
 		for i := range cfg.StakepooldHosts {
-			grpcConnections[i], err = stakepooldclient.ConnectStakepooldGRPC(cfg.StakepooldHosts, cfg.StakepooldCerts, i)
+			i := i
+			dependency := fmt.Sprintf("stakepoold host %d", i)
+			err = retryStartup(status, dependency, func() error {
+				var err error
+				grpcConnections[i], err = dialStakepoold(cfg.StakepooldHosts[i],
+					cfg.StakepooldCerts[i])
+				return err
+			})
 			if err != nil {
 				log.Errorf("Failed to connect to stakepoold host %d: %v", i, err)
 				return 8
@@ -117,15 +269,75 @@ func runMain() int {
 		}
 	}
 
+	var priceFeed *pricefeed.Feed
+	if !cfg.NoPriceFeed {
+		var providers []pricefeed.Provider
+		for _, name := range strings.Split(cfg.PriceFeeds, ",") {
+			// Names were validated by loadConfig.
+			provider, _ := pricefeed.NewProvider(name, transport)
+			providers = append(providers, provider)
+		}
+		priceFeed = pricefeed.New(providers, cfg.PriceFeedCache)
+		// Fill the feed so the first pages already show fiat values.
+		go priceFeed.Refresh()
+	}
+
+	var missedVoteAlert *controllers.MissedVoteAlert
+	if cfg.MissedVoteAlert > 0 {
+		missedVoteAlert = controllers.NewMissedVoteAlert(cfg.MissedVoteAlert,
+			cfg.MissedVoteBlocks, cfg.MissedVoteWebhook, cfg.MissedVoteSecret,
+			cfg.MissedVoteBanner)
+	}
+
+	captchaSecret, captchaSitekey := cfg.RecaptchaSecret, cfg.RecaptchaSitekey
+	if cfg.CaptchaProvider == controllers.CaptchaHCaptcha {
+		captchaSecret, captchaSitekey = cfg.HCaptchaSecret, cfg.HCaptchaSitekey
+	}
+	// The provider was validated by loadConfig.
+	captcha, _ := controllers.NewCaptcha(cfg.CaptchaProvider, captchaSecret,
+		captchaSitekey, transport)
+
+	var mailer *controllers.Mailer
+	if cfg.SMTPHost != "" {
+		// The security and auth mechanism were validated by loadConfig.
+		mailer, _ = controllers.NewMailer(cfg.SMTPHost, cfg.SMTPFrom,
+			cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPSecurity,
+			cfg.SMTPAuth)
+		defer mailer.Close()
+	}
+
+	var telegram *controllers.Telegram
+	if cfg.TelegramBotToken != "" {
+		// The chat ids were validated by loadConfig.
+		chatIDs, _ := controllers.ParseTelegramChatIDs(cfg.TelegramChatIDs)
+		telegram = controllers.NewTelegram(cfg.TelegramBotToken, chatIDs,
+			transport)
+	}
+
+	var chatNotifier *notifier.Notifier
+	if len(cfg.ChatWebhooks) > 0 {
+		webhooks := make([]*notifier.Webhook, 0, len(cfg.ChatWebhooks))
+		for _, s := range cfg.ChatWebhooks {
+			// The webhooks were validated by loadConfig.
+			webhook, _ := notifier.ParseWebhook(s)
+			webhooks = append(webhooks, webhook)
+		}
+		chatNotifier = notifier.New(webhooks, transport)
+	}
+
 	controller, err := controllers.NewMainController(activeNetParams.Params,
 		cfg.AdminIPs, cfg.AdminUserIDs, cfg.APISecret, APIVersionsSupported, cfg.BaseURL,
 		cfg.ClosePool, cfg.ClosePoolMsg, cfg.EnableStakepoold,
-		cfg.ColdWalletExtPub, grpcConnections, cfg.PoolFees, cfg.PoolEmail,
-		cfg.PoolLink, cfg.RecaptchaSecret, cfg.RecaptchaSitekey, cfg.SMTPFrom,
-		cfg.SMTPHost, cfg.SMTPUsername, cfg.SMTPPassword, cfg.Version,
+		cfg.ColdWalletExtPub, grpcConnections, cfg.StakepooldHosts,
+		dialStakepoold, cfg.PoolFees, cfg.PoolEmail,
+		cfg.PoolLink, cfg.PoolName, cfg.EmailLogoURL, captcha, mailer,
+		cfg.Version,
 		cfg.WalletHosts, cfg.WalletCerts, cfg.WalletUsers, cfg.WalletPasswords,
-		cfg.MinServers, cfg.RealIPHeader, cfg.VotingWalletExtPub,
-		cfg.MaxVotedAge)
+		cfg.MinServers, cfg.LockedWallets, cfg.RealIPHeader, cfg.VotingWalletExtPub,
+		cfg.MaxVotedAge, cfg.ExpiryWarning, priceFeed, missedVoteAlert,
+		telegram, chatNotifier, cfg.FeeAddressWarning, cfg.StakeVersionWarn, cfg.TermsVersion,
+		cfg.InviteSignup, cfg.InviteExpiry, cfg.MaintenanceFile, locales,
+		application)
 	if err != nil {
 		application.Close()
 		log.Errorf("Failed to initialize the main controller: %v",
@@ -135,37 +347,50 @@ func runMain() int {
 		return 3
 	}
 
-	// reset votebits if Vote Version changed or stored VoteBits are invalid
+	// migrate votebits if Vote Version changed and reset them if the stored
+	// VoteBits are invalid
 	controller.CheckAndResetUserVoteBits(application.DbMap)
 
 	if cfg.EnableStakepoold {
-		err = controller.StakepooldUpdateAll(application.DbMap, controllers.StakepooldUpdateKindAll)
+		err = verifyColdWalletExtPub(controller, grpcConnections)
 		if err != nil {
-			log.Errorf("TriggerStakepooldUpdates failed: %v", err)
-			return 9
+			log.Criticalf("Refusing to start: %v", err)
+			fmt.Fprintf(os.Stderr, "Fatal error verifying coldwalletextpub: "+
+				"%v\n", err)
+			return 10
 		}
-		for i := range grpcConnections {
-			addedLowFeeTickets, err := stakepooldclient.StakepooldGetAddedLowFeeTickets(grpcConnections[i])
-			if err != nil {
-				log.Errorf("GetAddedLowFeeTickets failed on host %d: %v", i, err)
-				return 9
-			}
-			ignoredLowFeeTickets, err := stakepooldclient.StakepooldGetIgnoredLowFeeTickets(grpcConnections[i])
-			if err != nil {
-				log.Errorf("GetIgnoredLowFeeTickets failed on host %d: %v", i, err)
-				return 9
-			}
-			liveTickets, err := stakepooldclient.StakepooldGetLiveTickets(grpcConnections[i])
-			if err != nil {
-				log.Errorf("GetLiveTickets failed on host %d: %v", i, err)
-				return 9
-			}
-			log.Infof("stakepoold %d reports ticket totals of AddedLowFee %v "+
-				"IgnoredLowFee %v Live %v", i, len(addedLowFeeTickets),
-				len(ignoredLowFeeTickets), len(liveTickets))
+
+		err = retryStartup(status, "stakepoold sync", func() error {
+			return stakepooldSync(controller, application.DbMap,
+				grpcConnections)
+		})
+		if err != nil {
+			log.Errorf("Failed to sync stakepoold: %v", err)
+			return 9
 		}
 	}
 
+	// Warn about, or with walletcheck=refuse refuse, hot wallets attached as
+	// voting wallets by mistake.
+	maxWalletBalance, _ := hcutil.NewAmount(cfg.MaxWalletBalance)
+	problems, err := controller.CheckVotingWallets(maxWalletBalance)
+	if err != nil {
+		application.Close()
+		log.Errorf("Failed to check the voting wallets: %v", err)
+		return 11
+	}
+	for _, problem := range problems {
+		log.Criticalf("Voting wallet is not voting-only: %s", problem)
+	}
+	if len(problems) != 0 && cfg.WalletCheck == walletCheckRefuse {
+		application.Close()
+		log.Critical("Refusing to start with voting wallets that aren't " +
+			"voting-only, set walletcheck=warn to start anyway")
+		fmt.Fprintf(os.Stderr, "Fatal error: %d problems with the voting "+
+			"wallets, see the log\n", len(problems))
+		return 11
+	}
+
 	err = controller.RPCSync(application.DbMap)
 	if err != nil {
 		application.Close()
@@ -176,26 +401,49 @@ func runMain() int {
 
 	controller.RPCStart()
 
+	app.Use(controller.ApplyRememberMe)
+	app.Use(controller.ApplyMissedVoteAlert)
+	app.Use(controller.ApplyMaintenance)
+	app.Use(controller.ApplyLanguage)
+	app.Use(controller.ApplyTerms)
+
 	// Couple of files - in the real world you would use nginx to serve them.
-	app.Get("/robots.txt", http.FileServer(http.Dir(cfg.PublicPath)))
-	app.Get("/favicon.ico", http.FileServer(http.Dir(cfg.PublicPath+"/images")))
+	app.Get("/robots.txt", http.FileServer(publicFS("")))
+	app.Get("/favicon.ico", http.FileServer(publicFS("images")))
 
 	// Home page
 	app.Get("/", application.Route(controller, "Index"))
 
+	// Admin agenda outcome simulation page
+	app.Get("/adminagendas", application.Route(controller, "AdminAgendas"))
+
 	// Admin tickets page
 	app.Get("/admintickets", application.Route(controller, "AdminTickets"))
 	app.Post("/admintickets", application.Route(controller, "AdminTicketsPost"))
 	// Admin status page
 	app.Get("/status", application.Route(controller, "AdminStatus"))
+	// Admin ticket export
+	app.Get("/adminexport", application.Route(controller, "AdminExport"))
+	// Admin sign in lockouts
+	app.Get("/adminlockouts", application.Route(controller, "AdminLockouts"))
+	app.Post("/adminlockouts", application.Route(controller, "AdminLockoutsPost"))
+	// Admin stakepoold backends page
+	app.Get("/adminstakepoold", application.Route(controller, "AdminStakepoold"))
+	app.Post("/adminstakepoold", application.Route(controller, "AdminStakepooldPost"))
+	// Admin audit log
+	app.Get("/adminaudit", application.Route(controller, "AdminAudit"))
+	// Admin invite codes
+	app.Get("/admininvites", application.Route(controller, "AdminInvites"))
+	app.Post("/admininvites", application.Route(controller, "AdminInvitesPost"))
 
 	// Address form
 	app.Get("/address", application.Route(controller, "Address"))
 	app.Post("/address", application.Route(controller, "AddressPost"))
 
 	// API
-	app.Handle("/api/v1/:command", application.APIHandler(controller.API))
-	app.Handle("/api/v2/:command", application.APIHandler(controller.API))
+	app.Handle("/api/v1/:command", system.DeprecatedAPIHandler("/api/v2",
+		application.APIHandler(controller.API)))
+	app.Handle("/api/v2/:command", application.APIv2Handler(controller.APIv2))
 	app.Handle("/api/*", gojify(system.APIInvalidHandler))
 
 	// Email change/update confirmation
@@ -218,6 +466,7 @@ func runMain() int {
 	// Settings routes
 	app.Get("/settings", application.Route(controller, "Settings"))
 	app.Post("/settings", application.Route(controller, "SettingsPost"))
+	app.Get("/settings/export", application.Route(controller, "AccountExport"))
 
 	// Sign In routes
 	app.Get("/signin", application.Route(controller, "SignIn"))
@@ -230,8 +479,17 @@ func runMain() int {
 	// Stats
 	app.Get("/stats", application.Route(controller, "Stats"))
 
+	// Terms of service
+	app.Get("/terms", application.Route(controller, "Terms"))
+	app.Post("/terms", application.Route(controller, "TermsPost"))
+
 	// Tickets
 	app.Get("/tickets", application.Route(controller, "Tickets"))
+	app.Get("/tickets/:ticket", application.Route(controller, "Ticket"))
+
+	// Redeem script verification
+	app.Get("/verifyscript", application.Route(controller, "VerifyScript"))
+	app.Post("/verifyscript", application.Route(controller, "VerifyScriptPost"))
 
 	// Voting routes
 	app.Get("/voting", application.Route(controller, "Voting"))
@@ -243,20 +501,37 @@ func runMain() int {
 	graceful.PostHook(func() {
 		controller.RPCStop()
 		application.Close()
+		tracer.Shutdown()
 	})
 	app.Abandon(middleware.Logger)
 	app.Compile()
 
-	server := &http.Server{Handler: app}
-	listener, err := listenTo(cfg.Listen)
-	if err != nil {
-		log.Errorf("could not bind %v", err)
-		return 5
+	handler.set(app)
+	log.Infof("startup complete, serving on %v", listeners[0].Addr())
+	if err := systemd.Notify("READY=1"); err != nil {
+		log.Warnf("unable to notify systemd: %v", err)
 	}
 
-	log.Infof("listening on %v", listener.Addr())
+	go controller.VoteVersionHandler(application.DbMap)
+	go controller.TicketNotificationHandler(application.DbMap)
+	go controller.WebhookHandler(application.DbMap)
+	go controller.VoteHistoryHandler(application.DbMap)
+	go controller.PoolGrowthHandler(application.DbMap)
+	if missedVoteAlert != nil {
+		go controller.MissedVoteAlertHandler(application.DbMap)
+	}
+	if telegram != nil {
+		go controller.TelegramHandler(application.DbMap)
+	}
+	if telegram != nil || chatNotifier != nil {
+		go controller.OperatorAlertHandler(application.DbMap)
+	}
+	if cfg.ConsistencyCheck > 0 {
+		go controller.ConsistencyCheckHandler(application.DbMap,
+			cfg.ConsistencyCheck)
+	}
 
-	if err = server.Serve(listener); err != nil {
+	if err = <-serveErr; err != nil {
 		log.Errorf("Serve error: %s", err.Error())
 		return 6
 	}
@@ -264,6 +539,51 @@ func runMain() int {
 	return 0
 }
 
+// verifyColdWalletExtPub checks that every stakepoold uses the same
+// coldwalletextpub as the frontend, the way backends added at runtime are
+// checked.
+func verifyColdWalletExtPub(controller *controllers.MainController,
+	grpcConnections []*grpc.ClientConn) error {
+	for i := range grpcConnections {
+		addr, err := controller.VerifyColdWalletExtPub(grpcConnections[i])
+		if err != nil {
+			return fmt.Errorf("stakepoold %d: %v", i, err)
+		}
+		log.Infof("stakepoold %d uses the same coldwalletextpub, first fee "+
+			"address %s", i, addr)
+	}
+	return nil
+}
+
+// stakepooldSync pushes the current user voting preferences and low fee
+// tickets to all stakepoold servers and reports their ticket totals.
+func stakepooldSync(controller *controllers.MainController, dbMap *gorp.DbMap,
+	grpcConnections []*grpc.ClientConn) error {
+	err := controller.StakepooldUpdateAll(stdcontext.Background(), dbMap,
+		controllers.StakepooldUpdateKindAll)
+	if err != nil {
+		return fmt.Errorf("TriggerStakepooldUpdates failed: %v", err)
+	}
+	for i := range grpcConnections {
+		addedLowFeeTickets, err := stakepooldclient.StakepooldGetAddedLowFeeTickets(grpcConnections[i])
+		if err != nil {
+			return fmt.Errorf("GetAddedLowFeeTickets failed on host %d: %v", i, err)
+		}
+		ignoredLowFeeTickets, err := stakepooldclient.StakepooldGetIgnoredLowFeeTickets(grpcConnections[i])
+		if err != nil {
+			return fmt.Errorf("GetIgnoredLowFeeTickets failed on host %d: %v", i, err)
+		}
+		liveTickets, err := stakepooldclient.StakepooldGetLiveTickets(grpcConnections[i])
+		if err != nil {
+			return fmt.Errorf("GetLiveTickets failed on host %d: %v", i, err)
+		}
+		log.Infof("stakepoold %d reports ticket totals of AddedLowFee %v "+
+			"IgnoredLowFee %v Live %v", i, len(addedLowFeeTickets),
+			len(ignoredLowFeeTickets), len(liveTickets))
+	}
+	return nil
+}
+
 func main() {
 	os.Exit(runMain())
 }
@@ -2,19 +2,68 @@ package stakepooldclient
 
 import (
 	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
 
+	"github.com/btcsuite/go-socks/socks"
 	"github.com/coolsnady/hcd/chaincfg/chainhash"
 	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
 	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/tracing"
+	"github.com/coolsnady/hcutil"
 	"golang.org/x/net/context"
 )
 
 var requiredStakepooldAPI = semver{major: 4, minor: 0, patch: 0}
 
-func ConnectStakepooldGRPC(stakepooldHosts []string, stakepooldCerts []string, serverID int) (*grpc.ClientConn, error) {
+// tokenCredentials authenticates every call to stakepoold with a token.
+type tokenCredentials string
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context,
+	uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials.
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// compressedMethods are the stakepoold methods with large responses.  When
+// their calls are compressed, stakepoold compresses the responses too.
+var compressedMethods = map[string]bool{
+	"/stakepoolrpc.StakepooldService/GetAddedLowFeeTickets":     true,
+	"/stakepoolrpc.StakepooldService/GetIgnoredLowFeeTickets":   true,
+	"/stakepoolrpc.StakepooldService/GetLiveTickets":            true,
+	"/stakepoolrpc.StakepooldService/GetStakeDifficultyHistory": true,
+	"/stakepoolrpc.StakepooldService/GetVoteHistory":            true,
+}
+
+// compressLargeResponses compresses the calls of the methods with large
+// responses with gzip and traces every call.
+func compressLargeResponses(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if compressedMethods[method] {
+		opts = append(opts, grpc.UseCompressor(gzip.Name))
+	}
+	return tracing.UnaryClientInterceptor(ctx, method, req, reply, cc,
+		invoker, opts...)
+}
+
+// ConnectStakepooldGRPC connects to the stakepoold gRPC server at serverID and
+// checks that it advertises a compatible API version.  When token is set, it
+// is sent with every call for stakepoold to authorize.  When compress is set,
+// the calls with large responses are compressed with gzip so stakepoold
+// compresses their responses too.  When proxyAddr is set, the connection is
+// made through that SOCKS5 proxy.
+func ConnectStakepooldGRPC(stakepooldHosts []string, stakepooldCerts []string, serverID int, token string, compress bool, proxyAddr, proxyUser, proxyPass string) (*grpc.ClientConn, error) {
 	log.Infof("Attempting to connect to stakepoold gRPC %s using "+
 		"certificate located in %s", stakepooldHosts[serverID],
 		stakepooldCerts[serverID])
@@ -22,7 +71,32 @@ func ConnectStakepooldGRPC(stakepooldHosts []string, stakepooldCerts []string, s
 	if err != nil {
 		return nil, err
 	}
-	conn, err := grpc.Dial(stakepooldHosts[serverID], grpc.WithTransportCredentials(creds))
+	var interceptor grpc.UnaryClientInterceptor = tracing.UnaryClientInterceptor
+	if compress {
+		interceptor = compressLargeResponses
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithUnaryInterceptor(interceptor),
+	}
+	if token != "" {
+		dialOpts = append(dialOpts,
+			grpc.WithPerRPCCredentials(tokenCredentials(token)))
+	}
+	if proxyAddr != "" {
+		// Hand the unresolved host to the proxy so that no DNS lookups
+		// leak outside of it when connecting over Tor.
+		proxy := &socks.Proxy{
+			Addr:     proxyAddr,
+			Username: proxyUser,
+			Password: proxyPass,
+		}
+		dialOpts = append(dialOpts, grpc.WithDialer(
+			func(addr string, timeout time.Duration) (net.Conn, error) {
+				return proxy.DialTimeout("tcp", addr, timeout)
+			}))
+	}
+	conn, err := grpc.Dial(stakepooldHosts[serverID], dialOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -31,6 +105,7 @@ func ConnectStakepooldGRPC(stakepooldHosts []string, stakepooldCerts []string, s
 	versionRequest := &pb.VersionRequest{}
 	versionResponse, err := c.Version(context.Background(), versionRequest)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 
@@ -41,84 +116,749 @@ func ConnectStakepooldGRPC(stakepooldHosts []string, stakepooldCerts []string, s
 	}
 
 	if !semverCompatible(requiredStakepooldAPI, semverResponse) {
+		conn.Close()
 		return nil, fmt.Errorf("Stakepoold gRPC server does not have "+
 			"a compatible API version. Advertises %v but require %v",
 			versionResponse, requiredStakepooldAPI)
 	}
 
-	log.Infof("Established connection to gRPC server %s",
-		stakepooldHosts[serverID])
+	log.Infof("Established connection to gRPC server %s (stakepoold %s, "+
+		"capabilities %v)", stakepooldHosts[serverID],
+		versionResponse.AppVersion, versionCapabilities(versionResponse))
 
 	return conn, nil
 }
 
+// ticketPageSize is the number of tickets requested from stakepoold per call
+// when fetching a whole ticket list.
+const ticketPageSize = 10000
+
+// ticketListCall fetches the page of a ticket list selected by options.
+type ticketListCall func(options *pb.TicketListOptions) ([]*pb.TicketEntry, []byte, error)
+
+// getTickets fetches every ticket of a list page by page.  stakepoold versions
+// without paging return the whole list on the first call.
+func getTickets(list ticketListCall) (map[chainhash.Hash]string, error) {
+	tickets := make(map[chainhash.Hash]string)
+	options := &pb.TicketListOptions{
+		Limit:  ticketPageSize,
+		Fields: []string{"TicketHash", "TicketAddress"},
+	}
+	for {
+		entries, nextCursor, err := list(options)
+		if err != nil {
+			return tickets, err
+		}
+
+		for _, ticketData := range entries {
+			hash, err := chainhash.NewHash(ticketData.TicketHash)
+			if err != nil {
+				log.Warnf("NewHash failed for %v: %v", ticketData.TicketHash, err)
+				continue
+			}
+			tickets[*hash] = ticketData.TicketAddress
+		}
+
+		if len(nextCursor) == 0 {
+			return tickets, nil
+		}
+		options.Cursor = nextCursor
+	}
+}
+
 func StakepooldGetAddedLowFeeTickets(conn *grpc.ClientConn) (map[chainhash.Hash]string, error) {
-	addedLowFeeTickets := make(map[chainhash.Hash]string)
+	client := pb.NewStakepooldServiceClient(conn)
+	return getTickets(func(options *pb.TicketListOptions) ([]*pb.TicketEntry, []byte, error) {
+		resp, err := client.GetAddedLowFeeTickets(context.Background(),
+			&pb.GetAddedLowFeeTicketsRequest{Options: options})
+		if err != nil {
+			return nil, nil, err
+		}
+		return resp.Tickets, resp.NextCursor, nil
+	})
+}
+
+func StakepooldGetIgnoredLowFeeTickets(conn *grpc.ClientConn) (map[chainhash.Hash]string, error) {
+	client := pb.NewStakepooldServiceClient(conn)
+	return getTickets(func(options *pb.TicketListOptions) ([]*pb.TicketEntry, []byte, error) {
+		resp, err := client.GetIgnoredLowFeeTickets(context.Background(),
+			&pb.GetIgnoredLowFeeTicketsRequest{Options: options})
+		if err != nil {
+			return nil, nil, err
+		}
+		return resp.Tickets, resp.NextCursor, nil
+	})
+}
 
+func StakepooldGetLiveTickets(conn *grpc.ClientConn) (map[chainhash.Hash]string, error) {
 	client := pb.NewStakepooldServiceClient(conn)
-	resp, err := client.GetAddedLowFeeTickets(context.Background(), &pb.GetAddedLowFeeTicketsRequest{})
-	// return early if the list is empty
-	if resp == nil || err != nil {
-		return addedLowFeeTickets, err
+	return getTickets(func(options *pb.TicketListOptions) ([]*pb.TicketEntry, []byte, error) {
+		resp, err := client.GetLiveTickets(context.Background(),
+			&pb.GetLiveTicketsRequest{Options: options})
+		if err != nil {
+			return nil, nil, err
+		}
+		return resp.Tickets, resp.NextCursor, nil
+	})
+}
+
+// TicketFeeStatus selects tickets by whether they paid the pool fee.
+type TicketFeeStatus int32
+
+const (
+	TicketFeeAny   = TicketFeeStatus(pb.TicketFeeStatus_ANY_FEE)
+	TicketFeeValid = TicketFeeStatus(pb.TicketFeeStatus_VALID_FEE)
+	TicketFeeLow   = TicketFeeStatus(pb.TicketFeeStatus_LOW_FEE)
+)
+
+// TicketFilter selects live tickets.  The zero value selects all of them.
+// Non-zero purchase heights leave out the tickets whose purchase height
+// stakepoold doesn't know.  OmitMultiSigAddress and OmitPurchaseHeight leave
+// those fields of the tickets unset to save stakepoold from sending them.
+type TicketFilter struct {
+	MultiSigAddress     string
+	FeeStatus           TicketFeeStatus
+	MinPurchaseHeight   int64
+	MaxPurchaseHeight   int64
+	OmitMultiSigAddress bool
+	OmitPurchaseHeight  bool
+}
+
+// fields returns the ticket entry fields selected by the filter.
+func (filter *TicketFilter) fields() []string {
+	if !filter.OmitMultiSigAddress && !filter.OmitPurchaseHeight {
+		return nil
+	}
+	fields := []string{"TicketHash"}
+	if !filter.OmitMultiSigAddress {
+		fields = append(fields, "TicketAddress")
+	}
+	if !filter.OmitPurchaseHeight {
+		fields = append(fields, "purchase_height")
 	}
+	return fields
+}
+
+// LiveTicket is a live ticket of the pool.  PurchaseHeight is 0 when
+// stakepoold doesn't know it.
+type LiveTicket struct {
+	Hash            chainhash.Hash
+	MultiSigAddress string
+	PurchaseHeight  int64
+}
 
-	for _, ticketData := range resp.Tickets {
-		hash, err := chainhash.NewHash(ticketData.TicketHash)
+// StakepooldListLiveTickets returns the live tickets selected by filter,
+// ordered by hash.  stakepoold versions before 4.13.0 ignore the fee status
+// and purchase heights of filter, and versions before 4.17.0 send every
+// field.
+func StakepooldListLiveTickets(conn *grpc.ClientConn, filter *TicketFilter) ([]LiveTicket, error) {
+	client := pb.NewStakepooldServiceClient(conn)
+	options := &pb.TicketListOptions{
+		Limit:             ticketPageSize,
+		MultisigAddress:   filter.MultiSigAddress,
+		FeeStatus:         pb.TicketFeeStatus(filter.FeeStatus),
+		MinPurchaseHeight: filter.MinPurchaseHeight,
+		MaxPurchaseHeight: filter.MaxPurchaseHeight,
+		Fields:            filter.fields(),
+	}
+	var tickets []LiveTicket
+	for {
+		resp, err := client.GetLiveTickets(context.Background(),
+			&pb.GetLiveTicketsRequest{Options: options})
 		if err != nil {
-			log.Warnf("NewHash failed for %v: %v", ticketData.TicketHash, err)
-			continue
+			return nil, err
 		}
-		addedLowFeeTickets[*hash] = ticketData.TicketAddress
+		for _, entry := range resp.Tickets {
+			hash, err := chainhash.NewHash(entry.TicketHash)
+			if err != nil {
+				return nil, err
+			}
+			tickets = append(tickets, LiveTicket{
+				Hash:            *hash,
+				MultiSigAddress: entry.TicketAddress,
+				PurchaseHeight:  entry.PurchaseHeight,
+			})
+		}
+		if len(resp.NextCursor) == 0 {
+			return tickets, nil
+		}
+		options.Cursor = resp.NextCursor
 	}
+}
 
-	return addedLowFeeTickets, err
+// PoolStats are the rolling statistics stakepoold keeps over the last
+// WindowSize blocks.  TotalMisses counts the missed pool tickets since
+// stakepoold started.
+type PoolStats struct {
+	BlockHash       string
+	BlockHeight     int64
+	PoolSize        uint32
+	StakeDifficulty hcutil.Amount
+	WindowSize      int64
+	WindowBlocks    int64
+	NetworkVotes    int64
+	PoolVotes       int64
+	PoolMisses      int64
+	TotalMisses     int64
+	LiveTickets     int64
 }
 
-func StakepooldGetIgnoredLowFeeTickets(conn *grpc.ClientConn) (map[chainhash.Hash]string, error) {
-	ignoredLowFeeTickets := make(map[chainhash.Hash]string)
+// StakepooldGetPoolStats returns the rolling pool statistics.  stakepoold
+// versions before 4.3.0 don't implement this call.
+func StakepooldGetPoolStats(conn *grpc.ClientConn) (*PoolStats, error) {
+	client := pb.NewStakepooldServiceClient(conn)
+	resp, err := client.GetPoolStats(context.Background(),
+		&pb.GetPoolStatsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	hash, err := chainhash.NewHash(resp.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	return &PoolStats{
+		BlockHash:       hash.String(),
+		BlockHeight:     resp.BlockHeight,
+		PoolSize:        resp.PoolSize,
+		StakeDifficulty: hcutil.Amount(resp.StakeDifficulty),
+		WindowSize:      resp.WindowSize,
+		WindowBlocks:    resp.WindowBlocks,
+		NetworkVotes:    resp.NetworkVotes,
+		PoolVotes:       resp.PoolVotes,
+		PoolMisses:      resp.PoolMisses,
+		TotalMisses:     resp.TotalMisses,
+		LiveTickets:     resp.LiveTickets,
+	}, nil
+}
+
+// Status is the state of a stakepoold.  BlockHeight is the last block it
+// processed and WalletHeight the best block of its hcwallet, 0 when unknown.
+// PendingCommands are the calls waiting for or being processed by it and
+// PendingNotifications the hcd notifications waiting to be processed.
+// VotingPaused is set while it pauses voting on a suspected chain fork, which
+// Fork describes, and never by stakepoold versions before 4.21.0.
+type Status struct {
+	NodeConnected        bool
+	WalletConnected      bool
+	BlockHeight          int64
+	WalletHeight         int64
+	Users                uint32
+	LiveTickets          uint32
+	AddedLowFeeTickets   uint32
+	IgnoredLowFeeTickets uint32
+	PendingCommands      uint32
+	PendingNotifications uint32
+	VotingPaused         bool
+	Fork                 string
+}
 
+// StakepooldGetStatus returns the state of stakepoold.  The call, and the
+// hcwallet call stakepoold makes for it, are abandoned once ctx is done.
+// stakepoold versions before 4.9.0 don't implement this call.
+func StakepooldGetStatus(ctx context.Context, conn *grpc.ClientConn) (*Status, error) {
 	client := pb.NewStakepooldServiceClient(conn)
-	resp, err := client.GetIgnoredLowFeeTickets(context.Background(), &pb.GetIgnoredLowFeeTicketsRequest{})
-	// return early if the list is empty
-	if resp == nil || err != nil {
-		return ignoredLowFeeTickets, err
+	resp, err := client.GetStatus(ctx, &pb.GetStatusRequest{})
+	if err != nil {
+		return nil, err
 	}
+	return &Status{
+		NodeConnected:        resp.NodeConnected,
+		WalletConnected:      resp.WalletConnected,
+		BlockHeight:          resp.BlockHeight,
+		WalletHeight:         resp.WalletHeight,
+		Users:                resp.Users,
+		LiveTickets:          resp.LiveTickets,
+		AddedLowFeeTickets:   resp.AddedLowFeeTickets,
+		IgnoredLowFeeTickets: resp.IgnoredLowFeeTickets,
+		PendingCommands:      resp.PendingCommands,
+		PendingNotifications: resp.PendingNotifications,
+		VotingPaused:         resp.VotingPaused,
+		Fork:                 resp.Fork,
+	}, nil
+}
 
-	for _, ticketData := range resp.Tickets {
-		hash, err := chainhash.NewHash(ticketData.TicketHash)
-		if err != nil {
-			log.Warnf("NewHash failed for %v: %v", ticketData.TicketHash, err)
+// WalletInfo is the state of the hcwallet of a stakepoold.  Version is its
+// JSON-RPC API version and Height the block it is synced to.  AutoUnlock is
+// set when stakepoold unlocks the wallet only to vote, which stakepoold
+// versions before 4.19.0 don't do.  The other fields are only set when
+// Connected is.
+type WalletInfo struct {
+	Connected       bool
+	Version         string
+	DaemonConnected bool
+	Unlocked        bool
+	AutoUnlock      bool
+	Voting          bool
+	Height          int64
+	VoteVersion     uint32
+}
+
+// CanVote returns whether the wallet is able to vote: it must be connected to
+// stakepoold and hcd, unlocked or unlocked by stakepoold to vote, and have
+// voting enabled.
+func (w *WalletInfo) CanVote() bool {
+	return w.Connected && w.DaemonConnected && (w.Unlocked || w.AutoUnlock) &&
+		w.Voting
+}
+
+// StakepooldGetWalletInfo returns the state of the hcwallet of stakepoold.
+// The call, and the hcwallet calls stakepoold makes for it, are abandoned once
+// ctx is done.  stakepoold versions before 4.15.0 don't implement this call.
+func StakepooldGetWalletInfo(ctx context.Context, conn *grpc.ClientConn) (*WalletInfo, error) {
+	client := pb.NewStakepooldServiceClient(conn)
+	resp, err := client.GetWalletInfo(ctx, &pb.GetWalletInfoRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return &WalletInfo{
+		Connected:       resp.WalletConnected,
+		Version:         resp.Version,
+		DaemonConnected: resp.DaemonConnected,
+		Unlocked:        resp.Unlocked,
+		AutoUnlock:      resp.AutoUnlock,
+		Voting:          resp.Voting,
+		Height:          resp.BestBlockHeight,
+		VoteVersion:     resp.VoteVersion,
+	}, nil
+}
+
+// Capability is an optional feature of stakepoold.
+type Capability int32
+
+// Capabilities of stakepoold.
+const (
+	CapabilitySpentMissedStream    = Capability(pb.Capability_CAPABILITY_SPENT_MISSED_STREAM)
+	CapabilityAgendaVoting         = Capability(pb.Capability_CAPABILITY_AGENDA_VOTING)
+	CapabilityBatchUserVotingPrefs = Capability(pb.Capability_CAPABILITY_BATCH_USER_VOTING_PREFS)
+	CapabilityTicketFilters        = Capability(pb.Capability_CAPABILITY_TICKET_FILTERS)
+	CapabilityMissingTickets       = Capability(pb.Capability_CAPABILITY_MISSING_TICKETS)
+	CapabilityWalletInfo           = Capability(pb.Capability_CAPABILITY_WALLET_INFO)
+	CapabilityWalletRescan         = Capability(pb.Capability_CAPABILITY_WALLET_RESCAN)
+	CapabilityDoubleVotes          = Capability(pb.Capability_CAPABILITY_DOUBLE_VOTES)
+	CapabilityStakeDiffHistory     = Capability(pb.Capability_CAPABILITY_STAKE_DIFFICULTY_HISTORY)
+	CapabilityVoteHistoryStream    = Capability(pb.Capability_CAPABILITY_VOTE_HISTORY_STREAM)
+)
+
+// capabilityVersions are the API versions that introduced the capabilities,
+// which stakepoold versions before 4.16.0 support without advertising them.
+var capabilityVersions = []struct {
+	capability Capability
+	version    semver
+}{
+	{CapabilityAgendaVoting, semver{major: 4, minor: 0, patch: 0}},
+	{CapabilitySpentMissedStream, semver{major: 4, minor: 11, patch: 0}},
+	{CapabilityBatchUserVotingPrefs, semver{major: 4, minor: 12, patch: 0}},
+	{CapabilityTicketFilters, semver{major: 4, minor: 13, patch: 0}},
+	{CapabilityMissingTickets, semver{major: 4, minor: 14, patch: 0}},
+	{CapabilityWalletInfo, semver{major: 4, minor: 15, patch: 0}},
+}
+
+// String returns the name of the capability, e.g. spent_missed_stream.
+func (c Capability) String() string {
+	name := strings.TrimPrefix(pb.Capability(c).String(), "CAPABILITY_")
+	return strings.ToLower(name)
+}
+
+// versionCapabilities returns the capabilities of a stakepoold from its
+// version response.
+func versionCapabilities(resp *pb.VersionResponse) []Capability {
+	if len(resp.Capabilities) == 0 {
+		api := semver{
+			major: resp.Major,
+			minor: resp.Minor,
+			patch: resp.Patch,
+		}
+		var caps []Capability
+		for _, c := range capabilityVersions {
+			if semverCompatible(c.version, api) {
+				caps = append(caps, c.capability)
+			}
+		}
+		return caps
+	}
+	caps := make([]Capability, 0, len(resp.Capabilities))
+	for _, c := range resp.Capabilities {
+		if c != pb.Capability_CAPABILITY_UNKNOWN {
+			caps = append(caps, Capability(c))
+		}
+	}
+	return caps
+}
+
+// Version is the RPC API version of stakepoold, the version of the
+// application serving it, and the optional features it supports.
+type Version struct {
+	API          string
+	App          string
+	Commit       string
+	Capabilities []Capability
+}
+
+// Supports returns whether the stakepoold supports the capability.
+func (v *Version) Supports(capability Capability) bool {
+	for _, c := range v.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// StakepooldVersion returns the versions and capabilities stakepoold
+// advertises.
+func StakepooldVersion(conn *grpc.ClientConn) (*Version, error) {
+	client := pb.NewVersionServiceClient(conn)
+	resp, err := client.Version(context.Background(), &pb.VersionRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return &Version{
+		API:          resp.VersionString,
+		App:          resp.AppVersion,
+		Commit:       resp.AppCommit,
+		Capabilities: versionCapabilities(resp),
+	}, nil
+}
+
+// StakepooldVerifyColdWalletExtPub asks stakepoold to check that xpub is its
+// coldwalletextpub and returns the first fee address it derives from it.  The
+// call fails with FailedPrecondition when the keys differ.  stakepoold versions
+// before 4.10.0 don't implement this call.
+func StakepooldVerifyColdWalletExtPub(conn *grpc.ClientConn, xpub string) (string, error) {
+	client := pb.NewStakepooldServiceClient(conn)
+	resp, err := client.VerifyColdWalletExtPub(context.Background(),
+		&pb.VerifyColdWalletExtPubRequest{ColdWalletExtPub: xpub})
+	if err != nil {
+		return "", err
+	}
+	return resp.TestAddress, nil
+}
+
+// VoteLatency are percentiles of the time stakepoold took from the winning
+// tickets notification to sending a vote, over its last WindowVotes votes.
+type VoteLatency struct {
+	Votes       int64
+	WindowVotes int64
+	P50         time.Duration
+	P90         time.Duration
+	P99         time.Duration
+	Max         time.Duration
+}
+
+// StakepooldGetVoteLatency returns the vote latency percentiles.  stakepoold
+// versions before 4.4.0 don't implement this call.
+func StakepooldGetVoteLatency(conn *grpc.ClientConn) (*VoteLatency, error) {
+	client := pb.NewStakepooldServiceClient(conn)
+	resp, err := client.GetVoteLatency(context.Background(),
+		&pb.GetVoteLatencyRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return &VoteLatency{
+		Votes:       resp.Votes,
+		WindowVotes: resp.WindowVotes,
+		P50:         time.Duration(resp.P50Ns),
+		P90:         time.Duration(resp.P90Ns),
+		P99:         time.Duration(resp.P99Ns),
+		Max:         time.Duration(resp.MaxNs),
+	}, nil
+}
+
+// UserVotingStats are the votes and misses of the tickets of a pool user and
+// the rewards of the votes, as seen by stakepoold.  Fallbacks are the tickets
+// voted with the default vote bits of stakepoold for lack of valid voting
+// preferences, which stakepoold versions before 4.22.0 don't count.
+type UserVotingStats struct {
+	Votes         int64
+	Misses        int64
+	Fallbacks     int64
+	TotalReward   hcutil.Amount
+	AverageReward hcutil.Amount
+}
+
+// StakepooldGetUserVotingStats returns the voting statistics of the user with
+// the multisig address msa.  stakepoold versions before 4.6.0 don't implement
+// this call.
+func StakepooldGetUserVotingStats(conn *grpc.ClientConn, msa string) (*UserVotingStats, error) {
+	client := pb.NewStakepooldServiceClient(conn)
+	resp, err := client.GetUserVotingStats(context.Background(),
+		&pb.GetUserVotingStatsRequest{MultisigAddresses: []string{msa}})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &UserVotingStats{}
+	for _, u := range resp.Users {
+		if u.MultisigAddress != msa {
 			continue
 		}
-		ignoredLowFeeTickets[*hash] = ticketData.TicketAddress
+		stats.Votes = u.Votes
+		stats.Misses = u.Misses
+		stats.Fallbacks = u.Fallbacks
+		stats.TotalReward = hcutil.Amount(u.Reward)
+		if u.Votes > 0 {
+			stats.AverageReward = hcutil.Amount(u.Reward / u.Votes)
+		}
 	}
+	return stats, nil
+}
 
-	return ignoredLowFeeTickets, err
+// VoteHistoryEvent is an event of a pool ticket reported by stakepoold.  Event
+// is one of the models.VoteHistory events.  VoteHash and Reward are only set
+// for voted events.
+type VoteHistoryEvent struct {
+	Ticket          string
+	MultiSigAddress string
+	Event           string
+	BlockHash       string
+	BlockHeight     int64
+	VoteHash        string
+	VoteBits        uint16
+	Reward          hcutil.Amount
+	Time            int64
 }
 
-func StakepooldGetLiveTickets(conn *grpc.ClientConn) (map[chainhash.Hash]string, error) {
-	liveTickets := make(map[chainhash.Hash]string)
+// voteHistoryEvents are the names of the vote events.
+var voteHistoryEvents = map[pb.VoteEvent]string{
+	pb.VoteEvent_SELECTED: models.VoteHistorySelected,
+	pb.VoteEvent_VOTED:    models.VoteHistoryVoted,
+	pb.VoteEvent_MISSED:   models.VoteHistoryMissed,
+}
 
+// StakepooldGetVoteHistory returns the events of the pool tickets at or above
+// sinceHeight that stakepoold still remembers, oldest first.  stakepoold
+// versions before 4.8.0 don't implement this call.
+func StakepooldGetVoteHistory(conn *grpc.ClientConn, sinceHeight int64) ([]VoteHistoryEvent, error) {
 	client := pb.NewStakepooldServiceClient(conn)
-	resp, err := client.GetLiveTickets(context.Background(), &pb.GetLiveTicketsRequest{})
-	// return early if the list is empty
-	if resp == nil || err != nil {
-		return liveTickets, err
+	resp, err := client.GetVoteHistory(context.Background(),
+		&pb.GetVoteHistoryRequest{SinceHeight: sinceHeight})
+	if err != nil {
+		return nil, err
 	}
 
-	for _, ticketData := range resp.Tickets {
-		hash, err := chainhash.NewHash(ticketData.TicketHash)
+	events := make([]VoteHistoryEvent, 0, len(resp.Events))
+	for _, e := range resp.Events {
+		event, err := voteHistoryEvent(e)
 		if err != nil {
-			log.Warnf("NewHash failed for %v: %v", ticketData.TicketHash, err)
-			continue
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+	return events, nil
+}
+
+// StakepooldSubscribeVoteHistory calls fn with the events of the pool tickets
+// at or above sinceHeight that stakepoold still remembers and then with every
+// new event as stakepoold sees it, until ctx is done or the stream fails.
+// Events may be passed more than once.  stakepoold versions before 4.26.0
+// don't implement this call.
+func StakepooldSubscribeVoteHistory(ctx context.Context, conn *grpc.ClientConn, sinceHeight int64, fn func(*VoteHistoryEvent) error) error {
+	client := pb.NewStakepooldServiceClient(conn)
+	stream, err := client.SubscribeVoteHistory(ctx,
+		&pb.SubscribeVoteHistoryRequest{SinceHeight: sinceHeight})
+	if err != nil {
+		return err
+	}
+	for {
+		e, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		event, err := voteHistoryEvent(e)
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
+
+// voteHistoryEvent returns the event of a vote history entry.
+func voteHistoryEvent(e *pb.VoteHistoryEntry) (*VoteHistoryEvent, error) {
+	ticket, err := chainhash.NewHash(e.TicketHash)
+	if err != nil {
+		return nil, err
+	}
+	blockHash, err := chainhash.NewHash(e.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	event := &VoteHistoryEvent{
+		Ticket:          ticket.String(),
+		MultiSigAddress: e.MultisigAddress,
+		Event:           voteHistoryEvents[e.Event],
+		BlockHash:       blockHash.String(),
+		BlockHeight:     e.BlockHeight,
+		VoteBits:        uint16(e.VoteBits),
+		Reward:          hcutil.Amount(e.Reward),
+		Time:            e.Time,
+	}
+	if len(e.VoteHash) != 0 {
+		voteHash, err := chainhash.NewHash(e.VoteHash)
+		if err != nil {
+			return nil, err
+		}
+		event.VoteHash = voteHash.String()
+	}
+	return event, nil
+}
+
+// Sources of the conflicting vote of a double vote.
+const (
+	DoubleVoteSourceUnknown  = "unknown"
+	DoubleVoteSourcePool     = "pool"
+	DoubleVoteSourceExternal = "external"
+)
+
+// DoubleVote is a vote of stakepoold that hcd rejected because another vote of
+// the ticket, ConflictingVoteHash, was seen first.  Source tells who signed
+// the conflicting vote: another voting wallet of the pool with other vote
+// bits, someone outside of the pool, or unknown when stakepoold couldn't look
+// it up.  ConflictingVoteBits is only set when it could.
+type DoubleVote struct {
+	Ticket              string
+	MultiSigAddress     string
+	BlockHash           string
+	BlockHeight         int64
+	VoteHash            string
+	VoteBits            uint16
+	ConflictingVoteHash string
+	ConflictingVoteBits uint16
+	Source              string
+	Time                int64
+}
+
+// doubleVoteSources are the names of the double vote sources.
+var doubleVoteSources = map[pb.DoubleVoteSource]string{
+	pb.DoubleVoteSource_DOUBLE_VOTE_SOURCE_UNKNOWN:  DoubleVoteSourceUnknown,
+	pb.DoubleVoteSource_DOUBLE_VOTE_SOURCE_POOL:     DoubleVoteSourcePool,
+	pb.DoubleVoteSource_DOUBLE_VOTE_SOURCE_EXTERNAL: DoubleVoteSourceExternal,
+}
+
+// StakepooldGetDoubleVotes returns the double votes of the pool tickets at or
+// above sinceHeight that stakepoold still remembers, oldest first.
+// stakepoold versions before 4.20.0 don't implement this call.
+func StakepooldGetDoubleVotes(ctx context.Context, conn *grpc.ClientConn, sinceHeight int64) ([]DoubleVote, error) {
+	client := pb.NewStakepooldServiceClient(conn)
+	resp, err := client.GetDoubleVotes(ctx,
+		&pb.GetDoubleVotesRequest{SinceHeight: sinceHeight})
+	if err != nil {
+		return nil, err
+	}
+
+	votes := make([]DoubleVote, 0, len(resp.DoubleVotes))
+	for _, v := range resp.DoubleVotes {
+		var hashes [4]*chainhash.Hash
+		for i, b := range [][]byte{v.TicketHash, v.BlockHash, v.VoteHash,
+			v.ConflictingVoteHash} {
+			hashes[i], err = chainhash.NewHash(b)
+			if err != nil {
+				return nil, err
+			}
 		}
-		liveTickets[*hash] = ticketData.TicketAddress
+		votes = append(votes, DoubleVote{
+			Ticket:              hashes[0].String(),
+			MultiSigAddress:     v.MultisigAddress,
+			BlockHash:           hashes[1].String(),
+			BlockHeight:         v.BlockHeight,
+			VoteHash:            hashes[2].String(),
+			VoteBits:            uint16(v.VoteBits),
+			ConflictingVoteHash: hashes[3].String(),
+			ConflictingVoteBits: uint16(v.ConflictingVoteBits),
+			Source:              doubleVoteSources[v.Source],
+			Time:                v.Time,
+		})
+	}
+	return votes, nil
+}
+
+// StakeDifficultyInterval is the stake difficulty of the interval of blocks
+// starting at StartHeight.  PoolSize is the ticket pool size at the last block
+// of the interval stakepoold saw and Time the timestamp of the first.
+type StakeDifficultyInterval struct {
+	StartHeight     int64
+	StakeDifficulty hcutil.Amount
+	PoolSize        uint32
+	Time            int64
+}
+
+// StakepooldGetStakeDifficultyHistory returns the stake difficulty of the
+// intervals starting at or above sinceHeight that stakepoold keeps, oldest
+// first.  stakepoold versions before 4.23.0 don't implement this call.
+func StakepooldGetStakeDifficultyHistory(ctx context.Context, conn *grpc.ClientConn, sinceHeight int64) ([]StakeDifficultyInterval, error) {
+	client := pb.NewStakepooldServiceClient(conn)
+	resp, err := client.GetStakeDifficultyHistory(ctx,
+		&pb.GetStakeDifficultyHistoryRequest{SinceHeight: sinceHeight})
+	if err != nil {
+		return nil, err
 	}
 
-	return liveTickets, err
+	intervals := make([]StakeDifficultyInterval, 0, len(resp.Intervals))
+	for _, in := range resp.Intervals {
+		intervals = append(intervals, StakeDifficultyInterval{
+			StartHeight:     in.StartHeight,
+			StakeDifficulty: hcutil.Amount(in.StakeDifficulty),
+			PoolSize:        in.PoolSize,
+			Time:            in.Time,
+		})
+	}
+	return intervals, nil
+}
+
+// RescanProgress is the progress of a wallet rescan.  hcwallet doesn't tell
+// how far it got, so Elapsed is the time the rescan has taken so far.  Once
+// Done, LiveTickets is the number of live tickets stakepoold tracks and
+// FlaggedTickets are the live tickets of the users the wallet still doesn't
+// know.
+type RescanProgress struct {
+	BeginHeight    int64
+	BestHeight     int64
+	Elapsed        time.Duration
+	Done           bool
+	LiveTickets    uint32
+	FlaggedTickets []chainhash.Hash
 }
 
-func StakepooldSetAddedLowFeeTickets(conn *grpc.ClientConn, dbTickets []models.LowFeeTicket) (processed bool, err error) {
+// StakepooldRescanWallet makes the wallet of stakepoold rescan the chain from
+// beginHeight, e.g. after it was restored from seed, and calls progress with
+// the progress stakepoold reports until the rescan and the reconciliation of
+// the tickets that follows it are done.  The rescan continues when ctx is
+// done.  stakepoold versions before 4.18.0 don't implement this call.
+func StakepooldRescanWallet(ctx context.Context, conn *grpc.ClientConn, beginHeight int64, progress func(*RescanProgress)) error {
+	client := pb.NewStakepooldServiceClient(conn)
+	stream, err := client.RescanWallet(ctx,
+		&pb.RescanWalletRequest{BeginHeight: beginHeight})
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		p := &RescanProgress{
+			BeginHeight: resp.BeginHeight,
+			BestHeight:  resp.BestHeight,
+			Elapsed:     time.Duration(resp.ElapsedSeconds) * time.Second,
+			Done:        resp.Done,
+			LiveTickets: resp.LiveTickets,
+		}
+		for _, t := range resp.FlaggedTickets {
+			hash, err := chainhash.NewHash(t)
+			if err != nil {
+				return err
+			}
+			p.FlaggedTickets = append(p.FlaggedTickets, *hash)
+		}
+		progress(p)
+	}
+}
+
+func StakepooldSetAddedLowFeeTickets(ctx context.Context, conn *grpc.ClientConn, dbTickets []models.LowFeeTicket) (processed bool, err error) {
 	var tickets []*pb.TicketEntry
 	for _, ticket := range dbTickets {
 		hash, err := chainhash.NewHashFromStr(ticket.TicketHash)
@@ -136,7 +876,7 @@ func StakepooldSetAddedLowFeeTickets(conn *grpc.ClientConn, dbTickets []models.L
 	setAddedTicketsReq := &pb.SetAddedLowFeeTicketsRequest{
 		Tickets: tickets,
 	}
-	_, err = client.SetAddedLowFeeTickets(context.Background(),
+	_, err = client.SetAddedLowFeeTickets(ctx,
 		setAddedTicketsReq)
 	if err != nil {
 		return false, err
@@ -144,8 +884,48 @@ func StakepooldSetAddedLowFeeTickets(conn *grpc.ClientConn, dbTickets []models.L
 	return true, err
 }
 
-func StakepooldSetUserVotingPrefs(conn *grpc.ClientConn, dbUsers map[int64]*models.User) (processed bool, err error) {
-	var users []*pb.UserVotingConfigEntry
+// MissingTicketResult is the outcome of adding a missing ticket to
+// stakepoold.  Error is set when Status is MISSING_TICKET_FAILED.
+type MissingTicketResult struct {
+	Ticket chainhash.Hash
+	Status pb.MissingTicketStatus
+	Error  string
+}
+
+// StakepooldAddMissingTickets makes stakepoold validate tickets against the
+// chain and its wallet and track those of pool users it missed.  stakepoold
+// versions before 4.14.0 don't implement this call.
+func StakepooldAddMissingTickets(ctx context.Context, conn *grpc.ClientConn, tickets []chainhash.Hash) ([]MissingTicketResult, error) {
+	hashes := make([][]byte, 0, len(tickets))
+	for i := range tickets {
+		hashes = append(hashes, tickets[i].CloneBytes())
+	}
+
+	client := pb.NewStakepooldServiceClient(conn)
+	resp, err := client.AddMissingTickets(ctx,
+		&pb.AddMissingTicketsRequest{TicketHashes: hashes})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]MissingTicketResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		hash, err := chainhash.NewHash(r.TicketHash)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, MissingTicketResult{
+			Ticket: *hash,
+			Status: r.Status,
+			Error:  r.Error,
+		})
+	}
+	return results, nil
+}
+
+// userVotingConfigEntries returns the voting preferences of the voting
+// addresses of dbUsers, including their additional ones.
+func userVotingConfigEntries(dbUsers map[int64]*models.User) []*pb.UserVotingConfigEntry {
+	users := make([]*pb.UserVotingConfigEntry, 0, len(dbUsers))
 	for userid, data := range dbUsers {
 		users = append(users, &pb.UserVotingConfigEntry{
 			UserId:          userid,
@@ -153,13 +933,42 @@ func StakepooldSetUserVotingPrefs(conn *grpc.ClientConn, dbUsers map[int64]*mode
 			VoteBits:        data.VoteBits,
 			VoteBitsVersion: data.VoteBitsVersion,
 		})
+		for _, a := range data.Addresses {
+			users = append(users, &pb.UserVotingConfigEntry{
+				UserId:          userid,
+				MultiSigAddress: a.MultiSigAddress,
+				VoteBits:        a.VoteBits,
+				VoteBitsVersion: a.VoteBitsVersion,
+			})
+		}
 	}
+	return users
+}
+
+// StakepooldBatchSetUserVotingPrefs adds or replaces the voting preferences
+// of dbUsers without touching those of other users and returns the number of
+// users stakepoold votes for.  stakepoold versions before 4.12.0 don't
+// implement this call.
+func StakepooldBatchSetUserVotingPrefs(ctx context.Context, conn *grpc.ClientConn, dbUsers map[int64]*models.User) (uint32, error) {
+	users := userVotingConfigEntries(dbUsers)
+
+	client := pb.NewStakepooldServiceClient(conn)
+	resp, err := client.BatchSetUserVotingPrefs(ctx,
+		&pb.BatchSetUserVotingPrefsRequest{UserVotingConfig: users})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Users, nil
+}
+
+func StakepooldSetUserVotingPrefs(ctx context.Context, conn *grpc.ClientConn, dbUsers map[int64]*models.User) (processed bool, err error) {
+	users := userVotingConfigEntries(dbUsers)
 
 	client := pb.NewStakepooldServiceClient(conn)
 	setVotingConfigReq := &pb.SetUserVotingPrefsRequest{
 		UserVotingConfig: users,
 	}
-	_, err = client.SetUserVotingPrefs(context.Background(),
+	_, err = client.SetUserVotingPrefs(ctx,
 		setVotingConfigReq)
 	if err != nil {
 		return false, err
@@ -0,0 +1,35 @@
+package stakepooldclient
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	pb "github.com/coolsnady/hcstakepool/backend/stakepoold/rpc/stakepoolrpc"
+	"github.com/coolsnady/hcstakepool/models"
+)
+
+func TestUserVotingConfigEntries(t *testing.T) {
+	users := map[int64]*models.User{
+		1: {MultiSigAddress: "msa1", VoteBits: 1, VoteBitsVersion: 5},
+		2: {MultiSigAddress: "msa2", VoteBits: 5, VoteBitsVersion: 5,
+			Addresses: []models.UserAddress{
+				{MultiSigAddress: "msa3", VoteBits: 3, VoteBitsVersion: 5},
+			}},
+	}
+	entries := userVotingConfigEntries(users)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].MultiSigAddress < entries[j].MultiSigAddress
+	})
+
+	// The additional voting addresses are entries of their users with
+	// their own voting preferences.
+	want := []*pb.UserVotingConfigEntry{
+		{UserId: 1, MultiSigAddress: "msa1", VoteBits: 1, VoteBitsVersion: 5},
+		{UserId: 2, MultiSigAddress: "msa2", VoteBits: 5, VoteBitsVersion: 5},
+		{UserId: 2, MultiSigAddress: "msa3", VoteBits: 3, VoteBitsVersion: 5},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries %v, want %v", entries, want)
+	}
+}
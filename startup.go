@@ -0,0 +1,137 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coolsnady/hcstakepool/system"
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+// startupRetryMin is the delay before the first retry of an unavailable
+// dependency.  It doubles after each failed attempt up to cfg.StartupRetryMax.
+const startupRetryMin = time.Second
+
+// startupStatus records which dependency the frontend is waiting on while it
+// starts.  It is shown on the status page of the degraded mode.
+type startupStatus struct {
+	sync.RWMutex
+	started   time.Time
+	waitingOn string
+	attempts  int
+}
+
+func newStartupStatus() *startupStatus {
+	return &startupStatus{started: time.Now()}
+}
+
+func (s *startupStatus) set(waitingOn string, attempts int) {
+	s.Lock()
+	s.waitingOn = waitingOn
+	s.attempts = attempts
+	s.Unlock()
+}
+
+func (s *startupStatus) String() string {
+	s.RLock()
+	defer s.RUnlock()
+	elapsed := time.Since(s.started) / time.Second * time.Second
+	return fmt.Sprintf("starting for %v, waiting on %s (attempt %d)\n",
+		elapsed, s.waitingOn, s.attempts)
+}
+
+// retryStartup calls fn until it succeeds, waiting between attempts with an
+// exponential backoff.  An error is returned once cfg.StartupTimeout has
+// passed since startup without fn succeeding.
+func retryStartup(status *startupStatus, dependency string, fn func() error) error {
+	delay := startupRetryMin
+	for attempt := 1; ; attempt++ {
+		status.set(dependency, attempt)
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		if cfg.StartupTimeout != 0 &&
+			time.Since(status.started)+delay > cfg.StartupTimeout {
+			return fmt.Errorf("%s still unavailable after %d "+
+				"attempts: %v", dependency, attempt, err)
+		}
+		log.Warnf("%s unavailable, retrying in %v: %v", dependency,
+			delay, err)
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > cfg.StartupRetryMax {
+			delay = cfg.StartupRetryMax
+		}
+	}
+}
+
+// degradedHandler returns the handler used while the frontend waits for its
+// dependencies.  Static files and a plain text status page are served, all
+// other pages show the maintenance page and API calls fail as unavailable.
+func degradedHandler(application *system.Application, assetHandler http.Handler, status *startupStatus) http.Handler {
+	retryAfter := fmt.Sprint(int(cfg.StartupRetryMax.Seconds()))
+
+	app := web.New()
+	app.Use(application.ApplySecurityHeaders)
+	app.Handle("/assets/*", assetHandler)
+	app.Get("/robots.txt", http.FileServer(publicFS("")))
+	app.Get("/favicon.ico", http.FileServer(publicFS("images")))
+	app.Get("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Retry-After", retryAfter)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, status.String())
+	})
+	app.Handle("/api/*", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", retryAfter)
+		system.WriteAPIResponse(system.NewAPIResponse("error",
+			codes.Unavailable, "stake pool is starting", nil),
+			http.StatusServiceUnavailable, w)
+	})
+	app.Handle("/*", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Retry-After", retryAfter)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		err := application.Template.ExecuteTemplate(w,
+			"maintenance.html", nil)
+		if err != nil {
+			log.Errorf("Failed to render maintenance page: %v", err)
+		}
+	})
+	app.Compile()
+
+	return app
+}
+
+// switchHandler is an http.Handler which forwards requests to a handler that
+// can be replaced while serving.  It allows the degraded mode handler to be
+// swapped for the full application once startup completes.
+type switchHandler struct {
+	mtx     sync.RWMutex
+	handler http.Handler
+}
+
+func (s *switchHandler) set(handler http.Handler) {
+	s.mtx.Lock()
+	s.handler = handler
+	s.mtx.Unlock()
+}
+
+func (s *switchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.RLock()
+	handler := s.handler
+	s.mtx.RUnlock()
+	handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,66 @@
+package system
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zenazn/goji/web"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestAPIv2Handler(t *testing.T) {
+	app := &Application{}
+	handler := app.APIv2Handler(func(c web.C, r *http.Request) *APIResponse {
+		switch c.URLParams["command"] {
+		case "ok":
+			return NewAPIResponse("success", codes.OK, "ok", "data")
+		case "denied":
+			return NewAPIResponse("error", codes.PermissionDenied, "not an admin", nil)
+		}
+		return nil
+	})
+
+	tests := []struct {
+		command  string
+		env      map[interface{}]interface{}
+		status   int
+		errorObj *APIError
+	}{
+		{"ok", nil, http.StatusOK, nil},
+		{"denied", nil, http.StatusForbidden,
+			&APIError{Code: "PermissionDenied", Message: "not an admin"}},
+		{"missing", nil, http.StatusNotFound,
+			&APIError{Code: "NotFound", Message: "invalid API command or version"}},
+		{"ok", map[interface{}]interface{}{"APITokenError": "invalid api token"},
+			http.StatusUnauthorized,
+			&APIError{Code: "Unauthenticated", Message: "invalid api token"}},
+	}
+	for _, test := range tests {
+		c := web.C{
+			URLParams: map[string]string{"command": test.command},
+			Env:       test.env,
+		}
+		if c.Env == nil {
+			c.Env = make(map[interface{}]interface{})
+		}
+		w := httptest.NewRecorder()
+		handler(c, w, httptest.NewRequest("GET", "/api/v2/"+test.command, nil))
+
+		var resp APIResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", test.command, err)
+		}
+		if w.Code != test.status {
+			t.Errorf("%s: HTTP status %d, want %d", test.command, w.Code,
+				test.status)
+		}
+		if (resp.Error == nil) != (test.errorObj == nil) ||
+			resp.Error != nil && *resp.Error != *test.errorObj {
+			t.Errorf("%s: error %+v, want %+v", test.command, resp.Error,
+				test.errorObj)
+		}
+	}
+}
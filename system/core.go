@@ -3,7 +3,6 @@ package system
 import (
 	"crypto/sha256"
 	"encoding/json"
-	"errors"
 	"html/template"
 	"io"
 	"net/http"
@@ -11,6 +10,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	texttemplate "text/template"
 
 	"github.com/coolsnady/hcstakepool/models"
 	"github.com/go-gorp/gorp"
@@ -27,20 +27,27 @@ const (
 	CSRFKey    = "csrf_token"
 )
 
+// RememberMeCookie is the name of the cookie holding the remember me token
+// that signs a browser back in once its session ended.
+const RememberMeCookie = "remember"
+
 type Application struct {
 	APISecret      string
+	Funcs          template.FuncMap
 	Template       *template.Template
+	TextTemplate   *texttemplate.Template
 	TemplatesPath  string
+	OverridePath   string
 	Store          *sessions.CookieStore
 	DbMap          *gorp.DbMap
 	CsrfProtection *CsrfProtection
+	Security       SecurityOptions
 }
 
 type CsrfProtection struct {
 	Key    string
 	Cookie string
 	Header string
-	Secure bool
 }
 
 // GojiWebHandlerFunc is an adaptor that allows an http.HanderFunc where a
@@ -51,8 +58,11 @@ func GojiWebHandlerFunc(h http.HandlerFunc) web.HandlerFunc {
 	}
 }
 
+// Init opens the database and sets up the session store and CSRF protection.
+// The cookies are secured according to the Security options, which must be
+// set before.
 func (application *Application) Init(APISecret string, baseURL string,
-	cookieSecret string, cookieSecure bool, DBHost string, DBName string,
+	cookieSecret string, DBHost string, DBName string,
 	DBPassword string,
 	DBPort string, DBUser string) {
 
@@ -62,7 +72,7 @@ func (application *Application) Init(APISecret string, baseURL string,
 	application.Store.Options = &sessions.Options{
 		Path:     "/",
 		HttpOnly: true,
-		Secure:   cookieSecure,
+		Secure:   application.Security.CookieSecure,
 	}
 
 	application.DbMap = models.GetDbMap(
@@ -78,13 +88,14 @@ func (application *Application) Init(APISecret string, baseURL string,
 		Key:    CSRFKey,
 		Cookie: CSRFCookie,
 		Header: CSRFHeader,
-		Secure: cookieSecure,
 	}
 
 	application.APISecret = APISecret
 }
 
-func (application *Application) LoadTemplates(templatePath string) error {
+// findTemplates returns the files with extension ext in templatePath and its
+// subfolders.
+func findTemplates(templatePath, ext string) ([]string, error) {
 	var templates []string
 
 	fn := func(path string, f os.FileInfo, err error) error {
@@ -93,13 +104,34 @@ func (application *Application) LoadTemplates(templatePath string) error {
 		if err != nil {
 			return err
 		}
-		if !f.IsDir() && strings.HasSuffix(f.Name(), ".html") {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ext) {
 			templates = append(templates, path)
 		}
 		return nil
 	}
 
 	err := filepath.Walk(templatePath, fn)
+	return templates, err
+}
+
+// Folders of the override path holding the templates and the static files
+// replacing the built-in ones.
+const (
+	OverrideViews  = "views"
+	OverridePublic = "public"
+)
+
+// LoadTemplates parses the templates in templatePath: the html files as
+// html/template templates for pages and HTML emails, and the txt files as
+// text/template templates for plain text emails.  When OverridePath is set,
+// the templates in its views folder are parsed after them, so the templates
+// they define replace the built-in ones.
+func (application *Application) LoadTemplates(templatePath string) error {
+	templates, err := findTemplates(templatePath, ".html")
+	if err != nil {
+		return err
+	}
+	textTemplates, err := findTemplates(templatePath, ".txt")
 	if err != nil {
 		return err
 	}
@@ -107,12 +139,49 @@ func (application *Application) LoadTemplates(templatePath string) error {
 	// Since template.Must panics with non-nil error, it is much more
 	// informative to pass the error to the caller (runMain) to log it and exit
 	// gracefully.
-	httpTemplates, err := template.ParseFiles(templates...)
+	httpTemplates, err := template.New("").Funcs(application.Funcs).
+		ParseFiles(templates...)
 	if err != nil {
 		return err
 	}
+	mailTemplates := texttemplate.New("").
+		Funcs(texttemplate.FuncMap(application.Funcs))
+	if len(textTemplates) > 0 {
+		mailTemplates, err = mailTemplates.ParseFiles(textTemplates...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if application.OverridePath != "" {
+		overridePath := filepath.Join(application.OverridePath,
+			OverrideViews)
+		overrides, err := findTemplates(overridePath, ".html")
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if len(overrides) > 0 {
+			httpTemplates, err = httpTemplates.ParseFiles(overrides...)
+			if err != nil {
+				return err
+			}
+			log.Infof("Templates overridden by %s", overridePath)
+		}
+		textOverrides, err := findTemplates(overridePath, ".txt")
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if len(textOverrides) > 0 {
+			mailTemplates, err = mailTemplates.ParseFiles(textOverrides...)
+			if err != nil {
+				return err
+			}
+			log.Infof("Text templates overridden by %s", overridePath)
+		}
+	}
 
 	application.Template = template.Must(httpTemplates, nil)
+	application.TextTemplate = mailTemplates
 	application.TemplatesPath = templatePath
 	return nil
 }
@@ -131,10 +200,16 @@ func (application *Application) Route(controller interface{}, route string) web.
 
 		body, code := method(c, r)
 
-		if err := saveSession(c, w, r); err != nil {
+		if err := application.saveSession(c, w); err != nil {
 			log.Errorf("Can't save session: %v", err)
 		}
 
+		if cookies, ok := c.Env["Cookies"].([]*http.Cookie); ok {
+			for _, cookie := range cookies {
+				http.SetCookie(w, cookie)
+			}
+		}
+
 		if respHeader, exists := c.Env["ResponseHeaderMap"]; exists {
 			if hdrMap, ok := respHeader.(map[string]string); ok {
 				for key, val := range hdrMap {
@@ -161,11 +236,14 @@ func (application *Application) Route(controller interface{}, route string) web.
 	return fn
 }
 
-func saveSession(c web.C, w http.ResponseWriter, r *http.Request) error {
-	if session, exists := c.Env["Session"]; exists {
-		return session.(*sessions.Session).Save(r, w)
+// RememberMe returns the cookie storing the remember me token value, or
+// deleting it when value is empty.  maxAge is the lifetime of the cookie in
+// seconds.
+func (application *Application) RememberMe(value string, maxAge int) *http.Cookie {
+	if value == "" {
+		maxAge = -1
 	}
-	return errors.New("Session not available")
+	return application.cookie(RememberMeCookie, value, maxAge, true)
 }
 
 // APIHandler executes an API processing function that provides an *APIResponse
@@ -175,7 +253,7 @@ func (application *Application) APIHandler(apiFun func(web.C, *http.Request) *AP
 	return func(c web.C, w http.ResponseWriter, r *http.Request) {
 		apiResp := apiFun(c, r)
 
-		if err := saveSession(c, w, r); err != nil {
+		if err := application.saveSession(c, w); err != nil {
 			log.Errorf("Can't save session: %v", err)
 		}
 
@@ -188,6 +266,76 @@ func (application *Application) APIHandler(apiFun func(web.C, *http.Request) *AP
 	}
 }
 
+// APIv2Handler is APIHandler for version 2 of the API.  Failed calls are
+// answered with the HTTP status matching their code and an error object, and
+// calls with an invalid API token fail instead of being handled as
+// unauthenticated ones.
+func (application *Application) APIv2Handler(apiFun func(web.C, *http.Request) *APIResponse) web.HandlerFunc {
+	return func(c web.C, w http.ResponseWriter, r *http.Request) {
+		var apiResp *APIResponse
+		if msg, ok := c.Env["APITokenError"].(string); ok {
+			apiResp = NewAPIResponse("error", codes.Unauthenticated, msg, nil)
+		} else {
+			apiResp = apiFun(c, r)
+		}
+
+		if err := application.saveSession(c, w); err != nil {
+			log.Errorf("Can't save session: %v", err)
+		}
+
+		if apiResp == nil {
+			apiResp = NewAPIResponse("error", codes.NotFound,
+				"invalid API command or version", nil)
+		}
+		status := http.StatusOK
+		if apiResp.Status == "error" {
+			apiResp.Error = &APIError{
+				Code:    apiResp.Code.String(),
+				Message: apiResp.Message,
+			}
+			status = HTTPStatus(apiResp.Code)
+		}
+		WriteAPIResponse(apiResp, status, w)
+	}
+}
+
+// DeprecatedAPIHandler marks the responses of h as deprecated in favor of the
+// API at successor.
+func DeprecatedAPIHandler(successor string, h web.HandlerFunc) web.HandlerFunc {
+	return func(c web.C, w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		h(c, w, r)
+	}
+}
+
+// httpStatuses are the HTTP statuses of failed API calls by their code.
+var httpStatuses = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           http.StatusRequestTimeout,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+}
+
+// HTTPStatus returns the HTTP status of an API call that failed with code.
+// Unknown and internal errors are server errors.
+func HTTPStatus(code codes.Code) int {
+	if status, ok := httpStatuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
 // WriteAPIResponse marshals the given poolapi.Response into the
 // http.ResponseWriter and sets HTTP status code.
 func WriteAPIResponse(resp *APIResponse, code int, w http.ResponseWriter) {
@@ -215,9 +363,35 @@ type APIResponse struct {
 	Code    codes.Code  `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+	Error   *APIError   `json:"error,omitempty"`
+}
+
+// APIError describes why a version 2 API call failed.  Code is the name of
+// the code of the response, such as Unauthenticated.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 // NewAPIResponse is a constructor for APIResponse.
 func NewAPIResponse(status string, code codes.Code, message string, data interface{}) *APIResponse {
-	return &APIResponse{status, code, message, data}
+	return &APIResponse{Status: status, Code: code, Message: message, Data: data}
+}
+
+// OverlayFS is an http.FileSystem serving each file from the first of its
+// folders that has it, so files in earlier folders override the ones in later
+// folders.
+type OverlayFS []http.FileSystem
+
+// Open opens name in the first folder that has it.
+func (fs OverlayFS) Open(name string) (http.File, error) {
+	err := error(os.ErrNotExist)
+	for _, dir := range fs {
+		var f http.File
+		f, err = dir.Open(name)
+		if err == nil || !os.IsNotExist(err) {
+			return f, err
+		}
+	}
+	return nil, err
 }
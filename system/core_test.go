@@ -0,0 +1,91 @@
+package system
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestOverrides(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"views/home.html":            `{{define "home"}}built-in home{{end}}`,
+		"views/admin/status.html":    `{{define "admin/status"}}status{{end}}`,
+		"views/email/signup.txt":     `{{define "email/signup/text"}}<verify>{{end}}`,
+		"views/email/reset.txt":      `{{define "email/reset/text"}}reset{{end}}`,
+		"public/robots.txt":          "built-in robots",
+		"public/css/style.css":       "built-in style",
+		"override/views/brand.html":  `{{define "home"}}branded home{{end}}`,
+		"override/public/robots.txt": "branded robots",
+		"override/views/email.txt":   `{{define "email/reset/text"}}branded reset{{end}}`,
+	})
+	defer os.RemoveAll(dir)
+
+	app := &Application{OverridePath: filepath.Join(dir, "override")}
+	if err := app.LoadTemplates(filepath.Join(dir, "views")); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"home":         "branded home",
+		"admin/status": "status",
+	} {
+		var buf bytes.Buffer
+		if err := app.Template.ExecuteTemplate(&buf, name, nil); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Errorf("template %s is %q, want %q", name, buf.String(), want)
+		}
+	}
+	for name, want := range map[string]string{
+		"email/signup/text": "<verify>",
+		"email/reset/text":  "branded reset",
+	} {
+		var buf bytes.Buffer
+		if err := app.TextTemplate.ExecuteTemplate(&buf, name, nil); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Errorf("text template %s is %q, want %q", name, buf.String(), want)
+		}
+	}
+
+	fs := OverlayFS{http.Dir(filepath.Join(dir, "override", OverridePublic)),
+		http.Dir(filepath.Join(dir, "public"))}
+	for name, want := range map[string]string{
+		"/robots.txt":    "branded robots",
+		"/css/style.css": "built-in style",
+	} {
+		f, err := fs.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil || string(b) != want {
+			t.Errorf("%s is %q, %v, want %q", name, b, err, want)
+		}
+	}
+	if _, err := fs.Open("/missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("opening a missing file: %v", err)
+	}
+}
@@ -9,12 +9,28 @@ import (
 	"strings"
 
 	"github.com/coolsnady/hcstakepool/models"
+	"github.com/coolsnady/hcstakepool/tracing"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/go-gorp/gorp"
 	"github.com/gorilla/sessions"
 	"github.com/zenazn/goji/web"
+	"github.com/zenazn/goji/web/middleware"
 )
 
+// ApplyRequestID adds the ID given to the request by goji's RequestID
+// middleware to the request's context, so it is sent to stakepoold with the
+// gRPC calls made for the request and shows up in its logs.
+func (application *Application) ApplyRequestID(c *web.C, h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if reqID := middleware.GetReqID(*c); reqID != "" {
+			r = r.WithContext(tracing.ContextWithRequestID(r.Context(),
+				reqID))
+		}
+		h.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
 // Makes sure templates are stored in the context
 func (application *Application) ApplyTemplates(c *web.C, h http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
@@ -60,6 +76,7 @@ func (application *Application) ApplyAPI(c *web.C, h http.Handler) http.Handler
 
 				if err != nil {
 					log.Warnf("invalid token %v: %v", apitoken, err)
+					c.Env["APITokenError"] = "invalid api token"
 				} else {
 					if claims, ok := JWTtoken.Claims.(jwt.MapClaims); ok && JWTtoken.Valid {
 						dbMap := c.Env["DbMap"].(*gorp.DbMap)
@@ -67,6 +84,10 @@ func (application *Application) ApplyAPI(c *web.C, h http.Handler) http.Handler
 						user, err := models.GetUserById(dbMap, int64(claims["loggedInAs"].(float64)))
 						if err != nil {
 							log.Errorf("unable to map apitoken %v to user id %v", apitoken, claims["loggedInAs"])
+							c.Env["APITokenError"] = "api token of unknown user"
+						} else if user.Deleted != 0 {
+							log.Warnf("apitoken %v of deleted user id %v", apitoken, user.Id)
+							c.Env["APITokenError"] = "api token of deleted user"
 						} else {
 							c.Env["APIUserID"] = user.Id
 							log.Infof("mapped apitoken %v to user id %v", apitoken, user.Id)
@@ -92,6 +113,10 @@ func (application *Application) ApplyAuth(c *web.C, h http.Handler) http.Handler
 			if err != nil {
 				log.Warnf("Auth error: %v", err)
 				c.Env["User"] = nil
+			} else if u, ok := user.(*models.User); ok && u.Deleted != 0 {
+				// The account was deleted while signed in elsewhere.
+				session.Values["UserId"] = nil
+				c.Env["User"] = nil
 			} else {
 				c.Env["User"] = user
 			}
@@ -122,7 +147,7 @@ func isValidToken(a, b string) bool {
 	return subtle.ConstantTimeCompare(x, y) == 1
 }
 
-var csrfProtectionMethodForNoXhr = []string{"POST", "PUT", "DELETE"}
+var csrfProtectionMethodForNoXhr = []string{"POST", "PUT", "PATCH", "DELETE"}
 
 func isCsrfProtectionMethodForNoXhr(method string) bool {
 	return strInSlice(csrfProtectionMethodForNoXhr, strings.ToUpper(method)) >= 0
@@ -160,8 +185,8 @@ func (application *Application) ApplyCsrfProtection(c *web.C, h http.Handler) ht
 			}
 			hash.Write(buffer)
 			session.Values["CsrfToken"] = fmt.Sprintf("%x", hash.Sum(nil))
-			if err = session.Save(r, w); err != nil {
-				log.Criticalf("session.Save() failed")
+			if err = application.saveSession(*c, w); err != nil {
+				log.Criticalf("saveSession failed: %v", err)
 				panic(err)
 			}
 		}
@@ -182,12 +207,10 @@ func (application *Application) ApplyCsrfProtection(c *web.C, h http.Handler) ht
 				}
 			}
 		}
-		http.SetCookie(w, &http.Cookie{
-			Name:   csrfProtection.Cookie,
-			Value:  csrfToken,
-			Secure: csrfProtection.Secure,
-			Path:   "/",
-		})
+		// The token is readable by scripts so they can send it with
+		// XMLHttpRequests.
+		http.SetCookie(w, application.cookie(csrfProtection.Cookie, csrfToken,
+			0, false))
 		h.ServeHTTP(w, r)
 	}
 	return http.HandlerFunc(fn)
@@ -0,0 +1,11 @@
+// +build go1.11
+
+package system
+
+import "net/http"
+
+// setSameSite keeps browsers from sending cookie with the requests other
+// sites make.
+func setSameSite(cookie *http.Cookie) {
+	cookie.SameSite = http.SameSiteLaxMode
+}
@@ -0,0 +1,9 @@
+// +build !go1.11
+
+package system
+
+import "net/http"
+
+// setSameSite does nothing, as http.Cookie has no SameSite attribute before
+// Go 1.11.  The CSRF token still protects the forms.
+func setSameSite(cookie *http.Cookie) {}
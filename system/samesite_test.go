@@ -0,0 +1,15 @@
+// +build go1.11
+
+package system
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCookieSameSite(t *testing.T) {
+	app := &Application{}
+	if cookie := app.cookie("name", "value", 0, false); cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie has SameSite %v", cookie.SameSite)
+	}
+}
@@ -0,0 +1,97 @@
+package system
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/zenazn/goji/web"
+)
+
+// DefaultContentSecurityPolicy allows the scripts, styles and images of the
+// built-in templates.  Inline scripts and styles are allowed because the
+// templates use them.
+const DefaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'unsafe-inline' https://oss.maxcdn.com " +
+	"https://cdnjs.cloudflare.com; style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data:; frame-ancestors 'none'; form-action 'self'; " +
+	"base-uri 'self'"
+
+// SecurityOptions are the security headers sent with every response and the
+// attributes of the cookies the pool sets.
+type SecurityOptions struct {
+	// ContentSecurityPolicy is sent as the Content-Security-Policy header
+	// unless it is empty.
+	ContentSecurityPolicy string
+
+	// HSTSMaxAge is the max-age of the Strict-Transport-Security header
+	// sent with responses over HTTPS, which all are with CookieSecure
+	// since the pool is then served over HTTPS by a proxy.  No header is
+	// sent when it is 0.
+	HSTSMaxAge time.Duration
+
+	// CookieSecure only lets browsers send the cookies over HTTPS.
+	CookieSecure bool
+}
+
+// cookie returns a cookie named name holding value for maxAge seconds, or the
+// browser session when maxAge is 0, with the security attributes of the pool:
+// it is only sent over HTTPS with CookieSecure and, when built with Go 1.11 or
+// later, not with the requests other sites make.  httpOnly hides it from
+// scripts.
+func (application *Application) cookie(name, value string, maxAge int,
+	httpOnly bool) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   application.Security.CookieSecure,
+		HttpOnly: httpOnly,
+	}
+	setSameSite(cookie)
+	return cookie
+}
+
+// saveSession saves the session of the request in its cookie.  The session
+// is encoded by the session store, but the cookie is made by cookie, as the
+// store doesn't set all of its attributes.
+func (application *Application) saveSession(c web.C, w http.ResponseWriter) error {
+	session, ok := c.Env["Session"].(*sessions.Session)
+	if !ok {
+		return fmt.Errorf("session not available")
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.Values,
+		application.Store.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, application.cookie(session.Name(), encoded, 0, true))
+	return nil
+}
+
+// ApplySecurityHeaders sets the security headers of every response: the
+// configured Content-Security-Policy and Strict-Transport-Security, and
+// headers keeping browsers from guessing content types, framing the pages
+// and sending their URLs to other sites.
+func (application *Application) ApplySecurityHeaders(c *web.C, h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "same-origin")
+		security := &application.Security
+		if security.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy",
+				security.ContentSecurityPolicy)
+		}
+		if security.HSTSMaxAge > 0 && (r.TLS != nil || security.CookieSecure) {
+			header.Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d",
+				int64(security.HSTSMaxAge/time.Second)))
+		}
+		h.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
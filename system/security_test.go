@@ -0,0 +1,64 @@
+package system
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zenazn/goji/web"
+)
+
+func TestApplySecurityHeaders(t *testing.T) {
+	tests := []struct {
+		security SecurityOptions
+		https    bool
+		csp      string
+		hsts     string
+	}{
+		{SecurityOptions{}, false, "", ""},
+		{SecurityOptions{ContentSecurityPolicy: "default-src 'self'"}, false,
+			"default-src 'self'", ""},
+		{SecurityOptions{HSTSMaxAge: time.Hour}, false, "", ""},
+		{SecurityOptions{HSTSMaxAge: time.Hour}, true, "", "max-age=3600"},
+		{SecurityOptions{HSTSMaxAge: time.Hour, CookieSecure: true}, false, "",
+			"max-age=3600"},
+	}
+	for i, test := range tests {
+		app := &Application{Security: test.security}
+		h := app.ApplySecurityHeaders(&web.C{}, http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {}))
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.https {
+			r.TLS = &tls.ConnectionState{}
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		header := w.Header()
+		if header.Get("X-Content-Type-Options") != "nosniff" ||
+			header.Get("X-Frame-Options") != "DENY" {
+			t.Errorf("%d: missing headers: %v", i, header)
+		}
+		if csp := header.Get("Content-Security-Policy"); csp != test.csp {
+			t.Errorf("%d: Content-Security-Policy %q, want %q", i, csp, test.csp)
+		}
+		if hsts := header.Get("Strict-Transport-Security"); hsts != test.hsts {
+			t.Errorf("%d: Strict-Transport-Security %q, want %q", i, hsts,
+				test.hsts)
+		}
+	}
+}
+
+func TestRememberMeCookie(t *testing.T) {
+	app := &Application{Security: SecurityOptions{CookieSecure: true}}
+	cookie := app.RememberMe("token", 60)
+	if !cookie.Secure || !cookie.HttpOnly || cookie.MaxAge != 60 ||
+		cookie.Path != "/" {
+		t.Errorf("unexpected remember me cookie %+v", cookie)
+	}
+	if cookie := app.RememberMe("", 60); cookie.MaxAge != -1 {
+		t.Errorf("deleting remember me cookie has max age %d", cookie.MaxAge)
+	}
+}
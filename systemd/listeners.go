@@ -0,0 +1,78 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package systemd implements the parts of the systemd service protocols that
+// hcstakepool and stakepoold use, without linking libsystemd.
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFdsStart is the first file descriptor passed by socket activation.
+const listenFdsStart = 3
+
+// Listeners returns the sockets systemd passed to the process with socket
+// activation, by the name they were given with FileDescriptorName= in the
+// socket unit.  It returns nil when the process wasn't socket activated.  The
+// environment variables describing the sockets are unset so child processes
+// don't take them for their own, which means only the first call returns
+// them.
+func Listeners() (map[string][]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	return listeners(os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS"),
+		os.Getenv("LISTEN_FDNAMES"), listenFdsStart)
+}
+
+// listeners returns the fds passed as listening sockets starting at the
+// first file descriptor, as described by the LISTEN_* environment variables.
+func listeners(pid, fds, names string, first int) (map[string][]net.Listener, error) {
+	if pid == "" || fds == "" {
+		return nil, nil
+	}
+	// The sockets were meant for another process that exec'd this one.
+	if p, err := strconv.Atoi(pid); err != nil || p != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", fds)
+	}
+	var fdNames []string
+	if names != "" {
+		fdNames = strings.Split(names, ":")
+	}
+
+	ls := make(map[string][]net.Listener)
+	for i := 0; i < n; i++ {
+		name := "unknown"
+		if i < len(fdNames) {
+			name = fdNames[i]
+		}
+		// FileListener dups the fd, so the passed one is closed either
+		// way.
+		f := os.NewFile(uintptr(first+i), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, nls := range ls {
+				for _, l := range nls {
+					l.Close()
+				}
+			}
+			return nil, fmt.Errorf("passed socket %d (%s) is not a "+
+				"listening socket: %v", first+i, name, err)
+		}
+		ls[name] = append(ls[name], l)
+	}
+	return ls, nil
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestListeners(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	if ls, err := listeners("", "1", "rpc", 3); ls != nil || err != nil {
+		t.Errorf("listeners without LISTEN_PID returned %v, %v", ls, err)
+	}
+	if ls, err := listeners("1", "1", "rpc", 3); ls != nil || err != nil {
+		t.Errorf("listeners for another process returned %v, %v", ls, err)
+	}
+	if _, err := listeners(pid, "two", "", 3); err == nil {
+		t.Error("listeners with invalid LISTEN_FDS succeeded")
+	}
+
+	// Pass a copy of a listening socket the way systemd would.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, err := syscall.Dup(int(f.Fd()))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := listeners(pid, "1", "rpc", fd)
+	if err != nil {
+		t.Fatalf("listeners: %v", err)
+	}
+	if len(ls["rpc"]) != 1 || ls["rpc"][0].Addr().String() != l.Addr().String() {
+		t.Fatalf("passed listeners %v, want rpc on %v", ls, l.Addr())
+	}
+	ls["rpc"][0].Close()
+}
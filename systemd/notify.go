@@ -0,0 +1,53 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notify sends state, such as "READY=1" or "WATCHDOG=1", to the service
+// manager.  It does nothing when the process wasn't started by a systemd
+// service with notifications enabled.
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	conn, err := net.DialUnix("unixgram", nil,
+		&net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval returns how long the service manager waits for a
+// "WATCHDOG=1" notification before it considers the process hung and
+// restarts it, as set with WatchdogSec= in the service unit.  It returns 0
+// when the watchdog is disabled.
+func WatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+	// The watchdog is meant for another process that exec'd this one.
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" {
+		if p, err := strconv.Atoi(pid); err != nil || p != os.Getpid() {
+			return 0, nil
+		}
+	}
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %q", usec)
+	}
+	return time.Duration(n) * time.Microsecond, nil
+}
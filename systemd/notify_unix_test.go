@@ -0,0 +1,57 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package systemd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systemd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram",
+		&net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	os.Setenv("NOTIFY_SOCKET", socket)
+	defer os.Unsetenv("NOTIFY_SOCKET")
+	if err := Notify("READY=1"); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Errorf("received %q, %v", buf[:n], err)
+	}
+
+	os.Setenv("WATCHDOG_USEC", "30000000")
+	defer os.Unsetenv("WATCHDOG_USEC")
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	defer os.Unsetenv("WATCHDOG_PID")
+	if d, err := WatchdogInterval(); d != 30*time.Second || err != nil {
+		t.Errorf("WatchdogInterval() = %v, %v", d, err)
+	}
+	os.Setenv("WATCHDOG_PID", "1")
+	if d, err := WatchdogInterval(); d != 0 || err != nil {
+		t.Errorf("WatchdogInterval() for another process = %v, %v", d,
+			err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// httpsConfig holds the TLS configuration of the HTTPS listener.
+type httpsConfig struct {
+	tlsConfig *tls.Config
+
+	// acmeManager is set when certificates are obtained with ACME.  Its
+	// HTTP-01 challenge handler must be reachable on port 80 of each
+	// domain, which is done by wrapping the plain HTTP listener's handler.
+	acmeManager *autocert.Manager
+}
+
+// newHTTPSConfig creates the TLS configuration for the HTTPS listener from the
+// application config.  When ACME domains are configured, certificates are
+// obtained and renewed automatically using TLS-ALPN-01 on the HTTPS listener
+// or HTTP-01 on the plain listener, and the certificate files, if any, are
+// only served when ACME fails to provide a certificate.
+func newHTTPSConfig() (*httpsConfig, error) {
+	var fallback *tls.Certificate
+	if cfg.TLSCert != "" {
+		keyPair, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+		fallback = &keyPair
+	}
+
+	if len(cfg.ACMEDomains) == 0 {
+		return &httpsConfig{
+			tlsConfig: &tls.Config{
+				Certificates: []tls.Certificate{*fallback},
+			},
+		}, nil
+	}
+
+	if err := os.MkdirAll(cfg.ACMECacheDir, 0700); err != nil {
+		return nil, err
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cfg.ACMECacheDir),
+		HostPolicy: autocert.HostWhitelist(cfg.ACMEDomains...),
+		Email:      cfg.ACMEEmail,
+	}
+	if cfg.ACMEDirectory != "" {
+		manager.Client = &acme.Client{DirectoryURL: cfg.ACMEDirectory}
+	}
+
+	tlsConfig := manager.TLSConfig()
+	tlsConfig.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, err := manager.GetCertificate(hello)
+		if err == nil || fallback == nil {
+			return cert, err
+		}
+		log.Warnf("ACME certificate for %q unavailable, using %s: %v",
+			hello.ServerName, cfg.TLSCert, err)
+		return fallback, nil
+	}
+
+	return &httpsConfig{
+		tlsConfig:   tlsConfig,
+		acmeManager: manager,
+	}, nil
+}
+
+// httpHandler wraps the handler of the plain HTTP listener so it answers ACME
+// HTTP-01 challenges.  All other requests are passed through.
+func (c *httpsConfig) httpHandler(h http.Handler) http.Handler {
+	if c == nil || c.acmeManager == nil {
+		return h
+	}
+	return c.acmeManager.HTTPHandler(h)
+}
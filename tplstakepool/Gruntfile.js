@@ -11,7 +11,7 @@ module.exports = function(grunt) {
                     }, {
                         expand: true,
                         cwd: 'src/js',
-                        src: ['main.js', 'd3pie.min.js'],
+                        src: ['main.js', 'd3pie.min.js', 'poolgrowth.js'],
                         dest: '../public/js',
                         filter: 'isFile'
                     },
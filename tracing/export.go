@@ -0,0 +1,252 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// exportInterval is how often queued spans are sent to the collector.
+	exportInterval = 5 * time.Second
+
+	// exportBatchSize is the most spans sent in one request.  A full batch
+	// is sent right away.
+	exportBatchSize = 512
+
+	// exportQueueSize is how many ended spans may wait for export.  Spans
+	// ended while the queue is full are dropped rather than blocking the
+	// traced work.
+	exportQueueSize = 4 * exportBatchSize
+
+	exportTimeout = 10 * time.Second
+
+	// scopeName is the instrumentation scope reported for all spans.
+	scopeName = "github.com/coolsnady/hcstakepool/tracing"
+
+	// statusCodeError is the OTLP status code of failed spans.
+	statusCodeError = 2
+)
+
+// The otlp types are the JSON encoding of an OTLP ExportTraceServiceRequest.
+// IDs are hex encoded and 64-bit integers are strings as required by the
+// OTLP/HTTP JSON mapping.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// otlpSpanFromSpan converts an ended span.
+func otlpSpanFromSpan(s *Span) otlpSpan {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.sc.TraceID[:]),
+		SpanID:            hex.EncodeToString(s.sc.SpanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	for k, v := range s.attrs {
+		span.Attributes = append(span.Attributes, otlpKeyValue{
+			Key:   k,
+			Value: otlpValue{StringValue: v},
+		})
+	}
+	sort.Slice(span.Attributes, func(i, j int) bool {
+		return span.Attributes[i].Key < span.Attributes[j].Key
+	})
+	if s.err != "" {
+		span.Status = &otlpStatus{Code: statusCodeError, Message: s.err}
+	}
+	return span
+}
+
+// exporter batches ended spans and posts them to an OTLP/HTTP collector.
+type exporter struct {
+	url      string
+	resource otlpResource
+	client   *http.Client
+	queue    chan otlpSpan
+	quit     chan struct{}
+	wg       sync.WaitGroup
+
+	mtx     sync.Mutex
+	dropped int
+}
+
+// newExporter starts an exporter posting the spans of service to the
+// collector at endpoint through transport.
+func newExporter(service, endpoint string, transport http.RoundTripper) *exporter {
+	e := &exporter{
+		url: strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		resource: otlpResource{Attributes: []otlpKeyValue{{
+			Key:   "service.name",
+			Value: otlpValue{StringValue: service},
+		}}},
+		client: &http.Client{
+			Timeout:   exportTimeout,
+			Transport: transport,
+		},
+		queue: make(chan otlpSpan, exportQueueSize),
+		quit:  make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// export queues an ended span, or drops it when the queue is full.
+func (e *exporter) export(s *Span) {
+	select {
+	case e.queue <- otlpSpanFromSpan(s):
+	default:
+		e.mtx.Lock()
+		e.dropped++
+		e.mtx.Unlock()
+	}
+}
+
+// shutdown sends the queued spans and stops the exporter.
+func (e *exporter) shutdown() {
+	close(e.quit)
+	e.wg.Wait()
+}
+
+func (e *exporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]otlpSpan, 0, exportBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.post(batch); err != nil {
+			log.Warnf("Unable to export %d spans: %v", len(batch), err)
+		}
+		batch = batch[:0]
+
+		e.mtx.Lock()
+		dropped := e.dropped
+		e.dropped = 0
+		e.mtx.Unlock()
+		if dropped != 0 {
+			log.Warnf("Dropped %d spans while the export queue was full",
+				dropped)
+		}
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) == exportBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.quit:
+			for {
+				select {
+				case span := <-e.queue:
+					batch = append(batch, span)
+					if len(batch) == exportBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// post sends spans to the collector.
+func (e *exporter) post(spans []otlpSpan) error {
+	body, err := json.Marshal(&otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: e.resource,
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: scopeName},
+				Spans: spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.url, "application/json",
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector responded %s", resp.Status)
+	}
+	return nil
+}
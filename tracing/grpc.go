@@ -0,0 +1,66 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"golang.org/x/net/context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryClientInterceptor records a client span for every call made on behalf
+// of a traced request and passes the trace context and request ID on to the
+// server in the call's metadata.  Calls made outside of a traced request are
+// not recorded.
+func UnaryClientInterceptor(ctx context.Context, method string, req,
+	reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption) error {
+	reqID := RequestID(ctx)
+	ctx, span := Start(ctx, method, SpanKindClient)
+	if span == nil && reqID == "" {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	defer span.End()
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	if span != nil {
+		md[TraceparentHeader] = []string{span.SpanContext().Traceparent()}
+	}
+	if reqID != "" {
+		md[RequestIDHeader] = []string{reqID}
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	span.SetError(err)
+	return err
+}
+
+// FromIncomingContext returns ctx with the trace context the client sent in
+// the metadata of a call added by ContextWithRemoteParent and the request ID
+// added by ContextWithRequestID.
+func FromIncomingContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if values := md[RequestIDHeader]; len(values) != 0 {
+		ctx = ContextWithRequestID(ctx, values[0])
+	}
+	if values := md[TraceparentHeader]; len(values) != 0 {
+		if sc, ok := ParseTraceparent(values[0]); ok {
+			ctx = ContextWithRemoteParent(ctx, sc)
+		}
+	}
+	return ctx
+}
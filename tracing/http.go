@@ -0,0 +1,61 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// statusRecorder remembers the status code written to a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Middleware records a server span for every request handled by h.  The
+// span continues the trace of the client's traceparent header, if any, and
+// is available to the handler through the request's context.  The request ID,
+// if already added to the context, is recorded with the span.
+func (t *Tracer) Middleware(h http.Handler) http.Handler {
+	if t == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if sc, ok := ParseTraceparent(r.Header.Get(TraceparentHeader)); ok {
+			ctx = ContextWithRemoteParent(ctx, sc)
+		}
+		// The path leaves out the query, which may hold secrets.
+		ctx, span := t.Start(ctx, r.Method+" "+r.URL.Path, SpanKindServer)
+		defer span.End()
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+		if reqID := RequestID(ctx); reqID != "" {
+			span.SetAttribute("request.id", reqID)
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttribute("http.status_code", strconv.Itoa(rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetError(httpStatusError(rec.status))
+		}
+	})
+}
+
+// httpStatusError is the error of a span whose request failed with a server
+// error.
+type httpStatusError int
+
+func (e httpStatusError) Error() string {
+	return http.StatusText(int(e))
+}
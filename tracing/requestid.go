@@ -0,0 +1,48 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import "context"
+
+// RequestIDHeader is the gRPC metadata key carrying the ID of the frontend
+// request a call is made for.
+const RequestIDHeader = "x-request-id"
+
+// maxRequestIDLen is the longest request ID accepted.
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// validRequestID returns whether id may be used as a request ID.  IDs end up
+// in log lines, so only printable ASCII without spaces is allowed.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// ContextWithRequestID returns ctx carrying the request ID id.  Invalid IDs
+// are ignored.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	if !validRequestID(id) {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID in ctx, or the empty string.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2018-2020 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package tracing records spans for work done on behalf of a request, from
+// the frontend's HTTP handlers through the gRPC calls to stakepoold and its
+// wallet RPCs, and exports them to an OpenTelemetry collector over OTLP/HTTP.
+// The trace context crosses process boundaries in the W3C traceparent format.
+//
+// A nil *Tracer and a nil *Span are valid and record nothing, so callers
+// don't need to check whether tracing is enabled.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentHeader is the HTTP header and gRPC metadata key carrying the
+// trace context.
+const TraceparentHeader = "traceparent"
+
+// SpanKind tells the collector what role a span plays in a trace.  The values
+// are those of OTLP.
+type SpanKind int
+
+// Span kinds.
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// SpanContext identifies a span and the trace it belongs to.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// IsValid returns whether sc identifies a span.  The all zero IDs are
+// invalid.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Traceparent formats sc as a traceparent header value of a sampled trace.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" +
+		hex.EncodeToString(sc.SpanID[:]) + "-01"
+}
+
+// ParseTraceparent parses a traceparent header value.  It returns false when
+// s is not a valid version 00 traceparent.
+func ParseTraceparent(s string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 ||
+		len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.DecodeString(parts[3]); err != nil {
+		return sc, false
+	}
+	return sc, sc.IsValid()
+}
+
+// Span is a timed operation in a trace.
+type Span struct {
+	tracer   *Tracer
+	name     string
+	kind     SpanKind
+	sc       SpanContext
+	parentID [8]byte
+	start    time.Time
+
+	mtx   sync.Mutex
+	end   time.Time
+	attrs map[string]string
+	err   string
+	ended bool
+}
+
+// SpanContext returns the IDs of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttribute records a key/value pair describing the span.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	s.attrs[key] = value
+	s.mtx.Unlock()
+}
+
+// SetError marks the span as failed with err.  A nil err is ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mtx.Lock()
+	s.err = err.Error()
+	s.mtx.Unlock()
+}
+
+// End finishes the span and queues it for export.  Only the first call has
+// an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	if s.ended {
+		s.mtx.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mtx.Unlock()
+	s.tracer.exporter.export(s)
+}
+
+// Tracer starts spans of one service and exports them.
+type Tracer struct {
+	service  string
+	exporter *exporter
+}
+
+// NewTracer returns a tracer naming its spans' service service that exports
+// them to the OTLP/HTTP collector at endpoint, e.g. http://127.0.0.1:4318,
+// through transport.  A nil transport uses http.DefaultTransport.  It returns
+// nil, which disables tracing, when endpoint is empty.
+func NewTracer(service, endpoint string, transport http.RoundTripper) *Tracer {
+	if endpoint == "" {
+		return nil
+	}
+	return &Tracer{
+		service:  service,
+		exporter: newExporter(service, endpoint, transport),
+	}
+}
+
+// ValidateEndpoint checks that endpoint is an http or https URL that spans can
+// be exported to.
+func ValidateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("OTLP endpoint %q is not an http or https URL",
+			endpoint)
+	}
+	return nil
+}
+
+// Shutdown exports the spans that are still queued and stops the tracer.
+func (t *Tracer) Shutdown() {
+	if t == nil {
+		return
+	}
+	t.exporter.shutdown()
+}
+
+// Start starts a span named name.  Its parent is the span in ctx or, when
+// there is none, the remote span added by ContextWithRemoteParent.  Without
+// either it starts a new trace.  The returned context carries the new span.
+func (t *Tracer) Start(ctx context.Context, name string,
+	kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+		attrs:  make(map[string]string),
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		span.sc.TraceID = parent.sc.TraceID
+		span.parentID = parent.sc.SpanID
+	} else if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		span.sc.TraceID = remote.TraceID
+		span.parentID = remote.SpanID
+	} else {
+		randomID(span.sc.TraceID[:])
+	}
+	randomID(span.sc.SpanID[:])
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Start starts a child of the span in ctx with the same tracer.  It records
+// nothing when ctx carries no span, so work that isn't part of a traced
+// request doesn't start traces of its own.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context,
+	*Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, kind)
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// SpanFromContext returns the span in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteParent returns a context whose spans are started as
+// children of the span sc of another process.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// randomID fills id with random bytes.  It never leaves id all zero.
+func randomID(id []byte) {
+	for {
+		if _, err := rand.Read(id); err != nil {
+			panic(err)
+		}
+		for _, b := range id {
+			if b != 0 {
+				return
+			}
+		}
+	}
+}
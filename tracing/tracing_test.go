@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := ParseTraceparent(tp)
+	if !ok {
+		t.Fatalf("ParseTraceparent(%q) failed", tp)
+	}
+	if got := hex.EncodeToString(sc.TraceID[:]); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id %s", got)
+	}
+	if got := hex.EncodeToString(sc.SpanID[:]); got != "00f067aa0ba902b7" {
+		t.Errorf("span id %s", got)
+	}
+	if got := sc.Traceparent(); got != tp {
+		t.Errorf("Traceparent() = %q, want %q", got, tp)
+	}
+
+	invalid := []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	}
+	for _, s := range invalid {
+		if _, ok := ParseTraceparent(s); ok {
+			t.Errorf("ParseTraceparent(%q) succeeded", s)
+		}
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "op", SpanKindInternal)
+	span.SetAttribute("k", "v")
+	span.SetError(errors.New("failed"))
+	span.End()
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Fatal("nil tracer started a span")
+	}
+	if _, span := Start(ctx, "child", SpanKindInternal); span != nil {
+		t.Fatal("Start without a parent started a span")
+	}
+	tracer.Shutdown()
+}
+
+func TestExport(t *testing.T) {
+	var mtx sync.Mutex
+	var spans []otlpSpan
+	var service string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("spans posted to %s", r.URL.Path)
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %v", err)
+			return
+		}
+		mtx.Lock()
+		for _, rs := range req.ResourceSpans {
+			service = rs.Resource.Attributes[0].Value.StringValue
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+		mtx.Unlock()
+	}))
+	defer collector.Close()
+
+	tracer := NewTracer("stakepoold", collector.URL+"/", nil)
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithRemoteParent(context.Background(), remote)
+	ctx, root := tracer.Start(ctx, "SetUserVotingPrefs", SpanKindServer)
+	_, child := Start(ctx, "ListScripts", SpanKindClient)
+	child.SetAttribute("users", "3")
+	child.SetError(errors.New("wallet down"))
+	child.End()
+	child.End()
+	root.End()
+	tracer.Shutdown()
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if service != "stakepoold" {
+		t.Errorf("service %q", service)
+	}
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if r.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		r.ParentSpanID != "00f067aa0ba902b7" || r.Kind != SpanKindServer {
+		t.Errorf("root span %+v doesn't continue the remote trace", r)
+	}
+	if c.TraceID != r.TraceID || c.ParentSpanID != r.SpanID {
+		t.Errorf("child span %+v isn't a child of %+v", c, r)
+	}
+	if len(c.Attributes) != 1 || c.Attributes[0].Key != "users" ||
+		c.Attributes[0].Value.StringValue != "3" {
+		t.Errorf("child attributes %+v", c.Attributes)
+	}
+	if c.Status == nil || c.Status.Code != statusCodeError ||
+		c.Status.Message != "wallet down" {
+		t.Errorf("child status %+v", c.Status)
+	}
+	if r.Status != nil {
+		t.Errorf("root status %+v", r.Status)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	ctx := context.Background()
+	if id := RequestID(ctx); id != "" {
+		t.Fatalf("RequestID = %q without an ID", id)
+	}
+
+	const valid = "pool.example.com/kQ2vbXb3Lb-000042"
+	if id := RequestID(ContextWithRequestID(ctx, valid)); id != valid {
+		t.Errorf("RequestID = %q, want %q", id, valid)
+	}
+
+	invalid := []string{"", "two words", "line\nbreak", "\x1b[31mred",
+		strings.Repeat("a", maxRequestIDLen+1)}
+	for _, id := range invalid {
+		if got := RequestID(ContextWithRequestID(ctx, id)); got != "" {
+			t.Errorf("invalid request ID %q accepted as %q", id, got)
+		}
+	}
+}
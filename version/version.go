@@ -0,0 +1,136 @@
+// Copyright (c) 2013-2014 The btcsuite developers
+// Copyright (c) 2015 The Hcd developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package version provides the version and build information shared by
+// hcstakepool and stakepoold.  Build information is stamped at build time
+// using the linker, e.g.
+//
+//	go build -ldflags "-X github.com/coolsnady/hcstakepool/version.Commit=$(git rev-parse HEAD) \
+//	    -X github.com/coolsnady/hcstakepool/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// semanticAlphabet
+const semanticAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-"
+
+// These constants define the application version and follow the semantic
+// versioning 2.0.0 spec (http://semver.org/).
+const (
+	Major uint = 0
+	Minor uint = 1
+	Patch uint = 0
+
+	// PreRelease MUST only contain characters from semanticAlphabet
+	// per the semantic versioning spec.
+	PreRelease = ""
+)
+
+// These variables are defined as variables so they can be overridden during
+// the build process with '-ldflags "-X
+// github.com/coolsnady/hcstakepool/version.Name=foo"'.
+var (
+	// Build is the build metadata of the version.  It MUST only contain
+	// characters from semanticAlphabet per the semantic versioning spec.
+	// stakepoold sets it to dev when it isn't stamped.
+	Build string
+
+	// Commit is the revision control commit the binary was built from.
+	Commit string
+
+	// BuildDate is the time the binary was built, preferably in RFC 3339
+	// format.
+	BuildDate string
+
+	// Network is the network used when none is selected in the config.
+	// It must be one of mainnet, testnet or simnet.
+	Network = "mainnet"
+)
+
+// Info is the version and build information in a form suitable for JSON
+// encoding.
+type Info struct {
+	Version   string `json:"version"`
+	Major     uint   `json:"major"`
+	Minor     uint   `json:"minor"`
+	Patch     uint   `json:"patch"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"builddate,omitempty"`
+	Network   string `json:"network"`
+	GoVersion string `json:"goversion"`
+}
+
+// String returns the application version as a properly formed string per the
+// semantic versioning 2.0.0 spec (http://semver.org/).
+func String() string {
+	// Start with the major, minor, and patch versions.
+	version := fmt.Sprintf("%d.%d.%d", Major, Minor, Patch)
+
+	// Append pre-release version if there is one.  The hyphen called for
+	// by the semantic versioning spec is automatically appended and should
+	// not be contained in the pre-release string.  The pre-release version
+	// is not appended if it contains invalid characters.
+	preRelease := normalizeVerString(PreRelease)
+	if preRelease != "" {
+		version = fmt.Sprintf("%s-%s", version, preRelease)
+	}
+
+	// Append build metadata if there is any.  The plus called for
+	// by the semantic versioning spec is automatically appended and should
+	// not be contained in the build metadata string.  The build metadata
+	// string is not appended if it contains invalid characters.
+	build := normalizeVerString(Build)
+	if build != "" {
+		version = fmt.Sprintf("%s+%s", version, build)
+	}
+
+	return version
+}
+
+// Get returns the version and build information of the running binary.
+func Get() Info {
+	return Info{
+		Version:   String(),
+		Major:     Major,
+		Minor:     Minor,
+		Patch:     Patch,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		Network:   Network,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// Summary returns the version followed by the build information that is
+// known, suitable for logging at startup.
+func Summary() string {
+	s := String()
+	if Commit != "" {
+		s += " commit " + Commit
+	}
+	if BuildDate != "" {
+		s += " built " + BuildDate
+	}
+	return fmt.Sprintf("%s (Go version %s)", s, runtime.Version())
+}
+
+// normalizeVerString returns the passed string stripped of all characters which
+// are not valid according to the semantic versioning guidelines for pre-release
+// version and build metadata strings.  In particular they MUST only contain
+// characters in semanticAlphabet.
+func normalizeVerString(str string) string {
+	var result bytes.Buffer
+	for _, r := range str {
+		if strings.ContainsRune(semanticAlphabet, r) {
+			result.WriteRune(r)
+		}
+	}
+	return result.String()
+}
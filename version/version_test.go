@@ -0,0 +1,29 @@
+package version
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	defer func(build string) { Build = build }(Build)
+
+	base := fmt.Sprintf("%d.%d.%d", Major, Minor, Patch)
+	tests := []struct {
+		build string
+		want  string
+	}{
+		{"", base},
+		{"dev", base + "+dev"},
+		{"abc-123", base + "+abc-123"},
+		{"a.b/c+d", base + "+abcd"},
+		{"!@#", base},
+	}
+	for _, test := range tests {
+		Build = test.build
+		if got := String(); got != test.want {
+			t.Errorf("String with build %q: got %q, want %q",
+				test.build, got, test.want)
+		}
+	}
+}